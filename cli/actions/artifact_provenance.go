@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opentable/sous/cli/queries"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/yaml"
+	"github.com/pkg/errors"
+)
+
+// ArtifactProvenance is an Action which shows how an artifact was built.
+type ArtifactProvenance struct {
+	Query     queries.ArtifactQuery
+	SourceID  sous.SourceID
+	OutWriter io.Writer
+}
+
+// Do implements Action on ArtifactProvenance.
+func (a *ArtifactProvenance) Do() error {
+	ba, err := a.Query.Provenance(a.SourceID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve artifact: %s", err)
+	}
+	if ba == nil {
+		return errors.Errorf("no artifact for %s", a.SourceID)
+	}
+	if ba.Provenance == nil && ba.SBOM == nil {
+		return errors.Errorf("no provenance was recorded for %s: it was not built by Sous", a.SourceID)
+	}
+	// yaml.Marshal cannot return an error, it panics if anything goes wrong.
+	yml, _ := yaml.Marshal(struct {
+		Artifact   string
+		Provenance *sous.Provenance
+		SBOM       *sous.SBOM
+	}{ba.DigestReference, ba.Provenance, ba.SBOM})
+	_, err = a.OutWriter.Write(yml)
+	return err
+}
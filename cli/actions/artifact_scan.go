@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/opentable/sous/cli/queries"
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// ArtifactScan is an Action which scans an artifact for vulnerabilities,
+// replacing any earlier report recorded on it.
+type ArtifactScan struct {
+	Query     queries.ArtifactQuery
+	Scanner   sous.VulnerabilityScanner
+	Replacer  sous.Replacer
+	SourceID  sous.SourceID
+	OutWriter io.Writer
+}
+
+// Do implements Action on ArtifactScan.
+func (a *ArtifactScan) Do() error {
+	if a.Scanner == nil {
+		return errors.New("no vulnerability scanner is configured: set Scanning.Scanner")
+	}
+	ba, err := a.Query.ByID(a.SourceID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve artifact: %s", err)
+	}
+	if ba == nil {
+		return errors.Errorf("no artifact for %s", a.SourceID)
+	}
+	report, err := a.Scanner.ScanArtifact(*ba)
+	if err != nil {
+		return errors.Wrapf(err, "scanning %s", ba.DigestReference)
+	}
+	// The artifact's other qualities are already recorded, and the report
+	// replaces any earlier one.
+	ba.Qualities = report.Qualities()
+	if err := a.Replacer.Replace(a.SourceID, *ba); err != nil {
+		return errors.Wrapf(err, "recording scan of %s", ba.DigestReference)
+	}
+	_, err = fmt.Fprintf(a.OutWriter, "%s: %s\n", ba.DigestReference, report)
+	return err
+}
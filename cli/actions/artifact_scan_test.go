@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentable/sous/cli/queries"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactScan_Do(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(sous.BuildArtifact{
+			Type:            "docker",
+			DigestReference: "docker.example.com/hello@sha256:cabba9e",
+			Qualities:       sous.Qualities{{Name: "ephemeral_tag", Kind: "advisory"}},
+		})
+	}))
+	defer srv.Close()
+	cl, err := restful.NewClient(srv.URL, logging.SilentLogSet())
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "sous-scan-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vulnerabilities.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"sha256:cabba9e": [{"ID": "CVE-2018-0001", "Severity": "critical"}]}`), 0644))
+
+	ins, ctl := sous.NewInserterSpy()
+	out := &bytes.Buffer{}
+	sid := sous.MustNewSourceID("github.com/testorg/repo", "", "1.2.3")
+	a := &ArtifactScan{
+		Query:     queries.ArtifactQuery{Client: cl},
+		Scanner:   sous.NewFileVulnerabilityScanner(path),
+		Replacer:  ins,
+		SourceID:  sid,
+		OutWriter: out,
+	}
+	require.NoError(t, a.Do())
+
+	replaces := ctl.CallsTo("Replace")
+	require.Len(t, replaces, 1)
+	ba := replaces[0].PassedArgs().Get(1).(sous.BuildArtifact)
+	assert.Equal(t, sous.Qualities{
+		{Name: "scan:file", Kind: sous.VulnerabilityKind},
+		{Name: "critical:CVE-2018-0001", Kind: sous.VulnerabilityKind},
+	}, ba.Qualities)
+	assert.Equal(t, "docker.example.com/hello@sha256:cabba9e: 1 critical found by file\n", out.String())
+}
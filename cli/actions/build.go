@@ -7,6 +7,13 @@ import (
 	"github.com/opentable/sous/util/cmdr"
 )
 
+// A BuildAction builds and registers an artifact.
+type BuildAction interface {
+	Action
+	// Result returns the result of the build, once it is done.
+	Result() *sous.BuildResult
+}
+
 // Build handles building deployable artifacts.
 type Build struct {
 	GetArtifact  *GetArtifact
@@ -17,6 +24,17 @@ type Build struct {
 	result *sous.BuildResult
 }
 
+// RemoteBuild builds a deployable artifact from a fresh clone of its source
+// code, rather than from the local workspace.
+type RemoteBuild struct {
+	GetArtifact *GetArtifact
+	Builder     sous.SourceBuilder
+	SourceID    sous.SourceID
+	User        sous.User
+
+	result *sous.BuildResult
+}
+
 // Result returns the result of this build.
 func (sb *Build) Result() *sous.BuildResult {
 	return sb.result
@@ -30,14 +48,38 @@ func (sb *Build) Do() error {
 		}
 	}
 
-	registered, err := sb.GetArtifact.ArtifactExists()
+	if err := checkUnregistered(sb.GetArtifact); err != nil {
+		return err
+	}
+
+	var err error
+	sb.result, err = sb.BuildManager.Build()
+	return err
+}
+
+// Result returns the result of this build.
+func (rb *RemoteBuild) Result() *sous.BuildResult {
+	return rb.result
+}
+
+// Do performs the build.
+func (rb *RemoteBuild) Do() error {
+	if err := checkUnregistered(rb.GetArtifact); err != nil {
+		return err
+	}
+
+	var err error
+	rb.result, err = rb.Builder.BuildSource(rb.SourceID, rb.User)
+	return err
+}
+
+func checkUnregistered(ga *GetArtifact) error {
+	registered, err := ga.ArtifactExists()
 	if err != nil {
 		fmt.Printf("unable to verify artifact existence: %s", err)
 	}
 	if registered {
 		return fmt.Errorf("artifact already registered")
 	}
-
-	sb.result, err = sb.BuildManager.Build()
-	return err
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentable/sous/config"
@@ -29,6 +30,9 @@ type Deploy struct {
 	LogSink            logging.LogSink
 	User               sous.User
 	Force, WaitStable  bool
+	// BreakGlass asks the server to allow the deploy through a freeze of its
+	// cluster, where the freeze permits it.
+	BreakGlass bool
 	*config.Config
 }
 
@@ -38,10 +42,22 @@ func (sd *Deploy) Do() error {
 	if err != nil {
 		return err
 	}
+	return sd.deploy(func(spec *sous.DeploySpec) error {
+		spec.Version = newVersion
+		return nil
+	})
+}
 
+// deploy retrieves the current DeploySpec of the target deployment, applies
+// change to it, and PUTs it back to the server, waiting for the result if
+// WaitStable is set.
+func (sd *Deploy) deploy(change func(*sous.DeploySpec) error) error {
 	d := server.SingleDeploymentBody{}
 	q := sd.TargetDeploymentID.QueryMap()
 	q["force"] = strconv.FormatBool(sd.Force)
+	if sd.BreakGlass {
+		q["break-glass"] = "true"
+	}
 
 	updater, err := sd.HTTPClient.Retrieve("./single-deployment", q, &d, sd.User.HTTPHeaders())
 	if err != nil {
@@ -50,7 +66,10 @@ func (sd *Deploy) Do() error {
 	messages.ReportLogFieldsMessage("SousNewDeploy.Execute Retrieved Deployment",
 		logging.ExtraDebug1Level, sd.LogSink, d)
 
-	d.Deployment.Version = newVersion
+	if err := change(d.Deployment); err != nil {
+		return err
+	}
+	newVersion := d.Deployment.Version
 
 	defer func() {
 		messages.ReportLogFieldsMessageToConsole(
@@ -141,7 +160,7 @@ func (sd *Deploy) pollDeployQueue(location string, pollAtempts int, bar *mpb.Bar
 		queuePosition := response.QueuePosition
 
 		if response.Resolution != nil && response.Resolution.Error != nil {
-			return errors.Wrapf(response.Resolution.Error, "Failed to deploy, duration: %s\n", timeTrack(start))
+			return sd.deployFailed(location, *response.Resolution, start)
 		}
 
 		if queuePosition < 0 && response.Resolution != nil &&
@@ -159,7 +178,7 @@ func (sd *Deploy) pollDeployQueue(location string, pollAtempts int, bar *mpb.Bar
 					return nil
 				}
 				//exit out to error handler
-				return errors.Errorf("Failed to deploy %s: %s", location, response.Resolution.Error)
+				return sd.deployFailed(location, *response.Resolution, start)
 			}
 
 		}
@@ -174,6 +193,31 @@ func (sd *Deploy) pollDeployQueue(location string, pollAtempts int, bar *mpb.Bar
 	return errors.Errorf("Failed to deploy %s after %d attempts for duration: %s\n Response: %s\n", location, pollAtempts, timeTrack(start), responseJSON)
 }
 
+// deployFailed waits for the failed r11n at location to finish, so that any
+// automatic rollback made by the server can be reported.
+func (sd *Deploy) deployFailed(location string, rez sous.DiffResolution, start time.Time) error {
+	sep := "?"
+	if strings.Contains(location, "?") {
+		sep = "&"
+	}
+	final := dto.R11nResponse{}
+	if _, err := sd.HTTPClient.Retrieve(location+sep+"wait=true", nil, &final, nil); err == nil && final.Resolution != nil {
+		rez = *final.Resolution
+	}
+	if rb := rez.Rollback; rb != nil {
+		return errors.Errorf("Failed to deploy %s, rolled back to %s: %s, duration: %s\n",
+			rb.From.Version, rb.To.Version, rb.Reason, timeTrack(start))
+	}
+	if rez.Error != nil {
+		return errors.Wrapf(rez.Error, "Failed to deploy, duration: %s\n", timeTrack(start))
+	}
+	reason := "no error reported"
+	if rez.DeployState != nil && rez.DeployState.ExecutorMessage != "" {
+		reason = rez.DeployState.ExecutorMessage
+	}
+	return errors.Errorf("Failed to deploy %s: %s, duration: %s\n", location, reason, timeTrack(start))
+}
+
 func checkFinished(resolution sous.DiffResolution) bool {
 	switch resolution.Desc {
 	default:
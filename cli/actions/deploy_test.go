@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/nyarly/spies"
@@ -65,6 +66,41 @@ func TestPollDeployQueue_fail(t *testing.T) {
 	assert.Error(t, sd.pollDeployQueue(location, 10, nil))
 }
 
+func TestPollDeployQueue_rolledBack(t *testing.T) {
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+
+	deployResult := dto.R11nResponse{
+		QueuePosition: -1,
+		Resolution: &sous.DiffResolution{
+			Desc:        sous.ModifyDiff,
+			Error:       sous.WrapResolveError(errors.New("unhealthy")),
+			DeployState: &sous.DeployState{Status: sous.DeployStatusFailed},
+			Rollback: &sous.RollbackEvent{
+				From:   sous.MustNewSourceID("github.com/example/a", "", "2.0.0"),
+				To:     sous.MustNewSourceID("github.com/example/a", "", "1.0.0"),
+				Reason: "unhealthy",
+			},
+		},
+	}
+	ud, _ := restfultest.NewUpdateSpy()
+	ctrl.MatchMethod("Retrieve", spies.AnyArgs, deployResult, ud, nil)
+
+	sd := &Deploy{
+		HTTPClient: httpClient,
+		LogSink:    log,
+	}
+
+	err := sd.pollDeployQueue("127.0.0.1:1234/deploy-queue-item?action=a", 1, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "rolled back to 1.0.0")
+	}
+	calls := ctrl.CallsTo("Retrieve")
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "http://127.0.0.1:1234/deploy-queue-item?action=a&wait=true", calls[1].PassedArgs().String(0))
+	}
+}
+
 /*
 func (m *MyMockedHTTPClient) SetRZBody(body dto.R11nResponse) {
 	m.body = body
@@ -0,0 +1,109 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+// GroupDeploy deploys a new version to each cluster of a cluster group.
+type GroupDeploy struct {
+	// Group is the name of the cluster group.
+	Group string
+	// Deploys deploy to each cluster in the group, in order.
+	Deploys []*Deploy
+	// Serial deploys to one cluster at a time, waiting for each deploy to
+	// finish and pausing at the first which fails, rather than to every
+	// cluster at once.
+	Serial bool
+	// StatusPoller polls the clusters of the group for the outcome of
+	// deploying to them all at once.
+	StatusPoller *sous.StatusPoller
+	LogSink      logging.LogSink
+}
+
+// Do implements Action on GroupDeploy.
+func (gd *GroupDeploy) Do() error {
+	if len(gd.Deploys) == 0 {
+		return errors.Errorf("cluster group %q has no clusters", gd.Group)
+	}
+	if gd.Serial {
+		return gd.serial()
+	}
+	return gd.parallel()
+}
+
+// serial deploys to each cluster in turn, stopping at the first failure, so
+// that the deploy can be continued by running it again once the failure is
+// dealt with: clusters already deployed to are left as they are.
+func (gd *GroupDeploy) serial() error {
+	for i, d := range gd.Deploys {
+		cluster := d.TargetDeploymentID.Cluster
+		gd.report(fmt.Sprintf("Deploying to %s (%d of %d in group %s)", cluster, i+1, len(gd.Deploys), gd.Group))
+		if err := d.Do(); err != nil {
+			var rest []string
+			for _, r := range gd.Deploys[i+1:] {
+				rest = append(rest, r.TargetDeploymentID.Cluster)
+			}
+			if len(rest) == 0 {
+				return errors.Wrapf(err, "deploying to %s", cluster)
+			}
+			return errors.Wrapf(err, "deploying to %s; paused before %s", cluster, strings.Join(rest, ", "))
+		}
+	}
+	return nil
+}
+
+// parallel requests the deploy to each cluster at once, then, if asked to
+// wait, polls them all for the outcome.
+func (gd *GroupDeploy) parallel() error {
+	errs := make([]error, len(gd.Deploys))
+	var wg sync.WaitGroup
+	for i, d := range gd.Deploys {
+		wg.Add(1)
+		go func(i int, d Deploy) {
+			defer wg.Done()
+			d.WaitStable = false
+			errs[i] = d.Do()
+		}(i, *d)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", gd.Deploys[i].TargetDeploymentID.Cluster, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("deploying to group %s failed in %d of %d clusters:\n\t%s",
+			gd.Group, len(failed), len(gd.Deploys), strings.Join(failed, "\n\t"))
+	}
+	if !gd.Deploys[0].WaitStable || gd.StatusPoller == nil {
+		return nil
+	}
+
+	gd.report(fmt.Sprintf("Deploy requested to each cluster in group %s; waiting for them to finish", gd.Group))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	state, err := gd.StatusPoller.Wait(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "waiting for deploys to group %s", gd.Group)
+	}
+	if state != sous.ResolveComplete {
+		return errors.Errorf("deploying to group %s failed (state is %s)", gd.Group, state)
+	}
+	gd.report(fmt.Sprintf("Deployment complete in each cluster of group %s", gd.Group))
+	return nil
+}
+
+func (gd *GroupDeploy) report(msg string) {
+	messages.ReportLogFieldsMessageToConsole(msg, logging.InformationLevel, gd.LogSink)
+}
@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful/restfultest"
+	"github.com/stretchr/testify/assert"
+)
+
+func groupDeployTestDeploy(cluster string) (*Deploy, func() int) {
+	log, _ := logging.NewLogSinkSpy()
+	httpClient, ctrl := restfultest.NewHTTPClientSpy()
+	rf := &sous.ResolveFilter{}
+	rf.SetTag("1.0.0")
+	d := &Deploy{
+		ResolveFilter:      rf,
+		HTTPClient:         httpClient,
+		TargetDeploymentID: sous.DeploymentID{Cluster: cluster},
+		LogSink:            log,
+		WaitStable:         true,
+	}
+	return d, func() int { return len(ctrl.CallsTo("Retrieve")) }
+}
+
+func TestGroupDeploy_serialPausesAtFailure(t *testing.T) {
+	log, _ := logging.NewLogSinkSpy()
+	first, firstCalls := groupDeployTestDeploy("prod-sc")
+	second, secondCalls := groupDeployTestDeploy("prod-ln")
+	gd := &GroupDeploy{
+		Group:   "prod",
+		Deploys: []*Deploy{first, second},
+		Serial:  true,
+		LogSink: log,
+	}
+
+	err := gd.Do()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "deploying to prod-sc; paused before prod-ln")
+	}
+	assert.Equal(t, 1, firstCalls())
+	assert.Equal(t, 0, secondCalls())
+}
+
+func TestGroupDeploy_parallelReportsEachFailure(t *testing.T) {
+	log, _ := logging.NewLogSinkSpy()
+	first, firstCalls := groupDeployTestDeploy("prod-sc")
+	second, secondCalls := groupDeployTestDeploy("prod-ln")
+	gd := &GroupDeploy{
+		Group:   "prod",
+		Deploys: []*Deploy{first, second},
+		LogSink: log,
+	}
+
+	err := gd.Do()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failed in 2 of 2 clusters")
+		assert.Contains(t, err.Error(), "prod-sc: ")
+		assert.Contains(t, err.Error(), "prod-ln: ")
+	}
+	assert.Equal(t, 1, firstCalls())
+	assert.Equal(t, 1, secondCalls())
+	// The Deploys themselves are left as they were.
+	assert.True(t, first.WaitStable)
+}
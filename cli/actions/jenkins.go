@@ -20,6 +20,9 @@ type Jenkins struct {
 	LogSink          logging.LogSink
 	User             sous.User
 	Cluster          string
+	// Defs are used to find the DeploySpec of the manifest's deployment to
+	// Cluster, which may be that of a cluster group.
+	Defs sous.Defs
 	*config.Config
 }
 
@@ -98,11 +101,13 @@ func (sj *Jenkins) Do() error {
 		return fmt.Errorf("no config cluster specified")
 	}
 
+	depspec, _ := sj.Defs.ClusterSpec(&mani, clusterWithJenkinsConfig)
+
 	currentConfigMap := make(map[string]string)
-	if err != nil || mani.Deployments[clusterWithJenkinsConfig].Metadata == nil {
-		messages.ReportLogFieldsMessageWithIDs(fmt.Sprintf("Couldn't determine metadata for %s", clusterWithJenkinsConfig), logging.WarningLevel, sj.LogSink, err)
+	if depspec.Metadata == nil {
+		messages.ReportLogFieldsMessageWithIDs(fmt.Sprintf("Couldn't determine metadata for %s", clusterWithJenkinsConfig), logging.WarningLevel, sj.LogSink)
 	} else {
-		currentConfigMap = mani.Deployments[clusterWithJenkinsConfig].Metadata
+		currentConfigMap = depspec.Metadata
 	}
 
 	jenkinsConfig := sj.mergeDefaults(currentConfigMap)
@@ -116,13 +121,13 @@ func (sj *Jenkins) Do() error {
 
 	messages.ReportLogFieldsMessageWithIDs("PipeLine", logging.ExtraDebug1Level, sj.LogSink, jenkinsPipelineString)
 
-	depspec := mani.Deployments[clusterWithJenkinsConfig]
+	// The spec may be shared with the other clusters of a group.
+	depspec.Metadata = depspec.Metadata.Clone()
 	if depspec.Metadata == nil {
-		depspec.Metadata = map[string]string{}
+		depspec.Metadata = sous.Metadata{}
 	}
-
 	depspec.Metadata = sj.updateMetaData(depspec.Metadata, jenkinsConfig)
-	mani.Deployments[clusterWithJenkinsConfig] = depspec
+	sj.Defs.SetClusterSpec(&mani, clusterWithJenkinsConfig, depspec)
 
 	if _, err := up.Update(&mani, sj.User.HTTPHeaders()); err != nil {
 		return err
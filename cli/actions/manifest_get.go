@@ -19,6 +19,10 @@ type ManifestGet struct {
 	LogSink          logging.LogSink
 	OutWriter        io.Writer
 	UpdaterCapture   *restful.Updater
+	// Expanded requests the manifest with the config its deployments inherit
+	// from templates and clusters filled in. An expanded manifest is for
+	// reading: it should not be put back.
+	Expanded bool
 }
 
 // Do implements Action on ManifestGet.
@@ -36,7 +40,11 @@ func (mg *ManifestGet) Do() error {
 // GetManifest returns the sous.Manifest.
 func (mg *ManifestGet) GetManifest() (sous.Manifest, error) {
 	mani := sous.Manifest{}
-	up, err := mg.HTTPClient.Retrieve("./manifest", mg.TargetManifestID.QueryMap(), &mani, nil)
+	qs := mg.TargetManifestID.QueryMap()
+	if mg.Expanded {
+		qs["expanded"] = "true"
+	}
+	up, err := mg.HTTPClient.Retrieve("./manifest", qs, &mani, nil)
 
 	if err != nil {
 		return mani, errors.Errorf("No manifest matched by %v yet. See `sous init` (%v)", mg.ResolveFilter, err)
@@ -16,7 +16,7 @@ type (
 		tries      int
 		sid        sous.SourceID
 		did        sous.DeploymentID
-		manifest   *sous.Manifest
+		spec       sous.DeploySpec
 		user       sous.User
 		interval   logging.MessageInterval
 		err        error
@@ -41,7 +41,7 @@ func newUpdateBeginMessage(tries int,
 func newUpdateSuccessMessage(tries int,
 	sid sous.SourceID,
 	did sous.DeploymentID,
-	manifest *sous.Manifest,
+	spec sous.DeploySpec,
 	user sous.User,
 	start time.Time) updateMessage {
 	return updateMessage{
@@ -49,7 +49,7 @@ func newUpdateSuccessMessage(tries int,
 		tries:      tries,
 		sid:        sid,
 		did:        did,
-		manifest:   manifest,
+		spec:       spec,
 		user:       user,
 		interval:   logging.CompleteInterval(start),
 	}
@@ -106,7 +106,7 @@ func (msg updateMessage) WriteToConsole(console io.Writer) {
 	}
 	if msg.interval.Complete() {
 		version := msg.sid.Version.String()
-		numInstances := msg.manifest.Deployments[msg.did.Cluster].NumInstances
+		numInstances := msg.spec.NumInstances
 
 		if _, err := fmt.Fprintf(console, "Updated global manifest: %d instances of version %s\n",
 			numInstances, version); err != nil {
@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+)
+
+const tokenTimeFormat = "2006-01-02 15:04:05"
+
+// PlumbTokenIssue issues a new API token.
+type PlumbTokenIssue struct {
+	Store sous.TokenStore
+	Token sous.APIToken
+	Out   io.Writer
+}
+
+// PlumbTokenRevoke revokes an API token.
+type PlumbTokenRevoke struct {
+	Store sous.TokenStore
+	ID    string
+}
+
+// PlumbTokenList lists the API tokens that have been issued.
+type PlumbTokenList struct {
+	Store sous.TokenStore
+	Out   io.Writer
+}
+
+// Do implements Action on PlumbTokenIssue. The token is written to Out, and
+// cannot be retrieved again.
+func (p *PlumbTokenIssue) Do() error {
+	token, issued, err := sous.IssueAPIToken(p.Store, p.Token)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.Out, "Issued token %s to %s.\n", issued.ID, issued.User)
+	fmt.Fprintf(p.Out, "Configure it with: sous config APIToken %s\n", token)
+	return nil
+}
+
+// Do implements Action on PlumbTokenRevoke.
+func (p *PlumbTokenRevoke) Do() error {
+	return p.Store.Revoke(p.ID, time.Now())
+}
+
+// Do implements Action on PlumbTokenList.
+func (p *PlumbTokenList) Do() error {
+	tokens, err := p.Store.List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(p.Out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "id\tuser\tgroups\tcreated\trevoked\tdescription")
+	for _, t := range tokens {
+		revoked := "-"
+		if !t.Live() {
+			revoked = t.Revoked.Format(tokenTimeFormat)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.User,
+			strings.Join(t.Groups, ","), t.Created.Format(tokenTimeFormat), revoked, t.Description)
+	}
+	return w.Flush()
+}
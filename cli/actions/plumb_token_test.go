@@ -0,0 +1,38 @@
+package actions
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlumbToken(t *testing.T) {
+	store := sous.NewMemoryTokenStore()
+	out := &bytes.Buffer{}
+
+	issue := &PlumbTokenIssue{
+		Store: store,
+		Token: sous.APIToken{User: sous.User{Name: "Test User", Email: "test@user.com"}, Groups: []string{"deployers"}},
+		Out:   out,
+	}
+	require.NoError(t, issue.Do())
+	m := regexp.MustCompile(`sous config APIToken (\S+)`).FindStringSubmatch(out.String())
+	require.Len(t, m, 2, "token should be printed: %s", out)
+
+	found, err := store.Lookup(sous.HashAPIToken(m[1]))
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	out.Reset()
+	require.NoError(t, (&PlumbTokenList{Store: store, Out: out}).Do())
+	assert.Contains(t, out.String(), found.ID)
+	assert.Contains(t, out.String(), "deployers")
+	assert.NotContains(t, out.String(), m[1])
+
+	require.NoError(t, (&PlumbTokenRevoke{Store: store, ID: found.ID}).Do())
+	assert.Error(t, (&PlumbTokenRevoke{Store: store, ID: found.ID}).Do())
+}
@@ -0,0 +1,63 @@
+package actions
+
+import (
+	"fmt"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// Promote deploys the version currently running in one cluster to another.
+type Promote struct {
+	*Deploy
+	// From is the cluster to promote from.
+	From string
+	// FromClient talks to the server for From.
+	FromClient restful.HTTPClient
+}
+
+// Do implements Action on Promote.
+func (p *Promote) Do() error {
+	fromID := p.TargetDeploymentID
+	fromID.Cluster = p.From
+
+	q := fromID.QueryMap()
+	q["status"] = "true"
+	body := server.SingleDeploymentBody{}
+	if _, err := p.FromClient.Retrieve("./single-deployment", q, &body, p.User.HTTPHeaders()); err != nil {
+		return errors.Wrapf(err, "Failed to retrieve %s", fromID)
+	}
+
+	ds, err := promotableState(fromID, body)
+	if err != nil {
+		return err
+	}
+	version := ds.SourceID.Version
+
+	return p.deploy(func(spec *sous.DeploySpec) error {
+		messages.ReportLogFieldsMessageToConsole(
+			fmt.Sprintf("Promoting %s from %s to %s: %s -> %s",
+				p.TargetDeploymentID.ManifestID, p.From, p.TargetDeploymentID.Cluster,
+				spec.Version, version),
+			logging.InformationLevel, p.LogSink)
+		spec.Version = version
+		return nil
+	})
+}
+
+// promotableState returns the DeployState of the source deployment in body,
+// or an error unless it is active.
+func promotableState(fromID sous.DeploymentID, body server.SingleDeploymentBody) (*sous.DeployState, error) {
+	ds := body.DeployState
+	if ds == nil {
+		return nil, errors.Errorf("server did not report the status of %s", fromID)
+	}
+	if ds.Status != sous.DeployStatusActive {
+		return nil, errors.Errorf("%s is %s, not active; only active deployments can be promoted", fromID, ds.Status)
+	}
+	return ds, nil
+}
@@ -0,0 +1,28 @@
+package actions
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromotableState(t *testing.T) {
+	did := sous.DeploymentID{Cluster: "qa"}
+
+	_, err := promotableState(did, server.SingleDeploymentBody{})
+	assert.Error(t, err)
+
+	_, err = promotableState(did, server.SingleDeploymentBody{
+		DeployState: &sous.DeployState{Status: sous.DeployStatusFailed},
+	})
+	assert.Error(t, err)
+
+	ds, err := promotableState(did, server.SingleDeploymentBody{
+		DeployState: &sous.DeployState{Status: sous.DeployStatusActive},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, sous.DeployStatusActive, ds.Status)
+	}
+}
@@ -0,0 +1,82 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// Rollback returns a deployment to an earlier DeploySpec from its history.
+type Rollback struct {
+	*Deploy
+	// To is the version to roll back to. If it is empty, Rollback uses the
+	// most recent successfully deployed version other than the current one.
+	To string
+}
+
+// Do implements Action on Rollback.
+func (r *Rollback) Do() error {
+	var to *semv.Version
+	if r.To != "" {
+		v, err := semv.Parse(r.To)
+		if err != nil {
+			return errors.Wrapf(err, "version to roll back to")
+		}
+		to = &v
+	}
+
+	hist := dto.DeploymentHistoryResponse{}
+	if _, err := r.HTTPClient.Retrieve("./deployment-history", r.TargetDeploymentID.QueryMap(), &hist, r.User.HTTPHeaders()); err != nil {
+		return errors.Wrapf(err, "Failed to retrieve history of %s", r.TargetDeploymentID)
+	}
+
+	return r.deploy(func(spec *sous.DeploySpec) error {
+		entry, err := rollbackTarget(hist.Entries, spec.Version, to)
+		if err != nil {
+			return err
+		}
+		messages.ReportLogFieldsMessageToConsole(
+			fmt.Sprintf("Rolling back %s from %s to %s (deployed by %s at %s)",
+				r.TargetDeploymentID, spec.Version, entry.DeploySpec.Version,
+				entry.User.Name, entry.Time.Format("2006-01-02 15:04:05")),
+			logging.InformationLevel, r.LogSink)
+		restoreSpec(spec, entry)
+		return nil
+	})
+}
+
+// restoreSpec sets the version, and the values the manifest set itself, that
+// entry records in spec. Values the deployment inherits from templates and its
+// cluster are left as they are now, rather than pinned to what they were then.
+func restoreSpec(spec *sous.DeploySpec, entry sous.DeploymentHistoryEntry) {
+	spec.Version = entry.DeploySpec.Version
+	spec.DeployConfig = entry.DeploySpec.DeployConfig.MergeDefaults(spec.DeployConfig)
+}
+
+// rollbackTarget chooses the history entry to roll back to from entries,
+// which are ordered most recent first. If to is nil, it is the most recent
+// succeeded entry with a version other than current; otherwise it is the most
+// recent entry with version to.
+func rollbackTarget(entries []sous.DeploymentHistoryEntry, current semv.Version, to *semv.Version) (sous.DeploymentHistoryEntry, error) {
+	for _, e := range entries {
+		v := e.DeploySpec.Version
+		if to != nil {
+			if v.Equals(*to) {
+				return e, nil
+			}
+			continue
+		}
+		if e.Outcome == sous.OutcomeSucceeded && !v.Equals(current) {
+			return e, nil
+		}
+	}
+	if to != nil {
+		return sous.DeploymentHistoryEntry{}, errors.Errorf("version %s is not in the deployment history", to)
+	}
+	return sous.DeploymentHistoryEntry{}, errors.Errorf("no successful deployment other than %s in the deployment history", current)
+}
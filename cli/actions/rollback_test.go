@@ -0,0 +1,66 @@
+package actions
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollbackTarget(t *testing.T) {
+	entry := func(v string, o sous.DeploymentOutcome) sous.DeploymentHistoryEntry {
+		return sous.DeploymentHistoryEntry{
+			DeploySpec: sous.DeploySpec{Version: semv.MustParse(v)},
+			Outcome:    o,
+		}
+	}
+	entries := []sous.DeploymentHistoryEntry{
+		entry("3.0.0", sous.OutcomeFailed),
+		entry("2.0.0", sous.OutcomeSucceeded),
+		entry("1.0.0", sous.OutcomeSucceeded),
+	}
+
+	e, err := rollbackTarget(entries, semv.MustParse("3.0.0"), nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2.0.0", e.DeploySpec.Version.String())
+	}
+
+	e, err = rollbackTarget(entries, semv.MustParse("2.0.0"), nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1.0.0", e.DeploySpec.Version.String())
+	}
+
+	to := semv.MustParse("1.0.0")
+	e, err = rollbackTarget(entries, semv.MustParse("3.0.0"), &to)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1.0.0", e.DeploySpec.Version.String())
+	}
+
+	to = semv.MustParse("0.1.0")
+	_, err = rollbackTarget(entries, semv.MustParse("3.0.0"), &to)
+	assert.Error(t, err)
+
+	_, err = rollbackTarget(entries[:1], semv.MustParse("3.0.0"), nil)
+	assert.Error(t, err)
+}
+
+func TestRestoreSpec(t *testing.T) {
+	spec := sous.DeploySpec{
+		Version: semv.MustParse("3.0.0"),
+		DeployConfig: sous.DeployConfig{
+			NumInstances: 3,
+			Env:          sous.Env{"REGION": "west", "FEATURE": "on"},
+		},
+	}
+	restoreSpec(&spec, sous.DeploymentHistoryEntry{
+		DeploySpec: sous.DeploySpec{
+			Version:      semv.MustParse("2.0.0"),
+			DeployConfig: sous.DeployConfig{Env: sous.Env{"FEATURE": "off"}},
+		},
+	})
+
+	assert.Equal(t, "2.0.0", spec.Version.String())
+	assert.Equal(t, 3, spec.NumInstances)
+	assert.Equal(t, sous.Env{"REGION": "west", "FEATURE": "off"}, spec.Env)
+}
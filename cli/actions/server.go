@@ -12,6 +12,7 @@ import (
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/git"
+	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/logging"
@@ -31,6 +32,9 @@ type Server struct {
 	*config.Config
 	ServerHandler http.Handler
 	*sous.AutoResolver
+	// Webhooks, if not nil, are registered with each Singularity cluster
+	// once the server is listening.
+	Webhooks *singularity.Webhooks
 }
 
 // Do runs the server.
@@ -49,9 +53,29 @@ func (ss *Server) Do() error {
 
 	reportServerMessage("Sous Server Running", ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
 
-	fmt.Printf("Listening on http://%s", ss.ListenAddr)
+	tlsConfig, err := ss.Config.Authorization.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var s *http.Server
+	var listenAndServeErrs <-chan error
+	if tlsConfig != nil {
+		fmt.Printf("Listening on https://%s", ss.ListenAddr)
+		auth := ss.Config.Authorization
+		s, listenAndServeErrs = server.RunTLS(ss.ListenAddr, ss.ServerHandler, tlsConfig, auth.TLSCertFile, auth.TLSKeyFile)
+	} else {
+		fmt.Printf("Listening on http://%s", ss.ListenAddr)
+		s, listenAndServeErrs = server.Run(ss.ListenAddr, ss.ServerHandler)
+	}
 
-	s, listenAndServeErrs := server.Run(ss.ListenAddr, ss.ServerHandler)
+	if ss.Webhooks != nil {
+		go func() {
+			if err := ss.Webhooks.Register(); err != nil {
+				reportServerMessage(fmt.Sprintf("Singularity webhooks not registered: %s", err), ss.DeployFilterFlags, ss.ListenAddr, ss.Log)
+			}
+		}()
+	}
 
 	sigs := make(chan os.Signal)
 	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
@@ -66,7 +90,7 @@ func (ss *Server) Do() error {
 		}()
 	}()
 
-	err := <-listenAndServeErrs
+	err = <-listenAndServeErrs
 	if err == http.ErrServerClosed {
 		return <-shutdownErr
 	}
@@ -98,7 +98,8 @@ func updateRetryLoop(ls logging.LogSink,
 			continue
 		}
 
-		logging.Deliver(ls, newUpdateSuccessMessage(tries, sid, did, manifest, user, start))
+		spec, _ := state.Defs.ClusterSpec(manifest, did.Cluster)
+		logging.Deliver(ls, newUpdateSuccessMessage(tries, sid, did, spec, user, start))
 		return gdm, nil
 	}
 
@@ -53,6 +53,12 @@ var (
 	DeployFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp + allFlagHelp + tagFlagHelp
 	// NewDeployFilterFlagsHelp is the text and config for deploy flags
 	NewDeployFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp + tagFlagHelp
+	// HistoryFilterFlagsHelp is the text and config for commands about the
+	// history of a single deployment
+	HistoryFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp + clusterFlagHelp
+	// PromoteFilterFlagsHelp is the text and config for sous promote, which
+	// takes its clusters from -from and -to instead of -cluster
+	PromoteFilterFlagsHelp = repoFlagHelp + offsetFlagHelp + flavorFlagHelp
 	// AddArtifactFlagsHelp is the text and config for add artifact flags
 	AddArtifactFlagsHelp = repoFlagHelp + offsetFlagHelp + tagFlagHelp
 )
@@ -16,10 +16,22 @@ type ArtifactQuery struct {
 // ByID returns the single artifact matched by sid. It returns nil, nil if there
 // is no match and no error determining that.
 func (q *ArtifactQuery) ByID(sid sous.SourceID) (*sous.BuildArtifact, error) {
+	return q.retrieve(sid.HTTPQueryMap())
+}
+
+// Provenance returns the artifact matched by sid, as ByID does, with its
+// Provenance and SBOM.
+func (q *ArtifactQuery) Provenance(sid sous.SourceID) (*sous.BuildArtifact, error) {
+	qm := sid.HTTPQueryMap()
+	qm["provenance"] = "true"
+	return q.retrieve(qm)
+}
+
+func (q *ArtifactQuery) retrieve(qm map[string]string) (*sous.BuildArtifact, error) {
 	ba := &sous.BuildArtifact{}
 	header := q.User.HTTPHeaders()
 
-	_, err := q.Client.Retrieve("./artifact", sid.HTTPQueryMap(), ba, header)
+	_, err := q.Client.Retrieve("./artifact", qm, ba, header)
 	if err == nil {
 		return ba, nil
 	}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousArtifactProvenance is the description of the `sous artifact provenance` command
+type SousArtifactProvenance struct {
+	SousGraph *graph.SousGraph
+	opts      graph.ArtifactOpts
+}
+
+func init() { ArtifactSubcommands["provenance"] = &SousArtifactProvenance{} }
+
+// Help prints the help.
+func (*SousArtifactProvenance) Help() string {
+	return `Show how an artifact was built.
+
+Prints the provenance Sous recorded when it built the artifact for a
+SourceID: the source revision, the build strategy and the images it used,
+advisories, who built it, and how long it took. Then follows its software bill
+of materials: the dependencies declared in its source.
+`
+}
+
+// AddFlags adds the flags.
+func (sa *SousArtifactProvenance) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sa.opts.SourceID, AddArtifactFlagsHelp)
+}
+
+// RegisterOn adds flag-derived values to the graph.
+func (sa *SousArtifactProvenance) RegisterOn(psy Addable) {
+	dff := sa.opts.SourceID.DeployFilterFlags()
+	psy.Add(&dff)
+}
+
+// Execute defines the behavior of 'sous artifact provenance'.
+func (sa *SousArtifactProvenance) Execute(args []string) cmdr.Result {
+	if sa.opts.SourceID.Repo == "" {
+		return cmdr.UsageErrorf("-repo flag required")
+	}
+	if sa.opts.SourceID.Tag == "" {
+		return cmdr.UsageErrorf("-tag flag required")
+	}
+
+	provenance, err := sa.SousGraph.GetArtifactProvenance(sa.opts, os.Stdout)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	if err := provenance.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
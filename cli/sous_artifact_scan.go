@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousArtifactScan is the description of the `sous artifact scan` command
+type SousArtifactScan struct {
+	SousGraph *graph.SousGraph
+	opts      graph.ArtifactOpts
+}
+
+func init() { ArtifactSubcommands["scan"] = &SousArtifactScan{} }
+
+// Help prints the help.
+func (*SousArtifactScan) Help() string {
+	return `Scan an artifact for vulnerabilities.
+
+Scans the artifact for a SourceID with the configured vulnerability scanner,
+and records what it finds on the artifact, replacing the results of any
+earlier scan. Clusters with VulnerabilityThresholds refuse to deploy artifacts
+which exceed them, or which have never been scanned.
+`
+}
+
+// AddFlags adds the flags.
+func (sa *SousArtifactScan) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sa.opts.SourceID, AddArtifactFlagsHelp)
+}
+
+// RegisterOn adds flag-derived values to the graph.
+func (sa *SousArtifactScan) RegisterOn(psy Addable) {
+	dff := sa.opts.SourceID.DeployFilterFlags()
+	psy.Add(&dff)
+}
+
+// Execute defines the behavior of 'sous artifact scan'.
+func (sa *SousArtifactScan) Execute(args []string) cmdr.Result {
+	if sa.opts.SourceID.Repo == "" {
+		return cmdr.UsageErrorf("-repo flag required")
+	}
+	if sa.opts.SourceID.Tag == "" {
+		return cmdr.UsageErrorf("-tag flag required")
+	}
+
+	scan, err := sa.SousGraph.GetArtifactScan(sa.opts, os.Stdout)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+
+	if err := scan.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -28,6 +28,16 @@ const sousBuildHelp = `build your project
 build builds the project in your current directory by default. If you pass it a
 path, it will instead build the project at that path.
 
+If -repo names a project which is not checked out in the current directory, or
+-force-clone is given, build instead clones -repo at -tag from its source host
+into a scratch directory, and builds that. This does not need a checkout, so it
+is how CI agents may build any version.
+
+-platforms builds an image for each of a comma separated list of platforms, e.g.
+linux/amd64,linux/arm64, and pushes a manifest list of them in place of a single
+image. It defaults to the Docker.Platforms config. Only projects built from a
+plain Dockerfile, or by a buildpack plugin, can be built for a platform.
+
 args: [path]
 `
 
@@ -36,8 +46,8 @@ func (sb *SousBuild) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &sb.DeployFilterFlags, SourceFlagsHelp)
 	fs.BoolVar(&sb.PolicyFlags.Strict, "strict", false, "require that the build be pristine")
 	fs.BoolVar(&sb.PolicyFlags.Dev, "dev", false, "run build with developer options")
-	//fs.BoolVar(&sb.PolicyFlags.ForceClone, "force-clone", false, "force a shallow clone of the codebase before build")
-	// above is commented prior to impl.
+	fs.BoolVar(&sb.PolicyFlags.ForceClone, "force-clone", false, "build a fresh clone of -repo at -tag, even inside a checkout of it")
+	fs.StringVar(&sb.PolicyFlags.Platforms, "platforms", "", "comma separated platforms to build a manifest list for")
 }
 
 // Help returns the help string for this command
@@ -54,6 +64,7 @@ func (sb *SousBuild) RegisterOn(psy Addable) {
 func (sb *SousBuild) Execute(args []string) cmdr.Result {
 	sb.opts.CLIArgs = args
 	sb.opts.DFF = sb.DeployFilterFlags
+	sb.opts.Policy = sb.PolicyFlags
 	build, err := sb.SousGraph.GetBuild(sb.opts)
 	if err != nil {
 		return cmdr.EnsureErrorResult(err)
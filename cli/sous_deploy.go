@@ -26,6 +26,11 @@ usage: sous deploy (options)
 
 sous deploy will deploy the version tag for this application in the named
 cluster.
+
+With -cluster-group, it deploys to every cluster in the named group at once,
+and then waits for them all. With -serial as well, it deploys to them one at a
+time, and pauses at the first that fails: running the same command again
+continues from there.
 `
 
 // Help returns the help string for this command.
@@ -37,6 +42,8 @@ func (sd *SousDeploy) AddFlags(fs *flag.FlagSet) {
 
 	fs.BoolVar(&sd.opts.Force, "force", false,
 		"force deploy no matter if GDM already is at the correct version")
+	fs.BoolVar(&sd.opts.BreakGlass, "break-glass", false,
+		"deploy even though the cluster is frozen, if the freeze allows it")
 	fs.BoolVar(&sd.opts.WaitStable, "wait-stable", true,
 		"wait for the deploy to complete before returning (otherwise, use --wait-stable=false)")
 	fs.StringVar(&sd.opts.DryRun, "dry-run", "none",
@@ -44,6 +51,10 @@ func (sd *SousDeploy) AddFlags(fs *flag.FlagSet) {
 			"values are none,scheduler,registry,both")
 	fs.StringVar(&sd.opts.InitSingularityRequestID, "init-singularity-request-id", "",
 		"If this is the first deployment to this cluster; set the Singularity request ID to this value.")
+	fs.StringVar(&sd.opts.ClusterGroup, "cluster-group", "",
+		"deploy to every cluster in this group of clusters, defined in the Defs, instead of to -cluster")
+	fs.BoolVar(&sd.opts.Serial, "serial", false,
+		"with -cluster-group, deploy to one cluster at a time, pausing at the first failure")
 }
 
 // Execute fulfills the cmdr.Executor interface.
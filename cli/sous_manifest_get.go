@@ -14,6 +14,9 @@ import (
 type SousManifestGet struct {
 	config.DeployFilterFlags `inject:"optional"`
 	SousGraph                *graph.SousGraph
+	// expanded shows the effective config of each deployment, including
+	// what it inherits from templates and clusters.
+	expanded bool
 }
 
 func init() { ManifestSubcommands["get"] = &SousManifestGet{} }
@@ -26,6 +29,7 @@ func (*SousManifestGet) Help() string { return sousManifestHelp }
 // AddFlags implements AddFlagger on SousManifestGet.
 func (smg *SousManifestGet) AddFlags(fs *flag.FlagSet) {
 	MustAddFlags(fs, &smg.DeployFilterFlags, ManifestFilterFlagsHelp)
+	fs.BoolVar(&smg.expanded, "expanded", false, "show the effective config of each deployment, including what it inherits from templates and clusters")
 }
 
 // Execute implements Executor on SousManifestGet.
@@ -35,6 +39,7 @@ func (smg *SousManifestGet) Execute(args []string) cmdr.Result {
 	if err != nil {
 		return EnsureErrorResult(err)
 	}
+	mg.Expanded = smg.expanded
 
 	if err := mg.Do(); err != nil {
 		return cmdr.EnsureErrorResult(err)
@@ -47,16 +47,26 @@ func (smg *SousMetadataSet) Execute(args []string) cmdr.Result {
 		return EnsureErrorResult(err)
 	}
 
-	for cname, depspec := range mani.Deployments {
+	defs := sous.Defs{}
+	if _, err := smg.HTTPClient.Retrieve("/defs", nil, &defs, nil); err != nil {
+		return EnsureErrorResult(err)
+	}
+
+	for _, cname := range defs.Clusters.Names() {
 		if !smg.ResolveFilter.FilterClusterName(cname) {
 			continue
 		}
-
+		depspec, ok := defs.ClusterSpec(&mani, cname)
+		if !ok {
+			continue
+		}
+		// The spec may be shared with the other clusters of a group.
+		depspec.Metadata = depspec.Metadata.Clone()
 		if depspec.Metadata == nil {
-			depspec.Metadata = map[string]string{}
-			mani.Deployments[cname] = depspec
+			depspec.Metadata = sous.Metadata{}
 		}
 		depspec.Metadata[key] = value
+		defs.SetClusterSpec(&mani, cname, depspec)
 	}
 
 	if _, err := up.Update(&mani, smg.User.HTTPHeaders()); err != nil {
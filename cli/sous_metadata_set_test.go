@@ -8,6 +8,7 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/restful/restfultest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestMetadataSet(t *testing.T) {
@@ -24,7 +25,17 @@ func TestMetadataSet(t *testing.T) {
 		HTTPClient:       graph.HTTPClient{cl},
 	}
 
+	defs := sous.Defs{Clusters: sous.Clusters{
+		"cluster-1": &sous.Cluster{Name: "cluster-1"},
+		"cluster-2": &sous.Cluster{Name: "cluster-2"},
+	}}
+
 	updater, upctl := restfultest.NewUpdateSpy()
+	control.MatchMethod(
+		"Retrieve",
+		func(args mock.Arguments) bool { return args.String(0) == "/defs" },
+		defs, restfultest.DummyUpdater(), nil,
+	)
 	control.MatchMethod(
 		"Retrieve",
 		spies.Once(),
@@ -42,9 +53,9 @@ func TestMetadataSet(t *testing.T) {
 	res := sms.Execute([]string{"BuildBranch", "development"})
 	assert.Equal(t, 0, res.ExitCode())
 
-	if assert.Len(t, control.Calls(), 1) {
-		args := control.Calls()[0].PassedArgs()
-		assert.Regexp(t, "/manifest", args.String(0))
+	if assert.Len(t, control.Calls(), 2) {
+		assert.Regexp(t, "/manifest", control.Calls()[0].PassedArgs().String(0))
+		assert.Regexp(t, "/defs", control.Calls()[1].PassedArgs().String(0))
 	}
 	if assert.Len(t, upctl.Calls(), 1) {
 		args := upctl.Calls()[0].PassedArgs()
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+type (
+	// SousPlumbingToken is the `sous plumbing token` command.
+	SousPlumbingToken struct{}
+
+	// SousPlumbingTokenIssue is the `sous plumbing token issue` command.
+	SousPlumbingTokenIssue struct {
+		SousGraph *graph.SousGraph
+		Out       graph.OutWriter
+
+		token  sous.APIToken
+		groups string
+	}
+
+	// SousPlumbingTokenRevoke is the `sous plumbing token revoke` command.
+	SousPlumbingTokenRevoke struct {
+		SousGraph *graph.SousGraph
+	}
+
+	// SousPlumbingTokenList is the `sous plumbing token list` command.
+	SousPlumbingTokenList struct {
+		SousGraph *graph.SousGraph
+		Out       graph.OutWriter
+	}
+)
+
+// TokenSubcommands are the subcommands of `sous plumbing token`.
+var TokenSubcommands = cmdr.Commands{
+	"issue":  &SousPlumbingTokenIssue{},
+	"revoke": &SousPlumbingTokenRevoke{},
+	"list":   &SousPlumbingTokenList{},
+}
+
+func init() { PlumbingSubcommands["token"] = &SousPlumbingToken{} }
+
+const sousPlumbingTokenHelp = `manages the API tokens which identify users to sous servers
+
+Tokens are kept in the database configured for this sous, so these commands
+must be run by an operator with access to it. Servers only accept tokens when
+their Authorization.Identity is "token-store".
+`
+
+// Help implements Command on SousPlumbingToken.
+func (*SousPlumbingToken) Help() string { return sousPlumbingTokenHelp }
+
+// Subcommands implements Subcommander on SousPlumbingToken.
+func (SousPlumbingToken) Subcommands() cmdr.Commands {
+	return TokenSubcommands
+}
+
+// Execute prints usage, as `sous plumbing token` requires a subcommand.
+func (*SousPlumbingToken) Execute(args []string) cmdr.Result {
+	err := cmdr.UsageErrorf("usage: sous plumbing token <issue|revoke|list>")
+	err.Tip = "try `sous help plumbing token` for details"
+	return err
+}
+
+const sousPlumbingTokenIssueHelp = `issues a new API token
+
+usage: sous plumbing token issue -name <name> -email <email> [-groups <groups>]
+
+The token is printed once, and cannot be recovered afterwards: only its hash is
+stored. The user it was issued to configures it with 'sous config APIToken'.
+`
+
+// Help implements Command on SousPlumbingTokenIssue.
+func (*SousPlumbingTokenIssue) Help() string { return sousPlumbingTokenIssueHelp }
+
+// AddFlags implements cmdr.AddFlags on SousPlumbingTokenIssue.
+func (spt *SousPlumbingTokenIssue) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&spt.token.User.Name, "name", "", "the name of the user the token identifies")
+	fs.StringVar(&spt.token.User.Email, "email", "", "the email address of the user the token identifies")
+	fs.StringVar(&spt.groups, "groups", "", "a comma separated list of the groups the user belongs to")
+	fs.StringVar(&spt.token.Description, "description", "", "a note of what the token is for")
+}
+
+// Execute implements cmdr.Executor on SousPlumbingTokenIssue.
+func (spt *SousPlumbingTokenIssue) Execute(args []string) cmdr.Result {
+	if spt.token.User.Email == "" {
+		return cmdr.UsageErrorf("-email is required")
+	}
+	for _, g := range strings.Split(spt.groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			spt.token.Groups = append(spt.token.Groups, g)
+		}
+	}
+	issue, err := spt.SousGraph.GetPlumbingTokenIssue(spt.token, spt.Out)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := issue.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
+
+const sousPlumbingTokenRevokeHelp = `revokes an API token
+
+usage: sous plumbing token revoke <id>
+
+The id of each token is listed by 'sous plumbing token list'.
+`
+
+// Help implements Command on SousPlumbingTokenRevoke.
+func (*SousPlumbingTokenRevoke) Help() string { return sousPlumbingTokenRevokeHelp }
+
+// Execute implements cmdr.Executor on SousPlumbingTokenRevoke.
+func (spt *SousPlumbingTokenRevoke) Execute(args []string) cmdr.Result {
+	if len(args) != 1 {
+		return cmdr.UsageErrorf("usage: sous plumbing token revoke <id>")
+	}
+	revoke, err := spt.SousGraph.GetPlumbingTokenRevoke(args[0])
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := revoke.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Successf("Revoked token %s.", args[0])
+}
+
+// Help implements Command on SousPlumbingTokenList.
+func (*SousPlumbingTokenList) Help() string {
+	return `lists the API tokens that have been issued, including revoked ones`
+}
+
+// Execute implements cmdr.Executor on SousPlumbingTokenList.
+func (spt *SousPlumbingTokenList) Execute(args []string) cmdr.Result {
+	list, err := spt.SousGraph.GetPlumbingTokenList(spt.Out)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := list.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
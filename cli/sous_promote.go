@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousPromote is the command description for `sous promote`.
+type SousPromote struct {
+	SousGraph *graph.SousGraph
+
+	opts graph.DeployActionOpts
+	from string
+}
+
+func init() { TopLevelCommands["promote"] = &SousPromote{} }
+
+const sousPromoteHelp = `deploys the version running in one cluster to another
+
+usage: sous promote (options) -from <cluster> -to <cluster>
+
+sous promote reads the version of this application that is running in the
+-from cluster and deploys it to the -to cluster. The deployment in the -from
+cluster must be active.
+
+If the server defines a PromotionOrder, it also refuses to deploy a version to
+a cluster unless that version is active in the cluster before it in the order.
+`
+
+// Help returns the help string for this command.
+func (sp *SousPromote) Help() string { return sousPromoteHelp }
+
+// AddFlags adds the flags for sous promote.
+func (sp *SousPromote) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sp.opts.DFF, PromoteFilterFlagsHelp)
+
+	fs.StringVar(&sp.from, "from", "",
+		"the cluster to promote from")
+	fs.StringVar(&sp.opts.DFF.Cluster, "to", "",
+		"the cluster to promote to")
+	fs.BoolVar(&sp.opts.WaitStable, "wait-stable", true,
+		"wait for the deploy to complete before returning (otherwise, use --wait-stable=false)")
+	fs.BoolVar(&sp.opts.Force, "force", false,
+		"force deploy no matter if GDM already is at the correct version")
+	fs.BoolVar(&sp.opts.BreakGlass, "break-glass", false,
+		"deploy even though the cluster is frozen, if the freeze allows it")
+}
+
+// Execute fulfills the cmdr.Executor interface.
+func (sp *SousPromote) Execute(args []string) cmdr.Result {
+	if sp.from == "" || sp.opts.DFF.Cluster == "" {
+		return cmdr.UsageErrorf("both -from and -to are required")
+	}
+	promote, err := sp.SousGraph.GetPromote(sp.opts, sp.from)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := promote.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success("Done.")
+}
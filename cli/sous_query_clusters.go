@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/graph"
+	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/cmdr"
 )
 
@@ -33,7 +36,9 @@ type (
 
 func init() { QuerySubcommands["clusters"] = &SousQueryClusters{} }
 
-const sousQueryClustersHelp = `The current set of available clusters for deployment.`
+const sousQueryClustersHelp = `The current set of available clusters for deployment.
+
+Any active or upcoming deployment freezes are shown alongside each cluster.`
 
 // Help prints the help
 func (*SousQueryClusters) Help() string { return sousQueryClustersHelp }
@@ -56,6 +61,12 @@ func (sqc *SousQueryClusters) Execute(args []string) cmdr.Result {
 		return cmdr.EnsureErrorResult(err)
 	}
 
+	defs := sous.Defs{}
+	if _, err := sqc.Retrieve("./defs", nil, &defs, nil); err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	now := time.Now()
+
 	out := &bytes.Buffer{}
 	w := &tabwriter.Writer{}
 	w.Init(out, 2, 4, 2, ' ', 0)
@@ -65,9 +76,51 @@ func (sqc *SousQueryClusters) Execute(args []string) cmdr.Result {
 		if sqc.flags.includeURLs {
 			fmt.Fprintf(w, "\t%s", s.URL)
 		}
+		if c, ok := defs.Clusters[s.ClusterName]; ok && c != nil {
+			if summary := freezeSummary(c.FreezeWindows, now); summary != "" {
+				fmt.Fprintf(w, "\t%s", summary)
+			}
+		}
 		fmt.Fprintln(w, "")
 	}
 	w.Flush()
 
 	return cmdr.SuccessData(out.Bytes())
 }
+
+// freezeSummary describes the freezes from fws which are active at now, or
+// failing that the next one to start.
+func freezeSummary(fws sous.FreezeWindows, now time.Time) string {
+	const layout = "2006-01-02 15:04 MST"
+	describe := func(prefix string, f sous.Freeze) string {
+		until := "further notice"
+		if !f.End.IsZero() {
+			until = f.End.UTC().Format(layout)
+		}
+		s := prefix + until
+		if f.Window.Reason != "" {
+			s += " (" + f.Window.Reason + ")"
+		}
+		return s
+	}
+
+	if active := fws.Active(now); len(active) > 0 {
+		var ss []string
+		for _, f := range active {
+			ss = append(ss, describe("FROZEN until ", f))
+		}
+		return strings.Join(ss, "; ")
+	}
+
+	var next *sous.Freeze
+	for _, f := range fws.Upcoming(now) {
+		f := f
+		if next == nil || f.Start.Before(next.Start) {
+			next = &f
+		}
+	}
+	if next == nil {
+		return ""
+	}
+	return describe("freeze from "+next.Start.UTC().Format(layout)+" until ", *next)
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+
+	"github.com/opentable/sous/cli/queries"
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousQueryDeps is the description of the `sous query deps` command
+type SousQueryDeps struct {
+	DeploymentQuery queries.Deployment
+	flags           struct {
+		cluster string
+		format  string
+	}
+
+	Out graph.OutWriter
+}
+
+func init() { QuerySubcommands["deps"] = &SousQueryDeps{} }
+
+const sousQueryDepsHelp = `The graph of the dependencies between the deployments to a cluster.
+
+Only deployments which have dependencies, or are depended on, are shown.
+Dependencies which are not met, and manifests which are depended on but not
+deployed to the cluster, are marked. The dot format can be drawn by Graphviz,
+e.g. 'sous query deps -cluster ci | dot -Tsvg > deps.svg'.
+`
+
+// Help prints the help
+func (*SousQueryDeps) Help() string { return sousQueryDepsHelp }
+
+// RegisterOn adds options set by flags to the injection graph.
+func (*SousQueryDeps) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&config.DeployFilterFlags{})
+}
+
+// AddFlags adds the flags for 'sous query deps'.
+func (sqd *SousQueryDeps) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sqd.flags.cluster, "cluster", "", "the cluster to show the dependencies in")
+	fs.StringVar(&sqd.flags.format, "format", "dot", "output format, one of (dot, json)")
+}
+
+func (sqd *SousQueryDeps) dump(g sous.DependencyGraph) error {
+	switch sqd.flags.format {
+	default:
+		return cmdr.UsageErrorf("output format %q not valid, pick one of: dot, json", sqd.flags.format)
+	case "", "dot":
+		return g.WriteDot(sqd.Out)
+	case "json":
+		return json.NewEncoder(sqd.Out).Encode(g)
+	}
+}
+
+// Execute defines the behavior of `sous query deps`.
+func (sqd *SousQueryDeps) Execute(args []string) cmdr.Result {
+	if sqd.flags.cluster == "" {
+		return cmdr.UsageErrorf("-cluster is required")
+	}
+
+	result, err := sqd.DeploymentQuery.Result(queries.DeploymentFilters{})
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+
+	if err := sqd.dump(result.Deployments.DependencyGraph(sqd.flags.cluster)); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success()
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+)
+
+func TestSousQueryDeps_dump(t *testing.T) {
+	api := &sous.Deployment{
+		ClusterName:  "ci",
+		SourceID:     sous.MustNewSourceID("github.com/user/api", "", "1.0.0"),
+		DeployConfig: sous.DeployConfig{NumInstances: 1},
+		Dependencies: sous.Dependencies{{Manifest: sous.MustParseManifestID("github.com/user/db"), Version: ">=2.0.0"}},
+	}
+	db := &sous.Deployment{
+		ClusterName:  "ci",
+		SourceID:     sous.MustNewSourceID("github.com/user/db", "", "1.0.0"),
+		DeployConfig: sous.DeployConfig{NumInstances: 1},
+	}
+	g := sous.NewDeployments(api, db).DependencyGraph("ci")
+
+	dump := func(format string) (string, error) {
+		sqd := &SousQueryDeps{}
+		out := &bytes.Buffer{}
+		sqd.Out = out
+		sqd.flags.format = format
+		err := sqd.dump(g)
+		return out.String(), err
+	}
+
+	dot, err := dump("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"github.com/user/api" -> "github.com/user/db" [label=">=2.0.0", color=red];`
+	if !strings.Contains(dot, want) {
+		t.Errorf("got dot output not containing %s:\n%s", want, dot)
+	}
+
+	js, err := dump("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sous.DependencyGraph{}
+	if err := json.Unmarshal([]byte(js), &got); err != nil {
+		t.Fatalf("invalid JSON: %s; output was:\n%s", err, js)
+	}
+	if len(got.Edges) != 1 || got.Edges[0].Violation != `version 1.0.0 is not in ">=2.0.0"` {
+		t.Errorf("got edges %+v; want the one unmet dependency", got.Edges)
+	}
+
+	if _, err := dump("table"); err == nil || !strings.Contains(err.Error(), `output format "table" not valid`) {
+		t.Errorf("got error %v; want invalid format", err)
+	}
+}
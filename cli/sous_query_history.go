@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/dto"
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousQueryHistory is the description of the `sous query history` command.
+type SousQueryHistory struct {
+	config.DeployFilterFlags `inject:"optional"`
+	TargetDeploymentID       graph.TargetDeploymentID
+	HTTP                     *graph.ClusterSpecificHTTPClient
+	Out                      graph.OutWriter
+	Err                      graph.ErrWriter
+	flags                    struct {
+		format string
+	}
+}
+
+func init() { QuerySubcommands["history"] = &SousQueryHistory{} }
+
+const sousQueryHistoryHelp = `The changes made to a single deployment, most recent first.
+
+Each change to a deployment in the GDM is recorded along with the user that
+made it, and whether it deployed successfully. Use 'sous rollback' to return to
+an earlier entry.
+`
+
+// Help prints the help
+func (*SousQueryHistory) Help() string { return sousQueryHistoryHelp }
+
+// RegisterOn adds options set by flags to the injection graph.
+func (sqh *SousQueryHistory) RegisterOn(psy Addable) {
+	psy.Add(graph.DryrunNeither)
+	psy.Add(&sqh.DeployFilterFlags)
+}
+
+// AddFlags adds the flags for 'sous query history'.
+func (sqh *SousQueryHistory) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sqh.DeployFilterFlags, HistoryFilterFlagsHelp)
+	fs.StringVar(&sqh.flags.format, "format", "table", "output format, one of (table, json)")
+}
+
+// Execute defines the behavior of `sous query history`.
+func (sqh *SousQueryHistory) Execute(args []string) cmdr.Result {
+	switch sqh.flags.format {
+	default:
+		return cmdr.UsageErrorf("output format %q not valid, pick one of: table, json", sqh.flags.format)
+	case "", "table", "json":
+	}
+
+	did := sous.DeploymentID(sqh.TargetDeploymentID)
+	hist := dto.DeploymentHistoryResponse{}
+	if _, err := sqh.HTTP.Retrieve("./deployment-history", did.QueryMap(), &hist, nil); err != nil {
+		return EnsureErrorResult(err)
+	}
+
+	fmt.Fprintf(sqh.Err, "%d results\n", len(hist.Entries))
+	if sqh.flags.format == "json" {
+		jsonHistory(sqh.Out, hist.Entries)
+	} else {
+		dumpHistory(sqh.Out, hist.Entries)
+	}
+	return cmdr.Success()
+}
+
+func dumpHistory(out io.Writer, entries []sous.DeploymentHistoryEntry) {
+	w := &tabwriter.Writer{}
+	w.Init(out, 2, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "time\tversion\tinstances\tuser\toutcome")
+	for _, e := range entries {
+		// Instances inherited from a template are not in the history.
+		instances := "-"
+		if n := e.DeploySpec.NumInstances; n != 0 {
+			instances = fmt.Sprint(n)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Format("2006-01-02 15:04:05"), e.DeploySpec.Version,
+			instances, e.User.Name, e.Outcome)
+	}
+	w.Flush()
+}
+
+func jsonHistory(out io.Writer, entries []sous.DeploymentHistoryEntry) {
+	j := json.NewEncoder(out)
+	for _, e := range entries {
+		j.Encode(e)
+	}
+}
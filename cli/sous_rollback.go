@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/opentable/sous/graph"
+	"github.com/opentable/sous/util/cmdr"
+)
+
+// SousRollback is the command description for `sous rollback`.
+type SousRollback struct {
+	SousGraph *graph.SousGraph
+
+	opts graph.DeployActionOpts
+	to   string
+}
+
+func init() { TopLevelCommands["rollback"] = &SousRollback{} }
+
+const sousRollbackHelp = `returns a deployment to an earlier version
+
+usage: sous rollback (options) [-to <version>]
+
+sous rollback redeploys a version of this application from its deployment
+history in the named cluster. Without -to, it uses the most recent version that
+deployed successfully and is not the current version. The whole deploy spec
+recorded with that version is restored, not just the version.
+
+Use 'sous query history' to list the deployment history.
+`
+
+// Help returns the help string for this command.
+func (sr *SousRollback) Help() string { return sousRollbackHelp }
+
+// AddFlags adds the flags for sous rollback.
+func (sr *SousRollback) AddFlags(fs *flag.FlagSet) {
+	MustAddFlags(fs, &sr.opts.DFF, HistoryFilterFlagsHelp)
+
+	fs.StringVar(&sr.to, "to", "",
+		"the version to roll back to (default: the last successfully deployed version)")
+	fs.BoolVar(&sr.opts.WaitStable, "wait-stable", true,
+		"wait for the deploy to complete before returning (otherwise, use --wait-stable=false)")
+	fs.BoolVar(&sr.opts.Force, "force", false,
+		"force deploy no matter if GDM already is at the correct version")
+	fs.BoolVar(&sr.opts.BreakGlass, "break-glass", false,
+		"deploy even though the cluster is frozen, if the freeze allows it")
+}
+
+// Execute fulfills the cmdr.Executor interface.
+func (sr *SousRollback) Execute(args []string) cmdr.Result {
+	rollback, err := sr.SousGraph.GetRollback(sr.opts, sr.to)
+	if err != nil {
+		return cmdr.EnsureErrorResult(err)
+	}
+	if err := rollback.Do(); err != nil {
+		return EnsureErrorResult(err)
+	}
+	return cmdr.Success("Done.")
+}
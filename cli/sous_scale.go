@@ -65,12 +65,17 @@ func (ss *SousScale) Execute(args []string) cmdr.Result {
 		return EnsureErrorResult(errors.Wrapf(err, "getting manifest"))
 	}
 
-	d, ok := m.Deployments[cluster]
+	defs, err := ss.SousGraph.GetDefs()
+	if err != nil {
+		return EnsureErrorResult(errors.Wrapf(err, "getting defs"))
+	}
+
+	d, ok := defs.ClusterSpec(&m, cluster)
 	if !ok {
 		return cmdr.UsageErrorf("no deployment defined for cluster %q", cluster)
 	}
 	d.NumInstances = n
-	m.Deployments[cluster] = d
+	defs.SetClusterSpec(&m, cluster, d)
 
 	set, err := ss.SousGraph.GetManifestSet(ss.DeployFilterFlags, &up, &bytes.Buffer{})
 	if err != nil {
@@ -49,7 +49,12 @@ func (sd *SousSingReqID) Execute(args []string) cmdr.Result {
 		return cmdr.EnsureErrorResult(err)
 	}
 
-	d, ok := m.Deployments[sd.DFF.Cluster]
+	defs, err := sd.SousGraph.GetDefs()
+	if err != nil {
+		return EnsureErrorResult(err)
+	}
+
+	d, ok := defs.ClusterSpec(&m, sd.DFF.Cluster)
 	if !ok {
 		return cmdr.UsageErrorf("manifest %q does not have a deployment for %q",
 			m.ID(), sd.DFF.Cluster)
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// Identity sources for AuthorizationConfig.Identity.
+const (
+	// IdentityNone leaves writes to the server unrestricted.
+	IdentityNone = ""
+	// IdentityTokenFile identifies callers by bearer tokens listed in
+	// AuthorizationConfig.TokenFile.
+	IdentityTokenFile = "token-file"
+	// IdentityTokenStore identifies callers by API tokens issued with `sous
+	// plumbing token issue`, whose hashes are kept in the database.
+	IdentityTokenStore = "token-store"
+	// IdentityClientCert identifies callers by their TLS client certificate.
+	IdentityClientCert = "client-cert"
+	// IdentityTrustedHeader identifies callers by headers set by an
+	// authenticating proxy.
+	IdentityTrustedHeader = "trusted-header"
+)
+
+// AuthorizationConfig configures who may write to a Sous server. Writes to a
+// manifest are allowed to its Owners, and to members of AdminGroup, who alone
+// may make writes affecting a whole cluster, such as to the Defs.
+type AuthorizationConfig struct {
+	// Identity is how the server identifies callers: one of "token-store",
+	// "token-file", "client-cert" or "trusted-header". If it is empty, any caller may write.
+	Identity string `env:"SOUS_AUTH_IDENTITY"`
+	// AdminGroup is the name, email or group of the administrators.
+	AdminGroup string `env:"SOUS_AUTH_ADMIN_GROUP"`
+	// TokenFile is a JSON file mapping bearer tokens to the Name, Email and
+	// Groups of their holders, for the "token-file" identity.
+	TokenFile string `env:"SOUS_AUTH_TOKEN_FILE"`
+	// UserHeader, EmailHeader and GroupsHeader name the headers used by the
+	// "trusted-header" identity. GroupsHeader is a comma separated list.
+	UserHeader   string `env:"SOUS_AUTH_USER_HEADER"`
+	EmailHeader  string `env:"SOUS_AUTH_EMAIL_HEADER"`
+	GroupsHeader string `env:"SOUS_AUTH_GROUPS_HEADER"`
+	// TLSCertFile and TLSKeyFile are the server's certificate and key, and
+	// ClientCAFile the CA certificates that client certificates are verified
+	// against, for the "client-cert" identity.
+	TLSCertFile  string `env:"SOUS_AUTH_TLS_CERT_FILE"`
+	TLSKeyFile   string `env:"SOUS_AUTH_TLS_KEY_FILE"`
+	ClientCAFile string `env:"SOUS_AUTH_CLIENT_CA_FILE"`
+}
+
+// Validate returns an error if ac is incomplete.
+func (ac AuthorizationConfig) Validate() error {
+	switch ac.Identity {
+	default:
+		return errors.Errorf("unknown identity %q", ac.Identity)
+	case IdentityNone:
+		return nil
+	case IdentityTokenFile:
+		if ac.TokenFile == "" {
+			return errors.New("TokenFile is required for the token-file identity")
+		}
+	case IdentityTokenStore:
+	case IdentityClientCert:
+		if ac.TLSCertFile == "" || ac.TLSKeyFile == "" || ac.ClientCAFile == "" {
+			return errors.New("TLSCertFile, TLSKeyFile and ClientCAFile are required for the client-cert identity")
+		}
+	case IdentityTrustedHeader:
+		if ac.UserHeader == "" {
+			return errors.New("UserHeader is required for the trusted-header identity")
+		}
+	}
+	if ac.AdminGroup == "" {
+		return errors.New("AdminGroup is required when Identity is set")
+	}
+	return nil
+}
+
+// IdentitySource returns the restful.IdentitySource configured by ac, or nil
+// if writes are unrestricted. The "token-store" identity looks tokens up in
+// tokens.
+func (ac AuthorizationConfig) IdentitySource(tokens sous.TokenStore) (restful.IdentitySource, error) {
+	switch ac.Identity {
+	default:
+		return nil, errors.Errorf("unknown identity %q", ac.Identity)
+	case IdentityNone:
+		return nil, nil
+	case IdentityTokenStore:
+		if tokens == nil {
+			return nil, errors.New("no token store for the token-store identity")
+		}
+		return sous.TokenIdentity{Store: tokens}, nil
+	case IdentityTokenFile:
+		return restful.NewTokenFileIdentity(ac.TokenFile)
+	case IdentityClientCert:
+		return restful.ClientCertIdentity{}, nil
+	case IdentityTrustedHeader:
+		return restful.TrustedHeaderIdentity{
+			UserHeader:   ac.UserHeader,
+			EmailHeader:  ac.EmailHeader,
+			GroupsHeader: ac.GroupsHeader,
+		}, nil
+	}
+}
+
+// TLSConfig returns the server TLS configuration needed to verify client
+// certificates, or nil if ac does not use them. Clients without certificates
+// may still connect, so that they can read from the server.
+func (ac AuthorizationConfig) TLSConfig() (*tls.Config, error) {
+	if ac.Identity != IdentityClientCert {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(ac.ClientCAFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading client CA file")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.Errorf("no certificates found in %s", ac.ClientCAFile)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
@@ -4,5 +4,8 @@ type (
 	// PolicyFlags capture user intent about the processing of a build
 	PolicyFlags struct {
 		ForceClone, Strict, Dev bool
+		// Platforms is a comma separated list of the platforms to build for.
+		// It overrides the configured Docker.Platforms.
+		Platforms string
 	}
 )
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// BuildpacksConfig configures how the build strategy is chosen.
+type BuildpacksConfig struct {
+	// PluginDir is a directory of buildpack plugins. Each executable in it is
+	// tried as a build strategy, named by its file name. See
+	// doc/buildpack-plugins.md for the protocol plugins speak.
+	PluginDir string `env:"SOUS_BUILDPACK_PLUGIN_DIR"`
+	// Priorities overrides the priority of build strategies, by name. The
+	// built in strategies are "runmount", "split" and "dockerfile". Strategies
+	// are tried highest priority first, and those with a negative priority
+	// are never tried.
+	Priorities map[string]int
+}
+
+// Validate returns an error if a priority is given for an unnamed strategy.
+func (bc BuildpacksConfig) Validate() error {
+	if _, ok := bc.Priorities[""]; ok {
+		return errors.New("Priorities must name a build strategy")
+	}
+	return nil
+}
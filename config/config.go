@@ -8,6 +8,7 @@ import (
 	"path"
 
 	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/kubernetes"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/firsterr"
@@ -44,10 +45,15 @@ type (
 		BuildStateDir string `env:"SOUS_BUILD_STATE_DIR"`
 		// Docker is the Docker configuration.
 		Docker docker.Config
+		// Kubernetes is the configuration for clusters of kind "kubernetes".
+		Kubernetes kubernetes.Config
 		// Logging is the logging configuration.
 		Logging logging.Config
 		// User identifies the user of this client.
 		User sous.User
+		// APIToken authenticates this client to Sous servers which identify
+		// callers by token. Tokens are issued with `sous plumbing token issue`.
+		APIToken string `env:"SOUS_API_TOKEN"`
 		// MaxHTTPConcurrencySingularity is the maximum number of concurrent
 		// requests that can be made to a single Singularity instance.
 		MaxHTTPConcurrencySingularity int `env:"MAX_HTTP_CONCURRENCY_SINGULARITY"`
@@ -59,6 +65,28 @@ type (
 		SlackChannel string `env:"SOUS_SLACK_CHANNEL"`
 		// AdditionalSlackChannels that should receive messages
 		AdditionalSlackChannels map[string]string `env:"SOUS_ADDITIONAL_SLACK_CHANNELS"`
+		// Authorization controls who may write to this server.
+		Authorization AuthorizationConfig
+		// Secrets configures the store of secrets referred to by deployments.
+		Secrets SecretsConfig
+		// SourceHosts names the GitLab and Bitbucket servers source code is
+		// kept on. GitHub is always known.
+		SourceHosts SourceHostsConfig
+		// BuildOnServer allows this server to build source code, cloned from
+		// its source host, on request. It needs Docker, and the credentials to
+		// clone from each source host.
+		BuildOnServer bool `env:"SOUS_BUILD_ON_SERVER"`
+		// Buildpacks configures the build strategies, including plugins,
+		// and the order they are tried in.
+		Buildpacks BuildpacksConfig
+		// Signing configures the key the images built by this client or server
+		// are signed with.
+		Signing SigningConfig
+		// Scanning configures the scanning of images for vulnerabilities.
+		Scanning ScanningConfig
+		// Webhooks configures the webhooks this server receives from
+		// Singularity.
+		Webhooks WebhookConfig
 	}
 )
 
@@ -88,6 +116,24 @@ func (c Config) Validate() error {
 	if err := c.Logging.Validate(); err != nil {
 		return errors.Wrapf(err, "Config.Logging")
 	}
+	if err := c.Authorization.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Authorization")
+	}
+	if err := c.Secrets.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Secrets")
+	}
+	if err := c.SourceHosts.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.SourceHosts")
+	}
+	if err := c.Buildpacks.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Buildpacks")
+	}
+	if err := c.Scanning.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Scanning")
+	}
+	if err := c.Webhooks.Validate(); err != nil {
+		return errors.Wrapf(err, "Config.Webhooks")
+	}
 	return nil
 }
 
@@ -95,6 +141,7 @@ func (c Config) Validate() error {
 func DefaultConfig() Config {
 	return Config{
 		Docker:                        docker.DefaultConfig(),
+		Kubernetes:                    kubernetes.DefaultConfig(),
 		MaxHTTPConcurrencySingularity: 10,
 		PollIntervalForClient:         600,
 	}
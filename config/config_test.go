@@ -6,10 +6,15 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/opentable/sous/ext/bitbucket"
 	"github.com/opentable/sous/ext/docker"
+	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/gitlab"
+	sous "github.com/opentable/sous/lib"
 )
 
 func TestDefaultStateLocation(t *testing.T) {
@@ -75,6 +80,78 @@ func TestConfig_Validate(t *testing.T) {
 
 	cfg.Server = ""
 	checkValid()
+
+	cfg.Authorization.Identity = "magic"
+	checkNotValid()
+
+	cfg.Authorization.Identity = IdentityTrustedHeader
+	cfg.Authorization.UserHeader = "X-Forwarded-User"
+	checkNotValid()
+
+	cfg.Authorization.AdminGroup = "sous-admins"
+	checkValid()
+
+	cfg.Authorization.Identity = IdentityTokenStore
+	checkValid()
+
+	cfg.Secrets.Store = SecretStoreFile
+	checkNotValid()
+
+	cfg.Secrets.Dir = "/etc/sous/secrets"
+	checkValid()
+
+	cfg.SourceHosts.GitLab = map[string]string{"gitlab.example.com": ""}
+	checkValid()
+
+	cfg.SourceHosts.Bitbucket = map[string]string{"https://bitbucket.example.com": ""}
+	checkNotValid()
+
+	cfg.SourceHosts.Bitbucket = map[string]string{"gitlab.example.com": ""}
+	checkNotValid()
+
+	cfg.SourceHosts.Bitbucket = map[string]string{"bitbucket.example.com": "ssh://git@bitbucket.example.com:7999/"}
+	checkValid()
+
+	cfg.SourceHosts.GitLab["github.com"] = ""
+	checkNotValid()
+
+	delete(cfg.SourceHosts.GitLab, "github.com")
+	checkValid()
+
+	cfg.Scanning.Scanner = ScannerFile
+	checkNotValid()
+
+	cfg.Scanning.File = "/etc/sous/vulnerabilities.json"
+	checkValid()
+
+	cfg.Scanning.Scanner = "clair"
+	checkNotValid()
+
+	cfg.Scanning.Scanner = ScannerNone
+	cfg.Webhooks.URL = "sous.example.com"
+	checkNotValid()
+
+	cfg.Webhooks.URL = "https://sous.example.com"
+	checkNotValid()
+
+	cfg.Webhooks.Secret = "s3cret"
+	checkValid()
+}
+
+func TestSourceHostsConfig_SourceHosts(t *testing.T) {
+	sc := SourceHostsConfig{
+		GitLab:    map[string]string{"gitlab.b.com": "", "gitlab.a.com": "git@gitlab.a.com:"},
+		Bitbucket: map[string]string{"bitbucket.a.com": ""},
+	}
+	expected := []sous.SourceHost{
+		gitlab.NewSourceHost("gitlab.a.com", "git@gitlab.a.com:"),
+		gitlab.NewSourceHost("gitlab.b.com", ""),
+		bitbucket.NewSourceHost("bitbucket.a.com", ""),
+		github.SourceHost{},
+	}
+	if actual := sc.SourceHosts(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got source hosts:\n%#v\nwant:\n%#v", actual, expected)
+	}
 }
 
 func TestConfig_Equals(t *testing.T) {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// Vulnerability scanners for ScanningConfig.Scanner.
+const (
+	// ScannerNone scans nothing.
+	ScannerNone = ""
+	// ScannerFile reads vulnerabilities from the JSON file ScanningConfig.File.
+	ScannerFile = "file"
+)
+
+// ScanningConfig configures how the images Sous builds, and those rescanned
+// with `sous artifact scan`, are scanned for vulnerabilities.
+type ScanningConfig struct {
+	// Scanner is the kind of scanner: "file", or empty if images are not
+	// scanned.
+	Scanner string `env:"SOUS_VULNERABILITY_SCANNER"`
+	// File is the file of the "file" scanner. It holds a JSON object mapping
+	// image digests to lists of vulnerabilities, each with an ID and Severity.
+	File string `env:"SOUS_VULNERABILITY_FILE"`
+}
+
+// Validate returns an error if sc is incomplete.
+func (sc ScanningConfig) Validate() error {
+	switch sc.Scanner {
+	default:
+		return errors.Errorf("unknown vulnerability scanner %q", sc.Scanner)
+	case ScannerNone:
+	case ScannerFile:
+		if sc.File == "" {
+			return errors.New("File is required for the file scanner")
+		}
+	}
+	return nil
+}
+
+// VulnerabilityScanner returns the sous.VulnerabilityScanner configured by sc,
+// or nil if there is none.
+func (sc ScanningConfig) VulnerabilityScanner() (sous.VulnerabilityScanner, error) {
+	switch sc.Scanner {
+	default:
+		return nil, errors.Errorf("unknown vulnerability scanner %q", sc.Scanner)
+	case ScannerNone:
+		return nil, nil
+	case ScannerFile:
+		return sous.NewFileVulnerabilityScanner(sc.File), nil
+	}
+}
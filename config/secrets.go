@@ -0,0 +1,52 @@
+package config
+
+import (
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// Secret stores for SecretsConfig.Store.
+const (
+	// SecretStoreNone allows no secret references in deployments.
+	SecretStoreNone = ""
+	// SecretStoreFile reads secrets from JSON files in SecretsConfig.Dir.
+	SecretStoreFile = "file"
+)
+
+// SecretsConfig configures where the server finds the secrets that
+// deployments refer to with secret://<path>#<key> Env values.
+type SecretsConfig struct {
+	// Store is the kind of secret store: "file", or empty if deployments may
+	// not refer to secrets.
+	Store string `env:"SOUS_SECRETS_STORE"`
+	// Dir is the directory of the "file" store. Each file in it holds a JSON
+	// object of the secrets at the path of that file.
+	Dir string `env:"SOUS_SECRETS_DIR"`
+}
+
+// Validate returns an error if sc is incomplete.
+func (sc SecretsConfig) Validate() error {
+	switch sc.Store {
+	default:
+		return errors.Errorf("unknown secret store %q", sc.Store)
+	case SecretStoreNone:
+	case SecretStoreFile:
+		if sc.Dir == "" {
+			return errors.New("Dir is required for the file secret store")
+		}
+	}
+	return nil
+}
+
+// SecretStore returns the sous.SecretStore configured by sc, or nil if there
+// is none.
+func (sc SecretsConfig) SecretStore() (sous.SecretStore, error) {
+	switch sc.Store {
+	default:
+		return nil, errors.Errorf("unknown secret store %q", sc.Store)
+	case SecretStoreNone:
+		return nil, nil
+	case SecretStoreFile:
+		return sous.NewFileSecretStore(sc.Dir), nil
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import sous "github.com/opentable/sous/lib"
+
+// SigningConfig configures the signing of the images Sous builds.
+type SigningConfig struct {
+	// Key is the path to a PEM encoded ECDSA private key. When it is set, the
+	// digest of each image pushed by a build is signed with it.
+	Key string `env:"SOUS_SIGNING_KEY"`
+}
+
+// Signer returns the sous.Signer configured by sc, or nil if builds are not
+// signed.
+func (sc SigningConfig) Signer() (sous.Signer, error) {
+	if sc.Key == "" {
+		return nil, nil
+	}
+	return sous.LoadKeySigner(sc.Key)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/opentable/sous/ext/bitbucket"
+	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/gitlab"
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+// SourceHostsConfig names the self-hosted servers that source code is kept
+// on. Each maps the host names of servers of one kind to the URL prefix their
+// repositories are cloned from; an empty prefix uses HTTPS.
+type SourceHostsConfig struct {
+	// GitLab lists GitLab servers. A project is cloned from its path appended
+	// to the prefix, so for SSH the prefix is e.g. "git@gitlab.example.com:".
+	GitLab map[string]string `env:"SOUS_GITLAB_HOSTS"`
+	// Bitbucket lists Bitbucket servers. A repository is cloned from
+	// "<project>/<repo>.git" appended to the prefix, so for SSH the prefix is
+	// e.g. "ssh://git@bitbucket.example.com:7999/".
+	Bitbucket map[string]string `env:"SOUS_BITBUCKET_HOSTS"`
+}
+
+// Validate returns an error if a host name is malformed, or is configured
+// more than once.
+func (sc SourceHostsConfig) Validate() error {
+	seen := map[string]string{strings.TrimSuffix(github.Prefix, "/"): "GitHub"}
+	for _, kind := range []struct {
+		name  string
+		hosts map[string]string
+	}{{"GitLab", sc.GitLab}, {"Bitbucket", sc.Bitbucket}} {
+		for _, host := range sortedKeys(kind.hosts) {
+			if host == "" || strings.ContainsAny(host, "/,:") {
+				return errors.Errorf("%s host %q must be a host name", kind.name, host)
+			}
+			if other, ok := seen[host]; ok {
+				return errors.Errorf("%s host %q is already a %s host", kind.name, host, other)
+			}
+			seen[host] = kind.name
+		}
+	}
+	return nil
+}
+
+// SourceHosts returns the configured source hosts, followed by GitHub. They
+// are in order of host name, so that the choice between them is stable.
+func (sc SourceHostsConfig) SourceHosts() []sous.SourceHost {
+	hosts := []sous.SourceHost{}
+	for _, h := range sortedKeys(sc.GitLab) {
+		hosts = append(hosts, gitlab.NewSourceHost(h, sc.GitLab[h]))
+	}
+	for _, h := range sortedKeys(sc.Bitbucket) {
+		hosts = append(hosts, bitbucket.NewSourceHost(h, sc.Bitbucket[h]))
+	}
+	return append(hosts, github.SourceHost{})
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,29 @@
+package config
+
+import "github.com/pkg/errors"
+
+// WebhookConfig configures the webhooks a Sous server registers with each
+// Singularity cluster, so that it learns of changes to deploys as they
+// happen.
+type WebhookConfig struct {
+	// URL is the URL Singularity reaches this server at. If it is empty,
+	// no webhooks are registered.
+	URL string `env:"SOUS_WEBHOOK_URL"`
+	// Secret is included in the webhook URLs, so that webhooks from
+	// anywhere but Singularity are rejected.
+	Secret string `env:"SOUS_WEBHOOK_SECRET"`
+}
+
+// Validate returns an error if wc is invalid.
+func (wc WebhookConfig) Validate() error {
+	if wc.URL == "" {
+		return nil
+	}
+	if err := checkURL(wc.URL); err != nil {
+		return err
+	}
+	if wc.Secret == "" {
+		return errors.New("Secret is required with URL")
+	}
+	return nil
+}
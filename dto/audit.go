@@ -0,0 +1,9 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// AuditResponse is returned by the server for /audit, and lists writes to the
+// GDM, oldest first.
+type AuditResponse struct {
+	Entries []sous.AuditEntry
+}
@@ -0,0 +1,10 @@
+package dto
+
+import sous "github.com/opentable/sous/lib"
+
+// DeploymentHistoryResponse is returned by the server for
+// /deployment-history, and lists changes to a single deployment, most recent
+// first.
+type DeploymentHistoryResponse struct {
+	Entries []sous.DeploymentHistoryEntry
+}
@@ -8,4 +8,6 @@ type R11nResponse struct {
 	// Pointer here is just to allow nil which is a clearer indication of
 	// "nothing to see here" than a JSON-marshalled zero value would be.
 	Resolution *sous.DiffResolution
+	// Stages lists the progress of each stage of a staged rollout.
+	Stages []sous.RolloutStage `json:",omitempty"`
 }
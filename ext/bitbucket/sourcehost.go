@@ -0,0 +1,70 @@
+// Package bitbucket provides a sous.SourceHost for Bitbucket servers.
+package bitbucket
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opentable/sous/ext/git"
+	"github.com/opentable/sous/lib"
+)
+
+// SourceHost is a Bitbucket source code host.
+// It satisfies sous.SourceHost.
+type SourceHost struct {
+	// Host is the host name of the Bitbucket server, e.g.
+	// "bitbucket.example.com".
+	Host string
+	// CloneURL is prepended to "<project>/<repo>.git" to get the URL a
+	// repository is cloned from, e.g. "ssh://git@bitbucket.example.com:7999/".
+	// It defaults to "https://<Host>/scm/".
+	CloneURL string
+	// ScratchDir is where source code is cloned to. It defaults to the system
+	// temporary directory.
+	ScratchDir string
+}
+
+// NewSourceHost returns a SourceHost for the Bitbucket server at host, cloning
+// repositories from cloneURL.
+func NewSourceHost(host, cloneURL string) SourceHost {
+	if cloneURL == "" {
+		cloneURL = "https://" + host + "/" + SCMPrefix
+	}
+	return SourceHost{Host: host, CloneURL: cloneURL}
+}
+
+// CanParseSourceLocation returns true if s begins with Host and a slash.
+func (h SourceHost) CanParseSourceLocation(s string) bool {
+	return strings.HasPrefix(s, h.prefix())
+}
+
+// ParseSourceLocation parses a Bitbucket source location.
+func (h SourceHost) ParseSourceLocation(s string) (sous.SourceLocation, error) {
+	return ParseSourceLocation(h.Host, s)
+}
+
+// Owns returns true if sl.Repo begins with Host and a slash.
+func (h SourceHost) Owns(sl sous.SourceLocation) bool {
+	return strings.HasPrefix(sl.Repo, h.prefix())
+}
+
+// GetSource clones the repository id is in to a scratch directory and checks
+// out the tag for its version.
+// It returns an error if Owns(id.Location) returns false, or if cloning or
+// checking out fails.
+func (h SourceHost) GetSource(id sous.SourceID) (sous.Source, error) {
+	if !h.Owns(id.Location) {
+		return sous.Source{}, fmt.Errorf("the bitbucket source host %s cannot get source for %q",
+			h.Host, id.Location)
+	}
+	return git.CloneSource(h.cloneURL(id.Location), id, h.ScratchDir)
+}
+
+func (h SourceHost) prefix() string {
+	return h.Host + "/"
+}
+
+func (h SourceHost) cloneURL(sl sous.SourceLocation) string {
+	repo := strings.TrimPrefix(strings.TrimPrefix(sl.Repo, h.prefix()), SCMPrefix)
+	return h.CloneURL + repo + ".git"
+}
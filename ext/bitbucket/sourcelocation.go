@@ -0,0 +1,87 @@
+package bitbucket
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+)
+
+const (
+	// SCMPrefix begins the path of HTTP clone URLs on Bitbucket Server, as in
+	// https://bitbucket.example.com/scm/proj/repo.git. Source locations taken
+	// from such a remote keep it.
+	SCMPrefix = "scm/"
+
+	// Alnum contains all the lower and upper case ASCII letters and digits.
+	Alnum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// ProjectAllowedChars are the characters allowed in a project key.
+	ProjectAllowedChars = Alnum + "_"
+	// UserAllowedChars are the characters allowed in the user slug which,
+	// prefixed with '~', is the key of a personal project.
+	UserAllowedChars = Alnum + "_-."
+	// RepoAllowedChars are the characters allowed in a repository slug.
+	RepoAllowedChars = Alnum + "_-."
+	// OffsetAllowedChars are characters allowed in the offset directory path.
+	OffsetAllowedChars = Alnum + "_-./"
+)
+
+// ParseSourceLocation parses s for a sous.SourceLocation on the Bitbucket
+// server at host. Repositories are always <project>/<repo>, so any further path
+// is the offset directory, as is anything after a comma.
+// It returns an error if the string is malformed, or if it does not begin with
+// host.
+func ParseSourceLocation(host, s string) (sous.SourceLocation, error) {
+	prefix := host + "/"
+	if !strings.HasPrefix(s, prefix) {
+		return sous.SourceLocation{}, fmt.Errorf("%q does not begin with %q", s, prefix)
+	}
+	rest, dir := strings.TrimPrefix(s, prefix), ""
+	if i := strings.Index(rest, ","); i != -1 {
+		rest, dir = rest[:i], rest[i+1:]
+	}
+	scm := ""
+	if strings.HasPrefix(rest, SCMPrefix) {
+		scm, rest = SCMPrefix, strings.TrimPrefix(rest, SCMPrefix)
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) < 2 || strings.TrimPrefix(parts[0], "~") == "" || parts[1] == "" {
+		return sous.SourceLocation{}, fmt.Errorf("%q does not identify a repository", s)
+	}
+	project, repo := parts[0], strings.TrimSuffix(parts[1], ".git")
+	if strings.HasPrefix(project, "~") {
+		if err := validateChars("user slug", project[1:], UserAllowedChars); err != nil {
+			return sous.SourceLocation{}, err
+		}
+	} else if err := validateChars("project key", project, ProjectAllowedChars); err != nil {
+		return sous.SourceLocation{}, err
+	}
+	if err := validateChars("repository slug", repo, RepoAllowedChars); err != nil {
+		return sous.SourceLocation{}, err
+	}
+	if len(parts) > 2 {
+		if dir != "" {
+			return sous.SourceLocation{}, fmt.Errorf("%q has two offset directories", s)
+		}
+		dir = path.Join(parts[2:]...)
+	}
+	dir = strings.Trim(dir, "/")
+	if err := validateChars("offset directory", dir, OffsetAllowedChars); err != nil {
+		return sous.SourceLocation{}, err
+	}
+	return sous.SourceLocation{
+		Repo: prefix + scm + project + "/" + repo,
+		Dir:  dir,
+	}, nil
+}
+
+func validateChars(what, s, allowed string) error {
+	for _, c := range s {
+		if !strings.ContainsRune(allowed, c) {
+			return fmt.Errorf("%s %q contains illegal character '%c'", what, s, c)
+		}
+	}
+	return nil
+}
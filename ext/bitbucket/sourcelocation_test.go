@@ -0,0 +1,131 @@
+package bitbucket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+)
+
+const testHost = "bitbucket.example.com"
+
+var sourceLocationTests = []struct {
+	String         string
+	SourceLocation sous.SourceLocation
+	Error          string
+}{
+	// wrong prefix
+	{
+		String: "github.com/some-user/some-repo",
+		Error:  `"github.com/some-user/some-repo" does not begin with "bitbucket.example.com/"`,
+	},
+
+	// no repository
+	{
+		String: "bitbucket.example.com/PROJ",
+		Error:  `"bitbucket.example.com/PROJ" does not identify a repository`,
+	},
+	{
+		String: "bitbucket.example.com/scm/PROJ/",
+		Error:  `"bitbucket.example.com/scm/PROJ/" does not identify a repository`,
+	},
+	{
+		String: "bitbucket.example.com/~/some-repo",
+		Error:  `"bitbucket.example.com/~/some-repo" does not identify a repository`,
+	},
+
+	// invalid characters
+	{
+		String: "bitbucket.example.com/PR-OJ/some-repo",
+		Error:  `project key "PR-OJ" contains illegal character '-'`,
+	},
+	{
+		String: "bitbucket.example.com/PROJ/some:repo",
+		Error:  `repository slug "some:repo" contains illegal character ':'`,
+	},
+	{
+		String: "bitbucket.example.com/PROJ/some-repo/offset~dir",
+		Error:  `offset directory "offset~dir" contains illegal character '~'`,
+	},
+	{
+		String: "bitbucket.example.com/PROJ/some-repo/offset,dir",
+		Error:  `"bitbucket.example.com/PROJ/some-repo/offset,dir" has two offset directories`,
+	},
+
+	// valid
+	{
+		String: "bitbucket.example.com/PROJ/some-repo",
+		SourceLocation: sous.SourceLocation{
+			Repo: "bitbucket.example.com/PROJ/some-repo",
+		},
+	},
+	{
+		String: "bitbucket.example.com/~some.user/some_repo.git",
+		SourceLocation: sous.SourceLocation{
+			Repo: "bitbucket.example.com/~some.user/some_repo",
+		},
+	},
+	{
+		String: "bitbucket.example.com/scm/proj/some-repo,some/offset",
+		SourceLocation: sous.SourceLocation{
+			Repo: "bitbucket.example.com/scm/proj/some-repo",
+			Dir:  "some/offset",
+		},
+	},
+	{
+		String: "bitbucket.example.com/PROJ/some-repo/some/offset",
+		SourceLocation: sous.SourceLocation{
+			Repo: "bitbucket.example.com/PROJ/some-repo",
+			Dir:  "some/offset",
+		},
+	},
+}
+
+func TestParseSourceLocation(t *testing.T) {
+	for _, test := range sourceLocationTests {
+		if err := checkParse(test.String, test.SourceLocation, test.Error); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func checkParse(input string, expected sous.SourceLocation, expectedErr string) error {
+	actual, actualErr := ParseSourceLocation(testHost, input)
+	if actualErr != nil && expectedErr == "" {
+		return actualErr
+	}
+	if actualErr == nil && expectedErr != "" {
+		return fmt.Errorf("%q got nil; want error:\n%#q", input, expectedErr)
+	}
+	if actualErr != nil && expectedErr != "" {
+		actual, expected := actualErr.Error(), expectedErr
+		if actual != expected {
+			return fmt.Errorf("%q got error:\n%#q;\nwant:\n%#q", input, actual, expected)
+		}
+	}
+	if actual != expected {
+		return fmt.Errorf("%q got %#v; want %#v", input, actual, expected)
+	}
+	return nil
+}
+
+func TestSourceHost_cloneURL(t *testing.T) {
+	h := NewSourceHost(testHost, "")
+	for repo, expected := range map[string]string{
+		"bitbucket.example.com/PROJ/some-repo":     "https://bitbucket.example.com/scm/PROJ/some-repo.git",
+		"bitbucket.example.com/scm/PROJ/some-repo": "https://bitbucket.example.com/scm/PROJ/some-repo.git",
+	} {
+		sl := sous.SourceLocation{Repo: repo}
+		if !h.Owns(sl) {
+			t.Errorf("does not own %q", repo)
+		}
+		if actual := h.cloneURL(sl); actual != expected {
+			t.Errorf("%q got clone URL %q; want %q", repo, actual, expected)
+		}
+	}
+	h = NewSourceHost(testHost, "ssh://git@bitbucket.example.com:7999/")
+	sl := sous.SourceLocation{Repo: "bitbucket.example.com/scm/PROJ/some-repo"}
+	if actual, expected := h.cloneURL(sl), "ssh://git@bitbucket.example.com:7999/PROJ/some-repo.git"; actual != expected {
+		t.Errorf("got clone URL %q; want %q", actual, expected)
+	}
+}
@@ -23,7 +23,11 @@ type (
 		DockerRegistryHost        string
 		SourceShell, ScratchShell shell.Shell
 		Pack                      sous.Buildpack
-		log                       logging.LogSink
+		// Signer, if set, signs the digest of each image pushed.
+		Signer sous.Signer
+		// Scanner, if set, scans each image pushed for vulnerabilities.
+		Scanner sous.VulnerabilityScanner
+		log     logging.LogSink
 	}
 	// BuildTarget represents a single target within a Build.
 	BuildTarget interface {
@@ -68,8 +72,11 @@ func (b *Builder) ApplyMetadata(br *sous.BuildResult) error {
 	return nil
 }
 
-// Register registers the build artifact to the the registry
+// Register registers the build artifact to the the registry. Products built
+// for a platform are gathered into a manifest list for each SourceID and
+// kind, which is registered in their place and added to br.
 func (b *Builder) Register(br *sous.BuildResult) error {
+	platformProds := []*sous.BuildProduct{}
 	for _, prod := range br.Products {
 		if prod.Advisories.Contains(sous.IsBuilder) {
 			messages.ReportLogFieldsMessage("not pushing builder image", logging.DebugLevel, b.log, prod)
@@ -80,11 +87,38 @@ func (b *Builder) Register(br *sous.BuildResult) error {
 			return err
 		}
 
+		if prod.Platform != "" {
+			platformProds = append(platformProds, prod)
+			continue
+		}
+
+		if err := b.sign(prod); err != nil {
+			return err
+		}
+		if err := b.scan(prod); err != nil {
+			return err
+		}
 		err = b.recordName(prod)
 		if err != nil {
 			return err
 		}
 	}
+	for _, prods := range groupPlatformProducts(platformProds) {
+		list, err := b.pushManifestList(prods)
+		if err != nil {
+			return err
+		}
+		if err := b.sign(list); err != nil {
+			return err
+		}
+		if err := b.scan(list); err != nil {
+			return err
+		}
+		if err := b.recordName(list); err != nil {
+			return err
+		}
+		br.Products = append(br.Products, list)
+	}
 	return nil
 }
 
@@ -97,16 +131,39 @@ func (b *Builder) info(msg string) {
 }
 
 func (b *Builder) applyMetadata(bp *sous.BuildProduct) error {
-	bp.VersionName = b.VersionTag(bp.Source, bp.Kind)
-	bp.RevisionName = b.RevisionTag(bp.Source, bp.RevisionName, bp.Kind, time.Now())
+	b.resolveBuilderImages(bp)
+	bp.VersionName = b.VersionTag(bp.Source, bp.Kind) + platformSuffix(bp.Platform)
+	bp.RevisionName = b.RevisionTag(bp.Source, bp.RevisionName, bp.Kind, time.Now()) + platformSuffix(bp.Platform)
 
-	c := b.SourceShell.Cmd("docker", "build", "-t", bp.VersionName, "-t", bp.RevisionName, "-")
+	args := []interface{}{"build"}
+	if bp.Platform != "" {
+		args = append(args, "--platform", bp.Platform)
+	}
+	args = append(args, "-t", bp.VersionName, "-t", bp.RevisionName, "-")
+	c := b.SourceShell.Cmd("docker", args...)
 	bf := b.metadataDockerfile(bp)
 	c.SetStdin(bf)
 
 	return c.Succeed()
 }
 
+// resolveBuilderImages names the builder images in bp's provenance by their
+// digests, where the Docker daemon knows them.
+func (b *Builder) resolveBuilderImages(bp *sous.BuildProduct) {
+	if bp.Provenance == nil {
+		return
+	}
+	for i, name := range bp.Provenance.BuilderImages {
+		if strings.Contains(name, "@") {
+			continue
+		}
+		output, err := b.SourceShell.Stdout("docker", "inspect", "--format={{index .RepoDigests 0}}", name)
+		if digested := strings.TrimSpace(output); err == nil && digested != "" {
+			bp.Provenance.BuilderImages[i] = digested
+		}
+	}
+}
+
 func (b *Builder) metadataDockerfile(bp *sous.BuildProduct) io.Reader {
 	bf := bytes.Buffer{}
 	sv := bp.Source
@@ -115,13 +172,18 @@ func (b *Builder) metadataDockerfile(bp *sous.BuildProduct) io.Reader {
 		panic(err)
 	}
 
+	labels := Labels(sv, bp.RevID)
+	if bp.Platform != "" {
+		labels[DockerPlatformLabel] = bp.Platform
+	}
+
 	md.Execute(&bf, struct {
 		ImageID    string
 		Labels     map[string]string
 		Advisories []string
 	}{
 		bp.ID,
-		Labels(sv, bp.RevID),
+		labels,
 		bp.Advisories.Strings(),
 	})
 	return &bf
@@ -150,6 +212,38 @@ func (b *Builder) pushToRegistry(bp *sous.BuildProduct) error {
 	return nil
 }
 
+// sign signs the digest of the pushed image bp, and the source it was built
+// from, if b has a Signer.
+func (b *Builder) sign(bp *sous.BuildProduct) error {
+	if b.Signer == nil {
+		return nil
+	}
+	digest, err := bp.BuildArtifact().ArtifactDigest()
+	if err != nil {
+		return err
+	}
+	sig, err := b.Signer.Sign(bp.Source, digest)
+	if err != nil {
+		return err
+	}
+	bp.Signatures = append(bp.Signatures, sig)
+	return nil
+}
+
+// scan scans the pushed image bp for vulnerabilities, if b has a Scanner.
+func (b *Builder) scan(bp *sous.BuildProduct) error {
+	if b.Scanner == nil {
+		return nil
+	}
+	report, err := b.Scanner.ScanArtifact(bp.BuildArtifact())
+	if err != nil {
+		return err
+	}
+	messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("%s: %s", bp.DigestName, report), logging.InformationLevel, b.log)
+	bp.Vulnerabilities = report
+	return nil
+}
+
 // recordName inserts metadata about the newly built image into our local name cache
 func (b *Builder) recordName(bp *sous.BuildProduct) error {
 	sv := bp.Source
@@ -82,3 +82,139 @@ func TestBuilderApplyMetadata(t *testing.T) {
 
 	assert.Len(t, srcCtl.CmdsLike("docker", "push"), 4)
 }
+
+func TestBuilderRegister_platforms(t *testing.T) {
+	srcSh, srcCtl := shell.NewTestShell()
+	_, inspect := srcCtl.CmdFor("docker", "inspect")
+	inspect.ResultSuccess("'docker.example.com/sous/docker@sha256:0123'\n", "")
+	_, push := srcCtl.CmdFor("docker", "manifest", "push")
+	push.ResultSuccess("Pushed ref docker.example.com/sous/docker@sha256:0123\nsha256:abcd\n", "")
+
+	scratchSh, _ := shell.NewTestShell()
+
+	nc, ncCtl := sous.NewInserterSpy()
+
+	b, err := NewBuilder(nc, "docker.example.com", srcSh, scratchSh, logging.SilentLogSet())
+	require.NoError(t, err)
+
+	sid := sous.MustNewSourceID("github.com/opentable/sous", "docker", "1.2.3")
+	br := &sous.BuildResult{
+		Products: []*sous.BuildProduct{
+			{Source: sid, Platform: "linux/amd64"},
+			{Source: sid, Platform: "linux/arm64"},
+		},
+	}
+
+	require.NoError(t, b.ApplyMetadata(br))
+	assert.Equal(t, "docker.example.com/sous/docker:1.2.3-linux-arm64", br.Products[1].VersionName)
+	assert.Len(t, srcCtl.CmdsLike("docker", "build", "--platform", "linux/arm64"), 1)
+
+	require.NoError(t, b.Register(br))
+	require.Len(t, br.Products, 3)
+	list := br.Products[2]
+	assert.Equal(t, "docker.example.com/sous/docker:1.2.3", list.VersionName)
+	assert.Equal(t, "docker.example.com/sous/docker@sha256:abcd", list.DigestName)
+	assert.Equal(t, map[string]string{
+		"linux/amd64": "docker.example.com/sous/docker@sha256:0123",
+		"linux/arm64": "docker.example.com/sous/docker@sha256:0123",
+	}, list.Platforms)
+
+	assert.Len(t, srcCtl.CmdsLike("docker", "push"), 4)
+	assert.Len(t, srcCtl.CmdsLike("docker", "manifest", "create", "--amend", "docker.example.com/sous/docker:1.2.3"), 1)
+	assert.Len(t, srcCtl.CmdsLike("docker", "manifest", "push"), 2)
+
+	inserts := ncCtl.CallsTo("Insert")
+	require.Len(t, inserts, 1)
+	ba := inserts[0].PassedArgs().Get(1).(sous.BuildArtifact)
+	assert.Equal(t, list.DigestName, ba.DigestReference)
+	assert.Equal(t, list.Platforms, ba.Platforms)
+}
+
+func TestMetadataDockerfile_platform(t *testing.T) {
+	bp := sous.BuildProduct{
+		ID:       "identifier",
+		Source:   sous.MakeSourceID("github.com/opentable/test", "", "2.3.7"),
+		Platform: "linux/arm64",
+	}
+	mddf, err := ioutil.ReadAll((&Builder{}).metadataDockerfile(&bp))
+	require.NoError(t, err)
+	assert.Contains(t, string(mddf), `com.opentable.sous.platform="linux/arm64"`)
+}
+
+func TestBuilderResolveBuilderImages(t *testing.T) {
+	srcSh, srcCtl := shell.NewTestShell()
+	_, inspect := srcCtl.CmdFor("docker", "inspect", "--format={{index .RepoDigests 0}}", "base:1")
+	inspect.ResultSuccess("base@sha256:cabba9e\n", "")
+	b := &Builder{SourceShell: srcSh, log: logging.SilentLogSet()}
+
+	bp := &sous.BuildProduct{Provenance: &sous.Provenance{
+		BuilderImages: []string{"base:1", "other@sha256:0123"},
+	}}
+	b.resolveBuilderImages(bp)
+	assert.Equal(t, []string{"base@sha256:cabba9e", "other@sha256:0123"}, bp.Provenance.BuilderImages)
+	assert.Len(t, srcCtl.CmdsLike("docker", "inspect"), 1)
+
+	b.resolveBuilderImages(&sous.BuildProduct{})
+}
+
+type digestSigner struct{}
+
+func (digestSigner) Sign(sid sous.SourceID, digest string) (sous.Signature, error) {
+	return sous.Signature{KeyID: "test", Value: "signed " + sid.String() + "@" + digest}, nil
+}
+
+func TestBuilderRegister_signs(t *testing.T) {
+	srcSh, srcCtl := shell.NewTestShell()
+	_, inspect := srcCtl.CmdFor("docker", "inspect")
+	inspect.ResultSuccess("'docker.example.com/sous/docker@sha256:0123'\n", "")
+
+	scratchSh, _ := shell.NewTestShell()
+
+	nc, ncCtl := sous.NewInserterSpy()
+
+	b, err := NewBuilder(nc, "docker.example.com", srcSh, scratchSh, logging.SilentLogSet())
+	require.NoError(t, err)
+	b.Signer = digestSigner{}
+
+	sid := sous.MustNewSourceID("github.com/opentable/sous", "docker", "1.2.3")
+	br := &sous.BuildResult{Products: []*sous.BuildProduct{{Source: sid}}}
+
+	require.NoError(t, b.ApplyMetadata(br))
+	require.NoError(t, b.Register(br))
+
+	inserts := ncCtl.CallsTo("Insert")
+	require.Len(t, inserts, 1)
+	ba := inserts[0].PassedArgs().Get(1).(sous.BuildArtifact)
+	assert.Equal(t, []sous.Signature{{KeyID: "test", Value: "signed github.com/opentable/sous,1.2.3,docker@sha256:0123"}}, ba.Signatures)
+}
+
+type cleanScanner struct{}
+
+func (cleanScanner) ScanArtifact(ba sous.BuildArtifact) (*sous.VulnerabilityReport, error) {
+	return &sous.VulnerabilityReport{Scanner: "clean"}, nil
+}
+
+func TestBuilderRegister_scans(t *testing.T) {
+	srcSh, srcCtl := shell.NewTestShell()
+	_, inspect := srcCtl.CmdFor("docker", "inspect")
+	inspect.ResultSuccess("'docker.example.com/sous/docker@sha256:0123'\n", "")
+
+	scratchSh, _ := shell.NewTestShell()
+
+	nc, ncCtl := sous.NewInserterSpy()
+
+	b, err := NewBuilder(nc, "docker.example.com", srcSh, scratchSh, logging.SilentLogSet())
+	require.NoError(t, err)
+	b.Scanner = cleanScanner{}
+
+	sid := sous.MustNewSourceID("github.com/opentable/sous", "docker", "1.2.3")
+	br := &sous.BuildResult{Products: []*sous.BuildProduct{{Source: sid}}}
+
+	require.NoError(t, b.ApplyMetadata(br))
+	require.NoError(t, b.Register(br))
+
+	inserts := ncCtl.CallsTo("Insert")
+	require.Len(t, inserts, 1)
+	ba := inserts[0].PassedArgs().Get(1).(sous.BuildArtifact)
+	assert.Equal(t, &sous.VulnerabilityReport{Scanner: "clean", Vulnerabilities: []sous.Vulnerability{}}, ba.Qualities.VulnerabilityReport())
+}
@@ -1,7 +1,14 @@
 package docker
 
+import "strings"
+
 type Config struct {
 	RegistryHost string `env:"SOUS_DOCKER_REGISTRY_HOST"`
+	// Platforms is a comma separated list of the platforms to build images
+	// for by default, e.g. "linux/amd64,linux/arm64". Images built for more
+	// than one platform are pushed as a manifest list. If it is empty,
+	// images are built for the platform of the Docker daemon.
+	Platforms string `env:"SOUS_DOCKER_PLATFORMS"`
 }
 
 // DefaultConfig builds a default configuration, which can be then overridden by
@@ -11,3 +18,15 @@ func DefaultConfig() Config {
 		RegistryHost: "docker.otenv.com",
 	}
 }
+
+// SplitPlatforms splits a comma separated list of platforms, such as
+// Config.Platforms.
+func SplitPlatforms(list string) []string {
+	platforms := []string{}
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			platforms = append(platforms, p)
+		}
+	}
+	return platforms
+}
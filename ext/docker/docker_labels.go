@@ -5,4 +5,7 @@ const (
 	DockerPathLabel     = "com.opentable.sous.repo_offset"
 	DockerVersionLabel  = "com.opentable.sous.version"
 	DockerRevisionLabel = "com.opentable.sous.revision"
+	// DockerPlatformLabel marks an image built for one platform of a
+	// manifest list.
+	DockerPlatformLabel = "com.opentable.sous.platform"
 )
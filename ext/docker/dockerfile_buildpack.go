@@ -60,6 +60,11 @@ func NewDockerfileBuildpack(ls logging.LogSink) *DockerfileBuildpack {
 // omnis mutatum
 // var successfulBuildRE = regexp.MustCompile(`Successfully built (\w+)`)
 
+// StrategyName implements sous.StrategyNamer on DockerfileBuildpack.
+func (d *DockerfileBuildpack) StrategyName() string {
+	return DockerfileStrategy
+}
+
 // Detect detects if c has a Dockerfile or not.
 func (d *DockerfileBuildpack) Detect(c *sous.BuildContext) (*sous.DetectResult, error) {
 	dfPath := filepath.Join(c.Source.OffsetDir, "Dockerfile")
@@ -96,6 +101,9 @@ func (d *DockerfileBuildpack) Build(c *sous.BuildContext) (*sous.BuildResult, er
 	if c.ShouldPullDuringBuild() {
 		cmd = append(cmd, "--pull")
 	}
+	if c.Platform != "" {
+		cmd = append(cmd, "--platform", c.Platform)
+	}
 
 	r := dr.Data.(detectData)
 	if r.HasAppVersionArg {
@@ -148,7 +148,23 @@ func (nc *NameCache) GetArtifact(sid sous.SourceID) (*sous.BuildArtifact, error)
 	if err != nil {
 		return nil, err
 	}
-	return NewBuildArtifact(name, qls), nil
+	ba := NewBuildArtifact(name, qls)
+	if ba.Platforms, err = nc.dbQueryPlatformsForCName(name); err != nil {
+		return nil, err
+	}
+	if ba.Signatures, err = nc.dbQuerySignaturesForCName(name); err != nil {
+		return nil, err
+	}
+	return ba, nil
+}
+
+// GetProvenance implements sous.ProvenanceRegistry on NameCache.
+func (nc *NameCache) GetProvenance(sid sous.SourceID) (*sous.Provenance, *sous.SBOM, error) {
+	name, _, err := nc.getImageName(sid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nc.dbQueryProvenanceForCName(name)
 }
 
 func meansBodyUnchanged(err error) bool {
@@ -199,6 +215,13 @@ func (nc *NameCache) GetSourceID(a *sous.BuildArtifact) (sous.SourceID, error) {
 		return sid, err
 	}
 
+	if _, is := md.Labels[DockerPlatformLabel]; is && md.Platforms == nil {
+		// This is the image for one platform of a manifest list, which is
+		// recorded as the image for newSID instead.
+		logging.DebugMsg(nc.log, "Image is for a single platform: not recording it", in, newSID)
+		return newSID, nil
+	}
+
 	qualities := qualitiesFromLabels(md.Labels)
 
 	fullCanon := nc.DockerRegistryHost + "/" + md.CanonicalName
@@ -211,7 +234,16 @@ func (nc *NameCache) GetSourceID(a *sous.BuildArtifact) (sous.SourceID, error) {
 		}
 	}
 
-	if err := nc.dbInsert(newSID, fullCanon, md.Etag, md.AllNames, qualities); err != nil {
+	var platforms map[string]string
+	if md.Platforms != nil {
+		host := strings.TrimSuffix(fullCanon, md.CanonicalName)
+		platforms = map[string]string{}
+		for p, cn := range md.Platforms {
+			platforms[p] = host + cn
+		}
+	}
+
+	if err := nc.dbInsert(newSID, fullCanon, md.Etag, md.AllNames, qualities, platforms); err != nil {
 		logging.InfoMsg(nc.log, "Err recording", fullCanon, err)
 		return sid, err
 	}
@@ -296,7 +328,17 @@ func (nc *NameCache) Insert(sid sous.SourceID, ba sous.BuildArtifact) error {
 	in := ba.DigestReference
 	vn := ba.VersionName
 	qs := ba.Qualities
-	err := nc.dbInsert(sid, in, "", []string{vn}, qs)
+	names := []string{}
+	if vn != "" { // e.g. artifacts looked up to be rescanned
+		names = append(names, vn)
+	}
+	err := nc.dbInsert(sid, in, "", names, qs, ba.Platforms)
+	if err == nil && (ba.Provenance != nil || ba.SBOM != nil) {
+		err = nc.dbInsertProvenance(in, ba.Provenance, ba.SBOM)
+	}
+	if err == nil && len(ba.Signatures) > 0 {
+		err = nc.dbInsertSignatures(in, ba.Signatures)
+	}
 	reportTableMetrics(nc.log, nc.DB)
 	return err
 }
@@ -385,6 +427,9 @@ func (nc *NameCache) dumpTx(io io.Writer, tx *sql.Tx) {
 	nc.dumpRows(io, tx, "select * from docker_search_metadata")
 	nc.dumpRows(io, tx, "select * from docker_search_name")
 	nc.dumpRows(io, tx, "select * from docker_image_qualities")
+	nc.dumpRows(io, tx, "select * from docker_image_platforms")
+	nc.dumpRows(io, tx, "select * from docker_image_provenance")
+	nc.dumpRows(io, tx, "select * from docker_image_signatures")
 }
 
 func (nc *NameCache) dump(io io.Writer) {
@@ -416,6 +461,9 @@ func (tm tableMetrics) MetricsTo(sink logging.MetricsSink) {
 	tm.rowCount("docker_search_metadata", sink)
 	tm.rowCount("docker_search_name", sink)
 	tm.rowCount("docker_image_qualities", sink)
+	tm.rowCount("docker_image_platforms", sink)
+	tm.rowCount("docker_image_provenance", sink)
+	tm.rowCount("docker_image_signatures", sink)
 }
 
 func reportTableMetrics(ls logging.LogSink, db *sql.DB) {
@@ -14,6 +14,7 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/docker_registry"
 	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sbom"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -501,3 +502,127 @@ func TestMissingName(t *testing.T) {
 	assert.Equal("", name)
 	assert.Error(err)
 }
+
+func TestRecordPlatforms(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), sous.SetupDB(t))
+	defer sous.ReleaseDB(t)
+	require.NoError(err)
+	sv := sous.MustNewSourceID("github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	cn := host + "/ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+	platforms := map[string]string{
+		"linux/amd64": host + "/ot/wackadoo@sha256:112345678901234567890123456789AB012345678901234567890123456789AB",
+		"linux/arm64": host + "/ot/wackadoo@sha256:212345678901234567890123456789AB012345678901234567890123456789AB",
+	}
+
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn, Platforms: platforms}))
+
+	arty, err := nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Equal(cn, arty.DigestReference)
+	assert.Equal(platforms, arty.Platforms)
+
+	// An image for a single platform is not recorded in place of its list.
+	labels := Labels(sv, "abcdef")
+	labels[DockerPlatformLabel] = "linux/arm64"
+	dc.FeedMetadata(docker_registry.Metadata{
+		Registry:      host,
+		Labels:        labels,
+		CanonicalName: "ot/wackadoo@sha256:212345678901234567890123456789AB012345678901234567890123456789AB",
+	})
+	gsv, err := nc.GetSourceID(NewBuildArtifact(host+"/ot/wackadoo:1.2.3-linux-arm64", nil))
+	require.NoError(err)
+	assert.Equal(sv.Location, gsv.Location)
+
+	arty, err = nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Equal(cn, arty.DigestReference)
+}
+
+func TestRecordProvenance(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), sous.SetupDB(t))
+	defer sous.ReleaseDB(t)
+	require.NoError(err)
+	sv := sous.MustNewSourceID("github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	cn := host + "/ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn}))
+	prov, bom, err := nc.GetProvenance(sv)
+	require.NoError(err)
+	assert.Nil(prov)
+	assert.Nil(bom)
+
+	ba := sous.BuildArtifact{
+		DigestReference: cn,
+		Provenance:      &sous.Provenance{Revision: "abcdef", Strategy: SplitStrategy, BuilderImages: []string{"base@sha256:cabba9e"}},
+		SBOM:            &sous.SBOM{Components: []sbom.Component{{Type: "go", Name: "github.com/pkg/errors", Version: "v0.8.0"}}},
+	}
+	require.NoError(nc.Insert(sv, ba))
+	require.NoError(nc.Insert(sv, ba), "recording provenance again")
+
+	prov, bom, err = nc.GetProvenance(sv)
+	require.NoError(err)
+	assert.Equal(ba.Provenance, prov)
+	assert.Equal(ba.SBOM, bom)
+
+	arty, err := nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Nil(arty.Provenance, "provenance is only looked up when asked for")
+}
+
+func TestRecordSignatures(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), sous.SetupDB(t))
+	defer sous.ReleaseDB(t)
+	require.NoError(err)
+	sv := sous.MustNewSourceID("github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	cn := host + "/ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn}))
+	arty, err := nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Empty(arty.Signatures)
+
+	sigs := []sous.Signature{{KeyID: "0123456789abcdef", Value: "c2lnbmVk"}}
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn, Signatures: sigs}))
+	sigs[0].Value = "cmVzaWduZWQ="
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn, Signatures: sigs}), "signing again")
+
+	arty, err = nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Equal(sigs, arty.Signatures)
+}
+
+func TestRescanReplacesVulnerabilities(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dc := docker_registry.NewDummyClient()
+	host := "docker.repo.io"
+	nc, err := NewNameCache(host, dc, logging.SilentLogSet(), sous.SetupDB(t))
+	defer sous.ReleaseDB(t)
+	require.NoError(err)
+	sv := sous.MustNewSourceID("github.com/opentable/wackadoo", "nested/there", "1.2.3")
+	cn := host + "/ot/wackadoo@sha256:012345678901234567890123456789AB012345678901234567890123456789AB"
+
+	first := sous.VulnerabilityReport{Scanner: "file", Vulnerabilities: []sous.Vulnerability{{ID: "CVE-2018-0001", Severity: "critical"}}}
+	quals := append(sous.Qualities{{Name: "ephemeral_tag", Kind: "advisory"}}, first.Qualities()...)
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn, Qualities: quals}))
+
+	rescan := sous.VulnerabilityReport{Scanner: "file", Vulnerabilities: []sous.Vulnerability{{ID: "CVE-2018-0002", Severity: "low"}}}
+	require.NoError(nc.Insert(sv, sous.BuildArtifact{DigestReference: cn, Qualities: rescan.Qualities()}))
+
+	arty, err := nc.GetArtifact(sv)
+	require.NoError(err)
+	assert.Equal(&rescan, arty.Qualities.VulnerabilityReport())
+	assert.Equal(sous.Qualities{{Name: "ephemeral_tag", Kind: "advisory"}}, arty.Qualities.WithoutVulnerabilities())
+}
@@ -3,7 +3,9 @@ package docker
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/docker/distribution/reference"
@@ -61,7 +63,9 @@ func canonicalizeDockerReference(in string) (reference.Canonical, error) {
 
 }
 
-func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, extraNames []string, quals []sous.Quality) error {
+// dbInsert records in as the image for sid. If in names a manifest list,
+// platforms maps each of its platforms to the digest name of its image.
+func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, extraNames []string, quals []sous.Quality, platforms map[string]string) error {
 	ref, err := canonicalizeDockerReference(in)
 	if err != nil {
 		return err
@@ -109,6 +113,14 @@ func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, extraNames []s
 		return errors.Wrapf(err, "canonicalname:%q version:%q etag:%q repo:%q dir:%q", in, sid.Version, etag, sid.Location.Repo, sid.Location.Dir)
 	}
 
+	// A new vulnerability report replaces the last one.
+	if sous.Qualities(quals).VulnerabilityReport() != nil {
+		if _, err := tx.Exec("delete from docker_image_qualities where kind = $1 and metadata_id = "+
+			"(select metadata_id from docker_search_metadata where canonicalname = $2)", sous.VulnerabilityKind, in); err != nil {
+			return err
+		}
+	}
+
 	if err := ins.Exec("docker_image_qualities", sqlgen.DoNothing, func(fs sqlgen.FieldSet) {
 		for _, q := range quals {
 			if q.Kind == "advisory" && q.Name == "" {
@@ -124,6 +136,23 @@ func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, extraNames []s
 		return err
 	}
 
+	if _, err := tx.Exec("delete from docker_image_platforms where metadata_id = "+
+		"(select metadata_id from docker_search_metadata where canonicalname = $1)", in); err != nil {
+		return err
+	}
+
+	if err := ins.Exec("docker_image_platforms", sqlgen.DoNothing, func(fs sqlgen.FieldSet) {
+		for _, p := range sortedPlatforms(platforms) {
+			fs.Row(func(r sqlgen.RowDef) {
+				mdID(r, in)
+				r.KV("platform", p)
+				r.KV("digestname", platforms[p])
+			})
+		}
+	}); err != nil {
+		return err
+	}
+
 	if err := addSearchNames(ins, in, append(extraNames, in)); err != nil {
 		return err
 	}
@@ -131,6 +160,88 @@ func (nc *NameCache) dbInsert(sid sous.SourceID, in, etag string, extraNames []s
 	return tx.Commit()
 }
 
+func sortedPlatforms(platforms map[string]string) []string {
+	ps := make([]string, 0, len(platforms))
+	for p := range platforms {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+// dbInsertProvenance records prov and bom for the image in, which must
+// already have been inserted.
+func (nc *NameCache) dbInsertProvenance(in string, prov *sous.Provenance, bom *sous.SBOM) error {
+	ref, err := canonicalizeDockerReference(in)
+	if err != nil {
+		return err
+	}
+	in = ref.String()
+
+	pj, err := json.Marshal(prov)
+	if err != nil {
+		return err
+	}
+	bj, err := json.Marshal(bom)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	tx, err := nc.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback() // we commit before returning...
+
+	ins := sqlgen.NewInserter(ctx, nc.log, tx)
+
+	if err := ins.Exec("docker_image_provenance", sqlgen.Upsert, sqlgen.SingleRow(func(r sqlgen.RowDef) {
+		r.CF(`(select metadata_id from docker_search_metadata where canonicalname = ?)`, "metadata_id", in)
+		r.KV("provenance", string(pj))
+		r.KV("sbom", string(bj))
+	})); err != nil {
+		return errors.Wrapf(err, "recording provenance of %q", in)
+	}
+
+	return tx.Commit()
+}
+
+// dbInsertSignatures records sigs for the image in, which must already have
+// been inserted. A signature replaces any earlier one by the same key.
+func (nc *NameCache) dbInsertSignatures(in string, sigs []sous.Signature) error {
+	ref, err := canonicalizeDockerReference(in)
+	if err != nil {
+		return err
+	}
+	in = ref.String()
+
+	ctx := context.TODO()
+	tx, err := nc.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: false})
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback() // we commit before returning...
+
+	ins := sqlgen.NewInserter(ctx, nc.log, tx)
+
+	if err := ins.Exec("docker_image_signatures", sqlgen.Upsert, func(fs sqlgen.FieldSet) {
+		for _, sig := range sigs {
+			fs.Row(func(r sqlgen.RowDef) {
+				r.CF(`(select metadata_id from docker_search_metadata where canonicalname = ?)`, "metadata_id", in)
+				r.CF("?", "keyid", sig.KeyID)
+				r.KV("signature", sig.Value)
+			})
+		}
+	}); err != nil {
+		return errors.Wrapf(err, "recording signatures of %q", in)
+	}
+
+	return tx.Commit()
+}
+
 func (nc *NameCache) dbAddNames(in string, names []string) error {
 	ref, err := canonicalizeDockerReference(in)
 	if err != nil {
@@ -314,6 +425,89 @@ func (nc *NameCache) dbQueryCNameforSourceID(sid sous.SourceID) (cn string, ins
 	return
 }
 
+func (nc *NameCache) dbQueryPlatformsForCName(cn string) (map[string]string, error) {
+	rows, err := nc.DB.Query("select"+
+		" docker_image_platforms.platform,"+
+		" docker_image_platforms.digestname"+
+		"   from"+
+		" docker_image_platforms natural join docker_search_metadata"+
+		" where"+
+		" docker_search_metadata.canonicalname = $1", cn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	platforms := map[string]string{}
+	for rows.Next() {
+		var p, dn string
+		if err := rows.Scan(&p, &dn); err != nil {
+			return nil, err
+		}
+		platforms[p] = dn
+	}
+	if len(platforms) == 0 {
+		return nil, rows.Err()
+	}
+	return platforms, rows.Err()
+}
+
+// dbQueryProvenanceForCName returns the provenance and SBOM recorded for cn,
+// which are nil if there are none.
+func (nc *NameCache) dbQueryProvenanceForCName(cn string) (*sous.Provenance, *sous.SBOM, error) {
+	row := nc.DB.QueryRow("select"+
+		" docker_image_provenance.provenance,"+
+		" docker_image_provenance.sbom"+
+		"   from"+
+		" docker_image_provenance natural join docker_search_metadata"+
+		" where"+
+		" docker_search_metadata.canonicalname = $1", cn)
+
+	var pj, bj string
+	err := row.Scan(&pj, &bj)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var prov *sous.Provenance
+	var bom *sous.SBOM
+	if err := json.Unmarshal([]byte(pj), &prov); err != nil {
+		return nil, nil, errors.Wrapf(err, "provenance of %q", cn)
+	}
+	if err := json.Unmarshal([]byte(bj), &bom); err != nil {
+		return nil, nil, errors.Wrapf(err, "SBOM of %q", cn)
+	}
+	return prov, bom, nil
+}
+
+func (nc *NameCache) dbQuerySignaturesForCName(cn string) ([]sous.Signature, error) {
+	rows, err := nc.DB.Query("select"+
+		" docker_image_signatures.keyid,"+
+		" docker_image_signatures.signature"+
+		"   from"+
+		" docker_image_signatures natural join docker_search_metadata"+
+		" where"+
+		" docker_search_metadata.canonicalname = $1"+
+		" order by docker_image_signatures.keyid", cn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sigs []sous.Signature
+	for rows.Next() {
+		var sig sous.Signature
+		if err := rows.Scan(&sig.KeyID, &sig.Value); err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, rows.Err()
+}
+
 func (nc *NameCache) dbQueryQualsForCName(cn string) (quals strpairs, err error) {
 	rows, err := nc.DB.Query("select"+
 		" docker_image_qualities.quality,"+
@@ -34,7 +34,7 @@ func TestDbInsertNoDump(t *testing.T) {
 	sid := sous.MustNewSourceID("https://github.com/opentable/wacky", "", tag)
 
 	stderr := grabStdErr(func() {
-		err = nc.dbInsert(sid, cn, "etag", nil, nil)
+		err = nc.dbInsert(sid, cn, "etag", nil, nil, nil)
 		assert.NoError(err, "insert should succeed")
 	})
 
@@ -0,0 +1,100 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+// platformSuffix returns the suffix of the tags of an image built for
+// platform, e.g. "-linux-arm64". The image's manifest list has the
+// unsuffixed tags.
+func platformSuffix(platform string) string {
+	if platform == "" {
+		return ""
+	}
+	return "-" + strings.Replace(platform, "/", "-", -1)
+}
+
+// groupPlatformProducts groups prods, which were each built for a platform,
+// by the manifest list they belong to, in the order they were built.
+func groupPlatformProducts(prods []*sous.BuildProduct) [][]*sous.BuildProduct {
+	groups := [][]*sous.BuildProduct{}
+	index := map[string]int{}
+	for _, p := range prods {
+		key := p.Source.String() + " " + p.Kind
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], p)
+	}
+	return groups
+}
+
+// pushManifestList pushes a manifest list of the pushed images in prods,
+// tagged with their version and revision names less their platform
+// suffixes, and returns the product it stands for.
+func (b *Builder) pushManifestList(prods []*sous.BuildProduct) (*sous.BuildProduct, error) {
+	first := prods[0]
+	suffix := platformSuffix(first.Platform)
+	list := &sous.BuildProduct{
+		Source:       first.Source,
+		Kind:         first.Kind,
+		RevID:        first.RevID,
+		Advisories:   first.Advisories,
+		VersionName:  strings.TrimSuffix(first.VersionName, suffix),
+		RevisionName: strings.TrimSuffix(first.RevisionName, suffix),
+		Platforms:    map[string]string{},
+		Provenance:   first.Provenance,
+		SBOM:         first.SBOM,
+	}
+	images := []interface{}{}
+	for _, p := range prods {
+		list.Platforms[p.Platform] = p.DigestName
+		images = append(images, p.DigestName)
+	}
+
+	var digest string
+	for _, name := range []string{list.VersionName, list.RevisionName} {
+		create := append([]interface{}{"manifest", "create", "--amend", name}, images...)
+		if err := b.SourceShell.Run("docker", create...); err != nil {
+			return nil, err
+		}
+		out, err := b.SourceShell.Stdout("docker", "manifest", "push", "--purge", name)
+		if err != nil {
+			return nil, err
+		}
+		if digest, err = pushedDigest(out); err != nil {
+			return nil, errors.Wrapf(err, "pushing manifest list %s", name)
+		}
+	}
+
+	ref, err := reference.ParseNamed(list.VersionName)
+	if err != nil {
+		return nil, err
+	}
+	list.DigestName = ref.Name() + "@" + digest
+
+	logging.DebugConsole(b.log, fmt.Sprintf("push manifest list versionName: %s, revisionName: %s, digest: %s", list.VersionName,
+		list.RevisionName, list.DigestName), list)
+
+	return list, nil
+}
+
+// pushedDigest returns the digest printed by `docker manifest push`, which
+// is its last line of output.
+func pushedDigest(out string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	digest := strings.TrimSpace(lines[len(lines)-1])
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", errors.Errorf("no digest in output %q", out)
+	}
+	return digest, nil
+}
@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+// PluginBuildpack is a buildpack implemented by an external executable, so
+// that build strategies can be added without changing Sous.
+//
+// The executable is run as "<path> detect" and "<path> build" in the source
+// directory being built. On stdin it receives a PluginRequest as JSON, and
+// it must write a sous.DetectResult or sous.BuildResult respectively as JSON
+// on stdout; anything it wants shown to the user goes to stderr. A non-zero
+// exit is an error. A build must leave a docker image for each product, named
+// by the product's ID.
+type PluginBuildpack struct {
+	// Name identifies the plugin when choosing strategies; it is the file name
+	// of the executable.
+	Name string
+	// Path is the path of the executable.
+	Path string
+
+	detected *sous.DetectResult
+	log      logging.LogSink
+}
+
+// PluginRequest is what a PluginBuildpack writes to its executable's stdin.
+type PluginRequest struct {
+	// Context is the context of the build.
+	Context *sous.BuildContext
+	// Detected is the result of the detect step. It is nil when detecting.
+	Detected *sous.DetectResult
+}
+
+// NewPluginBuildpack returns a PluginBuildpack which runs the executable at
+// path.
+func NewPluginBuildpack(path string, ls logging.LogSink) *PluginBuildpack {
+	return &PluginBuildpack{Name: filepath.Base(path), Path: path, log: ls}
+}
+
+// DiscoverPlugins returns a PluginBuildpack for each executable file in dir,
+// in order of name. Other files are ignored.
+func DiscoverPlugins(dir string, ls logging.LogSink) ([]*PluginBuildpack, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading buildpack plugin dir")
+	}
+	plugins := []*PluginBuildpack{}
+	for _, fi := range infos {
+		path := filepath.Join(dir, fi.Name())
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if fi, err = os.Stat(path); err != nil {
+				return nil, errors.Wrap(err, "reading buildpack plugin")
+			}
+		}
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		plugins = append(plugins, NewPluginBuildpack(path, ls))
+	}
+	return plugins, nil
+}
+
+// StrategyName implements sous.StrategyNamer on PluginBuildpack.
+func (p *PluginBuildpack) StrategyName() string {
+	return p.Name
+}
+
+// Detect implements Buildpack.Detect on PluginBuildpack.
+func (p *PluginBuildpack) Detect(c *sous.BuildContext) (*sous.DetectResult, error) {
+	dr := &sous.DetectResult{}
+	if err := p.run(c, "detect", PluginRequest{Context: c}, dr); err != nil {
+		return nil, err
+	}
+	messages.ReportLogFieldsMessage("Buildpack plugin detected", logging.DebugLevel, p.log, p.Name, dr.Compatible)
+	p.detected = dr
+	return dr, nil
+}
+
+// Build implements Buildpack.Build on PluginBuildpack.
+func (p *PluginBuildpack) Build(c *sous.BuildContext) (*sous.BuildResult, error) {
+	start := time.Now()
+	br := &sous.BuildResult{}
+	if err := p.run(c, "build", PluginRequest{Context: c, Detected: p.detected}, br); err != nil {
+		return nil, err
+	}
+	if len(br.Products) == 0 {
+		return nil, errors.Errorf("buildpack plugin %s built nothing", p.Name)
+	}
+	for _, prdt := range br.Products {
+		if prdt == nil || prdt.ID == "" {
+			return nil, errors.Errorf("buildpack plugin %s returned a product with no image ID", p.Name)
+		}
+	}
+	if br.Elapsed == 0 {
+		br.Elapsed = time.Since(start)
+	}
+	return br, nil
+}
+
+func (p *PluginBuildpack) run(c *sous.BuildContext, verb string, req PluginRequest, v interface{}) error {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return errors.Wrapf(err, "buildpack plugin %s %s", p.Name, verb)
+	}
+	sh := c.Sh.Clone()
+	sh.LongRunning(verb == "build")
+	if err := sh.CD(c.Source.AbsDir()); err != nil {
+		return err
+	}
+	cmd := sh.Cmd(p.Path, verb)
+	cmd.SetStdin(bytes.NewReader(in))
+	out, err := cmd.Stdout()
+	if err != nil {
+		return errors.Wrapf(err, "buildpack plugin %s %s", p.Name, verb)
+	}
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		return errors.Wrapf(err, "buildpack plugin %s %s: parsing output", p.Name, verb)
+	}
+	return nil
+}
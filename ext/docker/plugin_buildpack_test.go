@@ -0,0 +1,133 @@
+package docker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlugin is compatible with sources containing a WORKSPACE file. It
+// records each request it receives in the source directory.
+const testPlugin = `#!/bin/sh
+cat > "request-$1.json"
+case "$1" in
+detect)
+	if [ -f WORKSPACE ]; then
+		echo '{"Compatible": true, "Data": {"Target": "//app"}}'
+	else
+		echo '{"Compatible": false}'
+	fi
+	;;
+build)
+	echo "building" >&2
+	echo '{"Products": [{"Kind": "docker", "ID": "sha256:cabba9e"}]}'
+	;;
+*)
+	exit 1
+	;;
+esac
+`
+
+// setupPluginTest creates a plugin dir and a source dir side by side in a
+// temporary dir, which the caller removes.
+func setupPluginTest(t *testing.T) (pluginDir string, ctx *sous.BuildContext) {
+	root, err := ioutil.TempDir("", "sous-plugin-test-")
+	require.NoError(t, err)
+
+	pluginDir = filepath.Join(root, "plugins")
+	srcDir := filepath.Join(root, "src")
+	for _, dir := range []string{pluginDir, filepath.Join(srcDir, "svc")} {
+		require.NoError(t, os.MkdirAll(dir, 0777))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pluginDir, "bazel"), []byte(testPlugin), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pluginDir, "README"), []byte("not a plugin"), 0644))
+
+	sh, err := shell.DefaultInDir(srcDir)
+	require.NoError(t, err)
+	return pluginDir, &sous.BuildContext{
+		Sh: sh,
+		Source: sous.SourceContext{
+			RootDir:   srcDir,
+			OffsetDir: "svc",
+			Revision:  "abcdef",
+		},
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	dir, _ := setupPluginTest(t)
+	defer os.RemoveAll(filepath.Dir(dir))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "buildkit"), []byte(testPlugin), 0755))
+
+	plugins, err := DiscoverPlugins(dir, logging.SilentLogSet())
+	require.NoError(t, err)
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "bazel", plugins[0].Name)
+	assert.Equal(t, filepath.Join(dir, "bazel"), plugins[0].Path)
+	assert.Equal(t, "buildkit", plugins[1].Name)
+
+	_, err = DiscoverPlugins(filepath.Join(dir, "missing"), logging.SilentLogSet())
+	assert.Error(t, err)
+}
+
+func TestPluginBuildpack(t *testing.T) {
+	dir, ctx := setupPluginTest(t)
+	defer os.RemoveAll(filepath.Dir(dir))
+	src := ctx.Source.AbsDir()
+	bp := NewPluginBuildpack(filepath.Join(dir, "bazel"), logging.SilentLogSet())
+
+	dr, err := bp.Detect(ctx)
+	require.NoError(t, err)
+	assert.False(t, dr.Compatible)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "WORKSPACE"), nil, 0644))
+	dr, err = bp.Detect(ctx)
+	require.NoError(t, err)
+	assert.True(t, dr.Compatible)
+
+	br, err := bp.Build(ctx)
+	require.NoError(t, err)
+	require.Len(t, br.Products, 1)
+	assert.Equal(t, "sha256:cabba9e", br.Products[0].ID)
+	assert.Equal(t, "docker", br.Products[0].Kind)
+	assert.NotZero(t, br.Elapsed)
+
+	b, err := ioutil.ReadFile(filepath.Join(src, "request-build.json"))
+	require.NoError(t, err)
+	req := struct {
+		Context  sous.BuildContext
+		Detected sous.DetectResult
+	}{}
+	require.NoError(t, json.Unmarshal(b, &req))
+	assert.Equal(t, "abcdef", req.Context.Source.Revision)
+	assert.Equal(t, map[string]interface{}{"Target": "//app"}, req.Detected.Data)
+}
+
+func TestPluginBuildpack_Failure(t *testing.T) {
+	_, ctx := setupPluginTest(t)
+	dir := filepath.Dir(ctx.Source.RootDir)
+	defer os.RemoveAll(dir)
+
+	failing := filepath.Join(dir, "plugins", "failing")
+	require.NoError(t, ioutil.WriteFile(failing, []byte("#!/bin/sh\nexit 3\n"), 0755))
+	_, err := NewPluginBuildpack(failing, logging.SilentLogSet()).Detect(ctx)
+	assert.Error(t, err)
+
+	garbled := filepath.Join(dir, "plugins", "garbled")
+	require.NoError(t, ioutil.WriteFile(garbled, []byte("#!/bin/sh\necho not json\n"), 0755))
+	_, err = NewPluginBuildpack(garbled, logging.SilentLogSet()).Detect(ctx)
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "plugins", "empty")
+	require.NoError(t, ioutil.WriteFile(empty, []byte("#!/bin/sh\necho '{}'\n"), 0755))
+	_, err = NewPluginBuildpack(empty, logging.SilentLogSet()).Build(ctx)
+	assert.Error(t, err)
+}
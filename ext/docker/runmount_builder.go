@@ -277,6 +277,7 @@ func product(ctx sous.BuildContext, builder *runnableBuilder) *sous.BuildProduct
 		Advisories:   advisories,
 		VersionName:  versionNameLocal(ctx),
 		RevisionName: revisionNameLocal(ctx),
+		Provenance:   &sous.Provenance{BuilderImages: []string{builder.RunSpec.Image.From}},
 	}
 
 	return bp
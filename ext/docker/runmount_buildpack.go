@@ -45,6 +45,11 @@ func readDockerfile(dfPath string) (string, error) {
 	return string(b), nil
 }
 
+// StrategyName implements sous.StrategyNamer on RunmountBuildpack.
+func (rmbp *RunmountBuildpack) StrategyName() string {
+	return RunmountStrategy
+}
+
 // Detect implements Buildpack on SplitBuildpack
 func (rmbp *RunmountBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, error) {
 	dfPath := filepath.Join(ctx.Source.OffsetDir, "Dockerfile")
@@ -83,6 +88,9 @@ func (rmbp *RunmountBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResul
 
 // Build implements Buildpack on RunmountBuildpack
 func (rmbp *RunmountBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	if ctx.Platform != "" {
+		return nil, errors.New("runmount builds cannot target a platform")
+	}
 	fmt.Println("Runmount Build.. ")
 	start := time.Now()
 	buildResult := &sous.BuildResult{}
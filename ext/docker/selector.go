@@ -4,43 +4,120 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/docker_registry"
 	"github.com/opentable/sous/util/logging"
 )
 
-type selector struct {
-	regClient docker_registry.Client
-	log       logging.LogSink
+type (
+	selector struct {
+		strategies []strategy
+		log        logging.LogSink
+	}
+
+	// A strategy is one of the buildpacks a selector chooses between.
+	strategy struct {
+		name, description string
+		priority          int
+		buildpack         func() sous.Buildpack
+	}
+)
+
+// The names of the built in build strategies, by which their priorities are
+// configured.
+const (
+	RunmountStrategy   = "runmount"
+	SplitStrategy      = "split"
+	DockerfileStrategy = "dockerfile"
+)
+
+// DefaultStrategyPriorities are the priorities of the built in strategies
+// unless configured otherwise. Plugins default to PluginPriority, ahead of
+// them all.
+var DefaultStrategyPriorities = map[string]int{
+	RunmountStrategy:   300,
+	SplitStrategy:      200,
+	DockerfileStrategy: 100,
 }
 
+// PluginPriority is the default priority of a buildpack plugin.
+const PluginPriority = 1000
+
 // NewBuildStrategySelector constructs a sous.Selector that uses docker build images as its strategies
 func NewBuildStrategySelector(ls logging.LogSink, rc docker_registry.Client) sous.Selector {
-	return &selector{regClient: rc, log: ls}
+	return NewPluginSelector(ls, rc, nil, nil)
 }
 
-// SelectBuildpack tries to select a buildpack for this BuildContext.
-func (s *selector) SelectBuildpack(ctx *sous.BuildContext) (sous.Buildpack, error) {
-	rmbp := NewRunmountBuildpack(s.regClient, s.log)
-	dr, err := rmbp.Detect(ctx)
-	if err == nil && dr.Compatible {
-		reportStrategyChoice("runmount container", s.log)
-		return rmbp, nil
+// NewPluginSelector constructs a sous.Selector which chooses between the built
+// in strategies and plugins. Each strategy is tried in descending order of
+// its priority in priorities, by name, and the first compatible one is
+// chosen. Strategies with a negative priority are never tried.
+func NewPluginSelector(ls logging.LogSink, rc docker_registry.Client, plugins []*PluginBuildpack, priorities map[string]int) sous.Selector {
+	priority := func(name string, def int) int {
+		if p, ok := priorities[name]; ok {
+			return p
+		}
+		return def
+	}
+	strategies := []strategy{
+		{
+			name:        RunmountStrategy,
+			description: "runmount container",
+			priority:    priority(RunmountStrategy, DefaultStrategyPriorities[RunmountStrategy]),
+			buildpack:   func() sous.Buildpack { return NewRunmountBuildpack(rc, ls) },
+		},
+		{
+			name:        SplitStrategy,
+			description: "split container",
+			priority:    priority(SplitStrategy, DefaultStrategyPriorities[SplitStrategy]),
+			buildpack:   func() sous.Buildpack { return NewSplitBuildpack(rc, ls) },
+		},
+		{
+			name:        DockerfileStrategy,
+			description: "simple dockerfile",
+			priority:    priority(DockerfileStrategy, DefaultStrategyPriorities[DockerfileStrategy]),
+			buildpack:   func() sous.Buildpack { return NewDockerfileBuildpack(ls) },
+		},
+	}
+	for _, p := range plugins {
+		p := p
+		strategies = append(strategies, strategy{
+			name:        p.Name,
+			description: "buildpack plugin " + p.Name,
+			priority:    priority(p.Name, PluginPriority),
+			buildpack:   func() sous.Buildpack { return NewPluginBuildpack(p.Path, ls) },
+		})
 	}
+	return newSelector(ls, strategies)
+}
 
-	sbp := NewSplitBuildpack(s.regClient, s.log)
-	dr, err = sbp.Detect(ctx)
-	if err == nil && dr.Compatible {
-		reportStrategyChoice("split container", s.log)
-		return sbp, nil
+func newSelector(ls logging.LogSink, strategies []strategy) *selector {
+	enabled := []strategy{}
+	for _, s := range strategies {
+		if s.priority >= 0 {
+			enabled = append(enabled, s)
+		}
 	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		if enabled[i].priority != enabled[j].priority {
+			return enabled[i].priority > enabled[j].priority
+		}
+		return enabled[i].name < enabled[j].name
+	})
+	return &selector{strategies: enabled, log: ls}
+}
 
-	dfbp := NewDockerfileBuildpack(s.log)
-	dr, err = dfbp.Detect(ctx)
-	if err == nil && dr.Compatible {
-		reportStrategyChoice("simple dockerfile", s.log)
-		return dfbp, nil
+// SelectBuildpack tries to select a buildpack for this BuildContext.
+func (s *selector) SelectBuildpack(ctx *sous.BuildContext) (sous.Buildpack, error) {
+	for _, st := range s.strategies {
+		bp := st.buildpack()
+		dr, err := bp.Detect(ctx)
+		if err == nil && dr.Compatible {
+			reportStrategyChoice(st.description, s.log)
+			return bp, nil
+		}
 	}
 	return nil, errors.New("no Dockerfile present")
 }
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type detectingBuildpack struct {
+	name       string
+	compatible bool
+	detected   *[]string
+}
+
+func (bp detectingBuildpack) Detect(*sous.BuildContext) (*sous.DetectResult, error) {
+	*bp.detected = append(*bp.detected, bp.name)
+	return &sous.DetectResult{Compatible: bp.compatible}, nil
+}
+
+func (bp detectingBuildpack) Build(*sous.BuildContext) (*sous.BuildResult, error) {
+	return &sous.BuildResult{}, nil
+}
+
+func TestSelectorPriority(t *testing.T) {
+	detected := []string{}
+	strat := func(name string, priority int, compatible bool) strategy {
+		bp := detectingBuildpack{name: name, compatible: compatible, detected: &detected}
+		return strategy{name: name, priority: priority, buildpack: func() sous.Buildpack { return bp }}
+	}
+	s := newSelector(logging.SilentLogSet(), []strategy{
+		strat("dockerfile", 100, true),
+		strat("disabled", -1, true),
+		strat("split", 200, false),
+		strat("bazel", 1000, false),
+		strat("buildkit", 1000, false),
+	})
+
+	bp, err := s.SelectBuildpack(&sous.BuildContext{})
+	assert.NoError(t, err)
+	assert.Equal(t, "dockerfile", bp.(detectingBuildpack).name)
+	assert.Equal(t, []string{"bazel", "buildkit", "split", "dockerfile"}, detected)
+
+	s = newSelector(logging.SilentLogSet(), []strategy{strat("split", 200, false)})
+	_, err = s.SelectBuildpack(&sous.BuildContext{})
+	assert.Error(t, err)
+}
+
+func TestNewPluginSelector(t *testing.T) {
+	plugins := []*PluginBuildpack{{Name: "bazel"}, {Name: "buildkit"}}
+	s := NewPluginSelector(logging.SilentLogSet(), nil, plugins, map[string]int{
+		"buildkit": 50,
+		"runmount": -1,
+	}).(*selector)
+
+	names := []string{}
+	for _, st := range s.strategies {
+		names = append(names, st.name)
+	}
+	assert.Equal(t, []string{"bazel", "split", "dockerfile", "buildkit"}, names)
+}
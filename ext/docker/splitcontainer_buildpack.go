@@ -51,6 +51,11 @@ func parseDockerfile(path string) (*parser.Node, error) {
 	return parseDocker(f)
 }
 
+// StrategyName implements sous.StrategyNamer on SplitBuildpack.
+func (sbp *SplitBuildpack) StrategyName() string {
+	return SplitStrategy
+}
+
 // Detect implements Buildpack on SplitBuildpack
 func (sbp *SplitBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, error) {
 	dfPath := filepath.Join(ctx.Source.OffsetDir, "Dockerfile")
@@ -83,6 +88,9 @@ func (sbp *SplitBuildpack) Detect(ctx *sous.BuildContext) (*sous.DetectResult, e
 
 // Build implements Buildpack on SplitBuildpack
 func (sbp *SplitBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	if ctx.Platform != "" {
+		return nil, errors.New("split container builds cannot target a platform")
+	}
 	drez := sbp.detected
 	script := splitBuilder{context: ctx, detected: drez, subBuilders: []*runnableBuilder{}}
 
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/shell"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// CloneSource clones the repository at cloneURL into a new scratch directory
+// inside dir, checks out the tag for id.Version, and returns the checked out
+// sous.Source. If dir is empty, the system temporary directory is used. The
+// caller is responsible for removing the clone at Source.LocalRootDir.
+func CloneSource(cloneURL string, id sous.SourceID, dir string) (sous.Source, error) {
+	root, err := ioutil.TempDir(dir, "sous-source-")
+	if err != nil {
+		return sous.Source{}, errors.Wrap(err, "creating scratch dir")
+	}
+	src, err := cloneSource(cloneURL, id, root)
+	if err != nil {
+		os.RemoveAll(root)
+		return sous.Source{}, errors.Wrapf(err, "getting source for %s", id)
+	}
+	return src, nil
+}
+
+func cloneSource(cloneURL string, id sous.SourceID, root string) (sous.Source, error) {
+	sh, err := shell.DefaultInDir(root)
+	if err != nil {
+		return sous.Source{}, err
+	}
+	c, err := NewClient(sh)
+	if err != nil {
+		return sous.Source{}, err
+	}
+	if err := c.CloneRepo(cloneURL, "."); err != nil {
+		return sous.Source{}, err
+	}
+	tags, err := c.ListTags()
+	if err != nil {
+		return sous.Source{}, err
+	}
+	tag, ok := versionTag(tags, id.Version)
+	if !ok {
+		return sous.Source{}, errors.Errorf("no tag for version %s in %s", id.Version, cloneURL)
+	}
+	if _, err := c.stdout("checkout", "--quiet", tag.Name); err != nil {
+		return sous.Source{}, err
+	}
+
+	offset := filepath.Join(root, filepath.FromSlash(id.Location.Dir))
+	osh, err := shell.DefaultInDir(offset)
+	if err != nil {
+		return sous.Source{}, errors.Wrapf(err, "offset %q", id.Location.Dir)
+	}
+	oc := c.CloneClient()
+	oc.Sh = osh
+	repo, err := NewRepo(oc)
+	if err != nil {
+		return sous.Source{}, err
+	}
+	ctx, err := repo.SourceContext()
+	if err != nil {
+		return sous.Source{}, err
+	}
+	// The clone URL need not canonicalise to the location it was cloned
+	// for, e.g. when it is an SSH URL with a port, so the remote is recorded
+	// as the location itself.
+	ctx.PrimaryRemoteURL = id.Location.Repo
+	ctx.RemoteURLs = append(ctx.RemoteURLs, id.Location.Repo)
+	return sous.Source{
+		ID:             id,
+		Context:        *ctx,
+		LocalRootDir:   root,
+		LocalOffsetDir: offset,
+	}, nil
+}
+
+var tagVersionPrefix = regexp.MustCompile(`^\D*`)
+
+// versionTag returns the tag in tags which names version v, which may be
+// prefixed, as in "v1.2.3".
+func versionTag(tags []sous.Tag, v semv.Version) (sous.Tag, bool) {
+	for _, t := range tags {
+		tv, err := semv.Parse(strings.TrimPrefix(t.Name, tagVersionPrefix.FindString(t.Name)))
+		if err != nil {
+			continue
+		}
+		if tv.Equals(v) {
+			return t, true
+		}
+	}
+	return sous.Tag{}, false
+}
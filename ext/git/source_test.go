@@ -0,0 +1,73 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionTag(t *testing.T) {
+	tags := []sous.Tag{
+		{Name: "not-a-version", Revision: "a"},
+		{Name: "v1.2.3", Revision: "b"},
+		{Name: "1.2.4", Revision: "c"},
+	}
+	tag, ok := versionTag(tags, semv.MustParse("1.2.3"))
+	assert.True(t, ok)
+	assert.Equal(t, "b", tag.Revision)
+
+	tag, ok = versionTag(tags, semv.MustParse("1.2.4+abcdef"))
+	assert.True(t, ok)
+	assert.Equal(t, "c", tag.Revision)
+
+	_, ok = versionTag(tags, semv.MustParse("2.0.0"))
+	assert.False(t, ok)
+}
+
+func TestCloneSource(t *testing.T) {
+	origin, err := ioutil.TempDir("", "sous-clone-origin-")
+	require.NoError(t, err)
+	defer os.RemoveAll(origin)
+
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = origin
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(origin, "svc"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(origin, "svc", "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	run("init", "-q")
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	run("tag", "v0.1.0")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(origin, "svc", "Dockerfile"), []byte("FROM busybox\n"), 0644))
+	run("commit", "-q", "-a", "-m", "second")
+
+	id := sous.SourceID{
+		Location: sous.SourceLocation{Repo: "git.example.com/group/project", Dir: "svc"},
+		Version:  semv.MustParse("0.1.0"),
+	}
+	src, err := CloneSource("file://"+origin, id, "")
+	require.NoError(t, err)
+	defer os.RemoveAll(src.LocalRootDir)
+
+	assert.Equal(t, id, src.ID)
+	assert.Equal(t, filepath.Join(src.LocalRootDir, "svc"), src.LocalOffsetDir)
+	assert.Equal(t, "svc", src.Context.OffsetDir)
+	assert.Equal(t, id.Location, src.Context.SourceLocation())
+	b, err := ioutil.ReadFile(filepath.Join(src.LocalOffsetDir, "Dockerfile"))
+	require.NoError(t, err)
+	assert.Equal(t, "FROM scratch\n", string(b))
+
+	id.Version = semv.MustParse("0.2.0")
+	_, err = CloneSource("file://"+origin, id, "")
+	assert.Error(t, err)
+}
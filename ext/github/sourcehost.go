@@ -4,12 +4,24 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/opentable/sous/ext/git"
 	"github.com/opentable/sous/lib"
 )
 
+// DefaultCloneURL is the URL GitHub repositories are cloned from by default.
+const DefaultCloneURL = "https://github.com/"
+
 // SourceHost is the GitHub source code host.
 // It satisfies sous.SourceHost.
-type SourceHost struct{}
+type SourceHost struct {
+	// CloneURL is prepended to "<user>/<repo>.git" to get the URL a
+	// repository is cloned from, e.g. "git@github.com:". It defaults to
+	// DefaultCloneURL.
+	CloneURL string
+	// ScratchDir is where source code is cloned to. It defaults to the system
+	// temporary directory.
+	ScratchDir string
+}
 
 // CanParseSourceLocation returns true if s begins with Prefix.
 func (SourceHost) CanParseSourceLocation(s string) bool {
@@ -26,14 +38,22 @@ func (SourceHost) Owns(sl sous.SourceLocation) bool {
 	return strings.HasPrefix(sl.Repo, Prefix)
 }
 
-// GetSource returns a sous.Source for the provided id.
-// It returns an error if Owns(id.Location) returns false, or
-// if any internal operations, which may include network requests,
-// disk access, etc, fail.
+// GetSource clones the repository id is in to a scratch directory and checks
+// out the tag for its version.
+// It returns an error if Owns(id.Location) returns false, or if cloning or
+// checking out fails.
 func (h SourceHost) GetSource(id sous.SourceID) (sous.Source, error) {
 	if !h.Owns(id.Location) {
 		return sous.Source{}, fmt.Errorf("the github source host cannot get source for %q",
 			id.Location)
 	}
-	return sous.Source{}, fmt.Errorf("fetching from GitHub not yet supported")
+	return git.CloneSource(h.cloneURL(id.Location), id, h.ScratchDir)
+}
+
+func (h SourceHost) cloneURL(sl sous.SourceLocation) string {
+	cloneURL := h.CloneURL
+	if cloneURL == "" {
+		cloneURL = DefaultCloneURL
+	}
+	return cloneURL + strings.TrimPrefix(sl.Repo, Prefix) + ".git"
 }
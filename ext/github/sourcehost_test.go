@@ -0,0 +1,89 @@
+package github
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBuildpack records the Dockerfile it was asked to build.
+type recordingBuildpack struct {
+	dockerfile string
+	registered *sous.BuildResult
+}
+
+func (bp *recordingBuildpack) SelectBuildpack(*sous.BuildContext) (sous.Buildpack, error) {
+	return bp, nil
+}
+
+func (bp *recordingBuildpack) Detect(*sous.BuildContext) (*sous.DetectResult, error) {
+	return &sous.DetectResult{Compatible: true}, nil
+}
+
+func (bp *recordingBuildpack) Build(ctx *sous.BuildContext) (*sous.BuildResult, error) {
+	b, err := ioutil.ReadFile(filepath.Join(ctx.Sh.Dir(), ctx.Source.OffsetDir, "Dockerfile"))
+	if err != nil {
+		return nil, err
+	}
+	bp.dockerfile = string(b)
+	return &sous.BuildResult{Products: []*sous.BuildProduct{{Kind: "test"}}}, nil
+}
+
+func (bp *recordingBuildpack) ApplyMetadata(*sous.BuildResult) error { return nil }
+
+func (bp *recordingBuildpack) Register(br *sous.BuildResult) error {
+	bp.registered = br
+	return nil
+}
+
+func TestSourceHost_RemoteBuild(t *testing.T) {
+	root, err := ioutil.TempDir("", "sous-github-origin-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	origin := filepath.Join(root, "opentable", "example.git")
+
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = origin
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(origin, "svc"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(origin, "svc", "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	run("init", "-q")
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	run("tag", "v1.2.3")
+
+	bp := &recordingBuildpack{}
+	rb := &sous.RemoteBuilder{
+		SourceHosts: sous.SourceHostChooser{SourceHosts: []sous.SourceHost{
+			SourceHost{CloneURL: "file://" + root + "/"},
+		}},
+		Selector:  bp,
+		Labeller:  bp,
+		Registrar: bp,
+		LogSink:   logging.SilentLogSet(),
+	}
+
+	id := sous.MustParseSourceID("github.com/opentable/example,1.2.3,svc")
+	result, err := rb.BuildSource(id, sous.User{Name: "Test User"})
+	require.NoError(t, err)
+	assert.Equal(t, "FROM scratch\n", bp.dockerfile)
+	assert.Equal(t, result, bp.registered)
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, id.Location, result.Products[0].Source.Location)
+}
+
+func TestSourceHost_cloneURL(t *testing.T) {
+	sl := sous.SourceLocation{Repo: "github.com/opentable/sous", Dir: "server"}
+	assert.Equal(t, "https://github.com/opentable/sous.git", SourceHost{}.cloneURL(sl))
+	assert.Equal(t, "git@github.com:opentable/sous.git", SourceHost{CloneURL: "git@github.com:"}.cloneURL(sl))
+}
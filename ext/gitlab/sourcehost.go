@@ -0,0 +1,68 @@
+// Package gitlab provides a sous.SourceHost for self-hosted GitLab servers.
+package gitlab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opentable/sous/ext/git"
+	"github.com/opentable/sous/lib"
+)
+
+// SourceHost is a GitLab source code host.
+// It satisfies sous.SourceHost.
+type SourceHost struct {
+	// Host is the host name of the GitLab server, e.g. "gitlab.example.com".
+	Host string
+	// CloneURL is prepended to the path of a project to get the URL it is
+	// cloned from, e.g. "git@gitlab.example.com:". It defaults to
+	// "https://<Host>/".
+	CloneURL string
+	// ScratchDir is where source code is cloned to. It defaults to the system
+	// temporary directory.
+	ScratchDir string
+}
+
+// NewSourceHost returns a SourceHost for the GitLab server at host, cloning
+// projects from cloneURL.
+func NewSourceHost(host, cloneURL string) SourceHost {
+	if cloneURL == "" {
+		cloneURL = "https://" + host + "/"
+	}
+	return SourceHost{Host: host, CloneURL: cloneURL}
+}
+
+// CanParseSourceLocation returns true if s begins with Host and a slash.
+func (h SourceHost) CanParseSourceLocation(s string) bool {
+	return strings.HasPrefix(s, h.prefix())
+}
+
+// ParseSourceLocation parses a GitLab source location.
+func (h SourceHost) ParseSourceLocation(s string) (sous.SourceLocation, error) {
+	return ParseSourceLocation(h.Host, s)
+}
+
+// Owns returns true if sl.Repo begins with Host and a slash.
+func (h SourceHost) Owns(sl sous.SourceLocation) bool {
+	return strings.HasPrefix(sl.Repo, h.prefix())
+}
+
+// GetSource clones the project id is in to a scratch directory and checks out
+// the tag for its version.
+// It returns an error if Owns(id.Location) returns false, or if cloning or
+// checking out fails.
+func (h SourceHost) GetSource(id sous.SourceID) (sous.Source, error) {
+	if !h.Owns(id.Location) {
+		return sous.Source{}, fmt.Errorf("the gitlab source host %s cannot get source for %q",
+			h.Host, id.Location)
+	}
+	return git.CloneSource(h.cloneURL(id.Location), id, h.ScratchDir)
+}
+
+func (h SourceHost) prefix() string {
+	return h.Host + "/"
+}
+
+func (h SourceHost) cloneURL(sl sous.SourceLocation) string {
+	return h.CloneURL + strings.TrimPrefix(sl.Repo, h.prefix()) + ".git"
+}
@@ -0,0 +1,88 @@
+package gitlab
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opentable/sous/lib"
+)
+
+const (
+	// Alnum contains all the lower and upper case ASCII letters and digits.
+	Alnum = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// PathAllowedFirstChars are the characters a group or project path may
+	// begin with.
+	PathAllowedFirstChars = Alnum + "_"
+	// PathAllowedChars are the characters allowed in a group or project path.
+	PathAllowedChars = Alnum + "_-."
+
+	// OffsetAllowedChars are characters allowed in the offset directory path.
+	OffsetAllowedChars = Alnum + "_-./"
+
+	// OffsetSeparator ends the project path in GitLab URLs: see
+	// sous.OffsetSeparator.
+	OffsetSeparator = sous.OffsetSeparator
+)
+
+// ParseSourceLocation parses s for a sous.SourceLocation on the GitLab server
+// at host. Projects may be in nested groups, so everything after the host is
+// taken to be the project path, up to a comma or OffsetSeparator which
+// introduces the offset directory.
+// It returns an error if the string is malformed, or if it does not begin with
+// host.
+func ParseSourceLocation(host, s string) (sous.SourceLocation, error) {
+	prefix := host + "/"
+	if !strings.HasPrefix(s, prefix) {
+		return sous.SourceLocation{}, fmt.Errorf("%q does not begin with %q", s, prefix)
+	}
+	project, dir := strings.TrimPrefix(s, prefix), ""
+	if i := strings.Index(project, ","); i != -1 {
+		project, dir = project[:i], project[i+1:]
+	}
+	if i := strings.Index(project, OffsetSeparator); i != -1 {
+		if dir != "" {
+			return sous.SourceLocation{}, fmt.Errorf("%q has two offset directories", s)
+		}
+		project, dir = project[:i], project[i+len(OffsetSeparator):]
+	}
+	project = strings.TrimSuffix(project, ".git")
+
+	parts := strings.Split(project, "/")
+	if len(parts) < 2 {
+		return sous.SourceLocation{}, fmt.Errorf("%q does not identify a project in a group", s)
+	}
+	for _, p := range parts {
+		if err := validatePath(p); err != nil {
+			return sous.SourceLocation{}, err
+		}
+	}
+	dir = strings.Trim(dir, "/")
+	for _, c := range dir {
+		if !strings.ContainsRune(OffsetAllowedChars, c) {
+			return sous.SourceLocation{}, fmt.Errorf("offset directory %q contains illegal character '%c'", dir, c)
+		}
+	}
+	return sous.SourceLocation{
+		Repo: prefix + project,
+		Dir:  dir,
+	}, nil
+}
+
+func validatePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("empty group or project path")
+	}
+	if !strings.ContainsRune(PathAllowedFirstChars, rune(p[0])) {
+		return fmt.Errorf("path %q must begin with a letter, digit or '_'", p)
+	}
+	for _, c := range p {
+		if !strings.ContainsRune(PathAllowedChars, c) {
+			return fmt.Errorf("path %q contains illegal character '%c'", p, c)
+		}
+	}
+	if strings.HasSuffix(p, ".atom") {
+		return fmt.Errorf("path %q must not end in .atom", p)
+	}
+	return nil
+}
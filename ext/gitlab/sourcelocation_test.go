@@ -0,0 +1,153 @@
+package gitlab
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentable/sous/lib"
+)
+
+const testHost = "gitlab.example.com"
+
+var sourceLocationTests = []struct {
+	String         string
+	SourceLocation sous.SourceLocation
+	Error          string
+}{
+	// wrong prefix
+	{
+		String: "github.com/some-group/some-project",
+		Error:  `"github.com/some-group/some-project" does not begin with "gitlab.example.com/"`,
+	},
+	{
+		String: "gitlab.example.com.evil/some-group/some-project",
+		Error:  `"gitlab.example.com.evil/some-group/some-project" does not begin with "gitlab.example.com/"`,
+	},
+
+	// no group
+	{
+		String: "gitlab.example.com/some-project",
+		Error:  `"gitlab.example.com/some-project" does not identify a project in a group`,
+	},
+
+	// invalid paths
+	{
+		String: "gitlab.example.com/some-group//some-project",
+		Error:  `empty group or project path`,
+	},
+	{
+		String: "gitlab.example.com/-group/some-project",
+		Error:  `path "-group" must begin with a letter, digit or '_'`,
+	},
+	{
+		String: "gitlab.example.com/some-group/some~project",
+		Error:  `path "some~project" contains illegal character '~'`,
+	},
+	{
+		String: "gitlab.example.com/some-group/some-project.atom",
+		Error:  `path "some-project.atom" must not end in .atom`,
+	},
+
+	// invalid offsets
+	{
+		String: "gitlab.example.com/some-group/some-project,offset:dir",
+		Error:  `offset directory "offset:dir" contains illegal character ':'`,
+	},
+	{
+		String: "gitlab.example.com/some-group/some-project/-/offset,dir",
+		Error:  `"gitlab.example.com/some-group/some-project/-/offset,dir" has two offset directories`,
+	},
+
+	// valid, without offset
+	{
+		String: "gitlab.example.com/some-group/some-project",
+		SourceLocation: sous.SourceLocation{
+			Repo: "gitlab.example.com/some-group/some-project",
+		},
+	},
+	{
+		String: "gitlab.example.com/some-group/some-project.git",
+		SourceLocation: sous.SourceLocation{
+			Repo: "gitlab.example.com/some-group/some-project",
+		},
+	},
+
+	// nested groups
+	{
+		String: "gitlab.example.com/some-group/sub_group/some.project",
+		SourceLocation: sous.SourceLocation{
+			Repo: "gitlab.example.com/some-group/sub_group/some.project",
+		},
+	},
+	{
+		String: "gitlab.example.com/a/b/c/d/some-project,some/offset",
+		SourceLocation: sous.SourceLocation{
+			Repo: "gitlab.example.com/a/b/c/d/some-project",
+			Dir:  "some/offset",
+		},
+	},
+
+	// offset using GitLab's separator
+	{
+		String: "gitlab.example.com/some-group/sub-group/some-project/-/some/offset",
+		SourceLocation: sous.SourceLocation{
+			Repo: "gitlab.example.com/some-group/sub-group/some-project",
+			Dir:  "some/offset",
+		},
+	},
+}
+
+func TestParseSourceLocation(t *testing.T) {
+	for _, test := range sourceLocationTests {
+		if err := checkParse(test.String, test.SourceLocation, test.Error); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func checkParse(input string, expected sous.SourceLocation, expectedErr string) error {
+	actual, actualErr := ParseSourceLocation(testHost, input)
+	if actualErr != nil && expectedErr == "" {
+		return actualErr
+	}
+	if actualErr == nil && expectedErr != "" {
+		return fmt.Errorf("%q got nil; want error:\n%#q", input, expectedErr)
+	}
+	if actualErr != nil && expectedErr != "" {
+		actual, expected := actualErr.Error(), expectedErr
+		if actual != expected {
+			return fmt.Errorf("%q got error:\n%#q;\nwant:\n%#q", input, actual, expected)
+		}
+	}
+	if actual != expected {
+		return fmt.Errorf("%q got %#v; want %#v", input, actual, expected)
+	}
+	return nil
+}
+
+func TestSourceHost(t *testing.T) {
+	h := NewSourceHost(testHost, "")
+	if !h.CanParseSourceLocation("gitlab.example.com/group/project") {
+		t.Errorf("cannot parse its own locations")
+	}
+	if h.CanParseSourceLocation("github.com/user/repo") {
+		t.Errorf("can parse GitHub locations")
+	}
+	sl := sous.SourceLocation{Repo: "gitlab.example.com/group/sub/project", Dir: "svc"}
+	if !h.Owns(sl) {
+		t.Errorf("does not own %q", sl)
+	}
+	if h.Owns(sous.SourceLocation{Repo: "gitlab.example.org/group/project"}) {
+		t.Errorf("owns another host's location")
+	}
+	if actual, expected := h.cloneURL(sl), "https://gitlab.example.com/group/sub/project.git"; actual != expected {
+		t.Errorf("got clone URL %q; want %q", actual, expected)
+	}
+	h = NewSourceHost(testHost, "git@gitlab.example.com:")
+	if actual, expected := h.cloneURL(sl), "git@gitlab.example.com:group/sub/project.git"; actual != expected {
+		t.Errorf("got clone URL %q; want %q", actual, expected)
+	}
+	if _, err := h.GetSource(sous.SourceID{Location: sous.SourceLocation{Repo: "github.com/user/repo"}}); err == nil {
+		t.Errorf("got source for a location it does not own")
+	}
+}
@@ -0,0 +1,291 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil" //ok
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nyarly/spies"
+	"github.com/pkg/errors"
+)
+
+type (
+	// kubeClient abstracts the queries we make to a Kubernetes API server.
+	kubeClient interface {
+		ListDeployments(namespace, selector string) (*kubeDeploymentList, error)
+		GetDeployment(namespace, name string) (*kubeDeployment, error)
+		PutDeployment(d *kubeDeployment) error
+		ListCronJobs(namespace, selector string) (*kubeCronJobList, error)
+		GetCronJob(namespace, name string) (*kubeCronJob, error)
+		PutCronJob(cj *kubeCronJob) error
+		PutService(s *kubeService) error
+		// The Delete methods succeed if there is no object to delete.
+		DeleteDeployment(namespace, name string) error
+		DeleteCronJob(namespace, name string) error
+		DeleteService(namespace, name string) error
+	}
+
+	// restClient is a kubeClient which talks JSON to a Kubernetes API server.
+	restClient struct {
+		baseURL string
+		token   string
+		http    *http.Client
+	}
+
+	// notFoundError is returned by a kubeClient when the named object does
+	// not exist.
+	notFoundError struct {
+		path string
+	}
+
+	kubeClientSpy struct {
+		spy *spies.Spy
+	}
+
+	kubeClientSpyController struct {
+		*spies.Spy
+	}
+)
+
+func (nf notFoundError) Error() string {
+	return fmt.Sprintf("kubernetes object not found: %s", nf.path)
+}
+
+func isNotFound(err error) bool {
+	_, is := errors.Cause(err).(notFoundError)
+	return is
+}
+
+func newRESTClient(baseURL, token string) *restClient {
+	return &restClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+func deploymentsPath(ns string) string {
+	return fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments", ns)
+}
+
+func cronJobsPath(ns string) string {
+	return fmt.Sprintf("/apis/batch/v1beta1/namespaces/%s/cronjobs", ns)
+}
+
+func servicesPath(ns string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/services", ns)
+}
+
+func (rc *restClient) do(method, path string, query url.Values, body, into interface{}) error {
+	u := rc.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody *bytes.Buffer
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewBuffer(b)
+	} else {
+		reqBody = &bytes.Buffer{}
+	}
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if rc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+rc.token)
+	}
+
+	rz, err := rc.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, u)
+	}
+	defer rz.Body.Close()
+	b, err := ioutil.ReadAll(rz.Body)
+	if err != nil {
+		return err
+	}
+	if rz.StatusCode == http.StatusNotFound {
+		return notFoundError{path: path}
+	}
+	if rz.StatusCode < 200 || rz.StatusCode > 299 {
+		return errors.Errorf("%s %s: %s: %s", method, u, rz.Status, string(b))
+	}
+	if into == nil {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(b, into), "decoding response from %s", u)
+}
+
+// put creates the object at collection/name, or replaces it if it exists.
+// When it exists, it is first read into current, and carry is called to copy
+// what the replacement must keep from current into obj.
+func (rc *restClient) put(collection, name string, obj, current interface{}, carry func()) error {
+	err := rc.do("GET", collection+"/"+name, nil, nil, current)
+	if isNotFound(err) {
+		return rc.do("POST", collection, nil, obj, nil)
+	}
+	if err != nil {
+		return err
+	}
+	carry()
+	return rc.do("PUT", collection+"/"+name, nil, obj, nil)
+}
+
+// delete deletes the object at path, along with the objects it owns, if it
+// exists.
+func (rc *restClient) delete(path string) error {
+	err := rc.do("DELETE", path, url.Values{"propagationPolicy": []string{"Background"}}, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func selectorQuery(selector string) url.Values {
+	if selector == "" {
+		return nil
+	}
+	return url.Values{"labelSelector": []string{selector}}
+}
+
+// ListDeployments implements kubeClient on restClient.
+func (rc *restClient) ListDeployments(ns, selector string) (*kubeDeploymentList, error) {
+	l := &kubeDeploymentList{}
+	return l, rc.do("GET", deploymentsPath(ns), selectorQuery(selector), nil, l)
+}
+
+// GetDeployment implements kubeClient on restClient.
+func (rc *restClient) GetDeployment(ns, name string) (*kubeDeployment, error) {
+	d := &kubeDeployment{}
+	return d, rc.do("GET", deploymentsPath(ns)+"/"+name, nil, nil, d)
+}
+
+// PutDeployment implements kubeClient on restClient.
+func (rc *restClient) PutDeployment(d *kubeDeployment) error {
+	current := &kubeDeployment{}
+	return rc.put(deploymentsPath(d.Metadata.Namespace), d.Metadata.Name, d, current,
+		func() { d.Metadata.ResourceVersion = current.Metadata.ResourceVersion })
+}
+
+// ListCronJobs implements kubeClient on restClient.
+func (rc *restClient) ListCronJobs(ns, selector string) (*kubeCronJobList, error) {
+	l := &kubeCronJobList{}
+	return l, rc.do("GET", cronJobsPath(ns), selectorQuery(selector), nil, l)
+}
+
+// GetCronJob implements kubeClient on restClient.
+func (rc *restClient) GetCronJob(ns, name string) (*kubeCronJob, error) {
+	cj := &kubeCronJob{}
+	return cj, rc.do("GET", cronJobsPath(ns)+"/"+name, nil, nil, cj)
+}
+
+// PutCronJob implements kubeClient on restClient.
+func (rc *restClient) PutCronJob(cj *kubeCronJob) error {
+	current := &kubeCronJob{}
+	return rc.put(cronJobsPath(cj.Metadata.Namespace), cj.Metadata.Name, cj, current,
+		func() { cj.Metadata.ResourceVersion = current.Metadata.ResourceVersion })
+}
+
+// PutService implements kubeClient on restClient.
+func (rc *restClient) PutService(s *kubeService) error {
+	current := &kubeService{}
+	return rc.put(servicesPath(s.Metadata.Namespace), s.Metadata.Name, s, current,
+		func() {
+			s.Metadata.ResourceVersion = current.Metadata.ResourceVersion
+			// The API server rejects changes to an allocated cluster IP.
+			s.Spec.ClusterIP = current.Spec.ClusterIP
+		})
+}
+
+// DeleteDeployment implements kubeClient on restClient.
+func (rc *restClient) DeleteDeployment(ns, name string) error {
+	return rc.delete(deploymentsPath(ns) + "/" + name)
+}
+
+// DeleteCronJob implements kubeClient on restClient.
+func (rc *restClient) DeleteCronJob(ns, name string) error {
+	return rc.delete(cronJobsPath(ns) + "/" + name)
+}
+
+// DeleteService implements kubeClient on restClient.
+func (rc *restClient) DeleteService(ns, name string) error {
+	return rc.delete(servicesPath(ns) + "/" + name)
+}
+
+func newKubeClientSpy() (kubeClientSpy, kubeClientSpyController) {
+	spy := spies.NewSpy()
+	return kubeClientSpy{spy: spy}, kubeClientSpyController{Spy: spy}
+}
+
+func (spy kubeClientSpy) ListDeployments(ns, selector string) (*kubeDeploymentList, error) {
+	res := spy.spy.Called(ns, selector)
+	return res.Get(0).(*kubeDeploymentList), res.Error(1)
+}
+
+func (spy kubeClientSpy) GetDeployment(ns, name string) (*kubeDeployment, error) {
+	res := spy.spy.Called(ns, name)
+	return res.Get(0).(*kubeDeployment), res.Error(1)
+}
+
+func (spy kubeClientSpy) PutDeployment(d *kubeDeployment) error {
+	return spy.spy.Called(d).Error(0)
+}
+
+func (spy kubeClientSpy) ListCronJobs(ns, selector string) (*kubeCronJobList, error) {
+	res := spy.spy.Called(ns, selector)
+	return res.Get(0).(*kubeCronJobList), res.Error(1)
+}
+
+func (spy kubeClientSpy) GetCronJob(ns, name string) (*kubeCronJob, error) {
+	res := spy.spy.Called(ns, name)
+	return res.Get(0).(*kubeCronJob), res.Error(1)
+}
+
+func (spy kubeClientSpy) PutCronJob(cj *kubeCronJob) error {
+	return spy.spy.Called(cj).Error(0)
+}
+
+func (spy kubeClientSpy) PutService(s *kubeService) error {
+	return spy.spy.Called(s).Error(0)
+}
+
+func (spy kubeClientSpy) DeleteDeployment(ns, name string) error {
+	return spy.spy.Called(ns, name).Error(0)
+}
+
+func (spy kubeClientSpy) DeleteCronJob(ns, name string) error {
+	return spy.spy.Called(ns, name).Error(0)
+}
+
+func (spy kubeClientSpy) DeleteService(ns, name string) error {
+	return spy.spy.Called(ns, name).Error(0)
+}
+
+func (ctrl kubeClientSpyController) cannedDeployments(ds ...kubeDeployment) {
+	ctrl.MatchMethod("ListDeployments", spies.AnyArgs, &kubeDeploymentList{Items: ds}, nil)
+	if len(ds) > 0 {
+		ctrl.MatchMethod("GetDeployment", spies.AnyArgs, &ds[0], nil)
+	}
+	ctrl.MatchMethod("ListCronJobs", spies.AnyArgs, &kubeCronJobList{}, nil)
+}
+
+func (ctrl kubeClientSpyController) acceptWrites() {
+	ctrl.MatchMethod("PutDeployment", spies.AnyArgs, nil)
+	ctrl.MatchMethod("PutCronJob", spies.AnyArgs, nil)
+	ctrl.MatchMethod("PutService", spies.AnyArgs, nil)
+	ctrl.MatchMethod("DeleteDeployment", spies.AnyArgs, nil)
+	ctrl.MatchMethod("DeleteCronJob", spies.AnyArgs, nil)
+	ctrl.MatchMethod("DeleteService", spies.AnyArgs, nil)
+}
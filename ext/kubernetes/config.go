@@ -0,0 +1,22 @@
+package kubernetes
+
+// Config is the configuration for talking to Kubernetes clusters.
+type Config struct {
+	// Namespace is the namespace Sous manages objects in.
+	Namespace string `env:"SOUS_KUBERNETES_NAMESPACE"`
+	// Token is a bearer token used to authenticate with Kubernetes API servers.
+	Token string `env:"SOUS_KUBERNETES_TOKEN"`
+	// BasePort is the first container port assigned to deployments. Further
+	// ports are numbered consecutively, and exposed as PORT0, PORT1... in the
+	// container's environment, as Singularity would.
+	BasePort int `env:"SOUS_KUBERNETES_BASE_PORT"`
+}
+
+// DefaultConfig builds a default configuration, which can be then overridden by
+// client code.
+func DefaultConfig() Config {
+	return Config{
+		Namespace: "default",
+		BasePort:  8080,
+	}
+}
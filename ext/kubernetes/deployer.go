@@ -0,0 +1,325 @@
+package kubernetes
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/opentable/sous/ext/docker"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	deployer struct {
+		clientFac func(baseURL string) kubeClient
+		config    Config
+		dryrun    bool
+		// secrets resolves the secret references in the Env of deployments.
+		// If it is nil, deployments which refer to secrets cannot be
+		// deployed.
+		secrets sous.SecretStore
+		log     logging.LogSink
+	}
+
+	// DeployerOption is an option for configuring Kubernetes deployers.
+	DeployerOption func(*deployer)
+
+	kubeTaskData struct {
+		name string
+	}
+)
+
+// NewDeployer creates a new Kubernetes-based sous.Deployer.
+func NewDeployer(cfg Config, ls logging.LogSink, options ...DeployerOption) sous.Deployer {
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultConfig().Namespace
+	}
+	if cfg.BasePort == 0 {
+		cfg.BasePort = DefaultConfig().BasePort
+	}
+	d := &deployer{config: cfg, log: ls}
+	d.clientFac = func(url string) kubeClient { return newRESTClient(url, d.config.Token) }
+	for _, opt := range options {
+		opt(d)
+	}
+	return d
+}
+
+// OptDryRun makes the deployer read from its clusters as usual, but only log
+// the changes it would otherwise write.
+func OptDryRun() DeployerOption {
+	return func(d *deployer) { d.dryrun = true }
+}
+
+// OptSecrets makes the deployer resolve secret references in the Env of
+// deployments from store.
+func OptSecrets(store sous.SecretStore) DeployerOption {
+	return func(d *deployer) { d.secrets = store }
+}
+
+func optClientFactory(fn func(string) kubeClient) DeployerOption {
+	return func(d *deployer) { d.clientFac = fn }
+}
+
+func rectifyRecover(d interface{}, f string, err *error, log logging.LogSink) {
+	if r := recover(); r != nil {
+		stack := string(debug.Stack())
+		messages.ReportLogFieldsMessage("Panic", logging.WarningLevel, log, d, f, err, r, stack)
+		*err = errors.Errorf("Panicked: %s; stack trace:\n%s", r, stack)
+	}
+}
+
+// Rectify implements sous.Deployer on deployer.
+func (r *deployer) Rectify(pair *sous.DeployablePair) sous.DiffResolution {
+	switch k := pair.Kind(); k {
+	default:
+		panic(fmt.Sprintf("unrecognised kind %q", k))
+	case sous.SameKind:
+		resolution := pair.SameResolution()
+		if pair.Post.Status == sous.DeployStatusFailed {
+			resolution.Error = sous.WrapResolveError(&sous.FailedStatusError{})
+		}
+		return resolution
+	case sous.AddedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.apply(pair.Post); err != nil {
+			result.Desc = "not created"
+			result.Error = sous.WrapResolveError(&sous.CreateError{Deployment: pair.Post.Deployment.Clone(), Err: err})
+		} else {
+			result.Desc = sous.CreateDiff
+		}
+		messages.ReportLogFieldsMessage("Result of create", logging.InformationLevel, r.log, result)
+		return result
+	case sous.RemovedKind:
+		// As with Singularity, removed deployments are left running for their
+		// owners to clean up.
+		messages.ReportLogFieldsMessage("Rectify not deleting deployment", logging.WarningLevel, r.log, pair.ID())
+		return sous.DiffResolution{DeploymentID: pair.ID(), Desc: sous.DeleteDiff}
+	case sous.ModifiedKind:
+		result := sous.DiffResolution{DeploymentID: pair.ID()}
+		if err := r.apply(pair.Post); err != nil {
+			result.Desc = "not updated"
+			result.Error = sous.WrapResolveError(&sous.ChangeError{
+				Deployments: &sous.DeploymentPair{
+					Prior: pair.Prior.Deployment.Clone(),
+					Post:  pair.Post.Deployment.Clone(),
+				},
+				Err: err,
+			})
+		} else {
+			result.Desc = sous.ModifyDiff
+		}
+		messages.ReportLogFieldsMessage("Result of modify", logging.InformationLevel, r.log, result)
+		return result
+	}
+}
+
+// apply writes the Kubernetes objects for d to its cluster, and deletes
+// those it no longer needs: the Deployment and Service of a deployment which
+// is now scheduled, the CronJob of one which no longer is, and the Service of
+// one without ports.
+func (r *deployer) apply(d *sous.Deployable) (err error) {
+	defer rectifyRecover(d, "apply", &err, r.log)
+	objs, err := buildObjects(*d, r.config.Namespace, int32(r.config.BasePort), r.secrets)
+	if err != nil {
+		return err
+	}
+	if r.dryrun {
+		messages.ReportLogFieldsMessage("Dry run: not writing objects", logging.InformationLevel, r.log, d, fmt.Sprintf("%+v", objs.redacted()))
+		return nil
+	}
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return err
+	}
+	ns := r.config.Namespace
+	client := r.clientFac(d.Deployment.Cluster.BaseURL)
+	if objs.CronJob != nil {
+		if err := client.PutCronJob(objs.CronJob); err != nil {
+			return errors.Wrap(err, "writing cronjob")
+		}
+		if err := client.DeleteDeployment(ns, name); err != nil {
+			return errors.Wrap(err, "deleting deployment")
+		}
+	} else {
+		if err := client.PutDeployment(objs.Deployment); err != nil {
+			return errors.Wrap(err, "writing deployment")
+		}
+		if err := client.DeleteCronJob(ns, name); err != nil {
+			return errors.Wrap(err, "deleting cronjob")
+		}
+	}
+	if objs.Service != nil {
+		return errors.Wrap(client.PutService(objs.Service), "writing service")
+	}
+	return errors.Wrap(client.DeleteService(ns, name), "deleting service")
+}
+
+// RemoveDeployment implements sous.DeploymentRemover on deployer, by deleting
+// the Kubernetes objects of d.
+func (r *deployer) RemoveDeployment(d *sous.Deployment) error {
+	name, err := MakeObjectName(d.ID())
+	if err != nil {
+		return err
+	}
+	if r.dryrun {
+		messages.ReportLogFieldsMessage("Dry run: not deleting objects", logging.InformationLevel, r.log, d.ID(), name)
+		return nil
+	}
+	ns := r.config.Namespace
+	client := r.clientFac(d.Cluster.BaseURL)
+	if err := client.DeleteDeployment(ns, name); err != nil {
+		return errors.Wrap(err, "deleting deployment")
+	}
+	if err := client.DeleteCronJob(ns, name); err != nil {
+		return errors.Wrap(err, "deleting cronjob")
+	}
+	return errors.Wrap(client.DeleteService(ns, name), "deleting service")
+}
+
+// RunningDeployments implements sous.Deployer on deployer. It lists the
+// Sous-managed Deployments and CronJobs in each cluster.
+func (r *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters) (sous.DeployStates, error) {
+	states := sous.NewDeployStates()
+	seen := map[string]struct{}{}
+	for _, cluster := range clusters {
+		url := cluster.BaseURL
+		if _, ok := seen[url]; ok {
+			continue
+		}
+		seen[url] = struct{}{}
+		client := r.clientFac(url)
+
+		deps, err := client.ListDeployments(r.config.Namespace, managedSelector())
+		if err != nil {
+			return states, errors.Wrapf(err, "listing deployments on %s", url)
+		}
+		for i := range deps.Items {
+			ds, err := r.deployState(reg, clusters, &deps.Items[i])
+			if err != nil {
+				logging.ReportError(r.log, errors.Wrapf(err, "deployment %s", deps.Items[i].Metadata.Name))
+				continue
+			}
+			if ds != nil {
+				states.Add(ds)
+			}
+		}
+
+		cjs, err := client.ListCronJobs(r.config.Namespace, managedSelector())
+		if err != nil {
+			return states, errors.Wrapf(err, "listing cronjobs on %s", url)
+		}
+		for i := range cjs.Items {
+			ds, err := r.cronJobState(reg, clusters, &cjs.Items[i])
+			if err != nil {
+				logging.ReportError(r.log, errors.Wrapf(err, "cronjob %s", cjs.Items[i].Metadata.Name))
+				continue
+			}
+			if ds != nil {
+				states.Add(ds)
+			}
+		}
+	}
+	return states, nil
+}
+
+// Status implements sous.Deployer on deployer.
+func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.DeployablePair) (*sous.DeployState, error) {
+	clusterName := pair.Post.Deployment.ClusterName
+	cluster, has := clusters[clusterName]
+	if !has {
+		return nil, errors.Errorf("No cluster found for %q. Known are: %q.", clusterName, clusters.Names())
+	}
+	name, err := MakeObjectName(pair.Post.ID())
+	if err != nil {
+		return nil, err
+	}
+	client := r.clientFac(cluster.BaseURL)
+
+	var ds *sous.DeployState
+	path := deploymentsPath(r.config.Namespace)
+	if pair.Post.Kind == sous.ManifestKindScheduled || pair.Post.Kind == sous.ScheduledJob {
+		path = cronJobsPath(r.config.Namespace)
+		cj, err := client.GetCronJob(r.config.Namespace, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting cronjob")
+		}
+		ds, err = r.cronJobState(reg, clusters, cj)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		kd, err := client.GetDeployment(r.config.Namespace, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting deployment")
+		}
+		ds, err = r.deployState(reg, clusters, kd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ds == nil {
+		return nil, errors.Errorf("%s does not belong to cluster %q", name, clusterName)
+	}
+	ds.SchedulerURL = fmt.Sprintf("%s%s/%s", cluster.BaseURL, path, name)
+	return ds, nil
+}
+
+func (r *deployer) deployState(reg sous.ImageLabeller, clusters sous.Clusters, kd *kubeDeployment) (*sous.DeployState, error) {
+	ds, err := r.baseState(reg, clusters, kd.Metadata, kd.Spec.Template.Spec)
+	if ds == nil || err != nil {
+		return ds, err
+	}
+	if kd.Spec.Replicas != nil {
+		ds.NumInstances = int(*kd.Spec.Replicas)
+	}
+	if kd.Spec.ProgressDeadlineSeconds != nil && *kd.Spec.ProgressDeadlineSeconds != defaultDeadlineSecond {
+		ds.Startup.Timeout = int(*kd.Spec.ProgressDeadlineSeconds)
+	}
+	ds.Status, ds.ExecutorMessage = determineStatus(kd)
+	return ds, nil
+}
+
+func (r *deployer) cronJobState(reg sous.ImageLabeller, clusters sous.Clusters, cj *kubeCronJob) (*sous.DeployState, error) {
+	ds, err := r.baseState(reg, clusters, cj.Metadata, cj.Spec.JobTemplate.Spec.Template.Spec)
+	if ds == nil || err != nil {
+		return ds, err
+	}
+	if p := cj.Spec.JobTemplate.Spec.Parallelism; p != nil {
+		ds.NumInstances = int(*p)
+	}
+	ds.Schedule = cj.Spec.Schedule
+	ds.Status = sous.DeployStatusActive
+	return ds, nil
+}
+
+// baseState builds the parts of a DeployState common to all object kinds. It
+// returns nil if the object belongs to a cluster not in clusters.
+func (r *deployer) baseState(reg sous.ImageLabeller, clusters sous.Clusters, meta objectMeta, spec podSpec) (*sous.DeployState, error) {
+	ds := &sous.DeployState{}
+	if err := unpackAnnotations(meta.Annotations, &ds.Deployment); err != nil {
+		return nil, err
+	}
+	cluster, ours := clusters[ds.ClusterName]
+	if !ours {
+		return nil, nil
+	}
+	ds.Cluster = cluster
+	ds.ExecutorData = &kubeTaskData{name: meta.Name}
+
+	image, err := unpackPodSpec(meta.Annotations, spec, &ds.DeployConfig)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := reg.ImageLabels(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "image labels for %s", image)
+	}
+	ds.SourceID, err = docker.SourceIDFromLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
@@ -0,0 +1,383 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/ext/docker"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type labeller map[string]string
+
+func (l labeller) ImageLabels(string) (map[string]string, error) {
+	return l, nil
+}
+
+func testClusters() sous.Clusters {
+	return sous.Clusters{
+		"kube-1": &sous.Cluster{Name: "kube-1", Kind: sous.ClusterKindKubernetes, BaseURL: "http://kube-1.example.com"},
+	}
+}
+
+func testDeployable(kind sous.ManifestKind) *sous.Deployable {
+	clusters := testClusters()
+	return &sous.Deployable{
+		Deployment: &sous.Deployment{
+			ClusterName: "kube-1",
+			Cluster:     clusters["kube-1"],
+			SourceID:    sous.MustNewSourceID("github.com/opentable/example", "", "1.2.3"),
+			Flavor:      "vanilla",
+			Kind:        kind,
+			Owners:      sous.NewOwnerSet("a@example.com", "b@example.com"),
+			DeployConfig: sous.DeployConfig{
+				NumInstances: 3,
+				Schedule:     "*/5 * * * *",
+				Resources:    sous.Resources{"cpus": "0.25", "memory": "512", "ports": "2", "disk": "100", "network-mbps": "10"},
+				Env:          sous.Env{"GREETING": "hello"},
+				Volumes: sous.Volumes{
+					&sous.Volume{Host: "/var/log", Container: "/logs", Mode: sous.ReadWrite},
+					&sous.Volume{Host: "/etc/certs", Container: "/certs", Mode: sous.ReadOnly},
+				},
+				Startup: sous.Startup{
+					ConnectDelay:              10,
+					ConnectInterval:           2,
+					CheckReadyProtocol:        "HTTP",
+					CheckReadyURIPath:         "/health",
+					CheckReadyPortIndex:       1,
+					CheckReadyFailureStatuses: []int{500, 503},
+					CheckReadyURITimeout:      5,
+					CheckReadyInterval:        3,
+					CheckReadyRetries:         7,
+				},
+			},
+		},
+		BuildArtifact: &sous.BuildArtifact{DigestReference: "docker.example.com/example@sha256:abc"},
+	}
+}
+
+func testLabels() labeller {
+	return labeller{
+		docker.DockerRepoLabel:     "github.com/opentable/example",
+		docker.DockerPathLabel:     "",
+		docker.DockerVersionLabel:  "1.2.3",
+		docker.DockerRevisionLabel: "cabbage",
+	}
+}
+
+func TestMakeObjectName(t *testing.T) {
+	name, err := MakeObjectName(sous.DeploymentID{
+		ManifestID: sous.ManifestID{
+			Source: sous.SourceLocation{
+				Repo: "github.com/ihaveanincrediblylongname/AndILikeMyProjectsToHaveIncrediblyLongNamesToo",
+				Dir:  "some/offset",
+			},
+			Flavor: "Spicy_Flavor",
+		},
+		Cluster: "prod.kube",
+	})
+	require.NoError(t, err)
+	assert.True(t, len(name) <= 63, "%q is longer than 63 characters", name)
+	assert.Regexp(t, `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`, name)
+}
+
+func TestBuildObjects_RoundTrip(t *testing.T) {
+	d := testDeployable(sous.ManifestKindService)
+	objs, err := buildObjects(*d, "sous", 8080, nil)
+	require.NoError(t, err)
+	require.NotNil(t, objs.Deployment)
+	require.NotNil(t, objs.Service)
+	assert.Nil(t, objs.CronJob)
+
+	c := objs.Deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "docker.example.com/example@sha256:abc", c.Image)
+	assert.Equal(t, int32(8081), c.ReadinessProbe.HTTPGet.Port)
+	assert.Len(t, objs.Service.Spec.Ports, 2)
+	assert.JSONEq(t, `{"disk":"100","network-mbps":"10"}`, objs.Deployment.Metadata.Annotations[sous.ResourcesLabel])
+
+	// Simulate an API server normalising quantities and rolling out.
+	c.Resources.Requests["cpu"] = "250m"
+	objs.Deployment.Spec.Template.Spec.Containers[0] = c
+	objs.Deployment.Status = deploymentStatus{UpdatedReplicas: 3, AvailableReplicas: 3}
+
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls).(*deployer)
+	ds, err := dep.deployState(testLabels(), testClusters(), objs.Deployment)
+	require.NoError(t, err)
+	require.NotNil(t, ds)
+
+	assert.Equal(t, sous.DeployStatusActive, ds.Status)
+	different, diffs := d.Deployment.Diff(&ds.Deployment)
+	assert.False(t, different, "%v", diffs)
+}
+
+func TestBuildObjects_Scheduled(t *testing.T) {
+	d := testDeployable(sous.ManifestKindScheduled)
+	objs, err := buildObjects(*d, "sous", 8080, nil)
+	require.NoError(t, err)
+	require.NotNil(t, objs.CronJob)
+	assert.Nil(t, objs.Deployment)
+	assert.Equal(t, "*/5 * * * *", objs.CronJob.Spec.Schedule)
+
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls).(*deployer)
+	ds, err := dep.cronJobState(testLabels(), testClusters(), objs.CronJob)
+	require.NoError(t, err)
+	different, diffs := d.Deployment.Diff(&ds.Deployment)
+	assert.False(t, different, "%v", diffs)
+}
+
+type secretStore map[string]string
+
+func (s secretStore) Secret(ref sous.SecretRef) (string, error) {
+	v, ok := s[ref.String()]
+	if !ok {
+		return "", fmt.Errorf("no secret %s", ref)
+	}
+	return v, nil
+}
+
+func TestBuildObjects_Secrets(t *testing.T) {
+	d := testDeployable(sous.ManifestKindService)
+	d.Env["DB_PASSWORD"] = "secret://db#password"
+
+	_, err := buildObjects(*d, "sous", 8080, nil)
+	assert.Error(t, err, "secrets cannot be resolved without a store")
+
+	objs, err := buildObjects(*d, "sous", 8080, secretStore{"secret://db#password": "hunter2"})
+	require.NoError(t, err)
+	env := map[string]string{}
+	for _, e := range objs.Deployment.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "hunter2", env["DB_PASSWORD"])
+	assert.Equal(t, "secret://db#password", d.Env["DB_PASSWORD"], "the deployment itself is unchanged")
+
+	logged := objs.redacted().Deployment.Spec.Template.Spec.Containers[0].Env
+	assert.NotContains(t, fmt.Sprint(logged), "hunter2")
+	assert.Contains(t, fmt.Sprint(objs.Deployment.Spec.Template.Spec.Containers[0].Env), "hunter2", "redaction copies the objects")
+
+	objs.Deployment.Status = deploymentStatus{UpdatedReplicas: 3, AvailableReplicas: 3}
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls).(*deployer)
+	ds, err := dep.deployState(testLabels(), testClusters(), objs.Deployment)
+	require.NoError(t, err)
+	assert.Equal(t, "secret://db#password", ds.Env["DB_PASSWORD"])
+	different, diffs := d.Deployment.Diff(&ds.Deployment)
+	assert.False(t, different, "%v", diffs)
+}
+
+func TestDetermineStatus(t *testing.T) {
+	three := int32(3)
+	kd := &kubeDeployment{
+		Metadata: objectMeta{Name: "x", Generation: 2},
+		Spec:     deploymentSpec{Replicas: &three},
+		Status:   deploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 3, AvailableReplicas: 3},
+	}
+	status, _ := determineStatus(kd)
+	assert.Equal(t, sous.DeployStatusPending, status)
+
+	kd.Status.ObservedGeneration = 2
+	status, _ = determineStatus(kd)
+	assert.Equal(t, sous.DeployStatusActive, status)
+
+	kd.Status.Conditions = []deploymentCondition{{
+		Type: "Progressing", Status: "False", Reason: "ProgressDeadlineExceeded", Message: "too slow",
+	}}
+	status, msg := determineStatus(kd)
+	assert.Equal(t, sous.DeployStatusFailed, status)
+	assert.Contains(t, msg, "too slow")
+}
+
+func TestRectify_Added(t *testing.T) {
+	client, ctrl := newKubeClientSpy()
+	ctrl.acceptWrites()
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls, optClientFactory(func(string) kubeClient { return client }))
+
+	pair := &sous.DeployablePair{Post: testDeployable(sous.ManifestKindService)}
+	pair.SetID(pair.Post.ID())
+	rez := dep.Rectify(pair)
+
+	assert.Nil(t, rez.Error)
+	assert.Equal(t, sous.CreateDiff, rez.Desc)
+	assert.Len(t, ctrl.CallsTo("PutDeployment"), 1)
+	assert.Len(t, ctrl.CallsTo("PutService"), 1)
+	assert.Len(t, ctrl.CallsTo("PutCronJob"), 0)
+	assert.Len(t, ctrl.CallsTo("DeleteCronJob"), 1)
+	assert.Len(t, ctrl.CallsTo("DeleteDeployment"), 0)
+	assert.Len(t, ctrl.CallsTo("DeleteService"), 0)
+}
+
+func TestRectify_deletesUnusedObjects(t *testing.T) {
+	for kind, deleted := range map[sous.ManifestKind][]string{
+		sous.ManifestKindScheduled: {"DeleteDeployment", "DeleteService"},
+		sous.ManifestKindWorker:    {"DeleteCronJob", "DeleteService"},
+	} {
+		client, ctrl := newKubeClientSpy()
+		ctrl.acceptWrites()
+		ls, _ := logging.NewLogSinkSpy()
+		dep := NewDeployer(DefaultConfig(), ls, optClientFactory(func(string) kubeClient { return client }))
+
+		pair := &sous.DeployablePair{Post: testDeployable(kind)}
+		pair.SetID(pair.Post.ID())
+		rez := dep.Rectify(pair)
+		require.Nil(t, rez.Error, "%s", kind)
+
+		name, err := MakeObjectName(pair.ID())
+		require.NoError(t, err)
+		for _, method := range []string{"DeleteDeployment", "DeleteCronJob", "DeleteService"} {
+			calls := ctrl.CallsTo(method)
+			want := 0
+			for _, d := range deleted {
+				if d == method {
+					want = 1
+				}
+			}
+			if assert.Len(t, calls, want, "%s %s", kind, method) && want == 1 {
+				assert.Equal(t, name, calls[0].PassedArgs().String(1))
+			}
+		}
+	}
+}
+
+func TestRemoveDeployment(t *testing.T) {
+	client, ctrl := newKubeClientSpy()
+	ctrl.acceptWrites()
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls, optClientFactory(func(string) kubeClient { return client }))
+
+	d := testDeployable(sous.ManifestKindService).Deployment
+	require.NoError(t, dep.(sous.DeploymentRemover).RemoveDeployment(d))
+	name, err := MakeObjectName(d.ID())
+	require.NoError(t, err)
+	for _, method := range []string{"DeleteDeployment", "DeleteCronJob", "DeleteService"} {
+		if calls := ctrl.CallsTo(method); assert.Len(t, calls, 1, method) {
+			assert.Equal(t, name, calls[0].PassedArgs().String(1))
+		}
+	}
+}
+
+func TestStatus_Scheduled(t *testing.T) {
+	d := testDeployable(sous.ManifestKindScheduled)
+	objs, err := buildObjects(*d, "default", 8080, nil)
+	require.NoError(t, err)
+
+	client, ctrl := newKubeClientSpy()
+	ctrl.MatchMethod("GetCronJob", spies.AnyArgs, objs.CronJob, nil)
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls, optClientFactory(func(string) kubeClient { return client }))
+
+	reg, rc := sous.NewRegistrySpy()
+	rc.MatchMethod("ImageLabels", spies.AnyArgs, map[string]string(testLabels()), nil)
+
+	pair := &sous.DeployablePair{Post: d}
+	pair.SetID(d.ID())
+	ds, err := dep.Status(reg, testClusters(), pair)
+	require.NoError(t, err)
+	assert.Equal(t, "http://kube-1.example.com/apis/batch/v1beta1/namespaces/default/cronjobs/"+objs.CronJob.Metadata.Name, ds.SchedulerURL)
+}
+
+func TestRunningDeployments(t *testing.T) {
+	d := testDeployable(sous.ManifestKindWorker)
+	objs, err := buildObjects(*d, "default", 8080, nil)
+	require.NoError(t, err)
+
+	other := *objs.Deployment
+	other.Metadata.Annotations = map[string]string{sous.ClusterNameLabel: "somewhere-else"}
+
+	client, ctrl := newKubeClientSpy()
+	ctrl.cannedDeployments(*objs.Deployment, other)
+	ls, _ := logging.NewLogSinkSpy()
+	dep := NewDeployer(DefaultConfig(), ls, optClientFactory(func(string) kubeClient { return client }))
+
+	reg, rc := sous.NewRegistrySpy()
+	rc.MatchMethod("ImageLabels", spies.AnyArgs, map[string]string(testLabels()), nil)
+
+	states, err := dep.RunningDeployments(reg, testClusters())
+	require.NoError(t, err)
+	assert.Equal(t, 1, states.Len())
+	_, has := states.Get(d.ID())
+	assert.True(t, has)
+}
+
+func TestRESTClient_PutDeployment(t *testing.T) {
+	var methods []string
+	var created kubeDeployment
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		assert.Equal(t, "Bearer sekrit", r.Header.Get("Authorization"))
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(404)
+		case "POST":
+			assert.True(t, strings.HasSuffix(r.URL.Path, "/apis/apps/v1/namespaces/sous/deployments"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			w.WriteHeader(201)
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	objs, err := buildObjects(*testDeployable(sous.ManifestKindService), "sous", 8080, nil)
+	require.NoError(t, err)
+
+	client := newRESTClient(srv.URL, "sekrit")
+	require.NoError(t, client.PutDeployment(objs.Deployment))
+	assert.Equal(t, []string{"GET", "POST"}, methods)
+	assert.Equal(t, objs.Deployment.Metadata.Name, created.Metadata.Name)
+}
+
+func TestRESTClient_PutService_keepsClusterIP(t *testing.T) {
+	var methods []string
+	var replaced kubeService
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"metadata":{"resourceVersion":"42"},"spec":{"clusterIP":"10.0.0.7"}}`))
+		case "PUT":
+			assert.True(t, strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/sous/services/"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&replaced))
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	objs, err := buildObjects(*testDeployable(sous.ManifestKindService), "sous", 8080, nil)
+	require.NoError(t, err)
+
+	client := newRESTClient(srv.URL, "")
+	require.NoError(t, client.PutService(objs.Service))
+	assert.Equal(t, []string{"GET", "PUT"}, methods)
+	assert.Equal(t, "42", replaced.Metadata.ResourceVersion)
+	assert.Equal(t, "10.0.0.7", replaced.Spec.ClusterIP)
+}
+
+func TestRESTClient_DeleteService(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "Background", r.URL.Query().Get("propagationPolicy"))
+		paths = append(paths, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/gone") {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	client := newRESTClient(srv.URL, "")
+	assert.NoError(t, client.DeleteService("sous", "example"))
+	assert.NoError(t, client.DeleteService("sous", "gone"), "deleting what is not there")
+	assert.Equal(t, []string{"/api/v1/namespaces/sous/services/example", "/api/v1/namespaces/sous/services/gone"}, paths)
+}
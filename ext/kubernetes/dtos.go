@@ -0,0 +1,177 @@
+package kubernetes
+
+// The types in this file are the subset of the Kubernetes API objects that
+// Sous reads and writes. Field names and JSON tags follow the upstream API
+// (apps/v1, batch/v1beta1 and core/v1) so that they can be sent to and
+// received from an API server directly.
+
+type (
+	objectMeta struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace,omitempty"`
+		Labels            map[string]string `json:"labels,omitempty"`
+		Annotations       map[string]string `json:"annotations,omitempty"`
+		Generation        int64             `json:"generation,omitempty"`
+		ResourceVersion   string            `json:"resourceVersion,omitempty"`
+		CreationTimestamp string            `json:"creationTimestamp,omitempty"`
+	}
+
+	listMeta struct {
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	}
+
+	labelSelector struct {
+		MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	}
+
+	// kubeDeployment is an apps/v1 Deployment.
+	kubeDeployment struct {
+		APIVersion string           `json:"apiVersion"`
+		Kind       string           `json:"kind"`
+		Metadata   objectMeta       `json:"metadata"`
+		Spec       deploymentSpec   `json:"spec"`
+		Status     deploymentStatus `json:"status,omitempty"`
+	}
+
+	kubeDeploymentList struct {
+		Metadata listMeta         `json:"metadata"`
+		Items    []kubeDeployment `json:"items"`
+	}
+
+	deploymentSpec struct {
+		Replicas                *int32          `json:"replicas,omitempty"`
+		Selector                *labelSelector  `json:"selector,omitempty"`
+		Template                podTemplateSpec `json:"template"`
+		ProgressDeadlineSeconds *int32          `json:"progressDeadlineSeconds,omitempty"`
+	}
+
+	deploymentStatus struct {
+		ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+		Replicas           int32                 `json:"replicas,omitempty"`
+		UpdatedReplicas    int32                 `json:"updatedReplicas,omitempty"`
+		ReadyReplicas      int32                 `json:"readyReplicas,omitempty"`
+		AvailableReplicas  int32                 `json:"availableReplicas,omitempty"`
+		Conditions         []deploymentCondition `json:"conditions,omitempty"`
+	}
+
+	deploymentCondition struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+
+	// kubeCronJob is a batch/v1beta1 CronJob.
+	kubeCronJob struct {
+		APIVersion string      `json:"apiVersion"`
+		Kind       string      `json:"kind"`
+		Metadata   objectMeta  `json:"metadata"`
+		Spec       cronJobSpec `json:"spec"`
+	}
+
+	kubeCronJobList struct {
+		Metadata listMeta      `json:"metadata"`
+		Items    []kubeCronJob `json:"items"`
+	}
+
+	cronJobSpec struct {
+		Schedule    string          `json:"schedule"`
+		Suspend     *bool           `json:"suspend,omitempty"`
+		JobTemplate jobTemplateSpec `json:"jobTemplate"`
+	}
+
+	jobTemplateSpec struct {
+		Metadata objectMeta `json:"metadata,omitempty"`
+		Spec     jobSpec    `json:"spec"`
+	}
+
+	jobSpec struct {
+		Parallelism *int32          `json:"parallelism,omitempty"`
+		Template    podTemplateSpec `json:"template"`
+	}
+
+	// kubeService is a core/v1 Service.
+	kubeService struct {
+		APIVersion string      `json:"apiVersion"`
+		Kind       string      `json:"kind"`
+		Metadata   objectMeta  `json:"metadata"`
+		Spec       serviceSpec `json:"spec"`
+	}
+
+	serviceSpec struct {
+		ClusterIP string            `json:"clusterIP,omitempty"`
+		Selector  map[string]string `json:"selector,omitempty"`
+		Ports     []servicePort     `json:"ports,omitempty"`
+	}
+
+	servicePort struct {
+		Name       string `json:"name,omitempty"`
+		Port       int32  `json:"port"`
+		TargetPort int32  `json:"targetPort,omitempty"`
+	}
+
+	podTemplateSpec struct {
+		Metadata objectMeta `json:"metadata,omitempty"`
+		Spec     podSpec    `json:"spec"`
+	}
+
+	podSpec struct {
+		Containers    []container `json:"containers"`
+		Volumes       []volume    `json:"volumes,omitempty"`
+		RestartPolicy string      `json:"restartPolicy,omitempty"`
+	}
+
+	container struct {
+		Name           string               `json:"name"`
+		Image          string               `json:"image"`
+		Env            []envVar             `json:"env,omitempty"`
+		Ports          []containerPort      `json:"ports,omitempty"`
+		Resources      resourceRequirements `json:"resources,omitempty"`
+		VolumeMounts   []volumeMount        `json:"volumeMounts,omitempty"`
+		ReadinessProbe *probe               `json:"readinessProbe,omitempty"`
+	}
+
+	envVar struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	containerPort struct {
+		Name          string `json:"name,omitempty"`
+		ContainerPort int32  `json:"containerPort"`
+	}
+
+	resourceRequirements struct {
+		Limits   map[string]string `json:"limits,omitempty"`
+		Requests map[string]string `json:"requests,omitempty"`
+	}
+
+	volumeMount struct {
+		Name      string `json:"name"`
+		MountPath string `json:"mountPath"`
+		ReadOnly  bool   `json:"readOnly,omitempty"`
+	}
+
+	volume struct {
+		Name     string                `json:"name"`
+		HostPath *hostPathVolumeSource `json:"hostPath,omitempty"`
+	}
+
+	hostPathVolumeSource struct {
+		Path string `json:"path"`
+	}
+
+	probe struct {
+		HTTPGet             *httpGetAction `json:"httpGet,omitempty"`
+		InitialDelaySeconds int32          `json:"initialDelaySeconds,omitempty"`
+		TimeoutSeconds      int32          `json:"timeoutSeconds,omitempty"`
+		PeriodSeconds       int32          `json:"periodSeconds,omitempty"`
+		FailureThreshold    int32          `json:"failureThreshold,omitempty"`
+	}
+
+	httpGetAction struct {
+		Path   string `json:"path,omitempty"`
+		Port   int32  `json:"port"`
+		Scheme string `json:"scheme,omitempty"`
+	}
+)
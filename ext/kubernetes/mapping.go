@@ -0,0 +1,502 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ManagedByLabel is the label Sous puts on every object it manages, so that
+	// they can be distinguished from objects created by other tools.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "sous"
+
+	// NameLabel is the pod label used to select the pods of a deployment.
+	NameLabel = "sous.opentable.com/name"
+
+	annotationPrefix      = "sous.opentable.com/"
+	ownersAnnotation      = annotationPrefix + "owners"
+	kindAnnotation        = annotationPrefix + "kind"
+	connectInterval       = annotationPrefix + "startup-connect-interval"
+	failureStatuses       = annotationPrefix + "startup-failure-statuses"
+	skipCheckAnnotation   = annotationPrefix + "startup-skip-check"
+	portEnvPrefix         = "PORT"
+	containerName         = "app"
+	maxNameLen            = 63
+	defaultDeadlineSecond = int32(sous.SingularityDeployTimeout)
+)
+
+var illegalNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// MakeObjectName creates a Kubernetes object name (a DNS-1123 label) from a
+// sous.DeploymentID.
+func MakeObjectName(did sous.DeploymentID) (string, error) {
+	sn, err := did.ManifestID.Source.ShortName()
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	for _, p := range []string{sn, did.ManifestID.Source.Dir, did.ManifestID.Flavor, did.Cluster} {
+		p = strings.Trim(illegalNameChars.ReplaceAllString(strings.ToLower(p), "-"), "-")
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	digest := fmt.Sprintf("%x", did.Digest())[:8]
+	base := strings.Join(parts, "-")
+	if len(base) > maxNameLen-len(digest)-1 {
+		base = strings.TrimRight(base[:maxNameLen-len(digest)-1], "-")
+	}
+	return base + "-" + digest, nil
+}
+
+func objectLabels(name string) map[string]string {
+	return map[string]string{
+		ManagedByLabel: managedByValue,
+		NameLabel:      name,
+	}
+}
+
+func managedSelector() string {
+	return ManagedByLabel + "=" + managedByValue
+}
+
+// kubeObjects is the set of Kubernetes objects that represent a single Sous
+// deployment.
+type kubeObjects struct {
+	Deployment *kubeDeployment
+	CronJob    *kubeCronJob
+	Service    *kubeService
+}
+
+// buildObjects maps a Deployable onto the Kubernetes objects needed to run it:
+// a CronJob for scheduled jobs, otherwise a Deployment, plus a Service for
+// HTTP services. Secret references in its Env are resolved from secrets, and
+// recorded in an annotation.
+func buildObjects(d sous.Deployable, namespace string, basePort int32, secrets sous.SecretStore) (*kubeObjects, error) {
+	if d.BuildArtifact == nil {
+		return nil, &sous.MissingImageNameError{Cause: fmt.Errorf("Missing BuildArtifact on Deployable")}
+	}
+	dep := d.Deployment
+	name, err := MakeObjectName(dep.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	ann, err := deploymentAnnotations(dep)
+	if err != nil {
+		return nil, err
+	}
+	env, secretEnv, err := sous.ResolveSecrets(secrets, dep.Env)
+	if err != nil {
+		return nil, err
+	}
+	if len(secretEnv) > 0 {
+		refs, err := json.Marshal(secretEnv)
+		if err != nil {
+			return nil, err
+		}
+		ann[sous.SecretEnvLabel] = string(refs)
+	}
+	meta := objectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      objectLabels(name),
+		Annotations: ann,
+	}
+
+	pod := podTemplateSpec{
+		Metadata: objectMeta{Labels: objectLabels(name)},
+		Spec:     buildPodSpec(d, env, basePort),
+	}
+
+	objs := &kubeObjects{}
+	replicas := int32(dep.NumInstances)
+
+	if dep.Kind == sous.ManifestKindScheduled || dep.Kind == sous.ScheduledJob {
+		pod.Spec.RestartPolicy = "OnFailure"
+		objs.CronJob = &kubeCronJob{
+			APIVersion: "batch/v1beta1",
+			Kind:       "CronJob",
+			Metadata:   meta,
+			Spec: cronJobSpec{
+				Schedule: dep.Schedule,
+				JobTemplate: jobTemplateSpec{
+					Spec: jobSpec{Parallelism: &replicas, Template: pod},
+				},
+			},
+		}
+		return objs, nil
+	}
+
+	deadline := defaultDeadlineSecond
+	if dep.Startup.Timeout > 0 {
+		deadline = int32(dep.Startup.Timeout)
+	}
+	objs.Deployment = &kubeDeployment{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   meta,
+		Spec: deploymentSpec{
+			Replicas:                &replicas,
+			Selector:                &labelSelector{MatchLabels: map[string]string{NameLabel: name}},
+			Template:                pod,
+			ProgressDeadlineSeconds: &deadline,
+		},
+	}
+
+	if dep.Kind == sous.ManifestKindService && dep.Resources.Ports() > 0 {
+		svc := &kubeService{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Metadata:   meta,
+			Spec:       serviceSpec{Selector: map[string]string{NameLabel: name}},
+		}
+		for i := int32(0); i < dep.Resources.Ports(); i++ {
+			svc.Spec.Ports = append(svc.Spec.Ports, servicePort{
+				Name:       fmt.Sprintf("port%d", i),
+				Port:       basePort + i,
+				TargetPort: basePort + i,
+			})
+		}
+		objs.Service = svc
+	}
+	return objs, nil
+}
+
+// redacted returns a copy of objs whose secret environment variables are
+// replaced by their references, so that it can be logged.
+func (objs *kubeObjects) redacted() *kubeObjects {
+	r := *objs
+	if objs.Deployment != nil {
+		kd := *objs.Deployment
+		kd.Spec.Template.Spec = redactPodSpec(kd.Metadata.Annotations, kd.Spec.Template.Spec)
+		r.Deployment = &kd
+	}
+	if objs.CronJob != nil {
+		cj := *objs.CronJob
+		cj.Spec.JobTemplate.Spec.Template.Spec = redactPodSpec(cj.Metadata.Annotations, cj.Spec.JobTemplate.Spec.Template.Spec)
+		r.CronJob = &cj
+	}
+	return &r
+}
+
+func redactPodSpec(ann map[string]string, spec podSpec) podSpec {
+	containers := make([]container, len(spec.Containers))
+	for i, c := range spec.Containers {
+		env := map[string]string{}
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		refs := sous.SecretEnvRefs(ann, env)
+		c.Env = append([]envVar{}, c.Env...)
+		for j, e := range c.Env {
+			if ref, ok := refs[e.Name]; ok {
+				c.Env[j].Value = ref
+			}
+		}
+		containers[i] = c
+	}
+	spec.Containers = containers
+	return spec
+}
+
+func deploymentAnnotations(dep *sous.Deployment) (map[string]string, error) {
+	owners := dep.Owners.Slice()
+	sort.Strings(owners)
+	ann := map[string]string{
+		sous.ClusterNameLabel: dep.ClusterName,
+		sous.FlavorLabel:      dep.Flavor,
+		ownersAnnotation:      strings.Join(owners, ","),
+		kindAnnotation:        string(dep.Kind),
+	}
+	if dep.Startup.SkipCheck {
+		ann[skipCheckAnnotation] = "true"
+	}
+	if dep.Startup.ConnectInterval != 0 {
+		ann[connectInterval] = strconv.Itoa(dep.Startup.ConnectInterval)
+	}
+	if len(dep.Startup.CheckReadyFailureStatuses) > 0 {
+		ss := make([]string, len(dep.Startup.CheckReadyFailureStatuses))
+		for i, s := range dep.Startup.CheckReadyFailureStatuses {
+			ss[i] = strconv.Itoa(s)
+		}
+		ann[failureStatuses] = strings.Join(ss, ",")
+	}
+	if extra := extraResources(dep.Resources); len(extra) > 0 {
+		rj, err := json.Marshal(extra)
+		if err != nil {
+			return nil, err
+		}
+		ann[sous.ResourcesLabel] = string(rj)
+	}
+	return ann, nil
+}
+
+// kubeResources are the resources which are mapped onto the pod template, by
+// mapResources and as container ports.
+var kubeResources = map[string]struct{}{
+	"cpus": {}, "memory": {}, "ports": {},
+}
+
+// extraResources returns the resources in r which the pod template has no
+// field for, which are recorded in an annotation instead, so that the
+// deployments they are part of can be restored from Kubernetes.
+func extraResources(r sous.Resources) sous.Resources {
+	extra := sous.Resources{}
+	for name, v := range r {
+		if _, mapped := kubeResources[name]; !mapped {
+			extra[name] = v
+		}
+	}
+	return extra
+}
+
+func buildPodSpec(d sous.Deployable, env sous.Env, basePort int32) podSpec {
+	dep := d.Deployment
+	c := container{
+		Name:  containerName,
+		Image: d.BuildArtifact.DigestReference,
+		Resources: resourceRequirements{
+			Requests: mapResources(dep.Resources),
+			Limits:   mapResources(dep.Resources),
+		},
+	}
+
+	names := make([]string, 0, len(env))
+	for n := range env {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		c.Env = append(c.Env, envVar{Name: n, Value: env[n]})
+	}
+
+	for i := int32(0); i < dep.Resources.Ports(); i++ {
+		port := basePort + i
+		c.Ports = append(c.Ports, containerPort{Name: fmt.Sprintf("port%d", i), ContainerPort: port})
+		c.Env = append(c.Env, envVar{Name: fmt.Sprintf("%s%d", portEnvPrefix, i), Value: strconv.Itoa(int(port))})
+	}
+
+	spec := podSpec{}
+	for i, v := range dep.Volumes {
+		if v == nil {
+			continue
+		}
+		vn := fmt.Sprintf("volume%d", i)
+		spec.Volumes = append(spec.Volumes, volume{Name: vn, HostPath: &hostPathVolumeSource{Path: v.Host}})
+		c.VolumeMounts = append(c.VolumeMounts, volumeMount{
+			Name:      vn,
+			MountPath: v.Container,
+			ReadOnly:  v.Mode == sous.ReadOnly,
+		})
+	}
+
+	c.ReadinessProbe = mapStartup(dep.Startup, c.Ports)
+	spec.Containers = []container{c}
+	return spec
+}
+
+// mapResources produces a Kubernetes resource list from sous.Resources.
+// Memory in Sous is measured in MiB.
+func mapResources(r sous.Resources) map[string]string {
+	return map[string]string{
+		"cpu":    strconv.FormatFloat(r.Cpus(), 'f', -1, 64),
+		"memory": fmt.Sprintf("%dMi", int64(r.Memory())),
+	}
+}
+
+// parseCPU converts a Kubernetes CPU quantity (e.g. "250m" or "2") to a
+// number of CPUs. API servers normalise fractional CPUs to millicores.
+func parseCPU(q string) string {
+	if strings.HasSuffix(q, "m") {
+		if m, err := strconv.ParseFloat(strings.TrimSuffix(q, "m"), 64); err == nil {
+			return strconv.FormatFloat(m/1000, 'f', -1, 64)
+		}
+	}
+	return q
+}
+
+// parseMemoryMiB converts a Kubernetes memory quantity to MiB, the unit Sous
+// uses for memory.
+func parseMemoryMiB(q string) string {
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"Ki", 1.0 / 1024}, {"Mi", 1}, {"Gi", 1024}, {"Ti", 1024 * 1024},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(q, u.suffix) {
+			if n, err := strconv.ParseFloat(strings.TrimSuffix(q, u.suffix), 64); err == nil {
+				return strconv.FormatFloat(n*u.factor, 'f', -1, 64)
+			}
+		}
+	}
+	if n, err := strconv.ParseFloat(q, 64); err == nil {
+		return strconv.FormatFloat(n/(1024*1024), 'f', -1, 64)
+	}
+	return q
+}
+
+func mapStartup(s sous.Startup, ports []containerPort) *probe {
+	if s.SkipCheck || s.CheckReadyURIPath == "" {
+		return nil
+	}
+	p := &probe{
+		InitialDelaySeconds: int32(s.ConnectDelay),
+		TimeoutSeconds:      int32(s.CheckReadyURITimeout),
+		PeriodSeconds:       int32(s.CheckReadyInterval),
+		FailureThreshold:    int32(s.CheckReadyRetries),
+		HTTPGet: &httpGetAction{
+			Path:   s.CheckReadyURIPath,
+			Scheme: strings.ToUpper(s.CheckReadyProtocol),
+		},
+	}
+	if s.CheckReadyPortIndex < len(ports) {
+		p.HTTPGet.Port = ports[s.CheckReadyPortIndex].ContainerPort
+	}
+	return p
+}
+
+// unpackPodSpec reads the parts of a DeployConfig that are recorded in the pod
+// template back out of it, returning the image name.
+func unpackPodSpec(ann map[string]string, spec podSpec, dc *sous.DeployConfig) (string, error) {
+	if len(spec.Containers) == 0 {
+		return "", errors.Errorf("pod template has no containers")
+	}
+	c := spec.Containers[0]
+
+	dc.Resources = sous.Resources{}
+	if cpu, ok := c.Resources.Requests["cpu"]; ok {
+		dc.Resources["cpus"] = parseCPU(cpu)
+	}
+	if mem, ok := c.Resources.Requests["memory"]; ok {
+		dc.Resources["memory"] = parseMemoryMiB(mem)
+	}
+	dc.Resources["ports"] = strconv.Itoa(len(c.Ports))
+	if rj, ok := ann[sous.ResourcesLabel]; ok {
+		extra := sous.Resources{}
+		if err := json.Unmarshal([]byte(rj), &extra); err != nil {
+			return "", errors.Wrapf(err, "invalid %s annotation", sous.ResourcesLabel)
+		}
+		for name, v := range extra {
+			dc.Resources[name] = v
+		}
+	}
+
+	injected := map[string]string{}
+	for i, p := range c.Ports {
+		injected[fmt.Sprintf("%s%d", portEnvPrefix, i)] = strconv.Itoa(int(p.ContainerPort))
+	}
+	dc.Env = sous.Env{}
+	for _, e := range c.Env {
+		if v, ok := injected[e.Name]; ok && v == e.Value {
+			continue
+		}
+		dc.Env[e.Name] = e.Value
+	}
+	// Secrets are reported as the references they were resolved from, so
+	// that they match the intended deployment and are not revealed.
+	dc.Env = sous.RedactSecrets(dc.Env, sous.SecretEnvRefs(ann, dc.Env))
+
+	vols := map[string]string{}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			vols[v.Name] = v.HostPath.Path
+		}
+	}
+	for _, vm := range c.VolumeMounts {
+		mode := sous.ReadWrite
+		if vm.ReadOnly {
+			mode = sous.ReadOnly
+		}
+		dc.Volumes = append(dc.Volumes, &sous.Volume{
+			Host:      vols[vm.Name],
+			Container: vm.MountPath,
+			Mode:      mode,
+		})
+	}
+
+	dc.Startup = unpackStartup(ann, c)
+	return c.Image, nil
+}
+
+func unpackStartup(ann map[string]string, c container) sous.Startup {
+	s := sous.Startup{}
+	if ann[skipCheckAnnotation] == "true" {
+		s.SkipCheck = true
+	}
+	if ci, err := strconv.Atoi(ann[connectInterval]); err == nil {
+		s.ConnectInterval = ci
+	}
+	if fs := ann[failureStatuses]; fs != "" {
+		for _, code := range strings.Split(fs, ",") {
+			if n, err := strconv.Atoi(code); err == nil {
+				s.CheckReadyFailureStatuses = append(s.CheckReadyFailureStatuses, n)
+			}
+		}
+	}
+
+	p := c.ReadinessProbe
+	if p == nil || p.HTTPGet == nil {
+		return s
+	}
+	s.ConnectDelay = int(p.InitialDelaySeconds)
+	s.CheckReadyURITimeout = int(p.TimeoutSeconds)
+	s.CheckReadyInterval = int(p.PeriodSeconds)
+	s.CheckReadyRetries = int(p.FailureThreshold)
+	s.CheckReadyURIPath = p.HTTPGet.Path
+	s.CheckReadyProtocol = p.HTTPGet.Scheme
+	for i, port := range c.Ports {
+		if port.ContainerPort == p.HTTPGet.Port {
+			s.CheckReadyPortIndex = i
+		}
+	}
+	return s
+}
+
+func unpackAnnotations(ann map[string]string, dep *sous.Deployment) error {
+	cn, ok := ann[sous.ClusterNameLabel]
+	if !ok {
+		return errors.Errorf("object has no %s annotation", sous.ClusterNameLabel)
+	}
+	dep.ClusterName = cn
+	dep.Flavor = ann[sous.FlavorLabel]
+	dep.Kind = sous.ManifestKind(ann[kindAnnotation])
+	dep.Owners = sous.OwnerSet{}
+	for _, o := range strings.Split(ann[ownersAnnotation], ",") {
+		if o != "" {
+			dep.Owners.Add(o)
+		}
+	}
+	return nil
+}
+
+// determineStatus determines the sous.DeployStatus of a Kubernetes
+// Deployment, along with a message explaining any failure.
+func determineStatus(kd *kubeDeployment) (sous.DeployStatus, string) {
+	for _, c := range kd.Status.Conditions {
+		if c.Type == "Progressing" && c.Status == "False" && c.Reason == "ProgressDeadlineExceeded" {
+			return sous.DeployStatusFailed, fmt.Sprintf("Deploy failure: %q deployment/%s", c.Message, kd.Metadata.Name)
+		}
+	}
+	if kd.Status.ObservedGeneration < kd.Metadata.Generation {
+		return sous.DeployStatusPending, ""
+	}
+	want := int32(1)
+	if kd.Spec.Replicas != nil {
+		want = *kd.Spec.Replicas
+	}
+	if kd.Status.UpdatedReplicas < want || kd.Status.AvailableReplicas < want {
+		return sous.DeployStatusPending, ""
+	}
+	return sous.DeployStatusActive, ""
+}
@@ -2,6 +2,7 @@ package singularity
 
 import (
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
+	"github.com/opentable/swaggering"
 	"github.com/pkg/errors"
 )
 
@@ -121,6 +123,77 @@ func (sc *deployer) RunningDeployments(reg sous.Registry, clusters sous.Clusters
 	}
 }
 
+// RunningDeploymentsOf implements sous.SelectiveDeployer on deployer. It
+// reads only the Singularity requests of ids, rather than every request in
+// their clusters.
+func (sc *deployer) RunningDeploymentsOf(reg sous.Registry, clusters sous.Clusters, ids []sous.DeploymentID, intended sous.Deployments) (sous.DeployStates, error) {
+	deps := sous.NewDeployStates()
+	for _, id := range ids {
+		cluster, has := clusters[id.Cluster]
+		if !has {
+			continue
+		}
+		d, has := intended.Get(id)
+		if !has {
+			d = &sous.Deployment{
+				ClusterName: id.Cluster,
+				SourceID:    sous.SourceID{Location: id.ManifestID.Source},
+				Flavor:      id.ManifestID.Flavor,
+			}
+		}
+		reqID, err := deploymentRequestID(d)
+		if err != nil {
+			return deps, err
+		}
+		dep, err := sc.runningDeployment(reg, clusters, cluster.BaseURL, reqID)
+		if err != nil {
+			return deps, err
+		}
+		if dep != nil && dep.ID() == id {
+			deps.Add(dep)
+		}
+	}
+	return deps, nil
+}
+
+// runningDeployment returns the deployment run by the request with reqID in
+// the Singularity at url, or nil if there is no such request, or it is not a
+// deployment Sous manages.
+func (sc *deployer) runningDeployment(reg sous.Registry, clusters sous.Clusters, url, reqID string) (*sous.DeployState, error) {
+	client := sc.buildSingClient(url)
+	for count := uint(0); ; count++ {
+		rp, err := client.GetRequest(reqID, false) // = don't use the web cache
+		if isRequestNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting request %s", reqID)
+		}
+		dep, err := sc.assembleDeployState(reg, clusters, SingReq{url, client, rp})
+		switch {
+		case err == nil:
+			return dep, nil
+		case isMalformed(err):
+			logging.ReportError(sc.log, errors.Wrapf(err, "malformed"))
+			return nil, nil
+		case ignorableDeploy(sc.log, err):
+			messages.ReportLogFieldsMessage("Ignorable deploy.", logging.DebugLevel, sc.log)
+			return nil, nil
+		}
+		if _, ok := errors.Cause(err).(*canRetryRequest); !ok || count >= retryLimit {
+			return nil, err
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+}
+
+// isRequestNotFound returns true if err is Singularity's response to a
+// request for a request which does not exist.
+func isRequestNotFound(err error) bool {
+	rerr, is := errors.Cause(err).(*swaggering.ReqError)
+	return is && rerr.Status == http.StatusNotFound
+}
+
 const retryLimit = 3
 
 func (rc retryCounter) maybe(err error, reqCh chan SingReq) bool {
@@ -264,7 +337,7 @@ func (sc *deployer) depPipeline(
 
 func (sc *deployer) assembleDeployState(reg sous.Registry, clusters sous.Clusters, req SingReq) (*sous.DeployState, error) {
 	messages.ReportLogFieldsMessage("Assembling deploy state", logging.ExtraDebug1Level, sc.log, req.SourceURL, reqID(req.ReqParent))
-	tgt, err := BuildDeployment(reg, clusters, req, sc.log)
+	tgt, err := buildDeployment(reg, clusters, req, sc.states, sc.log)
 	messages.ReportLogFieldsMessage("Collected deployment", logging.ExtraDebug1Level, sc.log, tgt)
 	return &tgt, errors.Wrap(err, "Building deployment")
 }
@@ -49,6 +49,7 @@ type (
 		Client        rectificationClient
 		singFac       func(string) singClient
 		ReqsPerServer int
+		states        *sous.DeployStateCache
 		log           logging.LogSink
 	}
 
@@ -227,6 +228,20 @@ func (r *deployer) RectifySingleDelete(d *sous.DeployablePair) (err error) {
 	//return r.Client.DeleteRequest(d.Cluster.BaseURL, requestID, "deleting request for removed manifest")
 }
 
+// RemoveDeployment implements sous.DeploymentRemover on deployer, by deleting
+// the Singularity request which runs d.
+func (r *deployer) RemoveDeployment(d *sous.Deployment) error {
+	reqID, err := deploymentRequestID(d)
+	if err != nil {
+		return err
+	}
+	err = r.Client.DeleteRequest(d.Cluster.BaseURL, reqID, "removing "+d.ID().String())
+	if isRequestNotFound(err) {
+		return nil
+	}
+	return err
+}
+
 func (r *deployer) RectifySingleModification(pair *sous.DeployablePair) (err error) {
 	different, diffs := pair.Post.Deployment.Diff(pair.Prior.Deployment)
 	if different {
@@ -355,19 +370,10 @@ func computeDeployID(d *sous.Deployable) string {
 }
 
 func computeDeployIDFromUUID(d *sous.Deployable, uid uuid.UUID) string {
-	var versionTrunc string
 	uuidEntire := stripDeployID(uid.String())
-	versionSansMeta := stripMetadata(d.Deployment.SourceID.Version.String())
-	versionEntire := sanitizeDeployID(versionSansMeta)
-
-	if len(versionEntire) > maxVersionLen {
-		versionTrunc = versionEntire[0:maxVersionLen]
-	} else {
-		versionTrunc = versionEntire
-	}
 
 	depBase := strings.Join([]string{
-		versionTrunc,
+		deployIDVersion(d.Deployment),
 		uuidEntire,
 	}, "_")
 
@@ -376,3 +382,15 @@ func computeDeployIDFromUUID(d *sous.Deployable, uid uuid.UUID) string {
 	}
 	return depBase
 }
+
+// deployIDVersion returns the part of the IDs of the deploys of d which
+// records its version.
+func deployIDVersion(d *sous.Deployment) string {
+	versionSansMeta := stripMetadata(d.SourceID.Version.String())
+	versionEntire := sanitizeDeployID(versionSansMeta)
+
+	if len(versionEntire) > maxVersionLen {
+		return versionEntire[0:maxVersionLen]
+	}
+	return versionEntire
+}
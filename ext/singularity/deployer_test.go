@@ -283,7 +283,7 @@ func matchedPair(t *testing.T, startDep *sous.Deployment) *sous.DeployablePair {
 	req := &dtos.SingularityRequest{}
 	jsonRoundtrip(t, aReq, req)
 
-	aDepReq, err := buildDeployRequest(deployable, reqID, depID, map[string]string{}, ls)
+	aDepReq, err := buildDeployRequest(deployable, reqID, depID, map[string]string{}, nil, ls)
 	assert.NoError(t, err)
 	assert.NotNil(t, aDepReq)
 
@@ -687,3 +687,21 @@ func TestOptMaxHTTPReqsPerServer(t *testing.T) {
 		t.Fatalf("got %d; want %d", deployer2.ReqsPerServer, x)
 	}
 }
+
+func TestRemoveDeployment(t *testing.T) {
+	drc := sous.NewDummyRectificationClient()
+	deployer := NewDeployer(drc, logging.SilentLogSet())
+
+	d := &sous.Deployment{
+		SourceID:     sous.MustNewSourceID("fake.tld/org/project", "", "0.0.1"),
+		Flavor:       "canary",
+		ClusterName:  "cluster",
+		Cluster:      &sous.Cluster{BaseURL: "http://cluster"},
+		DeployConfig: sous.DeployConfig{SingularityRequestID: "project-canary"},
+	}
+	require.NoError(t, deployer.(sous.DeploymentRemover).RemoveDeployment(d))
+	if assert.Len(t, drc.Deleted, 1) {
+		assert.Equal(t, "http://cluster", drc.Deleted[0].Cluster)
+		assert.Equal(t, "project-canary", drc.Deleted[0].Reqid)
+	}
+}
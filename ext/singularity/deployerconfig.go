@@ -1,5 +1,7 @@
 package singularity
 
+import "github.com/opentable/sous/lib"
+
 // DeployerOption is an option for configuring singularity deployers.
 type DeployerOption func(*deployer)
 
@@ -8,3 +10,10 @@ type DeployerOption func(*deployer)
 func OptMaxHTTPReqsPerServer(n int) DeployerOption {
 	return func(d *deployer) { d.ReqsPerServer = n }
 }
+
+// OptDeployStates has the deployer take the status of deploys which
+// Singularity still reports as pending from states, which Singularity
+// webhooks keep up to date.
+func OptDeployStates(states *sous.DeployStateCache) DeployerOption {
+	return func(d *deployer) { d.states = states }
+}
@@ -26,6 +26,7 @@ type (
 		req       SingReq
 		registry  sous.ImageLabeller
 		reqID     string
+		states    *sous.DeployStateCache
 		log       logging.LogSink
 	}
 
@@ -105,8 +106,14 @@ func (db *deploymentBuilder) isRetryable(err error) bool {
 // BuildDeployment does all the work to collect the data for a Deployment
 // from Singularity based on the initial SingularityRequest.
 func BuildDeployment(reg sous.ImageLabeller, clusters sous.Clusters, req SingReq, log logging.LogSink) (sous.DeployState, error) {
+	return buildDeployment(reg, clusters, req, nil, log)
+}
+
+// buildDeployment is BuildDeployment, taking the status of deploys which
+// Singularity still reports as pending from states, if they have finished.
+func buildDeployment(reg sous.ImageLabeller, clusters sous.Clusters, req SingReq, states *sous.DeployStateCache, log logging.LogSink) (sous.DeployState, error) {
 	messages.ReportLogFieldsMessage("Build Deployment", logging.ExtraDebug1Level, log, req.ReqParent)
-	db := deploymentBuilder{registry: reg, clusters: clusters, req: req, log: log}
+	db := deploymentBuilder{registry: reg, clusters: clusters, req: req, states: states, log: log}
 	return db.Target, db.canRetry(db.completeConstruction())
 }
 
@@ -130,6 +137,7 @@ func (db *deploymentBuilder) completeConstruction() error {
 		wrapError(db.unpackDeployConfig, "Could not convert data from a SingularityDeploy to a sous.Deployment."),
 		wrapError(db.determineManifestKind, "Could not determine SingularityRequestType."),
 		wrapError(db.extractSchedule, "Could not determine Singularity schedule."),
+		wrapError(db.applyReportedStatus, "Could not apply reported deploy status."),
 	)
 }
 
@@ -178,7 +186,8 @@ func (db *deploymentBuilder) getFullRequestParent() error {
 
 // If there is a Pending deploy, as far as Sous is concerned, that's "to
 // come" - we optimistically assume it will become Active, and that's the
-// Deployment we should consider live.
+// Deployment we should consider live. A webhook may since have reported how
+// it finished: see applyReportedStatus.
 func (db *deploymentBuilder) determineDeployStatus() error {
 	rp := db.req.ReqParent
 	if rp == nil {
@@ -394,8 +403,10 @@ func (db *deploymentBuilder) restoreFromMetadata() error {
 }
 
 func (db *deploymentBuilder) unpackDeployConfig() error {
-	db.Target.Env = db.deploy.Env
-	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.deploy.Env)
+	// Secrets are reported as the references they were resolved from, so
+	// that they match the intended deployment, and are never logged.
+	db.Target.Env = sous.RedactSecrets(db.deploy.Env, sous.SecretEnvRefs(db.deploy.Metadata, db.deploy.Env))
+	messages.ReportLogFieldsMessage("UnpackDeployConfig", logging.ExtraDebug1Level, db.log, db.reqID, db.Target.Env)
 	if db.Target.Env == nil {
 		db.Target.Env = make(map[string]string)
 	}
@@ -408,6 +419,18 @@ func (db *deploymentBuilder) unpackDeployConfig() error {
 	db.Target.Resources["cpus"] = fmt.Sprintf("%f", singRez.Cpus)
 	db.Target.Resources["memory"] = fmt.Sprintf("%f", singRez.MemoryMb)
 	db.Target.Resources["ports"] = fmt.Sprintf("%d", singRez.NumPorts)
+	if singRez.DiskMb > 0 {
+		db.Target.Resources["disk"] = fmt.Sprintf("%f", singRez.DiskMb)
+	}
+	if rj, ok := db.deploy.Metadata[sous.ResourcesLabel]; ok {
+		extra := sous.Resources{}
+		if err := json.Unmarshal([]byte(rj), &extra); err != nil {
+			return malformedResponse{fmt.Sprintf("Deploy metadata has invalid %s: %v", sous.ResourcesLabel, err)}
+		}
+		for name, v := range extra {
+			db.Target.Resources[name] = v
+		}
+	}
 
 	db.Target.NumInstances = int(db.request.Instances)
 	db.Target.Owners = make(sous.OwnerSet)
@@ -488,3 +511,26 @@ func (db *deploymentBuilder) extractSchedule() error {
 	}
 	return nil
 }
+
+// applyReportedStatus takes the status of a deploy Singularity reports as
+// pending from db.states, if a webhook has reported that the same version
+// has since finished deploying.
+func (db *deploymentBuilder) applyReportedStatus() error {
+	if db.states == nil || db.Target.Status != sous.DeployStatusPending {
+		return nil
+	}
+	ds, has := db.states.Get(db.Target.ID())
+	if !has || !ds.SourceID.Version.Equals(db.Target.SourceID.Version) {
+		return nil
+	}
+	switch ds.Status {
+	default:
+		return nil
+	case sous.DeployStatusActive, sous.DeployStatusFailed:
+	}
+	db.Target.Status = ds.Status
+	if db.Target.ExecutorMessage == "" {
+		db.Target.ExecutorMessage = ds.ExecutorMessage
+	}
+	return nil
+}
@@ -157,6 +157,12 @@ func TestBuildDeployment(t *testing.T) {
 			Metadata: map[string]string{
 				"com.opentable.sous.clustername": "left",
 				"com.opentable.sous.flavor":      "vanilla",
+				"com.opentable.sous.secret_env":  `{"DB_PASSWORD":"secret://db#password"}`,
+				"com.opentable.sous.resources":   `{"network-mbps":"100"}`,
+			},
+			Env: map[string]string{
+				"DB_PASSWORD": "hunter2",
+				"DB_USER":     "app",
 			},
 
 			Healthcheck: &dtos.HealthcheckOptions{
@@ -176,7 +182,7 @@ func TestBuildDeployment(t *testing.T) {
 					},
 				},
 			},
-			Resources: &dtos.Resources{},
+			Resources: &dtos.Resources{DiskMb: 2048},
 		},
 	}
 	fakeSing, fsc := newSingClientSpy()
@@ -212,6 +218,12 @@ func TestBuildDeployment(t *testing.T) {
 	assert.Equal(t, actual.Startup.CheckReadyURITimeout, 350)
 
 	assert.Equal(t, actual.Startup.Timeout, 700)
+
+	assert.Equal(t, sous.Env{"DB_PASSWORD": "secret://db#password", "DB_USER": "app"}, actual.Env)
+
+	assert.True(t, sous.Resources{
+		"cpus": "0", "memory": "0", "ports": "0", "disk": "2Gi", "network-mbps": "100",
+	}.Equal(actual.Resources), "resources: %v", actual.Resources)
 }
 
 func TestBuildDeployment_failed_deploy(t *testing.T) {
@@ -458,3 +470,34 @@ func TestConstructDeployment(t *testing.T) {
 	}
 }
 */
+
+func TestBuildDeployment_applyReportedStatus(t *testing.T) {
+	states := sous.NewDeployStateCache()
+	db := &deploymentBuilder{states: states}
+	db.Target.Status = sous.DeployStatusPending
+	db.Target.ClusterName = "test"
+	db.Target.SourceID = sous.MustParseSourceID("github.com/opentable/one,1.0.0")
+
+	reported := &sous.DeployState{Status: sous.DeployStatusFailed, ExecutorMessage: "TASK_FAILED: exited 1"}
+	reported.Deployment = db.Target.Deployment
+	reported.SourceID = sous.MustParseSourceID("github.com/opentable/one,0.9.0")
+	states.Update(db.Target.ID(), reported)
+	if err := db.applyReportedStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if db.Target.Status != sous.DeployStatusPending {
+		t.Errorf("Expected the status of another version to be ignored, got %s", db.Target.Status)
+	}
+
+	reported.SourceID = db.Target.SourceID
+	states.Update(db.Target.ID(), reported)
+	if err := db.applyReportedStatus(); err != nil {
+		t.Fatal(err)
+	}
+	if db.Target.Status != sous.DeployStatusFailed {
+		t.Errorf("Expected Status failed (%s), got %s", sous.DeployStatusFailed, db.Target.Status)
+	}
+	if db.Target.ExecutorMessage != "TASK_FAILED: exited 1" {
+		t.Errorf("Expected the reported message, got %q", db.Target.ExecutorMessage)
+	}
+}
@@ -1,6 +1,7 @@
 package singularity
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -39,6 +40,9 @@ type (
 		sync.RWMutex
 		labeller sous.ImageLabeller
 		log      logging.LogSink
+		// Secrets resolves the secret references in the Env of deployments.
+		// If it is nil, deployments which refer to secrets cannot be deployed.
+		Secrets sous.SecretStore
 	}
 
 	singularityTaskData struct {
@@ -62,9 +66,29 @@ func mapResources(r sous.Resources) dtoMap {
 		"Cpus":     r.Cpus(),
 		"MemoryMb": r.Memory(),
 		"NumPorts": int32(r.Ports()),
+		"DiskMb":   r.Disk(),
 	}
 }
 
+// singularityResources are the resources mapResources maps to fields of
+// dto.Resources.
+var singularityResources = map[string]struct{}{
+	"cpus": {}, "memory": {}, "ports": {}, "disk": {},
+}
+
+// extraResources returns the resources in r which Singularity has no field
+// for, which are recorded in the metadata of deploys instead, so that the
+// deployments they are part of can be restored from Singularity.
+func extraResources(r sous.Resources) sous.Resources {
+	extra := sous.Resources{}
+	for name, v := range r {
+		if _, mapped := singularityResources[name]; !mapped {
+			extra[name] = v
+		}
+	}
+	return extra
+}
+
 // Deploy sends requests to Singularity to make a deployment happen
 func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 	if d.BuildArtifact == nil {
@@ -78,12 +102,12 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 	}
 	messages.ReportLogFieldsMessage("Build deploying instance", logging.DebugLevel, ra.log, d, reqID)
 
-	depReq, err := buildDeployRequest(d, reqID, depID, labels, ra.log)
+	depReq, err := buildDeployRequest(d, reqID, depID, labels, ra.Secrets, ra.log)
 	if err != nil {
 		return err
 	}
 
-	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, ra.log, depReq)
+	messages.ReportLogFieldsMessage("Sending Deploy req to singularity Client", logging.DebugLevel, ra.log, redactDeployRequest(depReq))
 
 	pathParamMap := map[string]interface{}{}
 
@@ -101,21 +125,39 @@ func (ra *RectiAgent) Deploy(d sous.Deployable, reqID, depID string) error {
 	err = ra.getSingularityRequester(clusterURI).DTORequest("singularity-deploy", response, "POST", "/api/deploys", pathParamMap, queryParamMap, depReq)
 
 	if err != nil {
-		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, ra.log, depReq, reqID, err, response)
+		messages.ReportLogFieldsMessage("Singularity client returned following error", logging.WarningLevel, ra.log, redactDeployRequest(depReq), reqID, err, response)
 	}
 	return err
 }
 
-func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string, log logging.LogSink) (*dtos.SingularityDeployRequest, error) {
+func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[string]string, secrets sous.SecretStore, log logging.LogSink) (*dtos.SingularityDeployRequest, error) {
 	var depReq swaggering.Fielder
 	dockerImage := d.BuildArtifact.DigestReference
 	r := d.Deployment.DeployConfig.Resources
-	e := d.Deployment.DeployConfig.Env
 	vols := d.Deployment.DeployConfig.Volumes
 
 	metadata[sous.ClusterNameLabel] = d.Deployment.ClusterName
 	metadata[sous.FlavorLabel] = d.Deployment.Flavor
 
+	e, secretEnv, err := sous.ResolveSecrets(secrets, d.Deployment.DeployConfig.Env)
+	if err != nil {
+		return nil, err
+	}
+	if len(secretEnv) > 0 {
+		refs, err := json.Marshal(secretEnv)
+		if err != nil {
+			return nil, err
+		}
+		metadata[sous.SecretEnvLabel] = string(refs)
+	}
+	if extra := extraResources(r); len(extra) > 0 {
+		rj, err := json.Marshal(extra)
+		if err != nil {
+			return nil, err
+		}
+		metadata[sous.ResourcesLabel] = string(rj)
+	}
+
 	dockerInfo, err := swaggering.LoadMap(&dtos.SingularityDockerInfo{}, dtoMap{
 		"Image":   dockerImage,
 		"Network": dtos.SingularityDockerInfoSingularityDockerNetworkTypeBRIDGE, //defaulting to all bridge
@@ -177,7 +219,7 @@ func buildDeployRequest(d sous.Deployable, reqID, depID string, metadata map[str
 	if err != nil {
 		return nil, err
 	}
-	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, log, dep, ci, dockerInfo)
+	messages.ReportLogFieldsMessage("Deploy", logging.DebugLevel, log, redactDeploy(dep.(*dtos.SingularityDeploy)), ci, dockerInfo)
 
 	depReq, err = swaggering.LoadMap(&dtos.SingularityDeployRequest{}, dtoMap{"Deploy": dep, "Message": message})
 
@@ -358,3 +400,28 @@ func (ra *RectiAgent) singularityClient(url string) *singularity.Client {
 	ra.singClients[url] = cl
 	return cl
 }
+
+// redactDeploy returns a copy of dep whose secret environment variables are
+// replaced by their references, so that it can be logged.
+func redactDeploy(dep *dtos.SingularityDeploy) *dtos.SingularityDeploy {
+	if dep == nil {
+		return nil
+	}
+	refs := sous.SecretEnvRefs(dep.Metadata, dep.Env)
+	if len(refs) == 0 {
+		return dep
+	}
+	redacted := *dep
+	redacted.Env = sous.RedactSecrets(dep.Env, refs)
+	return &redacted
+}
+
+// redactDeployRequest is redactDeploy for a whole deploy request.
+func redactDeployRequest(req *dtos.SingularityDeployRequest) *dtos.SingularityDeployRequest {
+	if req == nil || req.Deploy == nil {
+		return req
+	}
+	redacted := *req
+	redacted.Deploy = redactDeploy(req.Deploy)
+	return &redacted
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	singularity "github.com/opentable/go-singularity"
@@ -173,7 +175,7 @@ func TestContainerStartupOptions(t *testing.T) {
 
 	ls, _ := logging.NewLogSinkSpy()
 
-	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, ls)
+	dr, err := buildDeployRequest(d, "fake-request-id", "fake-deploy-id", map[string]string{}, nil, ls)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -188,6 +190,72 @@ func TestContainerStartupOptions(t *testing.T) {
 
 }
 
+func TestBuildDeployRequest_secrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "db"), []byte(`{"password":"hunter2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := sous.Deployable{
+		Deployment:    &sous.Deployment{},
+		BuildArtifact: &sous.BuildArtifact{},
+	}
+	d.ClusterName = "cluster"
+	d.Env = sous.Env{"DB_PASSWORD": "secret://db#password", "DB_USER": "app"}
+	ls, _ := logging.NewLogSinkSpy()
+
+	_, err = buildDeployRequest(d, "request-id", "deploy-id", map[string]string{}, nil, ls)
+	assert.Error(t, err, "secrets cannot be resolved without a store")
+
+	dr, err := buildDeployRequest(d, "request-id", "deploy-id", map[string]string{}, sous.NewFileSecretStore(dir), ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "hunter2", dr.Deploy.Env["DB_PASSWORD"])
+	assert.Equal(t, "app", dr.Deploy.Env["DB_USER"])
+	assert.Equal(t, "secret://db#password", d.Env["DB_PASSWORD"], "the deployment itself is unchanged")
+
+	redacted := redactDeployRequest(dr)
+	assert.Equal(t, "secret://db#password", redacted.Deploy.Env["DB_PASSWORD"])
+	assert.Equal(t, "app", redacted.Deploy.Env["DB_USER"])
+	assert.Equal(t, "hunter2", dr.Deploy.Env["DB_PASSWORD"], "redaction copies the request")
+
+	d.Env["DB_PASSWORD"] = "secret://db#missing"
+	_, err = buildDeployRequest(d, "request-id", "deploy-id", map[string]string{}, sous.NewFileSecretStore(dir), ls)
+	assert.Error(t, err)
+}
+
+func TestBuildDeployRequest_resources(t *testing.T) {
+	d := sous.Deployable{
+		Deployment:    &sous.Deployment{},
+		BuildArtifact: &sous.BuildArtifact{},
+	}
+	d.ClusterName = "cluster"
+	d.Resources = sous.Resources{
+		"cpus":         "250m",
+		"memory":       "1Gi",
+		"ports":        "2",
+		"disk":         "512",
+		"network-mbps": "100",
+	}
+	ls, _ := logging.NewLogSinkSpy()
+
+	metadata := map[string]string{}
+	dr, err := buildDeployRequest(d, "request-id", "deploy-id", metadata, nil, ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0.25, dr.Deploy.Resources.Cpus)
+	assert.Equal(t, float64(1024), dr.Deploy.Resources.MemoryMb)
+	assert.Equal(t, int32(2), dr.Deploy.Resources.NumPorts)
+	assert.Equal(t, float64(512), dr.Deploy.Resources.DiskMb)
+	assert.JSONEq(t, `{"network-mbps":"100"}`, dr.Deploy.Metadata[sous.ResourcesLabel])
+}
+
 func TestDeploy_MockedSingularity(t *testing.T) {
 
 	checkReadyPath := "/use-this-route"
@@ -40,7 +40,7 @@ func TestBuildDeployRequest(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, map[string]string{}, ls)
+	}, rID, dID, map[string]string{}, nil, ls)
 	require.NoError(err)
 	assert.NotNil(dr)
 	assert.Equal(dr.Deploy.RequestId, rID)
@@ -78,7 +78,7 @@ func TestDockerMetadataSet(t *testing.T) {
 				BaseURL: "http://cluster",
 			},
 		},
-	}, rID, dID, md, ls)
+	}, rID, dID, md, nil, ls)
 
 	if err != nil {
 		t.Fatal(err)
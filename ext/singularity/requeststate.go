@@ -41,7 +41,7 @@ func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.
 		ReqParent: reqParent,
 	}
 
-	tgt, err := BuildDeployment(reg, clusters, singReq, r.log)
+	tgt, err := buildDeployment(reg, clusters, singReq, r.states, r.log)
 
 	tgt.SchedulerURL = fmt.Sprintf("%s/request/%s", url, reqID)
 	return &tgt, errors.Wrapf(err, "getting request state")
@@ -49,8 +49,13 @@ func (r *deployer) Status(reg sous.Registry, clusters sous.Clusters, pair *sous.
 
 func (r *deployer) getRequestID(d *sous.Deployable) (string, error) {
 	// TODO: add a cache of known Deployables to their Requests (and current state...)
+	return deploymentRequestID(d.Deployment)
+}
+
+// deploymentRequestID returns the ID of the Singularity request which runs d.
+func deploymentRequestID(d *sous.Deployment) (string, error) {
 	if d.SingularityRequestID != "" {
 		return d.SingularityRequestID, nil
 	}
-	return computeRequestID(d)
+	return MakeRequestID(d.ID())
 }
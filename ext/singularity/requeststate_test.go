@@ -1,12 +1,17 @@
 package singularity
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/nyarly/spies"
 	"github.com/opentable/go-singularity/dtos"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/swaggering"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatus(t *testing.T) {
@@ -47,7 +52,46 @@ func TestStatus(t *testing.T) {
 
 }
 
-func singClientFixture() singClient {
+func TestRunningDeploymentsOf(t *testing.T) {
+	ls, _ := logging.NewLogSinkSpy()
+	dep := &deployer{
+		log: ls,
+	}
+
+	found := sous.DeploymentID{ManifestID: sous.MustParseManifestID("github.com/example/test"), Cluster: "cluster-1"}
+	gone := sous.DeploymentID{ManifestID: sous.MustParseManifestID("github.com/example/gone"), Cluster: "cluster-1"}
+	goneReqID, err := MakeRequestID(gone)
+	require.NoError(t, err)
+
+	sing := singClientFixture(goneReqID)
+	dep.SetSingularityFactory(func(string) singClient {
+		return sing
+	})
+
+	reg, rc := sous.NewRegistrySpy()
+	rc.MatchMethod("ImageLabels", spies.AnyArgs, map[string]string{
+		"com.opentable.sous.repo_url":    "github.com/example/test",
+		"com.opentable.sous.version":     "1.2.3",
+		"com.opentable.sous.revision":    "",
+		"com.opentable.sous.repo_offset": "",
+	}, nil)
+
+	clusters := sous.Clusters{
+		"cluster-1": &sous.Cluster{BaseURL: "http://sing,example.com"},
+	}
+
+	states, err := dep.RunningDeploymentsOf(reg, clusters, []sous.DeploymentID{found, gone}, sous.NewDeployments())
+	require.NoError(t, err)
+	assert.Equal(t, 1, states.Len())
+	_, has := states.Get(found)
+	assert.True(t, has)
+
+	assert.Len(t, sing.(singClientSpy).spy.CallsTo("GetRequests"), 0, "not every request in the cluster")
+}
+
+// singClientFixture returns a singClient which responds to requests for any
+// request, except those with missing IDs, which are not found.
+func singClientFixture(missing ...string) singClient {
 	req := &dtos.SingularityRequestParent{
 		Request: &dtos.SingularityRequest{
 			RequestType: dtos.SingularityRequestRequestTypeSERVICE,
@@ -81,6 +125,12 @@ func singClientFixture() singClient {
 	pds := dtos.SingularityPendingDeployList{}
 
 	sing, c := newSingClientSpy()
+	for _, id := range missing {
+		id := id
+		c.MatchMethod("GetRequest", func(args mock.Arguments) bool {
+			return args.String(0) == id
+		}, (*dtos.SingularityRequestParent)(nil), &swaggering.ReqError{Status: http.StatusNotFound})
+	}
 	c.MatchMethod("GetRequest", spies.AnyArgs, req, nil)
 	c.MatchMethod("GetDeploy", spies.AnyArgs, dh, nil)
 	c.MatchMethod("GetDeploys", spies.AnyArgs, dhl, nil)
@@ -0,0 +1,396 @@
+package singularity
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentable/go-singularity"
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+// webhookRereadInterval is how long a webhook which matches no intended
+// deployment waits before the intended deployments are read again for it, so
+// that Singularity requests Sous does not manage do not each cause a read.
+const webhookRereadInterval = 10 * time.Second
+
+// WebhookPath is the path on a Sous server which receives webhooks from
+// Singularity.
+const WebhookPath = "/webhooks/singularity"
+
+// The kinds of webhook Singularity sends, as named in the "type" query
+// parameter of the URLs they are registered with.
+const (
+	WebhookRequest = "request"
+	WebhookDeploy  = "deploy"
+	WebhookTask    = "task"
+)
+
+type (
+	// Webhooks receives the webhooks Singularity sends when its requests,
+	// deploys and tasks change. It keeps the state of the deployments they
+	// concern in States, and reports changes which need resolving to Events,
+	// so that neither has to wait for Singularity to be polled.
+	Webhooks struct {
+		// URL is the URL of the Sous server, which Singularity sends
+		// webhooks to at WebhookPath.
+		URL string
+		// Secret is sent by Singularity with each webhook, so that webhooks
+		// from elsewhere are rejected.
+		Secret      string
+		StateReader sous.StateReader
+		States      *sous.DeployStateCache
+		Events      *sous.ResolveEvents
+		log         logging.LogSink
+		clientFac   func(url string) webhookClient
+
+		sync.RWMutex
+		// requests maps Singularity request IDs to the intended deployments
+		// they run, as of readAt.
+		requests map[string]*sous.Deployment
+		readAt   time.Time
+		// misses holds the request IDs and versions of webhooks not matched
+		// by requests since readAt.
+		misses map[webhookMiss]struct{}
+	}
+
+	// webhookMiss is a request ID and deploy version not found among the
+	// intended deployments.
+	webhookMiss struct {
+		reqID, version string
+	}
+
+	// webhookClient abstracts the Singularity API used to register webhooks.
+	webhookClient interface {
+		GetActiveWebhooks() (dtos.SingularityWebhookList, error)
+		AddWebhook(*dtos.SingularityWebhook) (string, error)
+	}
+
+	// requestWebhook is the part of a REQUEST webhook Sous uses.
+	requestWebhook struct {
+		Request struct {
+			ID string `json:"id"`
+		} `json:"request"`
+		EventType string `json:"eventType"`
+	}
+
+	// deployWebhook is the part of a DEPLOY webhook Sous uses.
+	deployWebhook struct {
+		DeployMarker struct {
+			RequestID string `json:"requestId"`
+			DeployID  string `json:"deployId"`
+		} `json:"deployMarker"`
+		EventType    string        `json:"eventType"`
+		DeployResult *deployResult `json:"deployResult,omitempty"`
+	}
+
+	// deployResult is the result of a finished deploy.
+	deployResult struct {
+		DeployState string `json:"deployState"`
+		Message     string `json:"message,omitempty"`
+	}
+
+	// taskWebhook is the part of a TASK webhook Sous uses.
+	taskWebhook struct {
+		Task struct {
+			TaskID struct {
+				RequestID string `json:"requestId"`
+				DeployID  string `json:"deployId"`
+			} `json:"taskId"`
+		} `json:"task"`
+		TaskUpdate struct {
+			TaskState     string `json:"taskState"`
+			StatusMessage string `json:"statusMessage,omitempty"`
+		} `json:"taskUpdate"`
+	}
+)
+
+// NewWebhooks returns a Webhooks receiving webhooks for the Sous server at
+// serverURL, for the deployments intended by sr.
+func NewWebhooks(serverURL, secret string, sr sous.StateReader, states *sous.DeployStateCache, events *sous.ResolveEvents, ls logging.LogSink) *Webhooks {
+	return &Webhooks{
+		URL:         serverURL,
+		Secret:      secret,
+		StateReader: sr,
+		States:      states,
+		Events:      events,
+		log:         ls,
+		requests:    map[string]*sous.Deployment{},
+		misses:      map[webhookMiss]struct{}{},
+	}
+}
+
+// webhookURL returns the URL webhooks of kind are sent to, at the Sous server
+// at serverURL.
+func webhookURL(serverURL, secret, kind string) string {
+	q := url.Values{"type": {kind}}
+	if secret != "" {
+		q.Set("secret", secret)
+	}
+	return strings.TrimSuffix(serverURL, "/") + WebhookPath + "?" + q.Encode()
+}
+
+func (w *Webhooks) client(baseURL string) webhookClient {
+	if w.clientFac == nil {
+		return singularity.NewClient(baseURL, w.log)
+	}
+	return w.clientFac(baseURL)
+}
+
+// Register registers webhooks of each kind with each Singularity cluster,
+// unless they are already registered.
+func (w *Webhooks) Register() error {
+	state, err := w.StateReader.ReadState()
+	if err != nil {
+		return errors.Wrap(err, "reading clusters to register webhooks with")
+	}
+	registered := map[string]struct{}{}
+	failed := 0
+	for _, name := range state.Defs.Clusters.Names() {
+		c := state.Defs.Clusters[name]
+		if c.ClusterKind() != sous.ClusterKindSingularity {
+			continue
+		}
+		if _, done := registered[c.BaseURL]; done {
+			continue
+		}
+		registered[c.BaseURL] = struct{}{}
+		if err := w.register(w.client(c.BaseURL)); err != nil {
+			logging.ReportError(w.log, errors.Wrapf(err, "registering webhooks with %s", c.BaseURL))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("registering webhooks failed with %d of %d Singularity servers", failed, len(registered))
+	}
+	return nil
+}
+
+func (w *Webhooks) register(client webhookClient) error {
+	active, err := client.GetActiveWebhooks()
+	if err != nil {
+		return err
+	}
+	for _, kind := range []dtos.SingularityWebhookWebhookType{
+		dtos.SingularityWebhookWebhookTypeREQUEST,
+		dtos.SingularityWebhookWebhookTypeDEPLOY,
+		dtos.SingularityWebhookWebhookTypeTASK,
+	} {
+		uri := webhookURL(w.URL, w.Secret, strings.ToLower(string(kind)))
+		if hasWebhook(active, uri) {
+			continue
+		}
+		if _, err := client.AddWebhook(&dtos.SingularityWebhook{Uri: uri, Type: kind}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasWebhook(hooks dtos.SingularityWebhookList, uri string) bool {
+	for _, h := range hooks {
+		if h != nil && h.Uri == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// Authentic returns true if secret is the secret webhooks are registered
+// with.
+func (w *Webhooks) Authentic(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(w.Secret)) == 1
+}
+
+// Receive updates States and Events with the webhook of kind, whose JSON is
+// body. Webhooks about requests which are not intended by Sous are ignored.
+func (w *Webhooks) Receive(kind string, body []byte) error {
+	switch kind {
+	default:
+		return errors.Errorf("unknown kind of webhook %q", kind)
+	case WebhookRequest:
+		var hook requestWebhook
+		if err := json.Unmarshal(body, &hook); err != nil {
+			return errors.Wrap(err, "parsing request webhook")
+		}
+		return w.receiveRequest(hook)
+	case WebhookDeploy:
+		var hook deployWebhook
+		if err := json.Unmarshal(body, &hook); err != nil {
+			return errors.Wrap(err, "parsing deploy webhook")
+		}
+		return w.receiveDeploy(hook)
+	case WebhookTask:
+		var hook taskWebhook
+		if err := json.Unmarshal(body, &hook); err != nil {
+			return errors.Wrap(err, "parsing task webhook")
+		}
+		return w.receiveTask(hook)
+	}
+}
+
+func (w *Webhooks) receiveRequest(hook requestWebhook) error {
+	d, err := w.intended(hook.Request.ID, "")
+	if err != nil || d == nil {
+		return err
+	}
+	w.report(fmt.Sprintf("Singularity request %s %s", hook.Request.ID, hook.EventType))
+	if hook.EventType == "DELETED" {
+		w.States.Remove(d.ID())
+	}
+	w.Events.DeploymentsChanged(d.ID())
+	return nil
+}
+
+func (w *Webhooks) receiveDeploy(hook deployWebhook) error {
+	reqID, deployID := hook.DeployMarker.RequestID, hook.DeployMarker.DeployID
+	d, err := w.intended(reqID, deployVersion(deployID))
+	if err != nil || d == nil {
+		return err
+	}
+	status, message := sous.DeployStatusPending, ""
+	if hook.DeployResult != nil {
+		status = deployResultStatus(hook.DeployResult.DeployState)
+		message = hook.DeployResult.Message
+	}
+	w.report(fmt.Sprintf("Singularity deploy %s of %s %s: %s", deployID, reqID, hook.EventType, status))
+
+	if deployIDVersion(d) != deployVersion(deployID) {
+		// The deploy is not of the intended version, which is all the state
+		// known here, so its state is left for the resolver to find.
+		w.States.Remove(d.ID())
+	} else {
+		ds := &sous.DeployState{
+			Deployment:      *d.Clone(),
+			Status:          status,
+			ExecutorMessage: message,
+		}
+		if d.Cluster != nil {
+			ds.SchedulerURL, _ = MakeRequestURL(d.Cluster.BaseURL, reqID)
+		}
+		w.States.Update(d.ID(), ds)
+	}
+	w.Events.DeploymentsChanged(d.ID())
+	return nil
+}
+
+// deployResultStatus returns the status of a deploy with the Singularity
+// deploy state ds.
+func deployResultStatus(ds string) sous.DeployStatus {
+	switch ds {
+	default:
+		return sous.DeployStatusFailed
+	case "SUCCEEDED":
+		return sous.DeployStatusActive
+	case "WAITING", "CANCELING":
+		return sous.DeployStatusPending
+	}
+}
+
+func (w *Webhooks) receiveTask(hook taskWebhook) error {
+	switch hook.TaskUpdate.TaskState {
+	default:
+		return nil
+	case "TASK_FAILED", "TASK_LOST", "TASK_KILLED", "TASK_ERROR":
+	}
+	d, err := w.intended(hook.Task.TaskID.RequestID, "")
+	if err != nil || d == nil {
+		return err
+	}
+	ds, has := w.States.Get(d.ID())
+	if !has || deployIDVersion(&ds.Deployment) != deployVersion(hook.Task.TaskID.DeployID) {
+		return nil
+	}
+	ds.ExecutorMessage = fmt.Sprintf("%s: %s", hook.TaskUpdate.TaskState, hook.TaskUpdate.StatusMessage)
+	w.States.Update(d.ID(), ds)
+	return nil
+}
+
+// intended returns the intended deployment run by the Singularity request
+// with reqID, or nil if Sous intends none. If the deployment is unknown, or
+// does not deploy version, the intended deployments are read again, since
+// they may have changed since they were last read, unless the same was found
+// by a read within webhookRereadInterval.
+func (w *Webhooks) intended(reqID, version string) (*sous.Deployment, error) {
+	w.RLock()
+	d, known := w.known(reqID, version)
+	w.RUnlock()
+	if known {
+		return d, nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	// The state may have been read while this webhook waited for the lock.
+	if d, known := w.known(reqID, version); known {
+		return d, nil
+	}
+	if err := w.readIntended(); err != nil {
+		return nil, err
+	}
+	d, has := w.requests[reqID]
+	if !has || (version != "" && deployIDVersion(d) != version) {
+		w.misses[webhookMiss{reqID, version}] = struct{}{}
+	}
+	return d, nil
+}
+
+// known returns the intended deployment run by reqID, and true if it need not
+// be read again: see intended. It must be called with w locked for reading.
+func (w *Webhooks) known(reqID, version string) (*sous.Deployment, bool) {
+	d, has := w.requests[reqID]
+	if has && (version == "" || deployIDVersion(d) == version) {
+		return d, true
+	}
+	if _, missed := w.misses[webhookMiss{reqID, version}]; missed && time.Since(w.readAt) < webhookRereadInterval {
+		return d, true
+	}
+	return nil, false
+}
+
+// readIntended indexes the intended Singularity deployments by request ID. It
+// must be called with w locked.
+func (w *Webhooks) readIntended() error {
+	state, err := w.StateReader.ReadState()
+	if err != nil {
+		return errors.Wrap(err, "reading state to find webhook deployment")
+	}
+	gdm, err := state.Deployments()
+	if err != nil {
+		return errors.Wrap(err, "reading state to find webhook deployment")
+	}
+	w.requests = map[string]*sous.Deployment{}
+	w.misses = map[webhookMiss]struct{}{}
+	w.readAt = time.Now()
+	for _, d := range gdm.Snapshot() {
+		if d.Cluster.ClusterKind() != sous.ClusterKindSingularity {
+			continue
+		}
+		if id, err := deploymentRequestID(d); err == nil {
+			w.requests[id] = d
+		}
+	}
+	return nil
+}
+
+// deployVersion returns the version recorded in the Singularity deployID of
+// a Sous deploy: see deployIDVersion.
+func deployVersion(deployID string) string {
+	i := strings.LastIndex(deployID, "_")
+	if i < 0 {
+		return ""
+	}
+	return deployID[:i]
+}
+
+func (w *Webhooks) report(msg string) {
+	messages.ReportLogFieldsMessage(msg, logging.DebugLevel, w.log)
+}
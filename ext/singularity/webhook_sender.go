@@ -0,0 +1,76 @@
+package singularity
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// A WebhookSender sends webhooks as Singularity does, for testing receivers
+// of them without a Singularity server.
+type WebhookSender struct {
+	// URL and Secret are those of the Sous server the webhooks are sent to,
+	// as for Webhooks.
+	URL, Secret string
+	Client      *http.Client
+}
+
+// NewWebhookSender returns a WebhookSender sending webhooks to the Sous server
+// at serverURL, with secret.
+func NewWebhookSender(serverURL, secret string) *WebhookSender {
+	return &WebhookSender{URL: serverURL, Secret: secret, Client: http.DefaultClient}
+}
+
+// SendRequest sends a request webhook reporting eventType, e.g. "UPDATED" or
+// "DELETED", for the request with reqID.
+func (s *WebhookSender) SendRequest(reqID, eventType string) error {
+	var hook requestWebhook
+	hook.Request.ID = reqID
+	hook.EventType = eventType
+	return s.send(WebhookRequest, hook)
+}
+
+// SendDeploy sends a deploy webhook reporting eventType, "STARTING" or
+// "FINISHED", for the deploy with deployID of the request with reqID. A
+// FINISHED deploy ends with deployState, e.g. "SUCCEEDED" or "FAILED", and
+// message.
+func (s *WebhookSender) SendDeploy(reqID, deployID, eventType, deployState, message string) error {
+	var hook deployWebhook
+	hook.DeployMarker.RequestID = reqID
+	hook.DeployMarker.DeployID = deployID
+	hook.EventType = eventType
+	if eventType == "FINISHED" {
+		hook.DeployResult = &deployResult{DeployState: deployState, Message: message}
+	}
+	return s.send(WebhookDeploy, hook)
+}
+
+// SendTask sends a task webhook reporting that a task of the deploy with
+// deployID, of the request with reqID, is in taskState, e.g. "TASK_RUNNING"
+// or "TASK_FAILED", with message.
+func (s *WebhookSender) SendTask(reqID, deployID, taskState, message string) error {
+	var hook taskWebhook
+	hook.Task.TaskID.RequestID = reqID
+	hook.Task.TaskID.DeployID = deployID
+	hook.TaskUpdate.TaskState = taskState
+	hook.TaskUpdate.StatusMessage = message
+	return s.send(WebhookTask, hook)
+}
+
+func (s *WebhookSender) send(kind string, hook interface{}) error {
+	body, err := json.Marshal(hook)
+	if err != nil {
+		return err
+	}
+	rz, err := s.Client.Post(webhookURL(s.URL, s.Secret, kind), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "sending %s webhook", kind)
+	}
+	defer rz.Body.Close()
+	if rz.StatusCode >= 300 {
+		return errors.Errorf("sending %s webhook: %s", kind, rz.Status)
+	}
+	return nil
+}
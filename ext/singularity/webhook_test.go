@@ -0,0 +1,181 @@
+package singularity
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentable/go-singularity/dtos"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookClientSpy struct {
+	active dtos.SingularityWebhookList
+	added  []*dtos.SingularityWebhook
+}
+
+func (c *webhookClientSpy) GetActiveWebhooks() (dtos.SingularityWebhookList, error) {
+	return c.active, nil
+}
+
+func (c *webhookClientSpy) AddWebhook(hook *dtos.SingularityWebhook) (string, error) {
+	c.added = append(c.added, hook)
+	return "", nil
+}
+
+func webhooksFixture(t *testing.T) (*Webhooks, *sous.DummyStateManager, *sous.Deployment) {
+	sm := sous.NewDummyStateManager()
+	sm.State = sous.DefaultStateFixture()
+	gdm, err := sm.State.Deployments()
+	require.NoError(t, err)
+	d := gdm.Snapshot()[sous.DeploymentID{ManifestID: sm.State.Manifests.Keys()[0], Cluster: "cluster1"}]
+	require.NotNil(t, d)
+
+	w := NewWebhooks("https://sous.example.com", "s3cret", sm,
+		sous.NewDeployStateCache(), sous.NewResolveEvents(), logging.SilentLogSet())
+	return w, sm, d
+}
+
+// sendTo returns a WebhookSender sending webhooks to w.
+func sendTo(t *testing.T, w *Webhooks) (*WebhookSender, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if !w.Authentic(q.Get("secret")) {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		if err := w.Receive(q.Get("type"), body); err != nil {
+			t.Error(err)
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	return NewWebhookSender(srv.URL, w.Secret), srv.Close
+}
+
+func TestWebhooks_Register(t *testing.T) {
+	w, _, _ := webhooksFixture(t)
+	client := &webhookClientSpy{active: dtos.SingularityWebhookList{
+		{Uri: "https://sous.example.com/webhooks/singularity?secret=s3cret&type=request"},
+	}}
+	var urls []string
+	w.clientFac = func(url string) webhookClient {
+		urls = append(urls, url)
+		return client
+	}
+
+	require.NoError(t, w.Register())
+	assert.Equal(t, []string{"127.0.0.1:5000"}, urls, "each Singularity should be registered with once")
+	if assert.Len(t, client.added, 2) {
+		assert.Equal(t, "https://sous.example.com/webhooks/singularity?secret=s3cret&type=deploy", client.added[0].Uri)
+		assert.Equal(t, dtos.SingularityWebhookWebhookTypeDEPLOY, client.added[0].Type)
+		assert.Equal(t, dtos.SingularityWebhookWebhookTypeTASK, client.added[1].Type)
+	}
+}
+
+func TestWebhooks_Receive(t *testing.T) {
+	w, _, d := webhooksFixture(t)
+	sender, stop := sendTo(t, w)
+	defer stop()
+
+	reqID, err := deploymentRequestID(d)
+	require.NoError(t, err)
+	deployID := computeDeployIDFromUUID(&sous.Deployable{Deployment: d}, uuid.NewV4())
+
+	_, changed := w.States.Version()
+	require.NoError(t, sender.SendDeploy(reqID, deployID, "STARTING", "", ""))
+	select {
+	case <-changed:
+	default:
+		t.Fatal("deploy state not updated")
+	}
+	assert.Equal(t, []sous.DeploymentID{d.ID()}, w.Events.Affected(sous.NewDeployments()))
+	ds, has := w.States.Get(d.ID())
+	require.True(t, has)
+	assert.Equal(t, sous.DeployStatusPending, ds.Status)
+	assert.Equal(t, d.SourceID, ds.SourceID)
+	assert.Equal(t, "127.0.0.1:5000/request/"+reqID, ds.SchedulerURL)
+
+	require.NoError(t, sender.SendDeploy(reqID, deployID, "FINISHED", "FAILED", "health checks failed"))
+	ds, _ = w.States.Get(d.ID())
+	assert.Equal(t, sous.DeployStatusFailed, ds.Status)
+	assert.Equal(t, "health checks failed", ds.ExecutorMessage)
+
+	require.NoError(t, sender.SendTask(reqID, deployID, "TASK_RUNNING", ""))
+	require.NoError(t, sender.SendTask(reqID, deployID, "TASK_FAILED", "exited 1"))
+	ds, _ = w.States.Get(d.ID())
+	assert.Equal(t, "TASK_FAILED: exited 1", ds.ExecutorMessage)
+	assert.Len(t, w.Events.Affected(sous.NewDeployments()), 1, "only the deploy should need resolving")
+
+	require.NoError(t, sender.SendRequest(reqID, "DELETED"))
+	_, has = w.States.Get(d.ID())
+	assert.False(t, has)
+
+	require.NoError(t, sender.SendRequest("not-sous", "UPDATED"))
+	assert.Len(t, w.Events.Affected(sous.NewDeployments()), 1, "only the deleted request should need resolving")
+}
+
+func TestWebhooks_Receive_newVersion(t *testing.T) {
+	w, sm, d := webhooksFixture(t)
+	sender, stop := sendTo(t, w)
+	defer stop()
+
+	reqID, err := deploymentRequestID(d)
+	require.NoError(t, err)
+	require.NoError(t, sender.SendRequest(reqID, "UPDATED"))
+
+	// The request is now known; a deploy of a version intended since should
+	// still be recognised.
+	m, _ := sm.State.Manifests.Get(d.ManifestID())
+	spec := m.Deployments[d.ClusterName]
+	spec.Version = semv.MustParse("2.0.0")
+	m.Deployments[d.ClusterName] = spec
+	next := d.Clone()
+	next.SourceID.Version = spec.Version
+
+	deployID := computeDeployIDFromUUID(&sous.Deployable{Deployment: next}, uuid.NewV4())
+	require.NoError(t, sender.SendDeploy(reqID, deployID, "FINISHED", "SUCCEEDED", ""))
+	ds, has := w.States.Get(d.ID())
+	require.True(t, has)
+	assert.Equal(t, sous.DeployStatusActive, ds.Status)
+	assert.Equal(t, "2.0.0", ds.SourceID.Version.String())
+}
+
+func TestWebhooks_Receive_unknownRequests(t *testing.T) {
+	w, sm, d := webhooksFixture(t)
+	sender, stop := sendTo(t, w)
+	defer stop()
+
+	reqID, err := deploymentRequestID(d)
+	require.NoError(t, err)
+	deployID := computeDeployIDFromUUID(&sous.Deployable{Deployment: d}, uuid.NewV4())
+	require.NoError(t, sender.SendDeploy(reqID, deployID, "STARTING", "", ""))
+	assert.Equal(t, 1, sm.ReadCount)
+
+	// Known requests, and requests Sous does not manage, are not read again.
+	require.NoError(t, sender.SendDeploy(reqID, deployID, "FINISHED", "SUCCEEDED", ""))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sender.SendTask("not-sous", "not-sous-deploy", "TASK_FAILED", ""))
+	}
+	assert.Equal(t, 2, sm.ReadCount)
+
+	// Once the interval has passed, they are.
+	w.readAt = w.readAt.Add(-webhookRereadInterval)
+	require.NoError(t, sender.SendTask("not-sous", "not-sous-deploy", "TASK_FAILED", ""))
+	assert.Equal(t, 3, sm.ReadCount)
+}
+
+func TestWebhookSender_wrongSecret(t *testing.T) {
+	w, _, _ := webhooksFixture(t)
+	sender, stop := sendTo(t, w)
+	defer stop()
+	sender.Secret = "guess"
+	assert.Error(t, sender.SendRequest("anything", "UPDATED"))
+}
@@ -89,7 +89,7 @@ func (dsm *DiskStateManager) ReadState() (*sous.State, error) {
 			return nil, fmt.Errorf("manifest %q is nil", k)
 		}
 		for clusterName := range m.Deployments {
-			if _, ok := s.Defs.Clusters[clusterName]; !ok {
+			if _, ok := s.Defs.Clusters[clusterName]; !ok && s.Defs.ClusterGroups[clusterName] == nil {
 				return s, errors.Errorf("cluster %q not defined (from manifest %q)",
 					clusterName, k)
 			}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresAuditLog provides the sous.AuditLog interface by storing entries in
+// the audit_log table.
+type PostgresAuditLog struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresAuditLog creates a new PostgresAuditLog.
+func NewPostgresAuditLog(db *sql.DB, log logging.LogSink) *PostgresAuditLog {
+	return &PostgresAuditLog{db: db, log: log}
+}
+
+const insertAuditSQL = `insert into audit_log
+	(user_name, user_email, trace_id, recorded_at, repos, clusters, changes)
+	values ($1, $2, $3, $4, $5, $6, $7)`
+
+// Record implements sous.AuditLog on PostgresAuditLog.
+func (l *PostgresAuditLog) Record(e sous.AuditEntry) error {
+	start := time.Now()
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	repos, clusters := []string{}, []string{}
+	for _, c := range e.Changes {
+		repos = append(repos, c.ManifestID.Source.Repo)
+		clusters = append(clusters, c.Clusters...)
+	}
+	_, err = l.db.ExecContext(context.TODO(), insertAuditSQL,
+		e.User.Name, e.User.Email, string(e.TraceID), e.Time,
+		pq.Array(repos), pq.Array(clusters), string(changes))
+	count := 1
+	if err != nil {
+		count = 0
+	}
+	sqlgen.ReportInsert(l.log, start, "audit_log", insertAuditSQL, count, err)
+	return errors.Wrap(err, "recording audit entry")
+}
+
+// Query implements sous.AuditLog on PostgresAuditLog. Entries are selected by
+// the database, and their Changes narrowed by f.Filter.
+func (l *PostgresAuditLog) Query(f sous.AuditFilter) ([]sous.AuditEntry, error) {
+	start := time.Now()
+	var conds []string
+	var args []interface{}
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Repo != "" {
+		where("$%d = any(repos)", f.Repo)
+	}
+	if f.Cluster != "" {
+		where("$%d = any(clusters)", f.Cluster)
+	}
+	if f.User != "" {
+		args = append(args, f.User)
+		conds = append(conds, fmt.Sprintf("(user_name = $%[1]d or user_email = $%[1]d)", len(args)))
+	}
+	if !f.Since.IsZero() {
+		where("recorded_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		where("recorded_at <= $%d", f.Until)
+	}
+	query := "select user_name, user_email, trace_id, recorded_at, changes from audit_log"
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+	query += " order by entry_id"
+
+	rows, err := l.db.QueryContext(context.TODO(), query, args...)
+	if err != nil {
+		sqlgen.ReportSelect(l.log, start, "audit_log", query, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []sous.AuditEntry{}
+	for rows.Next() {
+		var traceID, changes string
+		e := sous.AuditEntry{}
+		if err := rows.Scan(&e.User.Name, &e.User.Email, &traceID, &e.Time, &changes); err != nil {
+			sqlgen.ReportSelect(l.log, start, "audit_log", query, len(entries), err)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+			return nil, errors.Wrap(err, "audit entry changes")
+		}
+		e.TraceID = sous.TraceID(traceID)
+		if e, ok := f.Filter(e); ok {
+			entries = append(entries, e)
+		}
+	}
+	err = rows.Err()
+	sqlgen.ReportSelect(l.log, start, "audit_log", query, len(entries), err)
+	return entries, err
+}
@@ -0,0 +1,63 @@
+// +build integration
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresAuditLog(t *testing.T) {
+	db := sous.SetupDB(t)
+	defer sous.ReleaseDB(t)
+
+	l := NewPostgresAuditLog(db, logging.SilentLogSet())
+	change := func(repo string, clusters ...string) sous.ManifestChange {
+		return sous.ManifestChange{
+			ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: repo}},
+			Clusters:   clusters,
+			Diffs:      []string{"manifest added"},
+		}
+	}
+	now := time.Now()
+	require.NoError(t, l.Record(sous.AuditEntry{
+		User:    testUser,
+		TraceID: "trace-1",
+		Time:    now.Add(-time.Hour),
+		Changes: []sous.ManifestChange{change("github.com/example/a", "cluster1", "cluster2")},
+	}))
+	require.NoError(t, l.Record(sous.AuditEntry{
+		User: sous.User{Name: "Someone Else"},
+		Time: now,
+		Changes: []sous.ManifestChange{
+			change("github.com/example/a", "cluster2"),
+			change("github.com/example/b", "cluster1"),
+		},
+	}))
+
+	all, err := l.Query(sous.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, sous.TraceID("trace-1"), all[0].TraceID)
+	assert.Equal(t, []string{"cluster1", "cluster2"}, all[0].Changes[0].Clusters)
+
+	byCluster, err := l.Query(sous.AuditFilter{Cluster: "cluster1"})
+	require.NoError(t, err)
+	require.Len(t, byCluster, 2)
+	require.Len(t, byCluster[1].Changes, 1)
+	assert.Equal(t, "github.com/example/b", byCluster[1].Changes[0].ManifestID.Source.Repo)
+
+	byUser, err := l.Query(sous.AuditFilter{User: testUser.Email, Since: now.Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Len(t, byUser, 1)
+
+	recent, err := l.Query(sous.AuditFilter{Repo: "github.com/example/a", Since: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Len(t, recent[0].Changes, 1)
+}
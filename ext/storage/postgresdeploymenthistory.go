@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+// PostgresDeploymentHistory provides the sous.DeploymentHistory interface by
+// storing entries in the deployment_history table.
+type PostgresDeploymentHistory struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresDeploymentHistory creates a new PostgresDeploymentHistory.
+func NewPostgresDeploymentHistory(db *sql.DB, log logging.LogSink) *PostgresDeploymentHistory {
+	return &PostgresDeploymentHistory{db: db, log: log}
+}
+
+const insertHistorySQL = `insert into deployment_history
+	(repo, dir, flavor, cluster, versionstring, deploy_spec, user_name, user_email, recorded_at, outcome)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+// Record implements sous.DeploymentHistory on PostgresDeploymentHistory.
+func (h *PostgresDeploymentHistory) Record(entries ...sous.DeploymentHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ctx := context.TODO()
+	start := time.Now()
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "opening transaction")
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		spec, err := json.Marshal(e.DeploySpec)
+		if err != nil {
+			return err
+		}
+		did := e.DeploymentID
+		if _, err := tx.ExecContext(ctx, insertHistorySQL,
+			did.ManifestID.Source.Repo, did.ManifestID.Source.Dir, did.ManifestID.Flavor, did.Cluster,
+			e.DeploySpec.Version.String(), string(spec),
+			e.User.Name, e.User.Email, e.Time, string(e.Outcome),
+		); err != nil {
+			sqlgen.ReportInsert(h.log, start, "deployment_history", insertHistorySQL, 0, err)
+			return errors.Wrapf(err, "recording history for %s", did)
+		}
+	}
+	err = tx.Commit()
+	sqlgen.ReportInsert(h.log, start, "deployment_history", insertHistorySQL, len(entries), err)
+	return err
+}
+
+const updateOutcomeSQL = `update deployment_history set outcome = $1
+	where history_id = (
+		select max(history_id) from deployment_history
+		where repo = $2 and dir = $3 and flavor = $4 and cluster = $5 and versionstring = $6
+	)`
+
+// SetOutcome implements sous.DeploymentHistory on PostgresDeploymentHistory.
+func (h *PostgresDeploymentHistory) SetOutcome(did sous.DeploymentID, version semv.Version, outcome sous.DeploymentOutcome) error {
+	start := time.Now()
+	res, err := h.db.ExecContext(context.TODO(), updateOutcomeSQL, string(outcome),
+		did.ManifestID.Source.Repo, did.ManifestID.Source.Dir, did.ManifestID.Flavor, did.Cluster,
+		version.String())
+	count := 0
+	if err == nil {
+		n, _ := res.RowsAffected()
+		count = int(n)
+	}
+	sqlgen.ReportUpdate(h.log, start, "deployment_history", updateOutcomeSQL, count, err)
+	return err
+}
+
+const selectHistorySQL = `select deploy_spec, user_name, user_email, recorded_at, outcome
+	from deployment_history
+	where repo = $1 and dir = $2 and flavor = $3 and cluster = $4
+	order by history_id desc`
+
+// History implements sous.DeploymentHistory on PostgresDeploymentHistory.
+func (h *PostgresDeploymentHistory) History(did sous.DeploymentID) ([]sous.DeploymentHistoryEntry, error) {
+	start := time.Now()
+	rows, err := h.db.QueryContext(context.TODO(), selectHistorySQL,
+		did.ManifestID.Source.Repo, did.ManifestID.Source.Dir, did.ManifestID.Flavor, did.Cluster)
+	if err != nil {
+		sqlgen.ReportSelect(h.log, start, "deployment_history", selectHistorySQL, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []sous.DeploymentHistoryEntry{}
+	for rows.Next() {
+		var spec, outcome string
+		e := sous.DeploymentHistoryEntry{DeploymentID: did}
+		if err := rows.Scan(&spec, &e.User.Name, &e.User.Email, &e.Time, &outcome); err != nil {
+			sqlgen.ReportSelect(h.log, start, "deployment_history", selectHistorySQL, len(entries), err)
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(spec), &e.DeploySpec); err != nil {
+			return nil, errors.Wrapf(err, "history for %s", did)
+		}
+		e.Outcome = sous.DeploymentOutcome(outcome)
+		entries = append(entries, e)
+	}
+	err = rows.Err()
+	sqlgen.ReportSelect(h.log, start, "deployment_history", selectHistorySQL, len(entries), err)
+	return entries, err
+}
@@ -0,0 +1,49 @@
+// +build integration
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresDeploymentHistory(t *testing.T) {
+	db := sous.SetupDB(t)
+	defer sous.ReleaseDB(t)
+
+	h := NewPostgresDeploymentHistory(db, logging.SilentLogSet())
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/a"}},
+		Cluster:    "cluster1",
+	}
+	entry := func(v string) sous.DeploymentHistoryEntry {
+		return sous.DeploymentHistoryEntry{
+			DeploymentID: did,
+			DeploySpec: sous.DeploySpec{
+				DeployConfig: sous.DeployConfig{NumInstances: 2},
+				Version:      semv.MustParse(v),
+			},
+			User:    testUser,
+			Time:    time.Now(),
+			Outcome: sous.OutcomePending,
+		}
+	}
+
+	require.NoError(t, h.Record(entry("1.0.0"), entry("2.0.0")))
+	require.NoError(t, h.SetOutcome(did, semv.MustParse("1.0.0"), sous.OutcomeSucceeded))
+
+	entries, err := h.History(did)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2.0.0", entries[0].DeploySpec.Version.String())
+	assert.Equal(t, sous.OutcomePending, entries[0].Outcome)
+	assert.Equal(t, sous.OutcomeSucceeded, entries[1].Outcome)
+	assert.Equal(t, 2, entries[1].DeploySpec.NumInstances)
+	assert.Equal(t, testUser.Name, entries[1].User.Name)
+}
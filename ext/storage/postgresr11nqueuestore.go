@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresR11nQueueStore provides the sous.R11nQueueStore interface by
+// storing rectifications in the r11n_queue table.
+type PostgresR11nQueueStore struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresR11nQueueStore creates a new PostgresR11nQueueStore.
+func NewPostgresR11nQueueStore(db *sql.DB, log logging.LogSink) *PostgresR11nQueueStore {
+	return &PostgresR11nQueueStore{db: db, log: log}
+}
+
+const insertR11nSQL = `insert into r11n_queue (r11n_id, deployment_id, post, prior)
+	values ($1, $2, $3, $4)
+	on conflict (r11n_id) do nothing`
+
+// Queued implements sous.R11nQueueStore on PostgresR11nQueueStore.
+func (s *PostgresR11nQueueStore) Queued(sr sous.StoredR11n) error {
+	start := time.Now()
+	post, err := nullJSON(sr.Post)
+	if err != nil {
+		return err
+	}
+	prior, err := nullJSON(sr.Prior)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(context.TODO(), insertR11nSQL,
+		string(sr.ID), sr.DeploymentID.String(), post, prior)
+	count := 0
+	if err == nil {
+		count = 1
+	}
+	sqlgen.ReportInsert(s.log, start, "r11n_queue", insertR11nSQL, count, err)
+	return errors.Wrapf(err, "recording queued deploy %s", sr.ID)
+}
+
+const doneR11nSQL = `update r11n_queue set resolution = $1, done_at = now()
+	where r11n_id = $2`
+
+// pruneR11nsSQL deletes the done rectifications for a deployment beyond the
+// most recent sous.MaxRefsPerR11nQueue, which an R11nQueue would no longer
+// remember either.
+const pruneR11nsSQL = `delete from r11n_queue
+	where deployment_id = $1 and done_at is not null and r11n_seq not in (
+		select r11n_seq from r11n_queue
+		where deployment_id = $1 and done_at is not null
+		order by r11n_seq desc limit $2
+	)`
+
+// Done implements sous.R11nQueueStore on PostgresR11nQueueStore.
+func (s *PostgresR11nQueueStore) Done(id sous.R11nID, rez sous.DiffResolution) error {
+	start := time.Now()
+	rb, err := json.Marshal(rez)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(context.TODO(), doneR11nSQL, string(rb), string(id))
+	count := 0
+	if err == nil {
+		n, _ := res.RowsAffected()
+		count = int(n)
+	}
+	sqlgen.ReportUpdate(s.log, start, "r11n_queue", doneR11nSQL, count, err)
+	if err != nil {
+		return errors.Wrapf(err, "recording done deploy %s", id)
+	}
+
+	start = time.Now()
+	res, err = s.db.ExecContext(context.TODO(), pruneR11nsSQL,
+		rez.DeploymentID.String(), sous.MaxRefsPerR11nQueue)
+	count = 0
+	if err == nil {
+		n, _ := res.RowsAffected()
+		count = int(n)
+	}
+	sqlgen.ReportUpdate(s.log, start, "r11n_queue", pruneR11nsSQL, count, err)
+	return errors.Wrapf(err, "pruning done deploys for %s", rez.DeploymentID)
+}
+
+const selectPendingR11nsSQL = `select r11n_id, deployment_id, post, prior, resolution
+	from r11n_queue
+	where done_at is null
+	order by r11n_seq`
+
+// Pending implements sous.R11nQueueStore on PostgresR11nQueueStore.
+func (s *PostgresR11nQueueStore) Pending() ([]sous.StoredR11n, error) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(context.TODO(), selectPendingR11nsSQL)
+	if err != nil {
+		sqlgen.ReportSelect(s.log, start, "r11n_queue", selectPendingR11nsSQL, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := []sous.StoredR11n{}
+	for rows.Next() {
+		sr, err := scanStoredR11n(rows)
+		if err != nil {
+			sqlgen.ReportSelect(s.log, start, "r11n_queue", selectPendingR11nsSQL, len(pending), err)
+			return nil, err
+		}
+		pending = append(pending, sr)
+	}
+	err = rows.Err()
+	sqlgen.ReportSelect(s.log, start, "r11n_queue", selectPendingR11nsSQL, len(pending), err)
+	return pending, err
+}
+
+const selectR11nSQL = `select r11n_id, deployment_id, post, prior, resolution
+	from r11n_queue
+	where r11n_id = $1`
+
+// ByID implements sous.R11nQueueStore on PostgresR11nQueueStore.
+func (s *PostgresR11nQueueStore) ByID(id sous.R11nID) (sous.StoredR11n, bool, error) {
+	start := time.Now()
+	sr, err := scanStoredR11n(s.db.QueryRowContext(context.TODO(), selectR11nSQL, string(id)))
+	if err == sql.ErrNoRows {
+		sqlgen.ReportSelect(s.log, start, "r11n_queue", selectR11nSQL, 0, nil)
+		return sous.StoredR11n{}, false, nil
+	}
+	count := 0
+	if err == nil {
+		count = 1
+	}
+	sqlgen.ReportSelect(s.log, start, "r11n_queue", selectR11nSQL, count, err)
+	return sr, err == nil, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanStoredR11n(row rowScanner) (sous.StoredR11n, error) {
+	var id, did string
+	var post, prior, rez []byte
+	if err := row.Scan(&id, &did, &post, &prior, &rez); err != nil {
+		return sous.StoredR11n{}, err
+	}
+	sr := sous.StoredR11n{ID: sous.R11nID(id)}
+	var err error
+	if sr.DeploymentID, err = sous.ParseDeploymentID(did); err != nil {
+		return sr, errors.Wrapf(err, "queued deploy %s", id)
+	}
+	if len(post) > 0 {
+		sr.Post = &sous.Deployment{}
+		if err := json.Unmarshal(post, sr.Post); err != nil {
+			return sr, errors.Wrapf(err, "queued deploy %s", id)
+		}
+	}
+	if len(prior) > 0 {
+		sr.Prior = &sous.Deployment{}
+		if err := json.Unmarshal(prior, sr.Prior); err != nil {
+			return sr, errors.Wrapf(err, "queued deploy %s", id)
+		}
+	}
+	if len(rez) > 0 {
+		sr.Resolution = &sous.DiffResolution{}
+		if err := json.Unmarshal(rez, sr.Resolution); err != nil {
+			return sr, errors.Wrapf(err, "queued deploy %s", id)
+		}
+	}
+	return sr, nil
+}
+
+// nullJSON returns d marshalled as JSON, or nil if d is nil.
+func nullJSON(d *sous.Deployment) (interface{}, error) {
+	if d == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,55 @@
+// +build integration
+
+package storage
+
+import (
+	"testing"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresR11nQueueStore(t *testing.T) {
+	db := sous.SetupDB(t)
+	defer sous.ReleaseDB(t)
+
+	s := NewPostgresR11nQueueStore(db, logging.SilentLogSet())
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/a"}},
+		Cluster:    "cluster1",
+	}
+	post := &sous.Deployment{
+		SourceID:     sous.SourceID{Location: did.ManifestID.Source, Version: semv.MustParse("2.0.0")},
+		ClusterName:  did.Cluster,
+		DeployConfig: sous.DeployConfig{NumInstances: 3},
+	}
+
+	require.NoError(t, s.Queued(sous.StoredR11n{ID: "one", DeploymentID: did, Post: post}))
+	require.NoError(t, s.Queued(sous.StoredR11n{ID: "two", DeploymentID: did}))
+	require.NoError(t, s.Done("two", sous.DiffResolution{DeploymentID: did, Desc: sous.DeleteDiff}))
+
+	pending, err := s.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, sous.R11nID("one"), pending[0].ID)
+	assert.Equal(t, did, pending[0].DeploymentID)
+	require.NotNil(t, pending[0].Post)
+	assert.Equal(t, "2.0.0", pending[0].Post.SourceID.Version.String())
+	assert.Equal(t, 3, pending[0].Post.NumInstances)
+	assert.Nil(t, pending[0].Prior)
+	assert.Nil(t, pending[0].Resolution)
+
+	done, ok, err := s.ByID("two")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotNil(t, done.Resolution)
+	assert.Equal(t, sous.DeleteDiff, done.Resolution.Desc)
+	assert.Nil(t, done.Post)
+
+	_, ok, err = s.ByID("missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -59,6 +60,15 @@ func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.Stat
 	if err := loadClusters(ctx, log, tx, state); err != nil {
 		return nil, err
 	}
+	if err := loadPromotionOrder(ctx, log, tx, state); err != nil {
+		return nil, err
+	}
+	if err := loadTemplates(ctx, log, tx, state); err != nil {
+		return nil, err
+	}
+	if err := loadClusterGroups(ctx, log, tx, state); err != nil {
+		return nil, err
+	}
 	if err := loadManifests(ctx, log, tx, state); err != nil {
 		return nil, err
 	}
@@ -68,10 +78,10 @@ func loadState(ctx context.Context, log logging.LogSink, tx *sql.Tx) (*sous.Stat
 
 func loadEnvDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	return loadTable(context, log, tx, "env_var_defs",
-		`select "name", "desc", "scope", "type" from env_var_defs;`,
+		`select "name", "desc", "scope", "type", "required", "default_value" from env_var_defs;`,
 		func(rows *sql.Rows) error {
 			d := sous.EnvDef{}
-			if err := rows.Scan(&d.Name, &d.Desc, &d.Scope, &d.Type); err != nil {
+			if err := rows.Scan(&d.Name, &d.Desc, &d.Scope, &d.Type, &d.Required, &d.Default); err != nil {
 				return errors.Wrapf(err, "loadEnvDefs")
 			}
 			state.Defs.EnvVars = append(state.Defs.EnvVars, d)
@@ -94,10 +104,10 @@ func loadResourceDefs(context context.Context, log logging.LogSink, tx *sql.Tx,
 
 func loadMetadataDefs(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	return loadTable(context, log, tx, "metadata_fdefs",
-		`select "field_name", "var_type", "default_value" from metadata_fdefs;`,
+		`select "field_name", "var_type", coalesce("default_value", ''), "optional" from metadata_fdefs;`,
 		func(rows *sql.Rows) error {
 			d := sous.FieldDefinition{}
-			if err := rows.Scan(&d.Name, &d.Type, &d.Default); err != nil {
+			if err := rows.Scan(&d.Name, &d.Type, &d.Default, &d.Optional); err != nil {
 				return errors.Wrapf(err, "loadMetadataDefs")
 			}
 			state.Defs.Metadata = append(state.Defs.Metadata, d)
@@ -105,6 +115,56 @@ func loadMetadataDefs(context context.Context, log logging.LogSink, tx *sql.Tx,
 		})
 }
 
+func loadPromotionOrder(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
+	return loadTable(context, log, tx, "promotion_order",
+		`select "cluster_name" from promotion_order order by "position";`,
+		func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return errors.Wrapf(err, "loadPromotionOrder")
+			}
+			state.Defs.PromotionOrder = append(state.Defs.PromotionOrder, name)
+			return nil
+		})
+}
+
+func loadTemplates(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
+	return loadTable(context, log, tx, "templates",
+		`select "name", "config" from templates;`,
+		func(rows *sql.Rows) error {
+			var name string
+			var config []byte
+			if err := rows.Scan(&name, &config); err != nil {
+				return errors.Wrapf(err, "loadTemplates")
+			}
+			dc := sous.DeployConfig{}
+			if err := json.Unmarshal(config, &dc); err != nil {
+				return errors.Wrapf(err, "loadTemplates: template %s", name)
+			}
+			if state.Defs.Templates == nil {
+				state.Defs.Templates = sous.DeployConfigs{}
+			}
+			state.Defs.Templates[name] = dc
+			return nil
+		})
+}
+
+func loadClusterGroups(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
+	return loadTable(context, log, tx, "cluster_groups",
+		`select "group_name", "cluster_name" from cluster_groups order by "group_name", "position";`,
+		func(rows *sql.Rows) error {
+			var name, cluster string
+			if err := rows.Scan(&name, &cluster); err != nil {
+				return errors.Wrapf(err, "loadClusterGroups")
+			}
+			if state.Defs.ClusterGroups == nil {
+				state.Defs.ClusterGroups = sous.ClusterGroups{}
+			}
+			state.Defs.ClusterGroups[name] = append(state.Defs.ClusterGroups[name], cluster)
+			return nil
+		})
+}
+
 func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, state *sous.State) error {
 	clusters := make(map[int]*sous.Cluster)
 	if err := loadTable(context, log, tx, "clusters",
@@ -122,7 +182,7 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 			"crdef_skip", "crdef_connect_delay", "crdef_timeout", "crdef_connect_interval",
 			"crdef_proto", "crdef_path", "crdef_port_index", "crdef_failure_statuses",
 			"crdef_uri_timeout", "crdef_interval", "crdef_retries",
-			advisories.names
+			"auto_rollback", "freeze_windows", "signing_keys", "vulnerability_thresholds", "resource_limits", advisories.names
 		from
 			clusters
 			left join advisories using(cluster_id);
@@ -132,15 +192,40 @@ func loadClusters(context context.Context, log logging.LogSink, tx *sql.Tx, stat
 			c := new(sous.Cluster)
 			qnames := make(pq.StringArray, 10)
 			failStates := make(pq.Int64Array, 10)
+			var freezes, signingKeys, thresholds, limits []byte
 			if err := rows.Scan(
 				&cid, &c.Name, &c.Kind, &c.BaseURL,
 				&c.Startup.SkipCheck, &c.Startup.ConnectDelay, &c.Startup.Timeout, &c.Startup.ConnectInterval,
 				&c.Startup.CheckReadyProtocol, &c.Startup.CheckReadyURIPath, &c.Startup.CheckReadyPortIndex, &failStates,
 				&c.Startup.CheckReadyURITimeout, &c.Startup.CheckReadyInterval, &c.Startup.CheckReadyRetries,
-				&qnames,
+				&c.AutoRollback, &freezes, &signingKeys, &thresholds, &limits, &qnames,
 			); err != nil {
 				return errors.Wrapf(err, "loadClusters")
 			}
+			if err := json.Unmarshal(freezes, &c.FreezeWindows); err != nil {
+				return errors.Wrapf(err, "loadClusters: freeze windows for %s", c.Name)
+			}
+			if len(c.FreezeWindows) == 0 {
+				c.FreezeWindows = nil
+			}
+			if err := json.Unmarshal(signingKeys, &c.SigningKeys); err != nil {
+				return errors.Wrapf(err, "loadClusters: signing keys for %s", c.Name)
+			}
+			if len(c.SigningKeys) == 0 {
+				c.SigningKeys = nil
+			}
+			if err := json.Unmarshal(thresholds, &c.VulnerabilityThresholds); err != nil {
+				return errors.Wrapf(err, "loadClusters: vulnerability thresholds for %s", c.Name)
+			}
+			if len(c.VulnerabilityThresholds) == 0 {
+				c.VulnerabilityThresholds = nil
+			}
+			if err := json.Unmarshal(limits, &c.ResourceLimits); err != nil {
+				return errors.Wrapf(err, "loadClusters: resource limits for %s", c.Name)
+			}
+			if len(c.ResourceLimits) == 0 {
+				c.ResourceLimits = nil
+			}
 			for _, qs := range qnames {
 				c.AllowedAdvisories = append(c.AllowedAdvisories, qs)
 			}
@@ -168,11 +253,14 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 		// results in its own row. Maybe that could be reduced?
 		`select
 			"repo", "dir", "flavor", components.kind,
+			components.template, "spec_templates", "dependencies",
 			"versionstring", "num_instances", "schedule_string",
 			coalesce("singularity_deployment_bindings"."singularity_request_id", ''),
 			"cr_skip", "cr_connect_delay", "cr_timeout", "cr_connect_interval",
 			"cr_proto", "cr_path", "cr_port_index", "cr_failure_statuses",
 			"cr_uri_timeout", "cr_interval", "cr_retries",
+			"rollout_strategy", "rollout_canary_instances", "rollout_steps", "rollout_stage_delay",
+			"rollout_auto_rollback",
 			clusters.name,
 			"host", "container", "mode",
 			envs.key, envs.value,
@@ -217,15 +305,20 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 				volHost, volContainer, volMode sql.NullString
 
 			var ownerEmail sql.NullString
+			var specTemplates, dependencies []byte
 
 			failStates := make(pq.Int64Array, 0)
+			rolloutSteps := make(pq.Int64Array, 0)
 
 			if err := rows.Scan(
 				&m.Source.Repo, &m.Source.Dir, &m.Flavor, &m.Kind,
+				&m.Template, &specTemplates, &dependencies,
 				&versionString, &ds.NumInstances, &ds.Schedule, &ds.DeployConfig.SingularityRequestID,
 				&ds.Startup.SkipCheck, &ds.Startup.ConnectDelay, &ds.Startup.Timeout, &ds.Startup.ConnectInterval,
 				&ds.Startup.CheckReadyProtocol, &ds.Startup.CheckReadyURIPath, &ds.Startup.CheckReadyPortIndex, &failStates,
 				&ds.Startup.CheckReadyURITimeout, &ds.Startup.CheckReadyInterval, &ds.Startup.CheckReadyRetries,
+				&ds.Rollout.Strategy, &ds.Rollout.CanaryInstances, &rolloutSteps, &ds.Rollout.StageDelay,
+				&ds.Rollout.AutoRollback,
 				&clusterName,
 				&volHost, &volContainer, &volMode,
 				&envKey, &envValue,
@@ -238,6 +331,12 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 			if newM, has := state.Manifests.Get(m.ID()); has {
 				m = newM
 			} else {
+				if err := json.Unmarshal(dependencies, &m.Dependencies); err != nil {
+					return errors.Wrapf(err, "loadManifests: dependencies of %s", m.ID())
+				}
+				if len(m.Dependencies) == 0 {
+					m.Dependencies = nil
+				}
 				state.Manifests.Add(m)
 			}
 			set := sous.NewOwnerSet(m.Owners...)
@@ -259,6 +358,14 @@ func loadManifests(context context.Context, log logging.LogSink, tx *sql.Tx, sta
 				for _, s := range failStates {
 					ds.Startup.CheckReadyFailureStatuses = append(ds.Startup.CheckReadyFailureStatuses, int(s))
 				}
+				for _, s := range rolloutSteps {
+					ds.Rollout.Steps = append(ds.Rollout.Steps, int(s))
+				}
+				templates := map[string]string{}
+				if err := json.Unmarshal(specTemplates, &templates); err != nil {
+					return errors.Wrapf(err, "loadManifests: spec templates of %s", m.ID())
+				}
+				ds.Template = templates[clusterName]
 			}
 			if envKey.Valid && envValue.Valid {
 				ds.Env[envKey.String] = envValue.String
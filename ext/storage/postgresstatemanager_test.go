@@ -191,3 +191,124 @@ func (suite *PostgresStateManagerSuite) pluckSQL(sql string) interface{} {
 
 	return v
 }
+
+func TestPostgresStateManagerWriteState_promotionOrder(t *testing.T) {
+	suite := SetupTest(t, "postgresstatemanagerwritestate_promotionorder")
+	defer sous.ReleaseDB(t)
+
+	s := exampleState()
+	s.Defs.PromotionOrder = []string{"other-cluster", "cluster-1"}
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	read, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Equal(s.Defs.PromotionOrder, read.Defs.PromotionOrder)
+
+	s.Defs.PromotionOrder = []string{"cluster-1"}
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	read, err = suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Equal([]string{"cluster-1"}, read.Defs.PromotionOrder)
+}
+
+func TestPostgresStateManagerWriteState_templates(t *testing.T) {
+	suite := SetupTest(t, "postgresstatemanagerwritestate_templates")
+	defer sous.ReleaseDB(t)
+
+	s := exampleState()
+	s.Defs.Templates = sous.DeployConfigs{
+		"small": {Resources: sous.Resources{"cpus": "0.1"}, Env: sous.Env{"SIZE": "small"}},
+		"large": {Resources: sous.Resources{"cpus": "4"}, NumInstances: 2},
+	}
+	m, ok := s.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}})
+	suite.require.True(ok)
+	m.Template = "small"
+	spec := m.Deployments["cluster-1"]
+	spec.Template = "large"
+	m.Deployments["cluster-1"] = spec
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	read, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.require.Len(read.Defs.Templates, 2)
+	for name, dc := range s.Defs.Templates {
+		suite.True(dc.Equal(read.Defs.Templates[name]), "template %s", name)
+	}
+	rm, ok := read.Manifests.Get(m.ID())
+	suite.require.True(ok)
+	suite.Equal("small", rm.Template)
+	suite.Equal("large", rm.Deployments["cluster-1"].Template)
+	suite.Equal("", rm.Deployments["other-cluster"].Template)
+
+	written, err := s.Deployments()
+	suite.require.NoError(err)
+	readDeps, err := read.Deployments()
+	suite.require.NoError(err)
+	for _, p := range written.Diff(readDeps).Collect() {
+		suite.Equal(sous.SameKind, p.Kind(), "%s", p.ID())
+	}
+}
+
+func TestPostgresStateManagerWriteState_clusterGroups(t *testing.T) {
+	suite := SetupTest(t, "postgresstatemanagerwritestate_clustergroups")
+	defer sous.ReleaseDB(t)
+
+	s := exampleState()
+	s.Defs.ClusterGroups = sous.ClusterGroups{
+		"all":  {"other-cluster", "cluster-1"},
+		"solo": {"cluster-1"},
+	}
+	m, ok := s.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}})
+	suite.require.True(ok)
+	spec := m.Deployments["cluster-1"]
+	spec.SingularityRequestID = ""
+	delete(m.Deployments, "cluster-1")
+	delete(m.Deployments, "other-cluster")
+	m.Deployments["all"] = spec
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	read, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	suite.Empty(s.Defs.ClusterGroups.Diff(read.Defs.ClusterGroups))
+
+	written, err := s.Deployments()
+	suite.require.NoError(err)
+	readDeps, err := read.Deployments()
+	suite.require.NoError(err)
+	suite.Equal(written.Len(), readDeps.Len())
+	for _, p := range written.Diff(readDeps).Collect() {
+		suite.Equal(sous.SameKind, p.Kind(), "%s", p.ID())
+	}
+}
+
+func TestPostgresStateManagerWriteState_dependencies(t *testing.T) {
+	suite := SetupTest(t, "postgresstatemanagerwritestate_dependencies")
+	defer sous.ReleaseDB(t)
+
+	s := exampleState()
+	m, ok := s.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/opentable/sous"}})
+	suite.require.True(ok)
+	m.Dependencies = sous.Dependencies{{
+		Manifest: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}},
+		Version:  ">=0.3.0",
+		Clusters: []string{"cluster-1"},
+	}}
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+
+	read, err := suite.manager.ReadState()
+	suite.require.NoError(err)
+	rm, ok := read.Manifests.Get(m.ID())
+	suite.require.True(ok)
+	suite.True(m.Dependencies.Equal(rm.Dependencies), "%v != %v", m.Dependencies, rm.Dependencies)
+	other, ok := read.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/user/project"}})
+	suite.require.True(ok)
+	suite.Nil(other.Dependencies)
+
+	m.Dependencies = nil
+	suite.require.NoError(suite.manager.WriteState(s, testUser))
+	read, err = suite.manager.ReadState()
+	suite.require.NoError(err)
+	rm, ok = read.Manifests.Get(m.ID())
+	suite.require.True(ok)
+	suite.Nil(rm.Dependencies)
+}
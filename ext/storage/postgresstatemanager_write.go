@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/lib/pq"
@@ -31,6 +32,11 @@ func (m PostgresStateManager) WriteState(state *sous.State, user sous.User) erro
 		return err
 	}
 
+	if err := storeDefs(context, m.log, state, tx); err != nil {
+		reportWriting(m.log, start, state, errors.Wrapf(err, "storing defs"))
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		reportWriting(m.log, start, state, errors.Wrapf(err, "committing transaction"))
 		return err
@@ -94,15 +100,69 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 		return err
 	}
 
+	// The templates and dependencies of manifests are kept with their
+	// components, since deployments are only written when their effective
+	// config changes.
+	if err := ins.Exec("components", sqlgen.Upsert, func(fields sqlgen.FieldSet) {
+		for _, m := range state.Manifests.Snapshot() {
+			specTemplates := map[string]string{}
+			for name := range state.Defs.Clusters {
+				if spec, ok := state.Defs.ClusterSpec(m, name); ok && spec.Template != "" {
+					specTemplates[name] = spec.Template
+				}
+			}
+			st, err := json.Marshal(specTemplates)
+			if err != nil {
+				st = []byte("{}")
+			}
+			deps, err := json.Marshal(m.Dependencies)
+			if err != nil || m.Dependencies == nil {
+				deps = []byte("[]")
+			}
+			fields.Row(func(r sqlgen.RowDef) {
+				r.CF("?", "repo", m.Source.Repo)
+				r.CF("?", "dir", m.Source.Dir)
+				r.CF("?", "flavor", m.Flavor)
+				r.CF("?", "kind", m.Kind)
+				r.FD("?", "template", m.Template)
+				r.FD("?", "spec_templates", string(st))
+				r.FD("?", "dependencies", string(deps))
+			})
+		}
+	}); err != nil {
+		return err
+	}
+
 	if err := ins.Exec("clusters", sqlgen.Upsert,
 		deploymentsFieldSetter(alldeps, func(fields sqlgen.FieldSet, dep *sous.Deployment) {
 			c := dep.Cluster
 			s := c.Startup
+			freezes, err := json.Marshal(c.FreezeWindows)
+			if err != nil || c.FreezeWindows == nil {
+				freezes = []byte("[]")
+			}
+			signingKeys, err := json.Marshal(c.SigningKeys)
+			if err != nil || c.SigningKeys == nil {
+				signingKeys = []byte("[]")
+			}
+			thresholds, err := json.Marshal(c.VulnerabilityThresholds)
+			if err != nil || c.VulnerabilityThresholds == nil {
+				thresholds = []byte("{}")
+			}
+			limits, err := json.Marshal(c.ResourceLimits)
+			if err != nil || c.ResourceLimits == nil {
+				limits = []byte("{}")
+			}
 			fields.Row(func(r sqlgen.RowDef) {
 				r.CF("?", "name", dep.ClusterName)
 				r.FD("?", "kind", c.Kind)
 				r.FD("?", "base_url", c.BaseURL)
 				startupFields(r, "crdef", s)
+				r.FD("?", "auto_rollback", c.AutoRollback)
+				r.FD("?", "freeze_windows", string(freezes))
+				r.FD("?", "signing_keys", string(signingKeys))
+				r.FD("?", "vulnerability_thresholds", string(thresholds))
+				r.FD("?", "resource_limits", string(limits))
 			})
 		})); err != nil {
 		return err
@@ -170,6 +230,7 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 				r.FD("?", "schedule_string", dep.Schedule)
 				r.FD("?", "lifecycle", "active")
 				startupFields(r, "cr", s)
+				rolloutFields(r, dep.Rollout)
 			})
 		})); err != nil {
 		return err
@@ -189,6 +250,7 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 				r.FD("?", "schedule_string", dep.Schedule)
 				r.FD("?", "lifecycle", "decommisioned")
 				startupFields(r, "cr", s)
+				rolloutFields(r, dep.Rollout)
 			})
 		})); err != nil {
 		return err
@@ -273,6 +335,71 @@ func storeManifests(ctx context.Context, log logging.LogSink, state *sous.State,
 	return nil
 }
 
+// storeDefs stores the parts of the Defs which are not stored with the
+// deployments that use them. Each is replaced as a whole.
+func storeDefs(ctx context.Context, log logging.LogSink, state *sous.State, tx *sql.Tx) error {
+	ins := sqlgen.NewInserter(ctx, log, tx)
+
+	if err := clearTable(ctx, log, tx, "promotion_order"); err != nil {
+		return err
+	}
+	if err := ins.Exec("promotion_order", "", func(fields sqlgen.FieldSet) {
+		for i, name := range state.Defs.PromotionOrder {
+			fields.Row(func(r sqlgen.RowDef) {
+				r.FD("?", "position", i)
+				r.FD("?", "cluster_name", name)
+			})
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err := clearTable(ctx, log, tx, "templates"); err != nil {
+		return err
+	}
+	if err := ins.Exec("templates", "", func(fields sqlgen.FieldSet) {
+		for name, dc := range state.Defs.Templates {
+			config, err := json.Marshal(dc)
+			if err != nil {
+				config = []byte("{}")
+			}
+			fields.Row(func(r sqlgen.RowDef) {
+				r.FD("?", "name", name)
+				r.FD("?", "config", string(config))
+			})
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err := clearTable(ctx, log, tx, "cluster_groups"); err != nil {
+		return err
+	}
+	if err := ins.Exec("cluster_groups", "", func(fields sqlgen.FieldSet) {
+		for name, members := range state.Defs.ClusterGroups {
+			for i, cluster := range members {
+				fields.Row(func(r sqlgen.RowDef) {
+					r.FD("?", "group_name", name)
+					r.FD("?", "position", i)
+					r.FD("?", "cluster_name", cluster)
+				})
+			}
+		}
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func clearTable(ctx context.Context, log logging.LogSink, tx *sql.Tx, table string) error {
+	start := time.Now()
+	sql := "delete from " + table
+	_, err := tx.ExecContext(ctx, sql)
+	sqlgen.ReportUpdate(log, start, table, sql, 0, err)
+	return errors.Wrapf(err, "clearing %s", table)
+}
+
 func depID(row sqlgen.RowDef, dep *sous.Deployment) {
 	sid := dep.SourceID
 	row.FD(`(select max(deployment_id)
@@ -336,6 +463,18 @@ func startupFields(r sqlgen.RowDef, prefix string, s sous.Startup) {
 	r.FD("?", prefix+"_failure_statuses", pq.Array(statuses))
 }
 
+func rolloutFields(r sqlgen.RowDef, ro sous.Rollout) {
+	steps := []int64{}
+	for _, n := range ro.Steps {
+		steps = append(steps, int64(n))
+	}
+	r.FD("?", "rollout_strategy", string(ro.Strategy))
+	r.FD("?", "rollout_canary_instances", ro.CanaryInstances)
+	r.FD("?", "rollout_steps", pq.Array(steps))
+	r.FD("?", "rollout_stage_delay", ro.StageDelay)
+	r.FD("?", "rollout_auto_rollback", ro.AutoRollback)
+}
+
 func deploymentsFieldSetter(ds sous.Deployments, eachDep func(sqlgen.FieldSet, *sous.Deployment)) func(sqlgen.FieldSet) {
 	return func(fields sqlgen.FieldSet) {
 		for _, d := range ds.Snapshot() {
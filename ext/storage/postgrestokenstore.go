@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/sqlgen"
+	"github.com/pkg/errors"
+)
+
+// PostgresTokenStore provides the sous.TokenStore interface by storing token
+// hashes in the api_tokens table.
+type PostgresTokenStore struct {
+	db  *sql.DB
+	log logging.LogSink
+}
+
+// NewPostgresTokenStore creates a new PostgresTokenStore.
+func NewPostgresTokenStore(db *sql.DB, log logging.LogSink) *PostgresTokenStore {
+	return &PostgresTokenStore{db: db, log: log}
+}
+
+const (
+	insertTokenSQL = `insert into api_tokens
+	(token_id, token_hash, user_name, user_email, groups, description, created_at)
+	values ($1, $2, $3, $4, $5, $6, $7)`
+
+	selectTokensSQL = `select token_id, token_hash, user_name, user_email,
+	groups, description, created_at, revoked_at from api_tokens`
+
+	revokeTokenSQL = `update api_tokens set revoked_at = $2
+	where token_id = $1 and revoked_at is null`
+)
+
+// Add implements sous.TokenStore on PostgresTokenStore.
+func (s *PostgresTokenStore) Add(t sous.APIToken) error {
+	start := time.Now()
+	groups := t.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+	_, err := s.db.ExecContext(context.TODO(), insertTokenSQL,
+		t.ID, t.Hash, t.User.Name, t.User.Email, pq.Array(groups), t.Description, t.Created)
+	count := 1
+	if err != nil {
+		count = 0
+	}
+	sqlgen.ReportInsert(s.log, start, "api_tokens", insertTokenSQL, count, err)
+	return errors.Wrap(err, "adding token")
+}
+
+// Lookup implements sous.TokenStore on PostgresTokenStore.
+func (s *PostgresTokenStore) Lookup(hash string) (*sous.APIToken, error) {
+	tokens, err := s.query(selectTokensSQL+" where token_hash = $1 and revoked_at is null", hash)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	return &tokens[0], nil
+}
+
+// Revoke implements sous.TokenStore on PostgresTokenStore.
+func (s *PostgresTokenStore) Revoke(id string, at time.Time) error {
+	start := time.Now()
+	res, err := s.db.ExecContext(context.TODO(), revokeTokenSQL, id, at)
+	var count int64
+	if err == nil {
+		count, err = res.RowsAffected()
+	}
+	sqlgen.ReportUpdate(s.log, start, "api_tokens", revokeTokenSQL, int(count), err)
+	if err != nil {
+		return errors.Wrap(err, "revoking token")
+	}
+	if count == 0 {
+		return errors.Errorf("no live token %q", id)
+	}
+	return nil
+}
+
+// List implements sous.TokenStore on PostgresTokenStore.
+func (s *PostgresTokenStore) List() ([]sous.APIToken, error) {
+	return s.query(selectTokensSQL + " order by created_at")
+}
+
+func (s *PostgresTokenStore) query(query string, args ...interface{}) ([]sous.APIToken, error) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(context.TODO(), query, args...)
+	if err != nil {
+		sqlgen.ReportSelect(s.log, start, "api_tokens", query, 0, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []sous.APIToken{}
+	for rows.Next() {
+		t := sous.APIToken{}
+		var revoked pq.NullTime
+		if err := rows.Scan(&t.ID, &t.Hash, &t.User.Name, &t.User.Email,
+			pq.Array(&t.Groups), &t.Description, &t.Created, &revoked); err != nil {
+			sqlgen.ReportSelect(s.log, start, "api_tokens", query, len(tokens), err)
+			return nil, err
+		}
+		if revoked.Valid {
+			t.Revoked = revoked.Time
+		}
+		tokens = append(tokens, t)
+	}
+	err = rows.Err()
+	sqlgen.ReportSelect(s.log, start, "api_tokens", query, len(tokens), err)
+	return tokens, err
+}
@@ -0,0 +1,55 @@
+// +build integration
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresTokenStore(t *testing.T) {
+	db := sous.SetupDB(t)
+	defer sous.ReleaseDB(t)
+
+	s := NewPostgresTokenStore(db, logging.SilentLogSet())
+
+	token, issued, err := sous.IssueAPIToken(s, sous.APIToken{
+		User:        testUser,
+		Groups:      []string{"deployers", "admins"},
+		Description: "laptop",
+	})
+	require.NoError(t, err)
+	_, _, err = sous.IssueAPIToken(s, sous.APIToken{User: sous.User{Name: "CI"}})
+	require.NoError(t, err)
+
+	found, err := s.Lookup(sous.HashAPIToken(token))
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, issued.ID, found.ID)
+	assert.Equal(t, testUser, found.User)
+	assert.Equal(t, []string{"deployers", "admins"}, found.Groups)
+	assert.Equal(t, "laptop", found.Description)
+	assert.True(t, found.Live())
+
+	missing, err := s.Lookup(sous.HashAPIToken("not a token"))
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	require.NoError(t, s.Revoke(issued.ID, time.Now()))
+	assert.Error(t, s.Revoke(issued.ID, time.Now()))
+
+	revoked, err := s.Lookup(issued.Hash)
+	assert.NoError(t, err)
+	assert.Nil(t, revoked)
+
+	all, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.False(t, all[0].Live())
+	assert.True(t, all[1].Live())
+}
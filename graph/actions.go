@@ -93,6 +93,47 @@ func (di *SousGraph) GetPlumbingNormalizeGDM() (actions.Action, error) {
 	}, nil
 }
 
+// plumbingTokenStore returns the token store for the `sous plumbing token`
+// commands, which work directly on the configured database.
+func (di *SousGraph) plumbingTokenStore() (sous.TokenStore, error) {
+	scoop := struct {
+		DB MaybeDatabase
+		LS LogSink
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return newTokenStore(scoop.DB, scoop.LS)
+}
+
+// GetPlumbingTokenIssue returns an action which issues token, writing the
+// token itself to out.
+func (di *SousGraph) GetPlumbingTokenIssue(token sous.APIToken, out io.Writer) (actions.Action, error) {
+	store, err := di.plumbingTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	return &actions.PlumbTokenIssue{Store: store, Token: token, Out: out}, nil
+}
+
+// GetPlumbingTokenRevoke returns an action which revokes the token with id.
+func (di *SousGraph) GetPlumbingTokenRevoke(id string) (actions.Action, error) {
+	store, err := di.plumbingTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	return &actions.PlumbTokenRevoke{Store: store, ID: id}, nil
+}
+
+// GetPlumbingTokenList returns an action which lists tokens to out.
+func (di *SousGraph) GetPlumbingTokenList(out io.Writer) (actions.Action, error) {
+	store, err := di.plumbingTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	return &actions.PlumbTokenList{Store: store, Out: out}, nil
+}
+
 // GetUpdate returns an update Action.
 func (di *SousGraph) GetUpdate(dff config.DeployFilterFlags, otpl config.OTPLFlags) (actions.Action, error) {
 	di.guardedAdd("DeployFilterFlags", &dff)
@@ -145,6 +186,60 @@ func (di *SousGraph) GetGetArtifact(opts ArtifactOpts) (*actions.GetArtifact, er
 	}, nil
 }
 
+// GetArtifactProvenance returns an action which writes the provenance of an
+// artifact to out.
+func (di *SousGraph) GetArtifactProvenance(opts ArtifactOpts, out io.Writer) (*actions.ArtifactProvenance, error) {
+	sid, err := opts.SourceID.SourceID()
+	if err != nil {
+		return nil, err
+	}
+	di.guardedAdd("SourceIDFlags", &opts.SourceID)
+	scoop := struct {
+		Query queries.ArtifactQuery
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	return &actions.ArtifactProvenance{
+		Query:     scoop.Query,
+		SourceID:  sid,
+		OutWriter: out,
+	}, nil
+}
+
+// GetArtifactScan returns an action which scans an artifact for
+// vulnerabilities, recording the results on it and writing them to out.
+func (di *SousGraph) GetArtifactScan(opts ArtifactOpts, out io.Writer) (*actions.ArtifactScan, error) {
+	sid, err := opts.SourceID.SourceID()
+	if err != nil {
+		return nil, err
+	}
+	di.guardedAdd("SourceIDFlags", &opts.SourceID)
+	scoop := struct {
+		Query    queries.ArtifactQuery
+		Inserter sous.ClientInserter
+		Config   LocalSousConfig
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	scanner, err := scoop.Config.Scanning.VulnerabilityScanner()
+	if err != nil {
+		return nil, err
+	}
+	replacer, ok := scoop.Inserter.Inserter.(sous.Replacer)
+	if !ok {
+		return nil, fmt.Errorf("%T cannot replace recorded artifacts", scoop.Inserter.Inserter)
+	}
+	return &actions.ArtifactScan{
+		Query:     scoop.Query,
+		Scanner:   scanner,
+		Replacer:  replacer,
+		SourceID:  sid,
+		OutWriter: out,
+	}, nil
+}
+
 //GetAddArtifact will return artifact for cli add artifact
 func (di *SousGraph) GetAddArtifact(opts ArtifactOpts) (actions.Action, error) {
 	di.guardedAdd("SourceIDFlags", &opts.SourceID)
@@ -190,13 +285,18 @@ func (di *SousGraph) GetJenkins(opts DeployActionOpts) (actions.Action, error) {
 	client := scoop.HTTP.HTTPClient
 	if os.Getenv("SOUS_USE_SOUS_SERVER") == "YES" {
 		messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("TraceID: %s", scoop.TraceID), logging.DebugLevel, scoop.LogSink.LogSink, scoop.TraceID)
-		c, err := restful.NewClient(scoop.Config.Config.Server, scoop.LogSink.LogSink.Child("jenkins.http-client"), map[string]string{"OT-RequestId": string(scoop.TraceID)})
+		c, err := restful.NewClient(scoop.Config.Config.Server, scoop.LogSink.LogSink.Child("jenkins.http-client"), map[string]string{"OT-RequestId": string(scoop.TraceID)}, restful.BearerAuth(scoop.Config.APIToken))
 		if err != nil {
 			return nil, err
 		}
 		client = c
 	}
 
+	defs, err := di.GetDefs()
+	if err != nil {
+		return nil, err
+	}
+
 	return &actions.Jenkins{
 		HTTPClient:       client,
 		TargetManifestID: sous.ManifestID(scoop.TargetManifestID),
@@ -204,21 +304,27 @@ func (di *SousGraph) GetJenkins(opts DeployActionOpts) (actions.Action, error) {
 		User:             scoop.User,
 		Config:           scoop.Config.Config,
 		Cluster:          opts.DFF.Cluster,
+		Defs:             defs,
 	}, nil
 }
 
 // BuildActionOpts are options for GetBuild.
 type BuildActionOpts struct {
 	DFF     config.DeployFilterFlags
+	Policy  config.PolicyFlags
 	CLIArgs []string
 }
 
-// GetBuild gets the Build action.
-func (di *SousGraph) GetBuild(opts BuildActionOpts) (*actions.Build, error) {
+// GetBuild gets the Build action. If DFF names a repo which is not checked out
+// in the working directory, or Policy.ForceClone is set, the action builds a
+// fresh clone of the source instead.
+func (di *SousGraph) GetBuild(opts BuildActionOpts) (actions.BuildAction, error) {
 	scoop := struct {
 		ResolveFilter *RefinedResolveFilter
+		Discovered    *SourceContextDiscovery
 	}{}
 	di.MustInject(&scoop)
+	remote := opts.Policy.ForceClone || (opts.DFF.Repo != "" && !scoop.Discovered.ChecksOut(opts.DFF.Repo))
 	opts.DFF.Repo = scoop.ResolveFilter.Repo.ValueOr("")
 	getArtifactOpts := ArtifactOpts{
 		SourceID: opts.DFF.SourceIDFlags(),
@@ -227,6 +333,9 @@ func (di *SousGraph) GetBuild(opts BuildActionOpts) (*actions.Build, error) {
 	if err != nil {
 		return nil, cmdr.InternalErrorf("%s", err)
 	}
+	if remote {
+		return di.getRemoteBuild(opts, getArtifact)
+	}
 	di.guardedAdd("GetArtifact", getArtifact)
 	di.guardedAdd("CLIArgs", opts.CLIArgs)
 	b := &actions.Build{}
@@ -234,15 +343,100 @@ func (di *SousGraph) GetBuild(opts BuildActionOpts) (*actions.Build, error) {
 
 }
 
+func (di *SousGraph) getRemoteBuild(opts BuildActionOpts, getArtifact *actions.GetArtifact) (*actions.RemoteBuild, error) {
+	if len(opts.CLIArgs) != 0 {
+		return nil, cmdr.UsageErrorf("a path cannot be built without a local checkout; use -offset")
+	}
+	if opts.DFF.Tag == "" {
+		return nil, cmdr.UsageErrorf("-tag is required to build %s without a local checkout", opts.DFF.Repo)
+	}
+	sid, err := opts.DFF.SourceIDFlags().SourceID()
+	if err != nil {
+		return nil, cmdr.UsageErrorf("-tag %q: %s", opts.DFF.Tag, err)
+	}
+	scoop := struct {
+		Builder *sous.RemoteBuilder
+		Config  LocalSousConfig
+		User    sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	scoop.Builder.Strict = opts.Policy.Strict
+	scoop.Builder.Dev = opts.Policy.Dev
+	scoop.Builder.Platforms = buildPlatforms(scoop.Config, &opts.Policy)
+	return &actions.RemoteBuild{
+		GetArtifact: getArtifact,
+		Builder:     scoop.Builder,
+		SourceID:    sid,
+		User:        scoop.User,
+	}, nil
+}
+
 // DeployActionOpts are options for GetDeploy.
 type DeployActionOpts struct {
 	DFF                              config.DeployFilterFlags
 	DryRun, InitSingularityRequestID string
 	Force, WaitStable                bool
+	// BreakGlass asks for the deploy to be allowed through a cluster freeze.
+	BreakGlass bool
+	// ClusterGroup names a group of clusters in the Defs to deploy to,
+	// instead of the single cluster in DFF.
+	ClusterGroup string
+	// Serial deploys to the clusters of ClusterGroup one at a time.
+	Serial bool
 }
 
-// GetDeploy constructs a Deploy Action.
+// GetDeploy constructs a Deploy Action, or a GroupDeploy Action if
+// opts.ClusterGroup is set.
 func (di *SousGraph) GetDeploy(opts DeployActionOpts) (actions.Action, error) {
+	if opts.ClusterGroup != "" {
+		return di.getGroupDeploy(opts)
+	}
+	deploy, err := di.getDeploy(opts)
+	if err != nil {
+		return nil, err
+	}
+	return deploy, nil
+}
+
+// GetRollback constructs a Rollback Action, which returns a deployment to
+// version to from its history, or to its last good version if to is empty.
+func (di *SousGraph) GetRollback(opts DeployActionOpts, to string) (actions.Action, error) {
+	deploy, err := di.getDeploy(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &actions.Rollback{Deploy: deploy, To: to}, nil
+}
+
+// GetPromote constructs a Promote Action, which deploys the version running in
+// cluster from to the cluster in opts.
+func (di *SousGraph) GetPromote(opts DeployActionOpts, from string) (actions.Action, error) {
+	deploy, err := di.getDeploy(opts)
+	if err != nil {
+		return nil, err
+	}
+	if from == deploy.TargetDeploymentID.Cluster {
+		return nil, fmt.Errorf("cannot promote from %q to itself", from)
+	}
+
+	fromClient := deploy.HTTPClient
+	if os.Getenv("SOUS_USE_SOUS_SERVER") != "YES" {
+		scoop := struct{ Clients ClientBundle }{}
+		if err := di.Inject(&scoop); err != nil {
+			return nil, err
+		}
+		c, has := scoop.Clients[from]
+		if !has {
+			return nil, fmt.Errorf("no server for cluster %q", from)
+		}
+		fromClient = c
+	}
+	return &actions.Promote{Deploy: deploy, From: from, FromClient: fromClient}, nil
+}
+
+func (di *SousGraph) getDeploy(opts DeployActionOpts) (*actions.Deploy, error) {
 	di.guardedAdd("Dryrun", DryrunOption(opts.DryRun))
 	di.guardedAdd("DeployFilterFlags", &opts.DFF)
 
@@ -264,7 +458,7 @@ func (di *SousGraph) GetDeploy(opts DeployActionOpts) (actions.Action, error) {
 	client := scoop.HTTP.HTTPClient
 	if os.Getenv("SOUS_USE_SOUS_SERVER") == "YES" {
 		messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("TraceID: %s", scoop.TraceID), logging.DebugLevel, scoop.LogSink.LogSink, scoop.TraceID)
-		c, err := restful.NewClient(scoop.Config.Config.Server, scoop.LogSink.LogSink.Child(opts.DFF.Cluster+".http-client"), map[string]string{"OT-RequestId": string(scoop.TraceID)})
+		c, err := restful.NewClient(scoop.Config.Config.Server, scoop.LogSink.LogSink.Child(opts.DFF.Cluster+".http-client"), map[string]string{"OT-RequestId": string(scoop.TraceID)}, restful.BearerAuth(scoop.Config.APIToken))
 		if err != nil {
 			return nil, err
 		}
@@ -282,6 +476,91 @@ func (di *SousGraph) GetDeploy(opts DeployActionOpts) (actions.Action, error) {
 		Config:             scoop.Config.Config,
 		Force:              opts.Force,
 		WaitStable:         opts.WaitStable,
+		BreakGlass:         opts.BreakGlass,
+	}, nil
+}
+
+// GetDefs retrieves the Defs of the server, e.g. to find the DeploySpecs of
+// manifests' deployments to clusters in a group with Defs.ClusterSpec.
+func (di *SousGraph) GetDefs() (sous.Defs, error) {
+	scoop := struct {
+		HTTP HTTPClient
+		User sous.User
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return sous.Defs{}, err
+	}
+	defs := sous.Defs{}
+	_, err := scoop.HTTP.Retrieve("./defs", nil, &defs, scoop.User.HTTPHeaders())
+	return defs, err
+}
+
+// getGroupDeploy constructs a GroupDeploy of a Deploy to each cluster in the
+// group opts.ClusterGroup, as defined by the server's Defs.
+func (di *SousGraph) getGroupDeploy(opts DeployActionOpts) (*actions.GroupDeploy, error) {
+	if opts.DFF.Cluster != "" {
+		return nil, fmt.Errorf("-cluster and -cluster-group cannot be used together")
+	}
+	scoop := struct {
+		HTTP    HTTPClient
+		User    sous.User
+		LogSink LogSink
+	}{}
+	if err := di.Inject(&scoop); err != nil {
+		return nil, err
+	}
+	defs, err := di.GetDefs()
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster groups: %s", err)
+	}
+	members, has := defs.ClusterGroups[opts.ClusterGroup]
+	if !has || len(members) == 0 {
+		return nil, fmt.Errorf("no cluster group %q is defined", opts.ClusterGroup)
+	}
+
+	opts.DFF.Cluster = members[0]
+	first, err := di.getDeploy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients ClientBundle
+	if os.Getenv("SOUS_USE_SOUS_SERVER") != "YES" {
+		bundle := struct{ Clients ClientBundle }{}
+		if err := di.Inject(&bundle); err != nil {
+			return nil, err
+		}
+		clients = bundle.Clients
+	}
+
+	deploys := make([]*actions.Deploy, len(members))
+	for i, member := range members {
+		d := *first
+		d.TargetDeploymentID.Cluster = member
+		d.LogSink = scoop.LogSink.LogSink.Child("deploy", d.ResolveFilter, d.TargetDeploymentID)
+		if clients != nil {
+			c, has := clients[member]
+			if !has {
+				return nil, fmt.Errorf("no server for cluster %q", member)
+			}
+			d.HTTPClient = c
+		}
+		deploys[i] = &d
+	}
+
+	// Poll the servers of every cluster in the group, rather than only the
+	// first.
+	rf := *first.ResolveFilter
+	rf.Cluster = sous.ResolveFieldMatcher{}
+	poller := sous.NewStatusPoller(scoop.HTTP, &rf, scoop.User, scoop.LogSink.Child("status-poller"))
+	poller.Clusters = members
+
+	return &actions.GroupDeploy{
+		Group:        opts.ClusterGroup,
+		Deploys:      deploys,
+		Serial:       opts.Serial,
+		StatusPoller: poller,
+		LogSink:      scoop.LogSink.LogSink.Child("group-deploy"),
 	}, nil
 }
 
@@ -352,6 +631,7 @@ func (di *SousGraph) GetServer(
 		LogSink       LogSink
 		Config        *config.Config
 		ServerHandler ServerHandler
+		Webhooks      SingularityWebhooks
 	}{}
 
 	if err := di.Inject(&scoop); err != nil {
@@ -377,5 +657,6 @@ func (di *SousGraph) GetServer(
 		Config:            scoop.Config,
 		ServerHandler:     scoop.ServerHandler.Handler,
 		AutoResolver:      arScoop.AutoResolver,
+		Webhooks:          scoop.Webhooks.Webhooks,
 	}, nil
 }
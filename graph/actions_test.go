@@ -262,3 +262,24 @@ func TestGetDeploy(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, action)
 }
+
+func TestGetRollback(t *testing.T) {
+	fg := fixtureGraph(t)
+	opts := DeployActionOpts{
+		DFF: config.DeployFilterFlags{
+			DeploymentIDFlags: config.DeploymentIDFlags{
+				ManifestIDFlags: config.ManifestIDFlags{
+					SourceLocationFlags: config.SourceLocationFlags{
+						Repo: "repo1",
+					},
+				},
+				Cluster: "cluster1",
+			},
+		},
+	}
+	action, err := fg.GetRollback(opts, "1.2.3")
+	require.NoError(t, err)
+	if assert.IsType(t, &actions.Rollback{}, action) {
+		assert.Equal(t, "1.2.3", action.(*actions.Rollback).To)
+	}
+}
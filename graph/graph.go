@@ -11,7 +11,7 @@ import (
 	"github.com/opentable/sous/config"
 	"github.com/opentable/sous/ext/docker"
 	"github.com/opentable/sous/ext/git"
-	"github.com/opentable/sous/ext/github"
+	"github.com/opentable/sous/ext/kubernetes"
 	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
@@ -73,6 +73,19 @@ type (
 	ClusterSpecificHTTPClient struct{ restful.HTTPClient }
 	// ServerHandler wraps the http.Handler for the sous server
 	ServerHandler struct{ http.Handler }
+	// ServerIdentitySource wraps the restful.IdentitySource used to authorize
+	// writes to the sous server. It is nil if writes are unrestricted.
+	ServerIdentitySource struct{ restful.IdentitySource }
+	// SecretStore wraps the sous.SecretStore used to resolve secret
+	// references in deployments. It is nil if none is configured.
+	SecretStore struct{ sous.SecretStore }
+	// ServerSourceBuilder wraps the sous.SourceBuilder the server builds
+	// source with. It is nil unless the server is configured to build.
+	ServerSourceBuilder struct{ sous.SourceBuilder }
+	// SingularityWebhooks wraps the receiver of the server's webhooks from
+	// Singularity. It is nil unless the server is configured to register
+	// them.
+	SingularityWebhooks struct{ *singularity.Webhooks }
 	// MetricsHandler wraps an http.Handler for metrics
 	MetricsHandler struct{ http.Handler }
 	// LogSink wraps logging.LogSink
@@ -216,6 +229,9 @@ func AddFilesystem(graph adder) {
 		newMaybeDatabase, // we need to be able to progress in the absence of a DB.
 		newDuplexStateManager,
 		newServerStateManager,
+		newDeploymentHistory,
+		newAuditLog,
+		newR11nQueueStore,
 		newServerClusterManager,
 		newDistributedStateManager,
 		newGitStateManager,
@@ -258,7 +274,8 @@ func AddDocker(graph adder) {
 	)
 }
 
-// AddSingularity adds Singularity clients to the graph.
+// AddSingularity adds the scheduler clients (Singularity and Kubernetes) to
+// the graph.
 func AddSingularity(graph adder) {
 	graph.Add(
 		newDeployer,
@@ -280,6 +297,8 @@ func AddInternals(graph adder) {
 		newLabeller,
 		newRegistrar,
 		newBuildManager,
+		newRemoteBuilder,
+		newServerSourceBuilder,
 		newBuildConfig,
 		newBuildContext,
 		newSourceContext,
@@ -294,10 +313,16 @@ func AddInternals(graph adder) {
 		newResolveFilter,
 		newResolver,
 		newAutoResolver,
+		newResolveEvents,
+		newDeployStateCache,
+		newSingularityWebhooks,
+		newRollbacker,
 		newClientInserter,
 		newServerInserter,
 		newStatusPoller,
 		newServerComponentLocator,
+		newServerIdentitySource,
+		newSecretStore,
 		newHTTPClient,
 		newServerListData,
 		newHTTPClientBundle,
@@ -310,19 +335,42 @@ func newResolveFilter(sf *config.DeployFilterFlags, shc sous.SourceHostChooser)
 	return sf.BuildFilter(shc.ParseSourceLocation)
 }
 
-func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet) *sous.Resolver {
-	return sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+func newResolver(filter *sous.ResolveFilter, d sous.Deployer, r sous.Registry, ls LogSink, qs *sous.R11nQueueSet, rb *sous.Rollbacker) *sous.Resolver {
+	rez := sous.NewResolver(d, r, filter, ls.Child("resolver"), qs)
+	rez.Rollbacks = rb
+	return rez
 }
 
-func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, ls LogSink) *sous.AutoResolver {
-	return sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+func newAutoResolver(rez *sous.Resolver, sr *ServerStateManager, events *sous.ResolveEvents, ls LogSink) *sous.AutoResolver {
+	ar := sous.NewAutoResolver(rez, sr, ls.Child("autoresolver"))
+	ar.ListenTo(events)
+	return ar
 }
 
-func newSourceHostChooser() sous.SourceHostChooser {
+// newResolveEvents returns the ResolveEvents shared by the components which
+// report changes and the AutoResolver which resolves them.
+func newResolveEvents() *sous.ResolveEvents {
+	return sous.NewResolveEvents()
+}
+
+func newDeployStateCache() *sous.DeployStateCache {
+	return sous.NewDeployStateCache()
+}
+
+func newSingularityWebhooks(cfg LocalSousConfig, sr *ServerStateManager, states *sous.DeployStateCache, events *sous.ResolveEvents, ls LogSink) SingularityWebhooks {
+	if cfg.Webhooks.URL == "" {
+		return SingularityWebhooks{}
+	}
+	return SingularityWebhooks{singularity.NewWebhooks(cfg.Webhooks.URL, cfg.Webhooks.Secret, sr, states, events, ls.Child("webhooks"))}
+}
+
+func newRollbacker(sr *ServerStateManager, h sous.DeploymentHistory, ls LogSink) *sous.Rollbacker {
+	return sous.NewRollbacker(sr.StateManager, h, ls.Child("rollbacker"))
+}
+
+func newSourceHostChooser(c LocalSousConfig) sous.SourceHostChooser {
 	return sous.SourceHostChooser{
-		SourceHosts: []sous.SourceHost{
-			github.SourceHost{},
-		},
+		SourceHosts: c.SourceHosts.SourceHosts(),
 	}
 }
 
@@ -427,6 +475,12 @@ func (scd *SourceContextDiscovery) Unwrap(mid TargetManifestID) (*sous.SourceCon
 	return scd.SourceContext, nil
 }
 
+// ChecksOut returns true if the source context was discovered, and is a
+// checkout of repo.
+func (scd *SourceContextDiscovery) ChecksOut(repo string) bool {
+	return scd.Error == nil && scd.SourceContext != nil && scd.SourceLocation().Repo == repo
+}
+
 // GetContext returns the SourceContext discovered if there were no errors in
 // getting it. Otherwise returns a pointer to a zero SourceContext.
 func (scd *SourceContextDiscovery) GetContext() *sous.SourceContext {
@@ -442,7 +496,7 @@ func newBuildContext(wd LocalWorkDirShell, c *sous.SourceContext) *sous.BuildCon
 	return &sous.BuildContext{Sh: sh, Source: *c}
 }
 
-func newBuildConfig(ls LogSink, f *config.DeployFilterFlags, p *config.PolicyFlags, bc *sous.BuildContext) *sous.BuildConfig {
+func newBuildConfig(ls LogSink, c LocalSousConfig, u sous.User, f *config.DeployFilterFlags, p *config.PolicyFlags, bc *sous.BuildContext) *sous.BuildConfig {
 	offset := f.Offset
 	if offset == "" {
 		offset = bc.Source.OffsetDir
@@ -455,6 +509,8 @@ func newBuildConfig(ls LogSink, f *config.DeployFilterFlags, p *config.PolicyFla
 		Strict:     p.Strict,
 		ForceClone: p.ForceClone,
 		Dev:        p.Dev,
+		Platforms:  buildPlatforms(c, p),
+		User:       u,
 		Context:    bc,
 		LogSink:    ls,
 	}
@@ -463,6 +519,15 @@ func newBuildConfig(ls LogSink, f *config.DeployFilterFlags, p *config.PolicyFla
 	return &cfg
 }
 
+// buildPlatforms returns the platforms given by p, or else those configured
+// in c.
+func buildPlatforms(c LocalSousConfig, p *config.PolicyFlags) []string {
+	if p.Platforms != "" {
+		return docker.SplitPlatforms(p.Platforms)
+	}
+	return docker.SplitPlatforms(c.Docker.Platforms)
+}
+
 func newBuildManager(ls LogSink, bc *sous.BuildConfig, sl sous.Selector, lb sous.Labeller, rg sous.Registrar) *sous.BuildManager {
 	return &sous.BuildManager{
 		BuildConfig: bc,
@@ -504,15 +569,34 @@ func newLocalWorkDirShell(verbosity *config.Verbosity, l LocalWorkDir) (v LocalW
 	return v, initErr(err, "getting current working directory")
 }
 
-func newSelector(regClient LocalDockerClient, log LogSink) sous.Selector {
-	return docker.NewBuildStrategySelector(log.Child("docker-build-strategy"), regClient)
+func newSelector(cfg LocalSousConfig, regClient LocalDockerClient, log LogSink) (sous.Selector, error) {
+	ls := log.Child("docker-build-strategy")
+	bc := cfg.Buildpacks
+	var plugins []*docker.PluginBuildpack
+	if bc.PluginDir != "" {
+		var err error
+		if plugins, err = docker.DiscoverPlugins(bc.PluginDir, ls); err != nil {
+			return nil, err
+		}
+	}
+	return docker.NewPluginSelector(ls, regClient, plugins, bc.Priorities), nil
 }
 
-func newDockerBuilder(cfg LocalSousConfig, nc sous.ClientInserter, ctx *sous.SourceContext, source LocalWorkDirShell, scratch ScratchDirShell, log LogSink) (*docker.Builder, error) {
+func newDockerBuilder(cfg LocalSousConfig, nc sous.ClientInserter, source LocalWorkDirShell, scratch ScratchDirShell, log LogSink) (*docker.Builder, error) {
 	drh := cfg.Docker.RegistryHost
 	source.Sh = source.Sh.Clone().(*shell.Sh)
 	source.Sh.LongRunning(true)
-	return docker.NewBuilder(nc.Inserter, drh, source.Sh, scratch.Sh, log.Child("docker-builder"))
+	db, err := docker.NewBuilder(nc.Inserter, drh, source.Sh, scratch.Sh, log.Child("docker-builder"))
+	if err != nil {
+		return nil, err
+	}
+	if db.Signer, err = cfg.Signing.Signer(); err != nil {
+		return nil, initErr(err, "loading signing key")
+	}
+	if db.Scanner, err = cfg.Scanning.VulnerabilityScanner(); err != nil {
+		return nil, initErr(err, "creating vulnerability scanner")
+	}
+	return db, nil
 }
 
 func newLabeller(db *docker.Builder) sous.Labeller {
@@ -523,6 +607,45 @@ func newRegistrar(db *docker.Builder) sous.Registrar {
 	return db
 }
 
+func newRemoteBuilder(shc sous.SourceHostChooser, sl sous.Selector, lb sous.Labeller, rg sous.Registrar, ls LogSink) *sous.RemoteBuilder {
+	return &sous.RemoteBuilder{
+		SourceHosts: shc,
+		Selector:    sl,
+		Labeller:    lb,
+		Registrar:   rg,
+		LogSink:     ls.Child("remote-build"),
+	}
+}
+
+// newServerSourceBuilder builds source on this server if it is configured to,
+// registering artifacts directly rather than through a Sous server as
+// newDockerBuilder does.
+func newServerSourceBuilder(cfg LocalSousConfig, shc sous.SourceHostChooser, sl sous.Selector, ins serverInserter, source LocalWorkDirShell, scratch ScratchDirShell, log LogSink) (ServerSourceBuilder, error) {
+	if !cfg.BuildOnServer {
+		return ServerSourceBuilder{}, nil
+	}
+	sh := source.Sh.Clone().(*shell.Sh)
+	sh.LongRunning(true)
+	db, err := docker.NewBuilder(ins.Inserter, cfg.Docker.RegistryHost, sh, scratch.Sh, log.Child("docker-builder"))
+	if err != nil {
+		return ServerSourceBuilder{}, initErr(err, "creating server builder")
+	}
+	if db.Signer, err = cfg.Signing.Signer(); err != nil {
+		return ServerSourceBuilder{}, initErr(err, "loading signing key")
+	}
+	if db.Scanner, err = cfg.Scanning.VulnerabilityScanner(); err != nil {
+		return ServerSourceBuilder{}, initErr(err, "creating vulnerability scanner")
+	}
+	return ServerSourceBuilder{&sous.RemoteBuilder{
+		SourceHosts: shc,
+		Selector:    sl,
+		Labeller:    db,
+		Registrar:   db,
+		Platforms:   docker.SplitPlatforms(cfg.Docker.Platforms),
+		LogSink:     log.Child("server-build"),
+	}}, nil
+}
+
 func newRegistry(graph *SousGraph, nc lazyNameCache, dryrun DryrunOption, c LocalSousConfig) (sous.Registry, error) {
 	// We only need a real registry when running in server or workstation mode.
 	if c.Server == "" && dryrun != DryrunBoth && dryrun != DryrunRegistry {
@@ -531,27 +654,44 @@ func newRegistry(graph *SousGraph, nc lazyNameCache, dryrun DryrunOption, c Loca
 	return sous.NewDummyRegistry(), nil
 }
 
-func newDeployer(dryrun DryrunOption, nc lazyNameCache, ls LogSink, c LocalSousConfig) (sous.Deployer, error) {
+func newDeployer(dryrun DryrunOption, nc lazyNameCache, ls LogSink, c LocalSousConfig, ss SecretStore, states *sous.DeployStateCache) (sous.Deployer, error) {
 	// Eventually, based on configuration, we may make different decisions here.
 	if dryrun == DryrunBoth || dryrun == DryrunScheduler || c.Server != "" {
 		drc := sous.NewDummyRectificationClient()
 		drc.SetLogger(ls.Child("rectify"))
-		return singularity.NewDeployer(
-			drc,
-			ls.Child("singularity-deployer"),
-			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-		), nil
+		return sous.NewDispatchDeployer(map[string]sous.Deployer{
+			sous.ClusterKindSingularity: singularity.NewDeployer(
+				drc,
+				ls.Child("singularity-deployer"),
+				singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+			),
+			sous.ClusterKindKubernetes: kubernetes.NewDeployer(
+				c.Kubernetes,
+				ls.Child("kubernetes-deployer"),
+				kubernetes.OptDryRun(),
+			),
+		}), nil
 	}
 	// We need the real name cache.
 	labeller, err := nc()
 	if err != nil {
 		return nil, err
 	}
-	return singularity.NewDeployer(
-		singularity.NewRectiAgent(labeller, ls),
-		ls,
-		singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
-	), nil
+	ra := singularity.NewRectiAgent(labeller, ls)
+	ra.Secrets = ss.SecretStore
+	return sous.NewDispatchDeployer(map[string]sous.Deployer{
+		sous.ClusterKindSingularity: singularity.NewDeployer(
+			ra,
+			ls,
+			singularity.OptMaxHTTPReqsPerServer(c.MaxHTTPConcurrencySingularity),
+			singularity.OptDeployStates(states),
+		),
+		sous.ClusterKindKubernetes: kubernetes.NewDeployer(
+			c.Kubernetes,
+			ls.Child("kubernetes-deployer"),
+			kubernetes.OptSecrets(ss.SecretStore),
+		),
+	}), nil
 }
 
 func newServerHandler(g *SousGraph, Registry sous.Registry, ComponentLocator server.ComponentLocator, metrics MetricsHandler, log LogSink) ServerHandler {
@@ -561,6 +701,9 @@ func newServerHandler(g *SousGraph, Registry sous.Registry, ComponentLocator ser
 	g.Inject(&profileQuery)
 
 	ComponentLocator.Registry = Registry
+	if pr, ok := Registry.(sous.ProvenanceRegistry); ok {
+		ComponentLocator.Provenance = pr
+	}
 	if profileQuery.Yes {
 		handler = server.ProfilingHandler(ComponentLocator, metrics, log.Child("http-server"))
 	} else {
@@ -570,16 +713,21 @@ func newServerHandler(g *SousGraph, Registry sous.Registry, ComponentLocator ser
 	return ServerHandler{handler}
 }
 
+func newSecretStore(c LocalSousConfig) (SecretStore, error) {
+	ss, err := c.Secrets.SecretStore()
+	return SecretStore{ss}, err
+}
+
 func newServerListData(c HTTPClient) (ServerListData, error) {
 	serverList := ServerListData{}
 	_, err := c.Retrieve("./servers", nil, &serverList, nil)
 	return serverList, err
 }
 
-func newHTTPClientBundle(serverList ServerListData, tid sous.TraceID, log LogSink) (ClientBundle, error) {
+func newHTTPClientBundle(serverList ServerListData, c LocalSousConfig, tid sous.TraceID, log LogSink) (ClientBundle, error) {
 	bundle := ClientBundle{}
 	for _, s := range serverList.Servers {
-		client, err := restful.NewClient(s.URL, log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(tid)})
+		client, err := restful.NewClient(s.URL, log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(tid)}, restful.BearerAuth(c.APIToken))
 		if err != nil {
 			return nil, err
 		}
@@ -614,7 +762,7 @@ func newHTTPClient(c LocalSousConfig, user sous.User, tid sous.TraceID, log LogS
 		return HTTPClient{}, errors.New("no server configured")
 	}
 	messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("Using server %s", c.Server), logging.ExtraDebug1Level, log)
-	cl, err := restful.NewClient(c.Server, log.Child("http-client"), map[string]string{"OT-RequestId": string(tid)})
+	cl, err := restful.NewClient(c.Server, log.Child("http-client"), map[string]string{"OT-RequestId": string(tid)}, restful.BearerAuth(c.APIToken))
 	return HTTPClient{HTTPClient: cl}, err
 }
 
@@ -635,19 +783,19 @@ func newStatusPoller(cl HTTPClient, rf *RefinedResolveFilter, user sous.User, lo
 // The funcs named makeXXX below are used to create specific implementations of
 // sous native types.
 
-func newServerInserter(nc lazyNameCache) (serverInserter, error) {
+func newServerInserter(nc lazyNameCache, events *sous.ResolveEvents) (serverInserter, error) {
 	i, err := nc()
 	if err != nil {
 		return serverInserter{}, err
 	}
-	return serverInserter{i}, nil
+	return serverInserter{sous.NewEventInserter(i, events)}, nil
 }
 
 func newClientInserter(cfg LocalSousConfig, tid sous.TraceID, log LogSink) (sous.ClientInserter, error) {
-	cl, err := restful.NewClient(cfg.Server, log.Child("http-client"), map[string]string{"OT-RequestId": string(tid)})
-	return sous.ClientInserter{
-		Inserter: sous.NewHTTPNameInserter(cl, tid, log.LogSink),
-	}, err
+	cl, err := restful.NewClient(cfg.Server, log.Child("http-client"), map[string]string{"OT-RequestId": string(tid)}, restful.BearerAuth(cfg.APIToken))
+	hni := sous.NewHTTPNameInserter(cl, tid, log.LogSink)
+	hni.AuthToken = cfg.APIToken
+	return sous.ClientInserter{Inserter: hni}, err
 }
 
 // initErr returns nil if error is nil, otherwise an initialisation error.
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/opentable/sous/config"
@@ -174,12 +175,23 @@ func TestNewBuildConfig(t *testing.T) {
 		},
 	}
 
-	cfg := newBuildConfig(nonDefaultSilentLogSink, f, p, bc)
+	c := LocalSousConfig{Config: &config.Config{}}
+	c.Docker.Platforms = "linux/amd64"
+	cfg := newBuildConfig(nonDefaultSilentLogSink, c, sous.User{}, f, p, bc)
 	if cfg.Tag != `1.2.3` {
 		t.Errorf("Build config's tag wasn't 1.2.3: %#v", cfg.Tag)
 	}
 	if err := cfg.Validate(); err != nil {
 		t.Errorf("Not valid build config: %+v", err)
 	}
+	if !reflect.DeepEqual(cfg.Platforms, []string{"linux/amd64"}) {
+		t.Errorf("Build config's platforms weren't configured: %#v", cfg.Platforms)
+	}
+
+	p.Platforms = "linux/amd64, linux/arm64"
+	cfg = newBuildConfig(nonDefaultSilentLogSink, c, sous.User{}, f, p, bc)
+	if !reflect.DeepEqual(cfg.Platforms, []string{"linux/amd64", "linux/arm64"}) {
+		t.Errorf("Build config's platforms weren't taken from flags: %#v", cfg.Platforms)
+	}
 
 }
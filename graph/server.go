@@ -3,9 +3,11 @@ package graph
 import (
 	"fmt"
 
+	"github.com/opentable/sous/config"
 	sous "github.com/opentable/sous/lib"
 	"github.com/opentable/sous/server"
 	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
 	"github.com/samsalisbury/semv"
 )
 
@@ -19,6 +21,15 @@ func newServerComponentLocator(
 	v semv.Version,
 	qs *sous.R11nQueueSet,
 	ar *sous.AutoResolver,
+	rb *sous.Rollbacker,
+	d sous.Deployer,
+	h sous.DeploymentHistory,
+	al sous.AuditLog,
+	ids ServerIdentitySource,
+	ss SecretStore,
+	sb ServerSourceBuilder,
+	wh SingularityWebhooks,
+	dsc *sous.DeployStateCache,
 ) server.ComponentLocator {
 
 	logging.Deliver(ls, logging.SousGenericV1, logging.DebugLevel, logging.GetCallerInfo(),
@@ -32,29 +43,70 @@ func newServerComponentLocator(
 	case sous.DeploymentManager:
 		dm = ldm
 	}
+	var builds *sous.BuildJobs
+	if sb.SourceBuilder != nil {
+		builds = sous.NewBuildJobs(sb.SourceBuilder)
+	}
 	return server.ComponentLocator{
 
-		LogSink:           ls.LogSink,
-		Config:            cfg.Config,
-		Inserter:          ins.Inserter,
-		StateManager:      sm.StateManager,
-		ClusterManager:    cm.ClusterManager,
-		DeploymentManager: dm,
-		ResolveFilter:     rf,
-		Version:           v,
-		QueueSet:          qs,
-		AutoResolver:      ar,
+		LogSink:             ls.LogSink,
+		Config:              cfg.Config,
+		Inserter:            ins.Inserter,
+		StateManager:        sm.StateManager,
+		ClusterManager:      cm.ClusterManager,
+		DeploymentManager:   dm,
+		ResolveFilter:       rf,
+		Version:             v,
+		QueueSet:            qs,
+		AutoResolver:        ar,
+		Rollbacker:          rb,
+		Deployer:            d,
+		DeploymentHistory:   h,
+		AuditLog:            al,
+		Identity:            ids.IdentitySource,
+		Secrets:             ss.SecretStore,
+		Builds:              builds,
+		SingularityWebhooks: wh.Webhooks,
+		DeployStates:        dsc,
 	}
 
 }
 
+func newServerIdentitySource(cfg LocalSousConfig, mdb MaybeDatabase, log LogSink) (ServerIdentitySource, error) {
+	var tokens sous.TokenStore
+	if cfg.Authorization.Identity == config.IdentityTokenStore {
+		var err error
+		if tokens, err = newTokenStore(mdb, log); err != nil {
+			return ServerIdentitySource{}, err
+		}
+	}
+	ids, err := cfg.Authorization.IdentitySource(tokens)
+	return ServerIdentitySource{ids}, err
+}
+
 // NewR11nQueueSet returns a new queue set configured to start processing r11ns
-// immediately.
-func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager) *sous.R11nQueueSet {
+// immediately. Failed r11ns are passed to rb, which may roll them back, and
+// the outcome of each r11n is recorded in h if it is not nil. If qs has a
+// store, r11ns are recorded there, and those left pending by a previous server
+// are queued again.
+func NewR11nQueueSet(d sous.Deployer, r sous.Registry, rf *sous.ResolveFilter, sm *ServerStateManager, rb *sous.Rollbacker, h sous.DeploymentHistory, qs ServerR11nQueueStore, ls LogSink) *sous.R11nQueueSet {
 	sr := sm.StateManager
-	return sous.NewR11nQueueSet(sous.R11nQueueStartWithHandler(
+	set := sous.NewR11nQueueSet(sous.R11nQueueStorage(qs.R11nQueueStore, ls.Child("r11n-queue")), sous.R11nQueueStartWithHandler(
 		func(qr *sous.QueuedR11n) sous.DiffResolution {
+			pair := qr.Rectification.Pair
+			rb.Observe(&pair)
 			qr.Rectification.Begin(d, r, rf, sr)
-			return qr.Rectification.Wait()
+			rez := qr.Rectification.Wait()
+			rez.Rollback = rb.Resolved(&pair, rez)
+			if h != nil && pair.Post != nil {
+				if err := h.SetOutcome(pair.ID(), pair.Post.SourceID.Version, sous.ResolutionOutcome(rez)); err != nil {
+					logging.ReportError(ls, errors.Wrapf(err, "recording outcome of %s", pair.ID()))
+				}
+			}
+			return rez
 		}))
+	if err := set.Recover(ls.Child("r11n-queue")); err != nil {
+		logging.WarnMsg(ls, "queued deploys from before restart not recovered: %s", err)
+	}
+	return set
 }
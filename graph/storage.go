@@ -20,6 +20,9 @@ type (
 	ServerStateManager struct{ sous.StateManager }
 	// ServerClusterManager wraps the sous.ClusterManager interface and is used by `sous server`
 	ServerClusterManager struct{ sous.ClusterManager }
+	// ServerR11nQueueStore wraps the sous.R11nQueueStore interface and is used by `sous server`.
+	// Its R11nQueueStore is nil if there is no database.
+	ServerR11nQueueStore struct{ sous.R11nQueueStore }
 
 	// stateManagerAndErr contains a distributed state manager and the error
 	// returned from its construction.
@@ -49,6 +52,7 @@ func newClientStateManager(cl HTTPClient, c LocalSousConfig, mdb MaybeDatabase,
 		return nil, errors.New("no server configured for state management")
 	}
 	hsm := sous.NewHTTPStateManager(cl, tid, log.Child("http-state-manager"))
+	hsm.AuthToken = c.APIToken
 	return &ClientStateManager{StateManager: hsm}, nil
 }
 
@@ -56,16 +60,60 @@ func newDuplexStateManager(p primaryStateManager, s secondaryStateManager) duple
 	return duplexStateManager{primary: p, secondary: s}
 }
 
-func newServerStateManager(log LogSink, dsm duplexStateManager) *ServerStateManager {
+func newServerStateManager(log LogSink, dsm duplexStateManager, h sous.DeploymentHistory, al sous.AuditLog, events *sous.ResolveEvents) *ServerStateManager {
+	duplex := storage.NewDuplexStateManager(
+		dsm.primary, dsm.secondary, log.Child("duplex-state"),
+	)
+	hsm := sous.NewHistoryStateManager(duplex, h, log.Child("deployment-history"))
+	asm := sous.NewAuditStateManager(hsm, al, log.Child("audit-log"))
 	return &ServerStateManager{
-		StateManager: storage.NewDuplexStateManager(
-			dsm.primary, dsm.secondary, log.Child("duplex-state"),
-		),
+		StateManager: sous.NewEventStateManager(asm, events, log.Child("resolve-events")),
 	}
 }
 
-func newHTTPStateManager(cl HTTPClient, tid sous.TraceID, log LogSink) *sous.HTTPStateManager {
-	return sous.NewHTTPStateManager(cl, tid, log.Child("http-state-manager"))
+// newDeploymentHistory returns the database deployment history, or an
+// in-memory one if there is no database.
+func newDeploymentHistory(mdb MaybeDatabase, log LogSink) sous.DeploymentHistory {
+	if mdb.Err != nil {
+		logging.WarnMsg(log, "deployment history: no database, history will not be kept between restarts: %s", mdb.Err)
+		return sous.NewMemoryDeploymentHistory()
+	}
+	return storage.NewPostgresDeploymentHistory(mdb.Db, log.Child("deployment-history"))
+}
+
+// newAuditLog returns the database audit log, or an in-memory one if there is
+// no database.
+func newAuditLog(mdb MaybeDatabase, log LogSink) sous.AuditLog {
+	if mdb.Err != nil {
+		logging.WarnMsg(log, "audit log: no database, audit entries will not be kept between restarts: %s", mdb.Err)
+		return sous.NewMemoryAuditLog()
+	}
+	return storage.NewPostgresAuditLog(mdb.Db, log.Child("audit-log"))
+}
+
+// newR11nQueueStore returns the database store of queued r11ns, or none if
+// there is no database.
+func newR11nQueueStore(mdb MaybeDatabase, log LogSink) ServerR11nQueueStore {
+	if mdb.Err != nil {
+		logging.WarnMsg(log, "deploy queues: no database, queued deploys will be lost on restart: %s", mdb.Err)
+		return ServerR11nQueueStore{}
+	}
+	return ServerR11nQueueStore{storage.NewPostgresR11nQueueStore(mdb.Db, log.Child("r11n-queue"))}
+}
+
+// newTokenStore returns the store of API tokens. Tokens must be kept in the
+// database, so that they outlive the server and can be issued by operators.
+func newTokenStore(mdb MaybeDatabase, log LogSink) (sous.TokenStore, error) {
+	if mdb.Err != nil {
+		return nil, errors.Wrapf(mdb.Err, "API tokens are kept in the database")
+	}
+	return storage.NewPostgresTokenStore(mdb.Db, log.Child("api-tokens")), nil
+}
+
+func newHTTPStateManager(cl HTTPClient, c LocalSousConfig, tid sous.TraceID, log LogSink) *sous.HTTPStateManager {
+	hsm := sous.NewHTTPStateManager(cl, tid, log.Child("http-state-manager"))
+	hsm.AuthToken = c.APIToken
+	return hsm
 }
 
 // newPrimaryStateManager returns the configured primary, and any error
@@ -133,7 +181,7 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, tid sous.TraceID, rf *
 	clusterNames := []string{}
 	for n, u := range c.SiblingURLs {
 		// XXX not immediately clear how to conserve the request id through the distributed storage.
-		cl, err := restful.NewClient(u, log.Child(n+".http-client"))
+		cl, err := restful.NewClient(u, log.Child(n+".http-client"), restful.BearerAuth(c.APIToken))
 		if err != nil {
 			return nil, err
 		}
@@ -142,5 +190,6 @@ func newDistributedStorage(db *sql.DB, c LocalSousConfig, tid sous.TraceID, rf *
 	}
 	// XXX the first arg is used to get e.g. defs. Should be at least an in memory client for these purposes.
 	hsm := sous.NewHTTPStateManager(list[localName], tid, log.Child("http-state-manager"))
+	hsm.AuthToken = c.APIToken
 	return sous.NewDispatchStateManager(localName, clusterNames, local, hsm, log.Child("state-manager")), nil
 }
@@ -0,0 +1,179 @@
+package sous
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// An APIToken authenticates a client of the Sous server as a User. Only a
+	// hash of the token itself is ever stored.
+	APIToken struct {
+		// ID identifies the token when it is listed or revoked. It is a prefix
+		// of Hash.
+		ID string
+		// Hash is the hex encoded SHA-256 of the token.
+		Hash string `json:"-"`
+		// User is the user the token was issued to.
+		User User
+		// Groups are the groups the holder of the token belongs to, which may
+		// be named as manifest owners or as the admin group.
+		Groups []string `json:",omitempty"`
+		// Description is a note of what the token is for.
+		Description string `json:",omitempty"`
+		Created     time.Time
+		// Revoked is the time the token was revoked, or zero if it is live.
+		Revoked time.Time `json:",omitempty"`
+	}
+
+	// A TokenStore keeps the APITokens that have been issued.
+	TokenStore interface {
+		// Add stores a newly issued token.
+		Add(APIToken) error
+		// Lookup returns the live token with the given hash, or nil if there
+		// is none.
+		Lookup(hash string) (*APIToken, error)
+		// Revoke marks the live token with the given ID as revoked at a time.
+		Revoke(id string, at time.Time) error
+		// List returns all tokens, including revoked ones, oldest first.
+		List() ([]APIToken, error)
+	}
+
+	// MemoryTokenStore is a TokenStore which is only kept in memory.
+	MemoryTokenStore struct {
+		sync.Mutex
+		tokens []APIToken
+	}
+
+	// TokenIdentity is a restful.IdentitySource which identifies callers by
+	// an APIToken sent as a bearer token.
+	TokenIdentity struct {
+		Store TokenStore
+	}
+)
+
+// tokenIDLength is the number of hex digits of a token's hash used as its ID.
+const tokenIDLength = 12
+
+// HashAPIToken returns the hash of token under which it is stored.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIToken generates a new token for the User and Groups of t, and adds
+// it to ts. The returned token is the only copy of it: it cannot be recovered
+// from the store.
+func IssueAPIToken(ts TokenStore, t APIToken) (string, APIToken, error) {
+	if t.User.Name == "" && t.User.Email == "" {
+		return "", t, errors.New("tokens must be issued to a named user")
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", t, errors.Wrapf(err, "generating token")
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	t.Hash = HashAPIToken(token)
+	t.ID = t.Hash[:tokenIDLength]
+	t.Created = time.Now()
+	t.Revoked = time.Time{}
+	if err := ts.Add(t); err != nil {
+		return "", t, errors.Wrapf(err, "storing token")
+	}
+	return token, t, nil
+}
+
+// Live returns true if t has not been revoked.
+func (t APIToken) Live() bool {
+	return t.Revoked.IsZero()
+}
+
+// Principal returns the restful.Principal identified by t.
+func (t APIToken) Principal() *restful.Principal {
+	return &restful.Principal{
+		Name:   t.User.Name,
+		Email:  t.User.Email,
+		Groups: append([]string(nil), t.Groups...),
+	}
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+// Add implements TokenStore on MemoryTokenStore.
+func (s *MemoryTokenStore) Add(t APIToken) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, o := range s.tokens {
+		if o.ID == t.ID {
+			return errors.Errorf("token %s already exists", t.ID)
+		}
+	}
+	t.Groups = append([]string(nil), t.Groups...)
+	s.tokens = append(s.tokens, t)
+	return nil
+}
+
+// Lookup implements TokenStore on MemoryTokenStore.
+func (s *MemoryTokenStore) Lookup(hash string) (*APIToken, error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, t := range s.tokens {
+		if t.Hash == hash && t.Live() {
+			t := t
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// Revoke implements TokenStore on MemoryTokenStore.
+func (s *MemoryTokenStore) Revoke(id string, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+	for i, t := range s.tokens {
+		if t.ID == id && t.Live() {
+			s.tokens[i].Revoked = at
+			return nil
+		}
+	}
+	return errors.Errorf("no live token %q", id)
+}
+
+// List implements TokenStore on MemoryTokenStore.
+func (s *MemoryTokenStore) List() ([]APIToken, error) {
+	s.Lock()
+	defer s.Unlock()
+	tokens := append([]APIToken{}, s.tokens...)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Created.Before(tokens[j].Created)
+	})
+	return tokens, nil
+}
+
+// Identify implements restful.IdentitySource on TokenIdentity.
+func (id TokenIdentity) Identify(req *http.Request) (*restful.Principal, error) {
+	token, ok, err := restful.BearerToken(req)
+	if !ok || err != nil {
+		return nil, err
+	}
+	t, err := id.Store.Lookup(HashAPIToken(token))
+	if err != nil {
+		return nil, errors.Wrapf(err, "looking up token")
+	}
+	if t == nil {
+		return nil, errors.New("unknown or revoked token")
+	}
+	return t.Principal(), nil
+}
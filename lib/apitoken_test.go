@@ -0,0 +1,85 @@
+package sous
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAPIToken(t *testing.T) {
+	store := NewMemoryTokenStore()
+
+	_, _, err := IssueAPIToken(store, APIToken{})
+	assert.Error(t, err, "tokens need a user")
+
+	token, issued, err := IssueAPIToken(store, APIToken{
+		User:   User{Name: "Judson", Email: "judson@example.com"},
+		Groups: []string{"deployers"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, HashAPIToken(token), issued.Hash)
+	assert.Equal(t, issued.Hash[:tokenIDLength], issued.ID)
+	assert.NotContains(t, issued.Hash, token)
+
+	found, err := store.Lookup(issued.Hash)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "judson@example.com", found.User.Email)
+
+	other, _, err := IssueAPIToken(store, APIToken{User: User{Name: "Other"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	require.NoError(t, store.Revoke(issued.ID, time.Now()))
+	assert.Error(t, store.Revoke(issued.ID, time.Now()), "already revoked")
+	assert.Error(t, store.Revoke("unknown", time.Now()))
+
+	found, err = store.Lookup(issued.Hash)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	all, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.False(t, all[0].Live())
+	assert.True(t, all[1].Live())
+}
+
+func TestTokenIdentity(t *testing.T) {
+	store := NewMemoryTokenStore()
+	token, issued, err := IssueAPIToken(store, APIToken{
+		User:   User{Name: "Judson", Email: "judson@example.com"},
+		Groups: []string{"deployers"},
+	})
+	require.NoError(t, err)
+	ids := TokenIdentity{Store: store}
+
+	identify := func(auth string) error {
+		req := httptest.NewRequest("PUT", "/", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		// Headers describing the user are ignored in favour of the token.
+		req.Header.Set("Sous-User-Name", "Someone Else")
+		p, err := ids.Identify(req)
+		if auth == "" {
+			assert.Nil(t, p)
+		} else if err == nil && assert.NotNil(t, p) {
+			assert.Equal(t, "Judson", p.Name)
+			assert.Equal(t, "judson@example.com", p.Email)
+			assert.True(t, p.Is("deployers"))
+		}
+		return err
+	}
+
+	assert.NoError(t, identify(""))
+	assert.NoError(t, identify("Bearer "+token))
+	assert.Error(t, identify("Bearer not-"+token))
+
+	require.NoError(t, store.Revoke(issued.ID, time.Now()))
+	assert.Error(t, identify("Bearer "+token))
+}
@@ -0,0 +1,263 @@
+package sous
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+type (
+	// AuditLog stores an AuditEntry for every write to the GDM.
+	AuditLog interface {
+		// Record adds an entry to the log.
+		Record(AuditEntry) error
+		// Query returns the entries matching f, oldest first.
+		Query(f AuditFilter) ([]AuditEntry, error)
+	}
+
+	// AuditEntry records a single write to the GDM.
+	AuditEntry struct {
+		User    User
+		TraceID TraceID `json:",omitempty"`
+		Time    time.Time
+		// Changes lists each manifest changed by the write.
+		Changes []ManifestChange
+	}
+
+	// ManifestChange describes the changes made to a single manifest.
+	ManifestChange struct {
+		ManifestID ManifestID
+		// Clusters are the clusters whose deployments were affected.
+		Clusters []string
+		// Diffs are the differences between the manifest before and after, as
+		// reported by Manifest.Diff.
+		Diffs []string
+	}
+
+	// AuditFilter selects AuditEntries. Zero valued fields match everything.
+	AuditFilter struct {
+		Repo, Cluster string
+		// User matches either the name or email of the user.
+		User         string
+		Since, Until time.Time
+	}
+
+	// MemoryAuditLog is an AuditLog which is only kept in memory.
+	MemoryAuditLog struct {
+		sync.Mutex
+		entries []AuditEntry
+	}
+
+	// AuditStateManager is a StateManager that records an AuditEntry for
+	// each write.
+	AuditStateManager struct {
+		StateManager
+		Log     AuditLog
+		traceID TraceID
+		log     logging.LogSink
+	}
+)
+
+// Filter returns the part of e which matches f, and whether any of it did.
+// Only the Changes which match the Repo and Cluster of f are kept.
+func (f AuditFilter) Filter(e AuditEntry) (AuditEntry, bool) {
+	if f.User != "" && f.User != e.User.Name && f.User != e.User.Email {
+		return e, false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return e, false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return e, false
+	}
+	if f.Repo == "" && f.Cluster == "" {
+		return e, true
+	}
+	var changes []ManifestChange
+	for _, c := range e.Changes {
+		if f.Repo != "" && c.ManifestID.Source.Repo != f.Repo {
+			continue
+		}
+		if f.Cluster != "" && !stringInSlice(f.Cluster, c.Clusters) {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	e.Changes = changes
+	return e, len(changes) != 0
+}
+
+func stringInSlice(s string, ss []string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewMemoryAuditLog returns an empty MemoryAuditLog.
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Record implements AuditLog on MemoryAuditLog.
+func (l *MemoryAuditLog) Record(e AuditEntry) error {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, e)
+	return nil
+}
+
+// Query implements AuditLog on MemoryAuditLog.
+func (l *MemoryAuditLog) Query(f AuditFilter) ([]AuditEntry, error) {
+	l.Lock()
+	defer l.Unlock()
+	entries := []AuditEntry{}
+	for _, e := range l.entries {
+		if e, ok := f.Filter(e); ok {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// NewAuditStateManager wraps sm so that writes are recorded in al.
+func NewAuditStateManager(sm StateManager, al AuditLog, ls logging.LogSink) *AuditStateManager {
+	return &AuditStateManager{StateManager: sm, Log: al, log: ls}
+}
+
+// WithTraceID returns a copy of asm which records tid with each write.
+func (asm *AuditStateManager) WithTraceID(tid TraceID) *AuditStateManager {
+	c := *asm
+	c.traceID = tid
+	return &c
+}
+
+// TracedStateManager returns a StateManager that attributes writes to the
+// request tid, if sm keeps an audit log. Otherwise it returns sm.
+func TracedStateManager(sm StateManager, tid TraceID) StateManager {
+	if asm, ok := sm.(*AuditStateManager); ok {
+		return asm.WithTraceID(tid)
+	}
+	return sm
+}
+
+// WriteState implements StateWriter on AuditStateManager. Writes which change
+// no manifest are not recorded. Failing to record the audit entry is logged,
+// but does not fail the write.
+func (asm *AuditStateManager) WriteState(state *State, user User) error {
+	prior, err := asm.StateManager.ReadState()
+	if err != nil {
+		logging.ReportError(asm.log, errors.Wrap(err, "reading state for audit"))
+		return asm.StateManager.WriteState(state, user)
+	}
+	return asm.writeStateFrom(prior, state, user)
+}
+
+// writeStateFrom implements priorStateWriter on AuditStateManager.
+func (asm *AuditStateManager) writeStateFrom(prior, state *State, user User) error {
+	changes := ManifestChanges(prior.Manifests, state.Manifests)
+	if err := writeStateFrom(asm.StateManager, prior, state, user); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entry := AuditEntry{User: user, TraceID: asm.traceID, Time: time.Now(), Changes: changes}
+	if err := asm.Log.Record(entry); err != nil {
+		logging.ReportError(asm.log, errors.Wrap(err, "recording audit entry"))
+	}
+	return nil
+}
+
+// ManifestChanges returns the changes between prior and next, ordered by
+// ManifestID.
+func ManifestChanges(prior, next Manifests) []ManifestChange {
+	ids := map[ManifestID]struct{}{}
+	for _, mid := range prior.Keys() {
+		ids[mid] = struct{}{}
+	}
+	for _, mid := range next.Keys() {
+		ids[mid] = struct{}{}
+	}
+
+	var changes []ManifestChange
+	for mid := range ids {
+		before, hadBefore := prior.Get(mid)
+		after, hasAfter := next.Get(mid)
+		c := ManifestChange{ManifestID: mid}
+		switch {
+		case !hadBefore:
+			c.Diffs = []string{"manifest added"}
+			c.Clusters = specClusters(after.Deployments, nil)
+		case !hasAfter:
+			c.Diffs = []string{"manifest removed"}
+			c.Clusters = specClusters(before.Deployments, nil)
+		default:
+			different, diffs := before.Diff(after)
+			if !different {
+				continue
+			}
+			c.Diffs = diffs
+			if before.Kind != after.Kind || !NewOwnerSet(before.Owners...).Equal(NewOwnerSet(after.Owners...)) {
+				// Manifest-wide changes affect every deployment.
+				c.Clusters = specClusters(before.Deployments, after.Deployments)
+			} else {
+				c.Clusters = changedClusters(before.Deployments, after.Deployments)
+			}
+		}
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ManifestID.String() < changes[j].ManifestID.String()
+	})
+	return changes
+}
+
+// specClusters returns the sorted names of the clusters in either a or b.
+func specClusters(a, b DeploySpecs) []string {
+	names := map[string]struct{}{}
+	for n := range a {
+		names[n] = struct{}{}
+	}
+	for n := range b {
+		names[n] = struct{}{}
+	}
+	return sortedNames(names)
+}
+
+// changedClusters returns the sorted names of the clusters whose DeploySpecs
+// differ between a and b.
+func changedClusters(a, b DeploySpecs) []string {
+	names := map[string]struct{}{}
+	for n, spec := range a {
+		other, ok := b[n]
+		if !ok {
+			names[n] = struct{}{}
+			continue
+		}
+		if different, _ := spec.Diff(other); different {
+			names[n] = struct{}{}
+		}
+	}
+	for n := range b {
+		if _, ok := a[n]; !ok {
+			names[n] = struct{}{}
+		}
+	}
+	return sortedNames(names)
+}
+
+func sortedNames(names map[string]struct{}) []string {
+	ns := make([]string, 0, len(names))
+	for n := range names {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
@@ -0,0 +1,158 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditStateManager_WriteState(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	al := NewMemoryAuditLog()
+	asm := NewAuditStateManager(sm, al, logging.SilentLogSet())
+
+	next := sm.State.Clone()
+	mid := next.Manifests.Keys()[0]
+	m, _ := next.Manifests.Get(mid)
+	spec := m.Deployments["cluster1"]
+	spec.Version = semv.MustParse("2.0.0")
+	m.Deployments["cluster1"] = spec
+
+	user := User{Name: "Test User", Email: "test@example.com"}
+	require.NoError(t, TracedStateManager(asm, "trace-1").WriteState(next, user))
+	assert.Equal(t, 1, sm.WriteCount)
+
+	entries, err := al.Query(AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, user, e.User)
+	assert.Equal(t, TraceID("trace-1"), e.TraceID)
+	require.Len(t, e.Changes, 1)
+	assert.Equal(t, mid, e.Changes[0].ManifestID)
+	assert.Equal(t, []string{"cluster1"}, e.Changes[0].Clusters)
+	assert.NotEmpty(t, e.Changes[0].Diffs)
+
+	// Writes which change nothing are not recorded.
+	require.NoError(t, asm.WriteState(next.Clone(), user))
+	assert.Equal(t, 2, sm.WriteCount)
+	entries, err = al.Query(AuditFilter{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// The trace ID is not kept by the original.
+	next = next.Clone()
+	m, _ = next.Manifests.Get(mid)
+	spec.Version = semv.MustParse("3.0.0")
+	m.Deployments["cluster1"] = spec
+	require.NoError(t, asm.WriteState(next, user))
+	entries, err = al.Query(AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, TraceID(""), entries[1].TraceID)
+}
+
+func TestAuditStateManager_sharesPriorRead(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	al := NewMemoryAuditLog()
+	esm := NewEventStateManager(NewAuditStateManager(sm, al, logging.SilentLogSet()),
+		NewResolveEvents(), logging.SilentLogSet())
+
+	next := sm.State.Clone()
+	m, _ := next.Manifests.Get(next.Manifests.Keys()[0])
+	spec := m.Deployments["cluster1"]
+	spec.NumInstances++
+	m.Deployments["cluster1"] = spec
+
+	require.NoError(t, esm.WriteState(next, User{}))
+	assert.Equal(t, 1, sm.ReadCount)
+	entries, err := al.Query(AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Len(t, entries[0].Changes, 1)
+}
+
+func TestAuditStateManager_WriteState_error(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	sm.WriteErr = assert.AnError
+	al := NewMemoryAuditLog()
+	asm := NewAuditStateManager(sm, al, logging.SilentLogSet())
+
+	assert.Error(t, asm.WriteState(sm.State.Clone(), User{}))
+	assert.Len(t, al.entries, 0)
+}
+
+func TestManifestChanges(t *testing.T) {
+	mid := func(repo string) ManifestID {
+		return ManifestID{Source: SourceLocation{Repo: repo}}
+	}
+	manifest := func(repo string, clusters ...string) *Manifest {
+		m := &Manifest{Source: SourceLocation{Repo: repo}, Deployments: DeploySpecs{}}
+		for _, c := range clusters {
+			m.Deployments[c] = DeploySpec{Version: semv.MustParse("1.0.0")}
+		}
+		return m
+	}
+
+	prior := NewManifests(manifest("a", "c1", "c2"), manifest("b", "c1"))
+	changed := manifest("a", "c1", "c2")
+	spec := changed.Deployments["c2"]
+	spec.NumInstances = 3
+	changed.Deployments["c2"] = spec
+	next := NewManifests(changed, manifest("c", "c3"))
+
+	changes := ManifestChanges(prior, next)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, mid("a"), changes[0].ManifestID)
+	assert.Equal(t, []string{"c2"}, changes[0].Clusters)
+
+	assert.Equal(t, mid("b"), changes[1].ManifestID)
+	assert.Equal(t, []string{"manifest removed"}, changes[1].Diffs)
+	assert.Equal(t, []string{"c1"}, changes[1].Clusters)
+
+	assert.Equal(t, mid("c"), changes[2].ManifestID)
+	assert.Equal(t, []string{"manifest added"}, changes[2].Diffs)
+	assert.Equal(t, []string{"c3"}, changes[2].Clusters)
+}
+
+func TestAuditFilter_Filter(t *testing.T) {
+	now := time.Now()
+	e := AuditEntry{
+		User: User{Name: "Jo", Email: "jo@example.com"},
+		Time: now,
+		Changes: []ManifestChange{
+			{ManifestID: ManifestID{Source: SourceLocation{Repo: "a"}}, Clusters: []string{"c1", "c2"}},
+			{ManifestID: ManifestID{Source: SourceLocation{Repo: "b"}}, Clusters: []string{"c2"}},
+		},
+	}
+
+	got, ok := AuditFilter{}.Filter(e)
+	assert.True(t, ok)
+	assert.Len(t, got.Changes, 2)
+
+	got, ok = AuditFilter{Cluster: "c1"}.Filter(e)
+	assert.True(t, ok)
+	require.Len(t, got.Changes, 1)
+	assert.Equal(t, "a", got.Changes[0].ManifestID.Source.Repo)
+
+	got, ok = AuditFilter{Repo: "b", User: "jo@example.com"}.Filter(e)
+	assert.True(t, ok)
+	assert.Len(t, got.Changes, 1)
+
+	_, ok = AuditFilter{Repo: "b", Cluster: "c1"}.Filter(e)
+	assert.False(t, ok)
+	_, ok = AuditFilter{User: "someone"}.Filter(e)
+	assert.False(t, ok)
+	_, ok = AuditFilter{Since: now.Add(time.Minute)}.Filter(e)
+	assert.False(t, ok)
+	_, ok = AuditFilter{Until: now.Add(-time.Minute)}.Filter(e)
+	assert.False(t, ok)
+}
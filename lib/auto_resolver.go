@@ -7,6 +7,7 @@ import (
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -23,6 +24,9 @@ type (
 	// loop of resolution cycles.
 	AutoResolver struct {
 		UpdateTime time.Duration
+		// Events, if not nil, reports changes which are resolved as they
+		// happen, between full resolves: see ListenTo.
+		Events *ResolveEvents
 		StateReader
 		GDM Deployments
 		*Resolver
@@ -31,9 +35,18 @@ type (
 		sync.RWMutex
 		stableStatus, liveStatus *ResolveStatus
 		currentRecorder          *ResolveRecorder
+		// statusVersion counts the changes to stableStatus, and
+		// statusChanged is closed at the next.
+		statusVersion uint64
+		statusChanged chan struct{}
 	}
 )
 
+// SweepTime is the time between full resolves by an AutoResolver which
+// resolves changes as they happen. The full resolves catch changes which are
+// not reported, such as those made directly to a scheduler.
+const SweepTime = 10 * time.Minute
+
 func (tc TriggerChannel) trigger() {
 	tc <- TriggerType{}
 }
@@ -51,6 +64,13 @@ func NewAutoResolver(rez *Resolver, sr StateReader, ls logging.LogSink) *AutoRes
 	return ar
 }
 
+// ListenTo makes ar resolve the deployments affected by events as they are
+// reported. Full resolves then run only every SweepTime.
+func (ar *AutoResolver) ListenTo(events *ResolveEvents) {
+	ar.Events = events
+	ar.UpdateTime = SweepTime
+}
+
 // StandardListeners adds the usual listeners into the auto-resolve cycle.
 func (ar *AutoResolver) StandardListeners() {
 	ar.addListener(func(trigger, done TriggerChannel, ch announceChannel) {
@@ -117,6 +137,27 @@ func (ar *AutoResolver) Statuses() (stable, live *ResolveStatus) {
 	return ar.stableStatus, ar.liveStatus
 }
 
+// StatusVersion returns the number of times the stable status returned by
+// Statuses has changed, and a channel which is closed when it next changes.
+func (ar *AutoResolver) StatusVersion() (uint64, <-chan struct{}) {
+	ar.Lock()
+	defer ar.Unlock()
+	if ar.statusChanged == nil {
+		ar.statusChanged = make(chan struct{})
+	}
+	return ar.statusVersion, ar.statusChanged
+}
+
+// setStableStatus must be called with ar locked for writing.
+func (ar *AutoResolver) setStableStatus(ss *ResolveStatus) {
+	ar.stableStatus = ss
+	ar.statusVersion++
+	if ar.statusChanged != nil {
+		close(ar.statusChanged)
+	}
+	ar.statusChanged = make(chan struct{})
+}
+
 func loopTilDone(f func(), done TriggerChannel) {
 	for {
 		select {
@@ -144,6 +185,9 @@ func (ar *AutoResolver) resolveLoop(tc, done TriggerChannel, ac announceChannel)
 	select {
 	case <-done:
 		return
+	case <-ar.Events.Signal():
+		ar.resolveAffected()
+		return
 	case <-tc:
 	}
 	for {
@@ -189,11 +233,42 @@ func (ar *AutoResolver) resolveOnce(ac announceChannel) {
 
 		reportResolverStatus(ar.LogSink, &ss)
 
-		ar.stableStatus = &ss
+		ar.setStableStatus(&ss)
 	})
 	ar.Statuses() // XXX this is debugging
 }
 
+// resolveAffected resolves the deployments affected by the pending events.
+// Unlike a full resolve, it is not announced. Its results replace those for
+// the same deployments in ar's stable status, so that clients waiting for
+// them see them without waiting for the next full resolve.
+func (ar *AutoResolver) resolveAffected() {
+	state, err := ar.StateReader.ReadState()
+	if err != nil {
+		logging.ReportError(ar.LogSink, errors.Wrap(err, "reading state to resolve changes"))
+		return
+	}
+	gdm, err := state.Deployments()
+	if err != nil {
+		logging.ReportError(ar.LogSink, errors.Wrap(err, "reading state to resolve changes"))
+		return
+	}
+	ids := ar.Events.Affected(gdm)
+	if len(ids) == 0 {
+		return
+	}
+	messages.ReportLogFieldsMessage(fmt.Sprintf("Resolving %d changed deployments", len(ids)),
+		logging.InformationLevel, ar.LogSink)
+	recorder := ar.Resolver.BeginOnly(gdm, state.Defs.Clusters, ids)
+	if err := recorder.Wait(); err != nil {
+		logging.ReportError(ar.LogSink, errors.Wrap(err, "resolving changes"))
+	}
+	ar.write(func() {
+		ss := recorder.CurrentStatus()
+		ar.setStableStatus(ar.stableStatus.withChanges(ids, ss))
+	})
+}
+
 func (ar *AutoResolver) afterDone(tc, done TriggerChannel, ac announceChannel) {
 	select {
 	case <-done:
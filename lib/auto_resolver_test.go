@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nyarly/spies"
 	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
 )
@@ -100,3 +101,54 @@ func TestResolveLoop(t *testing.T) {
 		t.Error("Should have announced a result")
 	}
 }
+
+func TestResolveLoop_events(t *testing.T) {
+	state := DefaultStateFixture()
+	deployer, ctl := NewDeployerSpy()
+	ctl.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(), nil)
+	var rectified []DeploymentID
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		rectified = append(rectified, qr.Rectification.Pair.ID())
+		return DiffResolution{DeploymentID: qr.Rectification.Pair.ID()}
+	}))
+	rez := NewResolver(deployer, NewDummyRegistry(), &ResolveFilter{},
+		logging.SilentLogSet(), qs)
+	ar := NewAutoResolver(rez, &DummyStateManager{State: state}, logging.SilentLogSet())
+	events := NewResolveEvents()
+	ar.ListenTo(events)
+	assert.Equal(t, SweepTime, ar.UpdateTime)
+
+	tc := make(TriggerChannel, 1)
+	ac := make(announceChannel, 1)
+	done := make(TriggerChannel)
+
+	did := DeploymentID{ManifestID: state.Manifests.Keys()[0], Cluster: "cluster2"}
+	_, statusChanged := ar.StatusVersion()
+	events.DeploymentsChanged(did)
+	ar.resolveLoop(tc, done, ac)
+
+	calls := ctl.CallsTo("RunningDeployments")
+	if assert.Len(t, calls, 1) {
+		clusters := calls[0].PassedArgs().Get(1).(Clusters)
+		assert.Equal(t, []string{"cluster2"}, clusters.Names())
+	}
+	assert.Equal(t, []DeploymentID{did}, rectified)
+	select {
+	case <-ac:
+		t.Error("Changes should not be announced as a full resolve")
+	default:
+	}
+	stable, _ := ar.Statuses()
+	if assert.NotNil(t, stable, "Changes should be reported in the stable status") {
+		if assert.Len(t, stable.Log, 1) {
+			assert.Equal(t, did, stable.Log[0].DeploymentID)
+		}
+	}
+	select {
+	case <-statusChanged:
+	default:
+		t.Error("Stable status change not signalled")
+	}
+	version, _ := ar.StatusVersion()
+	assert.Equal(t, uint64(1), version)
+}
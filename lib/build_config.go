@@ -14,8 +14,15 @@ type (
 	BuildConfig struct {
 		Repo, Offset, Tag, Revision string
 		Strict, ForceClone, Dev     bool
-		Context                     *BuildContext
-		LogSink                     logging.LogSink
+		// Platforms lists the platforms to build for, as os/architecture or
+		// os/architecture/variant. If it is empty, the build is for the
+		// platform of the Docker daemon alone.
+		Platforms []string
+		// User is the user who asked for the build, recorded in its
+		// provenance.
+		User    User
+		Context *BuildContext
+		LogSink logging.LogSink
 	}
 )
 
@@ -92,8 +99,21 @@ func (c *BuildConfig) Resolve() {
 
 // Validate checks that the Config is well formed
 func (c *BuildConfig) Validate() error {
-	_, _, err := parseSemverTagWithOptionalPrefix(c.Tag)
-	return err
+	if _, _, err := parseSemverTagWithOptionalPrefix(c.Tag); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, p := range c.Platforms {
+		parts := strings.Split(p, "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("platform %q must be os/architecture[/variant]", p)
+		}
+		if seen[p] {
+			return fmt.Errorf("platform %q is listed twice", p)
+		}
+		seen[p] = true
+	}
+	return nil
 }
 
 // GuardStrict returns an error if there are imperfections in the proposed build
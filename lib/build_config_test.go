@@ -424,3 +424,13 @@ func TestBuildConfig_GuardRegister(t *testing.T) {
 		t.Errorf("got error %q; want %q", actual, expected)
 	}
 }
+
+func TestBuildConfigValidatePlatforms(t *testing.T) {
+	bc := BuildConfig{Tag: "1.2.3", Platforms: []string{"linux/amd64", "linux/arm/v7"}}
+	assert.NoError(t, bc.Validate())
+
+	for _, bad := range [][]string{{"linux"}, {"linux/"}, {"linux/arm/v7/x"}, {"linux/amd64", "linux/amd64"}} {
+		bc.Platforms = bad
+		assert.Error(t, bc.Validate(), "%v", bad)
+	}
+}
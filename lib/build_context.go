@@ -8,21 +8,26 @@ import (
 )
 
 type (
-	// BuildContext contains all the data required to perform a build.
+	// BuildContext contains all the data required to perform a build. It is
+	// also what buildpack plugins receive, encoded as JSON, so its shells are
+	// left out of the encoding.
 	BuildContext struct {
-		Sh         shell.Shell
+		Sh         shell.Shell `json:"-"`
 		Source     SourceContext
 		Scratch    ScratchContext
 		Machine    Machine
 		User       user.User
 		Changes    Changes
 		Advisories Advisories
+		// Platform is the platform to build for, as os/architecture[/variant],
+		// when building for several. It is empty otherwise.
+		Platform string `json:",omitempty"`
 	}
 
 	// ScratchContext represents an isolated copy of a project's source code
 	// somewhere on the host machine running Sous.
 	ScratchContext struct {
-		Sh                 *shell.Sh
+		Sh                 *shell.Sh `json:"-"`
 		RootDir, OffsetDir string
 	}
 
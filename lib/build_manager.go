@@ -3,6 +3,7 @@ package sous
 import (
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/opentable/sous/util/firsterr"
 	"github.com/opentable/sous/util/logging"
@@ -29,19 +30,46 @@ func (m *BuildManager) Build() (*BuildResult, error) {
 		bc *BuildContext
 		br *BuildResult
 	)
+	start := time.Now()
 	err := firsterr.Set(
 		func(e *error) { *e = m.BuildConfig.Validate() },
 		func(e *error) { bc = m.BuildConfig.NewContext() },
 		func(e *error) { *e = m.BuildConfig.GuardStrict(bc) },
 		func(e *error) { bp, *e = m.SelectBuildpack(bc) },
-		func(e *error) { br, *e = bp.Build(bc) },
+		func(e *error) { br, *e = m.build(bp, bc) },
 		func(e *error) { br.Contextualize(bc) },
+		func(e *error) { *e = br.recordProvenance(bp, bc, m.BuildConfig.User, start) },
 		func(e *error) { *e = m.ApplyMetadata(br) },
 		func(e *error) { *e = m.RegisterAndWarnAdvisories(br) },
 	)
 	return br, errors.Wrap(err, "unable to build")
 }
 
+// build builds bc with bp: once, or once for each platform in the
+// BuildConfig. In the latter case, each product records its platform, so
+// that the Registrar can gather them into manifest lists.
+func (m *BuildManager) build(bp Buildpack, bc *BuildContext) (*BuildResult, error) {
+	if len(m.BuildConfig.Platforms) == 0 {
+		return bp.Build(bc)
+	}
+	start := time.Now()
+	all := &BuildResult{}
+	for _, p := range m.BuildConfig.Platforms {
+		pc := *bc
+		pc.Platform = p
+		br, err := bp.Build(&pc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "building for %s", p)
+		}
+		for _, prdt := range br.Products {
+			prdt.Platform = p
+		}
+		all.Products = append(all.Products, br.Products...)
+	}
+	all.Elapsed = time.Since(start)
+	return all, nil
+}
+
 // RegisterAndWarnAdvisories registers the image if there are no blocking
 // advisories; warns about the advisories and does not register otherwise.
 func (m *BuildManager) RegisterAndWarnAdvisories(br *BuildResult) error {
@@ -149,3 +149,27 @@ func TestBuildManager_RegisterAndWarnAdvisories_noAdvisories(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestBuildManagerBuildsEachPlatform(t *testing.T) {
+	bp := &recordingBuildpack{}
+	bm := &BuildManager{
+		BuildConfig: &BuildConfig{Platforms: []string{"linux/amd64", "linux/arm64"}},
+		LogSink:     logging.SilentLogSet(),
+	}
+
+	br, err := bm.build(bp, &BuildContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(br.Products) != 2 {
+		t.Fatalf("got %d products; want 2", len(br.Products))
+	}
+	for i, p := range []string{"linux/amd64", "linux/arm64"} {
+		if br.Products[i].Platform != p {
+			t.Errorf("product %d platform = %q; want %q", i, br.Products[i].Platform, p)
+		}
+	}
+	if bp.built.Platform != "linux/arm64" {
+		t.Errorf("last build was for %q; want linux/arm64", bp.built.Platform)
+	}
+}
@@ -76,6 +76,16 @@ type (
 		VersionName     string
 		DigestReference string
 		Qualities       Qualities
+		// Platforms is set when DigestReference names a manifest list. It maps
+		// each platform in the list to the digest reference of its image.
+		Platforms map[string]string `json:",omitempty"`
+		// Provenance and SBOM record how the artifact was built. Since they
+		// are not needed to deploy it, looking it up leaves them out unless
+		// they are asked for.
+		Provenance *Provenance `json:",omitempty"`
+		SBOM       *SBOM       `json:",omitempty"`
+		// Signatures sign the image digest in DigestReference.
+		Signatures []Signature `json:",omitempty"`
 	}
 
 	// A BuildProduct is one of the individual outputs of a buildpack.
@@ -98,6 +108,23 @@ type (
 		VersionName  string
 		RevisionName string
 		DigestName   string
+
+		// Platform is the platform the product was built for, when it was
+		// built for several.
+		Platform string
+		// Platforms is set on a product which is a manifest list, mapping each
+		// platform to the DigestName of its image.
+		Platforms map[string]string
+
+		// Provenance records how the product was built, and SBOM what it
+		// depends on.
+		Provenance *Provenance
+		SBOM       *SBOM
+
+		// Signatures sign the image digest in DigestName.
+		Signatures []Signature
+		// Vulnerabilities is the result of scanning the product, if it was.
+		Vulnerabilities *VulnerabilityReport
 	}
 )
 
@@ -147,10 +174,17 @@ func (bp BuildProduct) BuildArtifact() BuildArtifact {
 		DigestReference: bp.DigestName,
 		Type:            bp.Kind,
 		Qualities:       make(Qualities, 0, len(bp.Advisories)),
+		Platforms:       bp.Platforms,
+		Provenance:      bp.Provenance,
+		SBOM:            bp.SBOM,
+		Signatures:      bp.Signatures,
 	}
 	for _, adv := range bp.Advisories {
 		ba.Qualities = append(ba.Qualities, Quality{Name: string(adv), Kind: "advisory"})
 	}
+	if bp.Vulnerabilities != nil {
+		ba.Qualities = append(ba.Qualities, bp.Vulnerabilities.Qualities()...)
+	}
 	return ba
 }
 
@@ -0,0 +1,20 @@
+package sous
+
+const (
+	// ClusterKindSingularity is the Cluster.Kind of clusters managed by a
+	// Singularity scheduler. Clusters with an empty Kind are assumed to be
+	// Singularity clusters.
+	ClusterKindSingularity = "singularity"
+	// ClusterKindKubernetes is the Cluster.Kind of clusters managed by a
+	// Kubernetes API server.
+	ClusterKindKubernetes = "kubernetes"
+)
+
+// ClusterKind returns the effective kind of a cluster, defaulting to
+// ClusterKindSingularity.
+func (c *Cluster) ClusterKind() string {
+	if c == nil || c.Kind == "" {
+		return ClusterKindSingularity
+	}
+	return c.Kind
+}
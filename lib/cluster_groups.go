@@ -0,0 +1,187 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClusterGroups maps the names of groups of clusters, e.g. "prod", to the
+// names of the clusters in them, e.g. [prod-sc, prod-ln]. A manifest's
+// DeploySpec for a group is its DeploySpec for each cluster in the group
+// which it does not name itself.
+type ClusterGroups map[string][]string
+
+// Names returns the names of the groups, sorted alphabetically.
+func (cgs ClusterGroups) Names() []string {
+	names := make([]string, 0, len(cgs))
+	for name := range cgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a deep copy of cgs.
+func (cgs ClusterGroups) Clone() ClusterGroups {
+	if cgs == nil {
+		return nil
+	}
+	c := make(ClusterGroups, len(cgs))
+	for name, members := range cgs {
+		c[name] = append([]string(nil), members...)
+	}
+	return c
+}
+
+// Diff reports the differences between cgs and o.
+func (cgs ClusterGroups) Diff(o ClusterGroups) []string {
+	vs := []string{}
+	for _, name := range cgs.Names() {
+		om, has := o[name]
+		if !has {
+			vs = append(vs, fmt.Sprintf("Doesn't have cluster group %s", name))
+			continue
+		}
+		if !stringSlicesEqual(cgs[name], om) {
+			vs = append(vs, fmt.Sprintf("cluster group %s members differ", name))
+		}
+	}
+	for _, name := range o.Names() {
+		if _, has := cgs[name]; !has {
+			vs = append(vs, fmt.Sprintf("Has extra cluster group %s", name))
+		}
+	}
+	return vs
+}
+
+// validate returns the flaws in cgs, given the clusters they group.
+func (cgs ClusterGroups) validate(clusters Clusters) []Flaw {
+	var flaws []Flaw
+	for _, name := range cgs.Names() {
+		if _, isCluster := clusters[name]; isCluster {
+			flaws = append(flaws, FatalFlaw("cluster group %s: has the name of a cluster", name))
+		}
+		if len(cgs[name]) == 0 {
+			flaws = append(flaws, FatalFlaw("cluster group %s: has no clusters", name))
+		}
+		seen := map[string]bool{}
+		for _, member := range cgs[name] {
+			if _, defined := clusters[member]; !defined {
+				flaws = append(flaws, FatalFlaw("cluster group %s: cluster %q is not defined", name, member))
+			}
+			if seen[member] {
+				flaws = append(flaws, FatalFlaw("cluster group %s: lists cluster %q twice", name, member))
+			}
+			seen[member] = true
+		}
+	}
+	return flaws
+}
+
+// isGroup is true if name is the name of a cluster group in d, rather than
+// of a cluster.
+func (d Defs) isGroup(name string) bool {
+	if _, isCluster := d.Clusters[name]; isCluster {
+		return false
+	}
+	_, isGroup := d.ClusterGroups[name]
+	return isGroup
+}
+
+// clusterKeys returns, for each cluster m deploys to, the key of its
+// DeploySpec in m.Deployments: the cluster's own name if m has a spec for it,
+// or else the name of the first group in m which it is a member of. Keys
+// naming neither a cluster nor a group in d are ignored.
+func (d Defs) clusterKeys(m *Manifest) map[string]string {
+	keys := map[string]string{}
+	names := m.Deployments.ClusterNames()
+	for _, name := range names {
+		if _, isCluster := d.Clusters[name]; isCluster {
+			keys[name] = name
+		}
+	}
+	for _, name := range names {
+		if !d.isGroup(name) {
+			continue
+		}
+		for _, member := range d.ClusterGroups[name] {
+			if _, has := keys[member]; !has {
+				keys[member] = name
+			}
+		}
+	}
+	return keys
+}
+
+// ClusterSpec returns the DeploySpec of m's deployment to cluster, which m
+// has either for the cluster itself, or for a group in d.ClusterGroups which
+// the cluster is a member of, and false if m does not deploy to cluster.
+func (d Defs) ClusterSpec(m *Manifest, cluster string) (DeploySpec, bool) {
+	if spec, own := m.Deployments[cluster]; own {
+		return spec, true
+	}
+	key, has := d.clusterKeys(m)[cluster]
+	if !has {
+		return DeploySpec{}, false
+	}
+	return m.Deployments[key], true
+}
+
+// SetClusterSpec sets the DeploySpec of m's deployment to cluster to spec.
+// Where m's deployment to cluster is that of a group, the group keeps the
+// spec most of its clusters share, and the others are given specs of their
+// own: see foldGroup.
+func (d Defs) SetClusterSpec(m *Manifest, cluster string, spec DeploySpec) {
+	key, has := d.clusterKeys(m)[cluster]
+	if _, own := m.Deployments[cluster]; own || !has {
+		m.Deployments[cluster] = spec
+		return
+	}
+	old := m.Deployments[key]
+	for _, member := range d.ClusterGroups[key] {
+		if _, own := m.Deployments[member]; !own {
+			m.Deployments[member] = old
+		}
+	}
+	m.Deployments[cluster] = spec
+	d.foldGroup(m, key, old)
+}
+
+// foldGroup replaces the specs m has for each cluster in group with a single
+// spec for the group, where it can. If every cluster in the group has a spec,
+// the group's spec becomes the one most of them have, preferring old, the
+// group's previous spec, and then that of the first cluster, and the clusters
+// with that spec no longer have their own. If any cluster has no spec, m no
+// longer deploys to the group.
+func (d Defs) foldGroup(m *Manifest, group string, old DeploySpec) {
+	members := d.ClusterGroups[group]
+	for _, member := range members {
+		if _, has := m.Deployments[member]; !has {
+			delete(m.Deployments, group)
+			return
+		}
+	}
+
+	count := func(spec DeploySpec) int {
+		n := 0
+		for _, member := range members {
+			if m.Deployments[member].Equal(spec) {
+				n++
+			}
+		}
+		return n
+	}
+	common, most := old, count(old)
+	for _, member := range members {
+		if n := count(m.Deployments[member]); n > most {
+			common, most = m.Deployments[member], n
+		}
+	}
+
+	m.Deployments[group] = common
+	for _, member := range members {
+		if m.Deployments[member].Equal(common) {
+			delete(m.Deployments, member)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clusterGroupsTestState() *State {
+	return defsFixtureState(groupedClustersFixture("sc", "ln", "ny"),
+		serviceManifestFixture("github.com/user/project", DeploySpecs{
+			"ci":      deploySpecFixture("2.0.0", DeployConfig{NumInstances: 1}),
+			"prod":    deploySpecFixture("1.0.0", DeployConfig{NumInstances: 4}),
+			"prod-ny": deploySpecFixture("1.0.0", DeployConfig{NumInstances: 2}),
+		}),
+	)
+}
+
+func TestDeploymentsFromManifest_clusterGroups(t *testing.T) {
+	s := clusterGroupsTestState()
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+	assert.Equal(t, 4, ds.Len())
+
+	instances := map[string]int{}
+	for _, d := range ds.Snapshot() {
+		instances[d.ClusterName] = d.NumInstances
+	}
+	assert.Equal(t, map[string]int{"ci": 1, "prod-sc": 4, "prod-ln": 4, "prod-ny": 2}, instances)
+
+	d, _ := ds.Single(func(d *Deployment) bool { return d.ClusterName == "prod-ln" })
+	assert.Equal(t, "ln", d.Env["DC"])
+}
+
+func TestDeployments_PutbackManifestClusterGroups(t *testing.T) {
+	s := clusterGroupsTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+	ls, _ := logging.NewLogSinkSpy()
+
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+	ms, err := ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ := ms.Get(m.ID())
+	if different, diffs := m.Diff(put); different {
+		t.Errorf("manifest changed by round trip: %v", diffs)
+	}
+
+	// Deploying to one cluster of the group gives it a spec of its own.
+	d, _ := ds.Single(func(d *Deployment) bool { return d.ClusterName == "prod-sc" })
+	d.SourceID.Version = semv.MustParse("2.0.0")
+	ms, err = ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ = ms.Get(m.ID())
+	assert.Equal(t, []string{"ci", "prod", "prod-ny", "prod-sc"}, put.Deployments.ClusterNames())
+	assert.Equal(t, "1.0.0", put.Deployments["prod"].Version.String())
+	assert.Equal(t, "2.0.0", put.Deployments["prod-sc"].Version.String())
+
+	// Once most of the group is deployed to, the group follows them.
+	d, _ = ds.Single(func(d *Deployment) bool { return d.ClusterName == "prod-ln" })
+	d.SourceID.Version = semv.MustParse("2.0.0")
+	ms, err = ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ = ms.Get(m.ID())
+	assert.Equal(t, []string{"ci", "prod", "prod-ny"}, put.Deployments.ClusterNames())
+	assert.Equal(t, "2.0.0", put.Deployments["prod"].Version.String())
+}
+
+func TestDefs_SetClusterSpec(t *testing.T) {
+	s := clusterGroupsTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+
+	spec, ok := s.Defs.ClusterSpec(m, "prod-sc")
+	require.True(t, ok)
+	assert.Equal(t, 4, spec.NumInstances)
+	spec, ok = s.Defs.ClusterSpec(m, "prod-ny")
+	require.True(t, ok)
+	assert.Equal(t, 2, spec.NumInstances)
+	_, ok = s.Defs.ClusterSpec(m, "elsewhere")
+	assert.False(t, ok)
+
+	spec, _ = s.Defs.ClusterSpec(m, "prod-sc")
+	spec.Version = semv.MustParse("2.0.0")
+	s.Defs.SetClusterSpec(m, "prod-sc", spec)
+	assert.Equal(t, []string{"ci", "prod", "prod-ny", "prod-sc"}, m.Deployments.ClusterNames())
+
+	spec, _ = s.Defs.ClusterSpec(m, "prod-ln")
+	spec.Version = semv.MustParse("2.0.0")
+	s.Defs.SetClusterSpec(m, "prod-ln", spec)
+	assert.Equal(t, []string{"ci", "prod", "prod-ny"}, m.Deployments.ClusterNames())
+	assert.Equal(t, "2.0.0", m.Deployments["prod"].Version.String())
+}
+
+func TestDefs_Validate_clusterGroups(t *testing.T) {
+	s := clusterGroupsTestState()
+	s.Defs.ClusterGroups["ci"] = []string{"ci"}
+	s.Defs.ClusterGroups["empty"] = nil
+	s.Defs.ClusterGroups["typo"] = []string{"prod-sf", "prod-sc", "prod-sc"}
+
+	assert.Equal(t, []string{
+		"cluster group ci: has the name of a cluster",
+		"cluster group empty: has no clusters",
+		`cluster group typo: cluster "prod-sf" is not defined`,
+		`cluster group typo: lists cluster "prod-sc" twice`,
+	}, flawStrings(s.Defs.Validate()))
+}
+
+func TestDefs_ValidateManifest_overlappingGroups(t *testing.T) {
+	s := clusterGroupsTestState()
+	s.Defs.ClusterGroups["west"] = []string{"prod-sc", "ci"}
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+	m.Deployments["west"] = m.Deployments["prod"]
+
+	assert.Equal(t, []string{
+		`manifest "github.com/user/project": cluster prod-sc is in both groups prod and west, so needs a deployment of its own`,
+	}, flawStrings(s.Defs.ValidateManifest(m)))
+}
@@ -21,3 +21,13 @@ const VersionLabel = "com.opentable.sous.version"
 
 // RevisionLabel is a metadata fieldname that records the git revision ID of a Sous-controlled service.
 const RevisionLabel = "com.opentable.sous.revision"
+
+// SecretEnvLabel is a metadata fieldname that records, as a JSON object, the
+// SecretRef that each secret environment variable of a deploy was resolved
+// from.
+const SecretEnvLabel = "com.opentable.sous.secret_env"
+
+// ResourcesLabel is a metadata fieldname that records, as a JSON object, the
+// resources of a deploy which its scheduler has no field for, in Singularity
+// deploy metadata or Kubernetes annotations.
+const ResourcesLabel = "com.opentable.sous.resources"
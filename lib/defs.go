@@ -2,6 +2,7 @@ package sous
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/opentable/sous/util/restful"
 )
@@ -46,6 +47,26 @@ func (ds *Defs) Diff(o *Defs) []string {
 	vs = append(vs, prefixed("metadata ", ds.Metadata.Diff(o.Metadata))...)
 	vs = append(vs, prefixed("envdefs ", ds.EnvVars.Diff(o.EnvVars))...)
 
+	if strings.Join(ds.PromotionOrder, ",") != strings.Join(o.PromotionOrder, ",") {
+		vs = append(vs, "PromotionOrder differs")
+	}
+
+	vs = append(vs, ds.ClusterGroups.Diff(o.ClusterGroups)...)
+
+	if len(ds.Templates) != len(o.Templates) {
+		vs = append(vs, "Different number of templates")
+	} else {
+		for name, dc := range ds.Templates {
+			odc, has := o.Templates[name]
+			if !has {
+				vs = append(vs, fmt.Sprintf("Doesn't have template %s", name))
+				continue
+			}
+			_, diffs := dc.Diff(odc)
+			vs = append(vs, prefixed("template "+name+": ", diffs)...)
+		}
+	}
+
 	return vs
 }
 
@@ -89,6 +110,12 @@ func (ed EnvDef) Diff(o EnvDef) []string {
 	if ed.Type != o.Type {
 		vs = append(vs, "types differ")
 	}
+	if ed.Required != o.Required {
+		vs = append(vs, "requiredness differs")
+	}
+	if ed.Default != o.Default {
+		vs = append(vs, "defaults differ")
+	}
 	return vs
 }
 
@@ -172,6 +199,26 @@ func (c *Cluster) Diff(oc *Cluster) []string {
 
 	vs = append(vs, prefixed("startup ", c.Startup.diff(oc.Startup))...)
 
+	if c.AutoRollback != oc.AutoRollback {
+		vs = append(vs, "auto-rollback differs")
+	}
+
+	if !c.FreezeWindows.Equal(oc.FreezeWindows) {
+		vs = append(vs, "freeze windows differ")
+	}
+
+	if !stringSlicesEqual(c.SigningKeys, oc.SigningKeys) {
+		vs = append(vs, "signing keys differ")
+	}
+
+	if !c.VulnerabilityThresholds.Equal(oc.VulnerabilityThresholds) {
+		vs = append(vs, "vulnerability thresholds differ")
+	}
+
+	if !c.ResourceLimits.Equal(oc.ResourceLimits) {
+		vs = append(vs, "resource limits differ")
+	}
+
 	if len(c.AllowedAdvisories) != len(oc.AllowedAdvisories) {
 		vs = append(vs, "advisories whitelist length differs")
 	} else {
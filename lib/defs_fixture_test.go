@@ -0,0 +1,42 @@
+package sous
+
+import "github.com/samsalisbury/semv"
+
+// defsFixtureState returns a State with defs and manifests.
+func defsFixtureState(defs Defs, manifests ...*Manifest) *State {
+	s := NewState()
+	s.Defs = defs
+	for _, m := range manifests {
+		s.Manifests.Add(m)
+	}
+	return s
+}
+
+// serviceManifestFixture returns a service manifest for repo deployed as specs.
+func serviceManifestFixture(repo string, specs DeploySpecs) *Manifest {
+	return &Manifest{
+		Source:      SourceLocation{Repo: repo},
+		Kind:        ManifestKindService,
+		Deployments: specs,
+	}
+}
+
+// deploySpecFixture returns a DeploySpec of version with config.
+func deploySpecFixture(version string, config DeployConfig) DeploySpec {
+	return DeploySpec{Version: semv.MustParse(version), DeployConfig: config}
+}
+
+// groupedClustersFixture returns Defs with a "ci" cluster and a "prod" group
+// of one "prod-<dc>" cluster per dc, each with DC set in its env.
+func groupedClustersFixture(dcs ...string) Defs {
+	defs := Defs{
+		Clusters:      Clusters{"ci": &Cluster{Name: "ci"}},
+		ClusterGroups: ClusterGroups{},
+	}
+	for _, dc := range dcs {
+		name := "prod-" + dc
+		defs.Clusters[name] = &Cluster{Name: name, Env: EnvDefaults{"DC": Var(dc)}}
+		defs.ClusterGroups["prod"] = append(defs.ClusterGroups["prod"], name)
+	}
+	return defs
+}
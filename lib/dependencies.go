@@ -0,0 +1,438 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// Dependency declares that the deployments of a manifest require those
+	// of another manifest, in the same cluster, to be deployed.
+	Dependency struct {
+		// Manifest is the ID of the manifest depended on.
+		Manifest ManifestID
+		// Version, if set, is the range of versions of Manifest which will
+		// do, e.g. ">=1.2.0" or "^1.2.0 <1.4.0".
+		Version VersionRange `yaml:",omitempty"`
+		// Clusters, if set, limits the dependency to these clusters, or the
+		// clusters of these cluster groups. Otherwise it applies to each
+		// cluster the manifest deploys to.
+		Clusters []string `yaml:",omitempty"`
+	}
+
+	// Dependencies is a list of Dependency.
+	Dependencies []Dependency
+
+	// VersionRange is a range of versions, made up of constraints separated
+	// by spaces, each of which the versions in the range satisfy. A
+	// constraint is a version, optionally prefixed by one of ==, =, >=, <=,
+	// >, <, ~ or ^, as understood by semv.ParseRange.
+	VersionRange string
+
+	// DependencyViolation describes a deployment whose dependency is not
+	// met.
+	DependencyViolation struct {
+		// DeploymentID is the ID of the deployment which has the dependency.
+		DeploymentID DeploymentID
+		// Dependency is the ID of the deployment depended on.
+		Dependency DeploymentID
+		// Reason is why the dependency is not met.
+		Reason string
+	}
+
+	// DependencyError reports that a change to the GDM would leave some
+	// deployments' dependencies not met.
+	DependencyError struct {
+		Violations []DependencyViolation
+	}
+)
+
+// Validate returns an error if vr is not a valid range.
+func (vr VersionRange) Validate() error {
+	_, err := vr.ranges()
+	return err
+}
+
+// SatisfiedBy returns true if v is in vr. An invalid range is satisfied by no
+// version.
+func (vr VersionRange) SatisfiedBy(v semv.Version) bool {
+	rs, err := vr.ranges()
+	if err != nil {
+		return false
+	}
+	for _, r := range rs {
+		if !r.SatisfiedBy(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (vr VersionRange) ranges() ([]semv.Range, error) {
+	var rs []semv.Range
+	for _, c := range strings.Fields(string(vr)) {
+		r, err := semv.ParseRange(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "version range %q", vr)
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// Clone returns a deep copy of ds.
+func (ds Dependencies) Clone() Dependencies {
+	if ds == nil {
+		return nil
+	}
+	c := make(Dependencies, len(ds))
+	for i, d := range ds {
+		d.Clusters = append([]string(nil), d.Clusters...)
+		if len(d.Clusters) == 0 {
+			d.Clusters = nil
+		}
+		c[i] = d
+	}
+	return c
+}
+
+// Equal returns true if ds and o declare the same dependencies, in the same
+// order.
+func (ds Dependencies) Equal(o Dependencies) bool {
+	if len(ds) != len(o) {
+		return false
+	}
+	for i, d := range ds {
+		if d.Manifest != o[i].Manifest || d.Version != o[i].Version || !stringSlicesEqual(d.Clusters, o[i].Clusters) {
+			return false
+		}
+	}
+	return true
+}
+
+// inCluster returns the dependencies of ds which apply to cluster, given the
+// cluster groups of defs, without their Clusters.
+func (ds Dependencies) inCluster(defs Defs, cluster string) Dependencies {
+	var in Dependencies
+	for _, d := range ds {
+		if !d.appliesTo(defs, cluster) {
+			continue
+		}
+		d.Clusters = nil
+		in = append(in, d)
+	}
+	return in
+}
+
+func (d Dependency) appliesTo(defs Defs, cluster string) bool {
+	if len(d.Clusters) == 0 {
+		return true
+	}
+	for _, name := range d.Clusters {
+		if name == cluster {
+			return true
+		}
+		if defs.isGroup(name) {
+			for _, member := range defs.ClusterGroups[name] {
+				if member == cluster {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// validateDependencies returns the flaws in the dependencies of m which can
+// be found without the rest of the GDM.
+func (m *Manifest) validateDependencies() []Flaw {
+	var flaws []Flaw
+	for _, d := range m.Dependencies {
+		if d.Manifest == m.ID() {
+			flaws = append(flaws, FatalFlaw("manifest %q depends on itself", m.ID()))
+		}
+		if err := d.Version.Validate(); err != nil {
+			flaws = append(flaws, FatalFlaw("manifest %q: dependency on %q: %v", m.ID(), d.Manifest, err))
+		}
+	}
+	return flaws
+}
+
+func (v DependencyViolation) String() string {
+	return fmt.Sprintf("%s requires %s: %s", v.DeploymentID, v.Dependency, v.Reason)
+}
+
+func (e *DependencyError) Error() string {
+	descs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		descs[i] = v.String()
+	}
+	return "dependencies not met: " + strings.Join(descs, "; ")
+}
+
+// IsDependencyError returns true if the cause of err is a DependencyError.
+func IsDependencyError(err error) bool {
+	_, is := errors.Cause(err).(*DependencyError)
+	return is
+}
+
+// DependencyViolations returns the dependencies of the deployments in ds
+// which are not met by the deployments in ds, ordered by the IDs of the
+// deployments which have them.
+func (ds Deployments) DependencyViolations() []DependencyViolation {
+	var vs []DependencyViolation
+	for _, d := range ds.Snapshot() {
+		for _, dep := range d.Dependencies {
+			did := DeploymentID{ManifestID: dep.Manifest, Cluster: d.ClusterName}
+			violation := func(format string, a ...interface{}) {
+				vs = append(vs, DependencyViolation{
+					DeploymentID: d.ID(),
+					Dependency:   did,
+					Reason:       fmt.Sprintf(format, a...),
+				})
+			}
+			target, has := ds.Get(did)
+			if !has || target.NumInstances == 0 {
+				violation("not deployed to %s", d.ClusterName)
+				continue
+			}
+			if dep.Version == "" {
+				continue
+			}
+			if version := target.SourceID.Version; !dep.Version.SatisfiedBy(version) {
+				violation("version %s is not in %q", version, dep.Version)
+			}
+		}
+	}
+	sort.SliceStable(vs, func(i, j int) bool {
+		return vs[i].DeploymentID.String() < vs[j].DeploymentID.String()
+	})
+	return vs
+}
+
+// CheckDependencies returns a *DependencyError if next has dependencies which
+// are not met that prior does not, i.e. if the changes from prior to next
+// would break dependencies.
+func CheckDependencies(prior, next Deployments) error {
+	already := map[DependencyViolation]struct{}{}
+	for _, v := range prior.DependencyViolations() {
+		already[v] = struct{}{}
+	}
+	var added []DependencyViolation
+	for _, v := range next.DependencyViolations() {
+		if _, was := already[v]; !was {
+			added = append(added, v)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return &DependencyError{Violations: added}
+}
+
+// dependencyWaits tracks the rectifications of the pairs received so far
+// by queueDiffs, so that the rectification of each pair can wait for those of
+// the pairs in the same cluster whose deployments its deployment depends on.
+type dependencyWaits struct {
+	pairs map[DeploymentID]*pairOutcome
+	// waiting maps the ID of each pair waiting for its dependencies to the
+	// IDs of the pairs it waits for.
+	waiting map[DeploymentID][]DeploymentID
+	// depended holds the IDs of the deployments which pairs received so far
+	// depend on.
+	depended map[DeploymentID]bool
+	// received is closed once every pair has been received.
+	received chan struct{}
+	sync.Mutex
+}
+
+// pairOutcome is the outcome of the rectification of a pair.
+type pairOutcome struct {
+	received bool
+	done     chan struct{}
+	// rez is set before done is closed, and is nil if the pair needed no
+	// rectification.
+	rez *DiffResolution
+}
+
+func newDependencyWaits() *dependencyWaits {
+	return &dependencyWaits{
+		pairs:    map[DeploymentID]*pairOutcome{},
+		waiting:  map[DeploymentID][]DeploymentID{},
+		depended: map[DeploymentID]bool{},
+		received: make(chan struct{}),
+	}
+}
+
+// outcome returns the outcome of the pair with id, whether or not it has
+// been received yet. It assumes w is locked.
+func (w *dependencyWaits) outcome(id DeploymentID) *pairOutcome {
+	o, has := w.pairs[id]
+	if !has {
+		o = &pairOutcome{done: make(chan struct{})}
+		w.pairs[id] = o
+	}
+	return o
+}
+
+// receive records that p has been received, and returns false if a pair with
+// its ID had been already.
+func (w *dependencyWaits) receive(p *DeployablePair) bool {
+	w.Lock()
+	defer w.Unlock()
+	id := p.ID()
+	o := w.outcome(id)
+	if o.received {
+		return false
+	}
+	o.received = true
+	if p.Post != nil && p.Post.Deployment != nil {
+		for _, dep := range p.Post.Dependencies {
+			did := DeploymentID{ManifestID: dep.Manifest, Cluster: id.Cluster}
+			if did != id {
+				w.depended[did] = true
+			}
+		}
+	}
+	return true
+}
+
+// dependedOn returns true if any pair depends on the deployment with id. If
+// none received so far does, it waits until every pair has been received.
+func (w *dependencyWaits) dependedOn(id DeploymentID) bool {
+	w.Lock()
+	depended := w.depended[id]
+	w.Unlock()
+	if depended {
+		return true
+	}
+	<-w.received
+	w.Lock()
+	defer w.Unlock()
+	return w.depended[id]
+}
+
+// receivedAll records that no more pairs will be received, so that no pair
+// waits for a dependency which has none.
+func (w *dependencyWaits) receivedAll() {
+	close(w.received)
+}
+
+// finish records rez as the outcome of the pair with id.
+func (w *dependencyWaits) finish(id DeploymentID, rez *DiffResolution) {
+	w.Lock()
+	defer w.Unlock()
+	o := w.outcome(id)
+	o.rez = rez
+	close(o.done)
+	delete(w.waiting, id)
+}
+
+// await waits for the rectifications of the pairs p depends on to finish,
+// and returns an error if any of them failed to become active. A dependency
+// which would complete a cycle of pairs waiting for each other is not waited
+// for, so that the pairs in a cycle are rectified in spite of it.
+func (w *dependencyWaits) await(p *DeployablePair) error {
+	if p.Post == nil || p.Post.Deployment == nil {
+		return nil
+	}
+	id := p.ID()
+	for _, dep := range p.Post.Dependencies {
+		did := DeploymentID{ManifestID: dep.Manifest, Cluster: id.Cluster}
+		w.Lock()
+		if did == id || w.reaches(did, id) {
+			w.Unlock()
+			continue
+		}
+		w.waiting[id] = append(w.waiting[id], did)
+		o := w.outcome(did)
+		w.Unlock()
+
+		select {
+		case <-o.done:
+		case <-w.received:
+			w.Lock()
+			received := o.received
+			w.Unlock()
+			if !received {
+				continue
+			}
+			<-o.done
+		}
+
+		switch rez := o.rez; {
+		case rez == nil:
+		case rez.Error != nil:
+			return errors.Errorf("dependency %s was not rectified: %s", did, rez.Error)
+		case rez.Desc != DeleteDiff && rez.DeployState != nil && rez.DeployState.Status != DeployStatusActive:
+			return errors.Errorf("dependency %s is %s", did, rez.DeployState.Status)
+		}
+	}
+	return nil
+}
+
+// reaches returns true if the pair with id from waits, directly or not, for
+// the pair with id to. It assumes w is locked.
+func (w *dependencyWaits) reaches(from, to DeploymentID) bool {
+	seen := map[DeploymentID]bool{}
+	var visit func(id DeploymentID) bool
+	visit = func(id DeploymentID) bool {
+		if id == to {
+			return true
+		}
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		for _, next := range w.waiting[id] {
+			if visit(next) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+// CheckManifestDependencies returns a *DependencyError if putting m in place
+// of the manifest with its ID in s would break the dependencies of m, or of
+// the manifests which depend on it.
+func (s *State) CheckManifestDependencies(m *Manifest) error {
+	prior, next := NewManifests(), NewManifests()
+	involve := func(mid ManifestID) {
+		if old, has := s.Manifests.Get(mid); has {
+			prior.Set(mid, old)
+			next.Set(mid, old)
+		}
+	}
+	for _, other := range s.Manifests.Snapshot() {
+		for _, d := range other.Dependencies {
+			if d.Manifest == m.ID() {
+				involve(other.ID())
+			}
+		}
+	}
+	if len(m.Dependencies) == 0 && next.Len() == 0 {
+		return nil
+	}
+	for _, d := range m.Dependencies {
+		involve(d.Manifest)
+	}
+	involve(m.ID())
+	next.Set(m.ID(), m)
+
+	priorDeployments, err := prior.Deployments(s.Defs)
+	if err != nil {
+		return errors.Wrap(err, "getting current deployments")
+	}
+	nextDeployments, err := next.Deployments(s.Defs)
+	if err != nil {
+		return err
+	}
+	return CheckDependencies(priorDeployments, nextDeployments)
+}
@@ -0,0 +1,449 @@
+package sous
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dependenciesTestState() *State {
+	manifest := func(repo, version string, deps ...Dependency) *Manifest {
+		m := serviceManifestFixture(repo, DeploySpecs{
+			"ci":   deploySpecFixture(version, DeployConfig{NumInstances: 1}),
+			"prod": deploySpecFixture(version, DeployConfig{NumInstances: 2}),
+		})
+		m.Dependencies = deps
+		return m
+	}
+	return defsFixtureState(groupedClustersFixture("sc", "ln"),
+		manifest("github.com/user/api", "1.0.0",
+			Dependency{Manifest: MustParseManifestID("github.com/user/db"), Version: ">=2.0.0 <3.0.0"},
+			Dependency{Manifest: MustParseManifestID("github.com/user/cache"), Clusters: []string{"prod"}},
+		),
+		manifest("github.com/user/db", "2.1.0"),
+	)
+}
+
+func TestVersionRange(t *testing.T) {
+	vr := VersionRange(">=1.2.0 <2.0.0")
+	assert.NoError(t, vr.Validate())
+	assert.True(t, vr.SatisfiedBy(semv.MustParse("1.2.0")))
+	assert.True(t, vr.SatisfiedBy(semv.MustParse("1.9.3")))
+	assert.False(t, vr.SatisfiedBy(semv.MustParse("1.1.0")))
+	assert.False(t, vr.SatisfiedBy(semv.MustParse("2.0.0")))
+
+	assert.True(t, VersionRange("").SatisfiedBy(semv.MustParse("0.0.1")))
+	assert.True(t, VersionRange("^1.2.0").SatisfiedBy(semv.MustParse("1.4.0")))
+	assert.Error(t, VersionRange(">=1.2.0 banana").Validate())
+	assert.False(t, VersionRange("banana").SatisfiedBy(semv.MustParse("1.0.0")))
+}
+
+func TestDeploymentsFromManifest_dependencies(t *testing.T) {
+	s := dependenciesTestState()
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+
+	api := MustParseManifestID("github.com/user/api")
+	ci, _ := ds.Get(DeploymentID{ManifestID: api, Cluster: "ci"})
+	require.Len(t, ci.Dependencies, 1)
+	assert.Equal(t, "github.com/user/db", ci.Dependencies[0].Manifest.String())
+
+	prod, _ := ds.Get(DeploymentID{ManifestID: api, Cluster: "prod-ln"})
+	require.Len(t, prod.Dependencies, 2)
+	assert.Nil(t, prod.Dependencies[1].Clusters)
+}
+
+func TestDeployments_DependencyViolations(t *testing.T) {
+	s := dependenciesTestState()
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"prod-ln:github.com/user/api requires prod-ln:github.com/user/cache: not deployed to prod-ln",
+		"prod-sc:github.com/user/api requires prod-sc:github.com/user/cache: not deployed to prod-sc",
+	}, violationStrings(ds.DependencyViolations()))
+
+	db, _ := ds.Get(DeploymentID{ManifestID: MustParseManifestID("github.com/user/db"), Cluster: "ci"})
+	db.SourceID.Version = semv.MustParse("3.0.0")
+	vs := ds.DependencyViolations()
+	require.Len(t, vs, 3)
+	assert.Equal(t, `ci:github.com/user/api requires ci:github.com/user/db: version 3.0.0 is not in ">=2.0.0 <3.0.0"`, vs[0].String())
+}
+
+func TestCheckDependencies(t *testing.T) {
+	s := dependenciesTestState()
+	prior, err := s.Deployments()
+	require.NoError(t, err)
+
+	// Violations which were there already don't stop other changes.
+	next := prior.Clone()
+	api, _ := next.Get(DeploymentID{ManifestID: MustParseManifestID("github.com/user/api"), Cluster: "ci"})
+	api.SourceID.Version = semv.MustParse("1.1.0")
+	assert.NoError(t, CheckDependencies(prior, next))
+
+	db, _ := next.Get(DeploymentID{ManifestID: MustParseManifestID("github.com/user/db"), Cluster: "ci"})
+	db.SourceID.Version = semv.MustParse("1.9.0")
+	err = CheckDependencies(prior, next)
+	if assert.Error(t, err) {
+		assert.True(t, IsDependencyError(err))
+		assert.Contains(t, err.Error(), "ci:github.com/user/api requires ci:github.com/user/db")
+	}
+}
+
+func TestState_CheckManifestDependencies(t *testing.T) {
+	s := dependenciesTestState()
+	db, _ := s.Manifests.Get(MustParseManifestID("github.com/user/db"))
+
+	m := db.Clone()
+	m.Deployments["prod"] = DeploySpec{Version: semv.MustParse("2.2.0"), DeployConfig: DeployConfig{NumInstances: 2}}
+	assert.NoError(t, s.CheckManifestDependencies(m))
+
+	delete(m.Deployments, "ci")
+	err := s.CheckManifestDependencies(m)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ci:github.com/user/api requires ci:github.com/user/db: not deployed to ci")
+	}
+}
+
+func TestManifest_Validate_dependencies(t *testing.T) {
+	s := dependenciesTestState()
+	m, _ := s.Manifests.Get(MustParseManifestID("github.com/user/api"))
+	m.Dependencies = append(m.Dependencies,
+		Dependency{Manifest: m.ID()},
+		Dependency{Manifest: MustParseManifestID("github.com/user/queue"), Version: ">=one"},
+		Dependency{Manifest: MustParseManifestID("github.com/user/queue"), Clusters: []string{"staging"}},
+	)
+
+	flaws := flawStrings(m.Validate())
+	require.Len(t, flaws, 2)
+	assert.Equal(t, `manifest "github.com/user/api" depends on itself`, flaws[0])
+	assert.Contains(t, flaws[1], `manifest "github.com/user/api": dependency on "github.com/user/queue": version range ">=one"`)
+
+	assert.Equal(t, []string{
+		`manifest "github.com/user/api": dependency on "github.com/user/queue": cluster "staging" is not defined`,
+	}, flawStrings(s.Defs.ValidateManifest(m)))
+}
+
+func TestDeployments_PutbackManifestDependencies(t *testing.T) {
+	s := dependenciesTestState()
+	ls, _ := logging.NewLogSinkSpy()
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+
+	ms, err := ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	for _, m := range s.Manifests.Snapshot() {
+		put, _ := ms.Get(m.ID())
+		if different, diffs := m.Diff(put); different {
+			t.Errorf("manifest %q changed by round trip: %v", m.ID(), diffs)
+		}
+	}
+}
+
+func TestDependencyWaits(t *testing.T) {
+	pair := func(repo, cluster string, deps ...string) *DeployablePair {
+		d := &Deployment{ClusterName: cluster, SourceID: MustParseSourceID(repo + ",1.0.0")}
+		for _, dep := range deps {
+			d.Dependencies = append(d.Dependencies, Dependency{Manifest: MustParseManifestID(dep)})
+		}
+		p := &DeployablePair{Post: &Deployable{Deployment: d}}
+		p.SetID(d.ID())
+		return p
+	}
+	pairs := []*DeployablePair{
+		pair("github.com/a", "ci", "github.com/b", "github.com/c"),
+		pair("github.com/b", "ci", "github.com/c"),
+		// A dependency in another cluster is not waited for.
+		pair("github.com/a", "prod", "github.com/c"),
+		// Nor is the dependency which closes a cycle.
+		pair("github.com/x", "ci", "github.com/y"),
+		pair("github.com/y", "ci", "github.com/x"),
+		pair("github.com/c", "ci"),
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	w := newDependencyWaits()
+	for _, p := range pairs {
+		require.True(t, w.receive(p))
+		wg.Add(1)
+		go func(p *DeployablePair) {
+			defer wg.Done()
+			assert.NoError(t, w.await(p))
+			mu.Lock()
+			order = append(order, p.ID().String())
+			mu.Unlock()
+			w.finish(p.ID(), nil)
+		}(p)
+	}
+	assert.False(t, w.receive(pairs[0]))
+	w.receivedAll()
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pairs still waiting for each other")
+	}
+
+	indexOf := func(id string) int {
+		for i, o := range order {
+			if o == id {
+				return i
+			}
+		}
+		return -1
+	}
+	assert.Len(t, order, len(pairs))
+	assert.True(t, indexOf("ci:github.com/c") < indexOf("ci:github.com/b"))
+	assert.True(t, indexOf("ci:github.com/b") < indexOf("ci:github.com/a"))
+}
+
+func TestDeployments_DependencyGraph(t *testing.T) {
+	s := dependenciesTestState()
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+
+	g := ds.DependencyGraph("prod-sc")
+	require.Len(t, g.Nodes, 3)
+	assert.Equal(t, "github.com/user/api", g.Nodes[0].Manifest.String())
+	assert.Equal(t, "1.0.0", g.Nodes[0].Version)
+	assert.Equal(t, "", g.Nodes[1].Version, "cache is not deployed")
+	require.Len(t, g.Edges, 2)
+	assert.Equal(t, "not deployed to prod-sc", g.Edges[0].Violation)
+	assert.Equal(t, "", g.Edges[1].Violation)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, g.WriteDot(out))
+	assert.Equal(t, `digraph "prod-sc" {
+  "github.com/user/api" [label="github.com/user/api\n1.0.0"];
+  "github.com/user/cache" [label="github.com/user/cache\nnot deployed", color=red];
+  "github.com/user/db" [label="github.com/user/db\n2.1.0"];
+  "github.com/user/api" -> "github.com/user/cache" [label="", color=red];
+  "github.com/user/api" -> "github.com/user/db" [label=">=2.0.0 <3.0.0"];
+}
+`, out.String())
+}
+
+func TestQueueDiffsWaitsForDependencies(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		id := qr.Rectification.Pair.ID()
+		mu.Lock()
+		order = append(order, id.ManifestID.String())
+		mu.Unlock()
+		rez := DiffResolution{DeploymentID: id, Desc: CreateDiff}
+		if id.ManifestID.Source.Repo == "github.com/broken" {
+			rez.Error = WrapResolveError(errors.New("no image"))
+		}
+		return rez
+	}))
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+
+	dcs := NewDeployableChans(5)
+	dcs.Pairs <- dependentPair("github.com/a", "github.com/b")
+	dcs.Pairs <- dependentPair("github.com/b", "github.com/c")
+	dcs.Pairs <- dependentPair("github.com/c")
+	dcs.Pairs <- dependentPair("github.com/d", "github.com/broken")
+	dcs.Pairs <- dependentPair("github.com/broken")
+	close(dcs.Pairs)
+	results := make(chan DiffResolution, 5)
+	r.queueDiffs(dcs, results)
+	close(results)
+
+	errs := map[string]error{}
+	for rez := range results {
+		if rez.Error != nil {
+			errs[rez.ManifestID.String()] = rez.Error
+		}
+	}
+	assert.Len(t, errs, 2)
+	if assert.Contains(t, errs, "github.com/d") {
+		assert.Contains(t, errs["github.com/d"].Error(), "dependency ci:github.com/broken was not rectified")
+	}
+
+	indexOf := func(repo string) int {
+		for i, o := range order {
+			if o == repo {
+				return i
+			}
+		}
+		return -1
+	}
+	assert.True(t, indexOf("github.com/c") < indexOf("github.com/b"))
+	assert.True(t, indexOf("github.com/b") < indexOf("github.com/a"))
+	assert.Equal(t, -1, indexOf("github.com/d"), "not rectified after its dependency failed")
+}
+
+func TestQueueDiffsQueuesPairsAsReceived(t *testing.T) {
+	handled := make(chan string, 2)
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		id := qr.Rectification.Pair.ID()
+		handled <- id.ManifestID.String()
+		return DiffResolution{DeploymentID: id, Desc: CreateDiff}
+	}))
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+
+	dcs := NewDeployableChans(2)
+	results := make(chan DiffResolution, 2)
+	finished := make(chan struct{})
+	go func() {
+		r.queueDiffs(dcs, results)
+		close(finished)
+	}()
+
+	dcs.Pairs <- dependentPair("github.com/a")
+	select {
+	case repo := <-handled:
+		assert.Equal(t, "github.com/a", repo)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not rectified before the last pair was received")
+	}
+	close(dcs.Pairs)
+	<-finished
+}
+
+func TestQueueDiffsWaitsForInFlightDependency(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []string
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		id := qr.Rectification.Pair.ID()
+		rez := DiffResolution{DeploymentID: id, Desc: CreateDiff}
+		if id.ManifestID.Source.Repo == "github.com/db" {
+			<-release
+			rez.Error = WrapResolveError(errors.New("no image"))
+		}
+		mu.Lock()
+		handled = append(handled, id.ManifestID.String())
+		mu.Unlock()
+		return rez
+	}))
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+
+	// A rectification of db, queued by an earlier resolution, is still in
+	// progress, so db's new one is not queued.
+	_, ok := qs.PushIfEmpty(NewRectification(*dependentPair("github.com/db"), logging.SilentLogSet()))
+	require.True(t, ok)
+	time.AfterFunc(100*time.Millisecond, func() { close(release) })
+
+	dcs := NewDeployableChans(2)
+	dcs.Pairs <- dependentPair("github.com/api", "github.com/db")
+	dcs.Pairs <- dependentPair("github.com/db")
+	close(dcs.Pairs)
+	results := make(chan DiffResolution, 2)
+	r.queueDiffs(dcs, results)
+	close(results)
+
+	var rezs []DiffResolution
+	for rez := range results {
+		rezs = append(rezs, rez)
+	}
+	if assert.Len(t, rezs, 1) {
+		assert.Equal(t, "github.com/api", rezs[0].ManifestID.String())
+		assert.Contains(t, rezs[0].Error.Error(), "dependency ci:github.com/db was not rectified")
+	}
+	assert.Equal(t, []string{"github.com/db"}, handled)
+}
+
+func TestQueueDiffsDropsInFlightWithoutDependents(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		id := qr.Rectification.Pair.ID()
+		if id.ManifestID.Source.Repo == "github.com/db" {
+			<-release
+		}
+		return DiffResolution{DeploymentID: id, Desc: CreateDiff}
+	}))
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+
+	// A slow rectification of db is still in progress, and nothing else
+	// depends on db, so the resolution need not wait for it.
+	_, ok := qs.PushIfEmpty(NewRectification(*dependentPair("github.com/db"), logging.SilentLogSet()))
+	require.True(t, ok)
+
+	dcs := NewDeployableChans(2)
+	dcs.Pairs <- dependentPair("github.com/db")
+	dcs.Pairs <- dependentPair("github.com/api")
+	close(dcs.Pairs)
+	results := make(chan DiffResolution, 2)
+	finished := make(chan struct{})
+	go func() {
+		r.queueDiffs(dcs, results)
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waited for a rectification nothing depends on")
+	}
+	close(results)
+
+	var rezs []DiffResolution
+	for rez := range results {
+		rezs = append(rezs, rez)
+	}
+	if assert.Len(t, rezs, 1) {
+		assert.Equal(t, "github.com/api", rezs[0].ManifestID.String())
+	}
+}
+
+func TestState_Deployments_undefinedDependencyCluster(t *testing.T) {
+	s := dependenciesTestState()
+	api, _ := s.Manifests.Get(MustParseManifestID("github.com/user/api"))
+	api.Dependencies = append(api.Dependencies, Dependency{
+		Manifest: MustParseManifestID("github.com/user/queue"),
+		Clusters: []string{"staging"},
+	})
+
+	// A cluster removed from defs is reported in the manifest depending on
+	// it, but doesn't stop the deployments of the state being read.
+	assert.Equal(t, []string{
+		`manifest "github.com/user/api": dependency on "github.com/user/queue": cluster "staging" is not defined`,
+	}, flawStrings(s.Defs.ValidateManifest(api)))
+
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+	assert.Equal(t, 6, ds.Len())
+	ci, _ := ds.Get(DeploymentID{ManifestID: api.ID(), Cluster: "ci"})
+	assert.Len(t, ci.Dependencies, 1)
+}
+
+// dependentPair returns a pair adding a deployment of repo to the "ci"
+// cluster, which depends on the deployments of deps.
+func dependentPair(repo string, deps ...string) *DeployablePair {
+	d := &Deployment{
+		ClusterName:  "ci",
+		Cluster:      &Cluster{Name: "ci"},
+		SourceID:     MustParseSourceID(repo + ",1.0.0"),
+		DeployConfig: DeployConfig{NumInstances: 1},
+	}
+	for _, dep := range deps {
+		d.Dependencies = append(d.Dependencies, Dependency{Manifest: MustParseManifestID(dep)})
+	}
+	p := &DeployablePair{Post: &Deployable{Deployment: d}}
+	p.SetID(d.ID())
+	return p
+}
+
+func violationStrings(vs []DependencyViolation) []string {
+	ss := make([]string, len(vs))
+	for i, v := range vs {
+		ss[i] = v.String()
+	}
+	return ss
+}
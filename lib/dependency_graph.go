@@ -0,0 +1,109 @@
+package sous
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+type (
+	// DependencyGraph is the graph of the dependencies between the
+	// deployments to a cluster.
+	DependencyGraph struct {
+		Cluster string
+		// Nodes are the deployments which have dependencies, or are depended
+		// on, ordered by manifest ID.
+		Nodes []DependencyNode
+		// Edges are the dependencies, from the deployments which have them
+		// to those they depend on.
+		Edges []DependencyEdge
+	}
+
+	// DependencyNode is a deployment in a DependencyGraph.
+	DependencyNode struct {
+		Manifest ManifestID
+		// Version is the version deployed, or empty if the manifest is not
+		// deployed to the cluster.
+		Version string `json:",omitempty"`
+	}
+
+	// DependencyEdge is a dependency in a DependencyGraph.
+	DependencyEdge struct {
+		From, To ManifestID
+		Version  VersionRange `json:",omitempty"`
+		// Violation is why the dependency is not met, if it is not.
+		Violation string `json:",omitempty"`
+	}
+)
+
+// DependencyGraph returns the graph of the dependencies between the
+// deployments in ds to cluster.
+func (ds Deployments) DependencyGraph(cluster string) DependencyGraph {
+	inCluster := ds.Filter(func(d *Deployment) bool { return d.ClusterName == cluster })
+	g := DependencyGraph{Cluster: cluster}
+
+	violations := map[[2]ManifestID]string{}
+	for _, v := range inCluster.DependencyViolations() {
+		violations[[2]ManifestID{v.DeploymentID.ManifestID, v.Dependency.ManifestID}] = v.Reason
+	}
+
+	nodes := map[ManifestID]bool{}
+	for _, d := range inCluster.Snapshot() {
+		for _, dep := range d.Dependencies {
+			from := d.ManifestID()
+			nodes[from] = true
+			nodes[dep.Manifest] = true
+			g.Edges = append(g.Edges, DependencyEdge{
+				From:      from,
+				To:        dep.Manifest,
+				Version:   dep.Version,
+				Violation: violations[[2]ManifestID{from, dep.Manifest}],
+			})
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From.String() < g.Edges[j].From.String()
+		}
+		return g.Edges[i].To.String() < g.Edges[j].To.String()
+	})
+
+	for mid := range nodes {
+		n := DependencyNode{Manifest: mid}
+		if d, has := inCluster.Get(DeploymentID{ManifestID: mid, Cluster: cluster}); has && d.NumInstances > 0 {
+			n.Version = d.SourceID.Version.String()
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].Manifest.String() < g.Nodes[j].Manifest.String()
+	})
+	return g
+}
+
+// WriteDot writes g to w in the DOT language of Graphviz. Dependencies which
+// are not met are drawn in red, as are manifests not deployed to the cluster.
+func (g DependencyGraph) WriteDot(w io.Writer) error {
+	lines := []string{fmt.Sprintf("digraph %q {", g.Cluster)}
+	for _, n := range g.Nodes {
+		if n.Version == "" {
+			lines = append(lines, fmt.Sprintf("  %q [label=%q, color=red];", n.Manifest, n.Manifest.String()+"\nnot deployed"))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %q [label=%q];", n.Manifest, n.Manifest.String()+"\n"+n.Version))
+	}
+	for _, e := range g.Edges {
+		attrs := fmt.Sprintf("label=%q", string(e.Version))
+		if e.Violation != "" {
+			attrs += ", color=red"
+		}
+		lines = append(lines, fmt.Sprintf("  %q -> %q [%s];", e.From, e.To, attrs))
+	}
+	lines = append(lines, "}")
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -33,13 +33,16 @@ type (
 		Startup Startup `yaml:",omitempty"`
 		// Schedule is a cronjob-format schedule for jobs.
 		Schedule string
+		// Rollout describes how new versions of this deployment are rolled out.
+		Rollout Rollout `yaml:",omitempty"`
 
 		// SingularityRequestID is the ID of the request representing this
 		// deployment in a Singularity scheduler.
 		SingularityRequestID string `yaml:",omitempty"`
 	}
 
-	// A DeployConfigs is a map from cluster name to DeployConfig
+	// A DeployConfigs is a map from cluster name, or template name in
+	// Defs.Templates, to DeployConfig
 	DeployConfigs map[string]DeployConfig
 
 	// Env is a mapping of environment variable name to value, used to provision
@@ -78,6 +81,12 @@ func (dc *DeployConfig) Validate() []Flaw {
 
 	flaws = append(flaws, dc.Startup.Validate()...)
 
+	flaws = append(flaws, dc.Rollout.Validate()...)
+
+	if _, err := dc.Env.SecretRefs(); err != nil {
+		flaws = append(flaws, FatalFlaw("Invalid Env: %s", err))
+	}
+
 	for _, f := range flaws {
 		f.AddContext("deploy config", dc)
 	}
@@ -117,6 +126,7 @@ func (dc *DeployConfig) Equal(o DeployConfig) bool {
 }
 
 // Diff returns a list of differences between this and the other DeployConfig.
+// Rollout is not compared, since running deployments do not report it.
 func (dc *DeployConfig) Diff(o DeployConfig) (bool, []string) {
 	var diffs []string
 	if dc.NumInstances != o.NumInstances {
@@ -183,6 +193,7 @@ func (dc DeployConfig) Clone() DeployConfig {
 	dc.Resources = dc.Resources.Clone()
 	dc.Metadata = dc.Metadata.Clone()
 	dc.Volumes = dc.Volumes.Clone()
+	dc.Rollout = dc.Rollout.Clone()
 	return dc
 }
 
@@ -226,6 +237,12 @@ func stringSlicesEqual(left, right []string) bool {
 	return true
 }
 
+// MergeDefaults returns dc with the values it does not set taken from
+// defaults.
+func (dc DeployConfig) MergeDefaults(defaults DeployConfig) DeployConfig {
+	return flattenDeployConfigs([]DeployConfig{dc, defaults})
+}
+
 func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 	dc := DeployConfig{
 		Resources: make(Resources),
@@ -250,6 +267,12 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			break
 		}
 	}
+	for _, c := range dcs {
+		if !c.Rollout.isZero() {
+			dc.Rollout = c.Rollout.Clone()
+			break
+		}
+	}
 	for _, c := range dcs {
 		for n, v := range c.Resources {
 			if _, set := dc.Resources[n]; !set {
@@ -267,8 +290,10 @@ func flattenDeployConfigs(dcs []DeployConfig) DeployConfig {
 			}
 		}
 
-		dc.Startup = c.Startup
-		dc.SingularityRequestID = c.SingularityRequestID
+		dc.Startup = c.Startup.MergeDefaults(dc.Startup)
+		if dc.SingularityRequestID == "" {
+			dc.SingularityRequestID = c.SingularityRequestID
+		}
 	}
 	return dc
 }
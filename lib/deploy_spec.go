@@ -29,6 +29,10 @@ type (
 		//     2. The metadata field is the full revision ID of the commit
 		//        which the tag in 1. points to.
 		Version semv.Version `validate:"nonzero"`
+		// Template names a template in Defs.Templates whose config this
+		// deployment inherits, in preference to that of its Manifest's
+		// Template.
+		Template string `yaml:",omitempty"`
 		// clusterName is the name of the cluster this deployment belongs to. Upon
 		// parsing the Manifest, this will be set to the key in
 		// Manifests.Deployments which points at this Deployment.
@@ -81,6 +85,9 @@ func (spec DeploySpec) Diff(other DeploySpec) (bool, []string) {
 	if !spec.Version.Equals(other.Version) {
 		diff("version; this: %q; other: %q", spec.Version, other.Version)
 	}
+	if spec.Template != other.Template {
+		diff("template; this: %q; other: %q", spec.Template, other.Template)
+	}
 	_, configDiffs := spec.DeployConfig.Diff(other.DeployConfig)
 	for _, d := range configDiffs {
 		diff(d)
@@ -0,0 +1,92 @@
+package sous
+
+import "sync"
+
+// A DeployStateCache holds the latest known DeployState of each deployment,
+// as reported by a scheduler when it changes, e.g. by Singularity webhooks.
+// Each change increases its version, so that clients may wait for the next.
+type DeployStateCache struct {
+	sync.RWMutex
+	states  map[DeploymentID]*DeployState
+	version uint64
+	changed chan struct{}
+}
+
+// NewDeployStateCache returns an empty DeployStateCache.
+func NewDeployStateCache() *DeployStateCache {
+	return &DeployStateCache{
+		states:  map[DeploymentID]*DeployState{},
+		changed: make(chan struct{}),
+	}
+}
+
+// Update records ds as the state of the deployment with id.
+func (c *DeployStateCache) Update(id DeploymentID, ds *DeployState) {
+	c.Lock()
+	defer c.Unlock()
+	c.states[id] = ds
+	c.bump()
+}
+
+// Remove forgets the state of the deployment with id.
+func (c *DeployStateCache) Remove(id DeploymentID) {
+	c.Lock()
+	defer c.Unlock()
+	if _, has := c.states[id]; !has {
+		return
+	}
+	delete(c.states, id)
+	c.bump()
+}
+
+// bump must be called with c locked.
+func (c *DeployStateCache) bump() {
+	c.version++
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Get returns a copy of the state of the deployment with id, and false if
+// there is none.
+func (c *DeployStateCache) Get(id DeploymentID) (*DeployState, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	ds, has := c.states[id]
+	if !has {
+		return nil, false
+	}
+	return cloneDeployState(ds), true
+}
+
+// Snapshot returns copies of all the states in c, which has none if it is
+// nil.
+func (c *DeployStateCache) Snapshot() map[DeploymentID]*DeployState {
+	if c == nil {
+		return map[DeploymentID]*DeployState{}
+	}
+	c.RLock()
+	defer c.RUnlock()
+	snap := make(map[DeploymentID]*DeployState, len(c.states))
+	for id, ds := range c.states {
+		snap[id] = cloneDeployState(ds)
+	}
+	return snap
+}
+
+func cloneDeployState(ds *DeployState) *DeployState {
+	clone := *ds
+	clone.Deployment = *ds.Deployment.Clone()
+	return &clone
+}
+
+// Version returns the version of c, and a channel which is closed when it
+// next changes. If c is nil, its version is 0 and the channel is nil, so
+// that receiving from it blocks forever.
+func (c *DeployStateCache) Version() (uint64, <-chan struct{}) {
+	if c == nil {
+		return 0, nil
+	}
+	c.RLock()
+	defer c.RUnlock()
+	return c.version, c.changed
+}
@@ -0,0 +1,57 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployStateCache(t *testing.T) {
+	c := NewDeployStateCache()
+	v0, changed := c.Version()
+
+	did := DeploymentID{ManifestID: MustParseManifestID("github.com/opentable/example"), Cluster: "cluster1"}
+	ds := &DeployState{Status: DeployStatusPending}
+	ds.ClusterName = "cluster1"
+	c.Update(did, ds)
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("update not signalled")
+	}
+	v1, changed := c.Version()
+	assert.True(t, v1 > v0, "version should increase")
+
+	got, has := c.Get(did)
+	require.True(t, has)
+	assert.Equal(t, DeployStatusPending, got.Status)
+	got.Status = DeployStatusFailed
+	got, _ = c.Get(did)
+	assert.Equal(t, DeployStatusPending, got.Status, "Get should return a copy")
+	assert.Len(t, c.Snapshot(), 1)
+
+	c.Remove(did)
+	<-changed
+	_, has = c.Get(did)
+	assert.False(t, has)
+
+	v2, changed := c.Version()
+	c.Remove(did)
+	v3, _ := c.Version()
+	assert.Equal(t, v2, v3, "removing nothing should not change the version")
+	select {
+	case <-changed:
+		t.Error("removing nothing should not be signalled")
+	default:
+	}
+}
+
+func TestDeployStateCache_nil(t *testing.T) {
+	var c *DeployStateCache
+	v, changed := c.Version()
+	assert.Zero(t, v)
+	assert.Nil(t, changed)
+	assert.Len(t, c.Snapshot(), 0)
+}
@@ -11,6 +11,30 @@ type (
 		Status(Registry, Clusters, *DeployablePair) (*DeployState, error)
 	}
 
+	// SelectiveDeployer is a Deployer which can read the running deployments
+	// with particular IDs without reading every deployment in their
+	// clusters.
+	SelectiveDeployer interface {
+		Deployer
+		// RunningDeploymentsOf returns the running deployments with ids in
+		// the clusters of from. Those of ids which are intended are in
+		// intended, which gives their scheduler-specific names, e.g.
+		// SingularityRequestID; the others are looked for by their default
+		// names.
+		RunningDeploymentsOf(reg Registry, from Clusters, ids []DeploymentID, intended Deployments) (DeployStates, error)
+	}
+
+	// A DeploymentRemover is a Deployer which can remove a deployment from
+	// its cluster altogether. Deployments which are no longer intended are
+	// left running for their owners to clean up, so this is only for those
+	// Sous makes for itself, like the canaries of staged rollouts.
+	DeploymentRemover interface {
+		Deployer
+		// RemoveDeployment removes d from its cluster. It is not an error if
+		// d is not there.
+		RemoveDeployment(d *Deployment) error
+	}
+
 	// DeployerSpy is a noop deployer.
 	DeployerSpy struct {
 		*spies.Spy
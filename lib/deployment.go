@@ -38,6 +38,9 @@ type (
 		Kind ManifestKind
 		// User
 		User User
+		// Dependencies are the dependencies of this deployment's manifest
+		// which apply to its cluster.
+		Dependencies Dependencies `yaml:",omitempty"`
 	}
 )
 
@@ -50,6 +53,7 @@ func (d Deployment) Clone() *Deployment {
 	if d.Owners != nil {
 		d.Owners = d.Owners.Clone()
 	}
+	d.Dependencies = d.Dependencies.Clone()
 	return &d
 }
 
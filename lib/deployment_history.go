@@ -0,0 +1,183 @@
+package sous
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// DeploymentHistory records every change made to the DeploySpecs in the
+	// GDM, so that earlier versions of a deployment can be found again.
+	DeploymentHistory interface {
+		// Record adds entries to the history.
+		Record(entries ...DeploymentHistoryEntry) error
+		// SetOutcome sets the outcome of the most recent entry for did with
+		// version.
+		SetOutcome(did DeploymentID, version semv.Version, outcome DeploymentOutcome) error
+		// History returns the entries for did, most recent first.
+		History(did DeploymentID) ([]DeploymentHistoryEntry, error)
+	}
+
+	// DeploymentHistoryEntry is a single change to a deployment in the GDM.
+	// Its DeploySpec is the manifest's own, without the values the
+	// deployment inherits from templates and its cluster.
+	DeploymentHistoryEntry struct {
+		DeploymentID DeploymentID
+		DeploySpec   DeploySpec
+		User         User
+		Time         time.Time
+		Outcome      DeploymentOutcome
+	}
+
+	// DeploymentOutcome is the result of resolving a DeploymentHistoryEntry.
+	DeploymentOutcome string
+
+	// MemoryDeploymentHistory is a DeploymentHistory which is only kept in
+	// memory.
+	MemoryDeploymentHistory struct {
+		sync.Mutex
+		entries map[DeploymentID][]DeploymentHistoryEntry
+	}
+
+	// HistoryStateManager is a StateManager that records the DeploySpecs
+	// changed by each write in a DeploymentHistory.
+	HistoryStateManager struct {
+		StateManager
+		History DeploymentHistory
+		log     logging.LogSink
+		// writing serializes writes, so that the state each is compared with
+		// is the one it replaces.
+		writing sync.Mutex
+	}
+)
+
+const (
+	// OutcomePending entries have not been resolved yet.
+	OutcomePending DeploymentOutcome = "pending"
+	// OutcomeSucceeded entries were deployed successfully.
+	OutcomeSucceeded DeploymentOutcome = "succeeded"
+	// OutcomeFailed entries failed to deploy.
+	OutcomeFailed DeploymentOutcome = "failed"
+)
+
+// ResolutionOutcome returns the DeploymentOutcome of a finished rectification.
+func ResolutionOutcome(rez DiffResolution) DeploymentOutcome {
+	if rez.Error == nil && rez.DeployState != nil && rez.DeployState.Status == DeployStatusActive {
+		return OutcomeSucceeded
+	}
+	return OutcomeFailed
+}
+
+// NewMemoryDeploymentHistory returns an empty MemoryDeploymentHistory.
+func NewMemoryDeploymentHistory() *MemoryDeploymentHistory {
+	return &MemoryDeploymentHistory{entries: map[DeploymentID][]DeploymentHistoryEntry{}}
+}
+
+// Record implements DeploymentHistory on MemoryDeploymentHistory.
+func (h *MemoryDeploymentHistory) Record(entries ...DeploymentHistoryEntry) error {
+	h.Lock()
+	defer h.Unlock()
+	if h.entries == nil {
+		h.entries = map[DeploymentID][]DeploymentHistoryEntry{}
+	}
+	for _, e := range entries {
+		h.entries[e.DeploymentID] = append(h.entries[e.DeploymentID], e)
+	}
+	return nil
+}
+
+// SetOutcome implements DeploymentHistory on MemoryDeploymentHistory.
+func (h *MemoryDeploymentHistory) SetOutcome(did DeploymentID, version semv.Version, outcome DeploymentOutcome) error {
+	h.Lock()
+	defer h.Unlock()
+	es := h.entries[did]
+	for i := len(es) - 1; i >= 0; i-- {
+		if es[i].DeploySpec.Version.Equals(version) {
+			es[i].Outcome = outcome
+			return nil
+		}
+	}
+	return nil
+}
+
+// History implements DeploymentHistory on MemoryDeploymentHistory.
+func (h *MemoryDeploymentHistory) History(did DeploymentID) ([]DeploymentHistoryEntry, error) {
+	h.Lock()
+	defer h.Unlock()
+	es := h.entries[did]
+	hist := make([]DeploymentHistoryEntry, 0, len(es))
+	for i := len(es) - 1; i >= 0; i-- {
+		hist = append(hist, es[i])
+	}
+	return hist, nil
+}
+
+// NewHistoryStateManager wraps sm so that writes are recorded in h.
+func NewHistoryStateManager(sm StateManager, h DeploymentHistory, ls logging.LogSink) *HistoryStateManager {
+	return &HistoryStateManager{StateManager: sm, History: h, log: ls}
+}
+
+// WriteState implements StateWriter on HistoryStateManager. Failing to record
+// history is logged, but does not fail the write.
+func (hsm *HistoryStateManager) WriteState(state *State, user User) error {
+	hsm.writing.Lock()
+	defer hsm.writing.Unlock()
+
+	var prior Deployments
+	current, err := hsm.StateManager.ReadState()
+	if err == nil {
+		prior, err = current.Deployments()
+	}
+	if err != nil {
+		logging.ReportError(hsm.log, errors.Wrap(err, "reading state for deployment history"))
+		return hsm.StateManager.WriteState(state, user)
+	}
+
+	if err := hsm.StateManager.WriteState(state, user); err != nil {
+		return err
+	}
+
+	entries, err := HistoryEntries(prior, state, user, time.Now())
+	if err == nil {
+		err = hsm.History.Record(entries...)
+	}
+	if err != nil {
+		logging.ReportError(hsm.log, errors.Wrap(err, "recording deployment history"))
+	}
+	return nil
+}
+
+// HistoryEntries returns an entry for each deployment in state which is new
+// or changed since prior, with the DeploySpec its manifest gives it.
+func HistoryEntries(prior Deployments, state *State, user User, at time.Time) ([]DeploymentHistoryEntry, error) {
+	deps, err := state.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	var entries []DeploymentHistoryEntry
+	for _, pair := range prior.Diff(deps).Collect() {
+		switch pair.Kind() {
+		default:
+			continue
+		case AddedKind, ModifiedKind:
+		}
+		d := pair.Post.Deployment
+		m, ok := state.Manifests.Get(d.ManifestID())
+		if !ok {
+			return nil, errors.Errorf("no manifest %q for deployment %s", d.ManifestID(), d.ID())
+		}
+		spec, _ := state.Defs.ClusterSpec(m, d.ClusterName)
+		entries = append(entries, DeploymentHistoryEntry{
+			DeploymentID: d.ID(),
+			DeploySpec:   spec.Clone(),
+			User:         user,
+			Time:         at,
+			Outcome:      OutcomePending,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryStateManager_WriteState(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	h := NewMemoryDeploymentHistory()
+	hsm := NewHistoryStateManager(sm, h, logging.SilentLogSet())
+
+	next := sm.State.Clone()
+	mid := next.Manifests.Keys()[0]
+	m, _ := next.Manifests.Get(mid)
+	spec := m.Deployments["cluster1"]
+	spec.Version = semv.MustParse("2.0.0")
+	m.Deployments["cluster1"] = spec
+
+	user := User{Name: "Test User"}
+	require.NoError(t, hsm.WriteState(next, user))
+	assert.Equal(t, 1, sm.WriteCount)
+
+	did := DeploymentID{ManifestID: mid, Cluster: "cluster1"}
+	entries, err := h.History(did)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "2.0.0", entries[0].DeploySpec.Version.String())
+	assert.Equal(t, spec, entries[0].DeploySpec, "the manifest's own DeploySpec")
+	assert.Equal(t, user, entries[0].User)
+	assert.Equal(t, OutcomePending, entries[0].Outcome)
+
+	others, err := h.History(DeploymentID{ManifestID: mid, Cluster: "cluster2"})
+	require.NoError(t, err)
+	assert.Len(t, others, 0)
+}
+
+func TestHistoryStateManager_WriteState_error(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	sm.WriteErr = assert.AnError
+	h := NewMemoryDeploymentHistory()
+	hsm := NewHistoryStateManager(sm, h, logging.SilentLogSet())
+
+	next := sm.State.Clone()
+	next.Manifests = NewManifests()
+	assert.Error(t, hsm.WriteState(next, User{}))
+	assert.Len(t, h.entries, 0)
+}
+
+func TestMemoryDeploymentHistory(t *testing.T) {
+	did := DeploymentID{ManifestID: ManifestID{Source: SourceLocation{Repo: "github.com/example/a"}}, Cluster: "c"}
+	entry := func(v string) DeploymentHistoryEntry {
+		return DeploymentHistoryEntry{
+			DeploymentID: did,
+			DeploySpec:   DeploySpec{Version: semv.MustParse(v)},
+			Time:         time.Now(),
+			Outcome:      OutcomePending,
+		}
+	}
+	h := NewMemoryDeploymentHistory()
+	require.NoError(t, h.Record(entry("1.0.0"), entry("2.0.0")))
+	require.NoError(t, h.SetOutcome(did, semv.MustParse("2.0.0"), OutcomeFailed))
+
+	entries, err := h.History(did)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2.0.0", entries[0].DeploySpec.Version.String())
+	assert.Equal(t, OutcomeFailed, entries[0].Outcome)
+	assert.Equal(t, OutcomePending, entries[1].Outcome)
+}
+
+func TestResolutionOutcome(t *testing.T) {
+	assert.Equal(t, OutcomeSucceeded, ResolutionOutcome(DiffResolution{DeployState: &DeployState{Status: DeployStatusActive}}))
+	assert.Equal(t, OutcomeFailed, ResolutionOutcome(DiffResolution{DeployState: &DeployState{Status: DeployStatusFailed}}))
+	assert.Equal(t, OutcomeFailed, ResolutionOutcome(DiffResolution{}))
+}
@@ -19,6 +19,11 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.Cluster.BaseURL",
 		"Deployment.Cluster.Env",
 		"Deployment.Cluster.AllowedAdvisories",
+		"Deployment.Cluster.AutoRollback",
+		"Deployment.Cluster.FreezeWindows",
+		"Deployment.Cluster.SigningKeys",
+		"Deployment.Cluster.VulnerabilityThresholds",
+		"Deployment.Cluster.ResourceLimits",
 		"Deployment.Cluster.Startup",
 		"Deployment.Cluster.Startup.SkipCheck",
 		"Deployment.Cluster.Startup.CheckReadyURIPath",
@@ -38,6 +43,22 @@ func TestDeploymentDiffAnalysis(t *testing.T) {
 		"Deployment.User",
 		"Deployment.User.Name",
 		"Deployment.User.Email",
+		// Rollout only describes how changes are applied; schedulers don't
+		// report it, so comparing it would make every staged deployment differ.
+		"Deployment.Rollout",
+		"Deployment.Rollout.Strategy",
+		"Deployment.Rollout.CanaryInstances",
+		"Deployment.Rollout.Steps",
+		"Deployment.Rollout.StageDelay",
+		"Deployment.Rollout.AutoRollback",
+		"Deployment.DeployConfig.Rollout",
+		"Deployment.DeployConfig.Rollout.Strategy",
+		"Deployment.DeployConfig.Rollout.CanaryInstances",
+		"Deployment.DeployConfig.Rollout.Steps",
+		"Deployment.DeployConfig.Rollout.StageDelay",
+		"Deployment.DeployConfig.Rollout.AutoRollback",
+		// Dependencies only order rectifications, and are not deployed.
+		"Deployment.Dependencies",
 		/*
 			"Deployment.Owners",
 			"Deployment.DeployConfig.Args",
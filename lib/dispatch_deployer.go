@@ -0,0 +1,157 @@
+package sous
+
+import "github.com/pkg/errors"
+
+// A DispatchDeployer is a Deployer that hands each operation to the Deployer
+// registered for the Kind of cluster concerned.
+type DispatchDeployer struct {
+	deployers map[string]Deployer
+}
+
+// NewDispatchDeployer builds a DispatchDeployer from a map of cluster kind to
+// Deployer.
+func NewDispatchDeployer(deployers map[string]Deployer) *DispatchDeployer {
+	dd := &DispatchDeployer{deployers: map[string]Deployer{}}
+	for kind, d := range deployers {
+		dd.deployers[kind] = d
+	}
+	return dd
+}
+
+func (dd *DispatchDeployer) deployerFor(kind string) (Deployer, error) {
+	d, ok := dd.deployers[kind]
+	if !ok {
+		return nil, errors.Errorf("no deployer for cluster kind %q", kind)
+	}
+	return d, nil
+}
+
+// RunningDeployments implements Deployer on DispatchDeployer. Clusters are
+// partitioned by kind, and the results of each kind's Deployer are merged.
+func (dd *DispatchDeployer) RunningDeployments(reg Registry, from Clusters) (DeployStates, error) {
+	byKind := map[string]Clusters{}
+	for name, c := range from {
+		kind := c.ClusterKind()
+		if byKind[kind] == nil {
+			byKind[kind] = Clusters{}
+		}
+		byKind[kind][name] = c
+	}
+
+	states := NewDeployStates()
+	for kind, clusters := range byKind {
+		d, err := dd.deployerFor(kind)
+		if err != nil {
+			return states, err
+		}
+		ds, err := d.RunningDeployments(reg, clusters)
+		if err != nil {
+			return states, errors.Wrapf(err, "%s clusters", kind)
+		}
+		for _, s := range ds.Snapshot() {
+			states.Add(s)
+		}
+	}
+	return states, nil
+}
+
+// RunningDeploymentsOf implements SelectiveDeployer on DispatchDeployer. The
+// ids are partitioned by the kind of their clusters, and each kind's
+// Deployer reads those of its kind, by themselves if it is a
+// SelectiveDeployer, or from all the deployments in their clusters if not.
+func (dd *DispatchDeployer) RunningDeploymentsOf(reg Registry, from Clusters, ids []DeploymentID, intended Deployments) (DeployStates, error) {
+	idsByKind := map[string][]DeploymentID{}
+	clustersByKind := map[string]Clusters{}
+	for _, id := range ids {
+		c, has := from[id.Cluster]
+		if !has {
+			continue
+		}
+		kind := c.ClusterKind()
+		idsByKind[kind] = append(idsByKind[kind], id)
+		if clustersByKind[kind] == nil {
+			clustersByKind[kind] = Clusters{}
+		}
+		clustersByKind[kind][id.Cluster] = c
+	}
+
+	states := NewDeployStates()
+	for kind, ids := range idsByKind {
+		d, err := dd.deployerFor(kind)
+		if err != nil {
+			return states, err
+		}
+		var ds DeployStates
+		if sd, ok := d.(SelectiveDeployer); ok {
+			ds, err = sd.RunningDeploymentsOf(reg, clustersByKind[kind], ids, intended)
+		} else {
+			ds, err = d.RunningDeployments(reg, clustersByKind[kind])
+		}
+		if err != nil {
+			return states, errors.Wrapf(err, "%s clusters", kind)
+		}
+		want := map[DeploymentID]struct{}{}
+		for _, id := range ids {
+			want[id] = struct{}{}
+		}
+		for _, s := range ds.Snapshot() {
+			if _, has := want[s.ID()]; has {
+				states.Add(s)
+			}
+		}
+	}
+	return states, nil
+}
+
+// Rectify implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Rectify(pair *DeployablePair) DiffResolution {
+	d, err := dd.deployerFor(pairClusterKind(pair))
+	if err != nil {
+		return DiffResolution{
+			DeploymentID: pair.ID(),
+			Desc:         "not rectified",
+			Error:        WrapResolveError(err),
+		}
+	}
+	return d.Rectify(pair)
+}
+
+// Status implements Deployer on DispatchDeployer.
+func (dd *DispatchDeployer) Status(reg Registry, clusters Clusters, pair *DeployablePair) (*DeployState, error) {
+	kind := pairClusterKind(pair)
+	if pair.Post != nil && pair.Post.Deployment != nil {
+		if c, has := clusters[pair.Post.Deployment.ClusterName]; has {
+			kind = c.ClusterKind()
+		}
+	}
+	d, err := dd.deployerFor(kind)
+	if err != nil {
+		return nil, err
+	}
+	return d.Status(reg, clusters, pair)
+}
+
+// RemoveDeployment implements DeploymentRemover on DispatchDeployer. It
+// returns an error if the Deployer for the kind of d's cluster is not a
+// DeploymentRemover.
+func (dd *DispatchDeployer) RemoveDeployment(d *Deployment) error {
+	kind := d.Cluster.ClusterKind()
+	dep, err := dd.deployerFor(kind)
+	if err != nil {
+		return err
+	}
+	dr, ok := dep.(DeploymentRemover)
+	if !ok {
+		return errors.Errorf("the deployer for cluster kind %q cannot remove deployments", kind)
+	}
+	return dr.RemoveDeployment(d)
+}
+
+func pairClusterKind(pair *DeployablePair) string {
+	for _, d := range []*Deployable{pair.Post, pair.Prior} {
+		if d != nil && d.Deployment != nil && d.Deployment.Cluster != nil {
+			return d.Deployment.Cluster.ClusterKind()
+		}
+	}
+	return ClusterKindSingularity
+}
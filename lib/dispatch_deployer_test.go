@@ -0,0 +1,151 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatchDeployer_RunningDeployments(t *testing.T) {
+	sing, sc := NewDeployerSpy()
+	kube, kc := NewDeployerSpy()
+
+	singState := &DeployState{Deployment: Deployment{ClusterName: "sing", SourceID: MustNewSourceID("github.com/example/a", "", "1.0.0")}}
+	kubeState := &DeployState{Deployment: Deployment{ClusterName: "kube", SourceID: MustNewSourceID("github.com/example/a", "", "1.0.0")}}
+	sc.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(singState), nil)
+	kc.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(kubeState), nil)
+
+	dd := NewDispatchDeployer(map[string]Deployer{
+		ClusterKindSingularity: sing,
+		ClusterKindKubernetes:  kube,
+	})
+
+	clusters := Clusters{
+		"sing": &Cluster{Name: "sing"},
+		"kube": &Cluster{Name: "kube", Kind: ClusterKindKubernetes},
+	}
+	states, err := dd.RunningDeployments(NewDummyRegistry(), clusters)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, states.Len())
+
+	singCalls := sc.CallsTo("RunningDeployments")
+	if assert.Len(t, singCalls, 1) {
+		assert.Equal(t, []string{"sing"}, singCalls[0].PassedArgs().Get(1).(Clusters).Names())
+	}
+	kubeCalls := kc.CallsTo("RunningDeployments")
+	if assert.Len(t, kubeCalls, 1) {
+		assert.Equal(t, []string{"kube"}, kubeCalls[0].PassedArgs().Get(1).(Clusters).Names())
+	}
+}
+
+func TestDispatchDeployer_Rectify(t *testing.T) {
+	sing, sc := NewDeployerSpy()
+	kube, kc := NewDeployerSpy()
+	kc.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: CreateDiff})
+
+	dd := NewDispatchDeployer(map[string]Deployer{
+		ClusterKindSingularity: sing,
+		ClusterKindKubernetes:  kube,
+	})
+
+	pair := &DeployablePair{Post: &Deployable{Deployment: &Deployment{
+		ClusterName: "kube",
+		Cluster:     &Cluster{Name: "kube", Kind: ClusterKindKubernetes},
+	}}}
+	rez := dd.Rectify(pair)
+	assert.Equal(t, CreateDiff, rez.Desc)
+	assert.Len(t, sc.CallsTo("Rectify"), 0)
+	assert.Len(t, kc.CallsTo("Rectify"), 1)
+
+	pair.Post.Cluster.Kind = "nomad"
+	rez = dd.Rectify(pair)
+	assert.NotNil(t, rez.Error)
+}
+
+// selectiveDeployerSpy is a DeployerSpy which is also a SelectiveDeployer.
+type selectiveDeployerSpy struct {
+	*DeployerSpy
+}
+
+func newSelectiveDeployerSpy() (SelectiveDeployer, *spies.Spy) {
+	spy := spies.NewSpy()
+	return selectiveDeployerSpy{&DeployerSpy{Spy: spy}}, spy
+}
+
+func (sd selectiveDeployerSpy) RunningDeploymentsOf(reg Registry, from Clusters, ids []DeploymentID, intended Deployments) (DeployStates, error) {
+	res := sd.Called(reg, from, ids, intended)
+	return res.Get(0).(DeployStates), res.Error(1)
+}
+
+func TestDispatchDeployer_RunningDeploymentsOf(t *testing.T) {
+	sing, sc := newSelectiveDeployerSpy()
+	kube, kc := NewDeployerSpy()
+
+	state := func(cluster, repo string) *DeployState {
+		return &DeployState{Deployment: Deployment{ClusterName: cluster, SourceID: MustNewSourceID(repo, "", "1.0.0")}}
+	}
+	sc.MatchMethod("RunningDeploymentsOf", spies.AnyArgs, NewDeployStates(state("sing", "github.com/example/a")), nil)
+	kc.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(
+		state("kube", "github.com/example/a"),
+		state("kube", "github.com/example/b"),
+	), nil)
+
+	dd := NewDispatchDeployer(map[string]Deployer{
+		ClusterKindSingularity: sing,
+		ClusterKindKubernetes:  kube,
+	})
+
+	clusters := Clusters{
+		"sing":  &Cluster{Name: "sing"},
+		"kube":  &Cluster{Name: "kube", Kind: ClusterKindKubernetes},
+		"other": &Cluster{Name: "other"},
+	}
+	ids := []DeploymentID{
+		{ManifestID: MustParseManifestID("github.com/example/a"), Cluster: "sing"},
+		{ManifestID: MustParseManifestID("github.com/example/a"), Cluster: "kube"},
+	}
+	states, err := dd.RunningDeploymentsOf(NewDummyRegistry(), clusters, ids, NewDeployments())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, states.Len(), "only the deployments asked for")
+
+	assert.Len(t, sc.CallsTo("RunningDeployments"), 0)
+	singCalls := sc.CallsTo("RunningDeploymentsOf")
+	if assert.Len(t, singCalls, 1) {
+		assert.Equal(t, []string{"sing"}, singCalls[0].PassedArgs().Get(1).(Clusters).Names())
+		assert.Equal(t, ids[:1], singCalls[0].PassedArgs().Get(2))
+	}
+	kubeCalls := kc.CallsTo("RunningDeployments")
+	if assert.Len(t, kubeCalls, 1) {
+		assert.Equal(t, []string{"kube"}, kubeCalls[0].PassedArgs().Get(1).(Clusters).Names())
+	}
+}
+
+// removingDeployerSpy is a Deployer which records the deployments it is
+// asked to remove.
+type removingDeployerSpy struct {
+	Deployer
+	removed []*Deployment
+}
+
+func (d *removingDeployerSpy) RemoveDeployment(dep *Deployment) error {
+	d.removed = append(d.removed, dep)
+	return nil
+}
+
+func TestDispatchDeployer_RemoveDeployment(t *testing.T) {
+	sing, _ := NewDeployerSpy()
+	kube := &removingDeployerSpy{}
+
+	dd := NewDispatchDeployer(map[string]Deployer{
+		ClusterKindSingularity: sing,
+		ClusterKindKubernetes:  kube,
+	})
+
+	d := &Deployment{ClusterName: "kube", Cluster: &Cluster{Name: "kube", Kind: ClusterKindKubernetes}}
+	assert.NoError(t, dd.RemoveDeployment(d))
+	assert.Equal(t, []*Deployment{d}, kube.removed)
+
+	// The singularity spy cannot remove deployments.
+	assert.Error(t, dd.RemoveDeployment(&Deployment{ClusterName: "sing", Cluster: &Cluster{Name: "sing"}}))
+}
@@ -0,0 +1,394 @@
+package sous
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// FreezeWindow is a period during which deployments to a cluster are
+	// refused. It is either a single period between Start and End, or a
+	// recurring one which begins according to Schedule and lasts for Duration.
+	FreezeWindow struct {
+		// Reason is reported to anyone whose deployment is refused.
+		Reason string
+		// Start and End bound a one-off freeze. A zero Start has already begun,
+		// and a zero End lasts until the window is removed.
+		Start time.Time `yaml:",omitempty"`
+		End   time.Time `yaml:",omitempty"`
+		// Schedule is a cron expression, evaluated in UTC, for the start of
+		// each occurrence of a recurring freeze, e.g. "0 18 * * 5" for every
+		// Friday at 18:00.
+		Schedule string `yaml:",omitempty"`
+		// Duration is how long each occurrence of a recurring freeze lasts.
+		Duration time.Duration `yaml:",omitempty"`
+		// AllowedOwners lists manifest owners whose deployments are allowed
+		// during the freeze.
+		AllowedOwners []string `yaml:",omitempty"`
+		// BreakGlass allows a single deployment to be forced through the
+		// freeze with `sous deploy -break-glass`.
+		BreakGlass bool `yaml:",omitempty"`
+	}
+
+	// FreezeWindows is a list of FreezeWindow.
+	FreezeWindows []FreezeWindow
+
+	// A Freeze is a single occurrence of a FreezeWindow.
+	Freeze struct {
+		Window     FreezeWindow
+		Start, End time.Time
+	}
+
+	// FreezeError reports that a deployment was refused because its cluster
+	// is frozen.
+	FreezeError struct {
+		Cluster string
+		Freeze
+	}
+
+	// cronSchedule is a parsed cron expression. Each field is a bitmask of
+	// the values it matches.
+	cronSchedule struct {
+		minute, hour, dom, month, dow uint64
+		// domAny and dowAny are set when those fields are "*", since cron
+		// matches either of them when both are restricted.
+		domAny, dowAny bool
+	}
+)
+
+// freezeHorizon is how far ahead NextFreeze looks for recurring freezes.
+const freezeHorizon = 7 * 24 * time.Hour
+
+func (e *FreezeError) Error() string {
+	until := "further notice"
+	if !e.End.IsZero() {
+		until = e.End.UTC().Format(time.RFC3339)
+	}
+	msg := fmt.Sprintf("cluster %q is frozen until %s", e.Cluster, until)
+	if e.Window.Reason != "" {
+		msg += ": " + e.Window.Reason
+	}
+	return msg
+}
+
+// IsFreezeError returns true if the cause of err is a FreezeError.
+func IsFreezeError(err error) bool {
+	_, is := errors.Cause(err).(*FreezeError)
+	return is
+}
+
+// Clone returns a deep copy of fws.
+func (fws FreezeWindows) Clone() FreezeWindows {
+	if fws == nil {
+		return nil
+	}
+	c := make(FreezeWindows, len(fws))
+	for i, fw := range fws {
+		fw.AllowedOwners = append([]string(nil), fw.AllowedOwners...)
+		c[i] = fw
+	}
+	return c
+}
+
+// Equal returns true if fws and other contain equal windows in the same order.
+func (fws FreezeWindows) Equal(other FreezeWindows) bool {
+	if len(fws) != len(other) {
+		return false
+	}
+	for i, fw := range fws {
+		o := other[i]
+		if fw.Reason != o.Reason || !fw.Start.Equal(o.Start) || !fw.End.Equal(o.End) ||
+			fw.Schedule != o.Schedule || fw.Duration != o.Duration || fw.BreakGlass != o.BreakGlass ||
+			!NewOwnerSet(fw.AllowedOwners...).Equal(NewOwnerSet(o.AllowedOwners...)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns an error if fw cannot be evaluated.
+func (fw FreezeWindow) Validate() error {
+	if fw.Schedule == "" {
+		if !fw.Start.IsZero() && !fw.End.IsZero() && !fw.End.After(fw.Start) {
+			return errors.Errorf("freeze window ends (%s) before it starts (%s)", fw.End, fw.Start)
+		}
+		return nil
+	}
+	if !fw.Start.IsZero() || !fw.End.IsZero() {
+		return errors.Errorf("freeze window %q has both a schedule and a start or end", fw.Schedule)
+	}
+	if fw.Duration <= 0 {
+		return errors.Errorf("freeze window %q needs a positive duration", fw.Schedule)
+	}
+	_, err := fw.schedule()
+	return err
+}
+
+// parsedSchedules caches the cronSchedule parsed from each expression, since
+// windows are evaluated for every change to their cluster.
+var parsedSchedules sync.Map
+
+// schedule returns fw's Schedule, parsed.
+func (fw FreezeWindow) schedule() (cronSchedule, error) {
+	if s, ok := parsedSchedules.Load(fw.Schedule); ok {
+		return s.(cronSchedule), nil
+	}
+	s, err := parseCron(fw.Schedule)
+	if err != nil {
+		return cronSchedule{}, err
+	}
+	parsedSchedules.Store(fw.Schedule, s)
+	return s, nil
+}
+
+// ActiveAt returns the occurrence of fw which covers t, if there is one.
+func (fw FreezeWindow) ActiveAt(t time.Time) (Freeze, bool) {
+	if fw.Schedule == "" {
+		if (fw.Start.IsZero() || !t.Before(fw.Start)) && (fw.End.IsZero() || t.Before(fw.End)) {
+			return Freeze{Window: fw, Start: fw.Start, End: fw.End}, true
+		}
+		return Freeze{}, false
+	}
+	sched, err := fw.schedule()
+	if err != nil || fw.Duration <= 0 {
+		return Freeze{}, false
+	}
+	t = t.UTC()
+	m, ok := sched.prev(t, t.Add(-fw.Duration))
+	if !ok {
+		return Freeze{}, false
+	}
+	return Freeze{Window: fw, Start: m, End: m.Add(fw.Duration)}, true
+}
+
+// NextAfter returns the first occurrence of fw which starts after t. For
+// recurring freezes, only the week following t is searched.
+func (fw FreezeWindow) NextAfter(t time.Time) (Freeze, bool) {
+	if fw.Schedule == "" {
+		if fw.Start.After(t) {
+			return Freeze{Window: fw, Start: fw.Start, End: fw.End}, true
+		}
+		return Freeze{}, false
+	}
+	sched, err := fw.schedule()
+	if err != nil || fw.Duration <= 0 {
+		return Freeze{}, false
+	}
+	t = t.UTC()
+	m, ok := sched.next(t, t.Add(freezeHorizon))
+	if !ok {
+		return Freeze{}, false
+	}
+	return Freeze{Window: fw, Start: m, End: m.Add(fw.Duration)}, true
+}
+
+// Allows returns true if a deployment owned by owners may proceed during fw,
+// either because one of its owners is allowed, or because breakGlass was
+// requested and fw permits it.
+func (fw FreezeWindow) Allows(owners OwnerSet, breakGlass bool) bool {
+	if breakGlass && fw.BreakGlass {
+		return true
+	}
+	for _, o := range fw.AllowedOwners {
+		if _, has := owners[o]; has {
+			return true
+		}
+	}
+	return false
+}
+
+// Active returns the freezes from fws which cover t.
+func (fws FreezeWindows) Active(t time.Time) []Freeze {
+	var fs []Freeze
+	for _, fw := range fws {
+		if f, ok := fw.ActiveAt(t); ok {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// Upcoming returns the next freeze after t from each of fws, if any.
+func (fws FreezeWindows) Upcoming(t time.Time) []Freeze {
+	var fs []Freeze
+	for _, fw := range fws {
+		if f, ok := fw.NextAfter(t); ok {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// CheckFreeze returns a *FreezeError if c is frozen at t for a deployment
+// owned by owners.
+func (c *Cluster) CheckFreeze(t time.Time, owners OwnerSet, breakGlass bool) error {
+	if c == nil {
+		return nil
+	}
+	for _, f := range c.FreezeWindows.Active(t) {
+		if !f.Window.Allows(owners, breakGlass) {
+			return &FreezeError{Cluster: c.Name, Freeze: f}
+		}
+	}
+	return nil
+}
+
+// CheckFreeze returns a *FreezeError if the change described by dp is to a
+// frozen cluster at t. Break glass is never applied to changes found by
+// diffing, since it is only meaningful for a single deployment.
+func (dp *DeployablePair) CheckFreeze(t time.Time) error {
+	d := dp.Post
+	if d == nil {
+		d = dp.Prior
+	}
+	if d == nil || d.Deployment == nil {
+		return nil
+	}
+	return d.Cluster.CheckFreeze(t, d.Owners, false)
+}
+
+// CheckFreezes returns an error if any change between prior and next is to a
+// frozen cluster at t.
+func CheckFreezes(prior, next Deployments, t time.Time) error {
+	for _, pair := range prior.Diff(next).Collect() {
+		if pair.Kind() == SameKind {
+			continue
+		}
+		if err := pair.CheckFreeze(t); err != nil {
+			return errors.Wrapf(err, "changing %s", pair.ID())
+		}
+	}
+	return nil
+}
+
+// parseCron parses a five field cron expression: minute, hour, day of month,
+// month and day of week. Each field may be "*", a number, a range "a-b", a
+// comma separated list of those, and may be followed by a step "/n".
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, errors.Errorf("cron expression %q: need 5 fields, got %d", expr, len(fields))
+	}
+	var s cronSchedule
+	var err error
+	bounds := []struct {
+		dest     *uint64
+		min, max uint
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		if *b.dest, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return cronSchedule{}, errors.Wrapf(err, "cron expression %q", expr)
+		}
+	}
+	// Both 0 and 7 are Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+func parseCronField(field string, min, max uint) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, uint64(1)
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, errors.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := uint64(min), uint64(max)
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = strconv.ParseUint(bounds[0], 10, 8); err != nil {
+				return 0, errors.Errorf("bad value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.ParseUint(bounds[1], 10, 8); err != nil {
+					return 0, errors.Errorf("bad value in %q", part)
+				}
+			}
+		}
+		if lo < uint64(min) || hi > uint64(max) || lo > hi {
+			return 0, errors.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first minute after t, and no later than limit, which s
+// describes. Whole months, days and hours which do not match are skipped.
+func (s cronSchedule) next(t, limit time.Time) (time.Time, bool) {
+	for m := t.Truncate(time.Minute).Add(time.Minute); !m.After(limit); {
+		y, mo, d := m.Date()
+		switch {
+		case s.month&(1<<uint(mo)) == 0:
+			m = time.Date(y, mo+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(m):
+			m = time.Date(y, mo, d+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(m.Hour())) == 0:
+			m = m.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(m.Minute())) == 0:
+			m = m.Add(time.Minute)
+		default:
+			return m, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// prev returns the last minute at or before t, and after limit, which s
+// describes. Whole months, days and hours which do not match are skipped.
+func (s cronSchedule) prev(t, limit time.Time) (time.Time, bool) {
+	for m := t.Truncate(time.Minute); m.After(limit); {
+		y, mo, d := m.Date()
+		switch {
+		case s.month&(1<<uint(mo)) == 0:
+			m = time.Date(y, mo, 1, 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.matchesDay(m):
+			m = time.Date(y, mo, d, 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case s.hour&(1<<uint(m.Hour())) == 0:
+			m = m.Truncate(time.Hour).Add(-time.Minute)
+		case s.minute&(1<<uint(m.Minute())) == 0:
+			m = m.Add(-time.Minute)
+		default:
+			return m, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// matchesDay returns true if the day of t (in UTC) is one s describes.
+func (s cronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// matches returns true if t (in UTC) is at a minute s describes.
+func (s cronSchedule) matches(t time.Time) bool {
+	return s.minute&(1<<uint(t.Minute())) != 0 && s.hour&(1<<uint(t.Hour())) != 0 &&
+		s.month&(1<<uint(t.Month())) != 0 && s.matchesDay(t)
+}
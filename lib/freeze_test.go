@@ -0,0 +1,185 @@
+package sous
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.Parse(time.RFC3339, s)
+	require.NoError(t, err)
+	return tm
+}
+
+func TestParseCron(t *testing.T) {
+	good := []string{"* * * * *", "0 18 * * 5", "*/15 9-17 1,15 * 1-5", "30 2 * 12 0,7"}
+	for _, expr := range good {
+		_, err := parseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+	bad := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"}
+	for _, expr := range bad {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCronSchedule_matches(t *testing.T) {
+	// 2018-06-01 is a Friday.
+	fri := mustTime(t, "2018-06-01T18:00:00Z")
+	s, err := parseCron("0 18 * * 5")
+	require.NoError(t, err)
+	assert.True(t, s.matches(fri))
+	assert.False(t, s.matches(fri.Add(time.Minute)))
+	assert.False(t, s.matches(fri.AddDate(0, 0, 1)))
+
+	// Day of month and day of week are alternatives when both are given.
+	s, err = parseCron("0 18 15 * 5")
+	require.NoError(t, err)
+	assert.True(t, s.matches(fri))
+	assert.True(t, s.matches(mustTime(t, "2018-06-15T18:00:00Z")))
+	assert.False(t, s.matches(mustTime(t, "2018-06-16T18:00:00Z")))
+
+	// 7 is Sunday.
+	s, err = parseCron("0 0 * * 7")
+	require.NoError(t, err)
+	assert.True(t, s.matches(mustTime(t, "2018-06-03T00:00:00Z")))
+}
+
+func TestCronSchedule_nextPrev(t *testing.T) {
+	from := mustTime(t, "2018-11-28T22:37:00Z")
+	for _, expr := range []string{"* * * * *", "0 18 * * 5", "*/15 9-17 1,15 * 1-5", "30 2 * 12 0,7", "0 0 29 2 *"} {
+		s, err := parseCron(expr)
+		require.NoError(t, err)
+
+		// Compare with a scan of every minute for the following week.
+		limit := from.Add(freezeHorizon)
+		var want time.Time
+		for m := from.Add(time.Minute); !m.After(limit); m = m.Add(time.Minute) {
+			if s.matches(m) {
+				want = m
+				break
+			}
+		}
+		got, ok := s.next(from, limit)
+		assert.Equal(t, !want.IsZero(), ok, expr)
+		assert.Equal(t, want, got, expr)
+
+		want = time.Time{}
+		limit = from.Add(-freezeHorizon)
+		for m := from; m.After(limit); m = m.Add(-time.Minute) {
+			if s.matches(m) {
+				want = m
+				break
+			}
+		}
+		got, ok = s.prev(from, limit)
+		assert.Equal(t, !want.IsZero(), ok, expr)
+		assert.Equal(t, want, got, expr)
+	}
+}
+
+func TestFreezeWindow_ActiveAt(t *testing.T) {
+	fixed := FreezeWindow{
+		Start: mustTime(t, "2018-12-20T00:00:00Z"),
+		End:   mustTime(t, "2019-01-02T00:00:00Z"),
+	}
+	_, ok := fixed.ActiveAt(mustTime(t, "2018-12-19T23:59:00Z"))
+	assert.False(t, ok)
+	f, ok := fixed.ActiveAt(mustTime(t, "2018-12-25T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, fixed.End, f.End)
+	_, ok = fixed.ActiveAt(fixed.End)
+	assert.False(t, ok)
+
+	weekend := FreezeWindow{Schedule: "0 18 * * 5", Duration: 62 * time.Hour}
+	f, ok = weekend.ActiveAt(mustTime(t, "2018-06-03T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, mustTime(t, "2018-06-01T18:00:00Z"), f.Start)
+	assert.Equal(t, mustTime(t, "2018-06-04T08:00:00Z"), f.End)
+	_, ok = weekend.ActiveAt(mustTime(t, "2018-06-04T08:00:00Z"))
+	assert.False(t, ok)
+	_, ok = weekend.ActiveAt(mustTime(t, "2018-06-01T17:59:00Z"))
+	assert.False(t, ok)
+}
+
+func TestFreezeWindow_NextAfter(t *testing.T) {
+	weekend := FreezeWindow{Schedule: "0 18 * * 5", Duration: 62 * time.Hour}
+	f, ok := weekend.NextAfter(mustTime(t, "2018-06-04T12:00:00Z"))
+	assert.True(t, ok)
+	assert.Equal(t, mustTime(t, "2018-06-08T18:00:00Z"), f.Start)
+
+	// Recurring freezes further away than a week are not found.
+	yearly := FreezeWindow{Schedule: "0 0 25 12 *", Duration: 24 * time.Hour}
+	_, ok = yearly.NextAfter(mustTime(t, "2018-06-04T12:00:00Z"))
+	assert.False(t, ok)
+
+	fixed := FreezeWindow{Start: mustTime(t, "2018-12-20T00:00:00Z")}
+	f, ok = fixed.NextAfter(mustTime(t, "2018-06-04T12:00:00Z"))
+	assert.True(t, ok)
+	assert.True(t, f.End.IsZero())
+	_, ok = fixed.NextAfter(mustTime(t, "2018-12-21T00:00:00Z"))
+	assert.False(t, ok)
+}
+
+func TestFreezeWindow_Validate(t *testing.T) {
+	assert.NoError(t, FreezeWindow{}.Validate())
+	assert.NoError(t, FreezeWindow{Schedule: "0 18 * * 5", Duration: time.Hour}.Validate())
+	assert.Error(t, FreezeWindow{Schedule: "0 18 * * 5"}.Validate())
+	assert.Error(t, FreezeWindow{Schedule: "0 18 * *", Duration: time.Hour}.Validate())
+	assert.Error(t, FreezeWindow{
+		Schedule: "0 18 * * 5", Duration: time.Hour,
+		Start: mustTime(t, "2018-12-20T00:00:00Z"),
+	}.Validate())
+	assert.Error(t, FreezeWindow{
+		Start: mustTime(t, "2018-12-20T00:00:00Z"),
+		End:   mustTime(t, "2018-12-19T00:00:00Z"),
+	}.Validate())
+}
+
+func TestCluster_CheckFreeze(t *testing.T) {
+	now := mustTime(t, "2018-06-01T12:00:00Z")
+	c := &Cluster{Name: "prod", FreezeWindows: FreezeWindows{{
+		Reason:        "incident",
+		AllowedOwners: []string{"sre@example.com"},
+		BreakGlass:    true,
+	}}}
+
+	err := c.CheckFreeze(now, NewOwnerSet("dev@example.com"), false)
+	require.Error(t, err)
+	assert.True(t, IsFreezeError(err))
+	assert.Equal(t, `cluster "prod" is frozen until further notice: incident`, err.Error())
+
+	assert.NoError(t, c.CheckFreeze(now, NewOwnerSet("sre@example.com"), false))
+	assert.NoError(t, c.CheckFreeze(now, NewOwnerSet("dev@example.com"), true))
+
+	c.FreezeWindows[0].BreakGlass = false
+	assert.Error(t, c.CheckFreeze(now, NewOwnerSet("dev@example.com"), true))
+
+	var none *Cluster
+	assert.NoError(t, none.CheckFreeze(now, nil, false))
+}
+
+func TestCheckFreezes(t *testing.T) {
+	frozen := &Cluster{Name: "frozen", FreezeWindows: FreezeWindows{{Reason: "holiday"}}}
+	open := &Cluster{Name: "open"}
+	dep := func(c *Cluster, instances int) *Deployment {
+		return &Deployment{
+			ClusterName:  c.Name,
+			Cluster:      c,
+			SourceID:     SourceID{Location: SourceLocation{Repo: "github.com/example/a"}},
+			DeployConfig: DeployConfig{NumInstances: instances},
+		}
+	}
+	now := time.Now()
+
+	prior := NewDeployments(dep(frozen, 1), dep(open, 1))
+	assert.NoError(t, CheckFreezes(prior, NewDeployments(dep(frozen, 1), dep(open, 2)), now))
+
+	err := CheckFreezes(prior, NewDeployments(dep(frozen, 2), dep(open, 1)), now)
+	assert.True(t, IsFreezeError(err))
+}
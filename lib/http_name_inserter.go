@@ -1,13 +1,16 @@
 package sous
 
 import (
+	"fmt"
 	"net/url"
+	"reflect"
 	"strings"
 	"sync"
 
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -17,6 +20,8 @@ type (
 		client  restful.HTTPClient
 		clients map[string]restful.HTTPClient
 		log     logging.LogSink
+		// AuthToken is sent as a bearer token to the servers of each cluster.
+		AuthToken string
 	}
 )
 
@@ -38,7 +43,7 @@ func (hni *HTTPNameInserter) getClients() error {
 	bundle := map[string]restful.HTTPClient{}
 	for _, s := range serverList.Servers {
 		//messages.ReportLogFieldsMessageToConsole(fmt.Sprintf("Adding %s : %s", s.ClusterName, s.URL), logging.ExtraDebug1Level, hni.log, s)
-		client, err := restful.NewClient(s.URL, hni.log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(hni.tid)})
+		client, err := restful.NewClient(s.URL, hni.log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(hni.tid)}, restful.BearerAuth(hni.AuthToken))
 		if err != nil {
 			return err
 		}
@@ -49,8 +54,37 @@ func (hni *HTTPNameInserter) getClients() error {
 	return nil
 }
 
-// Insert implements Inserter for HTTPNameInserter
+// Insert implements Inserter for HTTPNameInserter. An artifact already
+// recorded for sid by a server is left as it is there; see Replace.
 func (hni *HTTPNameInserter) Insert(sid SourceID, ba BuildArtifact) error {
+	return hni.eachServer(func(client restful.HTTPClient) error {
+		_, err := client.Create("./artifact", simplifyQV(sid.QueryValues()), ba, nil)
+		//TODO: Don't care about already existing (might be some other way to tell ./artifact that?)
+		if err != nil && strings.Contains(err.Error(), "412 Precondition Failed") {
+			return nil
+		}
+		return err
+	})
+}
+
+// Replace implements Replacer for HTTPNameInserter. It replaces the artifact
+// each server has recorded for sid with ba, so that e.g. the qualities of a
+// rescan are stored.
+func (hni *HTTPNameInserter) Replace(sid SourceID, ba BuildArtifact) error {
+	return hni.eachServer(func(client restful.HTTPClient) error {
+		recorded := &BuildArtifact{}
+		up, err := client.Retrieve("./artifact", simplifyQV(sid.QueryValues()), recorded, nil)
+		if err != nil {
+			return errors.Wrapf(err, "retrieving artifact for %s", sid)
+		}
+		_, err = up.Update(&ba, nil)
+		return errors.Wrapf(err, "replacing artifact for %s", sid)
+	})
+}
+
+// eachServer calls f concurrently with a client of each server, returning
+// the errors it returns.
+func (hni *HTTPNameInserter) eachServer(f func(restful.HTTPClient) error) error {
 	if err := hni.getClients(); err != nil {
 		return err
 	}
@@ -61,22 +95,17 @@ func (hni *HTTPNameInserter) Insert(sid SourceID, ba BuildArtifact) error {
 		wg.Add(1)
 		go func(client restful.HTTPClient) {
 			defer wg.Done()
-			if _, err := client.Create("./artifact", simplifyQV(sid.QueryValues()), ba, nil); err != nil {
-				//TODO: Don't care about already existing (might be some other way to tell ./artifact that?)
-				if !strings.Contains(err.Error(), "412 Precondition Failed") {
-					errs <- err
-				}
+			if err := f(client); err != nil {
+				errs <- err
 			}
 		}(cl)
 	}
 
 	wg.Wait()
+	close(errs)
 
 	var result *multierror.Error
-
-	select {
-	default:
-	case err := <-errs:
+	for err := range errs {
 		logging.ReportError(hni.log, err)
 		result = multierror.Append(result, err)
 	}
@@ -84,6 +113,27 @@ func (hni *HTTPNameInserter) Insert(sid SourceID, ba BuildArtifact) error {
 	return result.ErrorOrNil()
 }
 
+// EmptyReceiver implements Comparable on BuildArtifact.
+func (ba *BuildArtifact) EmptyReceiver() restful.Comparable {
+	return &BuildArtifact{}
+}
+
+// VariancesFrom implements Comparable on BuildArtifact.
+func (ba *BuildArtifact) VariancesFrom(c restful.Comparable) restful.Variances {
+	o, ok := c.(*BuildArtifact)
+	if !ok {
+		return restful.Variances{fmt.Sprintf("Not a *BuildArtifact: %T", c)}
+	}
+	var vs restful.Variances
+	if ba.DigestReference != o.DigestReference {
+		vs = append(vs, fmt.Sprintf("digest reference: %q != %q", ba.DigestReference, o.DigestReference))
+	}
+	if !reflect.DeepEqual(ba.Qualities, o.Qualities) {
+		vs = append(vs, fmt.Sprintf("qualities: %v != %v", ba.Qualities, o.Qualities))
+	}
+	return vs
+}
+
 func simplifyQV(qvs url.Values) map[string]string {
 	s := map[string]string{}
 	for n, vs := range qvs {
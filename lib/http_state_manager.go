@@ -21,7 +21,9 @@ type (
 		clusterClients  map[string]restful.HTTPClient
 		clusterUpdaters map[string]restful.UpdateDeleter
 		User            User
-		log             logging.LogSink
+		// AuthToken is sent as a bearer token to the servers of each cluster.
+		AuthToken string
+		log       logging.LogSink
 	}
 
 	gdmWrapper struct {
@@ -111,7 +113,7 @@ func (hsm *HTTPStateManager) buildClientBundle() error {
 	}
 	bundle := map[string]restful.HTTPClient{}
 	for _, s := range serverList.Servers {
-		client, err := restful.NewClient(s.URL, hsm.log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(hsm.tid)})
+		client, err := restful.NewClient(s.URL, hsm.log.Child(s.ClusterName+".http-client"), map[string]string{"OT-RequestId": string(hsm.tid)}, restful.BearerAuth(hsm.AuthToken))
 		if err != nil {
 			return err
 		}
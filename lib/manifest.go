@@ -29,6 +29,12 @@ type (
 		Owners []string
 		// Kind is the kind of software that SourceRepo represents.
 		Kind ManifestKind `validate:"nonzero"`
+		// Template names a template in Defs.Templates whose config each of
+		// the Deployments inherits, wherever it does not set its own.
+		Template string `yaml:",omitempty"`
+		// Dependencies lists the other manifests whose deployments this
+		// manifest's deployments require, in each cluster they apply to.
+		Dependencies Dependencies `yaml:",omitempty"`
 		// Deployments is a map of cluster names to DeploymentSpecs
 		Deployments DeploySpecs `validate:"keys=nonempty,values=nonzero"`
 	}
@@ -58,6 +64,7 @@ func (m Manifest) Clone() (c *Manifest) {
 		deployments[k] = v.Clone()
 	}
 	c.Owners = owners
+	c.Dependencies = m.Dependencies.Clone()
 	c.Deployments = deployments
 	return
 }
@@ -82,6 +89,12 @@ func (m *Manifest) Diff(o *Manifest) (bool, []string) {
 	if m.Kind != o.Kind {
 		diff("kind; this: %q; other: %q", m.Kind, o.Kind)
 	}
+	if m.Template != o.Template {
+		diff("template; this: %q; other: %q", m.Template, o.Template)
+	}
+	if !m.Dependencies.Equal(o.Dependencies) {
+		diff("dependencies; this: %v; other: %v", m.Dependencies, o.Dependencies)
+	}
 	if len(m.Owners) != len(o.Owners) {
 		diff("number of owners; this: %d; other: %d", len(m.Owners), len(o.Owners))
 	} else {
@@ -132,6 +145,8 @@ func (m *Manifest) Validate() []Flaw {
 		flaws = append(flaws, m.Kind.Validate()...)
 	}
 
+	flaws = append(flaws, m.validateDependencies()...)
+
 	/*
 		Cannot validate Deployments without defs...
 		In other words, we need (part of) the State context to do that.
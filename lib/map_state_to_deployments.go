@@ -58,28 +58,40 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests, log logging.Lo
 		var hadSpec bool
 
 		if was {
-			oldSpec, hadSpec = old.Deployments[d.ClusterName]
+			oldSpec, hadSpec = defs.ClusterSpec(old, d.ClusterName)
 		}
 
 		if !ok {
 			m = &Manifest{Deployments: DeploySpecs{}}
 			m.Owners = d.Owners.Slice()
 			m.SetID(mid)
+			if was {
+				m.Template = old.Template
+				m.Dependencies = old.Dependencies.Clone()
+			}
 		}
 		spec := DeploySpec{
 			Version:      d.SourceID.Version,
 			DeployConfig: d.DeployConfig.Clone(),
+			Template:     oldSpec.Template,
 		}
 
+		// Values the deployment inherits from the templates and cluster
+		// which the old manifest named, or from the defaults in defs, are
+		// removed again, unless the old manifest set them explicitly.
+		inherited, err := defs.inherited(d.Cluster, templateNames(oldSpec.Template, m.Template))
+		if err != nil {
+			return ms, errors.Wrapf(err, "manifest %q deployment to %s", mid, d.ClusterName)
+		}
 		// if was && hadSpec { if there's no old Spec, we'd unmerge from a zero Startup anyway...
-		spec.DeployConfig.Startup = d.Cluster.Startup.UnmergeDefaults(spec.DeployConfig.Startup, oldSpec.Startup)
+		spec.unmergeInherited(inherited, oldSpec)
 
 		for k, v := range spec.DeployConfig.Env {
-			clusterVal, ok := d.Cluster.Env[k]
+			inheritedVal, ok := inherited.Env[k]
 			if !ok {
 				continue
 			}
-			if string(clusterVal) == v {
+			if inheritedVal == v {
 				messages.ReportLogFieldsMessage("Redundant environment definition", logging.ExtremeLevel, log, k, v)
 				if was && hadSpec {
 					if _, present := oldSpec.Env[k]; present {
@@ -100,11 +112,20 @@ func (ds Deployments) PutbackManifests(defs Defs, olds Manifests, log logging.Lo
 		ms.Set(mid, m)
 	}
 
+	// Deployments to the clusters of a group the old manifest deployed to
+	// are put back under the group.
 	for _, k := range ms.Keys() {
 		m, there := ms.Get(k)
 		if !there {
 			continue
 		}
+		if old, was := olds.Get(k); was {
+			for _, key := range old.Deployments.ClusterNames() {
+				if defs.isGroup(key) {
+					defs.foldGroup(m, key, old.Deployments[key])
+				}
+			}
+		}
 		ms.Set(k, m)
 	}
 
@@ -168,21 +189,44 @@ func (ds Deployments) RawManifests(defs Defs, log logging.LogSink) (Manifests, e
 
 // DeploymentsFromManifest returns all deployments described by a single
 // manifest, in terms of the wider state (i.e. global and cluster definitions
-// and configuration).
+// and configuration). Each deployment inherits the config of the templates
+// in defs named by its DeploySpec and then by m, and then its cluster's
+// defaults, wherever it does not set its own. A DeploySpec for a group in
+// defs.ClusterGroups is that of each cluster in the group which m has no
+// DeploySpec of its own for. Required Env vars, Metadata fields and
+// Resources which are missing are given their defaults. Values which don't
+// conform to their definitions in defs are left for Defs.ValidateManifest to
+// report, and are rejected when the manifest is written.
 func DeploymentsFromManifest(defs Defs, m *Manifest) (Deployments, error) {
 	ds := NewDeployments()
-	var inherit []DeploySpec
 
-	for clusterName, spec := range m.Deployments {
+	for key := range m.Deployments {
+		if _, ok := defs.Clusters[key]; !ok && !defs.isGroup(key) {
+			return ds, errors.Errorf("cluster %q doesn't have a definition (but specified in manifest %q)", key, m.ID())
+		}
+	}
+
+	for clusterName, key := range defs.clusterKeys(m) {
 		cluster, ok := defs.Clusters[clusterName]
 		if !ok {
-			return ds, errors.Errorf("cluster %q doesn't have a definition (but specified in manifest %q)", clusterName, m.ID())
+			return ds, errors.Errorf("cluster %q of group %q doesn't have a definition (but specified in manifest %q)", clusterName, key, m.ID())
 		}
+		spec := m.Deployments[key]
 		spec.clusterName = cluster.BaseURL
+		templates, err := defs.templateConfigs(templateNames(spec.Template, m.Template))
+		if err != nil {
+			return ds, errors.Wrapf(err, "manifest %q deployment to %s", m.ID(), clusterName)
+		}
+		inherit := make([]DeploySpec, len(templates))
+		for i, dc := range templates {
+			inherit[i] = DeploySpec{DeployConfig: dc}
+		}
 		d, err := BuildDeployment(m, clusterName, cluster, spec, inherit)
 		if err != nil {
 			return ds, err
 		}
+		defs.applyDefaults(&d.DeployConfig)
+		d.Dependencies = m.Dependencies.inCluster(defs, clusterName)
 		ds.Add(d)
 	}
 	return ds, nil
@@ -104,5 +104,15 @@ func guardImage(r Registry, d *Deployment, log logging.LogSink) (*BuildArtifact,
 			return nil, &UnacceptableAdvisory{q, &d.SourceID}
 		}
 	}
+	if d.Cluster != nil {
+		if err := d.Cluster.VulnerabilityThresholds.Check(art.Qualities.VulnerabilityReport()); err != nil {
+			return nil, &UnacceptableVulnerabilities{SourceID: &d.SourceID, Cluster: d.ClusterName, Err: err}
+		}
+	}
+	if d.Cluster != nil && len(d.Cluster.SigningKeys) > 0 {
+		if err := art.VerifySignatures(d.SourceID, d.Cluster.SigningKeys); err != nil {
+			return nil, &UnverifiedArtifact{SourceID: &d.SourceID, Cluster: d.ClusterName, Err: err}
+		}
+	}
 	return art, err
 }
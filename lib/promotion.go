@@ -0,0 +1,146 @@
+package sous
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// PromotionChecker enforces Defs.PromotionOrder, by checking that a
+	// version is running in the previous stage before it is deployed to the
+	// next one.
+	PromotionChecker struct {
+		Deployer Deployer
+		Registry Registry
+		// History, if set, allows versions which have previously deployed
+		// successfully to a cluster to be deployed there again, e.g. for a
+		// rollback.
+		History DeploymentHistory
+	}
+
+	// PromotionError reports that a version may not be deployed to a cluster
+	// because it has not passed the previous stage of the promotion order.
+	PromotionError struct {
+		DeploymentID DeploymentID
+		Version      semv.Version
+		Stage        string
+		Reason       string
+	}
+)
+
+func (e *PromotionError) Error() string {
+	return fmt.Sprintf("version %s cannot be deployed to %s: %s must be running it first (%s)",
+		e.Version, e.DeploymentID, e.Stage, e.Reason)
+}
+
+// IsPromotionError returns true if the cause of err is a PromotionError.
+func IsPromotionError(err error) bool {
+	_, is := errors.Cause(err).(*PromotionError)
+	return is
+}
+
+// PreviousStage returns the cluster that precedes cluster in the promotion
+// order, if there is one.
+func (d Defs) PreviousStage(cluster string) (string, bool) {
+	for i, c := range d.PromotionOrder {
+		if c == cluster && i > 0 {
+			return d.PromotionOrder[i-1], true
+		}
+	}
+	return "", false
+}
+
+// CheckPromotionOrder returns an error if d.PromotionOrder names an unknown
+// cluster or repeats one.
+func (d Defs) CheckPromotionOrder() error {
+	seen := map[string]struct{}{}
+	for _, c := range d.PromotionOrder {
+		if _, ok := d.Clusters[c]; !ok {
+			return errors.Errorf("promotion order: no cluster named %q", c)
+		}
+		if _, ok := seen[c]; ok {
+			return errors.Errorf("promotion order: cluster %q appears twice", c)
+		}
+		seen[c] = struct{}{}
+	}
+	return nil
+}
+
+// Check returns a *PromotionError unless version may be deployed to did,
+// given the deployments in gdm.
+func (pc PromotionChecker) Check(defs Defs, gdm Deployments, did DeploymentID, version semv.Version) error {
+	stage, ok := defs.PreviousStage(did.Cluster)
+	if !ok {
+		return nil
+	}
+	refuse := func(format string, a ...interface{}) error {
+		return &PromotionError{
+			DeploymentID: did,
+			Version:      version,
+			Stage:        stage,
+			Reason:       fmt.Sprintf(format, a...),
+		}
+	}
+
+	if pc.History != nil {
+		entries, err := pc.History.History(did)
+		if err != nil {
+			return errors.Wrapf(err, "reading history of %s", did)
+		}
+		for _, e := range entries {
+			if e.Outcome == OutcomeSucceeded && e.DeploySpec.Version.Equals(version) {
+				return nil
+			}
+		}
+	}
+
+	stageDep, ok := gdm.Get(DeploymentID{ManifestID: did.ManifestID, Cluster: stage})
+	if !ok {
+		return refuse("not deployed to %s", stage)
+	}
+	if !stageDep.SourceID.Version.Equals(version) {
+		return refuse("%s is set to version %s", stage, stageDep.SourceID.Version)
+	}
+	if pc.Deployer == nil {
+		return errors.Errorf("cannot check the status of %s: no deployer", stageDep.ID())
+	}
+
+	pair := &DeployablePair{Post: &Deployable{Deployment: stageDep}}
+	pair.SetID(stageDep.ID())
+	ds, err := pc.Deployer.Status(pc.Registry, defs.Clusters, pair)
+	if err != nil {
+		return errors.Wrapf(err, "checking status of %s", stageDep.ID())
+	}
+	if ds.Status != DeployStatusActive {
+		return refuse("%s is %s", stage, ds.Status)
+	}
+	if !ds.SourceID.Version.Equals(version) {
+		return refuse("%s is running version %s", stage, ds.SourceID.Version)
+	}
+	return nil
+}
+
+// CheckChanges checks each deployment whose version differs between prior and
+// next.
+func (pc PromotionChecker) CheckChanges(defs Defs, prior, next Deployments) error {
+	if len(defs.PromotionOrder) == 0 {
+		return nil
+	}
+	for _, pair := range prior.Diff(next).Collect() {
+		switch pair.Kind() {
+		default:
+			continue
+		case AddedKind, ModifiedKind:
+		}
+		post := pair.Post.Deployment
+		if pair.Prior != nil && pair.Prior.SourceID.Version.Equals(post.SourceID.Version) {
+			continue
+		}
+		if err := pc.Check(defs, prior, pair.ID(), post.SourceID.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
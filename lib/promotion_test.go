@@ -0,0 +1,108 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/nyarly/spies"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefs_CheckPromotionOrder(t *testing.T) {
+	defs := DefaultStateFixture().Defs
+
+	defs.PromotionOrder = []string{"cluster1", "cluster2", "cluster3"}
+	assert.NoError(t, defs.CheckPromotionOrder())
+
+	defs.PromotionOrder = []string{"cluster1", "nowhere"}
+	assert.Error(t, defs.CheckPromotionOrder())
+
+	defs.PromotionOrder = []string{"cluster1", "cluster2", "cluster1"}
+	assert.Error(t, defs.CheckPromotionOrder())
+}
+
+func TestPromotionChecker_Check(t *testing.T) {
+	state := DefaultStateFixture()
+	state.Defs.PromotionOrder = []string{"cluster1", "cluster2"}
+	gdm, err := state.Deployments()
+	require.NoError(t, err)
+
+	mid := state.Manifests.Keys()[0]
+	target := DeploymentID{ManifestID: mid, Cluster: "cluster2"}
+	stage, ok := gdm.Get(DeploymentID{ManifestID: mid, Cluster: "cluster1"})
+	require.True(t, ok)
+	current := stage.SourceID.Version
+	next := semv.MustParse("9.9.9")
+
+	checker := func(ds *DeployState) (PromotionChecker, *spies.Spy) {
+		d, spy := NewDeployerSpy()
+		spy.MatchMethod("Status", spies.AnyArgs, ds, nil)
+		return PromotionChecker{Deployer: d}, spy
+	}
+	active := &DeployState{Status: DeployStatusActive, Deployment: *stage}
+
+	t.Run("first stage", func(t *testing.T) {
+		pc, spy := checker(active)
+		did := target
+		did.Cluster = "cluster1"
+		assert.NoError(t, pc.Check(state.Defs, gdm, did, next))
+		assert.Len(t, spy.CallsTo("Status"), 0)
+	})
+
+	t.Run("outside the order", func(t *testing.T) {
+		pc, _ := checker(active)
+		did := target
+		did.Cluster = "cluster3"
+		assert.NoError(t, pc.Check(state.Defs, gdm, did, next))
+	})
+
+	t.Run("active in previous stage", func(t *testing.T) {
+		pc, spy := checker(active)
+		assert.NoError(t, pc.Check(state.Defs, gdm, target, current))
+		assert.Len(t, spy.CallsTo("Status"), 1)
+	})
+
+	t.Run("not in previous stage", func(t *testing.T) {
+		pc, _ := checker(active)
+		err := pc.Check(state.Defs, gdm, target, next)
+		assert.True(t, IsPromotionError(err), "got %v", err)
+	})
+
+	t.Run("failed in previous stage", func(t *testing.T) {
+		pc, _ := checker(&DeployState{Status: DeployStatusFailed, Deployment: *stage})
+		err := pc.Check(state.Defs, gdm, target, current)
+		assert.True(t, IsPromotionError(err), "got %v", err)
+	})
+
+	t.Run("previously succeeded", func(t *testing.T) {
+		pc, spy := checker(active)
+		h := NewMemoryDeploymentHistory()
+		require.NoError(t, h.Record(DeploymentHistoryEntry{
+			DeploymentID: target,
+			DeploySpec:   DeploySpec{Version: next},
+			Outcome:      OutcomeSucceeded,
+		}))
+		pc.History = h
+		assert.NoError(t, pc.Check(state.Defs, gdm, target, next))
+		assert.Len(t, spy.CallsTo("Status"), 0)
+	})
+}
+
+func TestPromotionChecker_CheckChanges(t *testing.T) {
+	state := DefaultStateFixture()
+	state.Defs.PromotionOrder = []string{"cluster1", "cluster2"}
+	prior, err := state.Deployments()
+	require.NoError(t, err)
+	mid := state.Manifests.Keys()[0]
+
+	pc := PromotionChecker{}
+	assert.NoError(t, pc.CheckChanges(state.Defs, prior, prior.Clone()))
+
+	next := prior.Clone()
+	d, _ := next.Get(DeploymentID{ManifestID: mid, Cluster: "cluster2"})
+	d.SourceID.Version = semv.MustParse("9.9.9")
+	next.Set(d.ID(), d)
+	err = pc.CheckChanges(state.Defs, prior, next)
+	assert.True(t, IsPromotionError(err), "got %v", err)
+}
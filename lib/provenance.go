@@ -0,0 +1,96 @@
+package sous
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opentable/sous/util/sbom"
+)
+
+type (
+	// Provenance records how an artifact was built.
+	Provenance struct {
+		// Revision is the source revision that was built.
+		Revision string
+		// Strategy names the build strategy the buildpack used.
+		Strategy string
+		// BuilderImages are the images the build assembled the artifact from,
+		// e.g. those named by a split container's runspec. They are named by
+		// digest when it is known.
+		BuilderImages []string `json:",omitempty"`
+		// Advisories are the advisories determined on the build.
+		Advisories Advisories
+		// User is the user who asked for the build, and Host the machine which
+		// built it.
+		User, Host string
+		// Started is when the build started, and Elapsed how long it took.
+		Started time.Time
+		Elapsed time.Duration
+	}
+
+	// SBOM is a software bill of materials: the dependencies of an artifact,
+	// as declared in its source.
+	SBOM struct {
+		Components []sbom.Component
+	}
+
+	// A ProvenanceRegistry looks up how the artifacts in a Registry were
+	// built.
+	ProvenanceRegistry interface {
+		// GetProvenance returns the Provenance and SBOM recorded for the
+		// artifact for a source ID. Either is nil if it was not recorded.
+		GetProvenance(SourceID) (*Provenance, *SBOM, error)
+	}
+
+	// A StrategyNamer is a Buildpack which names its build strategy for the
+	// provenance of its builds.
+	StrategyNamer interface {
+		StrategyName() string
+	}
+)
+
+// strategyName returns the name of bp's build strategy.
+func strategyName(bp Buildpack) string {
+	if sn, ok := bp.(StrategyNamer); ok {
+		return sn.StrategyName()
+	}
+	return fmt.Sprintf("%T", bp)
+}
+
+// NewSBOM lists the dependencies declared in the source at dir.
+func NewSBOM(dir string) (*SBOM, error) {
+	cs, err := sbom.Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &SBOM{Components: cs}, nil
+}
+
+// recordProvenance records the provenance and SBOM of each product in br,
+// which bp built from bc for user, starting at started. Buildpacks may have
+// begun a product's provenance with its BuilderImages.
+func (br *BuildResult) recordProvenance(bp Buildpack, bc *BuildContext, user User, started time.Time) error {
+	bom, err := NewSBOM(bc.Source.AbsDir())
+	if err != nil {
+		return err
+	}
+	if user.Name == "" && user.Email == "" {
+		user.Name = bc.User.Username
+	}
+	for _, prdt := range br.Products {
+		p := prdt.Provenance
+		if p == nil {
+			p = &Provenance{}
+		}
+		p.Revision = bc.RevID()
+		p.Strategy = strategyName(bp)
+		p.Advisories = prdt.Advisories
+		p.User = user.String()
+		p.Host = bc.Machine.FullHost
+		p.Started = started
+		p.Elapsed = br.Elapsed
+		prdt.Provenance = p
+		prdt.SBOM = bom
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package sous
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/sbom"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedBuildpack struct{ recordingBuildpack }
+
+func (namedBuildpack) StrategyName() string { return "named" }
+
+func TestBuildResultRecordProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-provenance-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "svc"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "svc", "requirements.txt"), []byte("requests==2.19.1\n"), 0644))
+
+	bc := &BuildContext{
+		Source:  SourceContext{RootDir: dir, OffsetDir: "svc", Revision: "abcdef"},
+		Machine: Machine{FullHost: "builder.example.com"},
+	}
+	started := time.Now()
+	br := &BuildResult{
+		Elapsed: time.Minute,
+		Products: []*BuildProduct{
+			{Advisories: Advisories{DirtyWS}, Provenance: &Provenance{BuilderImages: []string{"base@sha256:cabba9e"}}},
+			{},
+		},
+	}
+
+	err = br.recordProvenance(&namedBuildpack{}, bc, User{Name: "Test User", Email: "test@example.com"}, started)
+	require.NoError(t, err)
+
+	p := br.Products[0].Provenance
+	require.NotNil(t, p)
+	assert.Equal(t, "abcdef", p.Revision)
+	assert.Equal(t, "named", p.Strategy)
+	assert.Equal(t, []string{"base@sha256:cabba9e"}, p.BuilderImages)
+	assert.Equal(t, Advisories{DirtyWS}, p.Advisories)
+	assert.Equal(t, "Test User <test@example.com>", p.User)
+	assert.Equal(t, "builder.example.com", p.Host)
+	assert.Equal(t, started, p.Started)
+	assert.Equal(t, time.Minute, p.Elapsed)
+
+	require.NotNil(t, br.Products[1].Provenance)
+	assert.Equal(t, "*sous.recordingBuildpack", strategyName(&recordingBuildpack{}))
+	assert.Equal(t, []sbom.Component{{Type: "pypi", Name: "requests", Version: "2.19.1"}}, br.Products[1].SBOM.Components)
+}
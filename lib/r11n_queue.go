@@ -5,6 +5,7 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/opentable/sous/util/logging"
 	"github.com/pborman/uuid"
 )
 
@@ -20,6 +21,8 @@ type (
 		fifoRefs      *ring.Ring
 		handler       func(*QueuedR11n) DiffResolution
 		start         bool
+		store         R11nQueueStore
+		storeLog      logging.LogSink
 		sync.Mutex
 	}
 	// QueuedR11n is a queue item wrapping a Rectification with an ID and position.
@@ -81,6 +84,16 @@ func R11nQueueStartWithHandler(handler func(*QueuedR11n) DiffResolution) R11nQue
 	}
 }
 
+// R11nQueueStorage records the rectifications queued in store, and looks up
+// those which are no longer in memory there. Failures of store are reported
+// to ls.
+func R11nQueueStorage(store R11nQueueStore, ls logging.LogSink) R11nQueueOpt {
+	return func(rq *R11nQueue) {
+		rq.store = store
+		rq.storeLog = ls
+	}
+}
+
 // Snapshot returns a slice of items to be processed in the queue ordered by
 // their queue position. It includes the item being worked on at the head of the
 // queue.
@@ -101,11 +114,47 @@ func (rq *R11nQueue) Snapshot() []QueuedR11n {
 // and false otherwise.
 func (rq *R11nQueue) ByID(id R11nID) (*QueuedR11n, bool) {
 	rq.Lock()
-	defer rq.Unlock()
 	qr, ok := rq.allRefs[id]
+	rq.Unlock()
+	if !ok {
+		return rq.stored(id)
+	}
 	return qr, ok
 }
 
+// stored returns the rectification with id from rq's store if it is done,
+// e.g. if it was done before this server started.
+func (rq *R11nQueue) stored(id R11nID) (*QueuedR11n, bool) {
+	if rq.store == nil {
+		return nil, false
+	}
+	return storedQueuedR11n(rq.store, rq.storeLog, id)
+}
+
+func storedQueuedR11n(store R11nQueueStore, ls logging.LogSink, id R11nID) (*QueuedR11n, bool) {
+	sr, ok, err := store.ByID(id)
+	if err != nil {
+		reportR11nStoreError(ls, err)
+		return nil, false
+	}
+	if !ok || sr.Resolution == nil {
+		return nil, false
+	}
+	return sr.queuedR11n(), true
+}
+
+// reportR11nStoreError reports err, a failure to record or look up a
+// rectification in an R11nQueueStore, and counts it in the
+// "r11n-store-errors" metric. Such failures don't stop the rectification, but
+// once it is no longer in memory, it can't be found.
+func reportR11nStoreError(ls logging.LogSink, err error) {
+	if ls == nil {
+		return
+	}
+	logging.ReportError(ls, err)
+	ls.Metrics().IncCounter("r11n-store-errors", 1)
+}
+
 func (rq *R11nQueue) init() *R11nQueue {
 	rq.Lock()
 	defer rq.Unlock()
@@ -128,7 +177,12 @@ func (rq *R11nQueue) Start(handler func(*QueuedR11n) DiffResolution) {
 	go func() {
 		for {
 			qr := rq.next()
-			handler(qr)
+			rez := handler(qr)
+			if rq.store != nil {
+				if err := rq.store.Done(qr.ID, rez); err != nil {
+					reportR11nStoreError(rq.storeLog, err)
+				}
+			}
 			rq.Lock()
 			close(qr.done)
 			delete(rq.refs, qr.ID)
@@ -145,12 +199,33 @@ func (rq *R11nQueue) Wait(id R11nID) (DiffResolution, bool) {
 	qr, ok := rq.allRefs[id]
 	rq.Unlock()
 	if !ok {
-		return DiffResolution{}, false
+		if qr, ok = rq.stored(id); !ok {
+			return DiffResolution{}, false
+		}
 	}
 	<-qr.done
 	return qr.Rectification.Resolution, true
 }
 
+// WaitLast waits for the last rectification in the queue to be processed
+// then returns its result. If the queue is empty, it immediately returns a
+// zero DiffResolution and false.
+func (rq *R11nQueue) WaitLast() (DiffResolution, bool) {
+	rq.Lock()
+	var last *QueuedR11n
+	for _, qr := range rq.refs {
+		if last == nil || qr.Pos > last.Pos {
+			last = qr
+		}
+	}
+	rq.Unlock()
+	if last == nil {
+		return DiffResolution{}, false
+	}
+	<-last.done
+	return last.Rectification.Resolution, true
+}
+
 // Push adds r to the queue, wrapped in a *QueuedR11n. It returns the wrapper.
 // If the push was successful, it returns the wrapper and true, otherwise it
 // returns nil and false.
@@ -165,13 +240,24 @@ func (rq *R11nQueue) Push(r *Rectification) (*QueuedR11n, bool) {
 
 // internalPush assumes rq is already locked.
 func (rq *R11nQueue) internalPush(r *Rectification) *QueuedR11n {
-	id := NewR11nID()
+	return rq.pushID(NewR11nID(), r, true)
+}
+
+// pushID adds r to the queue with id. If record is true, it is recorded in
+// rq's store before it can be handled. pushID assumes rq is already locked,
+// and that the queue is not full.
+func (rq *R11nQueue) pushID(id R11nID, r *Rectification, record bool) *QueuedR11n {
 	qr := &QueuedR11n{
 		ID:            id,
 		Pos:           len(rq.queue),
 		Rectification: r,
 		done:          make(chan struct{}),
 	}
+	if record && rq.store != nil {
+		if err := rq.store.Queued(storedR11n(qr)); err != nil {
+			reportR11nStoreError(rq.storeLog, err)
+		}
+	}
 	rq.refs[id] = qr
 	rq.allRefs[id] = qr
 	rq.fifoRefs = rq.fifoRefs.Next()
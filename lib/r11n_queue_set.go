@@ -1,9 +1,13 @@
 package sous
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/nyarly/spies"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -12,14 +16,17 @@ type (
 		PushIfEmpty(r *Rectification) (*QueuedR11n, bool)
 		Push(r *Rectification) (*QueuedR11n, bool)
 		Wait(did DeploymentID, id R11nID) (DiffResolution, bool)
+		ByID(did DeploymentID, id R11nID) (*QueuedR11n, bool)
 		Queues() map[DeploymentID]*R11nQueue
 	}
 
 	// R11nQueueSet is a concurrency-safe mapping of DeploymentID to R11nQueue.
 	R11nQueueSet struct {
-		set  map[DeploymentID]*R11nQueue
-		opts []R11nQueueOpt
-		reg  Registry
+		set      map[DeploymentID]*R11nQueue
+		opts     []R11nQueueOpt
+		reg      Registry
+		store    R11nQueueStore
+		storeLog logging.LogSink
 		sync.RWMutex
 	}
 
@@ -31,24 +38,83 @@ type (
 
 // NewR11nQueueSet returns a ready to use R11nQueueSet.
 func NewR11nQueueSet(opts ...R11nQueueOpt) *R11nQueueSet {
+	// The set needs the store its queues record r11ns in to find those
+	// which have no queue since this server started.
+	probe := &R11nQueue{}
+	for _, opt := range opts {
+		opt(probe)
+	}
 	return &R11nQueueSet{
-		set:  map[DeploymentID]*R11nQueue{},
-		opts: opts,
+		set:      map[DeploymentID]*R11nQueue{},
+		opts:     opts,
+		store:    probe.store,
+		storeLog: probe.storeLog,
 	}
 }
 
-// PushIfEmpty creates a queue for the DeploymentID of r if it does not already
-// exist. It calls PushIfEmpty on that R11nQueue passing r.
-func (rqs *R11nQueueSet) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
+// Recover queues again the r11ns which were queued in the set's store but
+// not done, e.g. because the server stopped. Those which delete deployments
+// are not recovered, since the deployments they delete are not stored: they
+// are recorded as done with an error, and the resolver deletes the
+// deployments again if they are still not wanted.
+func (rqs *R11nQueueSet) Recover(ls logging.LogSink) error {
+	if rqs.store == nil {
+		return nil
+	}
+	pending, err := rqs.store.Pending()
+	if err != nil {
+		return errors.Wrap(err, "recovering queued deploys")
+	}
 	rqs.Lock()
 	defer rqs.Unlock()
-	id := r.Pair.ID()
+	for _, sr := range pending {
+		if sr.Post == nil {
+			rqs.notRecovered(sr, "it deletes the deployment")
+			continue
+		}
+		queue := rqs.queue(sr.DeploymentID)
+		queue.Lock()
+		if len(queue.queue) == queue.cap {
+			queue.Unlock()
+			rqs.notRecovered(sr, "the queue is full")
+			continue
+		}
+		queue.pushID(sr.ID, sr.rectification(ls), false)
+		queue.Unlock()
+		messages.ReportLogFieldsMessageWithIDs(fmt.Sprintf("Recovered queued deploy %s", sr.ID),
+			logging.InformationLevel, ls, sr.DeploymentID)
+	}
+	return nil
+}
+
+func (rqs *R11nQueueSet) notRecovered(sr StoredR11n, why string) {
+	err := rqs.store.Done(sr.ID, DiffResolution{
+		DeploymentID: sr.DeploymentID,
+		Desc:         "not recovered",
+		Error:        WrapResolveError(errors.Errorf("not recovered after restart: %s", why)),
+	})
+	if err != nil {
+		reportR11nStoreError(rqs.storeLog, err)
+	}
+}
+
+// queue returns the queue for id, creating it if it does not exist. It
+// assumes rqs is locked.
+func (rqs *R11nQueueSet) queue(id DeploymentID) *R11nQueue {
 	queue, ok := rqs.set[id]
 	if !ok {
 		queue = NewR11nQueue(rqs.opts...)
 		rqs.set[id] = queue
 	}
-	return queue.PushIfEmpty(r)
+	return queue
+}
+
+// PushIfEmpty creates a queue for the DeploymentID of r if it does not already
+// exist. It calls PushIfEmpty on that R11nQueue passing r.
+func (rqs *R11nQueueSet) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
+	rqs.Lock()
+	defer rqs.Unlock()
+	return rqs.queue(r.Pair.ID()).PushIfEmpty(r)
 }
 
 // Push creates a queue for the DeploymentID of r if it does not already
@@ -56,28 +122,63 @@ func (rqs *R11nQueueSet) PushIfEmpty(r *Rectification) (*QueuedR11n, bool) {
 func (rqs *R11nQueueSet) Push(r *Rectification) (*QueuedR11n, bool) {
 	rqs.Lock()
 	defer rqs.Unlock()
-	id := r.Pair.ID()
-	queue, ok := rqs.set[id]
-	if !ok {
-		queue = NewR11nQueue(rqs.opts...)
-		rqs.set[id] = queue
-	}
-	return queue.Push(r)
+	return rqs.queue(r.Pair.ID()).Push(r)
 }
 
 // Wait waits for the r11n with id id to complete, if it is found in the
-// queue for did. If there is no queue for did or it exists but does not contain
-// id, then it returns zero DiffResolution, false.
+// queue for did, or in the set's store. If there is no queue for did or it
+// exists but does not contain id, then it returns zero DiffResolution, false.
 func (rqs *R11nQueueSet) Wait(did DeploymentID, id R11nID) (DiffResolution, bool) {
 	rqs.Lock()
 	rq, ok := rqs.set[did]
 	rqs.Unlock()
 	if !ok {
-		return DiffResolution{}, false
+		qr, ok := rqs.stored(did, id)
+		if !ok {
+			return DiffResolution{}, false
+		}
+		return qr.Rectification.Resolution, true
 	}
 	return rq.Wait(id)
 }
 
+// WaitLast waits for the last r11n in the queue for did to complete. If there
+// is no queue for did or it is empty, then it returns zero DiffResolution,
+// false.
+func (rqs *R11nQueueSet) WaitLast(did DeploymentID) (DiffResolution, bool) {
+	rqs.Lock()
+	rq, ok := rqs.set[did]
+	rqs.Unlock()
+	if !ok {
+		return DiffResolution{}, false
+	}
+	return rq.WaitLast()
+}
+
+// ByID returns the r11n with id id from the queue for did, or from the set's
+// store, and true. If it is not found, it returns nil, false.
+func (rqs *R11nQueueSet) ByID(did DeploymentID, id R11nID) (*QueuedR11n, bool) {
+	rqs.Lock()
+	rq, ok := rqs.set[did]
+	rqs.Unlock()
+	if !ok {
+		return rqs.stored(did, id)
+	}
+	return rq.ByID(id)
+}
+
+// stored returns the done r11n with id id for did from the set's store.
+func (rqs *R11nQueueSet) stored(did DeploymentID, id R11nID) (*QueuedR11n, bool) {
+	if rqs.store == nil {
+		return nil, false
+	}
+	qr, ok := storedQueuedR11n(rqs.store, rqs.storeLog, id)
+	if !ok || qr.Rectification.Pair.ID() != did {
+		return nil, false
+	}
+	return qr, true
+}
+
 // Queues returns a snapshot of queues in this set.
 func (rqs *R11nQueueSet) Queues() map[DeploymentID]*R11nQueue {
 	rqs.Lock()
@@ -116,6 +217,15 @@ func (s QueueSetSpy) Wait(did DeploymentID, id R11nID) (DiffResolution, bool) {
 	return res.Get(0).(DiffResolution), res.Bool(1)
 }
 
+// ByID is a spy implementation of QueueSet
+func (s QueueSetSpy) ByID(did DeploymentID, id R11nID) (*QueuedR11n, bool) {
+	res := s.Called(did, id)
+	if res.Get(0) == nil {
+		return nil, false
+	}
+	return res.Get(0).(*QueuedR11n), res.Bool(1)
+}
+
 // Queues is a spy implementation of QueueSet
 func (s QueueSetSpy) Queues() map[DeploymentID]*R11nQueue {
 	res := s.Called()
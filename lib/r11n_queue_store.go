@@ -0,0 +1,135 @@
+package sous
+
+import (
+	"sync"
+
+	"github.com/opentable/sous/util/logging"
+)
+
+type (
+	// An R11nQueueStore keeps a durable record of the rectifications queued in
+	// R11nQueues, so that those not yet done when a server stops can be queued
+	// again when it starts, and the results of those which are done can still
+	// be found by their R11nIDs.
+	R11nQueueStore interface {
+		// Queued records that sr has been queued.
+		Queued(sr StoredR11n) error
+		// Done records the resolution of the rectification with id.
+		Done(id R11nID, rez DiffResolution) error
+		// Pending returns the rectifications which were queued but are not
+		// done, in the order they were queued.
+		Pending() ([]StoredR11n, error)
+		// ByID returns the rectification with id, and false if there is none.
+		ByID(id R11nID) (StoredR11n, bool, error)
+	}
+
+	// StoredR11n is the record of a queued rectification in an
+	// R11nQueueStore.
+	StoredR11n struct {
+		ID           R11nID
+		DeploymentID DeploymentID
+		// Post is the deployment intended by the rectification, which is nil
+		// if it deletes the deployment.
+		Post *Deployment
+		// Prior is the deployment a staged rollout reverts to: see
+		// Rectification.PlanRollout.
+		Prior *Deployment
+		// Resolution is nil until the rectification is done.
+		Resolution *DiffResolution
+	}
+
+	// MemoryR11nQueueStore is an R11nQueueStore which is only kept in memory.
+	MemoryR11nQueueStore struct {
+		sync.Mutex
+		order []R11nID
+		r11ns map[R11nID]StoredR11n
+	}
+)
+
+// storedR11n returns the record of qr for an R11nQueueStore.
+func storedR11n(qr *QueuedR11n) StoredR11n {
+	r := qr.Rectification
+	sr := StoredR11n{ID: qr.ID, DeploymentID: r.Pair.ID()}
+	if r.Pair.Post != nil && r.Pair.Post.Deployment != nil {
+		sr.Post = r.Pair.Post.Deployment.Clone()
+	}
+	r.RLock()
+	if r.prior != nil {
+		sr.Prior = r.prior.Clone()
+	}
+	r.RUnlock()
+	return sr
+}
+
+// rectification returns a new Rectification of sr, to be queued again. It
+// deploys sr.Post without regard to the deployment it replaces, as
+// rectifications requested through the API do, and plans any staged rollout
+// again from the start.
+func (sr StoredR11n) rectification(ls logging.LogSink) *Rectification {
+	r := NewRectification(DeployablePair{Post: &Deployable{Deployment: sr.Post}}, ls)
+	r.Pair.SetID(sr.DeploymentID)
+	r.PlanRollout(sr.Prior)
+	return r
+}
+
+// queuedR11n returns the done rectification sr as a QueuedR11n.
+func (sr StoredR11n) queuedR11n() *QueuedR11n {
+	r := &Rectification{Resolution: *sr.Resolution}
+	if sr.Post != nil {
+		r.Pair.Post = &Deployable{Deployment: sr.Post}
+	}
+	r.Pair.SetID(sr.DeploymentID)
+	done := make(chan struct{})
+	close(done)
+	return &QueuedR11n{ID: sr.ID, Pos: -1, Rectification: r, done: done}
+}
+
+// NewMemoryR11nQueueStore returns an empty MemoryR11nQueueStore.
+func NewMemoryR11nQueueStore() *MemoryR11nQueueStore {
+	return &MemoryR11nQueueStore{r11ns: map[R11nID]StoredR11n{}}
+}
+
+// Queued implements R11nQueueStore on MemoryR11nQueueStore.
+func (s *MemoryR11nQueueStore) Queued(sr StoredR11n) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.r11ns[sr.ID]; !exists {
+		s.order = append(s.order, sr.ID)
+	}
+	s.r11ns[sr.ID] = sr
+	return nil
+}
+
+// Done implements R11nQueueStore on MemoryR11nQueueStore.
+func (s *MemoryR11nQueueStore) Done(id R11nID, rez DiffResolution) error {
+	s.Lock()
+	defer s.Unlock()
+	sr, ok := s.r11ns[id]
+	if !ok {
+		return nil
+	}
+	sr.Resolution = &rez
+	s.r11ns[id] = sr
+	return nil
+}
+
+// Pending implements R11nQueueStore on MemoryR11nQueueStore.
+func (s *MemoryR11nQueueStore) Pending() ([]StoredR11n, error) {
+	s.Lock()
+	defer s.Unlock()
+	pending := []StoredR11n{}
+	for _, id := range s.order {
+		if sr := s.r11ns[id]; sr.Resolution == nil {
+			pending = append(pending, sr)
+		}
+	}
+	return pending, nil
+}
+
+// ByID implements R11nQueueStore on MemoryR11nQueueStore.
+func (s *MemoryR11nQueueStore) ByID(id R11nID) (StoredR11n, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	sr, ok := s.r11ns[id]
+	return sr, ok, nil
+}
@@ -0,0 +1,151 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+func TestR11nQueueSet_Recover(t *testing.T) {
+	store := NewMemoryR11nQueueStore()
+
+	// Nothing handles r11ns queued before the "restart".
+	before := NewR11nQueueSet(R11nQueueStorage(store, logging.SilentLogSet()))
+	queued, ok := before.Push(makeTestR11nWithRepo("one"))
+	if !ok {
+		t.Fatal("push failed")
+	}
+	deletion := &Rectification{Pair: DeployablePair{Prior: makeTestR11nWithRepo("two").Pair.Post}}
+	deletion.Pair.SetID(makeTestR11nWithRepo("two").Pair.ID())
+	deleted, ok := before.Push(deletion)
+	if !ok {
+		t.Fatal("push failed")
+	}
+
+	after := NewR11nQueueSet(R11nQueueStorage(store, logging.SilentLogSet()), R11nQueueStartWithHandler(
+		func(qr *QueuedR11n) DiffResolution {
+			return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: ModifyDiff}
+		}))
+	if err := after.Recover(logging.SilentLogSet()); err != nil {
+		t.Fatal(err)
+	}
+
+	did := queued.Rectification.Pair.ID()
+	rez, ok := after.Wait(did, queued.ID)
+	if !ok {
+		t.Fatalf("recovered r11n %s not found", queued.ID)
+	}
+	if rez.Desc != ModifyDiff {
+		t.Errorf("got resolution %q; want %q", rez.Desc, ModifyDiff)
+	}
+	if err := checkR11nHasRepo("one")(mustByID(t, after, did, queued.ID)); err != nil {
+		t.Error(err)
+	}
+
+	rez, ok = after.Wait(deletion.Pair.ID(), deleted.ID)
+	if !ok {
+		t.Fatalf("deletion %s not found", deleted.ID)
+	}
+	if rez.Error == nil {
+		t.Errorf("deletion was recovered; want it recorded as failed")
+	}
+
+	pending, err := store.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("got %d pending r11ns; want 0", len(pending))
+	}
+}
+
+func TestR11nQueueSet_ByID_afterRestart(t *testing.T) {
+	store := NewMemoryR11nQueueStore()
+	before := NewR11nQueueSet(R11nQueueStorage(store, logging.SilentLogSet()), R11nQueueStartWithHandler(
+		func(qr *QueuedR11n) DiffResolution {
+			return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: CreateDiff}
+		}))
+	queued, ok := before.Push(makeTestR11nWithRepo("one"))
+	if !ok {
+		t.Fatal("push failed")
+	}
+	did := queued.Rectification.Pair.ID()
+	before.Wait(did, queued.ID)
+
+	after := NewR11nQueueSet(R11nQueueStorage(store, logging.SilentLogSet()))
+	qr := mustByID(t, after, did, queued.ID)
+	if qr.Pos != -1 {
+		t.Errorf("got position %d; want -1", qr.Pos)
+	}
+	if qr.Rectification.Resolution.Desc != CreateDiff {
+		t.Errorf("got resolution %q; want %q", qr.Rectification.Resolution.Desc, CreateDiff)
+	}
+	if rez, ok := after.Wait(did, queued.ID); !ok || rez.Desc != CreateDiff {
+		t.Errorf("Wait got %q, %t; want %q, true", rez.Desc, ok, CreateDiff)
+	}
+
+	other := makeTestR11nWithRepo("other").Pair.ID()
+	if _, ok := after.ByID(other, queued.ID); ok {
+		t.Errorf("r11n %s found for %q", queued.ID, other)
+	}
+}
+
+// failingR11nQueueStore is an R11nQueueStore whose storage is unavailable.
+type failingR11nQueueStore struct{}
+
+func (failingR11nQueueStore) Queued(StoredR11n) error {
+	return errors.New("no database")
+}
+
+func (failingR11nQueueStore) Done(R11nID, DiffResolution) error {
+	return errors.New("no database")
+}
+
+func (failingR11nQueueStore) Pending() ([]StoredR11n, error) {
+	return nil, errors.New("no database")
+}
+
+func (failingR11nQueueStore) ByID(R11nID) (StoredR11n, bool, error) {
+	return StoredR11n{}, false, errors.New("no database")
+}
+
+func TestR11nQueueSet_storeErrors(t *testing.T) {
+	ls, lc := logging.NewLogSinkSpy()
+	qs := NewR11nQueueSet(R11nQueueStorage(failingR11nQueueStore{}, ls), R11nQueueStartWithHandler(
+		func(qr *QueuedR11n) DiffResolution {
+			return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: CreateDiff}
+		}))
+
+	// The r11n is done in spite of the store, but its failures are counted.
+	queued, ok := qs.Push(makeTestR11nWithRepo("one"))
+	if !ok {
+		t.Fatal("push failed")
+	}
+	did := queued.Rectification.Pair.ID()
+	if rez, ok := qs.Wait(did, queued.ID); !ok || rez.Desc != CreateDiff {
+		t.Errorf("Wait got %q, %t; want %q, true", rez.Desc, ok, CreateDiff)
+	}
+	if _, ok := qs.ByID(did, NewR11nID()); ok {
+		t.Errorf("unknown r11n found")
+	}
+
+	counted := 0
+	for _, call := range lc.Metrics.CallsTo("IncCounter") {
+		if call.PassedArgs().String(0) == "r11n-store-errors" {
+			counted++
+		}
+	}
+	if counted != 3 {
+		t.Errorf("got %d store errors counted; want 3: queued, done and looked up", counted)
+	}
+}
+
+func mustByID(t *testing.T, qs *R11nQueueSet, did DeploymentID, id R11nID) *QueuedR11n {
+	t.Helper()
+	qr, ok := qs.ByID(did, id)
+	if !ok {
+		t.Fatalf("r11n %s not found for %q", id, did)
+	}
+	return qr
+}
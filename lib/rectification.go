@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -18,13 +19,27 @@ type Rectification struct {
 	sync.RWMutex
 	Resolution DiffResolution
 
-	log    logging.LogSink
-	uuid   uuid.UUID
-	once   sync.Once
-	ctx    context.Context
-	cancel func()
+	// stages is the rollout plan for a staged rectification, see PlanRollout.
+	stages []RolloutStage
+	// prior is the deployment a failed staged rollout reverts to.
+	prior *Deployment
+
+	log       logging.LogSink
+	uuid      uuid.UUID
+	once      sync.Once
+	ctx       context.Context
+	cancel    func()
+	abort     chan struct{}
+	abortInit sync.Once
+	abortOnce sync.Once
 }
 
+// RolloutUser is recorded as the user responsible for GDM changes made when a
+// staged rollout reverts.
+var RolloutUser = User{Name: "sous-rollout"}
+
+var errRolloutAborted = errors.New("rollout aborted")
+
 // NewRectification is used to rectify differences on a single Deployment.
 // After this its useful life is over.
 func NewRectification(dp DeployablePair, l logging.LogSink) *Rectification {
@@ -46,56 +61,153 @@ func (r *Rectification) EachField(fn logging.FieldReportFn) {
 	r.Resolution.EachField(fn)
 }
 
+// PlanRollout stages this rectification according to the Rollout of its Post
+// deployment, provided it replaces the version deployed by prior. Should the
+// staged rollout fail or be aborted, prior is deployed again and restored in
+// the GDM. PlanRollout must be called before Begin.
+func (r *Rectification) PlanRollout(prior *Deployment) {
+	post := r.Pair.Post
+	if prior == nil || post == nil || post.Deployment == nil {
+		return
+	}
+	if prior.SourceID.Equal(post.SourceID) {
+		return
+	}
+	switch post.Kind {
+	case ManifestKindScheduled, ScheduledJob, ManifestKindOnce, ManifestKindOnDemand:
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.stages = post.Rollout.Stages(post.NumInstances)
+	if r.stages != nil {
+		r.prior = prior.Clone()
+	}
+}
+
+// Stages returns a snapshot of the rollout stages of this rectification. It
+// returns nil unless the rectification is staged.
+func (r *Rectification) Stages() []RolloutStage {
+	r.RLock()
+	defer r.RUnlock()
+	if r.stages == nil {
+		return nil
+	}
+	return append([]RolloutStage{}, r.stages...)
+}
+
+// Abort stops a staged rollout, which then reverts to the previous
+// deployment. It returns false if there is no staged rollout in progress.
+func (r *Rectification) Abort() bool {
+	inProgress := false
+	for _, s := range r.Stages() {
+		if s.Name != RevertStageName && (s.Status == StagePending || s.Status == StageRunning) {
+			inProgress = true
+		}
+	}
+	if !inProgress {
+		return false
+	}
+	r.abortOnce.Do(func() { close(r.aborting()) })
+	return true
+}
+
+// aborting returns a channel that is closed when the rollout is aborted.
+func (r *Rectification) aborting() chan struct{} {
+	r.abortInit.Do(func() { r.abort = make(chan struct{}) })
+	return r.abort
+}
+
+func (r *Rectification) aborted() bool {
+	select {
+	default:
+		return false
+	case <-r.aborting():
+		return true
+	}
+}
+
+func (r *Rectification) setStage(i int, status RolloutStageStatus, msg string) RolloutStage {
+	r.Lock()
+	defer r.Unlock()
+	r.stages[i].Status = status
+	r.stages[i].Message = msg
+	return r.stages[i]
+}
+
+func (r *Rectification) report(level logging.Level, msg string) {
+	logging.Deliver(r.log,
+		logging.SousGenericV1,
+		logging.GetCallerInfo(logging.NotHere()),
+		level,
+		logging.ConsoleAndMessage(msg),
+		r.Pair,
+	)
+}
+
 // Begin begins applying sr.Pair using d Deployer. Call Result to get the
 // result. Begin can be called multiple times but performs its function only
 // once.
-func (r *Rectification) Begin(d Deployer, reg Registry, rf *ResolveFilter, stateReader StateReader) {
+func (r *Rectification) Begin(d Deployer, reg Registry, rf *ResolveFilter, sm StateManager) {
 	r.once.Do(func() {
-		go r.enact(d, reg, rf, stateReader)
+		go r.enact(d, reg, rf, sm)
 	})
 }
 
-func (r *Rectification) enact(d Deployer, reg Registry, rf *ResolveFilter, stateReader StateReader) {
+func (r *Rectification) enact(d Deployer, reg Registry, rf *ResolveFilter, sm StateManager) {
 	defer r.cancel()
+	if len(r.Stages()) != 0 {
+		r.enactStages(d, reg, rf, sm)
+		return
+	}
 	r.rectify(d, reg)
 	if r.Resolution.Error != nil {
-		logging.Deliver(r.log,
-			logging.SousGenericV1,
-			logging.GetCallerInfo(logging.NotHere()),
-			logging.WarningLevel,
-			logging.ConsoleAndMessage(fmt.Sprintf("Rectification failed: %s", r.Resolution.Error)),
-			r.Pair,
-		)
+		r.report(logging.WarningLevel, fmt.Sprintf("Rectification failed: %s", r.Resolution.Error))
 		return
 	}
-	r.awaitDone(d, reg, rf, stateReader)
+	r.awaitDone(d, reg, rf, sm)
+}
+
+// resolveArtifact returns a copy of pair with the BuildArtifact of its Post
+// deployable resolved, unless it already has one.
+func (r *Rectification) resolveArtifact(reg Registry, pair *DeployablePair) (*DeployablePair, error) {
+	if pair.Post.BuildArtifact != nil {
+		return pair, nil
+	}
+	resolved, diff := HandlePairsByRegistry(reg, pair, r.log)
+	if diff != nil && diff.Error != nil {
+		return nil, diff.Error
+	}
+	if resolved == nil {
+		return nil, fmt.Errorf("Unknown Error Occurred, no resolve error and no pair present")
+	}
+	return resolved, nil
 }
 
 func (r *Rectification) rectify(d Deployer, reg Registry) {
-	if r.Pair.Post.BuildArtifact == nil {
-		pair, diff := HandlePairsByRegistry(reg, &r.Pair, r.log)
-		if diff != nil && diff.Error != nil {
-			r.Lock()
-			r.Resolution.Error = WrapResolveError(diff.Error)
-			r.Unlock()
-			return
-		}
-		if pair != nil {
-			r.Pair = *pair
-		} else {
-			r.Lock()
-			r.Resolution.Error = WrapResolveError(fmt.Errorf("Unknown Error Occurred, no resolve error and no pair present"))
-			r.Unlock()
-			return
-		}
+	pair, err := r.resolveArtifact(reg, &r.Pair)
+	if err != nil {
+		r.Lock()
+		r.Resolution.Error = WrapResolveError(err)
+		r.Unlock()
+		return
 	}
+	r.Pair = *pair
 	r.Lock()
 	r.Resolution = d.Rectify(&r.Pair)
 	r.Unlock()
 }
 
-func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, stateReader StateReader) {
+func (r *Rectification) clusters(rf *ResolveFilter, stateReader StateReader) (Clusters, error) {
 	state, err := stateReader.ReadState()
+	if err != nil {
+		return nil, err
+	}
+	return rf.FilteredClusters(state.Defs.Clusters), nil
+}
+
+func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, stateReader StateReader) {
+	clusters, err := r.clusters(rf, stateReader)
 	if err != nil {
 		r.Lock()
 		r.Resolution.Error = WrapResolveError(err)
@@ -103,76 +215,317 @@ func (r *Rectification) awaitDone(d Deployer, reg Registry, rf *ResolveFilter, s
 		return
 	}
 
-	clusters := state.Defs.Clusters
-	clusters = rf.FilteredClusters(clusters)
+	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
+	defer ec()
+
+	r.report(logging.ExtraDebug1Level, fmt.Sprintf("Watching pending deployments for deploy ID: %s", r.Pair.Post.Deployment.SourceID))
 
+	s, err := r.pollUntilFinal(end, d, reg, clusters, &r.Pair)
+
+	r.Lock()
+	defer r.Unlock()
+	if err != nil {
+		r.Resolution.Error = &ErrorWrapper{error: err}
+		return
+	}
+	if s == nil {
+		if r.Resolution.DeployState == nil {
+			r.Resolution.DeployState = &DeployState{}
+		}
+		return
+	}
+
+	r.Resolution.DeployState = s
+
+	//If failed to deploy, make sure to include executor message in resolution error
+	if r.Resolution.DeployState.Status != DeployStatusActive && r.Resolution.DeployState.ExecutorMessage != "" {
+		if r.Resolution.Error == nil {
+			r.Resolution.Error = &ErrorWrapper{error: fmt.Errorf("%s", r.Resolution.DeployState.ExecutorMessage)}
+		} else {
+			r.Resolution.Error = &ErrorWrapper{error: fmt.Errorf("%s:%s", r.Resolution.Error.Error(), r.Resolution.DeployState.ExecutorMessage)}
+		}
+	}
+}
+
+// pollUntilFinal polls the status of pair until it is final for the Post
+// SourceID. It returns a nil DeployState and no error if ctx is done first.
+func (r *Rectification) pollUntilFinal(ctx context.Context, d Deployer, reg Registry, clusters Clusters, pair *DeployablePair) (*DeployState, error) {
 	// TODO constants / configs
 	tick := time.NewTicker(250 * time.Millisecond)
 	defer tick.Stop()
 
-	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
-	defer ec()
-
-	logging.Deliver(r.log,
-		logging.SousGenericV1,
-		logging.GetCallerInfo(logging.NotHere()),
-		logging.ExtraDebug1Level,
-		logging.ConsoleAndMessage(fmt.Sprintf("Watching pending deployments for deploy ID: %s", r.Pair.Post.Deployment.SourceID)),
-		r.Pair,
-	)
-
 	for {
-		s, err := r.pollOnce(d, reg, clusters)
+		s, err := r.pollOnce(d, reg, clusters, pair)
 		if err != nil {
-			r.Lock()
-			r.Resolution.Error = &ErrorWrapper{error: err}
-			r.Unlock()
-			return
+			return nil, err
 		}
 		if s == nil {
-			r.Lock()
-			r.Resolution.Error = &ErrorWrapper{error: fmt.Errorf("pollOnce returned nil status")}
-			r.Unlock()
-			return
+			return nil, fmt.Errorf("pollOnce returned nil status")
 		}
-		if s.Final() && s.SourceID.Equal(r.Pair.Post.SourceID) {
-			r.Lock()
+		if s.Final() && s.SourceID.Equal(pair.Post.SourceID) {
+			return s, nil
+		}
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
 
-			r.Resolution.DeployState = s
+func (r *Rectification) pollOnce(d Deployer, reg Registry, clusters Clusters, pair *DeployablePair) (*DeployState, error) {
+	// XXX thread the context from Begin into Deployer.Status
+	depState, err := d.Status(reg, clusters, pair)
+	if err != nil {
+		return nil, err
+	}
+	return depState, nil
+}
 
-			//If failed to deploy, make sure to include executor message in resolution error
-			if r.Resolution.DeployState.Status != DeployStatusActive && r.Resolution.DeployState.ExecutorMessage != "" {
-				if r.Resolution.Error == nil {
-					r.Resolution.Error = &ErrorWrapper{error: fmt.Errorf("%s", r.Resolution.DeployState.ExecutorMessage)}
-				} else {
-					r.Resolution.Error = &ErrorWrapper{error: fmt.Errorf("%s:%s", r.Resolution.Error.Error(), r.Resolution.DeployState.ExecutorMessage)}
-				}
-			}
+// enactStages rolls r.Pair out through each of its stages in turn. If a stage
+// fails or the rollout is aborted, the remaining stages are skipped and the
+// prior deployment is restored.
+func (r *Rectification) enactStages(d Deployer, reg Registry, rf *ResolveFilter, sm StateManager) {
+	stages := r.Stages()
+	skipFrom := func(i int) {
+		for ; i < len(stages); i++ {
+			r.setStage(i, StageSkipped, "")
+		}
+	}
+
+	clusters, err := r.clusters(rf, sm)
+	if err == nil {
+		var pair *DeployablePair
+		if pair, err = r.resolveArtifact(reg, &r.Pair); err == nil {
+			r.Pair = *pair
+		}
+	}
+	if err != nil {
+		r.Lock()
+		r.Resolution.Error = WrapResolveError(err)
+		r.Unlock()
+		skipFrom(0)
+		r.report(logging.WarningLevel, fmt.Sprintf("Rectification failed: %s", err))
+		return
+	}
 
+	last := len(stages) - 1
+	for i, stage := range stages {
+		rez, err := r.enactStage(i, i == last, d, reg, clusters)
+		if err == nil {
+			r.setStage(i, StageSucceeded, "")
+			r.Lock()
+			r.Resolution = rez
 			r.Unlock()
-			return
+			if i == last {
+				r.retireCanary(d)
+			}
+			continue
+		}
+		status := StageFailed
+		if err == errRolloutAborted {
+			status = StageAborted
 		}
+		r.setStage(i, status, err.Error())
+		skipFrom(i + 1)
+		rez.Error = WrapResolveError(fmt.Errorf("rollout stage %q %s: %s", stage.Name, status, err))
+		r.Lock()
+		r.Resolution = rez
+		r.Unlock()
+		r.report(logging.WarningLevel, fmt.Sprintf("Rectification failed: %s", rez.Error))
+		r.revert(d, reg, clusters, sm, i == last)
+		return
+	}
+}
+
+// enactStage deploys the new version at the size of stage i, and waits for it
+// to become healthy. The stages before the last run in the canary deployment,
+// alongside the deployment being rolled out, which keeps all its instances of
+// the prior version until the last stage replaces them.
+func (r *Rectification) enactStage(i int, last bool, d Deployer, reg Registry, clusters Clusters) (DiffResolution, error) {
+	rez := DiffResolution{DeploymentID: r.Pair.ID()}
+	if r.aborted() {
+		return rez, errRolloutAborted
+	}
+	stage := r.setStage(i, StageRunning, "")
+
+	pair := r.Pair
+	if !last {
+		pair = *r.canaryPair(stage.NumInstances)
+	}
+	post := *pair.Post
+
+	r.report(logging.InformationLevel, fmt.Sprintf("Rollout stage %q: deploying %d instances of %s to %s", stage.Name, stage.NumInstances, post.SourceID, pair.ID()))
+
+	rez = d.Rectify(&pair)
+	if rez.Error != nil {
+		return rez, rez.Error
+	}
+
+	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
+	defer ec()
+	go func() {
 		select {
-		case <-tick.C:
+		case <-r.aborting():
+			ec()
 		case <-end.Done():
-			r.Lock()
-			defer r.Unlock()
-			if r.Resolution.DeployState == nil {
-				r.Resolution.DeployState = &DeployState{}
-			}
-			return
 		}
+	}()
+
+	s, err := r.pollUntilFinal(end, d, reg, clusters, &pair)
+	if err != nil {
+		return rez, err
+	}
+	if s == nil {
+		if r.aborted() {
+			return rez, errRolloutAborted
+		}
+		return rez, fmt.Errorf("timed out waiting for %s", post.SourceID)
+	}
+	rez.DeployState = s
+	if s.Status != DeployStatusActive {
+		return rez, fmt.Errorf("deployment is %s: %s", s.Status, s.ExecutorMessage)
 	}
 
+	delay := post.Rollout.StageDelay
+	if delay == 0 {
+		return rez, nil
+	}
+	select {
+	case <-time.After(time.Duration(delay) * time.Second):
+	case <-r.aborting():
+		return rez, errRolloutAborted
+	}
+	// Check the stage is still healthy after watching it for a while.
+	s, err = r.pollOnce(d, reg, clusters, &pair)
+	if err != nil {
+		return rez, err
+	}
+	if s == nil || s.Status != DeployStatusActive {
+		return rez, fmt.Errorf("deployment became unhealthy within %ds", delay)
+	}
+	rez.DeployState = s
+	return rez, nil
 }
 
-func (r *Rectification) pollOnce(d Deployer, reg Registry, clusters Clusters) (*DeployState, error) {
-	// XXX thread the context from Begin into Deployer.Status
-	depState, err := d.Status(reg, clusters, &r.Pair)
+// canaryPair returns the pair which deploys numInstances instances of the new
+// version of r.Pair to its canary deployment, whose flavor is CanaryFlavor of
+// its own.
+func (r *Rectification) canaryPair(numInstances int) *DeployablePair {
+	post := *r.Pair.Post
+	post.Deployment = r.Pair.Post.Deployment.Clone()
+	post.Flavor = CanaryFlavor(post.Flavor)
+	if post.SingularityRequestID != "" {
+		post.SingularityRequestID += "-" + canarySuffix
+	}
+	post.NumInstances = numInstances
+	pair := &DeployablePair{Post: &post}
+	pair.SetID(post.ID())
+	return pair
+}
+
+// retireCanary removes the canary deployment of r, so that later resolutions
+// do not find it running without being intended. If d cannot remove
+// deployments, the canary is scaled down to no instances instead.
+func (r *Rectification) retireCanary(d Deployer) {
+	canary := r.canaryPair(0)
+	if dr, ok := d.(DeploymentRemover); ok {
+		if err := dr.RemoveDeployment(canary.Post.Deployment); err != nil {
+			r.report(logging.WarningLevel, fmt.Sprintf("Removing canary of %s failed: %s", r.Pair.ID(), err))
+		}
+		return
+	}
+	if rez := d.Rectify(canary); rez.Error != nil {
+		r.report(logging.WarningLevel, fmt.Sprintf("Retiring canary of %s failed: %s", r.Pair.ID(), rez.Error))
+	}
+}
+
+// revert retires the canary after a failed rollout, redeploys r.prior if the
+// last stage had begun to replace it, and restores r.prior in the GDM so that
+// later resolutions do not redeploy the failed version.
+func (r *Rectification) revert(d Deployer, reg Registry, clusters Clusters, sm StateManager, replaced bool) {
+	if r.prior == nil {
+		return
+	}
+	r.Lock()
+	r.stages = append(r.stages, RolloutStage{
+		Name:         RevertStageName,
+		NumInstances: r.prior.NumInstances,
+		Status:       StageRunning,
+	})
+	i := len(r.stages) - 1
+	r.Unlock()
+
+	r.report(logging.WarningLevel, fmt.Sprintf("Reverting to %s", r.prior.SourceID))
+
+	r.retireCanary(d)
+	var err error
+	if replaced {
+		err = r.deployPrior(d, reg, clusters)
+	}
+	if err == nil {
+		err = r.restoreGDM(sm)
+	}
+
+	r.Lock()
+	defer r.Unlock()
 	if err != nil {
-		return nil, err
+		r.stages[i].Status = StageFailed
+		r.stages[i].Message = err.Error()
+		r.Resolution.Error = WrapResolveError(fmt.Errorf("%s; reverting to %s failed: %s", r.Resolution.Error, r.prior.SourceID, err))
+		return
 	}
-	return depState, nil
+	r.stages[i].Status = StageSucceeded
+	r.Resolution.Error = WrapResolveError(fmt.Errorf("%s; reverted to %s", r.Resolution.Error, r.prior.SourceID))
+}
+
+func (r *Rectification) deployPrior(d Deployer, reg Registry, clusters Clusters) error {
+	pair := &DeployablePair{Post: &Deployable{Deployment: r.prior.Clone()}}
+	pair.SetID(r.Pair.ID())
+	pair, err := r.resolveArtifact(reg, pair)
+	if err != nil {
+		return err
+	}
+	if rez := d.Rectify(pair); rez.Error != nil {
+		return rez.Error
+	}
+
+	end, ec := context.WithTimeout(r.ctx, 20*time.Minute)
+	defer ec()
+	s, err := r.pollUntilFinal(end, d, reg, clusters, pair)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return fmt.Errorf("timed out waiting for %s", r.prior.SourceID)
+	}
+	if s.Status != DeployStatusActive {
+		return fmt.Errorf("deployment is %s: %s", s.Status, s.ExecutorMessage)
+	}
+	return nil
+}
+
+// restoreGDM puts r.prior back in the GDM, unless the deployment has been
+// changed to some other version since this rollout began.
+func (r *Rectification) restoreGDM(sm StateManager) error {
+	state, err := sm.ReadState()
+	if err != nil {
+		return err
+	}
+	did := r.Pair.ID()
+	m, ok := state.Manifests.Get(did.ManifestID)
+	if !ok {
+		return fmt.Errorf("no manifest %q in GDM", did.ManifestID)
+	}
+	spec, ok := state.Defs.ClusterSpec(m, did.Cluster)
+	if !ok || !spec.Version.Equals(r.Pair.Post.SourceID.Version) {
+		r.report(logging.WarningLevel, fmt.Sprintf("Not restoring %s in GDM: deployment changed since rollout began", did))
+		return nil
+	}
+	if err := state.UpdateDeployments(r.log, r.prior.Clone()); err != nil {
+		return err
+	}
+	return sm.WriteState(state, RolloutUser)
 }
 
 // Wait must be called after Begin. It waits for and returns the result.
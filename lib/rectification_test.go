@@ -8,6 +8,9 @@ import (
 
 	"github.com/nyarly/spies"
 	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestSingleRectification_Resolve_completes(t *testing.T) {
@@ -94,3 +97,203 @@ func TestRectification_enact_failsImmediatelyWhenRectifyFails(t *testing.T) {
 		t.Errorf("got error %q; want suffix %q", got, wantSuffix)
 	}
 }
+
+func stagedRectificationFixture(t *testing.T) (*Rectification, *Deployment, *DummyStateManager) {
+	sm := NewDummyStateManager()
+	sm.State.Defs.Clusters = Clusters{"c1": &Cluster{Name: "c1", BaseURL: "http://c1.example.com"}}
+	m := &Manifest{
+		Source: SourceLocation{Repo: "github.com/example/canary"},
+		Kind:   ManifestKindService,
+		Deployments: DeploySpecs{
+			"c1": DeploySpec{
+				Version: semv.MustParse("2.0.0"),
+				DeployConfig: DeployConfig{
+					NumInstances: 4,
+					Rollout:      Rollout{Strategy: RolloutCanary, Steps: []int{50}},
+				},
+			},
+		},
+	}
+	sm.State.Manifests.Add(m)
+	deps, err := sm.State.Deployments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	did := DeploymentID{ManifestID: m.ID(), Cluster: "c1"}
+	post, ok := deps.Get(did)
+	if !ok {
+		t.Fatalf("no deployment %q", did)
+	}
+	prior := post.Clone()
+	prior.SourceID.Version = semv.MustParse("1.0.0")
+
+	log, _ := logging.NewLogSinkSpy()
+	r := NewRectification(DeployablePair{Post: &Deployable{
+		Deployment:    post,
+		BuildArtifact: &BuildArtifact{DigestReference: "example/canary:2.0.0"},
+	}}, log)
+	r.Pair.SetID(did)
+	r.PlanRollout(prior)
+	return r, prior, sm
+}
+
+func stageStatuses(r *Rectification) []RolloutStageStatus {
+	var statuses []RolloutStageStatus
+	for _, s := range r.Stages() {
+		statuses = append(statuses, s.Status)
+	}
+	return statuses
+}
+
+func TestRectification_staged_succeeds(t *testing.T) {
+	r, _, sm := stagedRectificationFixture(t)
+
+	dpr, c := NewDeployerSpy()
+	c.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+	c.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Status:     DeployStatusActive,
+		Deployment: *r.Pair.Post.Deployment,
+	}, nil)
+
+	r.enact(dpr, &DummyRegistry{}, &ResolveFilter{}, sm)
+
+	if r.Resolution.Error != nil {
+		t.Fatalf("unexpected error: %s", r.Resolution.Error)
+	}
+	var sizes []int
+	var flavors []string
+	for _, call := range c.CallsTo("Rectify") {
+		pair := call.PassedArgs().Get(0).(*DeployablePair)
+		sizes = append(sizes, pair.Post.NumInstances)
+		flavors = append(flavors, pair.ID().ManifestID.Flavor)
+	}
+	// The canary and step stages run alongside the prior version, which the
+	// full stage replaces; the canary is then retired.
+	assert.Equal(t, []int{1, 2, 4, 0}, sizes)
+	assert.Equal(t, []string{"canary", "canary", "", "canary"}, flavors)
+	assert.Equal(t, []RolloutStageStatus{StageSucceeded, StageSucceeded, StageSucceeded}, stageStatuses(r))
+	assert.Equal(t, 0, sm.WriteCount)
+}
+
+func TestRectification_staged_removesCanary(t *testing.T) {
+	r, _, sm := stagedRectificationFixture(t)
+
+	spy, c := NewDeployerSpy()
+	c.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+	c.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Status:     DeployStatusActive,
+		Deployment: *r.Pair.Post.Deployment,
+	}, nil)
+	dpr := &removingDeployerSpy{Deployer: spy}
+
+	r.enact(dpr, &DummyRegistry{}, &ResolveFilter{}, sm)
+
+	if r.Resolution.Error != nil {
+		t.Fatalf("unexpected error: %s", r.Resolution.Error)
+	}
+	// The canary is removed rather than scaled down.
+	assert.Len(t, c.CallsTo("Rectify"), 3)
+	if assert.Len(t, dpr.removed, 1) {
+		assert.Equal(t, "canary", dpr.removed[0].Flavor)
+		assert.Equal(t, "c1", dpr.removed[0].ClusterName)
+	}
+}
+
+func TestRectification_staged_revertsFailedCanary(t *testing.T) {
+	r, prior, sm := stagedRectificationFixture(t)
+
+	dpr, c := NewDeployerSpy()
+	c.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+	c.MatchMethod("Status", func(args mock.Arguments) bool {
+		return args.Get(2).(*DeployablePair).Post.SourceID.Equal(prior.SourceID)
+	}, &DeployState{Status: DeployStatusActive, Deployment: *prior}, nil)
+	c.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Status:          DeployStatusFailed,
+		ExecutorMessage: "crashed",
+		Deployment:      *r.Pair.Post.Deployment,
+	}, nil)
+
+	r.enact(dpr, &DummyRegistry{}, &ResolveFilter{}, sm)
+
+	if r.Resolution.Error == nil {
+		t.Fatalf("got nil error")
+	}
+	assert.Contains(t, r.Resolution.Error.Error(), "crashed")
+	assert.Contains(t, r.Resolution.Error.Error(), "reverted to")
+	assert.Equal(t,
+		[]RolloutStageStatus{StageFailed, StageSkipped, StageSkipped, StageSucceeded},
+		stageStatuses(r))
+
+	m, _ := sm.State.Manifests.Get(r.Pair.ID().ManifestID)
+	assert.Equal(t, "1.0.0", m.Deployments["c1"].Version.String())
+	assert.Equal(t, 1, sm.WriteCount)
+
+	// Only the canary was deployed, so only it needs undoing.
+	var sizes []int
+	for _, call := range c.CallsTo("Rectify") {
+		pair := call.PassedArgs().Get(0).(*DeployablePair)
+		assert.Equal(t, "canary", pair.ID().ManifestID.Flavor)
+		sizes = append(sizes, pair.Post.NumInstances)
+	}
+	assert.Equal(t, []int{1, 0}, sizes)
+}
+
+func TestRectification_staged_revertsFailedFull(t *testing.T) {
+	r, prior, sm := stagedRectificationFixture(t)
+
+	dpr, c := NewDeployerSpy()
+	c.MatchMethod("Rectify", spies.AnyArgs, DiffResolution{Desc: ModifyDiff})
+	c.MatchMethod("Status", func(args mock.Arguments) bool {
+		pair := args.Get(2).(*DeployablePair)
+		return pair.ID() == r.Pair.ID() && !pair.Post.SourceID.Equal(prior.SourceID)
+	}, &DeployState{
+		Status:          DeployStatusFailed,
+		ExecutorMessage: "crashed",
+		Deployment:      *r.Pair.Post.Deployment,
+	}, nil)
+	c.MatchMethod("Status", func(args mock.Arguments) bool {
+		return args.Get(2).(*DeployablePair).Post.SourceID.Equal(prior.SourceID)
+	}, &DeployState{Status: DeployStatusActive, Deployment: *prior}, nil)
+	c.MatchMethod("Status", spies.AnyArgs, &DeployState{
+		Status:     DeployStatusActive,
+		Deployment: *r.Pair.Post.Deployment,
+	}, nil)
+
+	r.enact(dpr, &DummyRegistry{}, &ResolveFilter{}, sm)
+
+	if r.Resolution.Error == nil {
+		t.Fatalf("got nil error")
+	}
+	assert.Contains(t, r.Resolution.Error.Error(), "reverted to")
+	assert.Equal(t,
+		[]RolloutStageStatus{StageSucceeded, StageSucceeded, StageFailed, StageSucceeded},
+		stageStatuses(r))
+
+	var deployed []string
+	for _, call := range c.CallsTo("Rectify") {
+		pair := call.PassedArgs().Get(0).(*DeployablePair)
+		deployed = append(deployed, fmt.Sprintf("%s %s*%d", pair.ID(), pair.Post.SourceID.Version, pair.Post.NumInstances))
+	}
+	assert.Equal(t, []string{
+		"c1:github.com/example/canary~canary 2.0.0*1",
+		"c1:github.com/example/canary~canary 2.0.0*2",
+		"c1:github.com/example/canary 2.0.0*4",
+		"c1:github.com/example/canary~canary 2.0.0*0",
+		"c1:github.com/example/canary 1.0.0*4",
+	}, deployed)
+}
+
+func TestRectification_Abort(t *testing.T) {
+	r, _, _ := stagedRectificationFixture(t)
+	if !r.Abort() {
+		t.Fatalf("Abort returned false for a pending rollout")
+	}
+	if !r.aborted() {
+		t.Errorf("rollout not aborted")
+	}
+
+	unstaged := NewRectification(DeployablePair{Post: &Deployable{Deployment: &Deployment{}}}, nil)
+	if unstaged.Abort() {
+		t.Errorf("Abort returned true for an unstaged rectification")
+	}
+}
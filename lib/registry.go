@@ -38,6 +38,13 @@ type (
 		Insert(sid SourceID, ba BuildArtifact) error
 	}
 
+	// A Replacer replaces the artifact already recorded for a SourceID.
+	Replacer interface {
+		// Replace pairs a SourceID with a build artifact, in place of the
+		// one it was paired with.
+		Replace(sid SourceID, ba BuildArtifact) error
+	}
+
 	// An InserterSpy is a spy implementation of the Inserter interface
 	InserterSpy struct {
 		*spies.Spy
@@ -63,6 +70,11 @@ func (is InserterSpy) Insert(sid SourceID, ba BuildArtifact) error {
 	return is.Called(sid, ba).Error(0)
 }
 
+// Replace implements Replacer for InserterSpy.
+func (is InserterSpy) Replace(sid SourceID, ba BuildArtifact) error {
+	return is.Called(sid, ba).Error(0)
+}
+
 // NewRegistrySpy returns a spy Registry for testing.
 func NewRegistrySpy() (RegistrySpy, *spies.Spy) {
 	spy := spies.NewSpy()
@@ -0,0 +1,175 @@
+package sous
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/shell"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A SourceBuilder builds and registers the source code at a SourceID,
+	// without needing a local checkout of it, on behalf of a User.
+	SourceBuilder interface {
+		BuildSource(SourceID, User) (*BuildResult, error)
+	}
+
+	// RemoteBuilder is a SourceBuilder which gets source code from the
+	// SourceHost that owns it, and builds it as a BuildManager builds a
+	// workspace.
+	RemoteBuilder struct {
+		SourceHosts SourceHostChooser
+		Selector
+		Labeller
+		Registrar
+		// Strict, Dev and Platforms are as in BuildConfig.
+		Strict, Dev bool
+		Platforms   []string
+		LogSink     logging.LogSink
+
+		// building serialises builds, since Labellers and Registrars may share
+		// scratch space between them.
+		building sync.Mutex
+	}
+
+	// BuildJobs runs the builds of a SourceBuilder in the background, so that
+	// whoever asks for a build need not wait for it, and reports on each by
+	// its ID.
+	BuildJobs struct {
+		Builder SourceBuilder
+
+		sync.Mutex
+		jobs map[string]*BuildJob
+		// finished holds the IDs of finished jobs, oldest first.
+		finished []string
+	}
+
+	// A BuildJob reports on a build run by BuildJobs.
+	BuildJob struct {
+		ID       string
+		SourceID SourceID
+		User     User
+		Status   BuildJobStatus
+		Started  time.Time
+		Finished time.Time
+		// Result is the result of a build which succeeded.
+		Result *BuildResult `json:",omitempty"`
+		// Error is the reason a build failed.
+		Error string `json:",omitempty"`
+	}
+
+	// BuildJobStatus is the status of a BuildJob.
+	BuildJobStatus string
+)
+
+const (
+	// BuildJobRunning is the status of a build which has not finished.
+	BuildJobRunning BuildJobStatus = "running"
+	// BuildJobSucceeded is the status of a build which was registered.
+	BuildJobSucceeded BuildJobStatus = "succeeded"
+	// BuildJobFailed is the status of a build which failed.
+	BuildJobFailed BuildJobStatus = "failed"
+)
+
+// maxFinishedBuildJobs is the number of finished jobs BuildJobs remembers.
+const maxFinishedBuildJobs = 100
+
+// BuildSource implements SourceBuilder on RemoteBuilder. The source is cloned
+// into a scratch directory, which is removed once the build is done.
+func (rb *RemoteBuilder) BuildSource(id SourceID, user User) (*BuildResult, error) {
+	rb.building.Lock()
+	defer rb.building.Unlock()
+
+	src, err := rb.SourceHosts.GetSource(id)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(src.LocalRootDir)
+
+	sh, err := shell.DefaultInDir(src.LocalRootDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "building %s", id)
+	}
+	sh.LongRunning(true)
+
+	m := &BuildManager{
+		BuildConfig: &BuildConfig{
+			Repo:      id.Location.Repo,
+			Offset:    id.Location.Dir,
+			Tag:       src.Context.NearestTagName,
+			Revision:  src.Context.Revision,
+			Strict:    rb.Strict,
+			Dev:       rb.Dev,
+			Platforms: rb.Platforms,
+			User:      user,
+			Context:   &BuildContext{Sh: sh, Source: src.Context},
+			LogSink:   rb.LogSink,
+		},
+		Selector:  rb.Selector,
+		Labeller:  rb.Labeller,
+		Registrar: rb.Registrar,
+		LogSink:   rb.LogSink,
+	}
+	return m.Build()
+}
+
+// NewBuildJobs returns a BuildJobs running builds with b.
+func NewBuildJobs(b SourceBuilder) *BuildJobs {
+	return &BuildJobs{Builder: b, jobs: map[string]*BuildJob{}}
+}
+
+// Start starts building id on behalf of user, and returns the job doing it.
+// If id is already being built, that job is returned instead.
+func (bj *BuildJobs) Start(id SourceID, user User) BuildJob {
+	bj.Lock()
+	defer bj.Unlock()
+	for _, job := range bj.jobs {
+		if job.Status == BuildJobRunning && job.SourceID.Equal(id) {
+			return *job
+		}
+	}
+	job := &BuildJob{
+		ID:       uuid.New(),
+		SourceID: id,
+		User:     user,
+		Status:   BuildJobRunning,
+		Started:  time.Now(),
+	}
+	bj.jobs[job.ID] = job
+	go bj.run(job.ID, id, user)
+	return *job
+}
+
+func (bj *BuildJobs) run(jobID string, id SourceID, user User) {
+	result, err := bj.Builder.BuildSource(id, user)
+
+	bj.Lock()
+	defer bj.Unlock()
+	job := bj.jobs[jobID]
+	job.Finished = time.Now()
+	if err != nil {
+		job.Status, job.Error = BuildJobFailed, err.Error()
+	} else {
+		job.Status, job.Result = BuildJobSucceeded, result
+	}
+	bj.finished = append(bj.finished, jobID)
+	if len(bj.finished) > maxFinishedBuildJobs {
+		delete(bj.jobs, bj.finished[0])
+		bj.finished = bj.finished[1:]
+	}
+}
+
+// Job returns the job with ID id, and false if there is none.
+func (bj *BuildJobs) Job(id string) (BuildJob, bool) {
+	bj.Lock()
+	defer bj.Unlock()
+	job, has := bj.jobs[id]
+	if !has {
+		return BuildJob{}, false
+	}
+	return *job, true
+}
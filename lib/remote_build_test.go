@@ -0,0 +1,142 @@
+package sous
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cloningSourceHost gets source by making an empty scratch directory.
+type cloningSourceHost struct {
+	GenericHost
+	cloned []string
+}
+
+func (h *cloningSourceHost) GetSource(id SourceID) (Source, error) {
+	root, err := ioutil.TempDir("", "sous-remote-build")
+	if err != nil {
+		return Source{}, err
+	}
+	h.cloned = append(h.cloned, root)
+	return Source{
+		ID: id,
+		Context: SourceContext{
+			RootDir:            root,
+			OffsetDir:          id.Location.Dir,
+			PrimaryRemoteURL:   id.Location.Repo,
+			RemoteURLs:         []string{id.Location.Repo},
+			Revision:           "cabbage",
+			NearestTagName:     id.Version.String(),
+			NearestTagRevision: "cabbage",
+			NearestTag:         Tag{Name: id.Version.String(), Revision: "cabbage"},
+			Tags:               []Tag{{Name: id.Version.String(), Revision: "cabbage"}},
+		},
+		LocalRootDir:   root,
+		LocalOffsetDir: filepath.Join(root, id.Location.Dir),
+	}, nil
+}
+
+type recordingBuildpack struct {
+	built      *BuildContext
+	registered *BuildResult
+}
+
+func (bp *recordingBuildpack) SelectBuildpack(*BuildContext) (Buildpack, error) { return bp, nil }
+
+func (bp *recordingBuildpack) Detect(*BuildContext) (*DetectResult, error) {
+	return &DetectResult{Compatible: true}, nil
+}
+
+func (bp *recordingBuildpack) Build(ctx *BuildContext) (*BuildResult, error) {
+	bp.built = ctx
+	return &BuildResult{Products: []*BuildProduct{{Kind: "test"}}}, nil
+}
+
+func (bp *recordingBuildpack) ApplyMetadata(*BuildResult) error { return nil }
+
+func (bp *recordingBuildpack) Register(br *BuildResult) error {
+	bp.registered = br
+	return nil
+}
+
+func TestRemoteBuilder_BuildSource(t *testing.T) {
+	host := &cloningSourceHost{}
+	bp := &recordingBuildpack{}
+	rb := &RemoteBuilder{
+		SourceHosts: SourceHostChooser{SourceHosts: []SourceHost{host}},
+		Selector:    bp,
+		Labeller:    bp,
+		Registrar:   bp,
+		LogSink:     logging.SilentLogSet(),
+	}
+
+	id := MustParseSourceID("gitlab.example.com/group/project,1.2.3,svc")
+	result, err := rb.BuildSource(id, User{Name: "Test User"})
+	require.NoError(t, err)
+
+	require.Len(t, host.cloned, 1)
+	require.NotNil(t, bp.built)
+	assert.Equal(t, host.cloned[0], bp.built.Source.RootDir)
+	assert.Equal(t, host.cloned[0], bp.built.Sh.Dir())
+	assert.Equal(t, "svc", bp.built.Source.OffsetDir)
+	assert.Equal(t, "gitlab.example.com/group/project", bp.built.Source.RemoteURL)
+	assert.Equal(t, "1.2.3", bp.built.Source.NearestTagName)
+	assert.Empty(t, bp.built.Advisories)
+
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, id.Location, result.Products[0].Source.Location)
+	require.NotNil(t, result.Products[0].Provenance)
+	assert.Equal(t, "Test User", result.Products[0].Provenance.User)
+	assert.Equal(t, result, bp.registered)
+
+	_, err = os.Stat(host.cloned[0])
+	assert.True(t, os.IsNotExist(err), "the clone was not removed")
+}
+
+func TestRemoteBuilder_BuildSource_unowned(t *testing.T) {
+	rb := &RemoteBuilder{LogSink: logging.SilentLogSet()}
+	_, err := rb.BuildSource(MustParseSourceID("gitlab.example.com/group/project,1.2.3"), User{})
+	assert.Error(t, err)
+}
+
+// blockingSourceBuilder builds once release is closed.
+type blockingSourceBuilder struct {
+	release chan struct{}
+	builds  int
+}
+
+func (b *blockingSourceBuilder) BuildSource(id SourceID, _ User) (*BuildResult, error) {
+	<-b.release
+	b.builds++
+	return &BuildResult{Products: []*BuildProduct{{Source: id}}}, nil
+}
+
+func TestBuildJobs(t *testing.T) {
+	b := &blockingSourceBuilder{release: make(chan struct{})}
+	bj := NewBuildJobs(b)
+	id := MustParseSourceID("gitlab.example.com/group/project,1.2.3")
+
+	job := bj.Start(id, User{Name: "Test User"})
+	assert.Equal(t, BuildJobRunning, job.Status)
+	assert.Equal(t, job.ID, bj.Start(id, User{}).ID, "a running build is not started again")
+
+	close(b.release)
+	for job.Status == BuildJobRunning {
+		var ok bool
+		time.Sleep(time.Millisecond)
+		job, ok = bj.Job(job.ID)
+		require.True(t, ok)
+	}
+	assert.Equal(t, BuildJobSucceeded, job.Status)
+	assert.Equal(t, id, job.Result.Products[0].Source)
+	assert.Equal(t, 1, b.builds)
+
+	_, ok := bj.Job("unknown")
+	assert.False(t, ok)
+}
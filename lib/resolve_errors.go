@@ -44,6 +44,23 @@ type (
 		*SourceID
 	}
 
+	// UnacceptableVulnerabilities reports that an image has more known
+	// vulnerabilities than the target cluster allows, or has not been scanned
+	// for them.
+	UnacceptableVulnerabilities struct {
+		*SourceID
+		Cluster string
+		Err     error
+	}
+
+	// An UnverifiedArtifact reports that an image lacks a valid signature by
+	// any of the keys the target cluster trusts.
+	UnverifiedArtifact struct {
+		*SourceID
+		Cluster string
+		Err     error
+	}
+
 	// CreateError is returned when there's an error trying to create a deployment
 	CreateError struct {
 		Deployment *Deployment
@@ -164,11 +181,23 @@ func IsTransientResolveError(err error) bool {
 		// There's no expectation that it will self correct. In the future, we
 		// should do a automatic rollback.
 		return false
+	case *FreezeError:
+		// FreezeError is excluded: the freeze will end eventually, but not
+		// soon enough for a client to be kept waiting for it.
+		return false
 	case *UnacceptableAdvisory:
 		// UnacceptableAdvisory is excluded, since this requires operator
 		// intervention: either the image needs to be rebuilt clean, or the cluster
 		// reconfigured to accept the advisory.
 		return false
+	case *UnacceptableVulnerabilities:
+		// UnacceptableVulnerabilities is excluded for the same reason: the image
+		// needs to be rebuilt or rescanned, or the cluster's thresholds raised.
+		return false
+	case *UnverifiedArtifact:
+		// UnverifiedArtifact is excluded for the same reason: the image needs to
+		// be signed, or the cluster to trust the key it was signed with.
+		return false
 	case *MissingImageNameError:
 		// MissingImageNameError isn't transient: it requires that an appropriate
 		// image be built with the desired name and the server needs to be able to
@@ -200,6 +229,14 @@ func (e *UnacceptableAdvisory) Error() string {
 	return fmt.Sprintf("Advisory unacceptable on image: %s for %v", e.Quality.Name, e.SourceID)
 }
 
+func (e *UnacceptableVulnerabilities) Error() string {
+	return fmt.Sprintf("Vulnerabilities unacceptable on image for %v in %s: %v", e.SourceID, e.Cluster, e.Err)
+}
+
+func (e *UnverifiedArtifact) Error() string {
+	return fmt.Sprintf("Image for %v cannot be deployed to %s: %v", e.SourceID, e.Cluster, e.Err)
+}
+
 func (e *FailedStatusError) Error() string {
 	return "Deploy failed on Singularity."
 }
@@ -13,6 +13,8 @@ func TestIsTransientResolveError(t *testing.T) {
 
 	assert.False(IsTransientResolveError(fmt.Errorf("hi")))
 	assert.False(IsTransientResolveError(&UnacceptableAdvisory{}))
+	assert.False(IsTransientResolveError(&UnacceptableVulnerabilities{}))
+	assert.False(IsTransientResolveError(&UnverifiedArtifact{}))
 	assert.False(IsTransientResolveError(errors.Wrap(&MissingImageNameError{}, "wrapped")))
 	assert.True(IsTransientResolveError(&CreateError{}))
 	assert.True(IsTransientResolveError(errors.Wrap(&CreateError{}, "even if wrapped")))
@@ -0,0 +1,168 @@
+package sous
+
+import (
+	"sync"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/pkg/errors"
+)
+
+type (
+	// ResolveEvents collects notice of changes which may need resolving, so
+	// that an AutoResolver can resolve the deployments they affect promptly,
+	// rather than at its next full resolve. Notices which arrive while a
+	// resolve is underway are combined, and resolved together afterwards.
+	ResolveEvents struct {
+		sync.Mutex
+		deployments map[DeploymentID]struct{}
+		sources     map[string]SourceID
+		signal      chan struct{}
+	}
+
+	// EventStateManager is a StateManager that reports the deployments
+	// changed by each write to ResolveEvents.
+	EventStateManager struct {
+		StateManager
+		Events *ResolveEvents
+		log    logging.LogSink
+	}
+
+	// EventInserter is an Inserter that reports each artifact inserted to
+	// ResolveEvents, so that deployments waiting for it are resolved.
+	EventInserter struct {
+		Inserter
+		Events *ResolveEvents
+	}
+)
+
+// NewResolveEvents returns a new ResolveEvents with no pending notices.
+func NewResolveEvents() *ResolveEvents {
+	return &ResolveEvents{
+		deployments: map[DeploymentID]struct{}{},
+		sources:     map[string]SourceID{},
+		signal:      make(chan struct{}, 1),
+	}
+}
+
+// DeploymentsChanged notes that the intended or actual state of the
+// deployments with ids has changed.
+func (e *ResolveEvents) DeploymentsChanged(ids ...DeploymentID) {
+	if len(ids) == 0 {
+		return
+	}
+	e.Lock()
+	for _, id := range ids {
+		e.deployments[id] = struct{}{}
+	}
+	e.Unlock()
+	e.notify()
+}
+
+// ArtifactAdded notes that an artifact has been added for sid, so that the
+// deployments of sid may now be possible.
+func (e *ResolveEvents) ArtifactAdded(sid SourceID) {
+	e.Lock()
+	e.sources[sid.String()] = sid
+	e.Unlock()
+	e.notify()
+}
+
+func (e *ResolveEvents) notify() {
+	select {
+	case e.signal <- struct{}{}:
+	default:
+		// A signal is already pending, and the notice will be taken with it.
+	}
+}
+
+// Signal returns a channel which receives when notices are pending. It
+// returns nil if e is nil, so that receiving from it blocks forever.
+func (e *ResolveEvents) Signal() <-chan struct{} {
+	if e == nil {
+		return nil
+	}
+	return e.signal
+}
+
+// Affected takes the pending notices, and returns the IDs of the deployments
+// they affect, given the intended deployments gdm.
+func (e *ResolveEvents) Affected(gdm Deployments) []DeploymentID {
+	e.Lock()
+	deployments, sources := e.deployments, e.sources
+	e.deployments, e.sources = map[DeploymentID]struct{}{}, map[string]SourceID{}
+	e.Unlock()
+
+	if len(sources) > 0 {
+		for _, d := range gdm.Snapshot() {
+			if _, has := sources[d.SourceID.String()]; has {
+				deployments[d.ID()] = struct{}{}
+			}
+		}
+	}
+	ids := make([]DeploymentID, 0, len(deployments))
+	for id := range deployments {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// NewEventStateManager wraps sm so that writes are reported to events.
+func NewEventStateManager(sm StateManager, events *ResolveEvents, ls logging.LogSink) *EventStateManager {
+	return &EventStateManager{StateManager: sm, Events: events, log: ls}
+}
+
+// WriteState implements StateWriter on EventStateManager. If the changed
+// deployments cannot be determined, nothing is reported, and they are left
+// to the next full resolve.
+func (esm *EventStateManager) WriteState(state *State, user User) error {
+	var prior Deployments
+	current, err := esm.StateManager.ReadState()
+	if err == nil {
+		prior, err = current.Deployments()
+	}
+
+	if err := writeStateFrom(esm.StateManager, current, state, user); err != nil {
+		return err
+	}
+
+	var changed []DeploymentID
+	if err == nil {
+		changed, err = ChangedDeployments(prior, state)
+	}
+	if err != nil {
+		logging.ReportError(esm.log, errors.Wrap(err, "finding changed deployments to resolve"))
+		return nil
+	}
+	esm.Events.DeploymentsChanged(changed...)
+	return nil
+}
+
+// ChangedDeployments returns the IDs of the deployments in prior or state
+// which differ between them.
+func ChangedDeployments(prior Deployments, state *State) ([]DeploymentID, error) {
+	deps, err := state.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	var ids []DeploymentID
+	for _, pair := range prior.Diff(deps).Collect() {
+		if pair.Kind() != SameKind {
+			ids = append(ids, pair.ID())
+		}
+	}
+	return ids, nil
+}
+
+// NewEventInserter wraps i so that inserts are reported to events.
+func NewEventInserter(i Inserter, events *ResolveEvents) *EventInserter {
+	return &EventInserter{Inserter: i, Events: events}
+}
+
+// Insert implements Inserter on EventInserter.
+func (ei *EventInserter) Insert(sid SourceID, ba BuildArtifact) error {
+	if err := ei.Inserter.Insert(sid, ba); err != nil {
+		return err
+	}
+	ei.Events.ArtifactAdded(sid)
+	return nil
+}
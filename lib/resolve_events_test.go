@@ -0,0 +1,58 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStateManager_WriteState(t *testing.T) {
+	sm := NewDummyStateManager()
+	sm.State = DefaultStateFixture()
+	events := NewResolveEvents()
+	esm := NewEventStateManager(sm, events, logging.SilentLogSet())
+
+	next := sm.State.Clone()
+	mid := next.Manifests.Keys()[0]
+	m, _ := next.Manifests.Get(mid)
+	spec := m.Deployments["cluster1"]
+	spec.Version = semv.MustParse("2.0.0")
+	m.Deployments["cluster1"] = spec
+
+	require.NoError(t, esm.WriteState(next, User{}))
+	assert.Equal(t, 1, sm.WriteCount)
+
+	select {
+	case <-events.Signal():
+	default:
+		t.Fatal("write not signalled")
+	}
+	gdm, err := next.Deployments()
+	require.NoError(t, err)
+	assert.Equal(t, []DeploymentID{{ManifestID: mid, Cluster: "cluster1"}}, events.Affected(gdm))
+	assert.Len(t, events.Affected(gdm), 0, "notices should be taken once")
+}
+
+func TestResolveEvents_ArtifactAdded(t *testing.T) {
+	state := DefaultStateFixture()
+	gdm, err := state.Deployments()
+	require.NoError(t, err)
+	d := gdm.Snapshot()[DeploymentID{ManifestID: state.Manifests.Keys()[0], Cluster: "cluster2"}]
+	require.NotNil(t, d)
+
+	events := NewResolveEvents()
+	ins, ctl := NewInserterSpy()
+	ei := NewEventInserter(ins, events)
+	require.NoError(t, ei.Insert(d.SourceID, BuildArtifact{}))
+	assert.Len(t, ctl.CallsTo("Insert"), 1)
+
+	affected := events.Affected(gdm)
+	assert.Contains(t, affected, d.ID())
+	for _, id := range affected {
+		dep, _ := gdm.Get(id)
+		assert.True(t, dep.SourceID.Equal(d.SourceID), "%s does not deploy %s", id, d.SourceID)
+	}
+}
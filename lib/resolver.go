@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
@@ -18,6 +19,9 @@ type (
 		*ResolveFilter
 		ls       logging.LogSink
 		QueueSet *R11nQueueSet
+		// Rollbacks, if set, identifies automatic rollbacks, which are
+		// rectified even in frozen clusters.
+		Rollbacks *Rollbacker
 	}
 
 	// DeploymentPredicate takes a *Deployment and returns true if the
@@ -39,8 +43,12 @@ func NewResolver(d Deployer, r Registry, rf *ResolveFilter, ls logging.LogSink,
 
 // queueDiffs adds a rectification for each required change in DeployableChans,
 // as long as there is no planned or currently executing resolution for the
-// DeploymentID relating to that rectification.
+// DeploymentID relating to that rectification. Changes to deployments which
+// depend on others in the same cluster are only queued once the changes to
+// those they depend on are done, and are not queued at all if any of those
+// failed to become active.
 func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution) {
+	waits := newDependencyWaits()
 	var wg sync.WaitGroup
 	for p := range dcs.Pairs {
 		if p.Post == nil {
@@ -48,43 +56,93 @@ func (r *Resolver) queueDiffs(dcs *DeployableChans, results chan DiffResolution)
 			logging.ReportError(r.ls, err)
 			continue
 		}
-		// SameKind == "no diffs" meaning a no-op; do not add to queue.
-		if p.Kind() == SameKind {
-			messages.ReportLogFieldsMessageWithIDs("Not adding equal diff",
-				logging.ExtraDebug1Level, r.ls, p)
-			continue
-		}
-		// Zero instances or version 0.0.0 on brand new deployments is a no-op,
-		// so do not add to queue.
-		if p.Kind() == AddedKind && (p.Post.NumInstances == 0 ||
-			p.Post.DeploySpec().Version.String() == "0.0.0") {
-			messages.ReportLogFieldsMessageWithIDs("Not adding uninitialized new diff",
-				logging.ExtraDebug1Level, r.ls, p)
-			continue
-		}
-		sr := NewRectification(*p, r.ls)
-		r.reportQSWait("Adding to queue set", logging.NotHere(), sr)
-		queued, ok := r.QueueSet.PushIfEmpty(sr)
-		if !ok {
-			r.reportQSWait("Failed to queue", logging.NotHere(), sr)
-			reportR11nAnomaly(r.ls, sr, r11nDroppedQueueNotEmpty)
+		if !waits.receive(p) {
+			err := fmt.Errorf("queueDiffs called with a second pair for %s in the chan", p.ID())
+			logging.ReportError(r.ls, err)
 			continue
 		}
 		wg.Add(1)
 		go func(p *DeployablePair) {
 			defer wg.Done()
-			r.reportQSWait("Inserting to QueueSet.Wait", logging.NotHere(), queued.ID, sr)
-			result, ok := r.QueueSet.Wait(p.ID(), queued.ID)
-			if !ok {
-				r.reportQSWait("Failed to QueueSet.Wait", logging.NotHere(), queued.ID, sr)
-				reportR11nAnomaly(r.ls, sr, r11nWentMissing)
+			var result *DiffResolution
+			report := true
+			if err := waits.await(p); err != nil {
+				messages.ReportLogFieldsMessageWithIDs("Not rectifying diff with failed dependency",
+					logging.InformationLevel, r.ls, p)
+				result = &DiffResolution{
+					DeploymentID: p.ID(),
+					Desc:         "not rectified",
+					Error:        WrapResolveError(err),
+				}
+			} else {
+				result, report = r.rectify(p, waits)
+			}
+			waits.finish(p.ID(), result)
+			if report {
+				results <- *result
 			}
-			results <- result
 		}(p)
 	}
+	waits.receivedAll()
 	wg.Wait()
 }
 
+// rectify queues a rectification of p, unless none is needed, and waits for
+// it. It returns the resolution of p, or nil if none was needed, and whether
+// to report it. If a rectification of p's deployment is already in progress,
+// p is dropped, unless another pair in waits depends on it: then rectify waits
+// for the rectification in progress instead, and its resolution is not
+// reported.
+func (r *Resolver) rectify(p *DeployablePair, waits *dependencyWaits) (*DiffResolution, bool) {
+	// SameKind == "no diffs" meaning a no-op; do not add to queue.
+	if p.Kind() == SameKind {
+		messages.ReportLogFieldsMessageWithIDs("Not adding equal diff",
+			logging.ExtraDebug1Level, r.ls, p)
+		return nil, false
+	}
+	// Zero instances or version 0.0.0 on brand new deployments is a no-op,
+	// so do not add to queue.
+	if p.Kind() == AddedKind && (p.Post.NumInstances == 0 ||
+		p.Post.DeploySpec().Version.String() == "0.0.0") {
+		messages.ReportLogFieldsMessageWithIDs("Not adding uninitialized new diff",
+			logging.ExtraDebug1Level, r.ls, p)
+		return nil, false
+	}
+	if err := p.CheckFreeze(time.Now()); err != nil && !r.Rollbacks.IsRollback(p) {
+		messages.ReportLogFieldsMessageWithIDs("Not rectifying diff in frozen cluster",
+			logging.InformationLevel, r.ls, p)
+		return &DiffResolution{
+			DeploymentID: p.ID(),
+			Desc:         "not rectified",
+			Error:        WrapResolveError(err),
+		}, true
+	}
+	sr := NewRectification(*p, r.ls)
+	r.reportQSWait("Adding to queue set", logging.NotHere(), sr)
+	queued, ok := r.QueueSet.PushIfEmpty(sr)
+	if !ok {
+		r.reportQSWait("Failed to queue", logging.NotHere(), sr)
+		reportR11nAnomaly(r.ls, sr, r11nDroppedQueueNotEmpty)
+		// Deployments which depend on p's still wait for it to be done, but
+		// nothing else does, since it may take as long as a staged rollout.
+		if !waits.dependedOn(p.ID()) {
+			return nil, false
+		}
+		result, ok := r.QueueSet.WaitLast(p.ID())
+		if !ok {
+			return nil, false
+		}
+		return &result, false
+	}
+	r.reportQSWait("Inserting to QueueSet.Wait", logging.NotHere(), queued.ID, sr)
+	result, ok := r.QueueSet.Wait(p.ID(), queued.ID)
+	if !ok {
+		r.reportQSWait("Failed to QueueSet.Wait", logging.NotHere(), queued.ID, sr)
+		reportR11nAnomaly(r.ls, sr, r11nWentMissing)
+	}
+	return &result, true
+}
+
 // this is close to a generic deliver. Adding a level argument and handling the extra layer of exclusion is all it would take...
 func (r *Resolver) reportQSWait(msg string, notThere logging.Excluder, fields ...interface{}) {
 	logging.Deliver(r.ls,
@@ -106,6 +164,54 @@ func (r *Resolver) reportQSWait(msg string, notThere logging.Excluder, fields ..
 // the actual set, compute the diffs and then issue the commands to rectify
 // those differences.
 func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecorder {
+	return r.begin(intended, clusters, nil)
+}
+
+// BeginOnly is like Begin, but only resolves the deployments with ids. Only
+// the clusters of those deployments are read for their running deployments,
+// and if the Deployer is a SelectiveDeployer, only those deployments are.
+func (r *Resolver) BeginOnly(intended Deployments, clusters Clusters, ids []DeploymentID) *ResolveRecorder {
+	only := map[DeploymentID]struct{}{}
+	affected := Clusters{}
+	for _, id := range ids {
+		only[id] = struct{}{}
+		if c, has := clusters[id.Cluster]; has {
+			affected[id.Cluster] = c
+		}
+	}
+	intended = intended.Filter(func(d *Deployment) bool {
+		_, has := only[d.ID()]
+		return has
+	})
+	return r.begin(intended, affected, only)
+}
+
+// runningDeployments returns the running deployments in clusters with IDs in
+// only, or all of them if only is nil.
+func (r *Resolver) runningDeployments(intended Deployments, clusters Clusters, only map[DeploymentID]struct{}) (DeployStates, error) {
+	if only == nil {
+		return r.Deployer.RunningDeployments(r.Registry, clusters)
+	}
+	if sd, ok := r.Deployer.(SelectiveDeployer); ok {
+		ids := make([]DeploymentID, 0, len(only))
+		for id := range only {
+			ids = append(ids, id)
+		}
+		return sd.RunningDeploymentsOf(r.Registry, clusters, ids, intended)
+	}
+	actual, err := r.Deployer.RunningDeployments(r.Registry, clusters)
+	if err != nil {
+		return actual, err
+	}
+	return actual.Filter(func(ds *DeployState) bool {
+		_, has := only[ds.ID()]
+		return has
+	}), nil
+}
+
+// begin resolves intended, and those running deployments in clusters with
+// IDs in only, or all of them if only is nil.
+func (r *Resolver) begin(intended Deployments, clusters Clusters, only map[DeploymentID]struct{}) *ResolveRecorder {
 	intended = intended.Filter(r.FilterDeployment)
 
 	return NewResolveRecorder(intended, r.ls, func(recorder *ResolveRecorder) {
@@ -121,7 +227,7 @@ func (r *Resolver) Begin(intended Deployments, clusters Clusters) *ResolveRecord
 
 		recorder.performPhase("getting running deployments", func() error {
 			var err error
-			actual, err = r.Deployer.RunningDeployments(r.Registry, clusters)
+			actual, err = r.runningDeployments(intended, clusters, only)
 			return err
 		})
 
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/nyarly/spies"
 	"github.com/opentable/sous/util/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGuardImageMissing(t *testing.T) {
@@ -79,3 +81,144 @@ func TestAllowsWhitelistedAdvisories(t *testing.T) {
 	assert.NoError(err)
 	assert.NotNil(art)
 }
+
+func TestResolverRunningDeploymentsOnly(t *testing.T) {
+	d, dc := newSelectiveDeployerSpy()
+	dc.MatchMethod("RunningDeployments", spies.AnyArgs, NewDeployStates(), nil)
+	dc.MatchMethod("RunningDeploymentsOf", spies.AnyArgs, NewDeployStates(), nil)
+	r := NewResolver(d, NewDummyRegistry(), nil, logging.SilentLogSet(), nil)
+	clusters := Clusters{"x": &Cluster{Name: "x"}}
+
+	id := DeploymentID{ManifestID: MustParseManifestID("github.com/ot/one"), Cluster: "x"}
+	_, err := r.runningDeployments(NewDeployments(), clusters, map[DeploymentID]struct{}{id: {}})
+	assert.NoError(t, err)
+	assert.Len(t, dc.CallsTo("RunningDeployments"), 0)
+	if calls := dc.CallsTo("RunningDeploymentsOf"); assert.Len(t, calls, 1) {
+		assert.Equal(t, []DeploymentID{id}, calls[0].PassedArgs().Get(2))
+	}
+
+	_, err = r.runningDeployments(NewDeployments(), clusters, nil)
+	assert.NoError(t, err)
+	assert.Len(t, dc.CallsTo("RunningDeployments"), 1)
+}
+
+func TestQueueDiffsRefusesFrozenClusters(t *testing.T) {
+	qs := NewR11nQueueSet()
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+
+	frozen := &Cluster{Name: "x", FreezeWindows: FreezeWindows{{Reason: "holiday"}}}
+	dep := &Deployment{
+		ClusterName:  "x",
+		Cluster:      frozen,
+		SourceID:     MustParseSourceID(`github.com/ot/one,1.3.5`),
+		DeployConfig: DeployConfig{NumInstances: 1},
+	}
+	pair := &DeployablePair{Post: &Deployable{Deployment: dep}}
+	pair.SetID(dep.ID())
+
+	dcs := NewDeployableChans(1)
+	dcs.Pairs <- pair
+	close(dcs.Pairs)
+	results := make(chan DiffResolution, 1)
+	r.queueDiffs(dcs, results)
+
+	rez := <-results
+	assert.Equal(t, dep.ID(), rez.DeploymentID)
+	if assert.NotNil(t, rez.Error) {
+		assert.True(t, IsFreezeError(rez.Error.error))
+	}
+	assert.Len(t, qs.Queues(), 0)
+}
+
+func TestResolverRectifiesRollbacksInFrozenCluster(t *testing.T) {
+	sm, failed := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+	rb.Observe(failed)
+	require.NotNil(t, rb.Resolved(failed, failedResolution()))
+	failed.Post.Cluster.FreezeWindows = FreezeWindows{{Reason: "holiday"}}
+
+	qs := NewR11nQueueSet(R11nQueueStartWithHandler(func(qr *QueuedR11n) DiffResolution {
+		return DiffResolution{DeploymentID: qr.Rectification.Pair.ID(), Desc: ModifyDiff}
+	}))
+	r := NewResolver(nil, nil, nil, logging.SilentLogSet(), qs)
+	r.Rollbacks = rb
+
+	back := &DeployablePair{Prior: failed.Post, Post: failed.Prior}
+	back.SetID(failed.ID())
+	dcs := NewDeployableChans(1)
+	dcs.Pairs <- back
+	close(dcs.Pairs)
+	results := make(chan DiffResolution, 1)
+	r.queueDiffs(dcs, results)
+
+	rez := <-results
+	assert.Equal(t, ModifyDiff, rez.Desc)
+	assert.Nil(t, rez.Error)
+}
+
+func TestGuardImageVerifiesSignatures(t *testing.T) {
+	assert := assert.New(t)
+
+	private, public := testSigningKeys(t)
+	_, otherPublic := testSigningKeys(t)
+	signer, err := NewKeySigner(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svOne := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	config := DeployConfig{NumInstances: 1}
+	ls, _ := logging.NewLogSinkSpy()
+
+	signed := Deployment{ClusterName: `prod`, Cluster: &Cluster{SigningKeys: []string{public}}, SourceID: svOne, DeployConfig: config}
+	dr := NewDummyRegistry()
+	dr.FeedArtifact(testSignedArtifact(t, signer), nil)
+	art, err := guardImage(dr, &signed, ls)
+	assert.NoError(err)
+	assert.NotNil(art)
+
+	untrusted := Deployment{ClusterName: `prod`, Cluster: &Cluster{SigningKeys: []string{otherPublic}}, SourceID: svOne, DeployConfig: config}
+	dr.FeedArtifact(testSignedArtifact(t, signer), nil)
+	_, err = guardImage(dr, &untrusted, ls)
+	assert.IsType(&UnverifiedArtifact{}, err)
+
+	unsigned := testSignedArtifact(t, signer)
+	unsigned.Signatures = nil
+	dr.FeedArtifact(unsigned, nil)
+	_, err = guardImage(dr, &signed, ls)
+	assert.IsType(&UnverifiedArtifact{}, err)
+
+	unrequired := Deployment{ClusterName: `ci`, Cluster: &Cluster{}, SourceID: svOne, DeployConfig: config}
+	dr.FeedArtifact(unsigned, nil)
+	_, err = guardImage(dr, &unrequired, ls)
+	assert.NoError(err)
+}
+
+func TestGuardImageChecksVulnerabilities(t *testing.T) {
+	assert := assert.New(t)
+
+	svOne := MustParseSourceID(`github.com/ot/one,1.3.5`)
+	config := DeployConfig{NumInstances: 1}
+	prod := Deployment{ClusterName: `prod`, Cluster: &Cluster{VulnerabilityThresholds: VulnerabilityThresholds{"critical": 0}}, SourceID: svOne, DeployConfig: config}
+	ls, _ := logging.NewLogSinkSpy()
+
+	clean := VulnerabilityReport{Scanner: "file", Vulnerabilities: []Vulnerability{{ID: "CVE-2018-0002", Severity: "low"}}}
+	dr := NewDummyRegistry()
+	dr.FeedArtifact(&BuildArtifact{VersionName: "ot-docker/one:0.1", Type: "docker", Qualities: clean.Qualities()}, nil)
+	art, err := guardImage(dr, &prod, ls)
+	assert.NoError(err)
+	assert.NotNil(art)
+
+	critical := VulnerabilityReport{Scanner: "file", Vulnerabilities: []Vulnerability{{ID: "CVE-2018-0001", Severity: "critical"}}}
+	dr.FeedArtifact(&BuildArtifact{VersionName: "ot-docker/one:0.1", Type: "docker", Qualities: critical.Qualities()}, nil)
+	_, err = guardImage(dr, &prod, ls)
+	assert.IsType(&UnacceptableVulnerabilities{}, err)
+
+	dr.FeedArtifact(&BuildArtifact{VersionName: "ot-docker/one:0.1", Type: "docker"}, nil)
+	_, err = guardImage(dr, &prod, ls)
+	assert.IsType(&UnacceptableVulnerabilities{}, err, "unscanned artifacts are refused")
+
+	ci := Deployment{ClusterName: `ci`, Cluster: &Cluster{}, SourceID: svOne, DeployConfig: config}
+	dr.FeedArtifact(&BuildArtifact{VersionName: "ot-docker/one:0.1", Type: "docker", Qualities: critical.Qualities()}, nil)
+	_, err = guardImage(dr, &ci, ls)
+	assert.NoError(err)
+}
@@ -55,6 +55,10 @@ type (
 
 		// SchedulerURL is a URL where this deployment can be seen.
 		SchedulerURL string
+
+		// Rollback describes the automatic rollback made because this
+		// resolution failed, if any.
+		Rollback *RollbackEvent `json:",omitempty"`
 	}
 
 	// ResolutionType marks the kind of a DiffResolution
@@ -112,7 +116,9 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 	}
 
 	// Update status incrementally.
+	logged := make(chan struct{})
 	go func() {
+		defer close(logged)
 		for rez := range rr.Log {
 			rr.write(func() {
 				rr.status.Log = append(rr.status.Log, rez)
@@ -127,9 +133,11 @@ func NewResolveRecorder(intended Deployments, ls logging.LogSink, f func(*Resolv
 	// Execute the main function (f) over this resolve recorder.
 	go func() {
 		f(rr)
+		// Every resolution is recorded before the recorder is finished.
+		close(rr.Log)
+		<-logged
 		rr.write(func() {
 			defer close(rr.finished)
-			defer close(rr.Log)
 			rr.status.Finished = time.Now()
 			if rr.err == nil {
 				rr.status.Phase = "finished"
@@ -147,6 +155,48 @@ func (rs *ResolveStatus) Err() error {
 	return nil
 }
 
+// withChanges returns a copy of rs in which the intended deployments and
+// resolutions of the deployments with ids are replaced by those in changed,
+// which resolved only those deployments, and whose errors are those of the
+// resolutions it keeps. If rs is nil, it returns changed.
+func (rs *ResolveStatus) withChanges(ids []DeploymentID, changed ResolveStatus) *ResolveStatus {
+	if rs == nil {
+		return &changed
+	}
+	affected := map[DeploymentID]struct{}{}
+	for _, id := range ids {
+		affected[id] = struct{}{}
+	}
+	isAffected := func(id DeploymentID) bool {
+		_, has := affected[id]
+		return has
+	}
+
+	merged := *rs
+	merged.Finished = changed.Finished
+	merged.Intended = []*Deployment{}
+	for _, d := range rs.Intended {
+		if !isAffected(d.ID()) {
+			merged.Intended = append(merged.Intended, d)
+		}
+	}
+	merged.Intended = append(merged.Intended, changed.Intended...)
+	merged.Log = []DiffResolution{}
+	for _, rez := range rs.Log {
+		if !isAffected(rez.DeploymentID) {
+			merged.Log = append(merged.Log, rez)
+		}
+	}
+	merged.Log = append(merged.Log, changed.Log...)
+	merged.Errs.Causes = []ErrorWrapper{}
+	for _, rez := range merged.Log {
+		if rez.Error != nil {
+			merged.Errs.Causes = append(merged.Errs.Causes, ErrorWrapper{error: rez.Error})
+		}
+	}
+	return &merged
+}
+
 // CurrentStatus returns a copy of the current status of the resolve
 func (rr *ResolveRecorder) CurrentStatus() (rs ResolveStatus) {
 	rr.read(func() {
@@ -3,6 +3,7 @@ package sous
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/util/logging"
 )
@@ -145,3 +146,41 @@ func TestResolveRecorder(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveStatus_withChanges(t *testing.T) {
+	did := func(repo string) DeploymentID {
+		return DeploymentID{ManifestID: ManifestID{Source: SourceLocation{Repo: repo}}, Cluster: "c"}
+	}
+	dep := func(repo string) *Deployment {
+		return &Deployment{SourceID: SourceID{Location: SourceLocation{Repo: repo}}, ClusterName: "c"}
+	}
+	failed := DiffResolution{DeploymentID: did("two"), Desc: "not updated", Error: WrapResolveError(fmt.Errorf("no image"))}
+	stable := &ResolveStatus{
+		Intended: []*Deployment{dep("one"), dep("two")},
+		Log:      []DiffResolution{{DeploymentID: did("one"), Desc: StableDiff}, failed},
+		Errs:     ResolveErrors{Causes: []ErrorWrapper{{error: failed.Error}}},
+	}
+	changed := ResolveStatus{
+		Finished: time.Now(),
+		Intended: []*Deployment{dep("two")},
+		Log:      []DiffResolution{{DeploymentID: did("two"), Desc: ModifyDiff}},
+		Errs:     ResolveErrors{Causes: []ErrorWrapper{}},
+	}
+
+	merged := stable.withChanges([]DeploymentID{did("two")}, changed)
+	if len(merged.Intended) != 2 || len(merged.Log) != 2 {
+		t.Fatalf("got %d intended, %d resolutions; want 2, 2", len(merged.Intended), len(merged.Log))
+	}
+	if merged.Log[0].Desc != StableDiff || merged.Log[1].Desc != ModifyDiff {
+		t.Errorf("got resolutions %q, %q; want %q, %q", merged.Log[0].Desc, merged.Log[1].Desc, StableDiff, ModifyDiff)
+	}
+	if !merged.Finished.Equal(changed.Finished) {
+		t.Errorf("got finished %s; want %s", merged.Finished, changed.Finished)
+	}
+	if len(merged.Errs.Causes) != 0 {
+		t.Errorf("got errors %v; want none, since two was since resolved", merged.Errs.Causes)
+	}
+	if len(stable.Log) != 2 || stable.Log[1].Desc != failed.Desc || len(stable.Errs.Causes) != 1 {
+		t.Errorf("withChanges changed its receiver")
+	}
+}
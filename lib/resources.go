@@ -3,9 +3,47 @@ package sous
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// The types of resource, named by the Type of their definitions in
+// Defs.Resources. Resource types are not case sensitive, and underscores in
+// them are ignored, so "MemorySize" is a memory_size.
+const (
+	// ResourceTypeFloat is a decimal quantity, which may have an SI or binary
+	// suffix, e.g. "0.5", "250m" or "1k".
+	ResourceTypeFloat VarType = "float"
+	// ResourceTypeInteger is a whole number, e.g. "2". It may also be written
+	// "int".
+	ResourceTypeInteger VarType = "integer"
+	// ResourceTypeMemorySize is an amount of memory or storage, in MiB when
+	// it is a plain number, e.g. "512", or with a unit, e.g. "512Mi" or "2G".
+	ResourceTypeMemorySize VarType = "memory_size"
 )
 
+// builtinResources define the resources Sous understands without definitions
+// in Defs.Resources. Every deployment needs cpus, memory and ports, which are
+// given these defaults when they are missing.
+var builtinResources = FieldDefinitions{
+	{Name: "cpus", Type: ResourceTypeFloat, Default: "0.1"},
+	{Name: "memory", Type: ResourceTypeMemorySize, Default: "100"},
+	{Name: "ports", Type: ResourceTypeInteger, Default: "1"},
+	{Name: "disk", Type: ResourceTypeMemorySize, Optional: true},
+}
+
+// resourceUnits are the multipliers of the suffixes resource quantities may
+// have.
+var resourceUnits = map[string]float64{
+	"m": 1e-3,
+	"k": 1e3, "K": 1e3, "M": 1e6, "G": 1e9, "T": 1e12,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40,
+}
+
 type (
 	// Resources is a mapping of resource name to value, used to provision
 	// single instances of an application. It is validated against
@@ -21,8 +59,121 @@ type (
 		ClusterName    string
 		Field, Default string
 	}
+
+	// ResourceLimits are the least and most of each resource a deployment to
+	// a cluster may have, by resource name.
+	ResourceLimits map[string]ResourceLimit
+
+	// A ResourceLimit is the least and most of a resource, as quantities of
+	// its type. Either may be empty, for no limit.
+	ResourceLimit struct {
+		Min string `yaml:",omitempty"`
+		Max string `yaml:",omitempty"`
+	}
 )
 
+// ParseResource returns the quantity value represents for a resource of type
+// vt. Memory sizes are returned in MiB.
+func ParseResource(vt VarType, value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, unicode.IsLetter)
+	if i < 0 {
+		i = len(value)
+	}
+	num, unit := value[:i], value[i:]
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a number", value)
+	}
+	if n < 0 {
+		return 0, errors.Errorf("%q is negative", value)
+	}
+	mult, known := resourceUnits[unit]
+	if unit != "" && !known {
+		return 0, errors.Errorf("%q has unknown unit %q", value, unit)
+	}
+
+	switch kind, _ := resourceKind(vt); kind {
+	default:
+		return 0, errors.Errorf("unknown resource type %q", string(vt))
+	case ResourceTypeFloat:
+		if unit == "" {
+			return n, nil
+		}
+		return n * mult, nil
+	case ResourceTypeInteger:
+		if unit != "" || n != math.Trunc(n) {
+			return 0, errors.Errorf("%q is not a whole number", value)
+		}
+		return n, nil
+	case ResourceTypeMemorySize:
+		if unit == "" {
+			return n, nil
+		}
+		if mult < 1 {
+			return 0, errors.Errorf("%q is less than a byte", value)
+		}
+		return n * mult / (1 << 20), nil
+	}
+}
+
+// ValidateResourceType returns an error if vt is not a type of resource.
+func ValidateResourceType(vt VarType) error {
+	switch kind, arg := resourceKind(vt); kind {
+	default:
+		return errors.Errorf("unknown resource type %q", string(vt))
+	case ResourceTypeFloat, ResourceTypeInteger, ResourceTypeMemorySize:
+		if arg != "" {
+			return errors.Errorf("resource type %q takes no argument", string(vt))
+		}
+		return nil
+	}
+}
+
+// resourceKind returns the resource type vt names, which is one of the
+// ResourceType constants unless vt is invalid, and its argument, if any.
+func resourceKind(vt VarType) (VarType, string) {
+	kind, arg := vt.Kind()
+	switch strings.Replace(string(kind), "_", "", -1) {
+	case "memorysize":
+		return ResourceTypeMemorySize, arg
+	case "int", "integer":
+		return ResourceTypeInteger, arg
+	}
+	return kind, arg
+}
+
+// resourceDefinition returns the definition of the resource called name in
+// defs, or else its builtin definition, and false if it has neither.
+func resourceDefinition(defs FieldDefinitions, name string) (FieldDefinition, bool) {
+	for _, fds := range []FieldDefinitions{defs, builtinResources} {
+		for _, fd := range fds {
+			if fd.Name == name {
+				return fd, true
+			}
+		}
+	}
+	return FieldDefinition{}, false
+}
+
+// resourceQuantity returns the quantity of the resource called name given by
+// value, which for a builtin resource with a default is that default if value
+// doesn't parse, and false if it isn't a quantity.
+func resourceQuantity(name, value string) (float64, bool) {
+	fd, builtin := resourceDefinition(nil, name)
+	if !builtin {
+		fd.Type = ResourceTypeFloat
+	}
+	if q, err := ParseResource(fd.Type, value); err == nil {
+		return q, true
+	}
+	if fd.Default == "" {
+		return 0, false
+	}
+	q, err := ParseResource(fd.Type, fd.Default)
+	return q, err == nil
+}
+
 // Clone returns a deep copy of this Resources.
 func (r Resources) Clone() Resources {
 	rs := make(Resources, len(r))
@@ -80,14 +231,13 @@ func (f *MissingResourceFlaw) Repair() error {
 func (r Resources) Validate() []Flaw {
 	var flaws []Flaw
 
-	if f := r.validateField("cpus", "0.1"); f != nil {
-		flaws = append(flaws, f)
-	}
-	if f := r.validateField("memory", "100"); f != nil {
-		flaws = append(flaws, f)
-	}
-	if f := r.validateField("ports", "1"); f != nil {
-		flaws = append(flaws, f)
+	for _, fd := range builtinResources {
+		if fd.Optional {
+			continue
+		}
+		if f := r.validateField(fd.Name, fd.Default); f != nil {
+			flaws = append(flaws, f)
+		}
 	}
 
 	return flaws
@@ -102,51 +252,140 @@ func (r Resources) validateField(name, def string) Flaw {
 
 // Cpus returns the number of CPUs.
 func (r Resources) Cpus() float64 {
-	cpuStr := r["cpus"]
-	cpus, err := strconv.ParseFloat(cpuStr, 64)
-	if err != nil {
-		cpus = 0.1
-	}
+	cpus, _ := resourceQuantity("cpus", r["cpus"])
 	return cpus
 }
 
 // Memory returns memory in MB.
 func (r Resources) Memory() float64 {
-	memStr := r["memory"]
-	memory, err := strconv.ParseFloat(memStr, 64)
-	if err != nil {
-		memory = 100
-	}
+	memory, _ := resourceQuantity("memory", r["memory"])
 	return memory
 }
 
 // Ports returns the number of ports required.
 func (r Resources) Ports() int32 {
-	portStr := r["ports"]
-	ports, err := strconv.ParseInt(portStr, 10, 32)
-	if err != nil {
-		ports = 1
-	}
+	ports, _ := resourceQuantity("ports", r["ports"])
 	return int32(ports)
 }
 
-// Equal checks equivalence between resource maps
+// Disk returns disk space in MB, which is 0 if none is required.
+func (r Resources) Disk() float64 {
+	disk, _ := resourceQuantity("disk", r["disk"])
+	return disk
+}
+
+// Equal checks equivalence between resource maps. Values are compared as
+// quantities, so that e.g. "1024" and "1Gi" of memory are equal.
 func (r Resources) Equal(o Resources) bool {
 	if len(r) != len(o) {
 		return false
 	}
 
-	if r.Ports() != o.Ports() {
-		return false
+	for name, v := range r {
+		ov, has := o[name]
+		if !has {
+			return false
+		}
+		q, isQ := resourceQuantity(name, v)
+		oq, isOQ := resourceQuantity(name, ov)
+		if !isQ || !isOQ {
+			if v != ov {
+				return false
+			}
+			continue
+		}
+		if math.Abs(q-oq) > 0.001 {
+			return false
+		}
 	}
 
-	if math.Abs(r.Cpus()-o.Cpus()) > 0.001 {
-		return false
+	return true
+}
+
+// Validate returns an error if any of the limits in rl are not quantities of
+// the resources defined by defs, or their builtin definitions, or if a limit's
+// Min exceeds its Max.
+func (rl ResourceLimits) Validate(defs FieldDefinitions) error {
+	for _, name := range rl.names() {
+		fd, has := resourceDefinition(defs, name)
+		if !has {
+			return errors.Errorf("limit on undefined resource %q", name)
+		}
+		l := rl[name]
+		min, max, err := l.quantities(fd.Type)
+		if err != nil {
+			return errors.Wrapf(err, "limit on %s", name)
+		}
+		if l.Min != "" && l.Max != "" && min > max {
+			return errors.Errorf("limit on %s: minimum %s exceeds maximum %s", name, l.Min, l.Max)
+		}
 	}
+	return nil
+}
 
-	if math.Abs(r.Memory()-o.Memory()) > 0.001 {
-		return false
+// Check returns an error if value is outside l, for a resource of type vt.
+func (l ResourceLimit) Check(vt VarType, value string) error {
+	q, err := ParseResource(vt, value)
+	if err != nil {
+		return err
+	}
+	min, max, err := l.quantities(vt)
+	if err != nil {
+		return err
+	}
+	if l.Min != "" && q < min {
+		return errors.Errorf("%s is less than the minimum %s", value, l.Min)
+	}
+	if l.Max != "" && q > max {
+		return errors.Errorf("%s is more than the maximum %s", value, l.Max)
+	}
+	return nil
+}
+
+func (l ResourceLimit) quantities(vt VarType) (min, max float64, err error) {
+	if l.Min != "" {
+		if min, err = ParseResource(vt, l.Min); err != nil {
+			return 0, 0, errors.Wrap(err, "minimum")
+		}
+	}
+	if l.Max != "" {
+		if max, err = ParseResource(vt, l.Max); err != nil {
+			return 0, 0, errors.Wrap(err, "maximum")
+		}
+	}
+	return min, max, nil
+}
+
+// Clone returns a deep copy of rl.
+func (rl ResourceLimits) Clone() ResourceLimits {
+	if rl == nil {
+		return nil
+	}
+	c := make(ResourceLimits, len(rl))
+	for name, l := range rl {
+		c[name] = l
 	}
+	return c
+}
 
+// Equal returns true if rl and other have the same limits.
+func (rl ResourceLimits) Equal(other ResourceLimits) bool {
+	if len(rl) != len(other) {
+		return false
+	}
+	for name, l := range rl {
+		if ol, has := other[name]; !has || ol != l {
+			return false
+		}
+	}
 	return true
 }
+
+func (rl ResourceLimits) names() []string {
+	names := make([]string, 0, len(rl))
+	for name := range rl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -19,3 +19,74 @@ func TestValidateRepairResources(t *testing.T) {
 	assert.Equal(t, empty["memory"], "100")
 	assert.Equal(t, empty["ports"], "1")
 }
+
+func TestParseResource(t *testing.T) {
+	cases := []struct {
+		vt    VarType
+		value string
+		q     float64
+	}{
+		{"float", "0.5", 0.5},
+		{"Float", "250m", 0.25},
+		{"float", "2k", 2000},
+		{"integer", "3", 3},
+		{"int", "3", 3},
+		{"memory_size", "100", 100},
+		{"memory_size", "512Mi", 512},
+		{"memory_size", "2Gi", 2048},
+		{"memory_size", "1G", 1e9 / (1 << 20)},
+		{"MemorySize", "1Gi", 1024},
+		{"memorysize", "64", 64},
+		{"Integer", "2", 2},
+		{"Int", "2", 2},
+	}
+	for _, c := range cases {
+		q, err := ParseResource(c.vt, c.value)
+		if assert.NoError(t, err, "%s %q", c.vt, c.value) {
+			assert.InDelta(t, c.q, q, 1e-9, "%s %q", c.vt, c.value)
+		}
+	}
+
+	for _, c := range []struct {
+		vt    VarType
+		value string
+	}{
+		{"float", "lots"},
+		{"float", "-1"},
+		{"float", "1X"},
+		{"integer", "1.5"},
+		{"integer", "1k"},
+		{"memory_size", "500m"},
+		{"bandwidth", "1"},
+	} {
+		_, err := ParseResource(c.vt, c.value)
+		assert.Error(t, err, "%s %q", c.vt, c.value)
+	}
+}
+
+func TestResourcesEqual(t *testing.T) {
+	r := Resources{"cpus": "0.5", "memory": "1Gi", "ports": "1", "network-mbps": "100"}
+
+	assert.True(t, r.Equal(Resources{"cpus": "500m", "memory": "1024", "ports": "1", "network-mbps": "100.0"}))
+	assert.False(t, r.Equal(Resources{"cpus": "0.5", "memory": "1Gi", "ports": "1", "network-mbps": "200"}),
+		"resources other than cpus, memory and ports are compared")
+	assert.False(t, r.Equal(Resources{"cpus": "0.5", "memory": "1Gi", "ports": "1", "disk": "100"}))
+	assert.True(t, Resources{"cpus": "", "x": "a"}.Equal(Resources{"cpus": "0.1", "x": "a"}),
+		"defaults for unparseable builtin resources, and strings for others")
+}
+
+func TestResourceLimits(t *testing.T) {
+	defs := FieldDefinitions{{Name: "ephemeral-storage", Type: ResourceTypeMemorySize}}
+	rl := ResourceLimits{
+		"memory":            {Min: "128", Max: "16Gi"},
+		"ephemeral-storage": {Max: "1Gi"},
+	}
+	assert.NoError(t, rl.Validate(defs))
+	assert.Error(t, ResourceLimits{"gpus": {Max: "1"}}.Validate(defs))
+	assert.Error(t, ResourceLimits{"memory": {Min: "2Gi", Max: "1Gi"}}.Validate(defs))
+	assert.Error(t, ResourceLimits{"memory": {Max: "lots"}}.Validate(defs))
+
+	assert.NoError(t, rl["memory"].Check(ResourceTypeMemorySize, "2Gi"))
+	assert.Error(t, rl["memory"].Check(ResourceTypeMemorySize, "64Mi"))
+	assert.Error(t, rl["memory"].Check(ResourceTypeMemorySize, "17Gi"))
+}
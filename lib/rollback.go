@@ -0,0 +1,225 @@
+package sous
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// Rollbacker returns deployments to their last known-good version in the
+	// GDM when a rectification fails, for deployments whose Rollout or Cluster
+	// enables AutoRollback.
+	Rollbacker struct {
+		StateManager StateManager
+		// History, if set, supplies the last known-good versions of
+		// deployments this Rollbacker has not seen resolve, e.g. since the
+		// server restarted.
+		History DeploymentHistory
+		log     logging.LogSink
+
+		sync.Mutex
+		knownGood map[DeploymentID]SourceID
+		events    []RollbackEvent
+		// rolledBack holds the version each deployment was rolled back to,
+		// until that version has been rectified.
+		rolledBack map[DeploymentID]SourceID
+	}
+
+	// RollbackEvent records a single automatic rollback.
+	RollbackEvent struct {
+		DeploymentID DeploymentID
+		// From is the version that failed to deploy.
+		From SourceID
+		// To is the known-good version the GDM was returned to.
+		To     SourceID
+		Reason string
+		Time   time.Time
+	}
+)
+
+// RollbackUser is recorded as the user responsible for GDM changes made by
+// automatic rollbacks.
+var RollbackUser = User{Name: "sous-rollback"}
+
+// maxRollbackEvents is the number of recent rollbacks a Rollbacker remembers.
+const maxRollbackEvents = 100
+
+// NewRollbacker returns a Rollbacker that writes to sm, and finds known-good
+// versions in h, which may be nil.
+func NewRollbacker(sm StateManager, h DeploymentHistory, ls logging.LogSink) *Rollbacker {
+	return &Rollbacker{
+		StateManager: sm,
+		History:      h,
+		log:          ls,
+		knownGood:    map[DeploymentID]SourceID{},
+	}
+}
+
+// Observe records the running version of pair as known-good if it is active.
+func (rb *Rollbacker) Observe(pair *DeployablePair) {
+	if rb == nil || pair == nil || pair.Prior == nil {
+		return
+	}
+	if pair.Prior.Status != DeployStatusActive {
+		return
+	}
+	rb.remember(pair.ID(), pair.Prior.SourceID)
+}
+
+func (rb *Rollbacker) remember(did DeploymentID, sid SourceID) {
+	rb.Lock()
+	defer rb.Unlock()
+	if rb.knownGood == nil {
+		rb.knownGood = map[DeploymentID]SourceID{}
+	}
+	rb.knownGood[did] = sid
+}
+
+// lastGood returns the last known-good version of did other than failed. It
+// falls back to the most recent successful entry in the deployment history.
+func (rb *Rollbacker) lastGood(did DeploymentID, failed SourceID) (SourceID, bool) {
+	rb.Lock()
+	sid, ok := rb.knownGood[did]
+	rb.Unlock()
+	if ok && !sid.Equal(failed) {
+		return sid, true
+	}
+	if rb.History == nil {
+		return SourceID{}, false
+	}
+	entries, err := rb.History.History(did)
+	if err != nil {
+		logging.ReportError(rb.log, errors.Wrapf(err, "reading history of %s", did))
+		return SourceID{}, false
+	}
+	for _, e := range entries {
+		if e.Outcome == OutcomeSucceeded && !e.DeploySpec.Version.Equals(failed.Version) {
+			return did.ManifestID.Source.SourceID(e.DeploySpec.Version), true
+		}
+	}
+	return SourceID{}, false
+}
+
+// Resolved inspects the final resolution of pair. Successful deployments
+// become known-good. If the deployment failed and AutoRollback applies to it,
+// the GDM is returned to the last known-good version and the returned event
+// describes the rollback. It returns nil if no rollback was made.
+func (rb *Rollbacker) Resolved(pair *DeployablePair, rez DiffResolution) *RollbackEvent {
+	if rb == nil || pair == nil || pair.Post == nil || rez.DeployState == nil {
+		return nil
+	}
+	did := pair.ID()
+	post := pair.Post.Deployment
+	rb.Lock()
+	if sid, ok := rb.rolledBack[did]; ok && sid.Equal(post.SourceID) {
+		delete(rb.rolledBack, did)
+	}
+	rb.Unlock()
+	switch rez.DeployState.Status {
+	default:
+		return nil
+	case DeployStatusActive:
+		if rez.Error == nil {
+			rb.remember(did, post.SourceID)
+		}
+		return nil
+	case DeployStatusFailed:
+	}
+
+	if !post.Rollout.AutoRollback && (post.Cluster == nil || !post.Cluster.AutoRollback) {
+		return nil
+	}
+	good, ok := rb.lastGood(did, post.SourceID)
+	if !ok {
+		return nil
+	}
+
+	reason := rez.DeployState.ExecutorMessage
+	if reason == "" && rez.Error != nil && rez.Error.error != nil {
+		reason = rez.Error.Error()
+	}
+	if reason == "" {
+		reason = fmt.Sprintf("deployment of %s failed", post.SourceID)
+	}
+
+	rolled, err := rb.rollbackGDM(did, post.SourceID, good)
+	if err != nil {
+		logging.ReportError(rb.log, errors.Wrapf(err, "rolling back %s", did))
+		return nil
+	}
+	if !rolled {
+		return nil
+	}
+
+	ev := RollbackEvent{
+		DeploymentID: did,
+		From:         post.SourceID,
+		To:           good,
+		Reason:       reason,
+		Time:         time.Now(),
+	}
+	rb.Lock()
+	if rb.rolledBack == nil {
+		rb.rolledBack = map[DeploymentID]SourceID{}
+	}
+	rb.rolledBack[did] = good
+	rb.events = append(rb.events, ev)
+	if len(rb.events) > maxRollbackEvents {
+		rb.events = rb.events[len(rb.events)-maxRollbackEvents:]
+	}
+	rb.Unlock()
+
+	messages.ReportLogFieldsMessageWithIDs(
+		fmt.Sprintf("Rolled back %s from %s to %s: %s", did, ev.From.Version, ev.To.Version, reason),
+		logging.WarningLevel, rb.log, pair)
+	return &ev
+}
+
+// rollbackGDM sets the version of did in the GDM to good, as long as it is
+// still the failed version. It reports whether the GDM was changed.
+func (rb *Rollbacker) rollbackGDM(did DeploymentID, failed, good SourceID) (bool, error) {
+	state, err := rb.StateManager.ReadState()
+	if err != nil {
+		return false, err
+	}
+	m, ok := state.Manifests.Get(did.ManifestID)
+	if !ok {
+		return false, nil
+	}
+	spec, ok := state.Defs.ClusterSpec(m, did.Cluster)
+	if !ok || !spec.Version.Equals(failed.Version) {
+		// Someone has deployed something else already.
+		return false, nil
+	}
+	spec.Version = good.Version
+	state.Defs.SetClusterSpec(m, did.Cluster, spec)
+	return true, rb.StateManager.WriteState(state, RollbackUser)
+}
+
+// IsRollback returns true if pair deploys the version its deployment was
+// rolled back to, and that rollback has not yet been rectified. Rollbacks are
+// allowed through cluster freezes.
+func (rb *Rollbacker) IsRollback(pair *DeployablePair) bool {
+	if rb == nil || pair == nil || pair.Post == nil {
+		return false
+	}
+	rb.Lock()
+	defer rb.Unlock()
+	sid, ok := rb.rolledBack[pair.ID()]
+	return ok && sid.Equal(pair.Post.SourceID)
+}
+
+// Events returns the most recent automatic rollbacks, oldest first.
+func (rb *Rollbacker) Events() []RollbackEvent {
+	if rb == nil {
+		return nil
+	}
+	rb.Lock()
+	defer rb.Unlock()
+	return append([]RollbackEvent{}, rb.events...)
+}
@@ -0,0 +1,163 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rollbackFixture(autoRollback bool) (*DummyStateManager, *DeployablePair) {
+	mid := ManifestID{Source: SourceLocation{Repo: "github.com/example/a"}}
+	sm := NewDummyStateManager()
+	sm.State.Manifests.Add(&Manifest{
+		Source: mid.Source,
+		Kind:   ManifestKindService,
+		Deployments: DeploySpecs{
+			"cluster-1": DeploySpec{Version: semv.MustParse("2.0.0")},
+		},
+	})
+
+	cluster := &Cluster{Name: "cluster-1"}
+	prior := &DeployState{
+		Status: DeployStatusActive,
+		Deployment: Deployment{
+			ClusterName: "cluster-1",
+			Cluster:     cluster,
+			SourceID:    mid.Source.SourceID(semv.MustParse("1.0.0")),
+		},
+	}
+	post := &Deployment{
+		ClusterName: "cluster-1",
+		Cluster:     cluster,
+		SourceID:    mid.Source.SourceID(semv.MustParse("2.0.0")),
+	}
+	post.Rollout.AutoRollback = autoRollback
+
+	pair := &DeployablePair{
+		Prior: &Deployable{Status: DeployStatusActive, Deployment: &prior.Deployment},
+		Post:  &Deployable{Deployment: post},
+	}
+	pair.SetID(DeploymentID{ManifestID: mid, Cluster: "cluster-1"})
+	return sm, pair
+}
+
+func failedResolution() DiffResolution {
+	return DiffResolution{
+		Desc:        ModifyDiff,
+		DeployState: &DeployState{Status: DeployStatusFailed, ExecutorMessage: "healthcheck failed"},
+	}
+}
+
+func TestRollbacker_Resolved_rollsBack(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+
+	rb.Observe(pair)
+	ev := rb.Resolved(pair, failedResolution())
+
+	require.NotNil(t, ev)
+	assert.Equal(t, "2.0.0", ev.From.Version.String())
+	assert.Equal(t, "1.0.0", ev.To.Version.String())
+	assert.Equal(t, "healthcheck failed", ev.Reason)
+	assert.Equal(t, 1, sm.WriteCount)
+
+	m, ok := sm.State.Manifests.Get(pair.ID().ManifestID)
+	require.True(t, ok)
+	assert.Equal(t, "1.0.0", m.Deployments["cluster-1"].Version.String())
+	assert.Len(t, rb.Events(), 1)
+}
+
+func TestRollbacker_Resolved_clusterPolicy(t *testing.T) {
+	sm, pair := rollbackFixture(false)
+	pair.Post.Cluster.AutoRollback = true
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+
+	rb.Observe(pair)
+	assert.NotNil(t, rb.Resolved(pair, failedResolution()))
+}
+
+func TestRollbacker_Resolved_policyOff(t *testing.T) {
+	sm, pair := rollbackFixture(false)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+
+	rb.Observe(pair)
+	assert.Nil(t, rb.Resolved(pair, failedResolution()))
+	assert.Equal(t, 0, sm.WriteCount)
+}
+
+func TestRollbacker_Resolved_noKnownGood(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+
+	assert.Nil(t, rb.Resolved(pair, failedResolution()))
+	assert.Equal(t, 0, sm.WriteCount)
+}
+
+func TestRollbacker_Resolved_fromHistory(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	h := NewMemoryDeploymentHistory()
+	did := pair.ID()
+	require.NoError(t, h.Record(
+		DeploymentHistoryEntry{DeploymentID: did, DeploySpec: DeploySpec{Version: semv.MustParse("0.9.0")}, Outcome: OutcomeSucceeded},
+		DeploymentHistoryEntry{DeploymentID: did, DeploySpec: DeploySpec{Version: semv.MustParse("1.0.0")}, Outcome: OutcomeFailed},
+		DeploymentHistoryEntry{DeploymentID: did, DeploySpec: DeploySpec{Version: semv.MustParse("2.0.0")}, Outcome: OutcomeSucceeded},
+	))
+	// A new Rollbacker, as after a restart, has observed nothing.
+	rb := NewRollbacker(sm, h, logging.SilentLogSet())
+
+	ev := rb.Resolved(pair, failedResolution())
+	require.NotNil(t, ev)
+	assert.Equal(t, "0.9.0", ev.To.Version.String())
+	assert.Equal(t, 1, sm.WriteCount)
+}
+
+func TestRollbacker_Resolved_gdmChanged(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+	rb.Observe(pair)
+
+	m, _ := sm.State.Manifests.Get(pair.ID().ManifestID)
+	m.Deployments["cluster-1"] = DeploySpec{Version: semv.MustParse("3.0.0")}
+
+	assert.Nil(t, rb.Resolved(pair, failedResolution()))
+	assert.Equal(t, 0, sm.WriteCount)
+}
+
+func TestRollbacker_Resolved_remembersSuccess(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+
+	rb.Resolved(pair, DiffResolution{DeployState: &DeployState{Status: DeployStatusActive}})
+	good, ok := rb.lastGood(pair.ID(), pair.Prior.SourceID)
+	assert.True(t, ok)
+	assert.Equal(t, "2.0.0", good.Version.String())
+}
+
+func TestRollbacker_nil(t *testing.T) {
+	var rb *Rollbacker
+	_, pair := rollbackFixture(true)
+	rb.Observe(pair)
+	assert.Nil(t, rb.Resolved(pair, failedResolution()))
+	assert.Nil(t, rb.Events())
+}
+
+func TestRollbacker_IsRollback(t *testing.T) {
+	sm, pair := rollbackFixture(true)
+	rb := NewRollbacker(sm, nil, logging.SilentLogSet())
+	rb.Observe(pair)
+	assert.False(t, rb.IsRollback(pair))
+
+	ev := rb.Resolved(pair, failedResolution())
+	require.NotNil(t, ev)
+	assert.False(t, rb.IsRollback(pair), "the failed version is not a rollback")
+
+	back := &DeployablePair{Prior: pair.Post, Post: pair.Prior}
+	back.SetID(pair.ID())
+	assert.True(t, rb.IsRollback(back))
+
+	rb.Resolved(back, DiffResolution{Desc: ModifyDiff, DeployState: &DeployState{Status: DeployStatusActive}})
+	assert.False(t, rb.IsRollback(back), "rollbacks are only exempt until rectified")
+}
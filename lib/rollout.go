@@ -0,0 +1,155 @@
+package sous
+
+import "fmt"
+
+type (
+	// RolloutStrategy names a way of moving a deployment from one version to
+	// the next.
+	RolloutStrategy string
+
+	// Rollout describes how a new version of a deployment is rolled out. It
+	// is a property of the intended deployment only; schedulers do not report
+	// it, so it is not considered when comparing DeployConfigs.
+	Rollout struct {
+		// Strategy is the strategy to use. The zero value replaces all
+		// instances at once.
+		Strategy RolloutStrategy `yaml:",omitempty"`
+		// CanaryInstances is the number of instances run in the canary stage of
+		// a canary rollout. Defaults to 1.
+		CanaryInstances int `yaml:",omitempty"`
+		// Steps are percentages of NumInstances to promote to, in increasing
+		// order, once the canary is healthy. The final promotion to
+		// NumInstances is implied.
+		Steps []int `yaml:",omitempty"`
+		// StageDelay is the number of seconds a stage must stay healthy before
+		// the rollout moves on to the next stage.
+		StageDelay int `yaml:",omitempty"`
+		// AutoRollback, when true, returns the deployment to its last
+		// known-good version in the GDM if a deploy fails. It can also be
+		// enabled for a whole cluster, see Cluster.AutoRollback.
+		AutoRollback bool `yaml:",omitempty"`
+	}
+
+	// RolloutStage is a single stage of a staged rollout. During each stage the
+	// new version runs with NumInstances instances. Until the last stage they
+	// run in a canary deployment, alongside the prior version.
+	RolloutStage struct {
+		Name         string
+		NumInstances int
+		Status       RolloutStageStatus
+		// Message explains a failed or aborted stage.
+		Message string `json:",omitempty"`
+	}
+
+	// RolloutStageStatus is the progress of a single RolloutStage.
+	RolloutStageStatus string
+)
+
+const (
+	// RolloutAllAtOnce replaces every instance of a deployment in one step.
+	RolloutAllAtOnce RolloutStrategy = ""
+	// RolloutCanary first runs the new version on a few canary instances, and
+	// promotes it in steps once they are healthy.
+	RolloutCanary RolloutStrategy = "canary"
+)
+
+const (
+	// StagePending stages have not started yet.
+	StagePending RolloutStageStatus = "pending"
+	// StageRunning stages are being deployed or watched.
+	StageRunning RolloutStageStatus = "running"
+	// StageSucceeded stages deployed and stayed healthy.
+	StageSucceeded RolloutStageStatus = "succeeded"
+	// StageFailed stages did not become healthy.
+	StageFailed RolloutStageStatus = "failed"
+	// StageAborted stages were stopped by a user.
+	StageAborted RolloutStageStatus = "aborted"
+	// StageSkipped stages were never run because an earlier stage failed or
+	// was aborted.
+	StageSkipped RolloutStageStatus = "skipped"
+)
+
+// canarySuffix is added to the flavors, and any Singularity request IDs, of
+// canary deployments.
+const canarySuffix = "canary"
+
+// CanaryFlavor returns the flavor of the canary deployment of a deployment of
+// flavor, in which the stages of a canary rollout before the last run.
+func CanaryFlavor(flavor string) string {
+	if flavor == "" {
+		return canarySuffix
+	}
+	return flavor + "-" + canarySuffix
+}
+
+// RevertStageName is the name of the stage added to a rollout when it reverts
+// to the previous deployment.
+const RevertStageName = "revert"
+
+// Validate implements Flawed on Rollout.
+func (r *Rollout) Validate() []Flaw {
+	var flaws []Flaw
+	switch r.Strategy {
+	default:
+		flaws = append(flaws, FatalFlaw("Rollout Strategy must be empty or %q, was %q.", RolloutCanary, r.Strategy))
+	case RolloutAllAtOnce, RolloutCanary:
+	}
+	if r.CanaryInstances < 0 {
+		flaws = append(flaws, FatalFlaw("Rollout CanaryInstances less than zero: %d!", r.CanaryInstances))
+	}
+	if r.StageDelay < 0 {
+		flaws = append(flaws, FatalFlaw("Rollout StageDelay less than zero: %d!", r.StageDelay))
+	}
+	last := 0
+	for _, s := range r.Steps {
+		if s <= last || s > 100 {
+			flaws = append(flaws, FatalFlaw("Rollout Steps must be increasing percentages between 1 and 100, got %v.", r.Steps))
+			break
+		}
+		last = s
+	}
+	return flaws
+}
+
+// Clone returns an independent copy of r.
+func (r Rollout) Clone() Rollout {
+	if r.Steps != nil {
+		r.Steps = append([]int{}, r.Steps...)
+	}
+	return r
+}
+
+func (r Rollout) isZero() bool {
+	return r.Strategy == RolloutAllAtOnce && r.CanaryInstances == 0 &&
+		len(r.Steps) == 0 && r.StageDelay == 0 && !r.AutoRollback
+}
+
+// Stages returns the stages needed to roll out a deployment of numInstances
+// instances. It returns nil when the rollout happens in a single step.
+func (r Rollout) Stages(numInstances int) []RolloutStage {
+	if r.Strategy != RolloutCanary {
+		return nil
+	}
+	canaries := r.CanaryInstances
+	if canaries == 0 {
+		canaries = 1
+	}
+	if canaries >= numInstances {
+		return nil
+	}
+	stages := []RolloutStage{{Name: "canary", NumInstances: canaries, Status: StagePending}}
+	last := canaries
+	for i, pct := range r.Steps {
+		n := (numInstances*pct + 99) / 100
+		if n <= last || n >= numInstances {
+			continue
+		}
+		stages = append(stages, RolloutStage{
+			Name:         fmt.Sprintf("step %d", i+1),
+			NumInstances: n,
+			Status:       StagePending,
+		})
+		last = n
+	}
+	return append(stages, RolloutStage{Name: "full", NumInstances: numInstances, Status: StagePending})
+}
@@ -0,0 +1,45 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollout_Stages(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		rollout Rollout
+		num     int
+		want    []int
+	}{
+		{desc: "all at once", rollout: Rollout{}, num: 10},
+		{desc: "too small to stage", rollout: Rollout{Strategy: RolloutCanary, CanaryInstances: 2}, num: 2},
+		{desc: "canary only", rollout: Rollout{Strategy: RolloutCanary}, num: 4, want: []int{1, 4}},
+		{
+			desc:    "steps",
+			rollout: Rollout{Strategy: RolloutCanary, CanaryInstances: 2, Steps: []int{10, 25, 50, 100}},
+			num:     10,
+			want:    []int{2, 3, 5, 10},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			stages := tc.rollout.Stages(tc.num)
+			var got []int
+			for _, s := range stages {
+				assert.Equal(t, StagePending, s.Status)
+				got = append(got, s.NumInstances)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRollout_Validate(t *testing.T) {
+	good := Rollout{Strategy: RolloutCanary, CanaryInstances: 1, Steps: []int{20, 50}}
+	assert.Len(t, good.Validate(), 0)
+
+	bad := Rollout{Strategy: "blue-green", CanaryInstances: -1, Steps: []int{50, 20}, StageDelay: -5}
+	assert.Len(t, bad.Validate(), 4)
+}
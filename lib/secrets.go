@@ -0,0 +1,178 @@
+package sous
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A SecretRef refers to a secret in a SecretStore. In an Env value, it is
+	// written secret://<path>#<key>, and the secret is only read when the
+	// deployment is sent to its cluster, so that it never appears in the GDM.
+	SecretRef struct {
+		// Path names a set of secrets in the store.
+		Path string
+		// Key names a single secret at Path.
+		Key string
+	}
+
+	// A SecretStore holds the secrets referred to by deployments.
+	SecretStore interface {
+		// Secret returns the value of the secret ref refers to, or an error if
+		// there is no such secret.
+		Secret(ref SecretRef) (string, error)
+	}
+
+	// FileSecretStore is a SecretStore which reads secrets from JSON files in
+	// a directory. The secrets at a Path are the string values of the object in
+	// the file at that path, relative to Dir. It is meant for local testing.
+	FileSecretStore struct {
+		Dir string
+	}
+)
+
+// SecretRefScheme prefixes Env values which are SecretRefs.
+const SecretRefScheme = "secret://"
+
+// ParseSecretRef parses an Env value, returning its SecretRef, and whether
+// it was one. It returns an error if the value looks like a reference but
+// does not name both a path and a key.
+func ParseSecretRef(value string) (SecretRef, bool, error) {
+	if !strings.HasPrefix(value, SecretRefScheme) {
+		return SecretRef{}, false, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, SecretRefScheme), "#", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return SecretRef{}, true, errors.Errorf("secret reference %q must be %s<path>#<key>", value, SecretRefScheme)
+	}
+	return SecretRef{Path: parts[0], Key: parts[1]}, true, nil
+}
+
+func (ref SecretRef) String() string {
+	return SecretRefScheme + ref.Path + "#" + ref.Key
+}
+
+// SecretRefs returns the SecretRefs in e, by variable name.
+func (e Env) SecretRefs() (map[string]SecretRef, error) {
+	refs := map[string]SecretRef{}
+	for _, name := range e.sortedNames() {
+		ref, is, err := ParseSecretRef(e[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "env %s", name)
+		}
+		if is {
+			refs[name] = ref
+		}
+	}
+	return refs, nil
+}
+
+func (e Env) sortedNames() []string {
+	names := make([]string, 0, len(e))
+	for n := range e {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckSecrets returns an error unless every SecretRef in e refers to a
+// secret in store.
+func CheckSecrets(store SecretStore, e Env) error {
+	_, _, err := ResolveSecrets(store, e)
+	return err
+}
+
+// ResolveSecrets returns a copy of e with each SecretRef replaced by the
+// secret it refers to, and the references that were replaced, by variable
+// name. If e contains no references it is returned as it is, and store may be
+// nil.
+func ResolveSecrets(store SecretStore, e Env) (Env, map[string]string, error) {
+	refs, err := e.SecretRefs()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(refs) == 0 {
+		return e, nil, nil
+	}
+	resolved := e.Clone()
+	replaced := map[string]string{}
+	for _, name := range e.sortedNames() {
+		ref, ok := refs[name]
+		if !ok {
+			continue
+		}
+		if store == nil {
+			return nil, nil, errors.Errorf("env %s refers to a secret, but no secret store is configured", name)
+		}
+		v, err := store.Secret(ref)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "env %s", name)
+		}
+		resolved[name] = v
+		replaced[name] = ref.String()
+	}
+	return resolved, replaced, nil
+}
+
+// RedactSecrets returns a copy of e with the variables named in refs set
+// back to the references they were resolved from.
+func RedactSecrets(e Env, refs map[string]string) Env {
+	if len(refs) == 0 {
+		return e
+	}
+	redacted := e.Clone()
+	for name, ref := range refs {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = ref
+		}
+	}
+	return redacted
+}
+
+// SecretEnvRefs returns the references recorded, under SecretEnvLabel, in the
+// metadata of a deploy for the secret variables in its env.
+func SecretEnvRefs(metadata, env map[string]string) map[string]string {
+	refs := map[string]string{}
+	if j, ok := metadata[SecretEnvLabel]; ok {
+		if err := json.Unmarshal([]byte(j), &refs); err != nil {
+			// Redact every variable, rather than risk revealing one.
+			refs = map[string]string{}
+			for name := range env {
+				refs[name] = "<redacted>"
+			}
+		}
+	}
+	return refs
+}
+
+// NewFileSecretStore returns a FileSecretStore reading secrets from dir.
+func NewFileSecretStore(dir string) *FileSecretStore {
+	return &FileSecretStore{Dir: dir}
+}
+
+// Secret implements SecretStore on FileSecretStore.
+func (fs *FileSecretStore) Secret(ref SecretRef) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(ref.Path))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("secret path %q is outside the store", ref.Path)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(fs.Dir, rel))
+	if err != nil {
+		return "", errors.Wrapf(err, "reading secrets at %q", ref.Path)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		return "", errors.Wrapf(err, "parsing secrets at %q", ref.Path)
+	}
+	v, ok := secrets[ref.Key]
+	if !ok {
+		return "", errors.Errorf("no secret %q at %q", ref.Key, ref.Path)
+	}
+	return v, nil
+}
@@ -0,0 +1,70 @@
+package sous
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	ref, is, err := ParseSecretRef("secret://db/prod#password")
+	assert.NoError(t, err)
+	assert.True(t, is)
+	assert.Equal(t, SecretRef{Path: "db/prod", Key: "password"}, ref)
+	assert.Equal(t, "secret://db/prod#password", ref.String())
+
+	_, is, err = ParseSecretRef("plain value")
+	assert.NoError(t, err)
+	assert.False(t, is)
+
+	for _, bad := range []string{"secret://", "secret://db", "secret://#key", "secret://db#"} {
+		_, is, err = ParseSecretRef(bad)
+		assert.True(t, is, bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "db"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "db", "prod"), []byte(`{"password":"hunter2"}`), 0600))
+	store := NewFileSecretStore(dir)
+
+	env := Env{"DB_PASSWORD": "secret://db/prod#password", "DB_USER": "app"}
+	resolved, refs, err := ResolveSecrets(store, env)
+	require.NoError(t, err)
+	assert.Equal(t, Env{"DB_PASSWORD": "hunter2", "DB_USER": "app"}, resolved)
+	assert.Equal(t, map[string]string{"DB_PASSWORD": "secret://db/prod#password"}, refs)
+	assert.Equal(t, "secret://db/prod#password", env["DB_PASSWORD"], "env is not changed")
+	assert.Equal(t, env, RedactSecrets(resolved, refs))
+
+	plain := Env{"DB_USER": "app"}
+	resolved, refs, err = ResolveSecrets(nil, plain)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, resolved)
+	assert.Empty(t, refs)
+
+	_, _, err = ResolveSecrets(nil, env)
+	assert.Error(t, err, "no store")
+
+	for _, ref := range []string{"secret://db/prod#missing", "secret://db/none#password", "secret://../db/prod#password"} {
+		assert.Error(t, CheckSecrets(store, Env{"X": ref}), ref)
+	}
+}
+
+func TestDeployConfig_Validate_secretRefs(t *testing.T) {
+	dc := DeployConfig{
+		Resources: Resources{"cpus": "0.1", "memory": "100", "ports": "1"},
+		Startup:   Startup{CheckReadyProtocol: "HTTP"},
+		Env:       Env{"DB_PASSWORD": "secret://db"},
+	}
+	assert.Len(t, dc.Validate(), 1)
+	dc.Env["DB_PASSWORD"] = "secret://db#password"
+	assert.Empty(t, dc.Validate())
+}
@@ -0,0 +1,188 @@
+package sous
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
+)
+
+type (
+	// A Signature signs the image digest of a BuildArtifact, together with the
+	// SourceID it was built from.
+	Signature struct {
+		// KeyID identifies the public key which verifies the signature: see
+		// SigningKeyID.
+		KeyID string
+		// Value is the base64 encoded ASN.1 ECDSA signature of the SHA-256 hash
+		// of the signed content: see signedContent.
+		Value string
+	}
+
+	// A Signer signs the digests of images built from SourceIDs.
+	Signer interface {
+		Sign(sid SourceID, digest string) (Signature, error)
+	}
+
+	// KeySigner is a Signer which signs with an ECDSA private key.
+	KeySigner struct {
+		key   *ecdsa.PrivateKey
+		keyID string
+	}
+
+	// ecdsaSignature is the ASN.1 form of an ECDSA signature.
+	ecdsaSignature struct {
+		R, S *big.Int
+	}
+)
+
+// NewKeySigner returns a KeySigner which signs with the PEM encoded ECDSA
+// private key in pemKey, in either SEC 1 ("EC PRIVATE KEY") or PKCS #8
+// ("PRIVATE KEY") form.
+func NewKeySigner(pemKey []byte) (*KeySigner, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		pk, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if pkcs8Err != nil {
+			return nil, errors.Wrap(err, "parsing signing key")
+		}
+		var ok bool
+		if key, ok = pk.(*ecdsa.PrivateKey); !ok {
+			return nil, errors.Errorf("signing key is a %T, not an ECDSA key", pk)
+		}
+	}
+	id, err := SigningKeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: key, keyID: id}, nil
+}
+
+// LoadKeySigner returns a KeySigner for the private key in the file at path.
+func LoadKeySigner(path string) (*KeySigner, error) {
+	pemKey, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewKeySigner(pemKey)
+	return s, errors.Wrapf(err, "loading signing key %s", path)
+}
+
+// Sign implements Signer on KeySigner.
+func (s *KeySigner) Sign(sid SourceID, digest string) (Signature, error) {
+	hash := sha256.Sum256([]byte(signedContent(sid, digest)))
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, hash[:])
+	if err != nil {
+		return Signature{}, errors.Wrapf(err, "signing %s of %s", digest, sid)
+	}
+	sig, err := asn1.Marshal(ecdsaSignature{R: r, S: ss})
+	if err != nil {
+		return Signature{}, err
+	}
+	return Signature{KeyID: s.keyID, Value: base64.StdEncoding.EncodeToString(sig)}, nil
+}
+
+// signedContent returns what a Signature of the image with digest built from
+// sid signs. Including the source means a signature cannot be replayed for
+// another SourceID whose build produced the same image. Build metadata is left
+// out of the version, since deployments do not carry it.
+func signedContent(sid SourceID, digest string) string {
+	return fmt.Sprintf("%s,%s,%s@%s", sid.Location.Repo, sid.Version.Format(semv.MMPPre), sid.Location.Dir, digest)
+}
+
+// SigningKeyID returns the ID of a public key: the first 16 hex digits of the
+// SHA-256 hash of its DER encoding.
+func SigningKeyID(pub *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return hex.EncodeToString(hash[:8]), nil
+}
+
+// ParseSigningKeys parses PEM encoded ECDSA public keys, returning them by
+// their IDs.
+func ParseSigningKeys(pemKeys []string) (map[string]*ecdsa.PublicKey, error) {
+	keys := make(map[string]*ecdsa.PublicKey, len(pemKeys))
+	for i, pk := range pemKeys {
+		block, _ := pem.Decode([]byte(pk))
+		if block == nil {
+			return nil, errors.Errorf("signing key %d is not PEM encoded", i)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "signing key %d", i)
+		}
+		key, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.Errorf("signing key %d is a %T, not an ECDSA key", i, pub)
+		}
+		id, err := SigningKeyID(key)
+		if err != nil {
+			return nil, err
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// ArtifactDigest returns the digest of the image a BuildArtifact refers to,
+// which is what its Signatures sign.
+func (ba BuildArtifact) ArtifactDigest() (string, error) {
+	i := strings.LastIndex(ba.DigestReference, "@")
+	if i < 0 {
+		return "", errors.Errorf("artifact %q is not referred to by digest", ba.DigestReference)
+	}
+	return ba.DigestReference[i+1:], nil
+}
+
+// VerifySignatures returns nil if ba has a valid signature, for sid, by one of
+// pemKeys, the PEM encoded ECDSA public keys trusted to sign artifacts.
+func (ba BuildArtifact) VerifySignatures(sid SourceID, pemKeys []string) error {
+	keys, err := ParseSigningKeys(pemKeys)
+	if err != nil {
+		return err
+	}
+	digest, err := ba.ArtifactDigest()
+	if err != nil {
+		return err
+	}
+	if len(ba.Signatures) == 0 {
+		return errors.New("it is not signed")
+	}
+	hash := sha256.Sum256([]byte(signedContent(sid, digest)))
+	for _, sig := range ba.Signatures {
+		key, trusted := keys[sig.KeyID]
+		if !trusted {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(sig.Value)
+		if err != nil {
+			continue
+		}
+		var es ecdsaSignature
+		if _, err := asn1.Unmarshal(value, &es); err != nil {
+			continue
+		}
+		if ecdsa.Verify(key, hash[:], es.R, es.S) {
+			return nil
+		}
+	}
+	return errors.New("it has no valid signature by a trusted key")
+}
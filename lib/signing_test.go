@@ -0,0 +1,79 @@
+package sous
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSigningKeys returns a new PEM encoded ECDSA keypair.
+func testSigningKeys(t *testing.T) (private []byte, public string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+}
+
+// testSignedSourceID is the SourceID testSignedArtifact is signed for.
+var testSignedSourceID = MustParseSourceID(`github.com/ot/one,1.3.5`)
+
+// testSignedArtifact returns an artifact built from testSignedSourceID,
+// signed by signer.
+func testSignedArtifact(t *testing.T, signer Signer) *BuildArtifact {
+	ba := &BuildArtifact{
+		Type:            "docker",
+		VersionName:     "ot-docker/one:0.1",
+		DigestReference: "ot-docker/one@sha256:cabba9e",
+	}
+	sig, err := signer.Sign(testSignedSourceID, "sha256:cabba9e")
+	require.NoError(t, err)
+	ba.Signatures = []Signature{sig}
+	return ba
+}
+
+func TestVerifySignatures(t *testing.T) {
+	private, public := testSigningKeys(t)
+	_, otherPublic := testSigningKeys(t)
+	signer, err := NewKeySigner(private)
+	require.NoError(t, err)
+
+	sid := testSignedSourceID
+	ba := testSignedArtifact(t, signer)
+	assert.NoError(t, ba.VerifySignatures(sid, []string{otherPublic, public}))
+	assert.EqualError(t, ba.VerifySignatures(sid, []string{otherPublic}), "it has no valid signature by a trusted key")
+
+	tampered := *ba
+	tampered.DigestReference = "ot-docker/one@sha256:deadbeef"
+	assert.Error(t, tampered.VerifySignatures(sid, []string{public}))
+
+	// The signature is only good for the source it was made for.
+	assert.Error(t, ba.VerifySignatures(MustParseSourceID(`github.com/ot/one,1.3.6`), []string{public}))
+	assert.Error(t, ba.VerifySignatures(MustParseSourceID(`github.com/ot/two,1.3.5`), []string{public}))
+	assert.NoError(t, ba.VerifySignatures(MustParseSourceID(`github.com/ot/one,1.3.5+build`), []string{public}),
+		"build metadata is not signed")
+
+	unsigned := *ba
+	unsigned.Signatures = nil
+	assert.EqualError(t, unsigned.VerifySignatures(sid, []string{public}), "it is not signed")
+
+	assert.Error(t, ba.VerifySignatures(sid, []string{"not a key"}))
+}
+
+func TestNewKeySigner_invalid(t *testing.T) {
+	_, err := NewKeySigner([]byte("not a key"))
+	assert.Error(t, err)
+
+	_, public := testSigningKeys(t)
+	_, err = NewKeySigner([]byte(public))
+	assert.Error(t, err)
+}
@@ -23,3 +23,17 @@ func (e *SourceHostChooser) ParseSourceLocation(s string) (SourceLocation, error
 	}
 	return SourceLocation{}, fmt.Errorf("source location not recognised: %q", s)
 }
+
+// GetSource gets the source code for id from the first SourceHost that owns
+// id.Location.
+//
+// It returns an error if none of the SourceHosts own id.Location, or if the
+// owning SourceHost fails to get the source.
+func (e *SourceHostChooser) GetSource(id SourceID) (Source, error) {
+	for _, h := range e.SourceHosts {
+		if h.Owns(id.Location) {
+			return h.GetSource(id)
+		}
+	}
+	return Source{}, fmt.Errorf("no source host owns %q", id.Location)
+}
@@ -1,6 +1,7 @@
 package sous
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -33,3 +34,39 @@ func TestSourceHostChooser_ParseSourceLocation_genericHost(t *testing.T) {
 		t.Errorf("got:\n%#v; want:\n%#v", actual, expected)
 	}
 }
+
+type testSourceHost struct {
+	GenericHost
+	prefix string
+}
+
+func (h testSourceHost) Owns(sl SourceLocation) bool {
+	return strings.HasPrefix(sl.Repo, h.prefix)
+}
+
+func (h testSourceHost) GetSource(id SourceID) (Source, error) {
+	return Source{ID: id, LocalRootDir: h.prefix}, nil
+}
+
+func TestSourceHostChooser_GetSource(t *testing.T) {
+	e := &SourceHostChooser{
+		SourceHosts: []SourceHost{
+			testSourceHost{prefix: "gitlab.example.com/"},
+			testSourceHost{prefix: "bitbucket.example.com/"},
+		},
+	}
+	id := MustParseSourceID("bitbucket.example.com/PROJ/repo,0.1.0")
+	src, err := e.GetSource(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.LocalRootDir != "bitbucket.example.com/" {
+		t.Errorf("got source from %q; want it from bitbucket.example.com/", src.LocalRootDir)
+	}
+
+	_, err = e.GetSource(MustParseSourceID("github.com/user/repo,0.1.0"))
+	expected := `no source host owns "github.com/user/repo"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("got error %v; want %q", err, expected)
+	}
+}
@@ -168,6 +168,10 @@ func sourceIDFromChunks(source string, chunks []string) (SourceID, error) {
 	if len(chunks) > 2 {
 		repoOffset = chunks[2]
 	}
+	repoURL, repoOffset, err = splitOffset(source, repoURL, repoOffset)
+	if err != nil {
+		return SourceID{}, err
+	}
 	return SourceID{
 		Location: SourceLocation{
 			Dir:  repoOffset,
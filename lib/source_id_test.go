@@ -68,6 +68,17 @@ func TestParseSourceID_success(t *testing.T) {
 	}
 }
 
+func TestParseSourceID_offsetSeparator(t *testing.T) {
+	actual, err := ParseSourceID("gitlab.example.com/group/project/-/svc,1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MustNewSourceID("gitlab.example.com/group/project", "svc", "1.2.3")
+	if actual != expected {
+		t.Errorf("got %v; want %v", actual, expected)
+	}
+}
+
 func TestNewSourceID_success(t *testing.T) {
 	for _, sid := range parseSourceIDTests {
 		actual, err := NewSourceID(sid.Location.Repo, sid.Location.Dir, sid.Version.String())
@@ -87,6 +98,7 @@ func TestParseSourceID_errs(t *testing.T) {
 		{"", `invalid source ID ""`},
 		{"x", `parsing: No version found in "x" (did find repo: "x")`},
 		{"x,y", `invalid version "y": unexpected character 'y' at position 0`},
+		{"x/-/a,1,b", `"x/-/a,1,b" has two offset directories`},
 	}
 	for _, tc := range cases {
 		t.Run(tc.in, func(t *testing.T) {
@@ -28,6 +28,25 @@ func (sl *SourceLocation) EachField(f logging.FieldReportFn) {
 	f("sous-source-location-dir", sl.Dir)
 }
 
+// OffsetSeparator ends the project path in GitLab URLs, as in
+// gitlab.example.com/group/project/-/tree/master. It is accepted in place of
+// the delimiter before the offset directory of a SourceLocation.
+const OffsetSeparator = "/-/"
+
+// splitOffset returns repo up to OffsetSeparator, and the offset directory
+// after it, if it contains one. Otherwise it returns repo and dir. source is
+// what they were parsed from.
+func splitOffset(source, repo, dir string) (string, string, error) {
+	i := strings.Index(repo, OffsetSeparator)
+	if i == -1 {
+		return repo, dir, nil
+	}
+	if dir != "" {
+		return "", "", errors.Errorf("%q has two offset directories", source)
+	}
+	return repo[:i], strings.Trim(repo[i+len(OffsetSeparator):], "/"), nil
+}
+
 // NewSourceLocation creates a new SourceLocation from strings.
 func NewSourceLocation(repoURL, repoOffset string) SourceLocation {
 	return SourceLocation{repoURL, repoOffset}
@@ -61,6 +80,10 @@ func sourceLocationFromChunks(source string, chunks []string) (SourceLocation, e
 	if len(chunks) > 1 {
 		repoOffset = chunks[1]
 	}
+	repoURL, repoOffset, err := splitOffset(source, repoURL, repoOffset)
+	if err != nil {
+		return SourceLocation{}, err
+	}
 	return SourceLocation{Repo: repoURL, Dir: repoOffset}, nil
 }
 
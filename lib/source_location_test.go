@@ -21,6 +21,10 @@ var parseSourceLocationTests = map[string]SourceLocation{
 		Repo: "git+ssh://github.com/opentable/sous",
 		Dir:  "sous",
 	},
+	"gitlab.example.com/group/sub-group/project/-/some/offset": {
+		Repo: "gitlab.example.com/group/sub-group/project",
+		Dir:  "some/offset",
+	},
 }
 
 func TestParseSourceLocation(t *testing.T) {
@@ -35,14 +35,36 @@ type (
 		Resources FieldDefinitions
 		// Metadata contains the definitions for metadata fields
 		Metadata FieldDefinitions
+		// PromotionOrder lists clusters in the order versions are promoted
+		// through them, e.g. [ci, qa, prod]. A new version can only be
+		// deployed to a cluster in the list once it is running in the cluster
+		// before it.
+		PromotionOrder []string `yaml:",omitempty"`
+		// Templates are named DeployConfigs which manifests and their
+		// deployments inherit by naming them as their Template, so that
+		// defaults shared by many services are written once.
+		Templates DeployConfigs `yaml:",omitempty"`
+		// ClusterGroups names groups of Clusters, which manifests can deploy
+		// to together, and `sous deploy -cluster-group` can deploy to at
+		// once.
+		ClusterGroups ClusterGroups `yaml:",omitempty"`
 	}
 
 	// EnvDefs is a collection of EnvDef
 	EnvDefs []EnvDef
 	// EnvDef is an environment variable definition.
 	EnvDef struct {
-		Name, Desc, Scope string
-		Type              VarType
+		Name, Desc string
+		// Scope is where the variable is set: one of the EnvScope constants.
+		Scope string
+		// Type is the type of the variable's values.
+		Type VarType
+		// Required, when true, means every deployment must have a value for
+		// the variable, either from its cluster's Env or its manifest.
+		Required bool `yaml:",omitempty"`
+		// Default is the value the variable is given where it is required, or
+		// cluster scoped, but not set.
+		Default Var `yaml:",omitempty"`
 	}
 
 	// FieldDefinitions is just a type alias for a slice of FieldDefinition-s
@@ -52,7 +74,8 @@ type (
 	FieldDefinition struct {
 		Name string
 		// Type is the type of value used to represent quantities or instances
-		// of this resource, e.g. MemorySize, Float, or Int (not yet implemented).
+		// of this resource, e.g. MemorySize, Float, or Int. The types of
+		// Metadata fields are checked: see VarType.
 		Type VarType
 
 		// Default adds a GDM wide default for a key.
@@ -71,8 +94,8 @@ type (
 	Cluster struct {
 		// Name is the unique name of this cluster.
 		Name string
-		// Kind is the kind of cluster. Currently the only legal value is
-		// "singularity"
+		// Kind is the kind of cluster. Legal values are "singularity" (the
+		// default when empty) and "kubernetes".
 		Kind string
 		// BaseURL is the main entrypoint URL for interacting with this cluster.
 		BaseURL string
@@ -83,15 +106,33 @@ type (
 		// AllowedAdvisories lists the artifact advisories which are permissible in
 		// this cluster
 		AllowedAdvisories []string
+		// AutoRollback, when true, returns every deployment in this cluster to
+		// its last known-good version when a deploy fails.
+		AutoRollback bool `yaml:",omitempty"`
+		// FreezeWindows are periods during which deployments to this cluster
+		// are refused.
+		FreezeWindows FreezeWindows `yaml:",omitempty"`
+		// SigningKeys are the PEM encoded ECDSA public keys trusted to sign
+		// artifacts. When any are set, artifacts are only deployed to this
+		// cluster if they have a valid signature by one of them.
+		SigningKeys []string `yaml:",omitempty"`
+		// VulnerabilityThresholds are the most vulnerabilities of each
+		// severity that artifacts deployed to this cluster may have, e.g.
+		// {critical: 0}. When any are set, artifacts which have not been
+		// scanned are not deployed either.
+		VulnerabilityThresholds VulnerabilityThresholds `yaml:",omitempty"`
+		// ResourceLimits are the least and most of each resource deployments
+		// to this cluster may have, e.g. {memory: {Max: 16Gi}}.
+		ResourceLimits ResourceLimits `yaml:",omitempty"`
 	}
 
 	// EnvDefaults is a list of named environment variables along with their values.
 	EnvDefaults map[string]Var
 	// Var is a strongly typed string for use in environment variables and YAML
-	// files. It will implement sane YAML marshalling and unmarshalling. (Not
-	// yet implemented.)
+	// files.
 	Var string
-	// VarType represents the type of a Var (not yet implemented).
+	// VarType represents the type of a Var, e.g. "int" or "enum:a,b,c": see
+	// the VarType constants.
 	VarType string
 )
 
@@ -138,6 +179,17 @@ func (d Defs) Clone() Defs {
 	d.EnvVars = d.EnvVars.Clone()
 	d.Resources = d.Resources.Clone()
 	d.Metadata = d.Metadata.Clone()
+	if d.PromotionOrder != nil {
+		d.PromotionOrder = append([]string(nil), d.PromotionOrder...)
+	}
+	d.ClusterGroups = d.ClusterGroups.Clone()
+	if d.Templates != nil {
+		templates := make(DeployConfigs, len(d.Templates))
+		for name, dc := range d.Templates {
+			templates[name] = dc.Clone()
+		}
+		d.Templates = templates
+	}
 	return d
 }
 
@@ -167,6 +219,14 @@ func (c Cluster) Clone() *Cluster {
 	allowedAdvisories := make([]string, len(c.AllowedAdvisories))
 	copy(allowedAdvisories, c.AllowedAdvisories)
 	c.AllowedAdvisories = allowedAdvisories
+	c.FreezeWindows = c.FreezeWindows.Clone()
+	c.VulnerabilityThresholds = c.VulnerabilityThresholds.Clone()
+	c.ResourceLimits = c.ResourceLimits.Clone()
+	if c.SigningKeys != nil {
+		signingKeys := make([]string, len(c.SigningKeys))
+		copy(signingKeys, c.SigningKeys)
+		c.SigningKeys = signingKeys
+	}
 	return &c
 }
 
@@ -206,15 +266,20 @@ func (s *State) BaseURLs() []string {
 
 // Validate implements Flawed for State
 func (s *State) Validate() []Flaw {
-	var flaws []Flaw
+	flaws := s.Defs.Validate()
 
 	for _, m := range s.Manifests.Snapshot() {
 		flaws = append(flaws, m.Validate()...)
+		flaws = append(flaws, s.Defs.ValidateManifest(m)...)
 	}
 
 	ds, err := s.Deployments()
 	if err != nil {
-		return []Flaw{FatalFlaw("Cannot merge a set of deployments to validate: %v", err)}
+		flaws = append(flaws, FatalFlaw("Cannot merge a set of deployments to validate: %v", err))
+		for _, f := range flaws {
+			f.AddContext("state", s)
+		}
+		return flaws
 	}
 	for _, depl := range ds.Snapshot() {
 		flaws = append(flaws, depl.Validate()...)
@@ -18,6 +18,12 @@ type (
 		StateWriter
 	}
 
+	// priorStateWriter is a StateWriter which can be given the state a write
+	// replaces, so that wrappers which each compare the two share one read.
+	priorStateWriter interface {
+		writeStateFrom(prior, state *State, user User) error
+	}
+
 	// DummyStateManager is used for testing
 	DummyStateManager struct {
 		*State
@@ -37,6 +43,15 @@ type (
 	}
 )
 
+// writeStateFrom writes state to sw, passing on prior, the state it replaces,
+// if it is known and sw can use it.
+func writeStateFrom(sw StateWriter, prior, state *State, user User) error {
+	if pw, ok := sw.(priorStateWriter); ok && prior != nil {
+		return pw.writeStateFrom(prior, state, user)
+	}
+	return sw.WriteState(state, user)
+}
+
 // NewDummyStateManager returns a dummy StateManager, suitable for testing.
 func NewDummyStateManager() *DummyStateManager {
 	return &DummyStateManager{State: NewState()}
@@ -14,7 +14,10 @@ type (
 	StatusPoller struct {
 		restful.HTTPClient
 		*ResolveFilter
-		User            User
+		User User
+		// Clusters, if not empty, limits polling to the named clusters, e.g.
+		// the members of a cluster group, among those ResolveFilter matches.
+		Clusters        []string
 		statePerCluster map[string]*pollerState
 		status          ResolveState
 		logs            logging.LogSink
@@ -55,6 +58,9 @@ type (
 		// For 1.0 this field should go away.
 		Deployments           []*Deployment
 		Completed, InProgress *ResolveStatus
+		// Version is 0 from servers which cannot wait for the status to
+		// change.
+		Version uint64
 	}
 
 	pollResult struct {
@@ -135,7 +141,7 @@ func (sp *StatusPoller) subPollers(clusters *ServerListData, deps Deployments) (
 	subs := []*subPoller{}
 	for _, s := range clusters.Servers {
 		// skip clusters the user isn't interested in
-		if !sp.ResolveFilter.FilterClusterName(s.ClusterName) {
+		if !sp.ResolveFilter.FilterClusterName(s.ClusterName) || !sp.pollsCluster(s.ClusterName) {
 			messages.ReportLogFieldsMessage("Not rquested for polling", logging.ExtraDebug1Level, sp.logs, s.ClusterName)
 			continue
 		}
@@ -157,6 +163,18 @@ func (sp *StatusPoller) subPollers(clusters *ServerListData, deps Deployments) (
 	return subs, nil
 }
 
+func (sp *StatusPoller) pollsCluster(name string) bool {
+	if len(sp.Clusters) == 0 {
+		return true
+	}
+	for _, c := range sp.Clusters {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 // poll collects updates from each "sub" poller; once they've all crossed the
 // TERMINAL threshold, return the "maximum" state reached. We hope for ResolveComplete.
 func (sp *StatusPoller) poll(subs []*subPoller) ResolveState {
@@ -974,3 +974,33 @@ func TestStatusPoller_OldServer(t *testing.T) {
 		}
 	}
 }
+
+func TestSubPoller_waitsForChanges(t *testing.T) {
+	var queries []string
+	fail := false
+	h := func(rw http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if fail {
+			rw.WriteHeader(500)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(`{"Version": 3}`))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(h))
+	defer srv.Close()
+
+	sub, err := newSubPoller("main", srv.URL, &ResolveFilter{}, User{}, logging.SilentLogSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.pollOnce()
+	sub.pollOnce()
+	fail = true
+	sub.pollOnce()
+	fail = false
+	sub.pollOnce()
+
+	assert.Equal(t, []string{"", "since=3", "since=3", ""}, queries,
+		"should wait for changes after the server reports a version, until it errs")
+}
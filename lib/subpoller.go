@@ -3,6 +3,7 @@ package sous
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"time"
 
 	"github.com/opentable/sous/util/logging"
@@ -18,6 +19,9 @@ type (
 		User                     User
 		httpErrorCount           int
 		logs                     logging.LogSink
+		// since is the Version of the status last received, or 0 if the
+		// server cannot wait for it to change.
+		since uint64
 	}
 )
 
@@ -46,8 +50,9 @@ func newSubPoller(clusterName, serverURL string, baseFilter *ResolveFilter, user
 	}, nil
 }
 
-// start issues a new /status request every half second, reporting the state as computed.
-// c.f. pollOnce.
+// start issues /status requests, reporting the state as computed. c.f.
+// pollOnce. Servers which can wait for the status to change are asked to, and
+// asked again as soon as they answer; others are asked every half second.
 func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	rs <- pollResult{url: sub.URL, stat: ResolveNotPolled}
 	pollResult := sub.pollOnce()
@@ -55,13 +60,21 @@ func (sub *subPoller) start(rs chan pollResult, done chan struct{}) {
 	ticker := time.NewTicker(PollTimeout)
 	defer ticker.Stop()
 	for {
-		select {
-		case <-ticker.C:
-			latest := sub.pollOnce()
-			rs <- latest
-		case <-done:
-			return
+		if sub.since == 0 {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		} else {
+			select {
+			case <-done:
+				return
+			default:
+			}
 		}
+		latest := sub.pollOnce()
+		rs <- latest
 	}
 }
 
@@ -75,7 +88,12 @@ func (sub *subPoller) result(rs ResolveState, data *statusData, err error) pollR
 
 func (sub *subPoller) pollOnce() pollResult {
 	data := &statusData{}
-	if _, err := sub.Retrieve("./status", nil, data, sub.User.HTTPHeaders()); err != nil {
+	var query map[string]string
+	if sub.since != 0 {
+		query = map[string]string{"since": strconv.FormatUint(sub.since, 10)}
+	}
+	sub.since = 0
+	if _, err := sub.Retrieve("./status", query, data, sub.User.HTTPHeaders()); err != nil {
 		reportDebugSubPollerMessage(fmt.Sprintf("%s: error on GET /status: %s", sub.ClusterName, errors.Cause(err)), sub.logs)
 		reportDebugSubPollerMessage(fmt.Sprintf("%s: %T %+v", sub.ClusterName, errors.Cause(err), err), sub.logs)
 		sub.httpErrorCount++
@@ -89,6 +107,7 @@ func (sub *subPoller) pollOnce() pollResult {
 		return sub.result(ResolveErredHTTP, data, err)
 	}
 	sub.httpErrorCount = 0
+	sub.since = data.Version
 
 	// This serves to maintain backwards compatibility.
 	// XXX One day, remove it.
@@ -0,0 +1,122 @@
+package sous
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// templateNames returns the names of the templates in Defs.Templates which a
+// deployment inherits from, in order of precedence: that named by its
+// DeploySpec, then that named by its Manifest.
+func templateNames(specTemplate, manifestTemplate string) []string {
+	var names []string
+	if specTemplate != "" {
+		names = append(names, specTemplate)
+	}
+	if manifestTemplate != "" && manifestTemplate != specTemplate {
+		names = append(names, manifestTemplate)
+	}
+	return names
+}
+
+// templateConfigs returns the DeployConfigs of the templates with names, in
+// the same order.
+func (d Defs) templateConfigs(names []string) ([]DeployConfig, error) {
+	dcs := make([]DeployConfig, 0, len(names))
+	for _, name := range names {
+		dc, ok := d.Templates[name]
+		if !ok {
+			return nil, errors.Errorf("template %q is not described in defs.yaml", name)
+		}
+		dcs = append(dcs, dc)
+	}
+	return dcs, nil
+}
+
+// inherited returns the config a deployment to cluster inherits from the
+// templates with names, then from cluster itself, and then from the defaults
+// of the values d requires.
+func (d Defs) inherited(cluster *Cluster, names []string) (DeployConfig, error) {
+	dcs, err := d.templateConfigs(names)
+	if err != nil {
+		return DeployConfig{}, err
+	}
+	dc := flattenDeployConfigs(dcs)
+	dc.Startup = cluster.Startup.MergeDefaults(dc.Startup)
+	for name, val := range cluster.Env {
+		if _, set := dc.Env[name]; !set {
+			dc.Env[name] = string(val)
+		}
+	}
+	d.applyDefaults(&dc)
+	return dc, nil
+}
+
+// UnmergeClusterSpec removes from spec, which is to replace old as m's spec
+// for cluster, the values a deployment would inherit anyway from its
+// templates, the cluster or the defaults in d, unless old set them
+// explicitly.
+func (d Defs) UnmergeClusterSpec(m *Manifest, cluster string, spec *DeploySpec, old DeploySpec) error {
+	inherited, err := d.inherited(d.Clusters[cluster], templateNames(spec.Template, m.Template))
+	if err != nil {
+		return errors.Wrapf(err, "manifest %q deployment to %s", m.ID(), cluster)
+	}
+	spec.unmergeInherited(inherited, old)
+	unmergeMap(spec.Env, inherited.Env, old.Env)
+	return nil
+}
+
+// unmergeInherited removes from spec the values it would inherit anyway from
+// inherited, unless they were also set by old, the spec it replaces, so that
+// what was written explicitly stays explicit.
+func (spec *DeploySpec) unmergeInherited(inherited DeployConfig, old DeploySpec) {
+	spec.Startup = inherited.Startup.UnmergeDefaults(spec.Startup, old.Startup)
+
+	unmergeMap(spec.Resources, inherited.Resources, old.Resources)
+	unmergeMap(spec.Metadata, inherited.Metadata, old.Metadata)
+
+	if inherited.NumInstances != 0 && spec.NumInstances == inherited.NumInstances && old.NumInstances == 0 {
+		spec.NumInstances = 0
+	}
+	if len(inherited.Volumes) != 0 && spec.Volumes.Equal(inherited.Volumes) && len(old.Volumes) == 0 {
+		spec.Volumes = nil
+	}
+	if inherited.Schedule != "" && spec.Schedule == inherited.Schedule && old.Schedule == "" {
+		spec.Schedule = ""
+	}
+	if !inherited.Rollout.isZero() && reflect.DeepEqual(spec.Rollout, inherited.Rollout) && old.Rollout.isZero() {
+		spec.Rollout = Rollout{}
+	}
+}
+
+func unmergeMap(values, inherited, old map[string]string) {
+	for k, v := range values {
+		if iv, has := inherited[k]; !has || iv != v {
+			continue
+		}
+		if _, explicit := old[k]; !explicit {
+			delete(values, k)
+		}
+	}
+}
+
+// ExpandManifest returns a copy of m whose deployments each have the full
+// config they inherit from their templates and clusters in defs, and name no
+// templates.
+func ExpandManifest(defs Defs, m *Manifest) (*Manifest, error) {
+	ds, err := DeploymentsFromManifest(defs, m)
+	if err != nil {
+		return nil, err
+	}
+	expanded := m.Clone()
+	expanded.Template = ""
+	expanded.Deployments = DeploySpecs{}
+	for _, d := range ds.Snapshot() {
+		expanded.Deployments[d.ClusterName] = DeploySpec{
+			Version:      d.SourceID.Version,
+			DeployConfig: d.DeployConfig.Clone(),
+		}
+	}
+	return expanded, nil
+}
@@ -0,0 +1,120 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func templatesTestState() *State {
+	m := serviceManifestFixture("github.com/user/project", DeploySpecs{
+		"cluster-1": deploySpecFixture("1.0.0", DeployConfig{
+			Env:     Env{"B": "own"},
+			Startup: Startup{Timeout: 20},
+		}),
+	})
+	m.Template = "base"
+	spec := m.Deployments["cluster-1"]
+	spec.Template = "large"
+	m.Deployments["cluster-1"] = spec
+	return defsFixtureState(
+		Defs{
+			Clusters: Clusters{
+				"cluster-1": &Cluster{
+					Name:    "cluster-1",
+					Env:     EnvDefaults{"A": "cluster", "C": "cluster"},
+					Startup: Startup{Timeout: 10},
+				},
+			},
+			Templates: DeployConfigs{
+				"base": {
+					NumInstances: 2,
+					Env:          Env{"A": "base", "B": "base"},
+					Resources:    Resources{"cpus": "1"},
+					Startup:      Startup{ConnectDelay: 5},
+				},
+				"large": {
+					Env:       Env{"A": "large"},
+					Resources: Resources{"cpus": "4", "memory": "4096"},
+				},
+			},
+		},
+		m,
+	)
+}
+
+func TestDeploymentsFromManifest_templates(t *testing.T) {
+	s := templatesTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+
+	ds, err := DeploymentsFromManifest(s.Defs, m)
+	require.NoError(t, err)
+	d, ok := ds.Any(func(*Deployment) bool { return true })
+	require.True(t, ok)
+
+	assert.Equal(t, 2, d.NumInstances)
+	assert.Equal(t, Env{"A": "large", "B": "own", "C": "cluster"}, d.Env)
+	assert.Equal(t, Resources{"cpus": "4", "memory": "4096"}, d.Resources)
+	assert.Equal(t, 20, d.Startup.Timeout)
+	assert.Equal(t, 5, d.Startup.ConnectDelay)
+}
+
+func TestDeploymentsFromManifest_unknownTemplate(t *testing.T) {
+	s := templatesTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+	m.Template = "missing"
+
+	_, err := DeploymentsFromManifest(s.Defs, m)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `template "missing" is not described in defs.yaml`)
+}
+
+func TestDeployments_PutbackManifestTemplates(t *testing.T) {
+	s := templatesTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+
+	ls, _ := logging.NewLogSinkSpy()
+	ms, err := ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, ok := ms.Get(m.ID())
+	require.True(t, ok)
+	if different, diffs := m.Diff(put); different {
+		t.Errorf("manifest changed by round trip: %v", diffs)
+	}
+
+	// Values changed from those inherited are kept.
+	d, _ := ds.Any(func(*Deployment) bool { return true })
+	d.NumInstances = 3
+	d.Resources["cpus"] = "8"
+	ms, err = ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ = ms.Get(m.ID())
+	spec := put.Deployments["cluster-1"]
+	assert.Equal(t, "large", spec.Template)
+	assert.Equal(t, 3, spec.NumInstances)
+	assert.Equal(t, Resources{"cpus": "8"}, spec.Resources)
+	assert.Equal(t, Env{"B": "own"}, spec.Env)
+}
+
+func TestExpandManifest(t *testing.T) {
+	s := templatesTestState()
+	m, _ := s.Manifests.Any(func(*Manifest) bool { return true })
+
+	expanded, err := ExpandManifest(s.Defs, m)
+	require.NoError(t, err)
+	assert.Equal(t, "", expanded.Template)
+	spec := expanded.Deployments["cluster-1"]
+	assert.Equal(t, "", spec.Template)
+	assert.Equal(t, 2, spec.NumInstances)
+	assert.Equal(t, Env{"A": "large", "B": "own", "C": "cluster"}, spec.Env)
+	assert.Equal(t, "1.0.0", spec.Version.String())
+
+	// m itself is unchanged.
+	assert.Equal(t, "base", m.Template)
+	assert.Equal(t, Env{"B": "own"}, m.Deployments["cluster-1"].Env)
+}
@@ -0,0 +1,344 @@
+package sous
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The scopes of an EnvDef, which say where its variable may be set.
+const (
+	// EnvScopeAny variables may be set by clusters and manifests alike.
+	EnvScopeAny = ""
+	// EnvScopeCluster variables must be set by every cluster, and may be
+	// overridden by manifests.
+	EnvScopeCluster = "cluster"
+	// EnvScopeManifest variables may only be set by manifests.
+	EnvScopeManifest = "manifest"
+)
+
+// Validate returns the flaws in d's definitions of Env vars, Resources and
+// Metadata fields, in the Env vars and ResourceLimits its clusters set, and in
+// its ClusterGroups. A
+// cluster missing a cluster-scoped variable is repaired by giving it the
+// variable's Default.
+func (d Defs) Validate() []Flaw {
+	var flaws []Flaw
+
+	for _, ed := range d.EnvVars {
+		ed := ed
+		if err := ed.Type.Validate(); err != nil {
+			flaws = append(flaws, FatalFlaw("Env %s: %v", ed.Name, err))
+			continue
+		}
+		if ed.Default != "" {
+			if err := ed.Type.Check(string(ed.Default)); err != nil {
+				flaws = append(flaws, FatalFlaw("Env %s: default %v", ed.Name, err))
+				continue
+			}
+		}
+		switch ed.Scope {
+		default:
+			flaws = append(flaws, FatalFlaw("Env %s: unknown scope %q", ed.Name, ed.Scope))
+		case EnvScopeAny:
+		case EnvScopeCluster:
+			for _, name := range d.Clusters.Names() {
+				cluster := d.Clusters[name]
+				if _, has := cluster.Env[ed.Name]; has {
+					continue
+				}
+				if ed.Default == "" {
+					flaws = append(flaws, FatalFlaw("cluster %s: Env %s is cluster scoped, but not set, and has no default", name, ed.Name))
+					continue
+				}
+				flaws = append(flaws, NewFlaw(
+					fmt.Sprintf("cluster %s: Env %s is cluster scoped, but not set", name, ed.Name),
+					func() error {
+						if cluster.Env == nil {
+							cluster.Env = EnvDefaults{}
+						}
+						cluster.Env[ed.Name] = ed.Default
+						return nil
+					}))
+			}
+		case EnvScopeManifest:
+			for _, name := range d.Clusters.Names() {
+				if _, has := d.Clusters[name].Env[ed.Name]; has {
+					flaws = append(flaws, FatalFlaw("cluster %s: Env %s may only be set by manifests", name, ed.Name))
+				}
+			}
+		}
+	}
+
+	for _, name := range d.Clusters.Names() {
+		for _, n := range sortedVarNames(d.Clusters[name].Env) {
+			ed, has := d.EnvVars.Get(n)
+			if !has || ed.Type.Validate() != nil || isSecretRef(string(d.Clusters[name].Env[n])) {
+				continue
+			}
+			if err := ed.Type.Check(string(d.Clusters[name].Env[n])); err != nil {
+				flaws = append(flaws, FatalFlaw("cluster %s: Env %s: %v", name, n, err))
+			}
+		}
+	}
+
+	for _, fd := range d.Resources {
+		if err := ValidateResourceType(fd.Type); err != nil {
+			flaws = append(flaws, FatalFlaw("Resources %s: %v", fd.Name, err))
+			continue
+		}
+		if fd.Default != "" {
+			if _, err := ParseResource(fd.Type, fd.Default); err != nil {
+				flaws = append(flaws, FatalFlaw("Resources %s: default %v", fd.Name, err))
+			}
+		}
+	}
+
+	flaws = append(flaws, d.ClusterGroups.validate(d.Clusters)...)
+
+	for _, name := range d.Clusters.Names() {
+		if err := d.Clusters[name].ResourceLimits.Validate(d.Resources); err != nil {
+			flaws = append(flaws, FatalFlaw("cluster %s: %v", name, err))
+		}
+	}
+
+	for _, fd := range d.Metadata {
+		if err := fd.Type.Validate(); err != nil {
+			flaws = append(flaws, FatalFlaw("Metadata %s: %v", fd.Name, err))
+			continue
+		}
+		if fd.Default != "" {
+			if err := fd.Type.Check(fd.Default); err != nil {
+				flaws = append(flaws, FatalFlaw("Metadata %s: default %v", fd.Name, err))
+			}
+		}
+	}
+
+	return flaws
+}
+
+// ValidateManifest returns the flaws in the Env vars, Metadata fields and
+// Resources set by m, or the templates it names, given their definitions in
+// d, and the ResourceLimits of the clusters m deploys to, including those it
+// deploys to as members of cluster groups, and the clusters m's Dependencies
+// are limited to. A deployment missing a required value is repaired by
+// setting the value's Default in m; values of the wrong type, or outside
+// their limits, are not repairable. Env values which are secret references
+// are not checked against their types. Deployments to clusters d does not
+// define are left for DeploymentsFromManifest to reject.
+func (d Defs) ValidateManifest(m *Manifest) []Flaw {
+	var flaws []Flaw
+
+	keys := d.clusterKeys(m)
+	clusterNames := make([]string, 0, len(keys))
+	for name := range keys {
+		clusterNames = append(clusterNames, name)
+	}
+	sort.Strings(clusterNames)
+
+	for _, group := range m.Deployments.ClusterNames() {
+		if !d.isGroup(group) {
+			continue
+		}
+		for _, member := range d.ClusterGroups[group] {
+			if key := keys[member]; key != member && key != group {
+				flaws = append(flaws, FatalFlaw("manifest %q: cluster %s is in both groups %s and %s, so needs a deployment of its own", m.ID(), member, key, group))
+			}
+		}
+	}
+
+	for _, dep := range m.Dependencies {
+		for _, name := range dep.Clusters {
+			if _, isCluster := d.Clusters[name]; !isCluster && !d.isGroup(name) {
+				flaws = append(flaws, FatalFlaw("manifest %q: dependency on %q: cluster %q is not defined", m.ID(), dep.Manifest, name))
+			}
+		}
+	}
+
+	for _, clusterName := range clusterNames {
+		cluster, ok := d.Clusters[clusterName]
+		if !ok {
+			continue
+		}
+		key := keys[clusterName]
+		where := fmt.Sprintf("manifest %q deployment to %s", m.ID(), clusterName)
+		if key != clusterName {
+			where = fmt.Sprintf("manifest %q deployment to %s (of group %s)", m.ID(), clusterName, key)
+		}
+		// Values inherited from templates count as set by the deployment.
+		templates, err := d.templateConfigs(templateNames(m.Deployments[key].Template, m.Template))
+		if err != nil {
+			flaws = append(flaws, FatalFlaw("%s: %v", where, err))
+			continue
+		}
+		spec := flattenDeployConfigs(append([]DeployConfig{m.Deployments[key].DeployConfig}, templates...))
+
+		for _, ed := range d.EnvVars {
+			if ed.Type.Validate() != nil {
+				continue
+			}
+			if v, has := spec.Env[ed.Name]; has {
+				if isSecretRef(v) {
+					continue
+				}
+				if err := ed.Type.Check(v); err != nil {
+					flaws = append(flaws, FatalFlaw("%s: Env %s: %v", where, ed.Name, err))
+				}
+				continue
+			}
+			if _, has := cluster.Env[ed.Name]; has || !ed.Required {
+				continue
+			}
+			flaws = append(flaws, missingVarFlaw(m, key, where, "Env", ed.Name, string(ed.Default)))
+		}
+
+		for _, fd := range d.Metadata {
+			if fd.Type.Validate() != nil {
+				continue
+			}
+			if v, has := spec.Metadata[fd.Name]; has {
+				if err := fd.Type.Check(v); err != nil {
+					flaws = append(flaws, FatalFlaw("%s: Metadata %s: %v", where, fd.Name, err))
+				}
+				continue
+			}
+			if fd.Optional {
+				continue
+			}
+			flaws = append(flaws, missingVarFlaw(m, key, where, "Metadata", fd.Name, fd.Default))
+		}
+
+		for _, fd := range d.Resources {
+			if ValidateResourceType(fd.Type) != nil {
+				continue
+			}
+			if v, has := spec.Resources[fd.Name]; has {
+				if _, err := ParseResource(fd.Type, v); err != nil {
+					flaws = append(flaws, FatalFlaw("%s: Resources %s: %v", where, fd.Name, err))
+				}
+				continue
+			}
+			// Resources without defaults are defined only so that their
+			// values are checked; the builtin ones are given their defaults
+			// by Resources.Validate.
+			if fd.Optional || fd.Default == "" {
+				continue
+			}
+			flaws = append(flaws, missingVarFlaw(m, key, where, "Resources", fd.Name, fd.Default))
+		}
+
+		for _, name := range cluster.ResourceLimits.names() {
+			v, has := spec.Resources[name]
+			if !has {
+				continue
+			}
+			fd, defined := resourceDefinition(d.Resources, name)
+			if !defined || ValidateResourceType(fd.Type) != nil {
+				continue
+			}
+			if _, err := ParseResource(fd.Type, v); err != nil {
+				// Reported above for resources defined in d.
+				continue
+			}
+			if err := cluster.ResourceLimits[name].Check(fd.Type, v); err != nil {
+				flaws = append(flaws, FatalFlaw("%s: Resources %s: %v", where, name, err))
+			}
+		}
+	}
+
+	for _, f := range flaws {
+		f.AddContext("manifest", m)
+	}
+	return flaws
+}
+
+// missingVarFlaw returns the flaw of the deployment of m with key lacking the
+// required value of the Env var, Metadata field or Resource name, which is
+// repaired by setting it to def, unless def is empty.
+func missingVarFlaw(m *Manifest, key, where, field, name, def string) Flaw {
+	if def == "" {
+		return FatalFlaw("%s: %s %s is required, and has no default", where, field, name)
+	}
+	return NewFlaw(
+		fmt.Sprintf("%s: %s %s is required", where, field, name),
+		func() error {
+			spec := m.Deployments[key]
+			switch field {
+			case "Env":
+				if spec.Env == nil {
+					spec.Env = Env{}
+				}
+				spec.Env[name] = def
+			case "Metadata":
+				if spec.Metadata == nil {
+					spec.Metadata = Metadata{}
+				}
+				spec.Metadata[name] = def
+			case "Resources":
+				if spec.Resources == nil {
+					spec.Resources = Resources{}
+				}
+				spec.Resources[name] = def
+			}
+			m.Deployments[key] = spec
+			return nil
+		})
+}
+
+// applyDefaults sets the Default of each Env var, Metadata field and Resource
+// which d defines as required, but which dc lacks.
+func (d Defs) applyDefaults(dc *DeployConfig) {
+	for _, ed := range d.EnvVars {
+		if _, has := dc.Env[ed.Name]; has || !ed.Required || ed.Default == "" {
+			continue
+		}
+		if dc.Env == nil {
+			dc.Env = Env{}
+		}
+		dc.Env[ed.Name] = string(ed.Default)
+	}
+	for _, fd := range d.Metadata {
+		if _, has := dc.Metadata[fd.Name]; has || fd.Optional || fd.Default == "" {
+			continue
+		}
+		if dc.Metadata == nil {
+			dc.Metadata = Metadata{}
+		}
+		dc.Metadata[fd.Name] = fd.Default
+	}
+	for _, fd := range d.Resources {
+		if _, has := dc.Resources[fd.Name]; has || fd.Optional || fd.Default == "" {
+			continue
+		}
+		if dc.Resources == nil {
+			dc.Resources = Resources{}
+		}
+		dc.Resources[fd.Name] = fd.Default
+	}
+}
+
+// isSecretRef returns true if the Env value v refers to a secret, whose value
+// can't be checked until the deployment is sent to its cluster.
+func isSecretRef(v string) bool {
+	_, is, _ := ParseSecretRef(v)
+	return is
+}
+
+// Get returns the definition of the Env var called name, and false if there
+// is none.
+func (evs EnvDefs) Get(name string) (EnvDef, bool) {
+	for _, ed := range evs {
+		if ed.Name == name {
+			return ed, true
+		}
+	}
+	return EnvDef{}, false
+}
+
+func sortedVarNames(env EnvDefaults) []string {
+	names := make([]string, 0, len(env))
+	for n := range env {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
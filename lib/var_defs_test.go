@@ -0,0 +1,240 @@
+package sous
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func varDefsTestState() *State {
+	return defsFixtureState(
+		Defs{
+			Clusters: Clusters{
+				"cluster-1": &Cluster{Name: "cluster-1", Env: EnvDefaults{"REGION": "west"}},
+			},
+			EnvVars: EnvDefs{
+				{Name: "REGION", Scope: EnvScopeCluster, Type: "enum:east,west"},
+				{Name: "PORT", Type: "int", Required: true, Default: "8080"},
+				{Name: "TIMEOUT", Type: "duration"},
+			},
+			Metadata: FieldDefinitions{
+				{Name: "team", Type: "regex:[a-z]+"},
+				{Name: "tier", Type: "int", Default: "3"},
+				{Name: "docs", Type: "url", Optional: true},
+			},
+		},
+		serviceManifestFixture("github.com/user/project", DeploySpecs{
+			"cluster-1": deploySpecFixture("1.0.0", DeployConfig{
+				Env:      Env{"TIMEOUT": "30s"},
+				Metadata: Metadata{"team": "owners"},
+			}),
+		}),
+	)
+}
+
+func flawStrings(flaws []Flaw) []string {
+	strs := []string{}
+	for _, f := range flaws {
+		strs = append(strs, fmt.Sprint(f))
+	}
+	return strs
+}
+
+func TestDefs_ValidateManifest_defaults(t *testing.T) {
+	s := varDefsTestState()
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+
+	flaws := s.Defs.ValidateManifest(m)
+	assert.Equal(t, []string{
+		`manifest "github.com/user/project" deployment to cluster-1: Env PORT is required`,
+		`manifest "github.com/user/project" deployment to cluster-1: Metadata tier is required`,
+	}, flawStrings(flaws))
+
+	ds, err := DeploymentsFromManifest(s.Defs, m)
+	require.NoError(t, err)
+	for _, d := range ds.Snapshot() {
+		assert.Equal(t, "8080", d.Env["PORT"])
+		assert.Equal(t, "west", d.Env["REGION"])
+		assert.Equal(t, "3", d.Metadata["tier"])
+	}
+	assert.NotContains(t, m.Deployments["cluster-1"].Env, "PORT", "manifest changed")
+
+	unrepaired, errs := RepairAll(flaws)
+	assert.Empty(t, unrepaired)
+	assert.Empty(t, errs)
+	assert.Equal(t, "8080", m.Deployments["cluster-1"].Env["PORT"])
+	assert.Equal(t, "3", m.Deployments["cluster-1"].Metadata["tier"])
+	assert.Empty(t, s.Defs.ValidateManifest(m))
+}
+
+func TestDefs_ValidateManifest_invalid(t *testing.T) {
+	s := varDefsTestState()
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+	spec := m.Deployments["cluster-1"]
+	spec.Env["PORT"] = "banana"
+	spec.Env["REGION"] = "north"
+	spec.Metadata["tier"] = "1"
+	spec.Metadata["team"] = "Owners"
+	spec.Metadata["docs"] = "nowhere"
+
+	invalid := []string{
+		`manifest "github.com/user/project" deployment to cluster-1: Env REGION: "north" is not one of east,west`,
+		`manifest "github.com/user/project" deployment to cluster-1: Env PORT: "banana" is not an int`,
+		`manifest "github.com/user/project" deployment to cluster-1: Metadata team: "Owners" does not match [a-z]+`,
+		`manifest "github.com/user/project" deployment to cluster-1: Metadata docs: "nowhere" is not an absolute URL`,
+	}
+	assert.Equal(t, invalid, flawStrings(s.Defs.ValidateManifest(m)))
+
+	// The manifest is rejected on write, but doesn't stop the deployments of
+	// the state being read.
+	_, err := s.Deployments()
+	assert.NoError(t, err)
+
+	unrepaired, _ := RepairAll(s.Validate())
+	assert.Subset(t, flawStrings(unrepaired), invalid)
+}
+
+func TestDefs_ValidateManifest_secretRefs(t *testing.T) {
+	s := varDefsTestState()
+	s.Defs.Clusters["cluster-1"].Env["PORT"] = "secret://cluster#port"
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+	m.Deployments["cluster-1"].Env["TIMEOUT"] = "secret://project#timeout"
+	m.Deployments["cluster-1"].Metadata["tier"] = "1"
+
+	assert.Empty(t, s.Defs.Validate())
+	assert.Empty(t, s.Defs.ValidateManifest(m))
+}
+
+func TestDefs_ValidateManifest_requiredWithoutDefault(t *testing.T) {
+	s := varDefsTestState()
+	s.Defs.Metadata[1].Default = ""
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+
+	unrepaired, errs := RepairAll(s.Defs.ValidateManifest(m))
+	assert.Equal(t, []string{
+		`manifest "github.com/user/project" deployment to cluster-1: Metadata tier is required, and has no default`,
+	}, flawStrings(unrepaired))
+	assert.Len(t, errs, 1)
+}
+
+func TestDefs_Validate(t *testing.T) {
+	s := varDefsTestState()
+	assert.Empty(t, s.Defs.Validate())
+
+	s.Defs.Clusters["cluster-2"] = &Cluster{Name: "cluster-2"}
+	s.Defs.Clusters["cluster-3"] = &Cluster{Name: "cluster-3", Env: EnvDefaults{"REGION": "south", "PORT": "80"}}
+	s.Defs.EnvVars = append(s.Defs.EnvVars,
+		EnvDef{Name: "PORT0", Type: "float"},
+		EnvDef{Name: "SIDECAR", Scope: "global"},
+	)
+	s.Defs.EnvVars[1].Scope = EnvScopeManifest
+	s.Defs.Metadata[1].Default = "three"
+
+	assert.Equal(t, []string{
+		`cluster cluster-2: Env REGION is cluster scoped, but not set, and has no default`,
+		`cluster cluster-3: Env PORT may only be set by manifests`,
+		`Env PORT0: unknown type "float"`,
+		`Env SIDECAR: unknown scope "global"`,
+		`cluster cluster-3: Env REGION: "south" is not one of east,west`,
+		`Metadata tier: default "three" is not an int`,
+	}, flawStrings(s.Defs.Validate()))
+}
+
+func TestDefs_Validate_clusterScopeDefault(t *testing.T) {
+	s := varDefsTestState()
+	s.Defs.EnvVars[0].Default = "east"
+	s.Defs.Clusters["cluster-2"] = &Cluster{Name: "cluster-2"}
+
+	flaws := s.Defs.Validate()
+	require.Len(t, flaws, 1)
+	require.NoError(t, flaws[0].Repair())
+	assert.Equal(t, Var("east"), s.Defs.Clusters["cluster-2"].Env["REGION"])
+}
+
+func TestDefs_ValidateManifest_resources(t *testing.T) {
+	s := varDefsTestState()
+	s.Defs.Resources = FieldDefinitions{
+		{Name: "memory", Type: ResourceTypeMemorySize},
+		{Name: "ephemeral-storage", Type: ResourceTypeMemorySize, Default: "1Gi"},
+		{Name: "network-mbps", Type: ResourceTypeFloat, Optional: true},
+		// A legacy definition, without a default, which no manifest sets.
+		{Name: "cpu", Type: "Float"},
+	}
+	s.Defs.Clusters["cluster-1"].ResourceLimits = ResourceLimits{"memory": {Max: "4Gi"}}
+	assert.Empty(t, s.Defs.Validate())
+
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+	spec := m.Deployments["cluster-1"]
+	spec.Env["PORT"] = "80"
+	spec.Metadata["tier"] = "1"
+	spec.Resources = Resources{"memory": "8Gi", "network-mbps": "fast"}
+	m.Deployments["cluster-1"] = spec
+
+	assert.Equal(t, []string{
+		`manifest "github.com/user/project" deployment to cluster-1: Resources ephemeral-storage is required`,
+		`manifest "github.com/user/project" deployment to cluster-1: Resources network-mbps: "fast" is not a number`,
+		`manifest "github.com/user/project" deployment to cluster-1: Resources memory: 8Gi is more than the maximum 4Gi`,
+	}, flawStrings(s.Defs.ValidateManifest(m)))
+
+	spec.Resources = Resources{"memory": "2Gi"}
+	m.Deployments["cluster-1"] = spec
+	ds, err := DeploymentsFromManifest(s.Defs, m)
+	require.NoError(t, err)
+	for _, d := range ds.Snapshot() {
+		assert.Equal(t, "1Gi", d.Resources["ephemeral-storage"])
+	}
+
+	s.Defs.Resources[0].Type = "bandwidth"
+	s.Defs.Clusters["cluster-1"].ResourceLimits["gpus"] = ResourceLimit{Max: "1"}
+	assert.Equal(t, []string{
+		`Resources memory: unknown resource type "bandwidth"`,
+		`cluster cluster-1: limit on undefined resource "gpus"`,
+	}, flawStrings(s.Defs.Validate()))
+}
+
+func TestDeployments_PutbackManifests_defaults(t *testing.T) {
+	s := varDefsTestState()
+	mid := ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}}
+	m, _ := s.Manifests.Get(mid)
+
+	ds, err := s.Deployments()
+	require.NoError(t, err)
+	d, _ := ds.Any(func(*Deployment) bool { return true })
+	assert.Equal(t, "8080", d.Env["PORT"])
+	assert.Equal(t, "3", d.Metadata["tier"])
+
+	ls, _ := logging.NewLogSinkSpy()
+	ms, err := ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ := ms.Get(mid)
+	if different, diffs := m.Diff(put); different {
+		t.Errorf("manifest changed by round trip: %v", diffs)
+	}
+
+	// Defaults the manifest set explicitly stay explicit.
+	m.Deployments["cluster-1"].Env["PORT"] = "8080"
+	ds, err = s.Deployments()
+	require.NoError(t, err)
+	ms, err = ds.PutbackManifests(s.Defs, s.Manifests, ls)
+	require.NoError(t, err)
+	put, _ = ms.Get(mid)
+	assert.Equal(t, Env{"TIMEOUT": "30s", "PORT": "8080"}, put.Deployments["cluster-1"].Env)
+	assert.Equal(t, Metadata{"team": "owners"}, put.Deployments["cluster-1"].Metadata)
+}
+
+func TestDefs_UnmergeClusterSpec(t *testing.T) {
+	s := varDefsTestState()
+	m, _ := s.Manifests.Get(ManifestID{Source: SourceLocation{Repo: "github.com/user/project"}})
+	old := m.Deployments["cluster-1"]
+
+	spec := old.Clone()
+	spec.Env["PORT"] = "8080"
+	spec.Env["REGION"] = "west"
+	spec.Metadata["tier"] = "2"
+	require.NoError(t, s.Defs.UnmergeClusterSpec(m, "cluster-1", &spec, old))
+	assert.Equal(t, Env{"TIMEOUT": "30s"}, spec.Env)
+	assert.Equal(t, Metadata{"team": "owners", "tier": "2"}, spec.Metadata)
+}
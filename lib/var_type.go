@@ -0,0 +1,116 @@
+package sous
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The kinds of VarType. Enums and regexes are written with an argument after
+// a colon: see VarTypeEnum and VarTypeRegex.
+const (
+	// VarTypeString is any value. An empty VarType is a string.
+	VarTypeString VarType = "string"
+	// VarTypeInt is a decimal integer, e.g. "8080".
+	VarTypeInt VarType = "int"
+	// VarTypeBool is "true" or "false", or any other value Go parses as a
+	// bool, e.g. "1".
+	VarTypeBool VarType = "bool"
+	// VarTypeURL is an absolute URL, e.g. "http://example.com/path".
+	VarTypeURL VarType = "url"
+	// VarTypeDuration is a Go duration, e.g. "1m30s".
+	VarTypeDuration VarType = "duration"
+	// VarTypeEnum is one of a list of values, written "enum:" followed by
+	// the values separated by commas, e.g. "enum:debug,info,warn".
+	VarTypeEnum VarType = "enum"
+	// VarTypeRegex is a value entirely matched by a regular expression,
+	// written "regex:" followed by the expression, e.g. "regex:[a-z]+".
+	VarTypeRegex VarType = "regex"
+)
+
+// Kind returns the kind of vt, which is one of the VarType constants unless
+// vt is invalid, and the argument written after it, if any. Kinds are not
+// case sensitive, so "Int" is an int.
+func (vt VarType) Kind() (VarType, string) {
+	kind, arg := string(vt), ""
+	if i := strings.Index(kind, ":"); i >= 0 {
+		kind, arg = kind[:i], kind[i+1:]
+	}
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind == "" {
+		return VarTypeString, arg
+	}
+	return VarType(kind), arg
+}
+
+// Validate returns an error if vt is not a type values can be checked
+// against.
+func (vt VarType) Validate() error {
+	kind, arg := vt.Kind()
+	switch kind {
+	default:
+		return errors.Errorf("unknown type %q", string(vt))
+	case VarTypeString, VarTypeInt, VarTypeBool, VarTypeURL, VarTypeDuration:
+		if arg != "" {
+			return errors.Errorf("type %q takes no argument", string(vt))
+		}
+	case VarTypeEnum:
+		if strings.TrimSpace(arg) == "" {
+			return errors.Errorf("type %q lists no values", string(vt))
+		}
+	case VarTypeRegex:
+		if _, err := varRegexp(arg); err != nil {
+			return errors.Wrapf(err, "type %q", string(vt))
+		}
+	}
+	return nil
+}
+
+// Check returns an error describing how value is not of type vt, or nil if it
+// is.
+func (vt VarType) Check(value string) error {
+	if err := vt.Validate(); err != nil {
+		return err
+	}
+	kind, arg := vt.Kind()
+	switch kind {
+	case VarTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return errors.Errorf("%q is not an int", value)
+		}
+	case VarTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.Errorf("%q is not a bool", value)
+		}
+	case VarTypeURL:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.Errorf("%q is not an absolute URL", value)
+		}
+	case VarTypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return errors.Errorf("%q is not a duration, e.g. 1m30s", value)
+		}
+	case VarTypeEnum:
+		for _, v := range strings.Split(arg, ",") {
+			if strings.TrimSpace(v) == value {
+				return nil
+			}
+		}
+		return errors.Errorf("%q is not one of %s", value, arg)
+	case VarTypeRegex:
+		re, _ := varRegexp(arg)
+		if !re.MatchString(value) {
+			return errors.Errorf("%q does not match %s", value, arg)
+		}
+	}
+	return nil
+}
+
+// varRegexp compiles pattern to match only whole values.
+func varRegexp(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
@@ -0,0 +1,48 @@
+package sous
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarType_Check(t *testing.T) {
+	cases := []struct {
+		vt    VarType
+		value string
+		ok    bool
+	}{
+		{"", "anything", true},
+		{"string", "anything", true},
+		{"int", "8080", true},
+		{"Int", "8080", true},
+		{"int", "banana", false},
+		{"bool", "true", true},
+		{"bool", "yes", false},
+		{"url", "http://example.com/path", true},
+		{"url", "example.com", false},
+		{"duration", "1m30s", true},
+		{"duration", "90", false},
+		{"enum:debug,info, warn", "warn", true},
+		{"enum:debug,info,warn", "trace", false},
+		{"regex:[a-z]+", "abc", true},
+		{"regex:[a-z]+", "abc1", false},
+	}
+	for _, c := range cases {
+		err := c.vt.Check(c.value)
+		if c.ok {
+			assert.NoError(t, err, "%s %q", c.vt, c.value)
+		} else {
+			assert.Error(t, err, "%s %q", c.vt, c.value)
+		}
+	}
+}
+
+func TestVarType_Validate(t *testing.T) {
+	assert.NoError(t, VarType("").Validate())
+	assert.NoError(t, VarType("enum:a,b").Validate())
+	assert.Error(t, VarType("float").Validate())
+	assert.Error(t, VarType("int:8").Validate())
+	assert.Error(t, VarType("enum:").Validate())
+	assert.Error(t, VarType("regex:[a-").Validate())
+}
@@ -0,0 +1,230 @@
+package sous
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Vulnerability is a known vulnerability found in an artifact.
+	Vulnerability struct {
+		// ID identifies the vulnerability, e.g. a CVE ID.
+		ID string
+		// Severity is one of the Severities.
+		Severity string
+	}
+
+	// A VulnerabilityReport is the result of scanning an artifact.
+	VulnerabilityReport struct {
+		// Scanner names the scanner which made the report.
+		Scanner         string
+		Vulnerabilities []Vulnerability
+	}
+
+	// A VulnerabilityScanner scans artifacts for known vulnerabilities.
+	VulnerabilityScanner interface {
+		ScanArtifact(BuildArtifact) (*VulnerabilityReport, error)
+	}
+
+	// VulnerabilityThresholds are the most vulnerabilities of each severity
+	// which an artifact may have.
+	VulnerabilityThresholds map[string]int
+
+	// FileVulnerabilityScanner is a VulnerabilityScanner which reads its
+	// findings from a JSON file, mapping image digests to their
+	// vulnerabilities. Images not in the file have none. It is meant for
+	// testing.
+	FileVulnerabilityScanner struct {
+		Path string
+	}
+)
+
+// VulnerabilityKind is the Kind of the Qualities which record a
+// VulnerabilityReport. Each vulnerability is named <severity>:<id>, and the
+// scan itself scan:<scanner>.
+const VulnerabilityKind = "vulnerability"
+
+// scanQualityPrefix prefixes the name of the Quality which records a scan.
+const scanQualityPrefix = "scan:"
+
+// Severities are the severities of vulnerabilities, most severe first.
+var Severities = []string{"critical", "high", "medium", "low", "unknown"}
+
+// ValidSeverity returns an error if s is not one of the Severities.
+func ValidSeverity(s string) error {
+	for _, known := range Severities {
+		if s == known {
+			return nil
+		}
+	}
+	return errors.Errorf("unknown severity %q (known: %s)", s, strings.Join(Severities, ", "))
+}
+
+// Qualities returns the Qualities which record r on an artifact.
+func (r VulnerabilityReport) Qualities() Qualities {
+	qs := Qualities{{Name: scanQualityPrefix + r.Scanner, Kind: VulnerabilityKind}}
+	for _, v := range r.Vulnerabilities {
+		qs = append(qs, Quality{Name: v.Severity + ":" + v.ID, Kind: VulnerabilityKind})
+	}
+	return qs
+}
+
+// VulnerabilityReport returns the report recorded in qs, or nil if the
+// artifact they describe has not been scanned.
+func (qs Qualities) VulnerabilityReport() *VulnerabilityReport {
+	var r *VulnerabilityReport
+	vs := []Vulnerability{}
+	for _, q := range qs {
+		if q.Kind != VulnerabilityKind {
+			continue
+		}
+		if strings.HasPrefix(q.Name, scanQualityPrefix) {
+			r = &VulnerabilityReport{Scanner: strings.TrimPrefix(q.Name, scanQualityPrefix)}
+			continue
+		}
+		parts := strings.SplitN(q.Name, ":", 2)
+		if len(parts) != 2 {
+			parts = []string{"unknown", q.Name}
+		}
+		vs = append(vs, Vulnerability{Severity: parts[0], ID: parts[1]})
+	}
+	if r != nil {
+		r.Vulnerabilities = vs
+	}
+	return r
+}
+
+// WithoutVulnerabilities returns qs less any vulnerability report.
+func (qs Qualities) WithoutVulnerabilities() Qualities {
+	without := Qualities{}
+	for _, q := range qs {
+		if q.Kind != VulnerabilityKind {
+			without = append(without, q)
+		}
+	}
+	return without
+}
+
+// Counts returns the number of vulnerabilities of each severity in r.
+func (r VulnerabilityReport) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, v := range r.Vulnerabilities {
+		counts[v.Severity]++
+	}
+	return counts
+}
+
+// Validate returns an error if vt has unknown severities or negative
+// thresholds.
+func (vt VulnerabilityThresholds) Validate() error {
+	for _, s := range vt.severities() {
+		if err := ValidSeverity(s); err != nil {
+			return err
+		}
+		if vt[s] < 0 {
+			return errors.Errorf("threshold for %s is negative", s)
+		}
+	}
+	return nil
+}
+
+// Check returns an error if r, which is nil for an artifact which was never
+// scanned, exceeds any of vt.
+func (vt VulnerabilityThresholds) Check(r *VulnerabilityReport) error {
+	if len(vt) == 0 {
+		return nil
+	}
+	if r == nil {
+		return errors.New("it has not been scanned for vulnerabilities")
+	}
+	counts := r.Counts()
+	for _, s := range vt.severities() {
+		if counts[s] > vt[s] {
+			return errors.Errorf("it has %d %s vulnerabilities, and at most %d are allowed", counts[s], s, vt[s])
+		}
+	}
+	return nil
+}
+
+// Clone returns a copy of vt.
+func (vt VulnerabilityThresholds) Clone() VulnerabilityThresholds {
+	if vt == nil {
+		return nil
+	}
+	c := make(VulnerabilityThresholds, len(vt))
+	for s, n := range vt {
+		c[s] = n
+	}
+	return c
+}
+
+// Equal returns true if vt and other have the same thresholds.
+func (vt VulnerabilityThresholds) Equal(other VulnerabilityThresholds) bool {
+	if len(vt) != len(other) {
+		return false
+	}
+	for s, n := range vt {
+		if on, has := other[s]; !has || on != n {
+			return false
+		}
+	}
+	return true
+}
+
+func (vt VulnerabilityThresholds) severities() []string {
+	ss := make([]string, 0, len(vt))
+	for s := range vt {
+		ss = append(ss, s)
+	}
+	sort.Strings(ss)
+	return ss
+}
+
+func (r VulnerabilityReport) String() string {
+	counts := r.Counts()
+	parts := []string{}
+	for _, s := range Severities {
+		if counts[s] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], s))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("no vulnerabilities found by %s", r.Scanner)
+	}
+	return fmt.Sprintf("%s found by %s", strings.Join(parts, ", "), r.Scanner)
+}
+
+// NewFileVulnerabilityScanner returns a FileVulnerabilityScanner reading the
+// file at path.
+func NewFileVulnerabilityScanner(path string) *FileVulnerabilityScanner {
+	return &FileVulnerabilityScanner{Path: path}
+}
+
+// ScanArtifact implements VulnerabilityScanner on FileVulnerabilityScanner.
+func (fs *FileVulnerabilityScanner) ScanArtifact(ba BuildArtifact) (*VulnerabilityReport, error) {
+	digest, err := ba.ArtifactDigest()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(fs.Path)
+	if err != nil {
+		return nil, err
+	}
+	findings := map[string][]Vulnerability{}
+	if err := json.Unmarshal(b, &findings); err != nil {
+		return nil, errors.Wrapf(err, "reading vulnerabilities from %s", fs.Path)
+	}
+	vs := findings[digest]
+	for _, v := range vs {
+		if err := ValidSeverity(v.Severity); err != nil {
+			return nil, errors.Wrapf(err, "%s in %s", v.ID, fs.Path)
+		}
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i].ID < vs[j].ID })
+	return &VulnerabilityReport{Scanner: "file", Vulnerabilities: vs}, nil
+}
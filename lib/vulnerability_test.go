@@ -0,0 +1,71 @@
+package sous
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilityReportQualities(t *testing.T) {
+	r := VulnerabilityReport{Scanner: "file", Vulnerabilities: []Vulnerability{
+		{ID: "CVE-2018-0001", Severity: "critical"},
+		{ID: "CVE-2018-0002", Severity: "low"},
+	}}
+	qs := append(Qualities{{Name: "dirty workspace", Kind: "advisory"}}, r.Qualities()...)
+
+	assert.Equal(t, &r, qs.VulnerabilityReport())
+	assert.Equal(t, Qualities{{Name: "dirty workspace", Kind: "advisory"}}, qs.WithoutVulnerabilities())
+	assert.Nil(t, qs.WithoutVulnerabilities().VulnerabilityReport())
+	assert.Equal(t, "1 critical, 1 low found by file", r.String())
+	assert.Equal(t, "no vulnerabilities found by file", VulnerabilityReport{Scanner: "file"}.String())
+}
+
+func TestVulnerabilityThresholdsCheck(t *testing.T) {
+	r := &VulnerabilityReport{Scanner: "file", Vulnerabilities: []Vulnerability{
+		{ID: "CVE-2018-0001", Severity: "high"},
+		{ID: "CVE-2018-0002", Severity: "high"},
+	}}
+
+	assert.NoError(t, VulnerabilityThresholds(nil).Check(nil))
+	assert.NoError(t, VulnerabilityThresholds{"critical": 0}.Check(r))
+	assert.NoError(t, VulnerabilityThresholds{"high": 2}.Check(r))
+	assert.EqualError(t, VulnerabilityThresholds{"high": 1}.Check(r), "it has 2 high vulnerabilities, and at most 1 are allowed")
+	assert.EqualError(t, VulnerabilityThresholds{"critical": 0}.Check(nil), "it has not been scanned for vulnerabilities")
+
+	assert.NoError(t, VulnerabilityThresholds{"critical": 0, "high": 5}.Validate())
+	assert.Error(t, VulnerabilityThresholds{"severe": 0}.Validate())
+	assert.Error(t, VulnerabilityThresholds{"high": -1}.Validate())
+}
+
+func TestFileVulnerabilityScanner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-scan-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vulnerabilities.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{
+		"sha256:cabba9e": [
+			{"ID": "CVE-2018-0002", "Severity": "low"},
+			{"ID": "CVE-2018-0001", "Severity": "critical"}
+		],
+		"sha256:badbad": [{"ID": "CVE-2018-0003", "Severity": "dire"}]
+	}`), 0644))
+	fs := NewFileVulnerabilityScanner(path)
+
+	r, err := fs.ScanArtifact(BuildArtifact{DigestReference: "ot/one@sha256:cabba9e"})
+	require.NoError(t, err)
+	assert.Equal(t, &VulnerabilityReport{Scanner: "file", Vulnerabilities: []Vulnerability{
+		{ID: "CVE-2018-0001", Severity: "critical"},
+		{ID: "CVE-2018-0002", Severity: "low"},
+	}}, r)
+
+	r, err = fs.ScanArtifact(BuildArtifact{DigestReference: "ot/one@sha256:0123"})
+	require.NoError(t, err)
+	assert.Empty(t, r.Vulnerabilities)
+
+	_, err = fs.ScanArtifact(BuildArtifact{DigestReference: "ot/one@sha256:badbad"})
+	assert.Error(t, err)
+}
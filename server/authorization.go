@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+// Writes are authorized against the owners of the manifests they change.
+// Writes which affect a whole cluster, or the server itself, are only allowed
+// to members of the admin group, who may also write to any manifest.
+
+// isAdmin returns true if p is in the configured admin group.
+func (ctx ComponentLocator) isAdmin(p *restful.Principal) bool {
+	return ctx.Config != nil && p.Is(ctx.Config.Authorization.AdminGroup)
+}
+
+// authorizeAdmin returns an error unless p is an administrator.
+func (ctx ComponentLocator) authorizeAdmin(p *restful.Principal) error {
+	if ctx.isAdmin(p) {
+		return nil
+	}
+	return errors.Errorf("%s is not an administrator", p)
+}
+
+// authorizeManifest returns an error unless p is an administrator or an owner
+// of the manifest mid. If there is no such manifest, p must instead be one of
+// newOwners, the owners of the manifest being created.
+func (ctx ComponentLocator) authorizeManifest(p *restful.Principal, mid sous.ManifestID, newOwners []string) error {
+	if ctx.isAdmin(p) {
+		return nil
+	}
+	state := ctx.liveState()
+	if state == nil {
+		return errors.New("cannot read state to check manifest owners")
+	}
+	owners := newOwners
+	if m, ok := state.Manifests.Get(mid); ok {
+		owners = m.Owners
+	}
+	if p.Is(owners...) {
+		return nil
+	}
+	return errors.Errorf("%s is not an owner of %s", p, mid)
+}
+
+// authorizeDeployment authorizes writes to a deployment identified by the
+// query of req.
+func (ctx ComponentLocator) authorizeDeployment(p *restful.Principal, req *http.Request) error {
+	did, err := deploymentIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	if err != nil {
+		// Leave the handler to report bad queries, unless p could not have
+		// written anyway.
+		return ctx.authorizeAdmin(p)
+	}
+	return ctx.authorizeManifest(p, did.ManifestID, nil)
+}
+
+// Authorize implements restful.Authorizer on GDMResource. Since the whole GDM
+// is written, only administrators may do so.
+func (gr *GDMResource) Authorize(p *restful.Principal, _ *http.Request) error {
+	return gr.context.authorizeAdmin(p)
+}
+
+// Authorize implements restful.Authorizer on StateDefResource.
+func (sdr *StateDefResource) Authorize(p *restful.Principal, _ *http.Request) error {
+	return sdr.context.authorizeAdmin(p)
+}
+
+// Authorize implements restful.Authorizer on ServerListResource.
+func (slr *ServerListResource) Authorize(p *restful.Principal, _ *http.Request) error {
+	return slr.context.authorizeAdmin(p)
+}
+
+// Authorize implements restful.Authorizer on StateDeploymentResource.
+func (res *StateDeploymentResource) Authorize(p *restful.Principal, _ *http.Request) error {
+	return res.loc.authorizeAdmin(p)
+}
+
+// Authorize implements restful.Authorizer on ManifestResource. A new manifest
+// may be created by any of the owners it lists.
+func (mr *ManifestResource) Authorize(p *restful.Principal, req *http.Request) error {
+	mid, err := manifestIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	if err != nil {
+		return mr.context.authorizeAdmin(p)
+	}
+	var newOwners []string
+	if req.Method == "PUT" {
+		m := sous.Manifest{}
+		if err := json.NewDecoder(req.Body).Decode(&m); err == nil {
+			newOwners = m.Owners
+		}
+	}
+	return mr.context.authorizeManifest(p, mid, newOwners)
+}
+
+// Authorize implements restful.Authorizer on SingleDeploymentResource.
+func (sdr *SingleDeploymentResource) Authorize(p *restful.Principal, req *http.Request) error {
+	return sdr.context.authorizeDeployment(p, req)
+}
+
+// Authorize implements restful.Authorizer on R11nResource, so that only the
+// owners of a deployment may abort its rollout.
+func (r *R11nResource) Authorize(p *restful.Principal, req *http.Request) error {
+	return r.context.authorizeDeployment(p, req)
+}
+
+// Authorize implements restful.Authorizer on ArtifactResource. Artifacts may
+// be added by the owners of any manifest built from the same source.
+func (ar *ArtifactResource) Authorize(p *restful.Principal, req *http.Request) error {
+	return ar.context.authorizeSource(p, req)
+}
+
+// Authorize implements restful.Authorizer on BuildResource. Builds register
+// artifacts, so they are authorized as adding them is.
+func (br *BuildResource) Authorize(p *restful.Principal, req *http.Request) error {
+	return br.context.authorizeSource(p, req)
+}
+
+// authorizeSource authorizes writes for the source ID identified by the
+// query of req, which may be made by the owners of any manifest built from
+// the same source.
+func (ctx ComponentLocator) authorizeSource(p *restful.Principal, req *http.Request) error {
+	if ctx.isAdmin(p) {
+		return nil
+	}
+	sid, err := sourceIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	if err != nil {
+		return ctx.authorizeAdmin(p)
+	}
+	state := ctx.liveState()
+	if state == nil {
+		return errors.New("cannot read state to check manifest owners")
+	}
+	for _, m := range state.Manifests.Snapshot() {
+		if m.Source == sid.Location && p.Is(m.Owners...) {
+			return nil
+		}
+	}
+	return errors.Errorf("%s does not own a manifest for %s", p, sid.Location)
+}
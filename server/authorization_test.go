@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/config"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorization(t *testing.T) {
+	sm := defaultStateManager()
+	mid := sous.ManifestID{
+		Source: sous.SourceLocation{Repo: "github.com/user1/repo1", Dir: "dir1"},
+		Flavor: "flavor1",
+	}
+	m, ok := sm.State.Manifests.Get(mid)
+	if !ok {
+		t.Fatal("Setup failed to get manifest.")
+	}
+	m.Owners = []string{"owner@example.com", "team-one"}
+
+	cl := ComponentLocator{
+		StateManager: sm,
+		Config:       &config.Config{Authorization: config.AuthorizationConfig{AdminGroup: "admins"}},
+	}
+
+	admin := &restful.Principal{Name: "root", Groups: []string{"admins"}}
+	owner := &restful.Principal{Name: "o", Email: "owner@example.com"}
+	teammate := &restful.Principal{Name: "t", Groups: []string{"team-one"}}
+	stranger := &restful.Principal{Name: "s", Email: "stranger@example.com"}
+
+	didQuery := url.Values{}
+	didQuery.Set("repo", mid.Source.Repo)
+	didQuery.Set("offset", mid.Source.Dir)
+	didQuery.Set("flavor", mid.Flavor)
+	didQuery.Set("cluster", "cluster1")
+	req := func(method, query, body string) func() *http.Request {
+		return func() *http.Request {
+			return httptest.NewRequest(method, "http://sous.example.com/?"+query, strings.NewReader(body))
+		}
+	}
+
+	cases := []struct {
+		desc    string
+		res     restful.Authorizer
+		req     func() *http.Request
+		allowed []*restful.Principal
+		refused []*restful.Principal
+	}{
+		{
+			desc:    "gdm",
+			res:     newGDMResource(cl),
+			req:     req("PUT", "", ""),
+			allowed: []*restful.Principal{admin},
+			refused: []*restful.Principal{owner, stranger, nil},
+		},
+		{
+			desc:    "defs",
+			res:     newStateDefResource(cl),
+			req:     req("PUT", "", ""),
+			allowed: []*restful.Principal{admin},
+			refused: []*restful.Principal{owner, nil},
+		},
+		{
+			desc:    "single deployment",
+			res:     newSingleDeploymentResource(cl),
+			req:     req("PUT", didQuery.Encode(), ""),
+			allowed: []*restful.Principal{admin, owner, teammate},
+			refused: []*restful.Principal{stranger, nil},
+		},
+		{
+			desc:    "existing manifest",
+			res:     newManifestResource(cl),
+			req:     req("PUT", didQuery.Encode(), `{"Owners":["stranger@example.com"]}`),
+			allowed: []*restful.Principal{admin, owner},
+			refused: []*restful.Principal{stranger},
+		},
+		{
+			desc:    "new manifest",
+			res:     newManifestResource(cl),
+			req:     req("PUT", "repo=github.com/new/repo", `{"Owners":["stranger@example.com"]}`),
+			allowed: []*restful.Principal{admin, stranger},
+			refused: []*restful.Principal{owner, nil},
+		},
+		{
+			desc:    "delete manifest",
+			res:     newManifestResource(cl),
+			req:     req("DELETE", didQuery.Encode(), ""),
+			allowed: []*restful.Principal{owner},
+			refused: []*restful.Principal{stranger},
+		},
+		{
+			desc:    "artifact",
+			res:     newArtifactResource(cl),
+			req:     req("PUT", "repo=github.com/user1/repo1&offset=dir1&version=1.0.0", ""),
+			allowed: []*restful.Principal{teammate},
+			refused: []*restful.Principal{stranger},
+		},
+	}
+
+	for _, c := range cases {
+		for _, p := range c.allowed {
+			assert.NoError(t, c.res.Authorize(p, c.req()), "%s should be allowed: %s", p, c.desc)
+		}
+		for _, p := range c.refused {
+			assert.Error(t, c.res.Authorize(p, c.req()), "%s should not be allowed: %s", p, c.desc)
+		}
+	}
+}
+
+// userRecordingResource records the user GetUser finds for its GETs.
+type userRecordingResource struct {
+	userExtractor
+	user ClientUser
+}
+
+func (res *userRecordingResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	res.user = res.GetUser(req)
+	return res
+}
+
+func (res *userRecordingResource) Exchange() (interface{}, int) {
+	return res.user, http.StatusOK
+}
+
+func TestUserExtractor_GetUser(t *testing.T) {
+	getUser := func(ids restful.IdentitySource, header string) ClientUser {
+		res := &userRecordingResource{}
+		rm := restful.BuildRouteMap(func(re restful.RouteEntryBuilder) {
+			re("user", "/user", res)
+		})
+		req := httptest.NewRequest("GET", "/user", nil)
+		if header != "" {
+			req.Header.Set("X-User", header)
+		}
+		req.Header.Set("Sous-User-Name", "Someone Else")
+		req.Header.Set("Sous-User-Email", "someone@example.com")
+		rm.BuildAuthorizingRouter(logging.SilentLogSet(), ids).ServeHTTP(httptest.NewRecorder(), req)
+		return res.user
+	}
+	ids := restful.TrustedHeaderIdentity{UserHeader: "X-User"}
+
+	assert.Equal(t, ClientUser{Name: "alice"}, getUser(ids, "alice"))
+	assert.Equal(t, ClientUser{}, getUser(ids, ""), "anonymous callers may not name a user")
+	assert.Equal(t, ClientUser{Name: "Someone Else (unverified)", Email: "someone@example.com"}, getUser(nil, ""))
+}
@@ -52,6 +52,8 @@ type (
 	SingleDeploymentBody struct {
 		Meta       ResponseMeta
 		Deployment *sous.DeploySpec
+		// DeployState is only included when requested with status=true.
+		DeployState *sous.DeployState `json:",omitempty"`
 	}
 )
 
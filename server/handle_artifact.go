@@ -28,6 +28,10 @@ type (
 		*http.Request
 		restful.QueryValues
 		sous.Registry
+		// Provenance looks up the provenance and SBOM of the artifact when
+		// they are asked for, with provenance=true. If it is nil, they are not
+		// available.
+		Provenance sous.ProvenanceRegistry
 	}
 
 	// PUTArtifactHandler handles PUT requests to /artifact
@@ -49,6 +53,7 @@ func (ar *ArtifactResource) Get(_ *restful.RouteMap, ls logging.LogSink, writer
 		LogSink:     ls,
 		QueryValues: ar.ParseQuery(req),
 		Registry:    ar.context.Registry,
+		Provenance:  ar.context.Provenance,
 	}
 }
 
@@ -68,9 +73,23 @@ func (gh *GETArtifactHandler) Exchange() (interface{}, int) {
 		return err, http.StatusNotAcceptable
 	}
 
+	if gh.wantsProvenance() {
+		if gh.Provenance == nil {
+			return errors.New("this server does not record provenance"), http.StatusNotImplemented
+		}
+		if ba.Provenance, ba.SBOM, err = gh.Provenance.GetProvenance(sid); err != nil {
+			return err, http.StatusInternalServerError
+		}
+	}
+
 	return ba, http.StatusOK
 }
 
+func (gh *GETArtifactHandler) wantsProvenance() bool {
+	p, err := gh.QueryValues.Single("provenance", "false")
+	return err == nil && p == "true"
+}
+
 // Put implements Putable on ArtifactResource, which marks it as accepting PUT requests
 func (ar *ArtifactResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &PUTArtifactHandler{
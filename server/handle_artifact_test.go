@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
+	"github.com/nyarly/spies"
+	"github.com/opentable/sous/config"
 	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -53,3 +57,79 @@ func TestPUTArtifact(t *testing.T) {
 	assert.Equal(t, art.DigestReference, inBA.DigestReference, "build artifact digest name")
 	assert.Equal(t, art.VersionName, inBA.VersionName, "build artifact version name")
 }
+
+type testProvenanceRegistry struct {
+	sid sous.SourceID
+}
+
+func (r *testProvenanceRegistry) GetProvenance(sid sous.SourceID) (*sous.Provenance, *sous.SBOM, error) {
+	r.sid = sid
+	return &sous.Provenance{Revision: "abcdef", Strategy: "dockerfile"}, &sous.SBOM{}, nil
+}
+
+func TestGETArtifact_provenance(t *testing.T) {
+	reg, spy := sous.NewRegistrySpy()
+	spy.MatchMethod("GetArtifact", spies.AnyArgs, &sous.BuildArtifact{DigestReference: "test.reg.com/repo/test@sha256:123"}, nil)
+	handler := func(query string, pr sous.ProvenanceRegistry) *GETArtifactHandler {
+		q, err := url.ParseQuery(query)
+		require.NoError(t, err)
+		return &GETArtifactHandler{
+			LogSink:     logging.SilentLogSet(),
+			QueryValues: restful.QueryValues{Values: q},
+			Registry:    reg,
+			Provenance:  pr,
+		}
+	}
+	pr := &testProvenanceRegistry{}
+
+	body, status := handler("repo=github.com/opentable/test&version=1.2.3", pr).Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Nil(t, body.(*sous.BuildArtifact).Provenance)
+
+	body, status = handler("repo=github.com/opentable/test&version=1.2.3&provenance=true", pr).Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "github.com/opentable/test", pr.sid.Location.Repo)
+	ba := body.(*sous.BuildArtifact)
+	require.NotNil(t, ba.Provenance)
+	assert.Equal(t, "abcdef", ba.Provenance.Revision)
+	assert.NotNil(t, ba.SBOM)
+
+	_, status = handler("repo=github.com/opentable/test&version=1.2.3&provenance=true", nil).Exchange()
+	assert.Equal(t, http.StatusNotImplemented, status)
+}
+
+func TestArtifactReplace(t *testing.T) {
+	ls := logging.SilentLogSet()
+	ins, ctl := sous.NewInserterSpy()
+	cfg := &config.Config{}
+	locator := ComponentLocator{
+		LogSink:  ls,
+		Config:   cfg,
+		Registry: sous.NewDummyRegistry(),
+		Inserter: ins,
+	}
+	srv := httptest.NewServer(Handler(locator, http.NotFoundHandler(), ls))
+	defer srv.Close()
+	cfg.SiblingURLs = map[string]string{"cluster-1": srv.URL}
+
+	cl, err := restful.NewClient(srv.URL, ls)
+	require.NoError(t, err)
+	hni := sous.NewHTTPNameInserter(cl, sous.TraceID("trace"), ls)
+
+	sid := sous.MustNewSourceID("github.com/user/project", "", "1.2.3")
+	ba := sous.BuildArtifact{
+		Type:            "dummy",
+		DigestReference: sid.String(),
+		Qualities:       sous.Qualities{{Name: "critical:CVE-2018-0001", Kind: sous.VulnerabilityKind}},
+	}
+
+	// The registry already has an artifact for sid, so an insert is
+	// refused by the server, and ignored.
+	require.NoError(t, hni.Insert(sid, ba))
+	assert.Len(t, ctl.CallsTo("Insert"), 0)
+
+	require.NoError(t, hni.Replace(sid, ba))
+	inserts := ctl.CallsTo("Insert")
+	require.Len(t, inserts, 1)
+	assert.Equal(t, ba.Qualities, inserts[0].PassedArgs().Get(1).(sous.BuildArtifact).Qualities)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// AuditResource provides the /audit resource.
+	AuditResource struct {
+		context ComponentLocator
+	}
+
+	// GETAuditHandler lists the audit log of writes to the GDM.
+	GETAuditHandler struct {
+		AuditLog  sous.AuditLog
+		Filter    sous.AuditFilter
+		FilterErr error
+	}
+)
+
+func newAuditResource(ctx ComponentLocator) *AuditResource {
+	return &AuditResource{context: ctx}
+}
+
+// Get implements restful.Getable on AuditResource.
+func (r *AuditResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	filter, err := auditFilterFromValues(restful.QueryValues{Values: req.URL.Query()})
+	return &GETAuditHandler{
+		AuditLog:  r.context.AuditLog,
+		Filter:    filter,
+		FilterErr: err,
+	}
+}
+
+// auditFilterFromValues reads the repo, cluster, user, since and until query
+// parameters. Times are in RFC3339 format.
+func auditFilterFromValues(qv restful.QueryValues) (sous.AuditFilter, error) {
+	var f sous.AuditFilter
+	var err error
+	if f.Repo, err = qv.Single("repo", ""); err != nil {
+		return f, err
+	}
+	if f.Cluster, err = qv.Single("cluster", ""); err != nil {
+		return f, err
+	}
+	if f.User, err = qv.Single("user", ""); err != nil {
+		return f, err
+	}
+	parseTime := func(field string) (time.Time, error) {
+		s, err := qv.Single(field, "")
+		if err != nil || s == "" {
+			return time.Time{}, err
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		return t, errors.Wrapf(err, "%s", field)
+	}
+	if f.Since, err = parseTime("since"); err != nil {
+		return f, err
+	}
+	f.Until, err = parseTime("until")
+	return f, err
+}
+
+// Exchange implements restful.Exchanger on GETAuditHandler.
+func (h *GETAuditHandler) Exchange() (interface{}, int) {
+	if h.FilterErr != nil {
+		return h.FilterErr.Error(), http.StatusBadRequest
+	}
+	if h.AuditLog == nil {
+		return "No audit log is kept by this server.", http.StatusNotFound
+	}
+	entries, err := h.AuditLog.Query(h.Filter)
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	return dto.AuditResponse{Entries: entries}, http.StatusOK
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGETAuditHandler_Exchange(t *testing.T) {
+	l := sous.NewMemoryAuditLog()
+	require.NoError(t, l.Record(sous.AuditEntry{
+		User: sous.User{Name: "Test User"},
+		Changes: []sous.ManifestChange{{
+			ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/a"}},
+			Clusters:   []string{"cluster1"},
+		}},
+	}))
+
+	handler := &GETAuditHandler{AuditLog: l, Filter: sous.AuditFilter{Cluster: "cluster1"}}
+	body, status := handler.Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	if assert.IsType(t, dto.AuditResponse{}, body) {
+		assert.Len(t, body.(dto.AuditResponse).Entries, 1)
+	}
+
+	handler = &GETAuditHandler{AuditLog: l, Filter: sous.AuditFilter{Cluster: "cluster2"}}
+	body, status = handler.Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, body.(dto.AuditResponse).Entries, 0)
+
+	handler = &GETAuditHandler{Filter: sous.AuditFilter{}}
+	_, status = handler.Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestAuditFilterFromValues(t *testing.T) {
+	qv := func(s string) restful.QueryValues {
+		v, err := url.ParseQuery(s)
+		require.NoError(t, err)
+		return restful.QueryValues{Values: v}
+	}
+
+	f, err := auditFilterFromValues(qv("repo=github.com/example/a&cluster=c1&user=jo&since=2018-01-02T15:04:05Z"))
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/example/a", f.Repo)
+	assert.Equal(t, "c1", f.Cluster)
+	assert.Equal(t, "jo", f.User)
+	assert.Equal(t, 2018, f.Since.Year())
+	assert.True(t, f.Until.IsZero())
+
+	_, err = auditFilterFromValues(qv("until=yesterday"))
+	assert.Error(t, err)
+}
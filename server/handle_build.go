@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+)
+
+type (
+	// BuildResource provides the /build endpoint, which builds and registers
+	// the artifact for a source ID, and reports on builds it has started.
+	BuildResource struct {
+		restful.QueryParser
+		context ComponentLocator
+	}
+
+	// PUTBuildHandler handles PUT requests to /build.
+	PUTBuildHandler struct {
+		restful.QueryValues
+		User   ClientUser
+		Builds *sous.BuildJobs
+		// JobURI returns the URI of the job with an ID.
+		JobURI         func(id string) (string, error)
+		responseWriter http.ResponseWriter
+	}
+
+	// GETBuildHandler handles GET requests to /build.
+	GETBuildHandler struct {
+		restful.QueryValues
+		Builds *sous.BuildJobs
+	}
+)
+
+func newBuildResource(ctx ComponentLocator) *BuildResource {
+	return &BuildResource{context: ctx}
+}
+
+// Put implements Putable on BuildResource.
+func (br *BuildResource) Put(rm *restful.RouteMap, _ logging.LogSink, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &PUTBuildHandler{
+		QueryValues: br.ParseQuery(req),
+		User:        userExtractor{}.GetUser(req),
+		Builds:      br.context.Builds,
+		JobURI: func(id string) (string, error) {
+			return rm.FullURIFor(req.Host, "build", nil, restful.KV{"id", id})
+		},
+		responseWriter: rw,
+	}
+}
+
+// Get implements Getable on BuildResource.
+func (br *BuildResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	return &GETBuildHandler{
+		QueryValues: br.ParseQuery(req),
+		Builds:      br.context.Builds,
+	}
+}
+
+// Exchange implements Exchanger on PUTBuildHandler. The build is started, and
+// the response is sent at once with its job, which is at the URI in the
+// Location header.
+func (pbh *PUTBuildHandler) Exchange() (interface{}, int) {
+	if pbh.Builds == nil {
+		return errors.New("this server does not build source code"), http.StatusNotImplemented
+	}
+	sid, err := sourceIDFromValues(pbh.QueryValues)
+	if err != nil {
+		return err, http.StatusNotAcceptable
+	}
+	job := pbh.Builds.Start(sid, sous.User(pbh.User))
+	uri, err := pbh.JobURI(job.ID)
+	if err != nil {
+		return errors.Wrap(err, "determining build job URL"), http.StatusInternalServerError
+	}
+	if pbh.responseWriter != nil {
+		pbh.responseWriter.Header().Add("Location", uri)
+	}
+	return job, http.StatusAccepted
+}
+
+// Exchange implements Exchanger on GETBuildHandler.
+func (gbh *GETBuildHandler) Exchange() (interface{}, int) {
+	if gbh.Builds == nil {
+		return errors.New("this server does not build source code"), http.StatusNotImplemented
+	}
+	id, err := gbh.Single("id")
+	if err != nil {
+		return err, http.StatusNotAcceptable
+	}
+	job, has := gbh.Builds.Job(id)
+	if !has {
+		return errors.Errorf("no build job %q", id), http.StatusNotFound
+	}
+	return job, http.StatusOK
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSourceBuilder struct {
+	built []sous.SourceID
+	err   error
+}
+
+func (b *testSourceBuilder) BuildSource(sid sous.SourceID, _ sous.User) (*sous.BuildResult, error) {
+	b.built = append(b.built, sid)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &sous.BuildResult{Products: []*sous.BuildProduct{{Source: sid}}}, nil
+}
+
+func testPUTBuildHandler(t *testing.T, query string, b sous.SourceBuilder) *PUTBuildHandler {
+	q, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	h := &PUTBuildHandler{
+		QueryValues: restful.QueryValues{Values: q},
+		JobURI: func(id string) (string, error) {
+			return "http://sous.example.com/build?id=" + id, nil
+		},
+	}
+	if b != nil {
+		h.Builds = sous.NewBuildJobs(b)
+	}
+	return h
+}
+
+// finishedBuildJob waits for the job with id to finish.
+func finishedBuildJob(t *testing.T, builds *sous.BuildJobs, id string) sous.BuildJob {
+	h := &GETBuildHandler{
+		QueryValues: restful.QueryValues{Values: url.Values{"id": {id}}},
+		Builds:      builds,
+	}
+	for i := 0; i < 100; i++ {
+		body, status := h.Exchange()
+		require.Equal(t, http.StatusOK, status)
+		if job := body.(sous.BuildJob); job.Status != sous.BuildJobRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("build job %s did not finish", id)
+	return sous.BuildJob{}
+}
+
+func TestPUTBuild(t *testing.T) {
+	b := &testSourceBuilder{}
+	h := testPUTBuildHandler(t, "repo=gitlab.example.com/group/project&offset=svc&version=1.2.3", b)
+
+	body, status := h.Exchange()
+
+	assert.Equal(t, http.StatusAccepted, status)
+	require.IsType(t, sous.BuildJob{}, body)
+	job := finishedBuildJob(t, h.Builds, body.(sous.BuildJob).ID)
+	assert.Equal(t, sous.BuildJobSucceeded, job.Status)
+	require.Len(t, b.built, 1)
+	assert.Equal(t, sous.MustParseSourceID("gitlab.example.com/group/project,1.2.3,svc"), b.built[0])
+	assert.Equal(t, b.built[0], job.Result.Products[0].Source)
+}
+
+func TestPUTBuild_failures(t *testing.T) {
+	_, status := testPUTBuildHandler(t, "repo=gitlab.example.com/group/project&version=1.2.3", nil).Exchange()
+	assert.Equal(t, http.StatusNotImplemented, status, "without a builder")
+
+	b := &testSourceBuilder{}
+	_, status = testPUTBuildHandler(t, "repo=gitlab.example.com/group/project&version=latest", b).Exchange()
+	assert.Equal(t, http.StatusNotAcceptable, status, "bad version")
+	assert.Empty(t, b.built)
+
+	b.err = errors.New("no tag for version 1.2.3")
+	h := testPUTBuildHandler(t, "repo=gitlab.example.com/group/project&version=1.2.3", b)
+	body, status := h.Exchange()
+	require.Equal(t, http.StatusAccepted, status)
+	job := finishedBuildJob(t, h.Builds, body.(sous.BuildJob).ID)
+	assert.Equal(t, sous.BuildJobFailed, job.Status, "failed build")
+	assert.Equal(t, "no tag for version 1.2.3", job.Error)
+}
+
+func TestGETBuild_unknown(t *testing.T) {
+	h := &GETBuildHandler{
+		QueryValues: restful.QueryValues{Values: url.Values{"id": {"nope"}}},
+		Builds:      sous.NewBuildJobs(&testSourceBuilder{}),
+	}
+	_, status := h.Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/restful"
+)
+
+type (
+	// DeploymentHistoryResource provides the /deployment-history resource.
+	DeploymentHistoryResource struct {
+		context ComponentLocator
+	}
+
+	// GETDeploymentHistoryHandler lists the history of a single deployment.
+	GETDeploymentHistoryHandler struct {
+		History         sous.DeploymentHistory
+		DeploymentID    sous.DeploymentID
+		DeploymentIDErr error
+	}
+)
+
+func newDeploymentHistoryResource(ctx ComponentLocator) *DeploymentHistoryResource {
+	return &DeploymentHistoryResource{context: ctx}
+}
+
+// Get implements restful.Getable on DeploymentHistoryResource.
+func (r *DeploymentHistoryResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	did, didErr := deploymentIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	return &GETDeploymentHistoryHandler{
+		History:         r.context.DeploymentHistory,
+		DeploymentID:    did,
+		DeploymentIDErr: didErr,
+	}
+}
+
+// Exchange implements restful.Exchanger on GETDeploymentHistoryHandler.
+func (h *GETDeploymentHistoryHandler) Exchange() (interface{}, int) {
+	if h.DeploymentIDErr != nil {
+		return h.DeploymentIDErr.Error(), http.StatusBadRequest
+	}
+	if h.History == nil {
+		return "No deployment history is kept by this server.", http.StatusNotFound
+	}
+	entries, err := h.History.History(h.DeploymentID)
+	if err != nil {
+		return err.Error(), http.StatusInternalServerError
+	}
+	return dto.DeploymentHistoryResponse{Entries: entries}, http.StatusOK
+}
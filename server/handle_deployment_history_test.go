@@ -0,0 +1,44 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/opentable/sous/dto"
+	sous "github.com/opentable/sous/lib"
+	"github.com/samsalisbury/semv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGETDeploymentHistoryHandler_Exchange(t *testing.T) {
+	did := sous.DeploymentID{
+		ManifestID: sous.ManifestID{Source: sous.SourceLocation{Repo: "github.com/example/a"}},
+		Cluster:    "cluster1",
+	}
+	h := sous.NewMemoryDeploymentHistory()
+	require.NoError(t, h.Record(sous.DeploymentHistoryEntry{
+		DeploymentID: did,
+		DeploySpec:   sous.DeploySpec{Version: semv.MustParse("1.0.0")},
+		Outcome:      sous.OutcomeSucceeded,
+	}))
+
+	handler := &GETDeploymentHistoryHandler{History: h, DeploymentID: did}
+	body, status := handler.Exchange()
+	assert.Equal(t, http.StatusOK, status)
+	if assert.IsType(t, dto.DeploymentHistoryResponse{}, body) {
+		entries := body.(dto.DeploymentHistoryResponse).Entries
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "1.0.0", entries[0].DeploySpec.Version.String())
+		}
+	}
+
+	handler = &GETDeploymentHistoryHandler{History: h, DeploymentIDErr: errors.New("no cluster")}
+	_, status = handler.Exchange()
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	handler = &GETDeploymentHistoryHandler{DeploymentID: did}
+	_, status = handler.Exchange()
+	assert.Equal(t, http.StatusNotFound, status)
+}
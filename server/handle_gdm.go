@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/dto"
@@ -33,6 +34,7 @@ type (
 		//GDM          *sous.State
 		StateManager sous.StateManager
 		User         ClientUser
+		Promotion    sous.PromotionChecker
 	}
 )
 
@@ -79,8 +81,9 @@ func (gr *GDMResource) Put(_ *restful.RouteMap, ls logging.LogSink, _ http.Respo
 		Request: req,
 		LogSink: ls,
 		//GDM:          gr.context.liveState(),
-		StateManager: gr.context.StateManager,
+		StateManager: gr.context.tracedStateManager(req),
 		User:         gr.GetUser(req),
+		Promotion:    gr.context.promotionChecker(),
 	}
 }
 
@@ -102,6 +105,13 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 
 	reportDebugHandleGDMMessage(fmt.Sprintf("Put GDM Handler Exchange with Server State: %v", state), nil, nil, h.LogSink)
 
+	prior, err := state.Deployments()
+	if err != nil {
+		msg := "Error getting current deployments"
+		reportHandleGDMMessage(msg, nil, err, h.LogSink, logging.WarningLevel)
+		return msg, http.StatusInternalServerError
+	}
+
 	state.Manifests, err = deps.PutbackManifests(state.Defs, state.Manifests, h.LogSink)
 	if err != nil {
 		msg := "Error getting state"
@@ -109,6 +119,32 @@ func (h *PUTGDMHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusConflict
 	}
 
+	// Check against the deployments as the server's Defs describe them, not
+	// the client's.
+	next, err := state.Deployments()
+	if err != nil {
+		msg := "Error getting updated deployments"
+		reportHandleGDMMessage(msg, nil, err, h.LogSink, logging.WarningLevel)
+		return msg, http.StatusBadRequest
+	}
+	if err := sous.CheckFreezes(prior, next, time.Now()); err != nil {
+		reportHandleGDMMessage("Refusing GDM update during freeze", nil, err, h.LogSink, logging.WarningLevel)
+		return err.Error(), http.StatusLocked
+	}
+	if err := h.Promotion.CheckChanges(state.Defs, prior, next); err != nil {
+		if sous.IsPromotionError(err) {
+			reportHandleGDMMessage("Refusing GDM update out of promotion order", nil, err, h.LogSink, logging.WarningLevel)
+			return err.Error(), http.StatusConflict
+		}
+		msg := "Error checking promotion order"
+		reportHandleGDMMessage(msg, nil, err, h.LogSink, logging.WarningLevel)
+		return msg, http.StatusInternalServerError
+	}
+	if err := sous.CheckDependencies(prior, next); err != nil {
+		reportHandleGDMMessage("Refusing GDM update which breaks dependencies", nil, err, h.LogSink, logging.WarningLevel)
+		return err.Error(), http.StatusConflict
+	}
+
 	flaws := state.Validate()
 	if len(flaws) > 0 {
 		msg := "Invalid GDM"
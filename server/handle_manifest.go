@@ -2,7 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/lib"
@@ -34,6 +36,7 @@ type (
 		restful.QueryValues
 		User        ClientUser
 		StateWriter sous.StateWriter
+		Secrets     sous.SecretStore
 	}
 
 	// DELETEManifestHandler handles DELETE exchanges for manifests
@@ -64,7 +67,8 @@ func (mr *ManifestResource) Put(_ *restful.RouteMap, ls logging.LogSink, _ http.
 		Request:     req,
 		QueryValues: mr.ParseQuery(req),
 		User:        mr.GetUser(req),
-		StateWriter: sous.StateWriter(mr.context.StateManager),
+		StateWriter: sous.StateWriter(mr.context.tracedStateManager(req)),
+		Secrets:     mr.context.Secrets,
 	}
 }
 
@@ -73,7 +77,7 @@ func (mr *ManifestResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ htt
 	return &DELETEManifestHandler{
 		State:       mr.context.liveState(),
 		QueryValues: mr.ParseQuery(req),
-		StateWriter: sous.StateWriter(mr.context.StateManager),
+		StateWriter: sous.StateWriter(mr.context.tracedStateManager(req)),
 	}
 }
 
@@ -87,9 +91,24 @@ func (gmh *GETManifestHandler) Exchange() (interface{}, int) {
 	if !there {
 		return nil, http.StatusNotFound
 	}
+	if gmh.wantsExpanded() {
+		expanded, err := sous.ExpandManifest(gmh.State.Defs, m)
+		if err != nil {
+			return err, http.StatusInternalServerError
+		}
+		return expanded, http.StatusOK
+	}
 	return m, http.StatusOK
 }
 
+// wantsExpanded is true when the manifest is requested with expanded=true,
+// for the effective config of its deployments, rather than the manifest as
+// written.
+func (gmh *GETManifestHandler) wantsExpanded() bool {
+	e, err := gmh.QueryValues.Single("expanded", "false")
+	return err == nil && e == "true"
+}
+
 // Exchange implements restful.Exchanger
 func (dmh *DELETEManifestHandler) Exchange() (interface{}, int) {
 	mid, err := manifestIDFromValues(dmh.QueryValues)
@@ -121,9 +140,34 @@ func (pmh *PUTManifestHandler) Exchange() (interface{}, int) {
 		messages.ReportLogFieldsMessageToConsole("Exchange contains flaws", logging.ExtraDebug1Level, pmh.LogSink, flaws)
 		return "Invalid manifest", http.StatusBadRequest
 	}
+	// Required values which are missing are given their defaults; any flaws
+	// left are values which don't conform to their definitions.
+	if flaws, _ := sous.RepairAll(pmh.State.Defs.ValidateManifest(m)); len(flaws) > 0 {
+		return "Invalid manifest: " + describeFlaws(flaws), http.StatusBadRequest
+	}
+	for cluster, spec := range m.Deployments {
+		if err := sous.CheckSecrets(pmh.Secrets, spec.Env); err != nil {
+			return errors.Wrapf(err, "deployment to %s", cluster).Error(), http.StatusBadRequest
+		}
+	}
+	if err := pmh.State.CheckManifestDependencies(m); err != nil {
+		if sous.IsDependencyError(err) {
+			return err.Error(), http.StatusConflict
+		}
+		return "Invalid manifest: " + err.Error(), http.StatusBadRequest
+	}
 	pmh.State.Manifests.Set(mid, m)
 	if err := pmh.StateWriter.WriteState(pmh.State, sous.User(pmh.User)); err != nil {
 		return errors.Wrapf(err, "state recording collision - retry"), http.StatusConflict
 	}
 	return m, http.StatusOK
 }
+
+// describeFlaws returns the descriptions of flaws, separated by semicolons.
+func describeFlaws(flaws []sous.Flaw) string {
+	descs := make([]string, len(flaws))
+	for i, f := range flaws {
+		descs[i] = fmt.Sprint(f)
+	}
+	return strings.Join(descs, "; ")
+}
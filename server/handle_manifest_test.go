@@ -10,6 +10,8 @@ import (
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
+	"github.com/samsalisbury/semv"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +115,35 @@ func TestHandlesManifestGet(t *testing.T) {
 
 }
 
+func TestHandlesManifestGet_expanded(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	q, err := url.ParseQuery("repo=gh&expanded=true")
+	require.NoError(err)
+	state := sous.NewState()
+	state.Defs.Clusters = sous.Clusters{"cluster-1": &sous.Cluster{Name: "cluster-1"}}
+	state.Defs.Templates = sous.DeployConfigs{"base": {NumInstances: 3}}
+	state.Manifests.Add(&sous.Manifest{
+		Source:   sous.SourceLocation{Repo: "gh"},
+		Template: "base",
+		Deployments: sous.DeploySpecs{
+			"cluster-1": {Version: semv.MustParse("1.0.0")},
+		},
+	})
+
+	th := &GETManifestHandler{
+		State:       state,
+		QueryValues: restful.QueryValues{Values: q},
+	}
+	data, status := th.Exchange()
+	require.Equal(200, status)
+	m, is := data.(*sous.Manifest)
+	require.True(is)
+	assert.Equal("", m.Template)
+	assert.Equal(3, m.Deployments["cluster-1"].NumInstances)
+}
+
 func TestHandlesManifestPut(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -179,3 +210,143 @@ func TestHandlesManifestPut(t *testing.T) {
 	assert.Equal(changed.Deployments["ci"].SingularityRequestID, "custom-sing-req-id")
 
 }
+
+type testSecretStore map[string]string
+
+func (s testSecretStore) Secret(ref sous.SecretRef) (string, error) {
+	if v, ok := s[ref.String()]; ok {
+		return v, nil
+	}
+	return "", errors.Errorf("no secret %s", ref)
+}
+
+func TestHandlesManifestPut_secrets(t *testing.T) {
+	put := func(store sous.SecretStore) int {
+		q, _ := url.ParseQuery("repo=gh")
+		state := sous.NewState()
+		manifest := &sous.Manifest{
+			Source: sous.SourceLocation{Repo: "gh"},
+			Owners: []string{"sam"},
+			Kind:   sous.ManifestKindService,
+			Deployments: sous.DeploySpecs{
+				"ci": sous.DeploySpec{
+					DeployConfig: sous.DeployConfig{
+						Resources: sous.Resources{"cpus": "0.1", "memory": "100", "ports": "1"},
+						Env:       sous.Env{"DB_PASSWORD": "secret://db#password"},
+					},
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(manifest)
+		req, _ := http.NewRequest("PUT", "", buf)
+		log, _ := logging.NewLogSinkSpy()
+		th := &PUTManifestHandler{
+			Request:     req,
+			StateWriter: &sous.DummyStateManager{State: state},
+			State:       state,
+			QueryValues: restful.QueryValues{Values: q},
+			LogSink:     log,
+			Secrets:     store,
+		}
+		_, status := th.Exchange()
+		return status
+	}
+
+	assert.Equal(t, 400, put(nil), "no secret store")
+	assert.Equal(t, 400, put(testSecretStore{}), "missing secret")
+	assert.Equal(t, 200, put(testSecretStore{"secret://db#password": "hunter2"}))
+}
+
+func TestHandlesManifestPut_invalidVars(t *testing.T) {
+	put := func(env sous.Env) (interface{}, int, *sous.State) {
+		q, _ := url.ParseQuery("repo=gh")
+		state := sous.NewState()
+		state.Defs.Clusters = sous.Clusters{"ci": &sous.Cluster{Name: "ci"}}
+		state.Defs.EnvVars = sous.EnvDefs{{Name: "PORT", Type: "int", Required: true, Default: "8080"}}
+		manifest := &sous.Manifest{
+			Source: sous.SourceLocation{Repo: "gh"},
+			Owners: []string{"sam"},
+			Kind:   sous.ManifestKindService,
+			Deployments: sous.DeploySpecs{
+				"ci": sous.DeploySpec{
+					DeployConfig: sous.DeployConfig{
+						Resources: sous.Resources{"cpus": "0.1", "memory": "100", "ports": "1"},
+						Env:       env,
+					},
+				},
+			},
+		}
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(manifest)
+		req, _ := http.NewRequest("PUT", "", buf)
+		log, _ := logging.NewLogSinkSpy()
+		th := &PUTManifestHandler{
+			Request:     req,
+			StateWriter: &sous.DummyStateManager{State: state},
+			State:       state,
+			QueryValues: restful.QueryValues{Values: q},
+			LogSink:     log,
+		}
+		data, status := th.Exchange()
+		return data, status, state
+	}
+
+	data, status, _ := put(sous.Env{"PORT": "banana"})
+	assert.Equal(t, 400, status)
+	assert.Equal(t, `Invalid manifest: manifest "gh" deployment to ci: Env PORT: "banana" is not an int`, data)
+
+	_, status, state := put(nil)
+	assert.Equal(t, 200, status)
+	m, _ := state.Manifests.Get(sous.ManifestID{Source: sous.SourceLocation{Repo: "gh"}})
+	assert.Equal(t, "8080", m.Deployments["ci"].Env["PORT"], "default applied")
+}
+
+func TestHandlesManifestPut_dependencies(t *testing.T) {
+	put := func(version string) (interface{}, int) {
+		q, _ := url.ParseQuery("repo=db")
+		state := sous.NewState()
+		state.Defs.Clusters = sous.Clusters{"ci": &sous.Cluster{Name: "ci"}}
+		spec := func(version string) sous.DeploySpecs {
+			return sous.DeploySpecs{"ci": sous.DeploySpec{
+				Version:      semv.MustParse(version),
+				DeployConfig: sous.DeployConfig{NumInstances: 1},
+			}}
+		}
+		state.Manifests.Add(&sous.Manifest{
+			Source:       sous.SourceLocation{Repo: "api"},
+			Kind:         sous.ManifestKindService,
+			Dependencies: sous.Dependencies{{Manifest: sous.MustParseManifestID("db"), Version: "^1.0.0"}},
+			Deployments:  spec("1.0.0"),
+		})
+		state.Manifests.Add(&sous.Manifest{
+			Source:      sous.SourceLocation{Repo: "db"},
+			Kind:        sous.ManifestKindService,
+			Deployments: spec("1.0.0"),
+		})
+		manifest := &sous.Manifest{
+			Source:      sous.SourceLocation{Repo: "db"},
+			Kind:        sous.ManifestKindService,
+			Deployments: spec(version),
+		}
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(manifest)
+		req, _ := http.NewRequest("PUT", "", buf)
+		log, _ := logging.NewLogSinkSpy()
+		th := &PUTManifestHandler{
+			Request:     req,
+			StateWriter: &sous.DummyStateManager{State: state},
+			State:       state,
+			QueryValues: restful.QueryValues{Values: q},
+			LogSink:     log,
+		}
+		return th.Exchange()
+	}
+
+	_, status := put("1.2.0")
+	assert.Equal(t, 200, status)
+
+	data, status := put("2.0.0")
+	assert.Equal(t, 409, status)
+	assert.Equal(t, `dependencies not met: ci:api requires ci:db: version 2.0.0 is not in "^1.0.0"`, data)
+}
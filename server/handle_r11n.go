@@ -27,6 +27,14 @@ type (
 		R11nID            sous.R11nID
 		R11nIDErr         error
 	}
+
+	// DELETER11nHandler aborts staged rollouts.
+	DELETER11nHandler struct {
+		QueueSet        sous.QueueSet
+		DeploymentID    sous.DeploymentID
+		DeploymentIDErr error
+		R11nID          sous.R11nID
+	}
 )
 
 func newR11nResource(ctx ComponentLocator) *R11nResource {
@@ -56,6 +64,18 @@ func (r *R11nResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.Respon
 	}
 }
 
+// Delete returns a configured DELETER11nHandler.
+func (r *R11nResource) Delete(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	did, didErr := deploymentIDFromValues(restful.QueryValues{Values: req.URL.Query()})
+	rid, _ := r11nIDFromRoute(req)
+	return &DELETER11nHandler{
+		QueueSet:        r.context.QueueSet,
+		DeploymentID:    did,
+		DeploymentIDErr: didErr,
+		R11nID:          rid,
+	}
+}
+
 // Exchange returns the targeted r11nResponse and 200 if it exists, other
 // non-200 responses otherwise.
 func (h *GETR11nHandler) Exchange() (interface{}, int) {
@@ -71,16 +91,9 @@ func (h *GETR11nHandler) Exchange() (interface{}, int) {
 				h.R11nID, h.DeploymentID), http.StatusNotFound
 		}
 	}
-	queues := h.QueueSet.Queues()
-	queue, ok := queues[h.DeploymentID]
+	qr, ok := h.QueueSet.ByID(h.DeploymentID, h.R11nID)
 	if !ok {
-		return fmt.Sprintf("Nothing queued for %q.", h.DeploymentID),
-			http.StatusNotFound
-	}
-	qr, ok := queue.ByID(h.R11nID)
-	if !ok {
-		return fmt.Sprintf("Deploy action %q not found in queue for %q.",
-			h.R11nID, h.DeploymentID), http.StatusNotFound
+		return r11nNotFound(h.QueueSet, h.DeploymentID, h.R11nID)
 	}
 
 	// XXX Should this be part of the ByID contract?
@@ -93,9 +106,40 @@ func (h *GETR11nHandler) Exchange() (interface{}, int) {
 	return dto.R11nResponse{
 		QueuePosition: qr.Pos,
 		Resolution:    rez,
+		Stages:        qr.Rectification.Stages(),
+	}, http.StatusOK
+}
+
+// Exchange aborts the targeted staged rollout, which then reverts to the
+// previously deployed version. It returns 409 if the rectification is not a
+// staged rollout still in progress.
+func (h *DELETER11nHandler) Exchange() (interface{}, int) {
+	if h.DeploymentIDErr != nil {
+		return nil, http.StatusNotFound
+	}
+	qr, ok := h.QueueSet.ByID(h.DeploymentID, h.R11nID)
+	if !ok {
+		return r11nNotFound(h.QueueSet, h.DeploymentID, h.R11nID)
+	}
+	if !qr.Rectification.Abort() {
+		return fmt.Sprintf("Deploy action %q has no rollout in progress to abort.",
+			h.R11nID), http.StatusConflict
+	}
+	return dto.R11nResponse{
+		QueuePosition: qr.Pos,
+		Stages:        qr.Rectification.Stages(),
 	}, http.StatusOK
 }
 
+// r11nNotFound returns the response for an r11n which qs cannot find.
+func r11nNotFound(qs sous.QueueSet, did sous.DeploymentID, id sous.R11nID) (interface{}, int) {
+	if _, ok := qs.Queues()[did]; !ok {
+		return fmt.Sprintf("Nothing queued for %q.", did), http.StatusNotFound
+	}
+	return fmt.Sprintf("Deploy action %q not found in queue for %q.", id, did),
+		http.StatusNotFound
+}
+
 /*
 type r11nResponse struct {
 	QueuePosition int
@@ -509,3 +509,45 @@ func TestGETR11nHandler_Exchange_wait_success(t *testing.T) {
 		}
 	}
 }
+
+func TestDELETER11nHandler_Exchange(t *testing.T) {
+	queues := sous.NewR11nQueueSet()
+
+	staged := newR11n("staged")
+	staged.Pair.Post = &sous.Deployable{Deployment: &sous.Deployment{
+		SourceID: sous.MustNewSourceID("staged", "", "2.0.0"),
+		DeployConfig: sous.DeployConfig{
+			NumInstances: 3,
+			Rollout:      sous.Rollout{Strategy: sous.RolloutCanary},
+		},
+	}}
+	staged.PlanRollout(&sous.Deployment{SourceID: sous.MustNewSourceID("staged", "", "1.0.0")})
+	queuedStaged, ok := queues.Push(staged)
+	if !ok {
+		t.Fatal("setup failed to push r11n")
+	}
+	queuedPlain, ok := queues.Push(newR11n("plain"))
+	if !ok {
+		t.Fatal("setup failed to push r11n")
+	}
+
+	a := R11nHandlerAsserts{}
+
+	h := &DELETER11nHandler{QueueSet: queues, DeploymentID: newDid("staged"), R11nID: queuedStaged.ID}
+	body, gotStatus := h.Exchange()
+	a.wantStatus200(t, gotStatus)
+	got := a.wantR11nResponse(t, body)
+	if len(got.Stages) != 2 {
+		t.Errorf("got %d stages; want 2", len(got.Stages))
+	}
+
+	h = &DELETER11nHandler{QueueSet: queues, DeploymentID: newDid("plain"), R11nID: queuedPlain.ID}
+	_, gotStatus = h.Exchange()
+	if gotStatus != http.StatusConflict {
+		t.Errorf("got status %d; want %d", gotStatus, http.StatusConflict)
+	}
+
+	h = &DELETER11nHandler{QueueSet: queues, DeploymentID: newDid("nonexistent")}
+	_, gotStatus = h.Exchange()
+	a.wantStatus404(t, gotStatus)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/ext/singularity"
@@ -11,6 +12,7 @@ import (
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
 	"github.com/opentable/sous/util/restful"
+	"github.com/pkg/errors"
 )
 
 // https://github.com/opentable/sous/blob/0a96ed483cd86abc9604993120e8dd211cf7adc6/server/handle_single_deployment.go
@@ -28,12 +30,16 @@ type (
 		QueueSet    sous.QueueSet
 		routeMap    *restful.RouteMap
 		StateWriter sous.StateWriter
+		Promotion   sous.PromotionChecker
+		Secrets     sous.SecretStore
 	}
 
 	// GETSingleDeploymentHandler retrieves manifests containing single deployment
 	// specs. See Exchange method for more details.
 	GETSingleDeploymentHandler struct {
 		SingleDeploymentHandler
+		Deployer sous.Deployer
+		Registry sous.Registry
 	}
 
 	// SingleDeploymentHandler contains common data and methods to both
@@ -68,6 +74,16 @@ func (sdh *SingleDeploymentHandler) force() (force bool, err error) {
 	return forceFromValues(qv)
 }
 
+func (sdh *SingleDeploymentHandler) breakGlass() (bool, error) {
+	qv := restful.QueryValues{Values: sdh.req.URL.Query()}
+	return breakGlassFromValues(qv)
+}
+
+func (sdh *SingleDeploymentHandler) status() (bool, error) {
+	qv := restful.QueryValues{Values: sdh.req.URL.Query()}
+	return statusFromValues(qv)
+}
+
 func (sdh *SingleDeploymentHandler) depID() (sous.DeploymentID, error) {
 	qv := restful.QueryValues{Values: sdh.req.URL.Query()}
 	return deploymentIDFromValues(qv)
@@ -81,7 +97,9 @@ func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, ls logging.LogSin
 		SingleDeploymentHandler: sdh,
 		QueueSet:                sdr.context.QueueSet,
 		routeMap:                rm,
-		StateWriter:             sdr.context.StateManager,
+		StateWriter:             sdr.context.tracedStateManager(req),
+		Promotion:               sdr.context.promotionChecker(),
+		Secrets:                 sdr.context.Secrets,
 	}
 }
 
@@ -89,22 +107,32 @@ func (sdr *SingleDeploymentResource) Put(rm *restful.RouteMap, ls logging.LogSin
 func (sdr *SingleDeploymentResource) Get(rm *restful.RouteMap, ls logging.LogSink, rw http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	gdm := sdr.context.liveState()
 	sdh := sdr.newSingleDeploymentHandler(ls, req, rw, gdm)
-	return &GETSingleDeploymentHandler{SingleDeploymentHandler: sdh}
+	return &GETSingleDeploymentHandler{
+		SingleDeploymentHandler: sdh,
+		Deployer:                sdr.context.Deployer,
+		Registry:                sdr.context.Registry,
+	}
 }
 
-// Exchange returns a single deployment.
+// Exchange returns a single deployment. If the query has status=true, the
+// body also includes its current DeployState on the cluster.
 func (h *GETSingleDeploymentHandler) Exchange() (interface{}, int) {
 	did, err := h.depID()
 	if err != nil {
 		return h.err(400, "Cannot decode Deployment ID: %s.", err)
 	}
 
+	status, err := h.status()
+	if err != nil {
+		return h.err(400, "Cannot parse status from client: %s", err)
+	}
+
 	m, ok := h.GDM.Manifests.Get(did.ManifestID)
 	if !ok {
 		return h.err(404, "No manifest with ID %q", did.ManifestID)
 	}
 
-	dep, ok := m.Deployments[did.Cluster]
+	dep, ok := h.GDM.Defs.ClusterSpec(m, did.Cluster)
 	if !ok {
 		return h.err(404, "Manifest %q has no deployment for cluster %q.", m.ID(), did.Cluster)
 	}
@@ -123,9 +151,34 @@ func (h *GETSingleDeploymentHandler) Exchange() (interface{}, int) {
 
 	h.Body.Deployment = &dep
 
+	if status {
+		ds, err := h.deployState(did)
+		if err != nil {
+			return h.err(500, "Cannot get status of %s: %s", did, err)
+		}
+		h.Body.DeployState = ds
+	}
+
 	return h.ok(200, links)
 }
 
+func (h *GETSingleDeploymentHandler) deployState(did sous.DeploymentID) (*sous.DeployState, error) {
+	if h.Deployer == nil {
+		return nil, errors.New("no deployer configured")
+	}
+	deployments, err := h.GDM.Deployments()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := deployments.Get(did)
+	if !ok {
+		return nil, errors.Errorf("no deployment %s", did)
+	}
+	pair := &sous.DeployablePair{Post: &sous.Deployable{Deployment: d}}
+	pair.SetID(did)
+	return h.Deployer.Status(h.Registry, h.GDM.Defs.Clusters, pair)
+}
+
 func makeSingularityURL(baseURL string, singularityRequestID string, deploymentID sous.DeploymentID) string {
 	var err error
 	if len(singularityRequestID) == 0 {
@@ -171,6 +224,11 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.err(400, "Cannot parse force from client: %s", err)
 	}
 
+	breakGlass, err := psd.breakGlass()
+	if err != nil {
+		return psd.err(400, "Cannot parse break-glass from client: %s", err)
+	}
+
 	if err := json.NewDecoder(psd.req.Body).Decode(&psd.Body); err != nil {
 		return psd.err(400, "Error parsing body: %s.", err)
 	}
@@ -189,7 +247,7 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	if !ok {
 		return psd.err(404, "No manifest with ID %q.", did.ManifestID)
 	}
-	original, ok := m.Deployments[did.Cluster]
+	original, ok := psd.GDM.Defs.ClusterSpec(m, did.Cluster)
 	if !ok {
 		return psd.err(404, "Manifest %q has no deployment for cluster %q.",
 			did.ManifestID, did.Cluster)
@@ -199,12 +257,59 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 		return psd.err(400, "Cannot deploy: NumInstances is 0 for this deployment. Please update your manifest to NumInstances > 0 to enable deploying.")
 	}
 
+	if err := sous.CheckSecrets(psd.Secrets, psd.Body.Deployment.Env); err != nil {
+		return psd.err(400, "Cannot deploy: %s.", err)
+	}
+
+	// A change to the Rollout alone is not a difference: see
+	// DeployConfig.Diff.
 	different, _ := psd.Body.Deployment.Diff(original)
 	if !different && !force {
 		return psd.ok(200, nil)
 	}
 
-	m.Deployments[did.Cluster] = *psd.Body.Deployment
+	cluster := psd.GDM.Defs.Clusters[did.Cluster]
+	if err := cluster.CheckFreeze(time.Now(), sous.NewOwnerSet(m.Owners...), breakGlass); err != nil {
+		if fe, ok := err.(*sous.FreezeError); ok && fe.Window.BreakGlass {
+			return psd.err(http.StatusLocked, "Cannot deploy: %s. This freeze may be overridden with -break-glass.", err)
+		}
+		return psd.err(http.StatusLocked, "Cannot deploy: %s.", err)
+	}
+
+	// Capture the current deployment before changing the GDM, so that a
+	// staged rollout has something to revert to.
+	priorDeployments, err := psd.GDM.Deployments()
+	if err != nil {
+		return psd.err(500, "Failed to compute current deployments: %s", err)
+	}
+	prior, _ := priorDeployments.Get(did)
+
+	if version := psd.Body.Deployment.Version; !original.Version.Equals(version) {
+		if err := psd.Promotion.Check(psd.GDM.Defs, priorDeployments, did, version); err != nil {
+			if sous.IsPromotionError(err) {
+				return psd.err(409, "Cannot deploy: %s.", err)
+			}
+			return psd.err(500, "Checking promotion order: %s", err)
+		}
+	}
+
+	if prior != nil {
+		next := priorDeployments.Clone()
+		changed := prior.Clone()
+		changed.SourceID.Version = psd.Body.Deployment.Version
+		changed.NumInstances = psd.Body.Deployment.NumInstances
+		next.Set(did, changed)
+		if err := sous.CheckDependencies(priorDeployments, next); err != nil {
+			return psd.err(409, "Cannot deploy: %s.", err)
+		}
+	}
+
+	// Values the deployment would inherit anyway are not written to the
+	// manifest, unless it already had them.
+	if err := psd.GDM.Defs.UnmergeClusterSpec(m, did.Cluster, psd.Body.Deployment, original); err != nil {
+		return psd.err(500, "Failed to compute new deployment spec: %s", err)
+	}
+	psd.GDM.Defs.SetClusterSpec(m, did.Cluster, *psd.Body.Deployment)
 
 	user := sous.User(psd.GetUser(psd.req))
 
@@ -233,6 +338,7 @@ func (psd *PUTSingleDeploymentHandler) Exchange() (interface{}, int) {
 	}}, psd.log.Child("r11n"))
 
 	r.Pair.SetID(did)
+	r.PlanRollout(prior)
 
 	postID := ""
 	version := ""
@@ -367,6 +367,110 @@ func TestPUTSingleDeploymentHandler_Exchange(t *testing.T) {
 			"sous.example.com/deploy-queue-item?action=actionid1&cluster=cluster1&flavor=flavor1&offset=dir1&repo=github.com%2Fuser1%2Frepo1")
 	})
 
+	freeze := func(scenario *psdhExScenario, breakGlass bool) {
+		scenario.gdm.Defs.Clusters["cluster1"].FreezeWindows = sous.FreezeWindows{{
+			Reason:     "holiday",
+			BreakGlass: breakGlass,
+		}}
+	}
+
+	t.Run("frozen cluster", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		freeze(scenario, true)
+		scenario.exercise()
+
+		scenario.assertStatus(t, http.StatusLocked)
+		scenario.assertStringBody(t, `cluster "cluster1" is frozen until further notice: holiday`)
+		scenario.assertStringBody(t, "-break-glass")
+		scenario.assertNoR11nQueued(t)
+		if scenario.stateManager.WriteCount != 0 {
+			t.Errorf("Expected no write to a frozen cluster; written %d times.", scenario.stateManager.WriteCount)
+		}
+	})
+
+	t.Run("frozen cluster, break glass", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		query["break-glass"] = "true"
+		scenario := setup(body, query)
+		freeze(scenario, true)
+		scenario.queueSet.MatchMethod("Push", spies.AnyArgs, &sous.QueuedR11n{ID: "actionid1"}, true)
+		scenario.exercise()
+
+		scenario.assertStatus(t, 201)
+		scenario.assertDeploymentWritten(t)
+		scenario.assertR11nQueued(t)
+	})
+
+	t.Run("frozen cluster, break glass not allowed", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		query["break-glass"] = "true"
+		scenario := setup(body, query)
+		freeze(scenario, false)
+		scenario.exercise()
+
+		scenario.assertStatus(t, http.StatusLocked)
+		scenario.assertNoR11nQueued(t)
+	})
+
+	t.Run("out of promotion order", func(t *testing.T) {
+		body, query := makeBodyAndQuery(t, false)
+		body.Deployment.Version = semv.MustParse("2.0.0")
+		scenario := setup(body, query)
+		scenario.gdm.Defs.PromotionOrder = []string{"cluster2", "cluster1"}
+		scenario.exercise()
+
+		scenario.assertStatus(t, 409)
+		scenario.assertStringBody(t, "cluster2 must be running it first")
+		scenario.assertNoR11nQueued(t)
+	})
+}
+
+func TestGETSingleDeploymentHandler_Exchange_status(t *testing.T) {
+	d, spy := sous.NewDeployerSpy()
+	spy.MatchMethod("Status", spies.AnyArgs, &sous.DeployState{Status: sous.DeployStatusActive}, nil)
+	cl := ComponentLocator{
+		StateManager: defaultStateManager(),
+		Deployer:     d,
+	}
+	r := newSingleDeploymentResource(cl)
+
+	query := url.Values{}
+	query.Set("repo", "github.com/user1/repo1")
+	query.Set("offset", "dir1")
+	query.Set("flavor", "flavor1")
+	query.Set("cluster", "cluster1")
+
+	get := func(status string) (interface{}, int) {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		if status != "" {
+			q.Set("status", status)
+		}
+		req := httptest.NewRequest("GET", "http://sous.example.com/single-deployment?"+q.Encode(), nil)
+		ls, _ := logging.NewLogSinkSpy()
+		return r.Get(nil, ls, httptest.NewRecorder(), req, nil).Exchange()
+	}
+
+	body, status := get("")
+	assert.Equal(t, 200, status)
+	assert.Nil(t, body.(SingleDeploymentBody).DeployState)
+	assert.Len(t, spy.CallsTo("Status"), 0)
+
+	body, status = get("true")
+	assert.Equal(t, 200, status)
+	if ds := body.(SingleDeploymentBody).DeployState; assert.NotNil(t, ds) {
+		assert.Equal(t, sous.DeployStatusActive, ds.Status)
+	}
+	assert.Len(t, spy.CallsTo("Status"), 1)
+
+	_, status = get("maybe")
+	assert.Equal(t, 400, status)
 }
 
 func TestMakeSingularityURL_valid(t *testing.T) {
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -46,7 +47,7 @@ func (sdr *StateDefResource) Get(*restful.RouteMap, logging.LogSink, http.Respon
 // handle PUT requests.)
 func (sdr *StateDefResource) Put(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
 	return &StateDefPutHandler{
-		StateManager: sdr.context.StateManager,
+		StateManager: sdr.context.tracedStateManager(req),
 		req:          req,
 		user:         sdr.GetUser(req),
 	}
@@ -65,7 +66,29 @@ func (sdg *StateDefGetHandler) Exchange() (interface{}, int) {
 func (sdp *StateDefPutHandler) Exchange() (interface{}, int) {
 	defs := sous.Defs{}
 	dec := json.NewDecoder(sdp.req.Body)
-	dec.Decode(&defs)
+	if err := dec.Decode(&defs); err != nil {
+		return fmt.Sprintf("Invalid defs: %s", err), http.StatusBadRequest
+	}
+
+	for name, c := range defs.Clusters {
+		for _, fw := range c.FreezeWindows {
+			if err := fw.Validate(); err != nil {
+				return fmt.Sprintf("Invalid freeze window for cluster %q: %s", name, err), http.StatusBadRequest
+			}
+		}
+		if _, err := sous.ParseSigningKeys(c.SigningKeys); err != nil {
+			return fmt.Sprintf("Invalid signing keys for cluster %q: %s", name, err), http.StatusBadRequest
+		}
+		if err := c.VulnerabilityThresholds.Validate(); err != nil {
+			return fmt.Sprintf("Invalid vulnerability thresholds for cluster %q: %s", name, err), http.StatusBadRequest
+		}
+		if err := c.ResourceLimits.Validate(defs.Resources); err != nil {
+			return fmt.Sprintf("Invalid resource limits for cluster %q: %s", name, err), http.StatusBadRequest
+		}
+	}
+	if err := defs.CheckPromotionOrder(); err != nil {
+		return fmt.Sprintf("Invalid promotion order: %s", err), http.StatusBadRequest
+	}
 
 	state, err := sdp.StateManager.ReadState()
 	if err != nil {
@@ -73,6 +96,22 @@ func (sdp *StateDefPutHandler) Exchange() (interface{}, int) {
 		return msg, http.StatusInternalServerError
 	}
 
+	// Flaws which can be repaired, e.g. a cluster missing a cluster scoped
+	// Env var, are repaired in the defs written.
+	if flaws, _ := sous.RepairAll(defs.Validate()); len(flaws) > 0 {
+		return "Invalid defs: " + describeFlaws(flaws), http.StatusBadRequest
+	}
+	// The manifests must still conform to the new defs, apart from missing
+	// values which the new defs give defaults for.
+	var flaws []sous.Flaw
+	for _, m := range state.Manifests.Snapshot() {
+		unrepaired, _ := sous.RepairAll(defs.ValidateManifest(m.Clone()))
+		flaws = append(flaws, unrepaired...)
+	}
+	if len(flaws) > 0 {
+		return "Defs would invalidate manifests: " + describeFlaws(flaws), http.StatusConflict
+	}
+
 	state.Defs = defs
 	err = sdp.StateManager.WriteState(state, sous.User(sdp.user))
 	if err != nil {
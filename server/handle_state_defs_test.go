@@ -1,6 +1,11 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/opentable/sous/lib"
@@ -29,3 +34,48 @@ func TestStateDefGet(t *testing.T) {
 		t.Errorf("returned data wasn't a sous.Defs: %T", defs)
 	}
 }
+
+func TestStateDefPut(t *testing.T) {
+	clusters := sous.Clusters{"cluster-1": &sous.Cluster{Name: "cluster-1"}}
+	put := func(defs sous.Defs) (interface{}, int) {
+		state := sous.NewState()
+		state.Defs.Clusters = clusters
+		state.Manifests.Add(&sous.Manifest{
+			Source: sous.SourceLocation{Repo: "github.com/user/project"},
+			Kind:   sous.ManifestKindService,
+			Deployments: sous.DeploySpecs{
+				"cluster-1": {DeployConfig: sous.DeployConfig{Metadata: sous.Metadata{"tier": "gold"}}},
+			},
+		})
+		body, err := json.Marshal(defs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sm := &sous.DummyStateManager{State: state}
+		h := &StateDefPutHandler{
+			StateManager: sm,
+			req:          httptest.NewRequest("PUT", "/defs", bytes.NewReader(body)),
+		}
+		return h.Exchange()
+	}
+
+	defs := sous.Defs{Clusters: clusters}
+	defs.Metadata = sous.FieldDefinitions{{Name: "tier", Type: "enum:gold,silver"}}
+	if _, status := put(defs); status != 204 {
+		t.Errorf("status was %d, not 204", status)
+	}
+
+	defs.Metadata[0].Type = "int"
+	data, status := put(defs)
+	if status != 409 {
+		t.Errorf("status was %d, not 409", status)
+	}
+	if msg := fmt.Sprint(data); !strings.Contains(msg, `Metadata tier: "gold" is not an int`) {
+		t.Errorf("response %q doesn't name the flaw", msg)
+	}
+
+	defs.Metadata[0].Type = "float"
+	if data, status := put(defs); status != 400 {
+		t.Errorf("status was %d, not 400: %v", status, data)
+	}
+}
@@ -2,6 +2,9 @@ package server
 
 import (
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/opentable/sous/lib"
@@ -18,33 +21,95 @@ type (
 	// StatusHandler handles requests for status.
 	StatusHandler struct {
 		AutoResolver *sous.AutoResolver
+		Rollbacker   *sous.Rollbacker
+		DeployStates *sous.DeployStateCache
 		*sous.ResolveFilter
+		// Since is the Version of the status the client last received, if
+		// it is waiting for the next.
+		Since *uint64
 	}
 
 	statusData struct {
 		Deployments           []*sous.Deployment
 		Completed, InProgress *sous.ResolveStatus
+		// Rollbacks lists recent automatic rollbacks of failed deployments.
+		Rollbacks []sous.RollbackEvent `json:",omitempty"`
+		// DeployStates lists the states of deployments reported by the
+		// scheduler since they were last resolved.
+		DeployStates []*sous.DeployState `json:",omitempty"`
+		// Version increases whenever Completed or DeployStates change.
+		// Requesting /status?since=<Version> waits for the next change.
+		Version uint64
 	}
 )
 
+// StatusWaitTime is the longest a request for /status waits for it to
+// change.
+var StatusWaitTime = 20 * time.Second
+
 func newStatusResource(ctx ComponentLocator) *StatusResource {
 	return &StatusResource{context: ctx}
 }
 
 // Get implements Getable on StatusResource.
-func (sr *StatusResource) Get(*restful.RouteMap, logging.LogSink, http.ResponseWriter, *http.Request, httprouter.Params) restful.Exchanger {
-	return &StatusHandler{
+func (sr *StatusResource) Get(_ *restful.RouteMap, _ logging.LogSink, _ http.ResponseWriter, req *http.Request, _ httprouter.Params) restful.Exchanger {
+	h := &StatusHandler{
 		AutoResolver:  sr.context.AutoResolver,
+		Rollbacker:    sr.context.Rollbacker,
+		DeployStates:  sr.context.DeployStates,
 		ResolveFilter: sr.context.ResolveFilter,
 	}
+	if since, err := strconv.ParseUint(req.URL.Query().Get("since"), 10, 64); err == nil {
+		h.Since = &since
+	}
+	return h
 }
 
 // Exchange implements the Handler interface.
 func (h *StatusHandler) Exchange() (interface{}, int) {
-	status := statusData{}
+	if h.Since != nil {
+		h.wait(*h.Since)
+	}
+	status := statusData{Version: h.version()}
 	for _, d := range h.AutoResolver.GDM.Filter(h.ResolveFilter.FilterDeployment).Snapshot() {
 		status.Deployments = append(status.Deployments, d)
 	}
 	status.Completed, status.InProgress = h.AutoResolver.Statuses()
+	status.Rollbacks = h.Rollbacker.Events()
+	for _, ds := range h.DeployStates.Snapshot() {
+		if h.ResolveFilter.FilterDeployment(&ds.Deployment) {
+			status.DeployStates = append(status.DeployStates, ds)
+		}
+	}
+	sort.Slice(status.DeployStates, func(i, j int) bool {
+		return status.DeployStates[i].ID().String() < status.DeployStates[j].ID().String()
+	})
 	return status, http.StatusOK
 }
+
+// version is the sum of the versions of the stable status and DeployStates,
+// which changes whenever either does.
+func (h *StatusHandler) version() uint64 {
+	rv, _ := h.AutoResolver.StatusVersion()
+	dv, _ := h.DeployStates.Version()
+	return rv + dv
+}
+
+// wait returns when the version of the status is no longer since, or after
+// StatusWaitTime.
+func (h *StatusHandler) wait(since uint64) {
+	timeout := time.After(StatusWaitTime)
+	for {
+		rv, resolved := h.AutoResolver.StatusVersion()
+		dv, deployed := h.DeployStates.Version()
+		if rv+dv != since {
+			return
+		}
+		select {
+		case <-resolved:
+		case <-deployed:
+		case <-timeout:
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"testing"
+	"time"
 
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -36,3 +37,56 @@ func TestHandlesStatusGet(t *testing.T) {
 	assert.Equal(status, 200)
 	assert.Len(data.(statusData).Deployments, 0)
 }
+
+func TestHandlesStatusGet_since(t *testing.T) {
+	states := sous.NewDeployStateCache()
+	th := &StatusHandler{
+		AutoResolver:  &sous.AutoResolver{GDM: sous.NewDeployments(), LogSink: logging.SilentLogSet()},
+		DeployStates:  states,
+		ResolveFilter: &sous.ResolveFilter{},
+	}
+	since := uint64(0)
+	th.Since = &since
+
+	did := sous.DeploymentID{ManifestID: sous.MustParseManifestID("github.com/opentable/example"), Cluster: "cluster1"}
+	ds := &sous.DeployState{Status: sous.DeployStatusActive}
+	ds.ClusterName = "cluster1"
+	ds.SourceID = sous.MustNewSourceID("github.com/opentable/example", "", "1.0.0")
+
+	exchanged := make(chan statusData)
+	go func() {
+		data, _ := th.Exchange()
+		exchanged <- data.(statusData)
+	}()
+	select {
+	case <-exchanged:
+		t.Fatal("Exchange should wait for the status to change")
+	case <-time.After(10 * time.Millisecond):
+	}
+	states.Update(did, ds)
+
+	select {
+	case data := <-exchanged:
+		assert.Equal(t, uint64(1), data.Version)
+		if assert.Len(t, data.DeployStates, 1) {
+			assert.Equal(t, sous.DeployStatusActive, data.DeployStates[0].Status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Exchange should return when the status changes")
+	}
+}
+
+func TestHandlesStatusGet_sinceTimeout(t *testing.T) {
+	defer func(wait time.Duration) { StatusWaitTime = wait }(StatusWaitTime)
+	StatusWaitTime = 10 * time.Millisecond
+
+	th := &StatusHandler{
+		AutoResolver:  &sous.AutoResolver{GDM: sous.NewDeployments(), LogSink: logging.SilentLogSet()},
+		ResolveFilter: &sous.ResolveFilter{},
+	}
+	since := uint64(0)
+	th.Since = &since
+	data, status := th.Exchange()
+	assert.Equal(t, 200, status)
+	assert.Equal(t, uint64(0), data.(statusData).Version)
+}
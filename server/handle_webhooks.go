@@ -0,0 +1,54 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/opentable/sous/ext/singularity"
+	"github.com/opentable/sous/util/logging"
+)
+
+// maxWebhookSize is the largest webhook body read: Singularity's include
+// the whole request, deploy or task, but not its logs.
+const maxWebhookSize = 1 << 20
+
+// SingularityWebhookHandler receives the webhooks Singularity POSTs to
+// singularity.WebhookPath. It is not a restful resource, since restful does
+// not route POSTs.
+type SingularityWebhookHandler struct {
+	Webhooks *singularity.Webhooks
+	log      logging.LogSink
+}
+
+func addWebhooks(handler *http.ServeMux, sc ComponentLocator, ls logging.LogSink) {
+	if sc.SingularityWebhooks == nil {
+		return
+	}
+	handler.Handle(singularity.WebhookPath, &SingularityWebhookHandler{Webhooks: sc.SingularityWebhooks, log: ls})
+}
+
+// ServeHTTP implements http.Handler on SingularityWebhookHandler.
+func (h *SingularityWebhookHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "webhooks must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := req.URL.Query()
+	if !h.Webhooks.Authentic(q.Get("secret")) {
+		http.Error(rw, "unknown webhook secret", http.StatusForbidden)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.Webhooks.Receive(q.Get("type"), body); err != nil {
+		logging.ReportError(h.log, err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
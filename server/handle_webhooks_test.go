@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/singularity"
+	"github.com/opentable/sous/lib"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingularityWebhookHandler(t *testing.T) {
+	sm := sous.NewDummyStateManager()
+	sm.State = sous.DefaultStateFixture()
+	gdm, err := sm.State.Deployments()
+	require.NoError(t, err)
+	d := gdm.Snapshot()[sous.DeploymentID{ManifestID: sm.State.Manifests.Keys()[0], Cluster: "cluster1"}]
+	require.NotNil(t, d)
+
+	ls := logging.SilentLogSet()
+	events := sous.NewResolveEvents()
+	webhooks := singularity.NewWebhooks("https://sous.example.com", "s3cret", sm,
+		sous.NewDeployStateCache(), events, ls)
+	locator := ComponentLocator{
+		LogSink:             ls,
+		Config:              &config.Config{},
+		StateManager:        sm,
+		ResolveFilter:       &sous.ResolveFilter{},
+		AutoResolver:        &sous.AutoResolver{},
+		SingularityWebhooks: webhooks,
+	}
+	srv := httptest.NewServer(Handler(locator, http.NotFoundHandler(), ls))
+	defer srv.Close()
+
+	sender := singularity.NewWebhookSender(srv.URL, "s3cret")
+	require.NoError(t, sender.SendRequest(d.DeployConfig.SingularityRequestID, "UPDATED"))
+	assert.Equal(t, []sous.DeploymentID{d.ID()}, events.Affected(gdm))
+
+	require.NoError(t, sender.SendRequest("not-sous", "UPDATED"), "other requests should be ignored")
+	assert.Len(t, events.Affected(gdm), 0)
+
+	rz, err := http.Post(srv.URL+singularity.WebhookPath+"?type=offer&secret=s3cret", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	rz.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, rz.StatusCode, "unknown kinds of webhook should be rejected")
+
+	sender.Secret = "guess"
+	assert.Error(t, sender.SendRequest(d.DeployConfig.SingularityRequestID, "UPDATED"))
+
+	rz, err = http.Get(srv.URL + singularity.WebhookPath)
+	require.NoError(t, err)
+	rz.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, rz.StatusCode)
+}
@@ -42,6 +42,26 @@ func forceFromValues(qv restful.QueryValues) (force bool, err error) {
 	return force, nil
 }
 
+// breakGlassFromValues reads the optional break-glass parameter, which asks
+// for a deployment to be allowed through a cluster freeze.
+func breakGlassFromValues(qv restful.QueryValues) (bool, error) {
+	bg, err := qv.Single("break-glass", "false")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(bg)
+}
+
+// statusFromValues reads the optional status parameter, which asks for the
+// current DeployState to be included with a single deployment.
+func statusFromValues(qv restful.QueryValues) (bool, error) {
+	s, err := qv.Single("status", "false")
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(s)
+}
+
 func deploymentIDFromValues(qv restful.QueryValues) (sous.DeploymentID, error) {
 	cluster, err := qv.Single("cluster")
 	if err != nil {
@@ -1,11 +1,14 @@
 package server
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"strings"
 
 	"github.com/opentable/sous/config"
+	"github.com/opentable/sous/ext/singularity"
 	"github.com/opentable/sous/ext/storage"
 	"github.com/opentable/sous/lib"
 	"github.com/opentable/sous/util/logging"
@@ -19,6 +22,10 @@ type (
 	userExtractor struct{}
 )
 
+// unverifiedUserSuffix is appended to the names of users who identified
+// themselves only with the Sous-User headers.
+const unverifiedUserSuffix = "(unverified)"
+
 type (
 	// ComponentLocator is a service locator for the Sous components that server
 	// endpoints need to function.
@@ -32,8 +39,32 @@ type (
 		sous.DeploymentManager // xxx temporary?
 		ResolveFilter          *sous.ResolveFilter
 		*sous.AutoResolver
-		Version  semv.Version
-		QueueSet sous.QueueSet
+		Rollbacker        *sous.Rollbacker
+		Deployer          sous.Deployer
+		DeploymentHistory sous.DeploymentHistory
+		AuditLog          sous.AuditLog
+		Version           semv.Version
+		QueueSet          sous.QueueSet
+		// Identity identifies the callers of requests. Writes are then
+		// authorized against manifest owners and the admin group. If it is
+		// nil, writes are unrestricted.
+		Identity restful.IdentitySource
+		// Secrets is checked for the secrets referred to by deployments
+		// before they are written. If it is nil, deployments may not refer to
+		// secrets.
+		Secrets sous.SecretStore
+		// Builds builds source code on request. If it is nil, this server
+		// does not build.
+		Builds *sous.BuildJobs
+		// Provenance looks up how artifacts were built. If it is nil, it is
+		// not available.
+		Provenance sous.ProvenanceRegistry
+		// SingularityWebhooks receives webhooks from Singularity. If it is
+		// nil, they are not received.
+		SingularityWebhooks *singularity.Webhooks
+		// DeployStates is kept up to date by SingularityWebhooks, and
+		// reported with the server's status.
+		DeployStates *sous.DeployStateCache
 	}
 )
 
@@ -50,11 +81,40 @@ func (ctx ComponentLocator) liveState() *sous.State {
 	return state
 }
 
+// tracedStateManager returns the StateManager, with any audit entries for
+// writes made through it tagged with the trace ID of req.
+func (ctx ComponentLocator) tracedStateManager(req *http.Request) sous.StateManager {
+	return sous.TracedStateManager(ctx.StateManager, sous.TraceID(req.Header.Get("OT-RequestId")))
+}
+
+// promotionChecker returns a PromotionChecker which checks the previous stage
+// of a deployment using this server's Deployer.
+func (ctx ComponentLocator) promotionChecker() sous.PromotionChecker {
+	return sous.PromotionChecker{
+		Deployer: ctx.Deployer,
+		Registry: ctx.Registry,
+		History:  ctx.DeploymentHistory,
+	}
+}
+
+// GetUser returns the user who made req. When the server identifies its
+// callers, that is the caller's Principal, and anonymous callers are an empty
+// user. Otherwise, the only record of the user is the Sous-User headers, which
+// the client is free to set, so the user's name is marked as unverified.
 func (userExtractor) GetUser(req *http.Request) ClientUser {
+	if restful.Identified(req) {
+		if p := restful.PrincipalFromRequest(req); p != nil {
+			return ClientUser{Name: p.Name, Email: p.Email}
+		}
+		return ClientUser{}
+	}
 	clu := ClientUser{
 		Name:  req.Header.Get("Sous-User-Name"),
 		Email: req.Header.Get("Sous-User-Email"),
 	}
+	if clu.Name != "" || clu.Email != "" {
+		clu.Name = strings.TrimSpace(clu.Name + " " + unverifiedUserSuffix)
+	}
 
 	return clu
 }
@@ -69,6 +129,17 @@ func Run(laddr string, handler http.Handler) (*http.Server, <-chan error) {
 	return s, errs
 }
 
+// RunTLS starts a server up, serving HTTPS with the certificate and key in
+// certFile and keyFile.
+func RunTLS(laddr string, handler http.Handler, tlsConfig *tls.Config, certFile, keyFile string) (*http.Server, <-chan error) {
+	s := &http.Server{Addr: laddr, Handler: handler, TLSConfig: tlsConfig}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.ListenAndServeTLS(certFile, keyFile)
+	}()
+	return s, errs
+}
+
 // Handler builds the http.Handler for the Sous server httprouter.
 func Handler(sc ComponentLocator, metrics http.Handler, ls logging.LogSink) http.Handler {
 	handler := mux(sc, ls)
@@ -85,10 +156,11 @@ func ProfilingHandler(sc ComponentLocator, metrics http.Handler, ls logging.LogS
 }
 
 func mux(sc ComponentLocator, ls logging.LogSink) *http.ServeMux {
-	router := routemap(sc).BuildRouter(ls)
+	router := routemap(sc).BuildAuthorizingRouter(ls, sc.Identity)
 
 	handler := http.NewServeMux()
 	handler.Handle("/", router)
+	addWebhooks(handler, sc, ls)
 	return handler
 }
 
@@ -98,6 +170,7 @@ func routemap(context ComponentLocator) *restful.RouteMap {
 		re("defs", "/defs", newStateDefResource(context))
 		re("manifest", "/manifest", newManifestResource(context))
 		re("artifact", "/artifact", newArtifactResource(context))
+		re("build", "/build", newBuildResource(context))
 		re("status", "/status", newStatusResource(context))
 		re("servers", "/servers", newServerListResource(context))
 		re("health", "/health", newHealthResource(context))
@@ -106,6 +179,8 @@ func routemap(context ComponentLocator) *restful.RouteMap {
 		re("deploy-queue", "/deploy-queue", newDeployQueueResource(context))
 		re("deploy-queue-item", "/deploy-queue-item", newR11nResource(context))
 		re("single-deployment", "/single-deployment", newSingleDeploymentResource(context))
+		re("deployment-history", "/deployment-history", newDeploymentHistoryResource(context))
+		re("audit", "/audit", newAuditResource(context))
 		re("default", "/", newDefaultResource(context))
 	})
 }
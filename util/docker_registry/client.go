@@ -23,6 +23,7 @@ import (
 	"github.com/docker/distribution/registry/client/transport"
 	"github.com/opentable/sous/util/logging"
 	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
 
@@ -82,6 +83,10 @@ type (
 		CanonicalName string
 		AllNames      []string
 		OnBuild       []string
+		// Platforms is set when the image name refers to a manifest list. It
+		// maps each platform in the list to the canonical name of its image,
+		// and Labels, Env and OnBuild are those of the DefaultPlatform image.
+		Platforms map[string]string
 	}
 )
 
@@ -333,6 +338,16 @@ func (c *liveClient) metadataForImage(regHost string, ref reference.Named, etag
 	md.CanonicalName = ref.Name() + "@" + dg.String()
 	md.AllNames[1] = md.CanonicalName
 
+	if list, is := mani.(*ManifestList); is {
+		md.Platforms = map[string]string{}
+		for p, d := range list.Platforms() {
+			md.Platforms[p] = ref.Name() + "@" + d.String()
+		}
+		if mani, err = c.labelledManifest(rep, ref, list); err != nil {
+			return Metadata{}, err
+		}
+	}
+
 	switch mani := mani.(type) {
 	case *schema1.SignedManifest:
 		history := mani.History
@@ -397,6 +412,24 @@ func (c *liveClient) metadataForImage(regHost string, ref reference.Named, etag
 	}
 }
 
+// labelledManifest returns the manifest of the image in list whose labels
+// stand for the list's.
+func (c *liveClient) labelledManifest(rep *registry, ref reference.Named, list *ManifestList) (distribution.Manifest, error) {
+	d, err := list.labelledImage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", ref)
+	}
+	imgRef, err := digestRef(ref, d.String())
+	if err != nil {
+		return nil, err
+	}
+	mani, _, _, err := rep.getManifestWithEtag(c.ctx, imgRef, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting %s image of %s", d, ref)
+	}
+	return mani, nil
+}
+
 /*
  */
 
@@ -554,7 +587,7 @@ func (r *registry) manifestFromResponse(resp *http.Response) (distribution.Manif
 		case *schema1.SignedManifest:
 			//log.Print(string(v.Canonical))
 			d = digest.FromBytes(v.Canonical)
-		case *schema2.DeserializedManifest:
+		case *schema2.DeserializedManifest, *ManifestList:
 			_, pl, err := m.Payload()
 			if err != nil {
 				return nil, "", err
@@ -0,0 +1,159 @@
+package docker_registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MediaTypeManifestList is the media type of a Docker manifest list.
+	MediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	// MediaTypeImageIndex is the media type of an OCI image index, which is
+	// the OCI equivalent of a manifest list.
+	MediaTypeImageIndex = "application/vnd.oci.image.index.v1+json"
+	// MediaTypeImageManifest is the media type of an OCI image manifest. Its
+	// layout is that of a schema2 manifest.
+	MediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+
+	// DefaultPlatform is the platform whose image labels are read when an
+	// image name refers to a manifest list.
+	DefaultPlatform = "linux/amd64"
+)
+
+type (
+	// ManifestList is a manifest list or image index: it names an image built
+	// for each of several platforms.
+	ManifestList struct {
+		SchemaVersion int                  `json:"schemaVersion"`
+		MediaType     string               `json:"mediaType,omitempty"`
+		Manifests     []PlatformDescriptor `json:"manifests"`
+
+		canonical []byte
+	}
+
+	// PlatformDescriptor is an entry in a ManifestList.
+	PlatformDescriptor struct {
+		distribution.Descriptor
+		Platform Platform `json:"platform"`
+	}
+
+	// Platform describes the platform an image runs on.
+	Platform struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant,omitempty"`
+	}
+)
+
+func init() {
+	list := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := &ManifestList{}
+		if err := json.Unmarshal(b, m); err != nil {
+			return nil, distribution.Descriptor{}, errors.Wrap(err, "parsing manifest list")
+		}
+		m.canonical = b
+		mt := m.MediaType
+		if mt == "" {
+			mt = MediaTypeImageIndex
+		}
+		return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: mt}, nil
+	}
+	oci := func(b []byte) (distribution.Manifest, distribution.Descriptor, error) {
+		m := &schema2.DeserializedManifest{}
+		if err := m.UnmarshalJSON(b); err != nil {
+			return nil, distribution.Descriptor{}, err
+		}
+		return m, distribution.Descriptor{Digest: digest.FromBytes(b), Size: int64(len(b)), MediaType: MediaTypeImageManifest}, nil
+	}
+	for mt, u := range map[string]distribution.UnmarshalFunc{
+		MediaTypeManifestList:  list,
+		MediaTypeImageIndex:    list,
+		MediaTypeImageManifest: oci,
+	} {
+		if err := distribution.RegisterManifestSchema(mt, u); err != nil {
+			panic(fmt.Sprintf("Unable to register manifest: %s", err))
+		}
+	}
+}
+
+// References implements distribution.Manifest on ManifestList.
+func (ml *ManifestList) References() []distribution.Descriptor {
+	ds := make([]distribution.Descriptor, len(ml.Manifests))
+	for i, m := range ml.Manifests {
+		ds[i] = m.Descriptor
+	}
+	return ds
+}
+
+// Payload implements distribution.Manifest on ManifestList.
+func (ml *ManifestList) Payload() (string, []byte, error) {
+	return ml.MediaType, ml.canonical, nil
+}
+
+// Platforms returns the digest of the image for each platform in ml.
+// Entries which are not images, such as the attestations some builders
+// attach to an index, are left out.
+func (ml *ManifestList) Platforms() map[string]digest.Digest {
+	ps := map[string]digest.Digest{}
+	for _, m := range ml.Manifests {
+		if m.Platform.OS == "" || m.Platform.OS == "unknown" {
+			continue
+		}
+		ps[m.Platform.String()] = m.Digest
+	}
+	return ps
+}
+
+// labelledImage returns the digest of the image whose labels stand for
+// those of the whole list: DefaultPlatform if it is listed, otherwise the
+// first platform in order of name.
+func (ml *ManifestList) labelledImage() (digest.Digest, error) {
+	ps := ml.Platforms()
+	if d, ok := ps[DefaultPlatform]; ok {
+		return d, nil
+	}
+	first := ""
+	for p := range ps {
+		if first == "" || p < first {
+			first = p
+		}
+	}
+	if first == "" {
+		return "", errors.New("manifest list names no images")
+	}
+	return ps[first], nil
+}
+
+// ParsePlatform parses a platform written os/architecture[/variant], as
+// given to `docker build --platform`.
+func ParsePlatform(s string) (Platform, error) {
+	var p Platform
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	default:
+		return p, errors.Errorf("platform %q must be os/architecture[/variant]", s)
+	case 3:
+		p.Variant = parts[2]
+		fallthrough
+	case 2:
+		p.OS, p.Architecture = parts[0], parts[1]
+	}
+	if p.OS == "" || p.Architecture == "" {
+		return p, errors.Errorf("platform %q must be os/architecture[/variant]", s)
+	}
+	return p, nil
+}
+
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package docker_registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/distribution/digest"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatform(t *testing.T) {
+	p, err := ParsePlatform("linux/arm/v7")
+	require.NoError(t, err)
+	assert.Equal(t, Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, p)
+	assert.Equal(t, "linux/arm/v7", p.String())
+
+	p, err = ParsePlatform("linux/amd64")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPlatform, p.String())
+
+	for _, bad := range []string{"", "linux", "linux/", "/amd64", "a/b/c/d"} {
+		_, err := ParsePlatform(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestGetImageMetadata_manifestList(t *testing.T) {
+	config := `{"config": {"Labels": {"com.opentable.sous.repo_url": "github.com/opentable/test"}, "Env": ["A=1"]}}`
+	configDigest := digest.FromBytes([]byte(config))
+	amd64 := fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q, "config": {"mediaType": %q, "size": %d, "digest": %q}, "layers": []}`,
+		MediaTypeImageManifest, schema2.MediaTypeConfig, len(config), configDigest)
+	amd64Digest := digest.FromBytes([]byte(amd64))
+	arm64Digest := digest.FromBytes([]byte("arm64"))
+	list := fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q, "manifests": [
+		{"mediaType": %q, "digest": %q, "size": 1, "platform": {"os": "linux", "architecture": "arm64"}},
+		{"mediaType": %q, "digest": %q, "size": %d, "platform": {"os": "linux", "architecture": "amd64"}},
+		{"mediaType": %q, "digest": "sha256:aaaa", "size": 1, "platform": {"os": "unknown", "architecture": "unknown"}}
+	]}`, MediaTypeImageIndex,
+		MediaTypeImageManifest, arm64Digest,
+		MediaTypeImageManifest, amd64Digest, len(amd64),
+		MediaTypeImageManifest)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		default:
+			http.NotFound(w, r)
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/test/app/manifests/1.2.3":
+			w.Header().Set("Content-Type", MediaTypeImageIndex)
+			fmt.Fprint(w, list)
+		case "/v2/test/app/manifests/" + amd64Digest.String():
+			w.Header().Set("Content-Type", MediaTypeImageManifest)
+			fmt.Fprint(w, amd64)
+		case "/v2/test/app/blobs/" + configDigest.String():
+			fmt.Fprint(w, config)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	c := NewClient(logging.SilentLogSet())
+	c.BecomeFoolishlyTrusting()
+	md, err := c.GetImageMetadata(host+"/test/app:1.2.3", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "test/app@"+digest.FromBytes([]byte(list)).String(), md.CanonicalName)
+	assert.Equal(t, map[string]string{
+		"linux/amd64": "test/app@" + amd64Digest.String(),
+		"linux/arm64": "test/app@" + arm64Digest.String(),
+	}, md.Platforms)
+	assert.Equal(t, "github.com/opentable/test", md.Labels["com.opentable.sous.repo_url"])
+	assert.Equal(t, map[string]string{"A": "1"}, md.Env)
+}
@@ -0,0 +1,261 @@
+package restful
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/util/logging"
+	"github.com/opentable/sous/util/logging/messages"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Principal is the identity of the caller of a request.
+	Principal struct {
+		Name, Email string
+		// Groups are the groups the principal belongs to.
+		Groups []string
+	}
+
+	// An IdentitySource determines who made a request. Identify returns a nil
+	// Principal for anonymous requests, and an error if the request carries
+	// credentials that are not valid.
+	IdentitySource interface {
+		Identify(*http.Request) (*Principal, error)
+	}
+
+	// An Authorizer is implemented by Resources which restrict who may write
+	// to them. When a RouteMap is built with an IdentitySource, every PUT and
+	// DELETE is passed to Authorize before it is handled, and refused with 403
+	// if it returns an error. The request's body may be read by Authorize; the
+	// handler receives a fresh copy of it.
+	Authorizer interface {
+		Authorize(p *Principal, req *http.Request) error
+	}
+
+	// TrustedHeaderIdentity identifies callers from headers set by a proxy
+	// which has already authenticated them. It must only be used when the
+	// server cannot be reached except through that proxy.
+	TrustedHeaderIdentity struct {
+		// UserHeader holds the name of the caller. Requests without it are
+		// anonymous.
+		UserHeader string
+		// EmailHeader and GroupsHeader are optional. GroupsHeader holds a comma
+		// separated list.
+		EmailHeader, GroupsHeader string
+	}
+
+	// ClientCertIdentity identifies callers by the TLS client certificate
+	// they presented, which the server must be configured to verify. The
+	// certificate's common name is the principal's name, and its
+	// organizational units are the principal's groups.
+	ClientCertIdentity struct{}
+
+	// TokenFileIdentity identifies callers by a bearer token in the
+	// Authorization header, looked up in a fixed set of tokens.
+	TokenFileIdentity struct {
+		tokens map[string]Principal
+	}
+
+	principalKey struct{}
+
+	statusExchanger struct {
+		data   interface{}
+		status int
+	}
+)
+
+func (p *Principal) String() string {
+	if p == nil {
+		return "anonymous"
+	}
+	if p.Email == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s <%s>", p.Name, p.Email)
+}
+
+// Is returns true if p is known by name, email or group as any of names.
+func (p *Principal) Is(names ...string) bool {
+	if p == nil {
+		return false
+	}
+	for _, n := range names {
+		if n == "" {
+			continue
+		}
+		if n == p.Name || n == p.Email {
+			return true
+		}
+		for _, g := range p.Groups {
+			if n == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrincipalFromRequest returns the Principal identified for req, if the
+// request was identified by its RouteMap.
+func PrincipalFromRequest(req *http.Request) *Principal {
+	p, _ := req.Context().Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Identified returns true if its RouteMap tried to identify the caller of
+// req, even if they turned out to be anonymous. It is false when the RouteMap
+// has no IdentitySource.
+func Identified(req *http.Request) bool {
+	_, ok := req.Context().Value(principalKey{}).(*Principal)
+	return ok
+}
+
+// Identify implements IdentitySource on TrustedHeaderIdentity.
+func (id TrustedHeaderIdentity) Identify(req *http.Request) (*Principal, error) {
+	name := req.Header.Get(id.UserHeader)
+	if name == "" {
+		return nil, nil
+	}
+	p := &Principal{Name: name}
+	if id.EmailHeader != "" {
+		p.Email = req.Header.Get(id.EmailHeader)
+	}
+	if id.GroupsHeader != "" {
+		for _, g := range strings.Split(req.Header.Get(id.GroupsHeader), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				p.Groups = append(p.Groups, g)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Identify implements IdentitySource on ClientCertIdentity.
+func (ClientCertIdentity) Identify(req *http.Request) (*Principal, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil, nil
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	p := &Principal{
+		Name:   cert.Subject.CommonName,
+		Groups: append([]string(nil), cert.Subject.OrganizationalUnit...),
+	}
+	if len(cert.EmailAddresses) > 0 {
+		p.Email = cert.EmailAddresses[0]
+	}
+	return p, nil
+}
+
+// NewTokenFileIdentity reads a TokenFileIdentity from a JSON file mapping each
+// token to the Principal it identifies.
+func NewTokenFileIdentity(path string) (*TokenFileIdentity, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading token file")
+	}
+	tokens := map[string]Principal{}
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return nil, errors.Wrapf(err, "parsing token file %s", path)
+	}
+	return &TokenFileIdentity{tokens: tokens}, nil
+}
+
+// Identify implements IdentitySource on TokenFileIdentity.
+func (id *TokenFileIdentity) Identify(req *http.Request) (*Principal, error) {
+	token, ok, err := BearerToken(req)
+	if !ok || err != nil {
+		return nil, err
+	}
+	for t, p := range id.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			p := p
+			return &p, nil
+		}
+	}
+	return nil, errors.New("unknown token")
+}
+
+// BearerToken returns the bearer token from the Authorization header of req,
+// and whether there was one.
+func BearerToken(req *http.Request) (string, bool, error) {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return "", false, nil
+	}
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false, errors.New("Authorization header is not a bearer token")
+	}
+	return parts[1], true, nil
+}
+
+func (sx *statusExchanger) Exchange() (interface{}, int) {
+	return sx.data, sx.status
+}
+
+// authorizing wraps factory so that requests are refused unless the Principal
+// identified for them is authorized by res.
+func (mh *MetaHandler) authorizing(resName string, res Resource, factory ExchangeFactory) ExchangeFactory {
+	if mh.identity == nil {
+		return factory
+	}
+	return mh.identifying(resName, func(rm *RouteMap, ls logging.LogSink, w http.ResponseWriter, r *http.Request, p httprouter.Params) Exchanger {
+		principal := PrincipalFromRequest(r)
+		if err := authorize(res, principal, r); err != nil {
+			mh.reportRefusal(r, resName, principal, err)
+			return &statusExchanger{data: "Forbidden: " + err.Error(), status: http.StatusForbidden}
+		}
+		return factory(rm, ls, w, r, p)
+	})
+}
+
+// identifying wraps factory so that the caller of each request is identified
+// before it is handled, and requests with invalid credentials are refused.
+func (mh *MetaHandler) identifying(resName string, factory ExchangeFactory) ExchangeFactory {
+	if mh.identity == nil {
+		return factory
+	}
+	return func(rm *RouteMap, ls logging.LogSink, w http.ResponseWriter, r *http.Request, p httprouter.Params) Exchanger {
+		principal, err := mh.identity.Identify(r)
+		if err != nil {
+			mh.reportRefusal(r, resName, principal, err)
+			return &statusExchanger{data: "Not authenticated: " + err.Error(), status: http.StatusUnauthorized}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+		return factory(rm, ls, w, r, p)
+	}
+}
+
+// authorize calls res.Authorize with a copy of the body of r, which is then
+// replaced so that it can be read again.
+func authorize(res Resource, p *Principal, r *http.Request) error {
+	az, ok := res.(Authorizer)
+	if !ok {
+		return errors.New("writes are not permitted")
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return errors.Wrapf(err, "reading request body")
+		}
+		r.Body.Close()
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	defer func() { r.Body = ioutil.NopCloser(bytes.NewReader(body)) }()
+	return az.Authorize(p, r)
+}
+
+func (mh *MetaHandler) reportRefusal(r *http.Request, resName string, p *Principal, err error) {
+	messages.ReportLogFieldsMessage(
+		fmt.Sprintf("Refused %s %s (%s) to %s: %s", r.Method, resName, r.URL, p, err),
+		logging.WarningLevel, mh.LogSink)
+}
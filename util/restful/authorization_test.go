@@ -0,0 +1,215 @@
+package restful
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opentable/sous/util/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type authorizingTestResource struct {
+	*TestResource
+	allowed   string
+	gotBody   string
+	principal *Principal
+}
+
+func (tr *authorizingTestResource) Authorize(p *Principal, req *http.Request) error {
+	b, _ := ioutil.ReadAll(req.Body)
+	tr.gotBody = string(b)
+	tr.principal = p
+	if !p.Is(tr.allowed) {
+		return errors.New("not allowed")
+	}
+	return nil
+}
+
+// readingTestResource records how the callers of its GETs were identified.
+type readingTestResource struct {
+	*TestResource
+	identified bool
+	principal  *Principal
+}
+
+func (tr *readingTestResource) Get(rm *RouteMap, ls logging.LogSink, w http.ResponseWriter, req *http.Request, ps httprouter.Params) Exchanger {
+	tr.identified = Identified(req)
+	tr.principal = PrincipalFromRequest(req)
+	return tr.TestResource.Get(rm, ls, w, req, ps)
+}
+
+type failingIdentity struct{}
+
+func (failingIdentity) Identify(*http.Request) (*Principal, error) {
+	return nil, errors.New("bad credentials")
+}
+
+func TestBuildAuthorizingRouter_identifiesReads(t *testing.T) {
+	get := func(ids IdentitySource, method, user string) (*readingTestResource, *httptest.ResponseRecorder) {
+		res := &readingTestResource{TestResource: newTestResource("base")}
+		rm := &RouteMap{{"test", "/test/:param", res}}
+		req := httptest.NewRequest(method, "/test/present", nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		rw := httptest.NewRecorder()
+		rm.BuildAuthorizingRouter(logging.SilentLogSet(), ids).ServeHTTP(rw, req)
+		return res, rw
+	}
+	ids := TrustedHeaderIdentity{UserHeader: "X-User"}
+
+	for _, method := range []string{"GET", "HEAD"} {
+		res, rw := get(ids, method, "alice")
+		assert.Equal(t, 200, rw.Code, method)
+		assert.True(t, res.identified, method)
+		if assert.NotNil(t, res.principal, method) {
+			assert.Equal(t, "alice", res.principal.Name, method)
+		}
+	}
+
+	res, rw := get(ids, "GET", "")
+	assert.Equal(t, 200, rw.Code)
+	assert.True(t, res.identified, "anonymous callers are still identified")
+	assert.Nil(t, res.principal)
+
+	res, rw = get(failingIdentity{}, "GET", "alice")
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+	assert.False(t, res.identified)
+
+	res, rw = get(nil, "GET", "alice")
+	assert.Equal(t, 200, rw.Code)
+	assert.False(t, res.identified)
+	assert.Nil(t, res.principal)
+}
+
+func TestBuildAuthorizingRouter(t *testing.T) {
+	ids := TrustedHeaderIdentity{UserHeader: "X-User", GroupsHeader: "X-Groups"}
+
+	put := func(res Resource, user, groups string) *httptest.ResponseRecorder {
+		rm := &RouteMap{{"test", "/test/:param", res}}
+		router := rm.BuildAuthorizingRouter(logging.SilentLogSet(), ids)
+		req := httptest.NewRequest("PUT", "/test/missing", strings.NewReader(`{"Data":"new"}`))
+		req.Header.Set("If-None-Match", "*")
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		req.Header.Set("X-Groups", groups)
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		res := &authorizingTestResource{TestResource: newTestResource("base"), allowed: "admins"}
+		rw := put(res, "alice", "devs, admins")
+		assert.Equal(t, 200, rw.Code)
+		assert.Equal(t, `{"Data":"new"}`, res.gotBody)
+		assert.Equal(t, "alice", res.principal.Name)
+		// The handler still gets the body after Authorize has read it.
+		assert.Equal(t, "new", res.Data)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		res := &authorizingTestResource{TestResource: newTestResource("base"), allowed: "admins"}
+		rw := put(res, "bob", "devs")
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Equal(t, "base", res.Data)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		res := &authorizingTestResource{TestResource: newTestResource("base"), allowed: "admins"}
+		rw := put(res, "", "admins")
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Nil(t, res.principal)
+	})
+
+	t.Run("no authorizer", func(t *testing.T) {
+		res := newTestResource("base")
+		rw := put(res, "alice", "admins")
+		assert.Equal(t, http.StatusForbidden, rw.Code)
+		assert.Equal(t, "base", res.Data)
+	})
+
+	t.Run("no identity source", func(t *testing.T) {
+		res := newTestResource("base")
+		rm := &RouteMap{{"test", "/test/:param", res}}
+		req := httptest.NewRequest("PUT", "/test/missing", strings.NewReader(`{"Data":"new"}`))
+		req.Header.Set("If-None-Match", "*")
+		rw := httptest.NewRecorder()
+		rm.BuildRouter(logging.SilentLogSet()).ServeHTTP(rw, req)
+		assert.Equal(t, 200, rw.Code)
+		assert.Equal(t, "new", res.Data)
+	})
+}
+
+func TestTokenFileIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-tokens")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+	b, _ := json.Marshal(map[string]Principal{
+		"s3cret": {Name: "ci", Groups: []string{"builders"}},
+	})
+	require.NoError(t, ioutil.WriteFile(path, b, 0600))
+
+	ids, err := NewTokenFileIdentity(path)
+	require.NoError(t, err)
+
+	identify := func(auth string) (*Principal, error) {
+		req := httptest.NewRequest("PUT", "/", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return ids.Identify(req)
+	}
+
+	p, err := identify("Bearer s3cret")
+	if assert.NoError(t, err) && assert.NotNil(t, p) {
+		assert.Equal(t, "ci", p.Name)
+		assert.True(t, p.Is("builders"))
+	}
+
+	p, err = identify("")
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = identify("Bearer wrong")
+	assert.Error(t, err)
+
+	_, err = identify("Basic czNjcmV0")
+	assert.Error(t, err)
+}
+
+func TestClientCertIdentity(t *testing.T) {
+	req := httptest.NewRequest("PUT", "/", nil)
+	p, err := ClientCertIdentity{}.Identify(req)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         "deployer",
+			OrganizationalUnit: []string{"sre"},
+		},
+		EmailAddresses: []string{"deployer@example.com"},
+	}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	p, err = ClientCertIdentity{}.Identify(req)
+	if assert.NoError(t, err) && assert.NotNil(t, p) {
+		assert.Equal(t, "deployer", p.Name)
+		assert.Equal(t, "deployer@example.com", p.Email)
+		assert.True(t, p.Is("sre"))
+	}
+}
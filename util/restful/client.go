@@ -160,14 +160,24 @@ func NewInMemoryClient(handler http.Handler, ls logging.LogSink, headers ...map[
 
 func buildHeaders(maybeHeaders []map[string]string) http.Header {
 	hs := make(http.Header)
-	if len(maybeHeaders) > 0 {
-		for k, v := range maybeHeaders[0] {
+	for _, headers := range maybeHeaders {
+		for k, v := range headers {
 			hs.Set(k, v)
 		}
 	}
 	return hs
 }
 
+// BearerAuth returns headers which authenticate requests with token, for use
+// with NewClient. If token is empty, no headers are returned, so requests
+// are anonymous.
+func BearerAuth(token string) map[string]string {
+	if token == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
 // ****
 
 // Retrieve makes a GET request on urlPath, after transforming qParms into ?&=
@@ -149,3 +149,29 @@ func dig(m interface{}, index ...interface{}) interface{} {
 	}
 	return res
 }
+
+func TestClientBearerAuth(t *testing.T) {
+	var auth, reqID string
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		auth = req.Header.Get("Authorization")
+		reqID = req.Header.Get("OT-RequestId")
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte("{}"))
+	}))
+	defer s.Close()
+
+	retrieve := func(headers ...map[string]string) {
+		c, err := NewClient(s.URL, logging.SilentLogSet(), headers...)
+		require.NoError(t, err)
+		body := map[string]interface{}{}
+		_, err = c.Retrieve("/path", nil, &body, nil)
+		require.NoError(t, err)
+	}
+
+	retrieve(map[string]string{"OT-RequestId": "request"}, BearerAuth("s3cret"))
+	assert.Equal(t, "Bearer s3cret", auth)
+	assert.Equal(t, "request", reqID)
+
+	retrieve(map[string]string{"OT-RequestId": "request"}, BearerAuth(""))
+	assert.Equal(t, "", auth)
+}
@@ -98,12 +98,13 @@ func BuildRouteMap(f func(RouteEntryBuilder)) *RouteMap {
 	return &rm
 }
 
-func (rm *RouteMap) buildMetaHandler(r *httprouter.Router, ls logging.LogSink) *MetaHandler {
+func (rm *RouteMap) buildMetaHandler(r *httprouter.Router, ls logging.LogSink, ids IdentitySource) *MetaHandler {
 	ph := &StatusMiddleware{LogSink: ls, gatelatch: os.Getenv("GATELATCH")}
 	mh := &MetaHandler{
 		routeMap:      rm,
 		router:        r,
 		statusHandler: ph,
+		identity:      ids,
 		LogSink:       ls,
 	}
 	mh.InstallPanicHandler()
@@ -113,8 +114,17 @@ func (rm *RouteMap) buildMetaHandler(r *httprouter.Router, ls logging.LogSink) *
 
 // BuildRouter builds a returns an http.Handler based on some constant configuration
 func (rm *RouteMap) BuildRouter(ls logging.LogSink) http.Handler {
+	return rm.BuildAuthorizingRouter(ls, nil)
+}
+
+// BuildAuthorizingRouter is like BuildRouter, but the callers of GET, HEAD,
+// PUT and DELETE requests are identified by ids (c.f. PrincipalFromRequest),
+// and PUT and DELETE requests must be authorized by the resource (c.f.
+// Authorizer). If ids is nil, callers are not identified and writes are not
+// restricted.
+func (rm *RouteMap) BuildAuthorizingRouter(ls logging.LogSink, ids IdentitySource) http.Handler {
 	r := httprouter.New()
-	mh := rm.buildMetaHandler(r, ls)
+	mh := rm.buildMetaHandler(r, ls, ids)
 
 	for _, e := range *rm {
 		get, canGet := e.Resource.(Getable)
@@ -123,14 +133,14 @@ func (rm *RouteMap) BuildRouter(ls logging.LogSink) http.Handler {
 		opt, canOpt := e.Resource.(Optionsable)
 
 		if canGet {
-			r.Handle("GET", e.Path, mh.GetHandling(e.Name, get.Get))
-			r.Handle("HEAD", e.Path, mh.HeadHandling(e.Name, get.Get))
+			r.Handle("GET", e.Path, mh.GetHandling(e.Name, mh.identifying(e.Name, get.Get)))
+			r.Handle("HEAD", e.Path, mh.HeadHandling(e.Name, mh.identifying(e.Name, get.Get)))
 		}
 		if canPut {
-			r.Handle("PUT", e.Path, mh.PutHandling(e.Name, put.Put))
+			r.Handle("PUT", e.Path, mh.PutHandling(e.Name, mh.authorizing(e.Name, e.Resource, put.Put)))
 		}
 		if canDel {
-			r.Handle("DELETE", e.Path, mh.DeleteHandling(e.Name, del.Delete))
+			r.Handle("DELETE", e.Path, mh.DeleteHandling(e.Name, mh.authorizing(e.Name, e.Resource, del.Delete)))
 		}
 		if canOpt {
 			r.Handle("OPTIONS", e.Path, mh.OptionsHandling(e.Name, opt.Options))
@@ -171,7 +181,7 @@ func (rm *RouteMap) SingleExchanger(factory ExchangeFactory, gf func() Injector,
 	w := httptest.NewRecorder()
 	rq := httptest.NewRequest("GET", "/", nil)
 
-	mh := rm.buildMetaHandler(r, ls)
+	mh := rm.buildMetaHandler(r, ls, nil)
 
 	return mh.injectedHandler(factory, "single", w, rq, httprouter.Params{})
 }
@@ -28,6 +28,9 @@ type (
 		routeMap      *RouteMap
 		router        *httprouter.Router
 		statusHandler *StatusMiddleware
+		// identity, if set, identifies the callers of writes, which are then
+		// checked by the Authorizer of their resource.
+		identity IdentitySource
 		logging.LogSink
 	}
 
@@ -0,0 +1,210 @@
+// Package sbom lists the dependencies declared in a source tree, for a
+// software bill of materials.
+package sbom
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Component is a dependency of some software.
+	Component struct {
+		// Type is the kind of dependency, which is the ecosystem it comes from:
+		// docker, go, npm or pypi.
+		Type string
+		// Name and Version identify the dependency within its ecosystem.
+		// Version is empty if the source does not pin it.
+		Name, Version string
+	}
+
+	// A scanner lists the components declared in a file.
+	scanner func(io.Reader) ([]Component, error)
+)
+
+// scanners maps the names of the files which declare dependencies to their
+// scanners.
+var scanners = map[string]scanner{
+	"Dockerfile":        scanDockerfile,
+	"go.mod":            scanGoMod,
+	"package-lock.json": scanPackageLock,
+	"requirements.txt":  scanRequirements,
+}
+
+// Scan lists the components declared in the files in dir, sorted by type
+// and name. Files it does not know are ignored.
+func Scan(dir string) ([]Component, error) {
+	cs := []Component{}
+	for name, scan := range scanners {
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found, err := scan(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "scanning %s", name)
+		}
+		cs = append(cs, found...)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].Type != cs[j].Type {
+			return cs[i].Type < cs[j].Type
+		}
+		if cs[i].Name != cs[j].Name {
+			return cs[i].Name < cs[j].Name
+		}
+		return cs[i].Version < cs[j].Version
+	})
+	return cs, nil
+}
+
+// lines calls fn with each line of r which is not blank, trimmed of space
+// and of any comment started by '#'.
+func lines(r io.Reader, fn func(string)) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := s.Text()
+		if i := strings.Index(l, "#"); i >= 0 {
+			l = l[:i]
+		}
+		if l = strings.TrimSpace(l); l != "" {
+			fn(l)
+		}
+	}
+	return s.Err()
+}
+
+// scanDockerfile lists the images named in FROM instructions, leaving out
+// earlier build stages and scratch.
+func scanDockerfile(r io.Reader) ([]Component, error) {
+	cs := []Component{}
+	stages := map[string]bool{"scratch": true}
+	err := lines(r, func(l string) {
+		fs := strings.Fields(l)
+		if len(fs) < 2 || !strings.EqualFold(fs[0], "FROM") {
+			return
+		}
+		image := fs[1]
+		if strings.HasPrefix(image, "--") && len(fs) > 2 { // --platform=...
+			image = fs[2]
+		}
+		if len(fs) > 3 && strings.EqualFold(fs[len(fs)-2], "AS") {
+			stages[strings.ToLower(fs[len(fs)-1])] = true
+		}
+		if stages[strings.ToLower(image)] {
+			return
+		}
+		c := Component{Type: "docker", Name: image}
+		if i := strings.Index(image, "@"); i >= 0 {
+			c.Name, c.Version = image[:i], image[i+1:]
+		} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			c.Name, c.Version = image[:i], image[i+1:]
+		}
+		cs = append(cs, c)
+	})
+	return cs, err
+}
+
+// scanGoMod lists the modules a go.mod requires, in both the single line
+// and block forms.
+func scanGoMod(r io.Reader) ([]Component, error) {
+	cs := []Component{}
+	inBlock := false
+	err := lines(r, func(l string) {
+		if i := strings.Index(l, "//"); i >= 0 {
+			l = strings.TrimSpace(l[:i])
+		}
+		switch {
+		case l == "require (":
+			inBlock = true
+			return
+		case inBlock && l == ")":
+			inBlock = false
+			return
+		case strings.HasPrefix(l, "require "):
+			l = strings.TrimPrefix(l, "require ")
+		case !inBlock:
+			return
+		}
+		if fs := strings.Fields(l); len(fs) >= 2 {
+			cs = append(cs, Component{Type: "go", Name: fs[0], Version: fs[1]})
+		}
+	})
+	return cs, err
+}
+
+// scanPackageLock lists the packages in an npm package-lock.json. Newer lock
+// files list them under "packages", keyed by their path in node_modules;
+// older ones under "dependencies", keyed by name.
+func scanPackageLock(r io.Reader) ([]Component, error) {
+	type pkg struct {
+		Name, Version string
+	}
+	lock := struct {
+		Packages     map[string]pkg
+		Dependencies map[string]pkg
+	}{}
+	if err := json.NewDecoder(r).Decode(&lock); err != nil {
+		return nil, err
+	}
+	cs := []Component{}
+	seen := map[Component]bool{}
+	add := func(name, version string) {
+		c := Component{Type: "npm", Name: name, Version: version}
+		if name != "" && !seen[c] {
+			seen[c] = true
+			cs = append(cs, c)
+		}
+	}
+	const modules = "node_modules/"
+	for path, p := range lock.Packages {
+		i := strings.LastIndex(path, modules)
+		if i < 0 { // the package itself, or one in its workspace
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = path[i+len(modules):]
+		}
+		add(name, p.Version)
+	}
+	if len(lock.Packages) == 0 {
+		for name, p := range lock.Dependencies {
+			add(name, p.Version)
+		}
+	}
+	return cs, nil
+}
+
+// scanRequirements lists the packages in a pip requirements.txt. Versions
+// are only recorded when they are pinned with ==.
+func scanRequirements(r io.Reader) ([]Component, error) {
+	cs := []Component{}
+	err := lines(r, func(l string) {
+		if strings.HasPrefix(l, "-") { // options, such as -r other.txt
+			return
+		}
+		if i := strings.Index(l, ";"); i >= 0 { // environment markers
+			l = strings.TrimSpace(l[:i])
+		}
+		c := Component{Type: "pypi", Name: l}
+		if i := strings.Index(l, "=="); i >= 0 {
+			c.Name, c.Version = strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+2:])
+		} else if i := strings.IndexAny(l, "<>=!~ ["); i >= 0 {
+			c.Name = l[:i]
+		}
+		cs = append(cs, c)
+	})
+	return cs, err
+}
@@ -0,0 +1,77 @@
+package sbom
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFiles = map[string]string{
+	"Dockerfile": `FROM golang:1.10 AS build
+RUN go build
+FROM --platform=linux/amd64 docker.example.com/base/alpine@sha256:cabba9e
+COPY --from=build /app /app
+`,
+	"go.mod": `module example.com/app
+
+require github.com/pkg/errors v0.8.0 // indirect
+
+require (
+	github.com/stretchr/testify v1.2.2
+	// github.com/commented/out v1.0.0
+)
+`,
+	"package-lock.json": `{
+  "name": "app",
+  "packages": {
+    "": {"name": "app", "version": "1.0.0"},
+    "node_modules/left-pad": {"version": "1.3.0"},
+    "node_modules/a/node_modules/@scope/b": {"version": "2.0.0"}
+  }
+}`,
+	"requirements.txt": `# pinned
+requests==2.19.1
+-r more.txt
+flask>=1.0 ; python_version > "3"
+`,
+}
+
+func TestScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-sbom-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range testFiles {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	cs, err := Scan(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Component{
+		{Type: "docker", Name: "docker.example.com/base/alpine", Version: "sha256:cabba9e"},
+		{Type: "docker", Name: "golang", Version: "1.10"},
+		{Type: "go", Name: "github.com/pkg/errors", Version: "v0.8.0"},
+		{Type: "go", Name: "github.com/stretchr/testify", Version: "v1.2.2"},
+		{Type: "npm", Name: "@scope/b", Version: "2.0.0"},
+		{Type: "npm", Name: "left-pad", Version: "1.3.0"},
+		{Type: "pypi", Name: "flask"},
+		{Type: "pypi", Name: "requests", Version: "2.19.1"},
+	}, cs)
+}
+
+func TestScan_empty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sous-sbom-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cs, err := Scan(dir)
+	require.NoError(t, err)
+	assert.Empty(t, cs)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "package-lock.json"), []byte("not json"), 0644))
+	_, err = Scan(dir)
+	assert.Error(t, err)
+}